@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,8 @@ import (
 	"github.com/justincordova/dotcor/internal/core"
 	"github.com/justincordova/dotcor/internal/fs"
 	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/progress"
+	"github.com/justincordova/dotcor/internal/template"
 	"github.com/spf13/cobra"
 )
 
@@ -20,22 +23,62 @@ var addCmd = &cobra.Command{
 	Long: `Add one or more dotfiles or directories to DotCor management.
 
 Files are moved to the repository and replaced with symlinks.
-Supports glob patterns for batch operations.
+Supports glob patterns for batch operations. With --copy, the file stays in
+place as a copy of the repo file, for programs that rewrite their config or
+systems without symlink support; 'dotcor sync' pulls local edits back in.
+With --hardlink, the file stays in place as a hard link sharing the repo
+file's inode, for programs that resolve symlinks; the repository must be on
+the same filesystem. With --compose, the file is replaced with a small
+generated one that includes the repo file and then <file>.local, which
+isn't managed, for settings on this machine only (shell startup files,
+gitconfig, vimrc, tmux.conf and ssh config).
+
+~/.ssh and ~/.gnupg hold key material, so they are never added whole and
+their keys never at all. With --partial only their safe files are added
+(~/.ssh/config, known_hosts, ~/.gnupg/gpg.conf, ...), and those files are
+kept at mode 600 and the directory at 700.
+
+If a file is already a symlink into another location (such as an old
+dotfiles repository), add offers to import the real file into the
+repository and repoint the symlink. Use --reown to do this without asking.
 
 Examples:
   dotcor add ~/.zshrc                    # Add single file
   dotcor add ~/.zshrc ~/.bashrc          # Add multiple files
   dotcor add ~/.config/nvim/*            # Add with glob pattern
   dotcor add ~/.zshrc --category shell   # Add with custom category
-  dotcor add ~/.zshrc --force            # Skip validation warnings`,
-	Args: cobra.MinimumNArgs(1),
-	RunE: runAdd,
+  dotcor add ~/.zshrc --force            # Skip validation warnings
+  dotcor add ~/.zshrc --reown            # Import target of symlink into ~/dotfiles
+  dotcor add ~/.gitconfig --template     # Store as a per-machine template
+  dotcor add ~/.config/app.ini --copy    # Deploy as a copy, not a symlink
+  dotcor add ~/.config/app.ini --hardlink # Deploy as a hard link
+  dotcor add ~/.zshrc --compose          # Include the repo file and ~/.zshrc.local
+  dotcor add ~/.ssh --partial            # Only ~/.ssh/config, known_hosts, ...`,
+	Args:        cobra.MinimumNArgs(1),
+	RunE:        runAdd,
+	Annotations: structuredOutput,
+}
+
+// addOutput is the structured output of 'dotcor add'
+type addOutput struct {
+	DryRun    bool          `json:"dry_run" yaml:"dry_run"`
+	Added     int           `json:"added" yaml:"added"`
+	Skipped   int           `json:"skipped" yaml:"skipped"`
+	Committed bool          `json:"committed" yaml:"committed"`
+	Files     []fileOutcome `json:"files" yaml:"files"`
 }
 
 func init() {
 	addCmd.Flags().StringP("category", "c", "", "Override automatic category detection")
 	addCmd.Flags().BoolP("force", "f", false, "Force add, ignoring warnings (not errors)")
 	addCmd.Flags().Bool("dry-run", false, "Show what would be done without making changes")
+	addCmd.Flags().Bool("reown", false, "Import symlinks pointing outside the repo without asking")
+	addCmd.Flags().Bool("template", false, "Store the file as a template rendered per machine (see 'dotcor render')")
+	addCmd.Flags().Bool("copy", false, "Deploy the file as a copy instead of a symlink (mode: copy)")
+	addCmd.Flags().Bool("hardlink", false, "Deploy the file as a hard link instead of a symlink (mode: hardlink)")
+	addCmd.Flags().Bool("compose", false, "Deploy a file that includes the repo file and an unmanaged <file>.local (mode: compose)")
+	addCmd.Flags().Bool("partial", false, "Add only the safe files of ~/.ssh or ~/.gnupg, never the keys")
+	addCmd.MarkFlagsMutuallyExclusive("copy", "hardlink", "compose")
 	rootCmd.AddCommand(addCmd)
 }
 
@@ -43,6 +86,25 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	category, _ := cmd.Flags().GetString("category")
 	force, _ := cmd.Flags().GetBool("force")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	reown, _ := cmd.Flags().GetBool("reown")
+	asTemplate, _ := cmd.Flags().GetBool("template")
+	asCopy, _ := cmd.Flags().GetBool("copy")
+	asHardlink, _ := cmd.Flags().GetBool("hardlink")
+	asCompose, _ := cmd.Flags().GetBool("compose")
+	partial, _ := cmd.Flags().GetBool("partial")
+
+	mode := ""
+	switch {
+	case asCopy:
+		mode = config.DeployModeCopy
+	case asHardlink:
+		if asTemplate {
+			return fmt.Errorf("--hardlink can't be used with --template")
+		}
+		mode = config.DeployModeHardlink
+	case asCompose:
+		mode = config.DeployModeCompose
+	}
 
 	// Load config
 	cfg, err := config.LoadConfig()
@@ -52,15 +114,33 @@ func runAdd(cmd *cobra.Command, args []string) error {
 
 	// Acquire lock (skip for dry-run)
 	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
 		if err := core.AcquireLock(); err != nil {
 			return fmt.Errorf("acquiring lock: %w", err)
 		}
 		defer core.ReleaseLock()
+
+		if err := runHooks(cmd, cfg, config.HookPreAdd); err != nil {
+			return err
+		}
 	}
 
 	// Expand glob patterns in args
 	var files []string
 	for _, arg := range args {
+		if safe, ok, err := sensitiveDirFiles(arg, partial); ok {
+			if err != nil {
+				return err
+			}
+			files = append(files, safe...)
+			continue
+		}
+		if partial {
+			return fmt.Errorf("--partial is for ~/.ssh and ~/.gnupg, not %s", arg)
+		}
+
 		expanded, err := expandGlobArg(arg)
 		if err != nil {
 			return fmt.Errorf("expanding %s: %w", arg, err)
@@ -72,6 +152,34 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no files found matching the provided patterns")
 	}
 
+	out, err := addFiles(cmd, cfg, files, addOptions{
+		category:   category,
+		force:      force,
+		reown:      reown,
+		asTemplate: asTemplate,
+		mode:       mode,
+		dryRun:     dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	return writeResult(out)
+}
+
+// addOptions are the flags of 'dotcor add' applied to each file
+type addOptions struct {
+	category   string
+	force      bool
+	reown      bool
+	asTemplate bool
+	mode       string
+	dryRun     bool
+}
+
+// addFiles adds files, commits them and runs the post-add hooks, printing
+// a summary. The caller holds the lock and has run the pre-add hooks.
+func addFiles(cmd *cobra.Command, cfg *config.Config, files []string, opts addOptions) (addOutput, error) {
+	dryRun := opts.dryRun
 	if dryRun {
 		fmt.Println("Dry run - no changes will be made:")
 		fmt.Println("")
@@ -81,30 +189,53 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	added := 0
 	skipped := 0
 	var gitFiles []string
+	out := addOutput{DryRun: dryRun, Files: []fileOutcome{}}
 
-	for _, file := range files {
-		result, repoPath, err := processAddFile(cfg, file, category, force, dryRun)
+	var bar *progress.Bar
+	if !dryRun {
+		bar = startBar("Adding", len(files))
+	}
+
+	for i, file := range files {
+		// Each file is added in its own transaction, so stopping between
+		// files leaves the ones already added intact
+		if cmd.Context().Err() != nil {
+			break
+		}
+		bar.Set(i)
+		result, repoPath, err := processAddFile(cfg, file, opts.category, opts.force, opts.reown, opts.asTemplate, opts.mode, dryRun)
+		outcome := fileOutcome{Path: file, Repo: repoPath}
 		switch result {
 		case addResultSuccess:
 			added++
 			if repoPath != "" {
 				gitFiles = append(gitFiles, repoPath)
 			}
+			outcome.Result = "added"
 		case addResultSkipped:
 			skipped++
+			outcome.Result = "skipped"
 		case addResultError:
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", file, err)
+				outcome.Error = err.Error()
 			}
 			skipped++
+			outcome.Result = "failed"
 		}
+		out.Files = append(out.Files, outcome)
+	}
+	bar.Finish()
+	out.Added, out.Skipped = added, skipped
+	if err := cmd.Context().Err(); err != nil {
+		return out, err
 	}
 
 	// Summary
 	fmt.Println("")
 	if dryRun {
 		fmt.Printf("Would add %d file(s)\n", added)
-		return nil
+		return out, nil
 	}
 
 	fmt.Printf("Added %d file(s)", added)
@@ -112,23 +243,66 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		fmt.Printf(", skipped %d", skipped)
 	}
 	fmt.Println("")
+	recordChecksums(cfg, gitFiles...)
 
 	// Git commit
-	if git.IsGitInstalled() && added > 0 {
-		repoPath, err := config.ExpandPath(cfg.RepoPath)
+	if git.IsAvailable() && added > 0 {
+		repoPath, err := config.GetFilesRoot(cfg)
 		if err != nil {
 			fmt.Printf("⚠ Git commit skipped: invalid repo path: %v\n", err)
 		} else {
-			message := formatCommitMessage(gitFiles)
-			if err := git.AutoCommit(repoPath, message); err != nil {
+			trackLFSFiles(cfg, repoPath, gitFiles)
+			message := core.AddCommitMessage(gitFiles)
+			if err := git.AutoCommit(repoPath, message, userPathspecs...); err != nil {
 				fmt.Printf("⚠ Git commit failed: %v\n", err)
 			} else {
 				fmt.Println("✓ Committed to Git")
+				out.Committed = true
 			}
 		}
 	}
 
-	return nil
+	if added > 0 {
+		if err := runHooks(cmd, cfg, config.HookPostAdd); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// trackLFSFiles stores the added files that qualify for Git LFS with it
+// before they're committed, when lfs is enabled in the config
+func trackLFSFiles(cfg *config.Config, repoPath string, repoFiles []string) {
+	if !cfg.LFS.Enabled {
+		return
+	}
+
+	var large []string
+	for _, rel := range repoFiles {
+		needs, err := core.NeedsLFS(cfg.LFS, filepath.Join(repoPath, rel))
+		if err == nil && needs && !git.IsLFSTracked(repoPath, rel) {
+			large = append(large, rel)
+		}
+	}
+	if len(large) == 0 {
+		return
+	}
+
+	if !git.IsLFSInstalled() {
+		fmt.Printf("⚠ git-lfs is not installed, so %d large binary file(s) are committed as regular files\n", len(large))
+		return
+	}
+	if !git.LFSHooksInstalled(repoPath) {
+		if err := git.InstallLFS(repoPath); err != nil {
+			fmt.Printf("⚠ Git LFS skipped: %v\n", err)
+			return
+		}
+	}
+	if err := git.TrackLFS(repoPath, large...); err != nil {
+		fmt.Printf("⚠ Git LFS skipped: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Tracking %d file(s) with Git LFS\n", len(large))
 }
 
 type addResult int
@@ -140,7 +314,7 @@ const (
 )
 
 // processAddFile handles adding a single file
-func processAddFile(cfg *config.Config, sourcePath string, category string, force bool, dryRun bool) (addResult, string, error) {
+func processAddFile(cfg *config.Config, sourcePath string, category string, force bool, reown bool, asTemplate bool, mode string, dryRun bool) (addResult, string, error) {
 	// Expand source path
 	expanded, err := config.ExpandPath(sourcePath)
 	if err != nil {
@@ -158,6 +332,11 @@ func processAddFile(cfg *config.Config, sourcePath string, category string, forc
 		return addResultError, "", fmt.Errorf("file does not exist")
 	}
 
+	// System files are managed separately and need elevated privileges
+	if isSystem, _ := config.IsSystemPath(expanded); isSystem {
+		return addResultError, "", fmt.Errorf("file is outside your home directory, use 'dotcor system add'")
+	}
+
 	// Check if already managed
 	if cfg.IsManaged(sourcePath) {
 		fmt.Printf("  - %s (already managed)\n", normalized)
@@ -172,20 +351,46 @@ func processAddFile(cfg *config.Config, sourcePath string, category string, forc
 	}
 
 	// Run validation
+	linkTarget := ""
 	if err := core.ValidateSourceFile(expanded, cfg); err != nil {
-		// Check if it's a warning vs error
-		if isWarning(err) && force {
+		switch {
+		case errors.Is(err, core.ErrForeignSymlink):
+			// Symlink into another location - offer to take it over
+			target, evalErr := filepath.EvalSymlinks(expanded)
+			if evalErr != nil {
+				return addResultError, "", fmt.Errorf("resolving symlink: %w", evalErr)
+			}
+			if mode != "" {
+				return addResultError, "", fmt.Errorf("symlink to %s can't be added with --%s", target, mode)
+			}
+			if !reown && !dryRun {
+				ok, err := confirmReown(normalized, target)
+				if err != nil {
+					return addResultError, "", err
+				}
+				if !ok {
+					fmt.Printf("  - %s (symlink to %s left unchanged)\n", normalized, target)
+					return addResultSkipped, "", nil
+				}
+			}
+			linkTarget = target
+		case core.IsValidationWarning(err) && force:
+			// Check if it's a warning vs error
 			fmt.Printf("  ⚠ %s: %v (forced)\n", normalized, err)
-		} else {
+		default:
 			return addResultError, "", err
 		}
 	}
 
 	// Check for potential secrets
-	secrets, _ := core.DetectSecrets(expanded)
+	scanner, err := core.NewSecretScanner(cfg.Scan)
+	if err != nil {
+		return addResultError, "", err
+	}
+	secrets, _ := scanner.ScanFile(expanded)
 	if len(secrets) > 0 {
 		if !force {
-			return addResultError, "", fmt.Errorf("potential secrets detected: %v\nUse --force to add anyway", secrets)
+			return addResultError, "", fmt.Errorf("potential secrets detected: %v\nUse 'dotcor secret add' to store it encrypted, or --force to add anyway", secrets)
 		}
 		fmt.Printf("  ⚠ %s: potential secrets detected (forced)\n", normalized)
 	}
@@ -204,22 +409,32 @@ func processAddFile(cfg *config.Config, sourcePath string, category string, forc
 	if err != nil {
 		return addResultError, "", fmt.Errorf("generating repo path: %w", err)
 	}
+	if asTemplate {
+		repoPath += config.TemplateExt
+	}
 
 	// Validate repo file path can be constructed
-	if _, err := config.GetRepoFilePath(cfg, repoPath); err != nil {
+	fullRepoPath, err := config.GetRepoFilePath(cfg, repoPath)
+	if err != nil {
 		return addResultError, "", err
 	}
 
-	if dryRun {
-		fmt.Printf("  + %s → %s\n", normalized, repoPath)
-		return addResultSuccess, repoPath, nil
+	if mode == config.DeployModeCompose && !core.CanCompose(repoPath) {
+		return addResultError, "", fmt.Errorf("%s can't be composed, only shell startup files, gitconfig, vimrc, tmux.conf and ssh config can", normalized)
 	}
 
-	// Create backup
-	backupPath, err := core.CreateBackup(expanded)
-	if err != nil {
-		// Non-fatal, continue but warn
-		fmt.Printf("  ⚠ Backup failed for %s: %v\n", normalized, err)
+	// Hard links can't cross filesystems, catch that before touching anything
+	if mode == config.DeployModeHardlink && !fs.CanHardlink(expanded, filepath.Dir(fullRepoPath)) {
+		return addResultError, "", fmt.Errorf("%w, use --copy instead", fs.ErrCrossDevice)
+	}
+
+	if dryRun {
+		if linkTarget != "" {
+			fmt.Printf("  + %s → %s (imported from %s)\n", normalized, repoPath, linkTarget)
+		} else {
+			fmt.Printf("  + %s → %s\n", normalized, repoPath)
+		}
+		return addResultSuccess, repoPath, nil
 	}
 
 	// Create managed file entry
@@ -228,19 +443,37 @@ func processAddFile(cfg *config.Config, sourcePath string, category string, forc
 		RepoPath:   repoPath,
 		AddedAt:    time.Now(),
 		Platforms:  []string{}, // All platforms by default
+		Mode:       mode,
+	}
+
+	// Record permissions so clones and copies can be given the same mode
+	if perm, err := core.AddedPerm(expanded); err == nil {
+		mf.Perm = perm
+	}
+
+	// Create backup
+	backupPath, err := core.CreateManagedBackup(expanded, mf)
+	if err != nil {
+		// Non-fatal, continue but warn
+		fmt.Printf("  ⚠ Backup failed for %s: %v\n", normalized, err)
 	}
 
 	// Use transaction for atomic operation
-	tx, err := core.AddFileTransaction(cfg, sourcePath, repoPath, mf)
+	var tx *core.Transaction
+	if linkTarget != "" {
+		tx, err = core.ReownSymlinkTransaction(cfg, sourcePath, repoPath, mf)
+	} else {
+		tx, err = core.AddFileTransaction(cfg, sourcePath, repoPath, mf)
+	}
 	if err != nil {
 		return addResultError, "", fmt.Errorf("creating transaction: %w", err)
 	}
 
 	// Execute transaction
 	if err := tx.ExecuteAll(); err != nil {
-		// Rollback already happened in ExecuteAll
-		// Try to restore from backup if we have one
-		if backupPath != "" {
+		// Rollback already happened in ExecuteAll (which also restores
+		// a re-owned symlink). Try to restore from backup if we have one
+		if backupPath != "" && linkTarget == "" {
 			if restoreErr := core.RestoreBackup(backupPath, expanded); restoreErr != nil {
 				fmt.Fprintf(os.Stderr, "  ⚠ Failed to restore backup: %v\n", restoreErr)
 			}
@@ -249,12 +482,72 @@ func processAddFile(cfg *config.Config, sourcePath string, category string, forc
 	}
 
 	tx.Commit()
-	fmt.Printf("  ✓ %s\n", normalized)
+
+	// Templates are linked to their rendered output
+	if asTemplate {
+		data, err := template.NewData(cfg)
+		if err == nil {
+			_, err = renderAndLink(cfg, mf, data, false)
+		}
+		if err != nil {
+			fmt.Printf("  ⚠ %s: rendering template failed: %v\n", normalized, err)
+		}
+	}
+
+	if _, inSensitive := core.SensitiveDirFor(expanded); inSensitive {
+		if _, err := core.RestorePermissions(cfg, mf); err != nil {
+			fmt.Printf("  ⚠ %s: %v\n", normalized, err)
+		}
+	}
+
+	if linkTarget != "" {
+		fmt.Printf("  ✓ %s (imported from %s)\n", normalized, linkTarget)
+	} else {
+		fmt.Printf("  ✓ %s\n", normalized)
+	}
 
 	// Return relative repoPath (consistent with dry-run return)
 	return addResultSuccess, repoPath, nil
 }
 
+// sensitiveDirFiles returns the safe files of ~/.ssh or ~/.gnupg when arg
+// names one of them, refusing the directory without --partial. ok is false
+// for any other arg.
+func sensitiveDirFiles(arg string, partial bool) (files []string, ok bool, err error) {
+	expanded, err := config.ExpandPath(arg)
+	if err != nil {
+		return nil, false, nil
+	}
+	sensitive, inSensitive := core.SensitiveDirFor(expanded)
+	if dir, err := sensitive.Expanded(); !inSensitive || err != nil || dir != filepath.Clean(expanded) {
+		return nil, false, nil
+	}
+
+	if !partial {
+		return nil, true, fmt.Errorf("%s holds key material and is never added whole\nUse 'dotcor add %s --partial' to add only its safe files: %s",
+			sensitive.Path, sensitive.Path, strings.Join(sensitive.Safe, ", "))
+	}
+	safe, err := sensitive.SafeFiles()
+	if err != nil {
+		return nil, true, fmt.Errorf("listing %s: %w", sensitive.Path, err)
+	}
+	if len(safe) == 0 {
+		return nil, true, fmt.Errorf("%s has none of the files safe to manage: %s", sensitive.Path, strings.Join(sensitive.Safe, ", "))
+	}
+	for _, f := range safe {
+		if normalized, err := config.NormalizePath(f); err == nil {
+			files = append(files, normalized)
+		}
+	}
+	return files, true, nil
+}
+
+// confirmReown asks whether to import a symlink's target into the repo
+func confirmReown(path, target string) (bool, error) {
+	fmt.Printf("  %s is a symlink to %s\n", path, target)
+	return confirm("  Import it into the repo and repoint the symlink?", false)
+}
+
 // expandGlobArg expands a single argument that may contain glob patterns
 func expandGlobArg(arg string) ([]string, error) {
 	// First expand ~ if present
@@ -304,22 +597,3 @@ func containsGlob(s string) bool {
 	return strings.ContainsAny(s, "*?[")
 }
 
-// isWarning checks if an error is a warning vs a hard error
-func isWarning(err error) bool {
-	if err == nil {
-		return false
-	}
-	msg := err.Error()
-	return strings.Contains(msg, "warning") ||
-		strings.Contains(msg, "large file") ||
-		strings.Contains(msg, "unusual permissions")
-}
-
-// formatCommitMessage creates a commit message for added files
-func formatCommitMessage(files []string) string {
-	if len(files) == 1 {
-		basename := filepath.Base(files[0])
-		return fmt.Sprintf("Add %s", basename)
-	}
-	return fmt.Sprintf("Add %d dotfiles", len(files))
-}
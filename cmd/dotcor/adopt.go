@@ -24,24 +24,47 @@ DotCor's config without moving any files.
 
 Requirements:
 - The symlink must exist and be valid
-- The target must be inside the DotCor repository (~/.dotcor/files)
+- The target must be inside the DotCor repository (~/.dotcor/files),
+  unless --move is given
+
+A symlink that reaches the repository through other symlinks (such as
+~/.vimrc → ~/dotfiles/vimrc, with ~/dotfiles linked to the repository) is
+repointed straight at the repo file. A symlink to a directory in the
+repository is replaced with a real directory holding a symlink for each
+file in it, and every file is adopted.
+
+With --move, symlinks pointing outside the repository are adopted by moving
+the target file into the repository and repointing the symlink.
+
+--scan checks everything directly in the home directory and the symlinks in
+hidden directories below it, down to --depth levels (2 reaches ~/.config/*).
 
 Examples:
   dotcor adopt ~/.zshrc                 # Adopt single symlink
   dotcor adopt ~/.zshrc ~/.bashrc       # Adopt multiple symlinks
-  dotcor adopt --scan                   # Scan home directory for adoptable symlinks`,
+  dotcor adopt --scan                   # Scan home directory for adoptable symlinks
+  dotcor adopt --scan --depth 4         # Also find ~/.config/nvim/lua/* links
+  dotcor adopt ~/.config/nvim           # Adopt a linked directory file by file
+  dotcor adopt ~/.vimrc --move          # Move ~/dotfiles/vimrc into the repo`,
 	RunE: runAdopt,
 }
 
 func init() {
 	adoptCmd.Flags().Bool("scan", false, "Scan home directory for symlinks pointing to dotcor repo")
 	adoptCmd.Flags().Bool("dry-run", false, "Show what would be adopted without making changes")
+	adoptCmd.Flags().Bool("move", false, "Move targets outside the repo into it and repoint the symlinks")
+	adoptCmd.Flags().Int("depth", core.DefaultAdoptDepth, "Levels below the home directory --scan looks in")
 	rootCmd.AddCommand(adoptCmd)
 }
 
 func runAdopt(cmd *cobra.Command, args []string) error {
 	scanFlag, _ := cmd.Flags().GetBool("scan")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	move, _ := cmd.Flags().GetBool("move")
+	depth, _ := cmd.Flags().GetInt("depth")
+	if depth < 1 {
+		return fmt.Errorf("--depth must be at least 1")
+	}
 
 	// Load config
 	cfg, err := config.LoadConfig()
@@ -51,6 +74,9 @@ func runAdopt(cmd *cobra.Command, args []string) error {
 
 	// Acquire lock (skip for dry-run)
 	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
 		if err := core.AcquireLock(); err != nil {
 			return fmt.Errorf("acquiring lock: %w", err)
 		}
@@ -61,11 +87,13 @@ func runAdopt(cmd *cobra.Command, args []string) error {
 
 	if scanFlag {
 		// Scan for adoptable symlinks
-		found, err := scanForAdoptableSymlinks(cfg)
+		found, err := core.FindAdoptableSymlinks(cmd.Context(), cfg, depth)
 		if err != nil {
 			return fmt.Errorf("scanning for symlinks: %w", err)
 		}
-		symlinks = found
+		for _, link := range found {
+			symlinks = append(symlinks, link.Path)
+		}
 	} else {
 		if len(args) == 0 {
 			return fmt.Errorf("specify symlinks to adopt or use --scan to find them")
@@ -88,10 +116,10 @@ func runAdopt(cmd *cobra.Command, args []string) error {
 	skipped := 0
 
 	for _, symlink := range symlinks {
-		result, err := processAdoptSymlink(cfg, symlink, dryRun)
+		result, n, err := processAdoptSymlink(cfg, symlink, move, dryRun)
 		switch result {
 		case adoptResultSuccess:
-			adopted++
+			adopted += n
 		case adoptResultSkipped:
 			skipped++
 		case adoptResultError:
@@ -116,13 +144,13 @@ func runAdopt(cmd *cobra.Command, args []string) error {
 	fmt.Println("")
 
 	// Git commit (config changed, but no new files)
-	if git.IsGitInstalled() && adopted > 0 && !dryRun {
-		repoPath, err := config.ExpandPath(cfg.RepoPath)
+	if git.IsAvailable() && adopted > 0 && !dryRun {
+		repoPath, err := config.GetFilesRoot(cfg)
 		if err != nil {
 			fmt.Printf("⚠ Git commit skipped: invalid repo path: %v\n", err)
 		} else {
 			message := fmt.Sprintf("Adopt %d existing symlink(s)", adopted)
-			if err := git.AutoCommit(repoPath, message); err != nil {
+			if err := git.AutoCommit(repoPath, message, userPathspecs...); err != nil {
 				fmt.Printf("⚠ Git commit failed: %v\n", err)
 			}
 		}
@@ -139,161 +167,205 @@ const (
 	adoptResultError
 )
 
-// processAdoptSymlink handles adopting a single symlink
-func processAdoptSymlink(cfg *config.Config, symlinkPath string, dryRun bool) (adoptResult, error) {
-	// Expand and normalize path
-	expanded, err := config.ExpandPath(symlinkPath)
-	if err != nil {
-		return adoptResultError, fmt.Errorf("invalid path: %w", err)
-	}
-
+// processAdoptSymlink handles adopting a single symlink, returning how many
+// files were adopted (one, or each file of a linked directory)
+func processAdoptSymlink(cfg *config.Config, symlinkPath string, move bool, dryRun bool) (adoptResult, int, error) {
 	normalized, err := config.NormalizePath(symlinkPath)
 	if err != nil {
 		normalized = symlinkPath
 	}
 
-	// Check if it's actually a symlink
-	isLink, err := fs.IsSymlink(expanded)
-	if err != nil {
-		return adoptResultError, fmt.Errorf("checking symlink: %w", err)
-	}
-	if !isLink {
-		return adoptResultError, fmt.Errorf("not a symlink")
+	// Check if already managed
+	if cfg.IsManaged(normalized) {
+		fmt.Printf("  - %s (already managed)\n", normalized)
+		return adoptResultSkipped, 0, nil
 	}
 
-	// Get symlink target
-	target, err := fs.ReadSymlink(expanded)
+	link, ok, err := core.ResolveAdoptable(cfg, symlinkPath)
 	if err != nil {
-		return adoptResultError, fmt.Errorf("reading symlink: %w", err)
+		return adoptResultError, 0, err
 	}
-
-	// Resolve target to absolute path
-	var absoluteTarget string
-	if filepath.IsAbs(target) {
-		absoluteTarget = target
-	} else {
-		// Relative symlink - resolve from symlink directory
-		symlinkDir := filepath.Dir(expanded)
-		absoluteTarget = filepath.Clean(filepath.Join(symlinkDir, target))
-	}
-
-	// Check if target exists
-	if !fs.FileExists(absoluteTarget) {
-		return adoptResultError, fmt.Errorf("symlink target does not exist: %s", target)
-	}
-
-	// Check if target is inside the dotcor repo
-	repoFilesPath, err := config.ExpandPath(cfg.RepoPath)
-	if err != nil {
-		return adoptResultError, fmt.Errorf("expanding repo path: %w", err)
+	if !ok {
+		if move {
+			result, err := adoptMoveTarget(cfg, symlinkPath, normalized, dryRun)
+			return result, 1, err
+		}
+		expanded, _ := config.ExpandPath(symlinkPath)
+		target, _ := filepath.EvalSymlinks(expanded)
+		return adoptResultError, 0, fmt.Errorf("target is not inside dotcor repo: %s\nUse --move to move it into the repo", target)
 	}
 
-	relPath, err := filepath.Rel(repoFilesPath, absoluteTarget)
-	if err != nil || relPath == ".." || (len(relPath) > 2 && relPath[:3] == "../") {
-		return adoptResultError, fmt.Errorf("target is not inside dotcor repo: %s", absoluteTarget)
+	if link.Dir {
+		return adoptDirectory(cfg, link, dryRun)
 	}
 
-	// Check if already managed
-	if cfg.IsManaged(symlinkPath) {
-		fmt.Printf("  - %s (already managed)\n", normalized)
-		return adoptResultSkipped, nil
+	note := ""
+	if link.Indirect {
+		note = " (repointed)"
 	}
-
 	if dryRun {
-		fmt.Printf("  + %s → %s\n", normalized, relPath)
-		return adoptResultSuccess, nil
+		fmt.Printf("  + %s → %s%s\n", normalized, link.RepoPath, note)
+		return adoptResultSuccess, 1, nil
 	}
 
-	// Add to config
 	mf := config.ManagedFile{
 		SourcePath: normalized,
-		RepoPath:   relPath,
+		RepoPath:   link.RepoPath,
 		AddedAt:    time.Now(),
 		Platforms:  []string{},
 	}
 
-	if err := cfg.AddManagedFile(mf); err != nil {
-		return adoptResultError, fmt.Errorf("adding to config: %w", err)
+	tx := core.NewTransaction()
+	if link.Indirect {
+		// Point the link straight at the repo file, as dotcor does itself
+		if err := repointSymlink(tx, cfg, link.Path, link.RepoPath); err != nil {
+			return adoptResultError, 0, err
+		}
 	}
+	if err := tx.Execute(&core.AddToConfigOp{Config: cfg, File: mf}); err != nil {
+		return adoptResultError, 0, fmt.Errorf("adding to config: %w", err)
+	}
+	tx.Commit()
 
-	fmt.Printf("  ✓ %s → %s\n", normalized, relPath)
-	return adoptResultSuccess, nil
+	fmt.Printf("  ✓ %s → %s%s\n", normalized, link.RepoPath, note)
+	return adoptResultSuccess, 1, nil
 }
 
-// scanForAdoptableSymlinks scans the home directory for symlinks pointing to dotcor repo
-func scanForAdoptableSymlinks(cfg *config.Config) ([]string, error) {
-	home, err := os.UserHomeDir()
+// adoptDirectory adopts a symlink to a directory in the repo. dotcor
+// manages files, so the link is replaced with a real directory holding a
+// symlink to each repo file, and each file is adopted.
+func adoptDirectory(cfg *config.Config, link core.AdoptableLink, dryRun bool) (adoptResult, int, error) {
+	files, err := core.RepoFilesUnder(cfg, link.RepoPath)
 	if err != nil {
-		return nil, fmt.Errorf("getting home directory: %w", err)
+		return adoptResultError, 0, err
+	}
+	if len(files) == 0 {
+		fmt.Printf("  - %s (empty directory)\n", link.Path)
+		return adoptResultSkipped, 0, nil
 	}
 
-	repoFilesPath, err := config.ExpandPath(cfg.RepoPath)
-	if err != nil {
-		return nil, fmt.Errorf("expanding repo path: %w", err)
+	sourceFor := func(repoPath string) string {
+		rel, _ := filepath.Rel(link.RepoPath, repoPath)
+		return link.Path + "/" + filepath.ToSlash(rel)
 	}
 
-	var adoptable []string
+	if dryRun {
+		for _, repoPath := range files {
+			fmt.Printf("  + %s → %s\n", sourceFor(repoPath), repoPath)
+		}
+		return adoptResultSuccess, len(files), nil
+	}
 
-	// Scan common dotfile locations
-	locations := []string{
-		home,
-		filepath.Join(home, ".config"),
+	expanded, err := config.ExpandPath(link.Path)
+	if err != nil {
+		return adoptResultError, 0, fmt.Errorf("invalid path: %w", err)
+	}
+	tx := core.NewTransaction()
+	if err := tx.Execute(&core.RemoveSymlinkOp{Link: expanded}); err != nil {
+		return adoptResultError, 0, err
+	}
+	if err := tx.Execute(&core.CreateDirOp{Path: expanded}); err != nil {
+		return adoptResultError, 0, err
 	}
 
-	for _, location := range locations {
-		if !fs.PathExists(location) {
+	adopted := 0
+	for _, repoPath := range files {
+		source := sourceFor(repoPath)
+		if err := repointSymlink(tx, cfg, source, repoPath); err != nil {
+			return adoptResultError, 0, err
+		}
+		if cfg.IsManaged(source) {
 			continue
 		}
+		mf := config.ManagedFile{
+			SourcePath: source,
+			RepoPath:   repoPath,
+			AddedAt:    time.Now(),
+			Platforms:  []string{},
+		}
+		if err := tx.Execute(&core.AddToConfigOp{Config: cfg, File: mf}); err != nil {
+			return adoptResultError, 0, fmt.Errorf("adding to config: %w", err)
+		}
+		adopted++
+	}
+	tx.Commit()
 
-		entries, err := os.ReadDir(location)
-		if err != nil {
-			continue
+	fmt.Printf("  ✓ %s → %s (%d file(s), linked one by one)\n", link.Path, link.RepoPath, adopted)
+	return adoptResultSuccess, adopted, nil
+}
+
+// repointSymlink runs the steps that make source a symlink straight to the
+// repo file at repoPath, replacing any symlink already there
+func repointSymlink(tx *core.Transaction, cfg *config.Config, source, repoPath string) error {
+	expanded, err := config.ExpandPath(source)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	fullRepoPath, err := config.GetRepoFilePath(cfg, repoPath)
+	if err != nil {
+		return err
+	}
+	if isLink, _ := fs.IsSymlink(expanded); isLink {
+		if err := tx.Execute(&core.RemoveSymlinkOp{Link: expanded}); err != nil {
+			return err
 		}
+	}
+	return tx.Execute(&core.CreateSymlinkOp{Target: fullRepoPath, Link: expanded, Style: cfg.LinkStyle})
+}
 
-		for _, entry := range entries {
-			fullPath := filepath.Join(location, entry.Name())
+// adoptMoveTarget adopts a symlink pointing outside the repo by moving its
+// target into the repo and repointing the symlink
+func adoptMoveTarget(cfg *config.Config, symlinkPath, normalized string, dryRun bool) (adoptResult, error) {
+	expanded, err := config.ExpandPath(symlinkPath)
+	if err != nil {
+		return adoptResultError, fmt.Errorf("invalid path: %w", err)
+	}
 
-			// Check if it's a symlink
-			info, err := os.Lstat(fullPath)
-			if err != nil {
-				continue
-			}
-			if info.Mode()&os.ModeSymlink == 0 {
-				continue
-			}
+	// Follow the whole link chain to the real file
+	target, err := filepath.EvalSymlinks(expanded)
+	if err != nil {
+		return adoptResultError, fmt.Errorf("resolving symlink: %w", err)
+	}
+	if isDir, _ := fs.IsDirectory(target); isDir {
+		return adoptResultError, fmt.Errorf("symlink target is a directory: %s", target)
+	}
 
-			// Read symlink target
-			target, err := os.Readlink(fullPath)
-			if err != nil {
-				continue
-			}
+	repoPath, err := config.GenerateRepoPath(symlinkPath, "")
+	if err != nil {
+		return adoptResultError, fmt.Errorf("generating repo path: %w", err)
+	}
 
-			// Resolve target
-			var absoluteTarget string
-			if filepath.IsAbs(target) {
-				absoluteTarget = target
-			} else {
-				absoluteTarget = filepath.Clean(filepath.Join(filepath.Dir(fullPath), target))
-			}
+	fullRepoPath, err := config.GetRepoFilePath(cfg, repoPath)
+	if err != nil {
+		return adoptResultError, err
+	}
+	if fs.PathExists(fullRepoPath) {
+		return adoptResultError, fmt.Errorf("repo file already exists: %s", repoPath)
+	}
 
-			// Check if target is inside dotcor repo
-			relPath, err := filepath.Rel(repoFilesPath, absoluteTarget)
-			if err != nil {
-				continue
-			}
-			if relPath == ".." || (len(relPath) > 2 && relPath[:3] == "../") {
-				continue
-			}
+	if dryRun {
+		fmt.Printf("  + %s → %s (moved from %s)\n", normalized, repoPath, target)
+		return adoptResultSuccess, nil
+	}
 
-			// Check if already managed
-			normalized, _ := config.NormalizePath(fullPath)
-			if cfg.IsManaged(normalized) {
-				continue
-			}
+	mf := config.ManagedFile{
+		SourcePath: normalized,
+		RepoPath:   repoPath,
+		AddedAt:    time.Now(),
+		Platforms:  []string{},
+	}
 
-			adoptable = append(adoptable, normalized)
-		}
+	tx, err := core.MoveSymlinkTargetTransaction(cfg, symlinkPath, repoPath, mf)
+	if err != nil {
+		return adoptResultError, fmt.Errorf("creating transaction: %w", err)
 	}
 
-	return adoptable, nil
+	// Rollback (moving the target back) already happened in ExecuteAll
+	if err := tx.ExecuteAll(); err != nil {
+		return adoptResultError, err
+	}
+
+	tx.Commit()
+	fmt.Printf("  ✓ %s → %s (moved from %s)\n", normalized, repoPath, target)
+	return adoptResultSuccess, nil
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var autosyncCmd = &cobra.Command{
+	Use:   "autosync",
+	Short: "Sync on a schedule in the background",
+	Long: `Run 'dotcor sync --force' on a schedule, using a systemd user timer on
+Linux or a launchd agent on macOS. Each run's output is appended to
+~/.dotcor/logs/autosync.log.
+
+The interval comes from autosync.interval in config.yaml (default 1h).
+With --repo, the named repository gets a schedule of its own.
+
+Examples:
+  dotcor autosync install                # Sync every hour
+  dotcor autosync install --interval 30m # Sync every 30 minutes
+  dotcor autosync status                 # Is it installed, when did it last run?
+  dotcor autosync remove                 # Stop syncing on a schedule`,
+}
+
+var autosyncInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the scheduled sync",
+	Long: `Install and start the scheduled sync, replacing an earlier install.
+
+Run it again after changing autosync.interval or moving the dotcor binary.`,
+	Args: cobra.NoArgs,
+	RunE: runAutosyncInstall,
+}
+
+var autosyncRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Stop and remove the scheduled sync",
+	Args:  cobra.NoArgs,
+	RunE:  runAutosyncRemove,
+}
+
+var autosyncStatusCmd = &cobra.Command{
+	Use:         "status",
+	Short:       "Show whether the scheduled sync is installed and when it last ran",
+	Args:        cobra.NoArgs,
+	Annotations: readOnly,
+	RunE:        runAutosyncStatus,
+}
+
+func init() {
+	autosyncInstallCmd.Flags().String("interval", "", "Time between syncs, e.g. 30m or 2h (saved as autosync.interval)")
+	autosyncCmd.AddCommand(autosyncInstallCmd)
+	autosyncCmd.AddCommand(autosyncRemoveCmd)
+	autosyncCmd.AddCommand(autosyncStatusCmd)
+	rootCmd.AddCommand(autosyncCmd)
+}
+
+func runAutosyncInstall(cmd *cobra.Command, args []string) error {
+	interval, _ := cmd.Flags().GetString("interval")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if interval != "" {
+		if err := config.ValidateAutosyncInterval(interval); err != nil {
+			return err
+		}
+		cfg.Autosync.Interval = interval
+		if err := cfg.SaveConfig(); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+	}
+
+	logPath, err := autosyncLogPath()
+	if err != nil {
+		return err
+	}
+	binary, err := dotcorBinary()
+	if err != nil {
+		return err
+	}
+
+	job := core.AutosyncJob{
+		Binary:   binary,
+		Repo:     config.SelectedRepo(),
+		Interval: core.AutosyncInterval(cfg),
+		LogPath:  logPath,
+		Path:     os.Getenv("PATH"),
+	}
+	files, err := core.InstallAutosync(job)
+	for _, file := range files {
+		fmt.Printf("✓ Wrote %s\n", file)
+	}
+	if err != nil {
+		return fmt.Errorf("installing autosync: %w", err)
+	}
+
+	fmt.Printf("✓ dotcor sync will run every %s\n", job.Interval)
+	fmt.Printf("  Output is logged to %s\n", logPath)
+	return nil
+}
+
+func runAutosyncRemove(cmd *cobra.Command, args []string) error {
+	removed, err := core.RemoveAutosync(config.SelectedRepo())
+	for _, file := range removed {
+		fmt.Printf("✓ Removed %s\n", file)
+	}
+	if err != nil {
+		return fmt.Errorf("removing autosync: %w", err)
+	}
+	if len(removed) == 0 {
+		fmt.Println("Autosync is not installed.")
+	}
+	return nil
+}
+
+func runAutosyncStatus(cmd *cobra.Command, args []string) error {
+	status, err := core.GetAutosyncStatus(config.SelectedRepo())
+	if err != nil {
+		return err
+	}
+
+	if !status.Installed {
+		if len(status.Files) > 0 {
+			fmt.Println("✗ Autosync is partly installed. Run 'dotcor autosync install' to repair it.")
+		} else {
+			fmt.Println("Autosync is not installed. Run 'dotcor autosync install' to set it up.")
+		}
+		return nil
+	}
+
+	if status.Active {
+		fmt.Printf("✓ Autosync is running every %s\n", status.Interval)
+	} else {
+		fmt.Printf("✗ Autosync is installed (every %s) but not loaded. Run 'dotcor autosync install' to start it.\n", status.Interval)
+	}
+
+	if cfg, err := config.LoadConfig(); err == nil {
+		if want := core.AutosyncInterval(cfg); want != status.Interval {
+			fmt.Printf("⚠ autosync.interval is %s. Run 'dotcor autosync install' to apply it.\n", want)
+		}
+	}
+
+	for _, file := range status.Files {
+		fmt.Printf("  %s\n", file)
+	}
+
+	logPath, err := autosyncLogPath()
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(logPath); err == nil {
+		fmt.Printf("  Last run: %s (log: %s)\n", info.ModTime().Format("2006-01-02 15:04"), logPath)
+	} else {
+		fmt.Println("  Has not run yet")
+	}
+	return nil
+}
+
+// autosyncLogPath returns the file the scheduled sync of the selected
+// repository logs to
+func autosyncLogPath() (string, error) {
+	logsDir, err := config.GetLogsDir()
+	if err != nil {
+		return "", err
+	}
+	if repo := config.SelectedRepo(); repo != "" {
+		return filepath.Join(logsDir, "autosync-"+repo+".log"), nil
+	}
+	return filepath.Join(logsDir, "autosync.log"), nil
+}
+
+// dotcorBinary returns the absolute path of the dotcor binary for the
+// scheduler to run, preferring the one on PATH so upgrades are picked up
+func dotcorBinary() (string, error) {
+	if bin, err := exec.LookPath("dotcor"); err == nil {
+		if abs, err := filepath.Abs(bin); err == nil {
+			return abs, nil
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "", errors.New("can't find the dotcor binary, make sure it's on your PATH")
+	}
+	return exe, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "List, create and switch branches of the dotfiles repository",
+	Long: `Work on a branch other than the repository's default, e.g. one per machine.
+
+The branch this machine works on is kept in git_branch in config.yaml.
+'dotcor sync' commits to it and pushes it, setting its upstream on the first
+push, and refuses to sync while another branch is checked out.
+
+Switching branches changes the files your links point to. The ones that
+change are backed up first, so 'dotcor restore --from-backup' can bring them
+back. Copies and hard links are updated to match, and files the new branch
+doesn't have are reported.
+
+Examples:
+  dotcor branch                          # List branches
+  dotcor branch create machine/laptop    # Branch off for this machine
+  dotcor branch switch main              # Go back to the shared branch`,
+	Args: cobra.NoArgs,
+	RunE: runBranchList,
+}
+
+var branchCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a branch at the current commit and switch to it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBranchCheckout(cmd, args[0], true)
+	},
+}
+
+var branchSwitchCmd = &cobra.Command{
+	Use:   "switch <name>",
+	Short: "Switch to a branch, fetched from origin if it's only there",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBranchCheckout(cmd, args[0], false)
+	},
+}
+
+func init() {
+	branchCmd.AddCommand(branchCreateCmd, branchSwitchCmd)
+	rootCmd.AddCommand(branchCmd)
+}
+
+// openBranchRepo loads the config and opens the repository for the branch commands
+func openBranchRepo(cmd *cobra.Command) (*config.Config, *git.Repo, error) {
+	if err := requireInitialized(cmd); err != nil {
+		return nil, nil, err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+	if !git.IsGitInstalled() {
+		return nil, nil, fmt.Errorf("git is not installed")
+	}
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("expanding repo path: %w", err)
+	}
+	repo := git.OpenRepo(repoPath)
+	if !repo.IsRepo() {
+		return nil, nil, fmt.Errorf("dotcor repository is not a git repository")
+	}
+	return cfg, repo, nil
+}
+
+func runBranchList(cmd *cobra.Command, args []string) error {
+	cfg, repo, err := openBranchRepo(cmd)
+	if err != nil {
+		return err
+	}
+
+	branches, err := git.ListBranches(repo.Path())
+	if err != nil {
+		return err
+	}
+	if len(branches) == 0 {
+		fmt.Println("No branches yet. Add a file to make the first commit.")
+		return nil
+	}
+
+	for _, b := range branches {
+		marker := "  "
+		name := b.Name
+		if b.Current {
+			marker = "* "
+			name = paint(name, colorGreen)
+		}
+		line := marker + name
+		if b.Upstream != "" {
+			line += paint(" → "+b.Upstream, colorDim)
+		}
+		fmt.Println(line)
+	}
+
+	if cfg.GitBranch != "" {
+		fmt.Printf("\nThis machine syncs %s (git_branch).\n", cfg.GitBranch)
+	}
+	return nil
+}
+
+// runBranchCheckout switches to branch, creating it first with create, and
+// makes it the branch this machine syncs
+func runBranchCheckout(cmd *cobra.Command, branch string, create bool) error {
+	cfg, repo, err := openBranchRepo(cmd)
+	if err != nil {
+		return err
+	}
+	if err := git.ValidateBranchName(branch); err != nil {
+		return err
+	}
+	if err := checkBranchCheckout(cmd, repo, branch, create); err != nil {
+		return err
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	if err := checkoutBranch(cfg, repo, branch, create); err != nil {
+		return err
+	}
+
+	cfg.GitBranch = branch
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	return nil
+}
+
+// checkBranchCheckout returns an error if the repository can't switch to
+// branch: a merge or rebase is in progress, or branch exists (with create)
+// or doesn't exist anywhere (without)
+func checkBranchCheckout(cmd *cobra.Command, repo *git.Repo, branch string, create bool) error {
+	status, err := repo.Status()
+	if err != nil {
+		return fmt.Errorf("getting git status: %w", err)
+	}
+	if status.Rebasing || status.Merging || status.ConflictCount > 0 {
+		return conflictError(cmd, fmt.Sprintf("a merge or rebase is in progress in %s\nFinish or abort it with git before switching branches", repo.Path()))
+	}
+
+	exists := git.BranchExists(repo.Path(), branch)
+	if create && exists {
+		return fmt.Errorf("branch %s already exists\nSwitch to it with 'dotcor branch switch %s'", branch, branch)
+	}
+	if !create && !exists && !git.RemoteBranchExists(repo.Path(), "origin", branch) {
+		return fmt.Errorf("no branch %s here or on origin\nCreate it with 'dotcor branch create %s', or run 'git fetch' if another machine pushed it", branch, branch)
+	}
+	return nil
+}
+
+// checkoutBranch switches repo to branch, creating it first with create,
+// then brings deployed copies and hard links up to date with the files that
+// changed and warns about managed files the branch doesn't have
+func checkoutBranch(cfg *config.Config, repo *git.Repo, branch string, create bool) error {
+	repoPath := repo.Path()
+	before, _ := git.GetCurrentCommit(repoPath)
+
+	if create {
+		if err := git.CreateBranch(repoPath, branch); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Created branch %s\n", branch)
+	} else {
+		if current, _ := repo.Branch(); current == branch {
+			fmt.Printf("Already on %s\n", branch)
+			return nil
+		}
+		if err := backupForSwitch(cfg, repoPath, before, branch); err != nil {
+			return err
+		}
+		if err := git.SwitchBranch(repoPath, branch); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Switched to %s\n", branch)
+	}
+	repo.Refresh()
+
+	after, _ := git.GetCurrentCommit(repoPath)
+	if before != "" && after != "" && before != after {
+		changed, err := git.GetChangedBetween(repoPath, before, after)
+		if err != nil {
+			return err
+		}
+		redeployChanged(cfg, changed)
+		recordChecksums(cfg, changed...)
+	}
+
+	var missing []string
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		repoFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Lstat(repoFile); os.IsNotExist(err) {
+			missing = append(missing, mf.SourcePath)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Printf("⚠ %d managed file(s) aren't on %s, so their links are broken:\n", len(missing), branch)
+		for _, path := range missing {
+			fmt.Printf("  %s\n", path)
+		}
+		fmt.Println("  Switch back, or run 'dotcor remove' for files this machine doesn't need.")
+	}
+	return nil
+}
+
+// backupForSwitch backs up the linked files that switching from the before
+// commit to branch, the local one or else origin's, will change
+func backupForSwitch(cfg *config.Config, repoPath, before, branch string) error {
+	if before == "" {
+		return nil
+	}
+	target := branch
+	if !git.BranchExists(repoPath, branch) {
+		target = "origin/" + branch
+	}
+	changed, err := git.GetChangedBetween(repoPath, before, target)
+	if err != nil {
+		return err
+	}
+	backups, err := core.BackupLinkedFiles(cfg, changed)
+	if len(backups) > 0 {
+		fmt.Printf("✓ Backed up %d linked file(s) before switching\n", len(backups))
+	}
+	return err
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/crypto"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Group related dotfiles and enable or disable them together",
+	Long: `Group related dotfiles into named bundles, e.g. everything for nvim, so they
+can be enabled or disabled on a machine as one unit.
+
+Bundles are stored in the bundles section of config.yaml. A path in a
+bundle that is a directory covers every managed file below it. Disabling a
+bundle removes its deployed links but keeps the files in the repository and
+config; enabling it deploys them again. Either way the whole bundle is
+changed in one transaction, so a failure leaves every file as it was.
+
+Files in a disabled bundle are skipped by apply, status, list and sync.
+
+Examples:
+  dotcor bundle create nvim ~/.config/nvim   # Group all nvim files
+  dotcor bundle disable nvim                 # Unlink them on this machine
+  dotcor bundle enable nvim                  # Link them again
+  dotcor bundle list                         # Show bundles and their state`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create [name] [path]...",
+	Short: "Create a bundle from managed files or directories",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runBundleCreate,
+}
+
+var bundleEnableCmd = &cobra.Command{
+	Use:   "enable [name]",
+	Short: "Deploy every file in a bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBundleEnable,
+}
+
+var bundleDisableCmd = &cobra.Command{
+	Use:   "disable [name]",
+	Short: "Remove the deployed files of a bundle, keeping them in the repo",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBundleDisable,
+}
+
+var bundleDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete a bundle definition, leaving its files managed",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBundleDelete,
+}
+
+var bundleListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List bundles and the files they contain",
+	Args:        cobra.NoArgs,
+	Annotations: readOnly,
+	RunE:        runBundleList,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{bundleEnableCmd, bundleDisableCmd} {
+		c.Flags().Bool("dry-run", false, "Show what would be done without making changes")
+	}
+	bundleCmd.AddCommand(bundleCreateCmd, bundleEnableCmd, bundleDisableCmd, bundleDeleteCmd, bundleListCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	name := args[0]
+	if _, err := cfg.GetBundle(name); err == nil {
+		return fmt.Errorf("bundle %s already exists", name)
+	}
+
+	bundle := config.Bundle{Name: name}
+	for _, arg := range args[1:] {
+		normalized, err := config.NormalizePath(arg)
+		if err != nil {
+			return fmt.Errorf("invalid path %s: %w", arg, err)
+		}
+
+		// Every path must cover at least one managed file
+		covers := config.Bundle{Files: []string{normalized}}
+		found := false
+		for _, mf := range cfg.ManagedFiles {
+			if covers.Contains(mf.SourcePath) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no managed files at %s", normalized)
+		}
+
+		bundle.Files = append(bundle.Files, normalized)
+	}
+
+	cfg.Bundles = append(cfg.Bundles, bundle)
+	if err := config.ValidateBundles(cfg.Bundles); err != nil {
+		return err
+	}
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Printf("✓ Created bundle %s with %d files\n", name, len(cfg.GetBundleFiles(bundle)))
+	return nil
+}
+
+func runBundleEnable(cmd *cobra.Command, args []string) error {
+	return switchBundle(cmd, args[0], true)
+}
+
+func runBundleDisable(cmd *cobra.Command, args []string) error {
+	return switchBundle(cmd, args[0], false)
+}
+
+// switchBundle deploys or takes down every file in a bundle in a single
+// transaction, then records the new state in config as its last step
+func switchBundle(cmd *cobra.Command, name string, enable bool) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	bundle, err := cfg.GetBundle(name)
+	if err != nil {
+		return err
+	}
+	state := "enabled"
+	if !enable {
+		state = "disabled"
+	}
+	if bundle.Disabled != enable {
+		fmt.Printf("Bundle %s is already %s.\n", name, state)
+		return nil
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	tx := core.NewTransaction()
+	if dryRun {
+		tx = core.NewPlanTransaction()
+		fmt.Println("Dry run - no changes will be made:")
+	}
+
+	files := cfg.GetBundleFiles(*bundle)
+	var changed []config.ManagedFile
+
+	if enable {
+		fmt.Printf("Enabling bundle %s (%d files)...\n", name, len(files))
+
+		data, err := template.NewData(cfg)
+		if err != nil {
+			return fmt.Errorf("collecting template variables: %w", err)
+		}
+		var backend crypto.Backend
+
+		for _, mf := range files {
+			note, applied, err := applyFile(tx, cfg, mf, data, &backend)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("enabling bundle %s: %s: %w\nNo files were changed", name, mf.SourcePath, err)
+			}
+			reportBundleFile(mf, note, applied)
+			if applied {
+				changed = append(changed, mf)
+			}
+		}
+	} else {
+		fmt.Printf("Disabling bundle %s (%d files)...\n", name, len(files))
+
+		for _, mf := range files {
+			note, removed, err := disableFile(tx, cfg, mf)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("disabling bundle %s: %s: %w\nNo files were changed", name, mf.SourcePath, err)
+			}
+			reportBundleFile(mf, note, removed)
+		}
+	}
+
+	err = tx.Execute(&core.StepOp{
+		Desc: fmt.Sprintf("mark bundle %s %s in config", name, state),
+		DoFunc: func() error {
+			bundle.Disabled = !enable
+			return cfg.SaveConfig()
+		},
+		UndoFunc: func() error {
+			bundle.Disabled = enable
+			return cfg.SaveConfig()
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("saving config: %w\nNo files were changed", err)
+	}
+	tx.Commit()
+
+	if dryRun {
+		fmt.Println("\nPlanned steps:")
+		for _, step := range tx.Plan() {
+			fmt.Printf("  → %s\n", step)
+		}
+		return nil
+	}
+
+	fmt.Printf("\n✓ Bundle %s %s\n", name, state)
+	runFileHooks(cmd, cfg, config.HookPostApply, changed)
+	return nil
+}
+
+// reportBundleFile prints the outcome for one file of a bundle
+func reportBundleFile(mf config.ManagedFile, note string, changed bool) {
+	switch {
+	case !changed:
+		fmt.Printf("  - %s (%s)\n", mf.SourcePath, note)
+	case note != "":
+		fmt.Printf("  ✓ %s (%s)\n", mf.SourcePath, note)
+	default:
+		fmt.Printf("  ✓ %s\n", mf.SourcePath)
+	}
+}
+
+// disableFile runs the steps that remove the deployed copy of mf as part of
+// tx. The repo file and config entry are left alone. Files that aren't
+// deployed from the repo are skipped, and copies with local changes are an
+// error so they aren't lost.
+func disableFile(tx *core.Transaction, cfg *config.Config, mf config.ManagedFile) (note string, removed bool, err error) {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid path")
+	}
+
+	target, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid repo path")
+	}
+	if mf.IsTemplate() || mf.Encrypted {
+		if target, err = config.GetLinkTargetPath(cfg, mf); err != nil {
+			return "", false, err
+		}
+	}
+
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		resolved, _ := fs.ResolveSymlink(sourcePath)
+		if resolved != target && !linksToTemplateSource(cfg, mf, sourcePath) {
+			return "links elsewhere, left alone", false, nil
+		}
+		err = tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath})
+		return "unlinked", err == nil, err
+	}
+
+	if !fs.FileExists(sourcePath) {
+		return "not deployed", false, nil
+	}
+
+	switch {
+	case mf.IsHardlink():
+		if same, _ := fs.IsHardlinkTo(sourcePath, target); !same {
+			return "not linked to the repo, left alone", false, nil
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc:     fmt.Sprintf("remove hard link %s", sourcePath),
+			DoFunc:   func() error { return os.Remove(sourcePath) },
+			UndoFunc: func() error { return fs.CreateHardlink(target, sourcePath) },
+		})
+		return "unlinked", err == nil, err
+
+	case mf.IsCopy():
+		if same, _ := fs.SameContent(sourcePath, target); !same {
+			return "", false, fmt.Errorf("has local changes, run 'dotcor sync' first")
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc:     fmt.Sprintf("remove copy %s", sourcePath),
+			DoFunc:   func() error { return os.Remove(sourcePath) },
+			UndoFunc: func() error { return core.DeployCopy(cfg, mf) },
+		})
+		return "removed copy", err == nil, err
+	}
+
+	// A regular file where a symlink belongs isn't ours to remove
+	return "not a symlink, left alone", false, nil
+}
+
+func runBundleDelete(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	name := args[0]
+	bundle, err := cfg.GetBundle(name)
+	if err != nil {
+		return err
+	}
+	wasDisabled := bundle.Disabled
+
+	for i := range cfg.Bundles {
+		if cfg.Bundles[i].Name == name {
+			cfg.Bundles = append(cfg.Bundles[:i], cfg.Bundles[i+1:]...)
+			break
+		}
+	}
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Printf("✓ Deleted bundle %s\n", name)
+	if wasDisabled {
+		fmt.Println("Its files were not deployed. Run 'dotcor init --apply' to deploy them.")
+	}
+	return nil
+}
+
+func runBundleList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if len(cfg.Bundles) == 0 {
+		fmt.Println("No bundles defined.")
+		fmt.Println("Create one with: dotcor bundle create <name> <path>...")
+		return nil
+	}
+
+	for _, b := range cfg.Bundles {
+		state := "enabled"
+		if b.Disabled {
+			state = "disabled"
+		}
+		files := cfg.GetBundleFiles(b)
+		fmt.Printf("%s (%s, %d files)\n", b.Name, state, len(files))
+		for _, mf := range files {
+			fmt.Printf("  %s\n", mf.SourcePath)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var categoriesCmd = &cobra.Command{
+	Use:   "categories",
+	Short: "Show where new files are placed in the repository",
+	Long: `Show the rules that pick a new file's place in the repository.
+
+Rules in the categories section of config.yaml are checked first, in order,
+then the built-in ones. A pattern without a slash matches the file name; one
+with a slash matches the path under your home directory or a directory above
+the file, keeping the layout below it:
+
+  categories:
+    - pattern: .wezterm.lua
+      category: terminal
+    - pattern: ~/.config/kitty
+      category: terminal/kitty
+
+'dotcor add --category' and --repo-path still override the rules.
+
+Examples:
+  dotcor categories list                 # Show all rules
+  dotcor categories test ~/.wezterm.lua  # Show where a file would go`,
+}
+
+var categoriesListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List the configured and built-in category rules",
+	Args:        cobra.NoArgs,
+	Annotations: readOnly,
+	RunE:        runCategoriesList,
+}
+
+var categoriesTestCmd = &cobra.Command{
+	Use:         "test <path>...",
+	Short:       "Show where files would be placed by 'dotcor add'",
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: readOnly,
+	RunE:        runCategoriesTest,
+}
+
+func init() {
+	categoriesCmd.AddCommand(categoriesListCmd, categoriesTestCmd)
+	rootCmd.AddCommand(categoriesCmd)
+}
+
+func runCategoriesList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Configured (config.yaml):")
+	if len(cfg.Categories) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, rule := range cfg.Categories {
+		fmt.Fprintf(w, "  %s\t→ %s\n", rule.Pattern, rule.Category)
+	}
+
+	fmt.Fprintln(w, "\nBuilt-in:")
+	for _, rule := range config.BuiltinCategoryRules() {
+		fmt.Fprintf(w, "  %s\t→ %s\n", rule.Pattern, rule.Category)
+	}
+	fmt.Fprintln(w, "  ~/.config/<path>\t→ <path>")
+	fmt.Fprintln(w, "  ~/.local/<path>\t→ local/<path>")
+	fmt.Fprintln(w, "  anything else\t→ misc")
+	return w.Flush()
+}
+
+func runCategoriesTest(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	failed := 0
+	for _, arg := range args {
+		normalized, err := config.NormalizePath(arg)
+		if err != nil {
+			normalized = arg
+		}
+
+		if mf, err := cfg.GetManagedFile(normalized); err == nil {
+			fmt.Fprintf(w, "  %s\t→ %s\t(already managed)\n", normalized, mf.RepoPath)
+			continue
+		}
+		repoPath, reason, err := config.ExplainRepoPath(normalized)
+		if err != nil {
+			fmt.Fprintf(w, "  %s\t✗ %v\n", normalized, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t→ %s\t(%s)\n", normalized, repoPath, reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d path(s) can't be placed", failed)
+	}
+	return nil
+}
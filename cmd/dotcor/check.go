@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Validate repository contents against configurable rules",
+	Long: `Check the dotfiles repository for large files, potential secrets,
+and orphaned files.
+
+Each check has a strictness level: off, warn, or fail. Findings at the
+"fail" level make the command exit with code 2, so the check can be used
+to gate merges in a shared dotfiles repository.
+
+Default levels can be set in config.yaml:
+
+  check:
+    large_files: warn
+    secrets: fail
+    orphans: warn
+
+Exit codes:
+  0  All checks passed (warnings allowed)
+  1  Check could not run (e.g. invalid config)
+  2  One or more findings at the "fail" level
+
+Examples:
+  dotcor check                         # Run checks with configured levels
+  dotcor check --strict                # Treat all warnings as failures
+  dotcor check --orphans fail          # Override a single check level
+  dotcor check --json                  # Print report as JSON
+  dotcor check --report check.json     # Write JSON report to a file`,
+	Annotations: readOnly,
+	RunE:        runCheck,
+}
+
+func init() {
+	checkCmd.Flags().Bool("strict", false, "Treat all warnings as failures")
+	checkCmd.Flags().String("large-files", "", "Level for large file check (off, warn, fail)")
+	checkCmd.Flags().String("secrets", "", "Level for secret detection (off, warn, fail)")
+	checkCmd.Flags().String("orphans", "", "Level for orphaned file check (off, warn, fail)")
+	checkCmd.Flags().Bool("json", false, "Output report as JSON")
+	checkCmd.Flags().String("report", "", "Write JSON report to the given file")
+	rootCmd.AddCommand(checkCmd)
+}
+
+// CheckFinding is a single problem found by 'dotcor check'
+type CheckFinding struct {
+	Check   string `json:"check"`
+	Level   string `json:"level"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// CheckReport is the result of running all checks
+type CheckReport struct {
+	Passed    bool              `json:"passed"`
+	Strict    bool              `json:"strict"`
+	CheckedAt string            `json:"checked_at"`
+	RepoPath  string            `json:"repo_path"`
+	Levels    map[string]string `json:"levels"`
+	Failures  int               `json:"failures"`
+	Warnings  int               `json:"warnings"`
+	Findings  []CheckFinding    `json:"findings"`
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	strict, _ := cmd.Flags().GetBool("strict")
+	jsonFormat, _ := cmd.Flags().GetBool("json")
+	reportPath, _ := cmd.Flags().GetString("report")
+
+	// Load config
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	// Resolve levels: config defaults, then flag overrides, then --strict
+	levels := make(map[string]string)
+	flagNames := map[string]string{
+		config.CheckLargeFiles: "large-files",
+		config.CheckSecrets:    "secrets",
+		config.CheckOrphans:    "orphans",
+	}
+	for check, flagName := range flagNames {
+		level := cfg.Check.GetLevel(check)
+		if override, _ := cmd.Flags().GetString(flagName); override != "" {
+			level = override
+		}
+		if err := config.ValidateCheckLevel(level); err != nil {
+			return fmt.Errorf("%s: %w", flagName, err)
+		}
+		if strict && level == config.CheckLevelWarn {
+			level = config.CheckLevelFail
+		}
+		levels[check] = level
+	}
+
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+
+	report := runRepoChecks(cfg, repoPath, levels)
+	report.Strict = strict
+
+	// Write report artifact
+	if reportPath != "" {
+		if err := writeCheckReport(report, reportPath); err != nil {
+			return err
+		}
+	}
+
+	if jsonFormat {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		outputCheckReport(report)
+	}
+
+	if !report.Passed {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return &exitCodeError{
+			code: exitProblems,
+			msg:  fmt.Sprintf("check failed: %d finding(s) at fail level", report.Failures),
+		}
+	}
+
+	return nil
+}
+
+// runRepoChecks runs every enabled check and builds a report
+func runRepoChecks(cfg *config.Config, repoPath string, levels map[string]string) CheckReport {
+	report := CheckReport{
+		CheckedAt: time.Now().Format(time.RFC3339),
+		RepoPath:  repoPath,
+		Levels:    levels,
+		Findings:  []CheckFinding{},
+	}
+
+	addFinding := func(check, path, message string) {
+		level := levels[check]
+		report.Findings = append(report.Findings, CheckFinding{
+			Check:   check,
+			Level:   level,
+			Path:    path,
+			Message: message,
+		})
+		if level == config.CheckLevelFail {
+			report.Failures++
+		} else {
+			report.Warnings++
+		}
+	}
+
+	// An invalid scan config is reported instead of silently skipping secrets
+	scanner, err := core.NewSecretScanner(cfg.Scan)
+	if err != nil && levels[config.CheckSecrets] != config.CheckLevelOff {
+		addFinding(config.CheckSecrets, "config.yaml", err.Error())
+	}
+
+	// Per-file checks on every repo file, system files included
+	for _, files := range [][]config.ManagedFile{cfg.ManagedFiles, cfg.SystemFiles} {
+		for _, mf := range files {
+			fullPath := filepath.Join(repoPath, mf.RepoPath)
+			if !fs.FileExists(fullPath) {
+				continue // Missing files are reported by status/doctor
+			}
+
+			if levels[config.CheckLargeFiles] != config.CheckLevelOff {
+				// Files stored with Git LFS don't bloat the repository
+				if err := core.ValidateFileSize(fullPath); err != nil && !git.IsLFSTracked(repoPath, mf.RepoPath) {
+					addFinding(config.CheckLargeFiles, mf.RepoPath, err.Error())
+				}
+			}
+
+			if levels[config.CheckSecrets] != config.CheckLevelOff && scanner != nil && !mf.Encrypted {
+				findings, err := scanner.ScanFile(fullPath)
+				if err == nil && len(findings) > 0 {
+					var secrets []string
+					for _, f := range findings {
+						secrets = append(secrets, f.String())
+					}
+					addFinding(config.CheckSecrets, mf.RepoPath,
+						fmt.Sprintf("potential secrets detected: %s", strings.Join(secrets, "; ")))
+				}
+			}
+		}
+	}
+
+	// Repository-wide checks
+	if levels[config.CheckOrphans] != config.CheckLevelOff {
+		tracked := cfg.TrackedRepoPaths()
+		for _, orphan := range findOrphanedFiles(repoPath, tracked) {
+			addFinding(config.CheckOrphans, orphan, "file in repository is not tracked in config")
+		}
+	}
+
+	report.Passed = report.Failures == 0
+	return report
+}
+
+// writeCheckReport writes the report as JSON to path
+func writeCheckReport(report CheckReport, path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+
+	return nil
+}
+
+// outputCheckReport prints a human-readable check report
+func outputCheckReport(report CheckReport) {
+	fmt.Println("DotCor Check")
+	fmt.Println("============")
+	fmt.Println("")
+
+	for _, f := range report.Findings {
+		icon := "⚠"
+		if f.Level == config.CheckLevelFail {
+			icon = "✗"
+		}
+		fmt.Printf("  %s [%s] %s: %s\n", icon, f.Check, f.Path, f.Message)
+	}
+
+	if len(report.Findings) > 0 {
+		fmt.Println("")
+	}
+
+	if report.Passed {
+		if report.Warnings > 0 {
+			fmt.Printf("✓ Check passed with %d warning(s)\n", report.Warnings)
+		} else {
+			fmt.Println("✓ Check passed")
+		}
+		return
+	}
+
+	fmt.Printf("✗ Check failed: %d failure(s), %d warning(s)\n", report.Failures, report.Warnings)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestRepoChecksIncludeSystemFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg, err := config.NewDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoPath := t.TempDir()
+
+	systemRepoPath, err := config.GenerateSystemRepoPath("/etc/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ManagedFiles = []config.ManagedFile{{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"}}
+	cfg.SystemFiles = []config.ManagedFile{{SourcePath: "/etc/app.conf", RepoPath: systemRepoPath, Scope: config.ScopeSystem}}
+	for repoFile, content := range map[string]string{
+		"shell/zshrc":  "export EDITOR=vim\n",
+		systemRepoPath: "password=mysecretpassword123\n",
+	} {
+		path := filepath.Join(repoPath, repoFile)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := runRepoChecks(cfg, repoPath, map[string]string{
+		config.CheckLargeFiles: config.CheckLevelWarn,
+		config.CheckSecrets:    config.CheckLevelFail,
+		config.CheckOrphans:    config.CheckLevelFail,
+	})
+	if report.Passed {
+		t.Error("check passed with a secret in a system file")
+	}
+	if len(report.Findings) != 1 {
+		t.Fatalf("findings = %+v, want one", report.Findings)
+	}
+	if f := report.Findings[0]; f.Check != config.CheckSecrets || f.Path != systemRepoPath {
+		t.Errorf("finding = %+v, want a secret in %s", f, systemRepoPath)
+	}
+}
@@ -1,9 +1,7 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
@@ -94,13 +92,11 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 
 	// Confirmation
 	if !force {
-		fmt.Printf("Delete %d backup set(s), freeing %s? [y/N]: ", len(candidates), formatSize(freedSpace))
-
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
-
-		if input != "y" && input != "yes" {
+		ok, err := confirm(fmt.Sprintf("Delete %d backup set(s), freeing %s?", len(candidates), formatSize(freedSpace)), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Cancelled.")
 			return nil
 		}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"strings"
+	"runtime"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/core"
@@ -19,16 +17,24 @@ var cloneCmd = &cobra.Command{
 	Long: `Clone your dotfiles repository to a new machine.
 
 This command:
-1. Clones the repository to ~/.dotcor/files
-2. Creates symlinks for all managed files
+1. Clones the repository to ~/.dotcor/files, with its submodules
+2. Creates symlinks for all managed files (--apply)
 3. Sets up DotCor configuration
+4. Installs the packages declared in config.yaml (--packages)
+5. Applies the captured macOS preferences (--defaults)
+
+The cloned config's pre_apply, post_apply and per-file hooks run arbitrary
+commands, so --apply lists them and asks first; declining applies without
+them.
 
 This is the recommended way to set up DotCor on a new machine.
 
 Examples:
   dotcor clone git@github.com:user/dotfiles.git
   dotcor clone https://github.com/user/dotfiles.git
-  dotcor clone git@github.com:user/dotfiles.git --apply`,
+  dotcor clone git@github.com:user/dotfiles.git --apply
+  dotcor clone git@github.com:user/dotfiles.git --apply --packages
+  dotcor clone git@github.com:user/dotfiles.git --apply --defaults`,
 	Args: cobra.ExactArgs(1),
 	RunE: runClone,
 }
@@ -36,61 +42,84 @@ Examples:
 func init() {
 	cloneCmd.Flags().Bool("apply", false, "Create symlinks after cloning")
 	cloneCmd.Flags().BoolP("force", "f", false, "Overwrite existing dotcor directory")
+	cloneCmd.Flags().Bool("packages", false, "Install the declared packages after cloning")
+	cloneCmd.Flags().Bool("defaults", false, "Apply the captured macOS preferences after cloning")
 	rootCmd.AddCommand(cloneCmd)
 }
 
+// updateSubmodules checks out the repository's submodules, such as vendored
+// shell plugins, at their recorded commits. A failure is reported rather than
+// returned, so the rest of the setup goes ahead.
+func updateSubmodules(repoPath string) {
+	if !git.IsGitInstalled() || !git.HasSubmodules(repoPath) {
+		return
+	}
+
+	spinner := startSpinner("Checking out submodules")
+	err := git.UpdateSubmodules(repoPath)
+	spinner.Stop()
+	if err != nil {
+		fmt.Printf("⚠ Checking out submodules failed: %v\n", err)
+		fmt.Printf("  Run 'git submodule update --init --recursive' in %s to retry\n", repoPath)
+		return
+	}
+	fmt.Println("✓ Submodules checked out")
+}
+
 func runClone(cmd *cobra.Command, args []string) error {
 	repoURL := args[0]
 	apply, _ := cmd.Flags().GetBool("apply")
 	force, _ := cmd.Flags().GetBool("force")
+	installPkgs, _ := cmd.Flags().GetBool("packages")
+	applyPrefs, _ := cmd.Flags().GetBool("defaults")
 
 	// Check symlink support first
-	supported, err := fs.SupportsSymlinks()
-	if err != nil {
-		return fmt.Errorf("checking symlink support: %w", err)
-	}
-	if !supported {
-		fmt.Fprintln(os.Stderr, "Symlinks not supported on this platform.")
-		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(os.Stderr, "Windows users: Enable Developer Mode")
-		fmt.Fprintln(os.Stderr, "  Settings → Update & Security → For developers → Developer Mode")
-		return fmt.Errorf("symlinks not supported")
+	if supported, err := fs.SupportsSymlinks(); !supported {
+		return symlinkSupportError(err)
 	}
 
 	// Check if git is installed
-	if !git.IsGitInstalled() {
+	if !git.IsAvailable() {
 		return fmt.Errorf("git is not installed")
 	}
 
-	// Get config directory
-	configDir, err := config.GetConfigDir()
+	// Get the repository's home: ~/.dotcor (or its XDG data directory), or
+	// ~/.dotcor/repos/<name> for a repository selected with --repo
+	dataDir, err := config.GetRepoStateDir()
 	if err != nil {
 		return fmt.Errorf("getting config directory: %w", err)
 	}
 
-	filesDir := configDir + "/files"
+	filesDir := dataDir + "/files"
+
+	if err := checkEnvironment(cmd, filesDir); err != nil {
+		return err
+	}
 
 	// Check if already exists
-	if fs.PathExists(configDir) {
+	if fs.PathExists(dataDir) {
 		if !force {
-			fmt.Printf("DotCor directory already exists: %s\n", configDir)
-			fmt.Print("Overwrite? [y/N]: ")
-
-			reader := bufio.NewReader(os.Stdin)
-			input, _ := reader.ReadString('\n')
-			input = strings.TrimSpace(strings.ToLower(input))
-
-			if input != "y" && input != "yes" {
+			fmt.Printf("DotCor directory already exists: %s\n", dataDir)
+			ok, err := confirm("Overwrite?", false)
+			if err != nil {
+				return err
+			}
+			if !ok {
 				fmt.Println("Cancelled.")
 				return nil
 			}
 		}
 
-		// Remove existing
+		// Remove existing (to the trash unless the old config says otherwise)
 		fmt.Println("Removing existing DotCor directory...")
-		if err := fs.RemoveAll(configDir); err != nil {
+		oldCfg, _ := config.LoadConfig()
+		trashPath, err := core.DeleteUserFile(oldCfg, dataDir)
+		if err != nil {
 			return fmt.Errorf("removing existing directory: %w", err)
 		}
+		if trashPath != "" {
+			fmt.Printf("  → Moved to trash: %s\n", trashPath)
+		}
 	}
 
 	// Acquire lock - may fail if directory is new, which is expected
@@ -103,11 +132,14 @@ func runClone(cmd *cobra.Command, args []string) error {
 	// Create config directory structure
 	fmt.Println("Setting up DotCor...")
 
-	if err := fs.EnsureDir(configDir); err != nil {
+	if err := fs.EnsureDir(dataDir); err != nil {
 		return fmt.Errorf("creating config directory: %w", err)
 	}
 
-	backupsDir := configDir + "/backups"
+	backupsDir, err := core.GetBackupDir()
+	if err != nil {
+		return fmt.Errorf("getting backups directory: %w", err)
+	}
 	if err := fs.EnsureDir(backupsDir); err != nil {
 		return fmt.Errorf("creating backups directory: %w", err)
 	}
@@ -115,25 +147,42 @@ func runClone(cmd *cobra.Command, args []string) error {
 	// Clone repository
 	fmt.Printf("Cloning repository from %s...\n", repoURL)
 
-	if err := git.Clone(repoURL, filesDir); err != nil {
+	spinner := startSpinner("Cloning")
+	err = git.Clone(repoURL, filesDir)
+	spinner.Stop()
+	if err != nil {
 		return fmt.Errorf("cloning repository: %w", err)
 	}
 
 	fmt.Println("✓ Repository cloned")
+	updateSubmodules(filesDir)
 
 	// Check for config.yaml in repo
 	configPath := filesDir + "/config.yaml"
 	if fs.FileExists(configPath) {
-		// Copy config to correct location
-		destConfig := configDir + "/config.yaml"
-		if err := fs.CopyFile(configPath, destConfig); err != nil {
+		// Copy config to correct location. A named repository takes only
+		// its files from it, keeping the settings shared with the others.
+		var err error
+		if config.SelectedRepo() != "" {
+			err = adoptRepoConfig(configPath, filesDir)
+		} else {
+			var destConfig string
+			if destConfig, err = config.GetConfigPath(); err == nil {
+				err = fs.CopyFile(configPath, destConfig)
+			}
+		}
+		if err != nil {
 			fmt.Printf("⚠ Could not copy config: %v\n", err)
 		} else {
 			fmt.Println("✓ Configuration loaded from repository")
 		}
 	} else {
 		// Create default config
-		cfg, err := config.NewDefaultConfig()
+		newConfig := config.NewDefaultConfig
+		if config.SelectedRepo() != "" {
+			newConfig = config.LoadConfig
+		}
+		cfg, err := newConfig()
 		if err != nil {
 			return fmt.Errorf("creating config: %w", err)
 		}
@@ -144,17 +193,49 @@ func runClone(cmd *cobra.Command, args []string) error {
 		fmt.Println("  Note: Run 'dotcor rebuild-config --scan' to detect files")
 	}
 
-	// Apply symlinks if requested
-	if apply {
-		fmt.Println("")
-		fmt.Println("Creating symlinks...")
+	// The cloned content is what 'dotcor verify' checks against
+	if cfg, err := config.LoadConfig(); err == nil {
+		if err := core.RecordAllChecksums(cfg); err != nil {
+			fmt.Printf("⚠ Could not record checksums: %v\n", err)
+		}
+	}
 
+	// Apply symlinks, install packages and apply preferences if requested
+	if apply || installPkgs || applyPrefs {
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return fmt.Errorf("loading config: %w", err)
 		}
 
-		return applySymlinks(cfg)
+		if apply {
+			if err := confirmClonedHooks(cmd, cfg); err != nil {
+				return err
+			}
+			fmt.Println("")
+			fmt.Println("Creating symlinks...")
+			if err := applySymlinks(cmd, cfg, false); err != nil {
+				return err
+			}
+		}
+
+		if installPkgs {
+			fmt.Println("")
+			fmt.Println("Installing packages...")
+			if err := installPackages(cfg, false); err != nil {
+				return err
+			}
+		}
+
+		if applyPrefs {
+			fmt.Println("")
+			fmt.Println("Applying macOS preferences...")
+			if err := requireDefaults(); err != nil {
+				fmt.Printf("⚠ %v; skipping\n", err)
+			} else if err := applyDefaults(cfg, false); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	fmt.Println("")
@@ -162,8 +243,74 @@ func runClone(cmd *cobra.Command, args []string) error {
 	fmt.Println("")
 	fmt.Println("Next steps:")
 	fmt.Println("  dotcor init --apply    # Create symlinks for managed files")
+	if cfg, err := config.LoadConfig(); err == nil {
+		if !cfg.Packages.IsEmpty() {
+			fmt.Println("  dotcor packages install # Install the packages your dotfiles need")
+		}
+		if len(cfg.Defaults.Domains) > 0 && runtime.GOOS == "darwin" {
+			fmt.Println("  dotcor defaults apply  # Apply the captured macOS preferences")
+		}
+	}
 	fmt.Println("  dotcor list            # View managed files")
 	fmt.Println("  dotcor status          # Check current state")
 
 	return nil
 }
+
+// adoptRepoConfig makes the files managed by the config.yaml at configPath,
+// from a cloned repository, those of the selected named repository
+func adoptRepoConfig(configPath, filesDir string) error {
+	cloned, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.RepoPath = filesDir
+	cfg.FilesSubdir = cloned.FilesSubdir
+	cfg.ManagedFiles = cloned.ManagedFiles
+	cfg.SystemFiles = cloned.SystemFiles
+	return cfg.SaveConfig()
+}
+
+// confirmClonedHooks lists the commands the hooks of a cloned or imported
+// config run when it's applied and asks before running them, as they come
+// from someone else's repository. Declining applies it without hooks.
+func confirmClonedHooks(cmd *cobra.Command, cfg *config.Config) error {
+	if hooksDisabled(cmd) {
+		return nil
+	}
+
+	var commands []string
+	for _, event := range []string{config.HookPreApply, config.HookPostApply} {
+		for _, command := range cfg.Hooks.Get(event) {
+			commands = append(commands, fmt.Sprintf("%s: %s", event, command))
+		}
+	}
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		for _, command := range mf.Hooks {
+			commands = append(commands, fmt.Sprintf("%s: %s", mf.SourcePath, command))
+		}
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+
+	fmt.Println("")
+	fmt.Println("The config runs these commands when it's applied:")
+	for _, command := range commands {
+		fmt.Printf("  %s\n", command)
+	}
+	ok, err := confirm("Run them?", false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Applying without hooks.")
+		return cmd.Flags().Set("no-hooks", "true")
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change settings in config.yaml",
+	Long: `Read and change settings in ~/.dotcor/config.yaml without editing YAML by hand.
+
+Keys are the names used in config.yaml, with dots for nested settings.
+Values are parsed as YAML, so lists can be written in flow style. The
+whole config is validated before it is saved.
+
+Examples:
+  dotcor config get repo_path
+  dotcor config set git_enabled false
+  dotcor config set watch.debounce 1m
+  dotcor config set ignore_patterns "['*.log', .cache]"
+  dotcor config set variables.email you@example.com
+  dotcor config edit                       # Open config.yaml in $EDITOR`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:         "get [key]",
+	Short:       "Print a config value",
+	Args:        cobra.ExactArgs(1),
+	Annotations: readOnly,
+	RunE:        runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Change a config value",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit config.yaml in $EDITOR, validating before saving",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigEdit,
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd, configSetCmd, configEditCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	value, err := config.GetValue(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key, value := args[0], args[1]
+
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	// An invalid config loads anyway so it can be fixed; the result is validated
+	cfg, err := config.LoadConfigUnvalidated()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	updated, err := config.SetValue(cfg, key, value)
+	if err != nil {
+		return err
+	}
+	if err := git.ValidateCommitFormat(updated.CommitStyle, updated.CommitTemplate); err != nil {
+		return err
+	}
+	if err := updated.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	newValue, _ := config.GetValue(updated, key)
+	fmt.Printf("✓ %s = %s\n", key, newValue)
+	if key == "repo_path" || key == "files_subdir" {
+		fmt.Println("Files are not moved. Move the repository yourself, then run 'dotcor doctor --fix' to relink.")
+	}
+	return nil
+}
+
+func runConfigEdit(cmd *cobra.Command, args []string) error {
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return err
+	}
+	original, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no config at %s\nRun 'dotcor init' first", configPath)
+		}
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	// Edit a copy so an invalid config never replaces the real one
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), "config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	_, err = tmp.Write(original)
+	tmp.Close()
+	if err != nil {
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+
+	for {
+		if err := runEditor(tmpPath); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return fmt.Errorf("reading edited config: %w", err)
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Println("No changes.")
+			return nil
+		}
+
+		updated, err := config.ParseConfig(edited)
+		if err == nil {
+			err = git.ValidateCommitFormat(updated.CommitStyle, updated.CommitTemplate)
+		}
+		if err == nil {
+			if err := updated.SaveConfig(); err != nil {
+				return fmt.Errorf("saving config: %w", err)
+			}
+			fmt.Println("✓ Config saved")
+			return nil
+		}
+
+		fmt.Printf("✗ %v\n", err)
+		if nonInteractive {
+			return fmt.Errorf("config not saved: %w", err)
+		}
+		again, askErr := confirm("Edit again?", true)
+		if askErr != nil || !again {
+			return fmt.Errorf("config not saved: %w", err)
+		}
+	}
+}
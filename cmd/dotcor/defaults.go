@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/defaults"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var defaultsCmd = &cobra.Command{
+	Use:   "defaults",
+	Short: "Capture and apply macOS preferences",
+	Long: `Keep macOS preferences set with 'defaults write', such as Dock, Finder
+and keyboard settings, in the repository and replay them on other Macs.
+
+The domains and keys to keep are listed in config.yaml; a domain without
+keys keeps all of them:
+
+  defaults:
+    domains:
+      com.apple.dock: [autohide, tilesize]
+      com.apple.finder: [ShowPathbar]
+      com.apple.Terminal: []
+
+Each domain is stored as a property list in macos-defaults/ in the
+repository. With no domains configured, capture starts from a preset of
+Dock, Finder and keyboard settings. On a new Mac, run
+'dotcor clone <url> --apply --defaults' to set up files and preferences
+together.
+
+Examples:
+  dotcor defaults capture                          # Capture the configured domains
+  dotcor defaults capture com.apple.Terminal       # Add a domain and capture it
+  dotcor defaults capture com.apple.dock --keys autohide,tilesize
+  dotcor defaults apply --dry-run                  # Show what would change
+  dotcor defaults apply`,
+}
+
+var defaultsCaptureCmd = &cobra.Command{
+	Use:   "capture [domain]...",
+	Short: "Store the selected preferences of this Mac in the repository",
+	RunE:  runDefaultsCapture,
+}
+
+var defaultsApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Set this Mac's preferences to those stored in the repository",
+	Long: `Set this Mac's preferences to those stored in the repository. Only the
+stored keys are written; other keys of each domain are left as they are.
+Some applications, like the Dock and Finder, must be restarted to pick up
+the changes.`,
+	Args: cobra.NoArgs,
+	RunE: runDefaultsApply,
+}
+
+func init() {
+	defaultsCaptureCmd.Flags().StringSlice("keys", nil, "Keys to keep of the named domains (default: the preset's, or all)")
+	defaultsApplyCmd.Flags().Bool("dry-run", false, "Show the keys that would change without setting them")
+	defaultsCmd.AddCommand(defaultsCaptureCmd, defaultsApplyCmd)
+	rootCmd.AddCommand(defaultsCmd)
+}
+
+// requireDefaults fails on machines without the defaults command
+func requireDefaults() error {
+	if runtime.GOOS != "darwin" || !defaults.Available() {
+		return fmt.Errorf("macOS preferences can only be captured and applied on macOS")
+	}
+	return nil
+}
+
+func runDefaultsCapture(cmd *cobra.Command, args []string) error {
+	keys, _ := cmd.Flags().GetStringSlice("keys")
+	if len(keys) > 0 && len(args) == 0 {
+		return fmt.Errorf("--keys needs the domains to apply to")
+	}
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if err := requireDefaults(); err != nil {
+		return err
+	}
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid repo path: %w", err)
+	}
+
+	// Named domains are added to, or replace, the configured ones
+	configChanged := false
+	if cfg.Defaults.Domains == nil {
+		cfg.Defaults.Domains = map[string][]string{}
+	}
+	if len(args) == 0 && len(cfg.Defaults.Domains) == 0 {
+		fmt.Println("No domains configured; starting from the Dock, Finder and keyboard preset.")
+		for domain, presetKeys := range defaults.Preset {
+			cfg.Defaults.Domains[domain] = presetKeys
+		}
+		configChanged = true
+	}
+	for _, domain := range args {
+		domainKeys := keys
+		if len(domainKeys) == 0 {
+			if current, ok := cfg.Defaults.Domains[domain]; ok {
+				domainKeys = current
+			} else {
+				domainKeys = defaults.Preset[domain]
+			}
+		}
+		cfg.Defaults.Domains[domain] = domainKeys
+		configChanged = true
+	}
+	if err := config.ValidateDefaults(cfg.Defaults); err != nil {
+		return err
+	}
+
+	domains := args
+	if len(domains) == 0 {
+		domains = sortedDomains(cfg.Defaults)
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	for _, domain := range domains {
+		current, err := defaults.Export(domain)
+		if err != nil {
+			return err
+		}
+		captured := current.Select(cfg.Defaults.Domains[domain])
+
+		repoFile := filepath.Join(filesRoot, filepath.FromSlash(cfg.Defaults.RepoPath(domain)))
+		if err := os.MkdirAll(filepath.Dir(repoFile), 0755); err != nil {
+			return fmt.Errorf("creating %s: %w", filepath.Dir(repoFile), err)
+		}
+		if err := os.WriteFile(repoFile, captured.Encode(), 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", repoFile, err)
+		}
+		if len(captured) == 0 {
+			fmt.Printf("⚠ %s: none of the selected keys are set on this Mac\n", domain)
+		} else {
+			fmt.Printf("✓ Captured %d key(s) of %s\n", len(captured), domain)
+		}
+	}
+
+	if configChanged {
+		if err := cfg.SaveConfig(); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+	}
+
+	if git.IsAvailable() {
+		message := fmt.Sprintf("Capture macOS defaults: %s", strings.Join(domains, ", "))
+		if err := git.AutoCommit(filesRoot, message, userPathspecs...); err != nil {
+			fmt.Printf("⚠ Git commit failed: %v\n", err)
+		} else {
+			fmt.Println("✓ Committed to Git")
+		}
+	}
+	return nil
+}
+
+func runDefaultsApply(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if err := requireDefaults(); err != nil {
+		return err
+	}
+
+	return applyDefaults(cfg, dryRun)
+}
+
+// applyDefaults writes the stored keys of each configured domain that
+// differ on this Mac, then names the processes to restart
+func applyDefaults(cfg *config.Config, dryRun bool) error {
+	if len(cfg.Defaults.Domains) == 0 {
+		fmt.Println("No macOS defaults configured; capture some with 'dotcor defaults capture'.")
+		return nil
+	}
+	// Domains name the files read from the repo, so a cloned config's are
+	// checked before any of them is used
+	if err := config.ValidateDefaults(cfg.Defaults); err != nil {
+		return err
+	}
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid repo path: %w", err)
+	}
+
+	var restart []string
+	applied := 0
+	for _, domain := range sortedDomains(cfg.Defaults) {
+		repoFile := filepath.Join(filesRoot, filepath.FromSlash(cfg.Defaults.RepoPath(domain)))
+		data, err := os.ReadFile(repoFile)
+		if os.IsNotExist(err) {
+			fmt.Printf("⚠ %s has not been captured yet; run 'dotcor defaults capture' on a configured Mac\n", domain)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", repoFile, err)
+		}
+		stored, err := defaults.ParsePlist(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", repoFile, err)
+		}
+		stored = stored.Select(cfg.Defaults.Domains[domain])
+
+		current, err := defaults.Export(domain)
+		if err != nil {
+			return err
+		}
+		changed := current.Changed(stored)
+		if len(changed) == 0 {
+			fmt.Printf("✓ %s is up to date\n", domain)
+			continue
+		}
+
+		if dryRun {
+			fmt.Printf("Would set %s: %s\n", domain, strings.Join(changed, ", "))
+			continue
+		}
+		if err := defaults.Import(domain, current.Merge(stored)); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Set %d key(s) of %s\n", len(changed), domain)
+		applied++
+
+		if process := defaults.RestartProcess(domain); process != "" && !slices.Contains(restart, process) {
+			restart = append(restart, process)
+		}
+	}
+
+	if dryRun {
+		fmt.Println("\n(dry run - no preferences were changed)")
+		return nil
+	}
+	if len(restart) > 0 {
+		fmt.Printf("\nRestart %s for the changes to take effect:\n", strings.Join(restart, ", "))
+		fmt.Printf("  killall %s\n", strings.Join(restart, " "))
+	} else if applied > 0 {
+		fmt.Println("\nSome changes take effect only after logging out and in again.")
+	}
+	return nil
+}
+
+// sortedDomains returns the configured domains, sorted
+func sortedDomains(d config.DefaultsConfig) []string {
+	domains := make([]string, 0, len(d.Domains))
+	for domain := range d.Domains {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
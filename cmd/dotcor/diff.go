@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/justincordova/dotcor/internal/config"
@@ -16,20 +17,28 @@ var diffCmd = &cobra.Command{
 	Long: `Show Git diff of uncommitted changes in your dotfiles repository.
 
 Without arguments, shows all uncommitted changes. With a file argument,
-shows changes only for that specific file.
+shows changes only for that specific file, mapping its source path (like
+~/.zshrc) to the file in the repository.
+
+Diffs are colorized and shown through a pager when writing to a terminal.
+The pager is taken from $DOTCOR_PAGER or $PAGER and defaults to less.
 
 Examples:
   dotcor diff                  # Show all uncommitted changes
   dotcor diff ~/.zshrc         # Show changes for specific file
   dotcor diff --stat           # Show summary of changes
-  dotcor diff --name-only      # List changed files only`,
-	RunE: runDiff,
+  dotcor diff --name-only      # List changed files only
+  dotcor diff --staged         # Show only changes staged for commit
+  dotcor diff --no-pager       # Print straight to the terminal`,
+	Annotations: readOnly,
+	RunE:        runDiff,
 }
 
 func init() {
 	diffCmd.Flags().Bool("stat", false, "Show diffstat (summary of changes)")
 	diffCmd.Flags().Bool("name-only", false, "Show only names of changed files")
 	diffCmd.Flags().Bool("staged", false, "Show staged changes only")
+	diffCmd.Flags().Bool("no-pager", false, "Don't pipe output into a pager")
 	rootCmd.AddCommand(diffCmd)
 }
 
@@ -37,6 +46,7 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	statFlag, _ := cmd.Flags().GetBool("stat")
 	nameOnly, _ := cmd.Flags().GetBool("name-only")
 	staged, _ := cmd.Flags().GetBool("staged")
+	noPager, _ := cmd.Flags().GetBool("no-pager")
 
 	// Load config
 	cfg, err := config.LoadConfig()
@@ -50,7 +60,7 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get repo path
-	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	repoPath, err := config.GetFilesRoot(cfg)
 	if err != nil {
 		return fmt.Errorf("expanding repo path: %w", err)
 	}
@@ -81,6 +91,7 @@ func runDiff(cmd *cobra.Command, args []string) error {
 		output, err = getDiffStat(repoPath, filePath, staged)
 	} else {
 		output, err = getDiff(repoPath, filePath, staged)
+		output = colorize(output)
 	}
 
 	if err != nil {
@@ -96,36 +107,21 @@ func runDiff(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Print(output)
-	return nil
+	if noPager || nameOnly {
+		fmt.Print(output)
+		return nil
+	}
+	return page(output)
 }
 
 // getDiff returns the full diff output
 func getDiff(repoPath, filePath string, staged bool) (string, error) {
-	if filePath != "" {
-		return git.GetFileDiff(repoPath, filePath)
-	}
-	return git.GetDiff(repoPath)
+	return git.GetDiffWithOptions(repoPath, filePath, git.DiffOptions{Staged: staged})
 }
 
 // getDiffStat returns the diffstat output
 func getDiffStat(repoPath, filePath string, staged bool) (string, error) {
-	if filePath != "" {
-		// Git doesn't have a per-file stat, so we get full stat and filter
-		stat, err := git.GetDiffStat(repoPath)
-		if err != nil {
-			return "", err
-		}
-		// Filter lines containing our file
-		var filtered []string
-		for _, line := range strings.Split(stat, "\n") {
-			if strings.Contains(line, filePath) || strings.HasPrefix(line, " ") {
-				filtered = append(filtered, line)
-			}
-		}
-		return strings.Join(filtered, "\n"), nil
-	}
-	return git.GetDiffStat(repoPath)
+	return git.GetDiffWithOptions(repoPath, filePath, git.DiffOptions{Staged: staged, Stat: true})
 }
 
 // getChangedFileNames returns just the names of changed files
@@ -142,35 +138,29 @@ func getChangedFileNames(repoPath string, staged bool) (string, error) {
 	// Build output
 	var output strings.Builder
 	for _, file := range files {
-		output.WriteString(file)
+		output.WriteString(file.DisplayPath())
 		output.WriteString("\n")
 	}
 
 	return output.String(), nil
 }
 
-// colorize adds ANSI colors to diff output if terminal supports it
+// colorize adds ANSI colors to diff output when colors are on
 func colorize(diff string) string {
-	// Check if stdout is a terminal
-	if !isTerminal() {
+	if !useColor {
 		return diff
 	}
 
 	var colored strings.Builder
 	for _, line := range strings.Split(diff, "\n") {
-		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-			colored.WriteString("\033[32m") // Green
-			colored.WriteString(line)
-			colored.WriteString("\033[0m")
-		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
-			colored.WriteString("\033[31m") // Red
-			colored.WriteString(line)
-			colored.WriteString("\033[0m")
-		} else if strings.HasPrefix(line, "@@") {
-			colored.WriteString("\033[36m") // Cyan
-			colored.WriteString(line)
-			colored.WriteString("\033[0m")
-		} else {
+		switch {
+		case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+			colored.WriteString(paint(line, colorGreen))
+		case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+			colored.WriteString(paint(line, colorRed))
+		case strings.HasPrefix(line, "@@"):
+			colored.WriteString(paint(line, colorCyan))
+		default:
 			colored.WriteString(line)
 		}
 		colored.WriteString("\n")
@@ -179,11 +169,38 @@ func colorize(diff string) string {
 	return colored.String()
 }
 
-// isTerminal checks if stdout is a terminal
-func isTerminal() bool {
-	fileInfo, err := os.Stdout.Stat()
-	if err != nil {
-		return false
+// page shows output through the user's pager when stdout is a terminal,
+// falling back to printing it if no pager can be started
+func page(output string) error {
+	if !isTerminal() {
+		fmt.Print(output)
+		return nil
+	}
+
+	pager := os.Getenv("DOTCOR_PAGER")
+	if pager == "" {
+		pager = os.Getenv("PAGER")
+	}
+	if pager == "" {
+		pager = "less"
+	}
+	fields := strings.Fields(pager)
+	if len(fields) == 0 || fields[0] == "cat" {
+		fmt.Print(output)
+		return nil
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		fmt.Print(output)
+		return nil
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdin = strings.NewReader(output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Like git: quit if it fits on one screen, pass colors through
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
 	}
-	return (fileInfo.Mode() & os.ModeCharDevice) != 0
+	return cmd.Run()
 }
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/crypto"
 	"github.com/justincordova/dotcor/internal/fs"
 	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/output"
+	"github.com/justincordova/dotcor/internal/template"
 	"github.com/spf13/cobra"
 )
 
@@ -16,63 +22,233 @@ var doctorCmd = &cobra.Command{
 	Short: "Diagnose and repair DotCor issues",
 	Long: `Run diagnostics on your DotCor setup and optionally repair issues.
 
-Checks for:
-- Configuration validity
-- Symlink health
-- Git repository status
-- Stale lock files
-- Orphaned files
+Checks (select some with --check):
+  configuration   Configuration validity
+  lock            Stale lock files
+  interrupted     Changes a killed dotcor left half done
+  repository      Git repository status (also: git)
+  symlinks        Symlink health (also: links)
+  permissions     Recorded file permissions
+  system_files    System file health, when any are managed
+  lfs             Git LFS setup, when enabled or in use
+  orphaned_files  Repository files not in the config
+
+Exits with status 2 when issues remain, so it can alert from cron or CI,
+and 4 when dotcor is not initialized.
 
 Examples:
-  dotcor doctor          # Run diagnostics
-  dotcor doctor --fix    # Attempt to fix found issues`,
-	RunE: runDoctor,
+  dotcor doctor                      # Run diagnostics
+  dotcor doctor --fix                # Attempt to fix found issues
+  dotcor doctor --dry-run            # Show the fixes --fix would make
+  dotcor doctor --check symlinks,git # Run only some checks
+  dotcor doctor --json               # Per-check results as JSON`,
+	RunE:        runDoctor,
+	Annotations: structuredOutput,
 }
 
 func init() {
 	doctorCmd.Flags().Bool("fix", false, "Attempt to fix found issues")
+	doctorCmd.Flags().Bool("dry-run", false, "Show the fixes --fix would make without applying them")
+	doctorCmd.Flags().StringSlice("check", nil, "Run only these checks (comma-separated)")
+	doctorCmd.Flags().Bool("json", false, "Output results as JSON (same as --output json)")
 	rootCmd.AddCommand(doctorCmd)
 }
 
+// DoctorCheck is one named diagnostic run by 'dotcor doctor'
+type DoctorCheck interface {
+	Name() string                          // ID for --check and the structured output
+	Description() string                   // What is checked, shown while it runs
+	Enabled() bool                         // Whether the check applies to this setup
+	Run(fix *repairer) (issues, fixed int) // Reports problems, repairing them when fix is set
+}
+
+// funcCheck is a DoctorCheck backed by a function
+type funcCheck struct {
+	name    string
+	desc    string
+	aliases []string    // Other names accepted by --check
+	enabled func() bool // nil if the check always applies
+	run     func(fix *repairer) (issues, fixed int)
+}
+
+func (c funcCheck) Name() string        { return c.name }
+func (c funcCheck) Description() string { return c.desc }
+
+func (c funcCheck) Enabled() bool {
+	return c.enabled == nil || c.enabled()
+}
+
+func (c funcCheck) Run(fix *repairer) (issues, fixed int) {
+	return c.run(fix)
+}
+
+// doctorChecks are the checks run by 'dotcor doctor', in order
+var doctorChecks = []DoctorCheck{
+	funcCheck{name: "configuration", desc: "configuration", run: checkConfiguration},
+	funcCheck{name: "lock", desc: "lock file", run: checkLockFile},
+	funcCheck{name: "interrupted", desc: "for interrupted changes", run: checkInterrupted},
+	funcCheck{name: "repository", desc: "repository", aliases: []string{"git"}, run: checkRepository},
+	funcCheck{name: "symlinks", desc: "symlinks", aliases: []string{"links"}, run: checkSymlinks},
+	funcCheck{name: "permissions", desc: "permissions", run: checkPermissions},
+	funcCheck{
+		name:    "system_files",
+		desc:    "system files",
+		enabled: hasSystemFiles,
+		run: func(*repairer) (int, int) {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return 0, 0
+			}
+			return checkSystemFiles(cfg), 0
+		},
+	},
+	funcCheck{name: "lfs", desc: "Git LFS", enabled: usesLFS, run: checkLFS},
+	funcCheck{name: "orphaned_files", desc: "for orphaned files", run: checkOrphanedFiles},
+}
+
+// hasSystemFiles reports whether any system files are managed
+func hasSystemFiles() bool {
+	cfg, err := config.LoadConfig()
+	return err == nil && len(cfg.SystemFiles) > 0
+}
+
+// selectDoctorChecks returns the checks named by --check, in registry
+// order, or all of them if none are named
+func selectDoctorChecks(names []string) ([]DoctorCheck, error) {
+	if len(names) == 0 {
+		return doctorChecks, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, c := range doctorChecks {
+			if c.Name() == name || checkHasAlias(c, name) {
+				wanted[c.Name()] = true
+				found = true
+			}
+		}
+		if !found {
+			valid := make([]string, len(doctorChecks))
+			for i, c := range doctorChecks {
+				valid[i] = c.Name()
+			}
+			return nil, fmt.Errorf("unknown check %q (available: %s)", name, strings.Join(valid, ", "))
+		}
+	}
+
+	var selected []DoctorCheck
+	for _, c := range doctorChecks {
+		if wanted[c.Name()] {
+			selected = append(selected, c)
+		}
+	}
+	return selected, nil
+}
+
+// checkHasAlias reports whether c is also known as name
+func checkHasAlias(c DoctorCheck, name string) bool {
+	fc, ok := c.(funcCheck)
+	if !ok {
+		return false
+	}
+	for _, alias := range fc.aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+// doctorCheckResult is the outcome of one diagnostic in 'dotcor doctor'
+type doctorCheckResult struct {
+	Name    string `json:"name" yaml:"name"`
+	Healthy bool   `json:"healthy" yaml:"healthy"` // No issues left after fixes
+	Issues  int    `json:"issues" yaml:"issues"`
+	Fixed   int    `json:"fixed" yaml:"fixed"`
+}
+
+// doctorResult is the structured output of 'dotcor doctor'
+type doctorResult struct {
+	Healthy bool                `json:"healthy" yaml:"healthy"`
+	Issues  int                 `json:"issues" yaml:"issues"`
+	Fixed   int                 `json:"fixed" yaml:"fixed"`
+	Checks  []doctorCheckResult `json:"checks" yaml:"checks"`
+	Planned []string            `json:"planned,omitempty" yaml:"planned,omitempty"` // Fixes --dry-run would make
+}
+
+// repairer applies doctor's fixes. With --dry-run it records them in a plan
+// transaction instead, which is printed with the summary.
+type repairer struct {
+	plan *core.Transaction // Set for --dry-run
+}
+
+// apply runs a fix, or records it for --dry-run. It reports whether the fix
+// was actually applied.
+func (r *repairer) apply(desc string, do func() error) (bool, error) {
+	if r.plan != nil {
+		return false, r.plan.Execute(&core.StepOp{Desc: desc, DoFunc: do})
+	}
+	if err := do(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func runDoctor(cmd *cobra.Command, args []string) error {
-	fix, _ := cmd.Flags().GetBool("fix")
+	fixFlag, _ := cmd.Flags().GetBool("fix")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	checkNames, _ := cmd.Flags().GetStringSlice("check")
+	jsonFormat, _ := cmd.Flags().GetBool("json")
+
+	checks, err := selectDoctorChecks(checkNames)
+	if err != nil {
+		return err
+	}
+
+	if jsonFormat && !output.Structured() {
+		if err := output.SetFormat(output.FormatJSON); err != nil {
+			return err
+		}
+	}
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+
+	// fix stays nil unless repairs were asked for
+	var fix *repairer
+	if fixFlag || dryRun {
+		fix = &repairer{}
+		if dryRun {
+			fix.plan = core.NewPlanTransaction()
+		}
+	}
 
 	fmt.Println("DotCor Doctor")
 	fmt.Println("=============")
 	fmt.Println("")
 
-	issues := 0
-	fixed := 0
-
-	// Check 1: Configuration
-	fmt.Println("Checking configuration...")
-	configIssues, configFixed := checkConfiguration(fix)
-	issues += configIssues
-	fixed += configFixed
-
-	// Check 2: Lock file
-	fmt.Println("Checking lock file...")
-	lockIssues, lockFixed := checkLockFile(fix)
-	issues += lockIssues
-	fixed += lockFixed
-
-	// Check 3: Repository
-	fmt.Println("Checking repository...")
-	repoIssues, repoFixed := checkRepository(fix)
-	issues += repoIssues
-	fixed += repoFixed
-
-	// Check 4: Symlinks
-	fmt.Println("Checking symlinks...")
-	symlinkIssues, symlinkFixed := checkSymlinks(fix)
-	issues += symlinkIssues
-	fixed += symlinkFixed
-
-	// Check 5: Orphaned files
-	fmt.Println("Checking for orphaned files...")
-	orphanIssues, orphanFixed := checkOrphanedFiles(fix)
-	issues += orphanIssues
-	fixed += orphanFixed
+	var result doctorResult
+	for _, c := range checks {
+		if !c.Enabled() {
+			continue
+		}
+		fmt.Printf("Checking %s...\n", c.Description())
+		issues, fixed := c.Run(fix)
+		result.Checks = append(result.Checks, doctorCheckResult{
+			Name:    c.Name(),
+			Healthy: issues <= fixed,
+			Issues:  issues,
+			Fixed:   fixed,
+		})
+		result.Issues += issues
+		result.Fixed += fixed
+	}
+
+	issues, fixed := result.Issues, result.Fixed
+	result.Healthy = issues == 0
 
 	// Summary
 	fmt.Println("")
@@ -83,41 +259,70 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		fmt.Println("✓ No issues found. Your DotCor setup is healthy!")
 	} else {
 		fmt.Printf("Found %d issue(s)", issues)
-		if fix && fixed > 0 {
+		if fix != nil && fixed > 0 {
 			fmt.Printf(", fixed %d", fixed)
 		}
 		fmt.Println("")
 
-		if !fix && issues > fixed {
+		if fix == nil && issues > fixed {
 			fmt.Println("\nRun 'dotcor doctor --fix' to attempt repairs.")
 		}
 	}
 
+	if dryRun {
+		result.Planned = fix.plan.Plan()
+		if len(result.Planned) == 0 {
+			fmt.Println("\nNo fixes to make.")
+		} else {
+			fmt.Println("\nWould apply these fixes:")
+			for _, step := range result.Planned {
+				fmt.Printf("  → %s\n", step)
+			}
+		}
+	}
+
+	if err := writeResult(result); err != nil {
+		return err
+	}
+
+	// Issues --fix repaired don't count against the exit status
+	if issues > fixed {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return &exitCodeError{
+			code: exitProblems,
+			msg:  fmt.Sprintf("doctor found %d unresolved issue(s)", issues-fixed),
+		}
+	}
 	return nil
 }
 
 // checkConfiguration validates the config file
-func checkConfiguration(fix bool) (issues, fixed int) {
+func checkConfiguration(fix *repairer) (issues, fixed int) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		fmt.Printf("  ✗ Config error: %v\n", err)
 		issues++
 
-		if fix {
+		if fix != nil {
 			// Try to create default config
-			newCfg, err := config.NewDefaultConfig()
-			if err == nil {
-				if err := newCfg.SaveConfig(); err == nil {
-					fmt.Println("  ✓ Created new default config")
-					fixed++
+			applied, _ := fix.apply("create a default config", func() error {
+				newCfg, err := config.NewDefaultConfig()
+				if err != nil {
+					return err
 				}
+				return newCfg.SaveConfig()
+			})
+			if applied {
+				fmt.Println("  ✓ Created new default config")
+				fixed++
 			}
 		}
 		return
 	}
 
 	// Check repo path
-	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	repoPath, err := config.GetFilesRoot(cfg)
 	if err != nil {
 		fmt.Printf("  ✗ Invalid repo path: %v\n", err)
 		issues++
@@ -128,8 +333,11 @@ func checkConfiguration(fix bool) (issues, fixed int) {
 		fmt.Printf("  ✗ Repository directory missing: %s\n", repoPath)
 		issues++
 
-		if fix {
-			if err := fs.EnsureDir(repoPath); err == nil {
+		if fix != nil {
+			applied, _ := fix.apply("create repository directory "+repoPath, func() error {
+				return fs.EnsureDir(repoPath)
+			})
+			if applied {
 				fmt.Printf("  ✓ Created repository directory: %s\n", repoPath)
 				fixed++
 			}
@@ -141,7 +349,7 @@ func checkConfiguration(fix bool) (issues, fixed int) {
 }
 
 // checkLockFile checks for stale locks
-func checkLockFile(fix bool) (issues, fixed int) {
+func checkLockFile(fix *repairer) (issues, fixed int) {
 	info, err := core.GetLockInfo()
 	if err != nil {
 		return
@@ -174,11 +382,11 @@ func checkLockFile(fix bool) (issues, fixed int) {
 	fmt.Printf("  ✗ Stale lock from PID %d (process dead)\n", info.PID)
 	issues++
 
-	if fix {
-		if err := core.ForceReleaseLock(); err == nil {
+	if fix != nil {
+		if applied, err := fix.apply("remove stale lock", core.ForceReleaseLock); applied {
 			fmt.Println("  ✓ Removed stale lock")
 			fixed++
-		} else {
+		} else if err != nil {
 			fmt.Printf("  ✗ Could not remove lock: %v\n", err)
 		}
 	}
@@ -186,8 +394,47 @@ func checkLockFile(fix bool) (issues, fixed int) {
 	return
 }
 
+// checkInterrupted reports changes a killed dotcor process left half
+// done, rolling them back with --fix
+func checkInterrupted(fix *repairer) (issues, fixed int) {
+	interrupted, err := core.InterruptedTransactions()
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return 1, 0
+	}
+	if len(interrupted) == 0 {
+		fmt.Println("  ✓ No interrupted changes")
+		return
+	}
+
+	for _, it := range interrupted {
+		fmt.Printf("  ✗ Interrupted '%s' from %s (%d step(s) done)\n",
+			it.Entry.Command, it.Entry.Time.Local().Format("2006-01-02 15:04"), len(it.Entry.Ops))
+		issues++
+	}
+
+	cfg, err := config.LoadConfig()
+	if fix == nil || err != nil {
+		return
+	}
+	applied, err := fix.apply("roll back interrupted changes", func() error {
+		return core.WithLock(func() error {
+			recovered, err := core.RecoverTransactions(cfg)
+			fixed = len(recovered)
+			return err
+		})
+	})
+	if applied {
+		fmt.Printf("  ✓ Rolled back %d interrupted change(s)\n", fixed)
+	} else if err != nil {
+		fmt.Printf("  ✗ Could not roll back: %v\n", err)
+		fmt.Println("    Fix the files by hand, then delete its log in ~/.dotcor/journal/pending/")
+	}
+	return
+}
+
 // checkRepository checks the Git repository
-func checkRepository(fix bool) (issues, fixed int) {
+func checkRepository(fix *repairer) (issues, fixed int) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return
@@ -198,10 +445,24 @@ func checkRepository(fix bool) (issues, fixed int) {
 		return
 	}
 
-	// Check if git is installed
+	// Check if git is installed; the built-in backend covers the core workflow
 	if !git.IsGitInstalled() {
-		fmt.Println("  ⚠ Git is not installed (recommended)")
-		return
+		if !git.IsAvailable() {
+			fmt.Println("  ⚠ Git is not installed (recommended)")
+			return
+		}
+		fmt.Println("  ⚠ Git is not installed, using the built-in backend (history, diff and restore need git)")
+	}
+
+	// Validate worktree linkage before anything else: a broken link makes the
+	// directory look like it isn't a repository at all
+	if _, ok := git.ReadGitLink(repoPath); ok {
+		wtIssues, wtFixed, healthy := checkWorktreeLinkage(repoPath, fix)
+		issues += wtIssues
+		fixed += wtFixed
+		if !healthy {
+			return
+		}
 	}
 
 	// Check if it's a git repo
@@ -209,21 +470,47 @@ func checkRepository(fix bool) (issues, fixed int) {
 		fmt.Printf("  ✗ Not a Git repository: %s\n", repoPath)
 		issues++
 
-		if fix {
-			if err := git.InitRepo(repoPath); err == nil {
+		if fix != nil {
+			applied, err := fix.apply("initialize Git repository in "+repoPath, func() error {
+				return git.InitRepo(repoPath)
+			})
+			if applied {
 				fmt.Println("  ✓ Initialized Git repository")
 				fixed++
-			} else {
+			} else if err != nil {
 				fmt.Printf("  ✗ Could not initialize: %v\n", err)
 			}
 		}
 		return
 	}
 
-	// Check for uncommitted changes
-	hasChanges, _ := git.HasChanges(repoPath)
-	if hasChanges {
-		fmt.Println("  ⚠ Uncommitted changes in repository")
+	// The pre-commit hook isn't versioned, so clones and new machines lack it
+	if cfg.Scan.PreCommit && git.IsGitInstalled() && !git.HookInstalled(repoPath, "pre-commit") {
+		fmt.Println("  ✗ Pre-commit secret hook is not installed")
+		issues++
+
+		if fix != nil {
+			applied, err := fix.apply("install pre-commit secret hook", func() error {
+				return installPreCommitHook(cfg)
+			})
+			if applied {
+				fmt.Println("  ✓ Installed pre-commit hook")
+				fixed++
+			} else if err != nil {
+				fmt.Printf("  ✗ Could not install hook: %v\n", err)
+			}
+		}
+	}
+
+	// Check for uncommitted changes in the files directory only; the
+	// repository may hold unrelated content when files_subdir is set
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return
+	}
+	changes, _ := git.GetChangedFiles(filesRoot)
+	if len(changes) > 0 {
+		fmt.Printf("  ⚠ %d uncommitted change(s) in repository\n", len(changes))
 		fmt.Println("    Run 'dotcor sync' to commit changes")
 	} else {
 		fmt.Println("  ✓ Git repository healthy")
@@ -232,8 +519,230 @@ func checkRepository(fix bool) (issues, fixed int) {
 	return
 }
 
-// checkSymlinks validates all managed symlinks
-func checkSymlinks(fix bool) (issues, fixed int) {
+// checkWorktreeLinkage validates a files directory that is a linked worktree.
+// Returns healthy=false if the linkage is still broken after any fix attempt.
+func checkWorktreeLinkage(repoPath string, fix *repairer) (issues, fixed int, healthy bool) {
+	mainPath, err := git.GetWorktreeMainPath(repoPath)
+	if err != nil {
+		// .git file that isn't a worktree link (e.g. submodule) - nothing to validate
+		return 0, 0, true
+	}
+
+	if err := git.CheckWorktreeLink(repoPath); err != nil {
+		fmt.Printf("  ✗ Broken worktree link: %v\n", err)
+		issues++
+
+		if fix == nil {
+			fmt.Printf("    Run 'git worktree repair %s' from %s\n", repoPath, mainPath)
+			return issues, fixed, false
+		}
+
+		applied, err := fix.apply("repair worktree link of "+repoPath, func() error {
+			return git.RepairWorktree(mainPath, repoPath)
+		})
+		if err != nil {
+			fmt.Printf("  ✗ Could not repair worktree: %v\n", err)
+			return issues, fixed, false
+		}
+		if !applied {
+			return issues, fixed, false
+		}
+		fmt.Println("  ✓ Repaired worktree link")
+		fixed++
+	}
+
+	fmt.Printf("  ✓ Linked worktree of %s\n", mainPath)
+	return issues, fixed, true
+}
+
+// checkCopy validates a copy-mode file. Missing copies and leftover symlinks
+// are fixed by copying from the repo; edited copies are left for 'dotcor sync'.
+func checkCopy(cfg *config.Config, mf config.ManagedFile, sourcePath, repoPath string, fix *repairer) (issues, fixed int) {
+	isLink, _ := fs.IsSymlink(sourcePath)
+	switch {
+	case !fs.PathExists(sourcePath) && !isLink:
+		fmt.Printf("  ✗ Missing copy: %s\n", mf.SourcePath)
+	case isLink:
+		fmt.Printf("  ✗ Symlink instead of copy: %s\n", mf.SourcePath)
+	default:
+		if same, err := fs.SameContent(sourcePath, repoPath); err == nil && !same {
+			fmt.Printf("  ⚠ Copy differs from repo: %s\n", mf.SourcePath)
+			fmt.Println("    Run 'dotcor sync' to pull local edits in")
+			return 1, 0
+		}
+		return 0, 0
+	}
+
+	issues++
+	if fix != nil && fs.FileExists(repoPath) {
+		applied, _ := fix.apply("copy "+mf.RepoPath+" to "+mf.SourcePath, func() error {
+			return core.DeployCopy(cfg, mf)
+		})
+		if applied {
+			fmt.Printf("  ✓ Copied from repo: %s\n", mf.SourcePath)
+			fixed++
+		}
+	}
+	return issues, fixed
+}
+
+// checkHardlink verifies a hardlink-mode file shares an inode with its repo
+// file. A broken link is only relinked when the content still matches, so
+// local edits are never discarded.
+func checkHardlink(cfg *config.Config, mf config.ManagedFile, sourcePath, repoPath string, fix *repairer) (issues, fixed int) {
+	isLink, _ := fs.IsSymlink(sourcePath)
+	switch {
+	case !fs.PathExists(sourcePath) && !isLink:
+		fmt.Printf("  ✗ Missing hard link: %s\n", mf.SourcePath)
+	case isLink:
+		fmt.Printf("  ✗ Symlink instead of hard link: %s\n", mf.SourcePath)
+	default:
+		same, err := fs.IsHardlinkTo(sourcePath, repoPath)
+		if err != nil || same {
+			return 0, 0
+		}
+		if sameContent, _ := fs.SameContent(sourcePath, repoPath); !sameContent {
+			fmt.Printf("  ✗ Hard link broken, file differs from repo: %s\n", mf.SourcePath)
+			fmt.Println("    Copy your edits into the repo file, then run 'dotcor init --apply'")
+			return 1, 0
+		}
+		fmt.Printf("  ✗ Hard link broken: %s\n", mf.SourcePath)
+	}
+
+	issues++
+	if fix != nil && fs.FileExists(repoPath) {
+		applied, err := fix.apply("hard link "+mf.SourcePath+" to "+mf.RepoPath, func() error {
+			return core.DeployHardlink(cfg, mf)
+		})
+		if applied {
+			fmt.Printf("  ✓ Relinked: %s\n", mf.SourcePath)
+			fixed++
+		} else if errors.Is(err, fs.ErrCrossDevice) {
+			fmt.Printf("    %s is on another filesystem than the repo, use mode: copy\n", mf.SourcePath)
+		}
+	}
+	return issues, fixed
+}
+
+// checkComposed verifies a compose-mode file is the generated file that
+// includes its repo file. An edited one is reported, not overwritten.
+func checkComposed(cfg *config.Config, mf config.ManagedFile, sourcePath string, fix *repairer) (issues, fixed int) {
+	isLink, _ := fs.IsSymlink(sourcePath)
+	switch {
+	case !fs.PathExists(sourcePath) && !isLink:
+		fmt.Printf("  ✗ Missing composed file: %s\n", mf.SourcePath)
+	case isLink:
+		fmt.Printf("  ✗ Symlink instead of composed file: %s\n", mf.SourcePath)
+	default:
+		intact, err := core.ComposedIntact(cfg, mf)
+		if err != nil || intact {
+			return 0, 0
+		}
+		fmt.Printf("  ✗ Composed file was edited: %s\n", mf.SourcePath)
+		fmt.Printf("    Move your edits into %s or the repo file, then run 'dotcor init --apply'\n", mf.LocalPath())
+		return 1, 0
+	}
+
+	issues++
+	if fix != nil {
+		applied, _ := fix.apply("compose "+mf.SourcePath+" from "+mf.RepoPath, func() error {
+			return core.DeployComposed(cfg, mf)
+		})
+		if applied {
+			fmt.Printf("  ✓ Composed: %s\n", mf.SourcePath)
+			fixed++
+		}
+	}
+	return issues, fixed
+}
+
+// Problems found by inspectSymlink
+const (
+	linkHealthy     = ""
+	linkSkipped     = "skipped" // Paths couldn't be expanded
+	linkDenied      = "denied"
+	linkNotRendered = "not-rendered"
+	linkCopy        = "copy"     // Copy mode, checked by checkCopy
+	linkHardlink    = "hardlink" // Hardlink mode, checked by checkHardlink
+	linkComposed    = "composed" // Compose mode, checked by checkComposed
+	linkMissing     = "missing"
+	linkNotSymlink  = "not-symlink"
+	linkBroken      = "broken"
+)
+
+// symlinkCheck is what inspectSymlink found for one managed file
+type symlinkCheck struct {
+	mf         config.ManagedFile
+	sourcePath string
+	repoPath   string // What the source should point to
+	deniedPath string // Path that couldn't be checked, for linkDenied
+	problem    string
+}
+
+// inspectSymlink checks one managed file without changing anything, so
+// files can be inspected in parallel and reported in order
+func inspectSymlink(cfg *config.Config, mf config.ManagedFile) symlinkCheck {
+	check := symlinkCheck{mf: mf, problem: linkSkipped}
+
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return check
+	}
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return check
+	}
+	check.sourcePath, check.repoPath = sourcePath, repoPath
+
+	// Permission problems look like missing files; report them instead
+	// of recreating links we can't see. Fixes are only suggested.
+	if deniedPath, ok := permissionDenied(sourcePath, repoPath); ok {
+		check.deniedPath, check.problem = deniedPath, linkDenied
+		return check
+	}
+
+	// Templates are linked to their rendered output, which must exist
+	if mf.IsTemplate() {
+		if fs.FileExists(repoPath) && needsRender(cfg, mf, sourcePath) {
+			check.problem = linkNotRendered
+			return check
+		}
+		if target, err := config.GetLinkTargetPath(cfg, mf); err == nil {
+			check.repoPath = target
+		}
+	}
+
+	// Secrets are linked to their decrypted copy
+	if mf.Encrypted {
+		if target, err := config.GetLinkTargetPath(cfg, mf); err == nil {
+			check.repoPath = target
+		}
+	}
+
+	switch {
+	case mf.IsCopy():
+		check.problem = linkCopy
+	case mf.IsHardlink():
+		check.problem = linkHardlink
+	case mf.IsComposed():
+		check.problem = linkComposed
+	case !fs.PathExists(sourcePath):
+		check.problem = linkMissing
+	default:
+		if isLink, _ := fs.IsSymlink(sourcePath); !isLink {
+			check.problem = linkNotSymlink
+		} else if valid, _ := fs.IsValidSymlink(sourcePath); !valid {
+			check.problem = linkBroken
+		} else {
+			check.problem = linkHealthy
+		}
+	}
+	return check
+}
+
+// checkSymlinks validates all managed symlinks. Files are inspected in
+// parallel; problems are reported and fixed in config order.
+func checkSymlinks(fix *repairer) (issues, fixed int) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return
@@ -245,49 +754,89 @@ func checkSymlinks(fix bool) (issues, fixed int) {
 		return
 	}
 
-	for _, mf := range files {
-		sourcePath, err := config.ExpandPath(mf.SourcePath)
-		if err != nil {
-			continue
-		}
+	checks := parallelMap(files, func(mf config.ManagedFile) symlinkCheck {
+		return inspectSymlink(cfg, mf)
+	})
 
-		repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
-		if err != nil {
-			continue
-		}
+	for _, check := range checks {
+		mf, sourcePath, repoPath := check.mf, check.sourcePath, check.repoPath
+
+		switch check.problem {
+		case linkDenied:
+			fmt.Printf("  ✗ Permission denied: %s\n", mf.SourcePath)
+			fmt.Printf("    %s\n", fs.PermissionHint(check.deniedPath))
+			issues++
 
-		// Check if source exists
-		if !fs.PathExists(sourcePath) {
+		case linkNotRendered:
+			fmt.Printf("  ✗ Template not rendered: %s\n", mf.SourcePath)
+			issues++
+
+			if fix != nil {
+				applied, _ := fix.apply("render and link "+mf.SourcePath, func() error {
+					data, err := template.NewData(cfg)
+					if err != nil {
+						return err
+					}
+					_, err = renderAndLink(cfg, mf, data, false)
+					return err
+				})
+				if applied {
+					fmt.Printf("  ✓ Rendered template: %s\n", mf.SourcePath)
+					fixed++
+				}
+			}
+
+		case linkCopy:
+			// Copies are checked against the repo instead of as symlinks
+			copyIssues, copyFixed := checkCopy(cfg, mf, sourcePath, repoPath, fix)
+			issues += copyIssues
+			fixed += copyFixed
+
+		case linkHardlink:
+			// Hard links are checked for inode identity with the repo file
+			linkIssues, linkFixed := checkHardlink(cfg, mf, sourcePath, repoPath, fix)
+			issues += linkIssues
+			fixed += linkFixed
+
+		case linkComposed:
+			// Composed files are compared with what dotcor generates
+			composeIssues, composeFixed := checkComposed(cfg, mf, sourcePath, fix)
+			issues += composeIssues
+			fixed += composeFixed
+
+		case linkMissing:
 			fmt.Printf("  ✗ Missing symlink: %s\n", mf.SourcePath)
 			issues++
 
-			if fix && fs.FileExists(repoPath) {
-				if err := fs.CreateSymlink(repoPath, sourcePath); err == nil {
+			if fix != nil && fs.FileExists(repoPath) {
+				applied, _ := fix.apply("create symlink "+mf.SourcePath+" -> "+repoPath, func() error {
+					return fs.CreateSymlinkWithStyle(repoPath, sourcePath, cfg.LinkStyle)
+				})
+				if applied {
 					fmt.Printf("  ✓ Recreated symlink: %s\n", mf.SourcePath)
 					fixed++
 				}
 			}
-			continue
-		}
 
-		// Check if it's a symlink
-		isLink, _ := fs.IsSymlink(sourcePath)
-		if !isLink {
+		case linkNotSymlink:
 			fmt.Printf("  ✗ Not a symlink: %s (regular file)\n", mf.SourcePath)
 			issues++
-			continue
-		}
 
-		// Check if symlink is valid
-		valid, _ := fs.IsValidSymlink(sourcePath)
-		if !valid {
+			if fix != nil && fs.FileExists(repoPath) && resolveNotSymlink(cfg, mf, sourcePath, repoPath, fix) {
+				fixed++
+			}
+
+		case linkBroken:
 			fmt.Printf("  ✗ Broken symlink: %s\n", mf.SourcePath)
 			issues++
 
-			if fix && fs.FileExists(repoPath) {
+			if fix != nil && fs.FileExists(repoPath) {
 				// Remove broken symlink and recreate
-				os.Remove(sourcePath)
-				if err := fs.CreateSymlink(repoPath, sourcePath); err == nil {
+				applied, _ := fix.apply("replace broken symlink "+mf.SourcePath+" -> "+repoPath, func() error {
+					os.Remove(sourcePath)
+					return fs.CreateSymlinkWithStyle(repoPath, sourcePath, cfg.LinkStyle)
+				})
+				if applied {
 					fmt.Printf("  ✓ Fixed symlink: %s\n", mf.SourcePath)
 					fixed++
 				}
@@ -302,23 +851,350 @@ func checkSymlinks(fix bool) (issues, fixed int) {
 	return
 }
 
+// resolveNotSymlink fixes a managed path that was replaced by a regular
+// file. A file matching the repo is simply linked again; otherwise the
+// differences are shown and the user picks whether to adopt the local
+// content into the repo or restore the symlink. Either way the local file
+// is backed up first. It reports whether the path was fixed.
+func resolveNotSymlink(cfg *config.Config, mf config.ManagedFile, sourcePath, repoPath string, fix *repairer) bool {
+	choice := "restore"
+	if same, _ := fs.SameContent(sourcePath, repoPath); !same {
+		if fix.plan != nil {
+			fix.apply("ask whether to adopt "+mf.SourcePath+" into the repo or restore its symlink", nil)
+			return false
+		}
+		showLocalDiff(repoPath, sourcePath)
+
+		// Generated files can't be copied back over their source
+		canAdopt := !mf.IsTemplate() && !mf.Encrypted
+		var err error
+		if choice, err = askNotSymlinkFix(canAdopt); err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return false
+		}
+		if choice == "" {
+			fmt.Printf("  - Left %s as it is\n", mf.SourcePath)
+			return false
+		}
+	}
+
+	desc := "restore symlink " + mf.SourcePath + " -> " + repoPath
+	if choice == "adopt" {
+		desc = "adopt " + mf.SourcePath + " into the repo and link it"
+	}
+	applied, err := fix.apply(desc, func() error {
+		return relinkRegularFile(cfg, mf, sourcePath, choice == "adopt")
+	})
+	if err != nil {
+		fmt.Printf("  ✗ Fixing %s failed: %v\n", mf.SourcePath, err)
+		return false
+	}
+	if !applied {
+		return false
+	}
+
+	if choice == "adopt" {
+		if err := core.RecordChecksums(cfg, mf.RepoPath); err != nil {
+			fmt.Printf("  ⚠ Recording checksums failed: %v\n", err)
+		}
+		fmt.Printf("  ✓ Adopted %s into the repo (commit it with 'dotcor sync')\n", mf.SourcePath)
+	} else {
+		fmt.Printf("  ✓ Restored symlink: %s\n", mf.SourcePath)
+	}
+	return true
+}
+
+// showLocalDiff prints how the local file differs from the repo copy
+func showLocalDiff(repoPath, sourcePath string) {
+	if !git.IsAvailable() {
+		return
+	}
+	diff, err := git.DiffFiles(repoPath, sourcePath)
+	if err != nil || diff == "" {
+		return
+	}
+	diff = colorize(diff)
+	fmt.Println("    Changes in the local file compared to the repo:")
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+}
+
+// askNotSymlinkFix asks how to fix a file that replaced its symlink,
+// returning "adopt", "restore" or "" to leave it. Non-interactive runs
+// leave it, since either fix discards one side's changes.
+func askNotSymlinkFix(canAdopt bool) (string, error) {
+	prompt := "  [r]estore the symlink, or [s]kip? [s]: "
+	if canAdopt {
+		prompt = "  [a]dopt local content into the repo, [r]estore the symlink, or [s]kip? [s]: "
+	}
+	input, err := ask(prompt, "s")
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(input) {
+	case "a", "adopt":
+		if canAdopt {
+			return "adopt", nil
+		}
+	case "r", "restore":
+		return "restore", nil
+	}
+	return "", nil
+}
+
+// relinkRegularFile replaces the regular file at sourcePath with mf's
+// deployment in one transaction, first copying it over the repo file if
+// adopt is set. The local file and the replaced repo file are backed up.
+func relinkRegularFile(cfg *config.Config, mf config.ManagedFile, sourcePath string, adopt bool) error {
+	resolved, err := config.ResolveVariant(mf)
+	if err != nil {
+		return err
+	}
+	repoFile, err := config.GetRepoFilePath(cfg, resolved.RepoPath)
+	if err != nil {
+		return err
+	}
+
+	tx := core.NewTransaction()
+	if adopt {
+		backup := &core.BackupFileOp{Path: repoFile, File: &resolved}
+		if err := tx.Execute(backup); err != nil {
+			return err
+		}
+		err := tx.Execute(&core.StepOp{
+			Desc:     fmt.Sprintf("copy %s to %s", sourcePath, repoFile),
+			DoFunc:   func() error { return fs.CopyFile(sourcePath, repoFile) },
+			UndoFunc: func() error { return core.RestoreBackup(backup.BackupPath, repoFile) },
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := template.NewData(cfg)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("collecting template variables: %w", err)
+	}
+	var backend crypto.Backend
+	if _, _, err := applyFile(tx, cfg, mf, data, &backend); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// needsRender reports whether a template's output is missing or its
+// symlink still points at the raw template
+func needsRender(cfg *config.Config, mf config.ManagedFile, sourcePath string) bool {
+	target, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return false
+	}
+	return !fs.FileExists(target) || linksToTemplateSource(cfg, mf, sourcePath)
+}
+
+// permissionDenied returns the first of paths that can't be checked
+// because of missing permissions
+func permissionDenied(paths ...string) (string, bool) {
+	for _, path := range paths {
+		if _, err := fs.CheckExists(path); fs.IsPermissionError(err) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// checkPermissions checks that repo files and deployed copies still have
+// the permissions recorded when they were added
+func checkPermissions(fix *repairer) (issues, fixed int) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		mismatches, err := core.CheckPermissions(cfg, mf)
+		if err != nil {
+			fmt.Printf("  ✗ %s: %v\n", mf.SourcePath, err)
+			issues++
+			continue
+		}
+		for _, m := range mismatches {
+			fmt.Printf("  ✗ %s\n", m)
+			issues++
+		}
+		if len(mismatches) == 0 || fix == nil {
+			continue
+		}
+
+		applied, err := fix.apply(fmt.Sprintf("restore permissions of %s", mf.SourcePath), func() error {
+			_, err := core.RestorePermissions(cfg, mf)
+			return err
+		})
+		if applied {
+			fmt.Printf("  ✓ Restored permissions of %s\n", mf.SourcePath)
+			fixed += len(mismatches)
+		} else if err != nil {
+			fmt.Printf("  ✗ Could not restore: %v\n", err)
+		}
+	}
+
+	if issues == 0 {
+		fmt.Println("  ✓ Permissions match")
+	}
+	return
+}
+
+// checkSystemFiles validates deployed system files. They are never repaired
+// automatically because fixing them requires elevated privileges.
+func checkSystemFiles(cfg *config.Config) (issues int) {
+	files := cfg.GetSystemFilesForPlatform()
+	for _, status := range checkFilesStatus(cfg, files) {
+		if status.Status != "ok" {
+			fmt.Printf("  ✗ %s: %s\n", status.SourcePath, status.Problem)
+			issues++
+		}
+	}
+
+	if issues == 0 {
+		fmt.Printf("  ✓ All %d system files healthy\n", len(files))
+	} else {
+		fmt.Println("    Run 'dotcor system apply' to deploy system files")
+	}
+
+	return issues
+}
+
+// usesLFS reports whether Git LFS is enabled in the config or already
+// stores files in the repository
+func usesLFS() bool {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false
+	}
+	repoPath, err := config.GetFilesRoot(cfg)
+	return cfg.LFS.Enabled || (err == nil && git.UsesLFS(repoPath))
+}
+
+// checkLFS checks that git-lfs is set up, that LFS file contents are
+// downloaded and, with lfs enabled, that large binary files are stored with it
+func checkLFS(fix *repairer) (issues, fixed int) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil || !git.IsRepo(repoPath) {
+		return
+	}
+
+	if !git.IsLFSInstalled() {
+		fmt.Println("  ✗ git-lfs is not installed")
+		fmt.Println("    Install it from https://git-lfs.com, then run 'dotcor doctor --fix'")
+		issues++
+		return
+	}
+
+	if !git.LFSHooksInstalled(repoPath) {
+		fmt.Println("  ✗ Git LFS is not set up in the repository")
+		issues++
+		if fix == nil {
+			fmt.Println("    Run 'dotcor doctor --fix' to set it up")
+			return
+		}
+
+		applied, err := fix.apply("run git lfs install in "+repoPath, func() error {
+			return git.InstallLFS(repoPath)
+		})
+		if !applied {
+			if err != nil {
+				fmt.Printf("  ✗ Could not set up Git LFS: %v\n", err)
+			}
+			return
+		}
+		fmt.Println("  ✓ Set up Git LFS")
+		fixed++
+	}
+
+	// Without the content, links point at small pointer files
+	missing, err := git.LFSMissingFiles(repoPath)
+	if err != nil {
+		fmt.Printf("  ⚠ Could not list LFS files: %v\n", err)
+	} else if len(missing) > 0 {
+		fmt.Printf("  ✗ %d LFS file(s) not downloaded:\n", len(missing))
+		for _, path := range missing {
+			fmt.Printf("    - %s\n", path)
+		}
+		issues++
+
+		if fix != nil {
+			applied, err := fix.apply("download LFS files with git lfs pull", func() error {
+				return git.PullLFS(repoPath)
+			})
+			if applied {
+				fmt.Println("  ✓ Downloaded LFS files")
+				fixed++
+			} else if err != nil {
+				fmt.Printf("  ✗ Could not download LFS files: %v\n", err)
+			}
+		}
+	}
+
+	var untracked []string
+	if cfg.LFS.Enabled {
+		for _, mf := range cfg.ManagedFiles {
+			needs, err := core.NeedsLFS(cfg.LFS, filepath.Join(repoPath, mf.RepoPath))
+			if err == nil && needs && !git.IsLFSTracked(repoPath, mf.RepoPath) {
+				untracked = append(untracked, mf.RepoPath)
+			}
+		}
+	}
+	if len(untracked) > 0 {
+		fmt.Printf("  ⚠ %d large binary file(s) not stored with Git LFS:\n", len(untracked))
+		for _, path := range untracked {
+			fmt.Printf("    - %s\n", path)
+		}
+		issues++
+
+		if fix != nil {
+			applied, err := fix.apply(fmt.Sprintf("track %d file(s) with Git LFS and commit", len(untracked)), func() error {
+				if err := git.TrackLFS(repoPath, untracked...); err != nil {
+					return err
+				}
+				return git.AutoCommit(repoPath, "Store large files with Git LFS", append(untracked, ".gitattributes")...)
+			})
+			if applied {
+				fmt.Println("  ✓ Stored files with Git LFS")
+				fixed++
+			} else if err != nil {
+				fmt.Printf("  ✗ Could not track files: %v\n", err)
+			}
+		}
+	}
+
+	if issues == 0 {
+		fmt.Println("  ✓ Git LFS healthy")
+	}
+	return
+}
+
 // checkOrphanedFiles finds files in repo not tracked in config
-func checkOrphanedFiles(fix bool) (issues, fixed int) {
+func checkOrphanedFiles(fix *repairer) (issues, fixed int) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return
 	}
 
-	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	repoPath, err := config.GetFilesRoot(cfg)
 	if err != nil {
 		return
 	}
 
 	// Build set of tracked repo paths
-	tracked := make(map[string]bool)
-	for _, mf := range cfg.ManagedFiles {
-		tracked[mf.RepoPath] = true
-	}
+	tracked := cfg.TrackedRepoPaths()
 
 	// Walk repo directory and find orphans
 	orphans := findOrphanedFiles(repoPath, tracked)
@@ -351,7 +1227,11 @@ func findOrphanedFiles(repoPath string, tracked map[string]bool) []string {
 	}
 
 	for _, entry := range entries {
-		if entry.Name() == ".git" || entry.Name() == "config.yaml" {
+		if entry.Name() == ".git" || entry.Name() == "config.yaml" || entry.Name() == ".gitattributes" {
+			continue
+		}
+		// Captured macOS preferences and the like aren't managed files
+		if config.IsDataRepoPath(entry.Name()) {
 			continue
 		}
 
@@ -382,6 +1262,9 @@ func findOrphanedFilesRecursive(basePath, relDir string, tracked map[string]bool
 
 	for _, entry := range entries {
 		relPath := relDir + "/" + entry.Name()
+		if config.IsDataRepoPath(relPath) {
+			continue
+		}
 
 		if entry.IsDir() {
 			subOrphans := findOrphanedFilesRecursive(basePath, relPath, tracked)
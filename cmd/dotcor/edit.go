@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit [file]",
+	Short: "Edit a managed file in the repository",
+	Long: `Open the repository copy of a managed file in your editor ($VISUAL or
+$EDITOR), then show what changed and offer to commit it.
+
+The file is looked up by its source path, so there's no need to remember
+where it lives in the repository. Templates are edited as templates and
+re-rendered afterwards; copies and hard links are brought up to date.
+Use 'dotcor secret edit' for encrypted files.
+
+Examples:
+  dotcor edit ~/.zshrc                    # Edit, review the diff, commit
+  dotcor edit ~/.gitconfig -m "Add alias" # Commit with this message
+  dotcor edit ~/.tmux.conf --no-commit    # Leave the change uncommitted`,
+	Args: cobra.ExactArgs(1),
+	RunE: runEdit,
+}
+
+func init() {
+	editCmd.Flags().StringP("message", "m", "", "Commit message (skips the prompt)")
+	editCmd.Flags().Bool("no-commit", false, "Don't offer to commit the change")
+	rootCmd.AddCommand(editCmd)
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	message, _ := cmd.Flags().GetString("message")
+	noCommit, _ := cmd.Flags().GetBool("no-commit")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	managed, err := cfg.GetManagedFile(args[0])
+	if err != nil {
+		return fmt.Errorf("file not managed: %s", args[0])
+	}
+	if managed.Encrypted {
+		return fmt.Errorf("%s is encrypted, use 'dotcor secret edit'", managed.SourcePath)
+	}
+
+	// Edit the variant this machine uses
+	mf, err := config.ResolveVariant(*managed)
+	if err != nil {
+		return err
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	repoFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+	original, err := os.ReadFile(repoFile)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", mf.RepoPath, err)
+	}
+
+	// A local copy or broken hard link with its own edits must not be
+	// overwritten afterwards
+	inSync := true
+	switch {
+	case mf.IsCopy():
+		edited, err := core.CopyDiffers(cfg, mf)
+		if err != nil {
+			return err
+		}
+		inSync = !edited
+	case mf.IsHardlink():
+		inSync, _ = core.HardlinkIntact(cfg, mf)
+	}
+
+	if err := runEditor(repoFile); err != nil {
+		return err
+	}
+
+	edited, err := os.ReadFile(repoFile)
+	if err != nil {
+		return fmt.Errorf("reading edited file: %w", err)
+	}
+	if bytes.Equal(original, edited) {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	if err := redeployEdited(cfg, mf, inSync); err != nil {
+		fmt.Printf("⚠ %v\n", err)
+	}
+	recordChecksums(cfg, mf.RepoPath)
+
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+	if git.IsGitInstalled() && git.IsRepo(filesRoot) {
+		if diff, err := getDiff(filesRoot, mf.RepoPath, false); err == nil && diff != "" {
+			fmt.Println()
+			fmt.Print(colorize(diff))
+			fmt.Println()
+		}
+	}
+
+	if noCommit || !git.IsAvailable() {
+		fmt.Printf("✓ Edited %s (not committed)\n", mf.SourcePath)
+		return nil
+	}
+
+	if message == "" {
+		defaultMessage := fmt.Sprintf("Update %s", filepath.Base(mf.RepoPath))
+		commit, err := confirm("Commit this change?", true)
+		if err != nil {
+			return err
+		}
+		if !commit {
+			fmt.Println("Left uncommitted. Run 'dotcor sync' to commit it later.")
+			return nil
+		}
+
+		if message, err = ask(fmt.Sprintf("Commit message [%s]: ", defaultMessage), defaultMessage); err != nil {
+			return err
+		}
+	}
+
+	if err := git.AutoCommit(filesRoot, message, filepath.ToSlash(mf.RepoPath)); err != nil {
+		return fmt.Errorf("committing: %w", err)
+	}
+	fmt.Println("✓ Committed to Git")
+	return nil
+}
+
+// redeployEdited brings what's deployed for mf up to date with its edited
+// repo file. Symlinked files need nothing; a copy or hard link is only
+// replaced if it matched the repo before the edit (inSync).
+func redeployEdited(cfg *config.Config, mf config.ManagedFile, inSync bool) error {
+	if mf.IsTemplate() {
+		data, err := template.NewData(cfg)
+		if err != nil {
+			return fmt.Errorf("collecting template variables: %w", err)
+		}
+		if _, _, err := template.RenderManagedFile(cfg, mf, data); err != nil {
+			return fmt.Errorf("rendering %s: %w", mf.RepoPath, err)
+		}
+		fmt.Printf("✓ Rendered %s\n", mf.RepoPath)
+	}
+
+	if !mf.IsCopy() && !mf.IsHardlink() {
+		return nil
+	}
+	if !inSync {
+		return fmt.Errorf("%s has local edits and was not updated, run 'dotcor doctor' to reconcile", mf.SourcePath)
+	}
+
+	switch {
+	case mf.IsCopy():
+		if err := core.DeployCopy(cfg, mf); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Updated copy at %s\n", mf.SourcePath)
+
+	case mf.IsHardlink():
+		// Editors that save by replacing the file break the link
+		if intact, _ := core.HardlinkIntact(cfg, mf); !intact {
+			if err := core.DeployHardlink(cfg, mf); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Relinked %s\n", mf.SourcePath)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export your dotfiles as an archive or a plain directory tree",
+	Long: `Export your dotfiles as a self-contained archive, for air-gapped machines
+or any machine that can't reach your git remote, or as the plain files a
+fresh apply would deploy.
+
+Set the machine up from the archive with 'dotcor import tarball'.
+
+Examples:
+  dotcor export tarball                        # Write dotcor-export-<date>.tar.gz
+  dotcor export tarball ~/usb/dotfiles.tar.gz  # Write to a given path
+  dotcor export tarball --script               # Include apply.sh for machines without dotcor
+  dotcor export tree ./image/home              # Write the deployed files, e.g. for a Docker image`,
+}
+
+var exportTarballCmd = &cobra.Command{
+	Use:   "tarball [path]",
+	Short: "Write the repository and config to a .tar.gz archive",
+	Long: `Write the repository, with its git history, and config.yaml to a gzipped
+tar archive that 'dotcor import tarball' sets up on another machine without
+any git remote. The files are taken as they are in the repository, committed
+or not. With --repo, only the named repository is exported.
+
+With --script the archive also holds apply.sh, a POSIX shell script that
+deploys the files on a machine without dotcor. Templates, secrets and system
+files need dotcor and are skipped by the script.
+
+Pass - as the path to write the archive to stdout.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runExportTarball,
+}
+
+var exportTreeCmd = &cobra.Command{
+	Use:   "tree <dir>",
+	Short: "Write the files an apply would deploy into a directory",
+	Long: `Write the files a fresh 'dotcor init --apply' would deploy on this machine
+into dir, as real files at their paths relative to the home directory:
+~/.zshrc becomes <dir>/.zshrc. Templates are rendered, per-host variants
+resolved and recorded permissions set, without touching $HOME.
+
+Use it to build a container image's home directory or to inspect what an
+apply would produce. dir must be empty or not exist yet. Secrets are left
+out unless --secrets is given, and system files are always left out.
+
+Examples:
+  dotcor export tree ./image/home
+  dotcor export tree /tmp/preview --secrets`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: readOnly,
+	RunE:        runExportTree,
+}
+
+func init() {
+	exportTarballCmd.Flags().Bool("script", false, "Include apply.sh, which deploys the files without dotcor")
+	exportTreeCmd.Flags().Bool("secrets", false, "Decrypt secrets into the tree")
+	exportCmd.AddCommand(exportTarballCmd, exportTreeCmd)
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExportTarball(cmd *cobra.Command, args []string) error {
+	script, _ := cmd.Flags().GetBool("script")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	opts := core.ExportOptions{Script: script}
+	outPath := fmt.Sprintf("dotcor-export-%s.tar.gz", time.Now().Format("2006-01-02"))
+	if len(args) > 0 {
+		outPath = args[0]
+	}
+	if outPath == "-" {
+		return core.ExportArchive(cfg, os.Stdout, opts)
+	}
+
+	if outPath, err = config.ExpandPath(outPath); err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	if fs.PathExists(outPath) {
+		ok, err := confirm(fmt.Sprintf("%s already exists. Overwrite?", outPath), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	// Written next to its destination and renamed, so a failed export
+	// doesn't leave half an archive behind
+	f, err := os.CreateTemp(filepath.Dir(outPath), ".dotcor-export-*")
+	if err != nil {
+		return fmt.Errorf("creating archive: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	spinner := startSpinner("Exporting")
+	err = core.ExportArchive(cfg, f, opts)
+	spinner.Stop()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return fmt.Errorf("creating archive: %w", err)
+	}
+	if err := os.Rename(f.Name(), outPath); err != nil {
+		return fmt.Errorf("creating archive: %w", err)
+	}
+
+	files := len(cfg.ManagedFiles) + len(cfg.SystemFiles)
+	fmt.Printf("✓ Exported %d file(s) to %s\n", files, outPath)
+	fmt.Println("")
+	fmt.Println("On the other machine:")
+	fmt.Printf("  dotcor import tarball %s\n", filepath.Base(outPath))
+	if script {
+		fmt.Printf("  # or, without dotcor: tar xzf %s && sh %s/%s\n", filepath.Base(outPath), core.ExportDir, core.ExportScriptFile)
+	}
+	return nil
+}
+
+func runExportTree(cmd *cobra.Command, args []string) error {
+	secrets, _ := cmd.Flags().GetBool("secrets")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	dir, err := config.ExpandPath(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	files, err := core.ExportTree(cfg, dir, core.TreeOptions{Secrets: secrets})
+	written := 0
+	for _, f := range files {
+		if f.Skipped != "" {
+			fmt.Printf("  - %s (skipped: %s)\n", f.SourcePath, f.Skipped)
+			continue
+		}
+		fmt.Printf("  ✓ %s\n", f.Path)
+		written++
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("")
+	fmt.Printf("Wrote %d file(s) to %s\n", written, dir)
+	if system := len(cfg.GetSystemFilesForPlatform()); system > 0 {
+		fmt.Printf("  %d system file(s) left out\n", system)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Fleet host states, from best to worst
+const (
+	fleetOK             = "ok"              // Healthy, nothing to commit, push or pull
+	fleetOutOfSync      = "out-of-sync"     // Uncommitted changes or commits to push or pull
+	fleetProblems       = "problems"        // Some managed files aren't ok
+	fleetConflict       = "conflict"        // A merge or rebase needs resolving
+	fleetNotInitialized = "not-initialized" // dotcor was never set up there
+	fleetUnreachable    = "unreachable"     // SSH or dotcor couldn't be run
+)
+
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Check dotcor on your other machines over SSH",
+	Long: `Check dotcor on several machines at once over SSH.
+
+Examples:
+  dotcor fleet status laptop server nas   # One table for every machine`,
+}
+
+var fleetStatusCmd = &cobra.Command{
+	Use:   "status <host>...",
+	Short: "Show which machines are out of sync",
+	Long: `Run 'dotcor status --json' on each host over SSH and show the results in
+one table, so you can see at a glance which machines have broken links,
+uncommitted changes or commits to push or pull.
+
+Hosts are anything ssh accepts, such as user@host or a Host from
+~/.ssh/config. They are contacted in parallel with BatchMode, so logging in
+must work without a password prompt (keys or an agent). Ahead and behind
+counts are as of each machine's last fetch.
+
+Non-interactive SSH sessions often have a shorter PATH than a login shell;
+if dotcor isn't found on a host, give its path with --dotcor.
+
+Exit codes:
+  0  Every host is healthy and in sync
+  1  The command could not run
+  2  A host has problems, is out of sync or couldn't be checked
+
+Examples:
+  dotcor fleet status laptop server
+  dotcor fleet status pi@nas --dotcor ~/go/bin/dotcor
+  dotcor fleet status laptop server -o json`,
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: annotations(structuredOutput, readOnly),
+	RunE:        runFleetStatus,
+}
+
+func init() {
+	fleetStatusCmd.Flags().String("dotcor", "dotcor", "Command that runs dotcor on the hosts")
+	fleetStatusCmd.Flags().Duration("timeout", 30*time.Second, "Give up on a host after this long")
+	fleetCmd.AddCommand(fleetStatusCmd)
+	rootCmd.AddCommand(fleetCmd)
+}
+
+// fleetHost is the status of one machine
+type fleetHost struct {
+	Host        string `json:"host" yaml:"host"`
+	State       string `json:"state" yaml:"state"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+	Files       int    `json:"files" yaml:"files"`
+	Problems    int    `json:"problems" yaml:"problems"`
+	Branch      string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Uncommitted int    `json:"uncommitted" yaml:"uncommitted"`
+	Ahead       int    `json:"ahead" yaml:"ahead"`
+	Behind      int    `json:"behind" yaml:"behind"`
+}
+
+func runFleetStatus(cmd *cobra.Command, args []string) error {
+	dotcor, _ := cmd.Flags().GetString("dotcor")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return fmt.Errorf("ssh is not installed")
+	}
+
+	spinner := startSpinner(fmt.Sprintf("Checking %d host(s)", len(args)))
+	hosts := parallelMap(args, func(host string) fleetHost {
+		return fleetHostStatus(cmd.Context(), host, dotcor, timeout)
+	})
+	spinner.Stop()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tFILES\tISSUES\tCHANGES\tAHEAD\tBEHIND\tSTATE")
+	bad := 0
+	for _, h := range hosts {
+		if h.State != fleetOK {
+			bad++
+		}
+		if h.Error != "" {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\t%s\n", h.Host, fleetStateText(h.State))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", h.Host, h.Files, h.Problems, h.Uncommitted, h.Ahead, h.Behind, fleetStateText(h.State))
+	}
+	w.Flush()
+
+	// Why hosts couldn't be checked goes below the table
+	listed := false
+	for _, h := range hosts {
+		if h.Error == "" {
+			continue
+		}
+		if !listed {
+			fmt.Println()
+			listed = true
+		}
+		fmt.Printf("%s: %s\n", h.Host, h.Error)
+	}
+
+	if err := writeResult(hosts); err != nil {
+		return err
+	}
+	if bad == 0 {
+		return nil
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &exitCodeError{
+		code: exitProblems,
+		msg:  fmt.Sprintf("%d of %d host(s) need attention", bad, len(hosts)),
+	}
+}
+
+// fleetHostStatus runs 'dotcor status --json' on host and summarizes it
+func fleetHostStatus(ctx context.Context, host, dotcor string, timeout time.Duration) fleetHost {
+	result := fleetHost{Host: host}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The remote command is passed as one string for the remote shell, so
+	// a --dotcor like ~/go/bin/dotcor is expanded there
+	cmd := exec.CommandContext(ctx, "ssh",
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout=10",
+		"--", host, dotcor+" status --json")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	// status exits non-zero when it finds problems but still prints JSON
+	var status statusJSONOutput
+	if jsonErr := json.Unmarshal(stdout.Bytes(), &status); jsonErr == nil && stdout.Len() > 0 {
+		summarizeFleetHost(&result, status)
+		return result
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.State = fleetUnreachable
+		result.Error = fmt.Sprintf("timed out after %s", timeout)
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == exitNotInitialized:
+		result.State = fleetNotInitialized
+		result.Error = "dotcor is not initialized there"
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 127:
+		result.State = fleetUnreachable
+		result.Error = fmt.Sprintf("%s not found, give its path with --dotcor", dotcor)
+	default:
+		result.State = fleetUnreachable
+		result.Error = lastLine(stderr.String())
+		if result.Error == "" && err != nil {
+			result.Error = err.Error()
+		}
+		if result.Error == "" {
+			result.Error = "no status in the output"
+		}
+	}
+	return result
+}
+
+// summarizeFleetHost fills in a host's counts and state from its status
+func summarizeFleetHost(h *fleetHost, status statusJSONOutput) {
+	h.Files = status.TotalFiles
+	h.Problems = status.ProblematicFiles
+
+	conflict := false
+	if g := status.Git; g != nil {
+		h.Branch = g.Branch
+		h.Uncommitted = g.Staged + g.Unstaged + g.Untracked
+		if g.RemoteExists {
+			h.Ahead, h.Behind = g.Ahead, g.Behind
+		}
+		conflict = g.Conflicts > 0 || g.Merging || g.Rebasing
+	}
+
+	switch {
+	case conflict:
+		h.State = fleetConflict
+	case h.Problems > 0:
+		h.State = fleetProblems
+	case h.Uncommitted > 0 || h.Ahead > 0 || h.Behind > 0:
+		h.State = fleetOutOfSync
+	default:
+		h.State = fleetOK
+	}
+}
+
+// fleetStateText returns how a state is shown in the table
+func fleetStateText(state string) string {
+	switch state {
+	case fleetOK:
+		return paint("✓ in sync", colorGreen)
+	case fleetOutOfSync:
+		return paint("○ out of sync", colorYellow)
+	case fleetProblems:
+		return paint("⚠ problems", colorYellow)
+	case fleetConflict:
+		return paint("✗ merge to resolve", colorRed)
+	case fleetNotInitialized:
+		return paint("○ not initialized", colorDim)
+	}
+	return paint("✗ unreachable", colorRed)
+}
+
+// lastLine returns the last non-empty line of s, where ssh and the remote
+// shell put the reason they failed
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
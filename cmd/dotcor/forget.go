@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var forgetCmd = &cobra.Command{
+	Use:   "forget <file> --purge-history",
+	Short: "Remove a file and purge it from the repository's history",
+	Long: `Remove a file from management like 'dotcor remove', then rewrite the
+repository's history so no commit has it, for when a secret was committed
+by mistake. git filter-repo is used when installed, git filter-branch
+otherwise. The file itself is copied back to its original location.
+
+Rewriting history changes every commit from the first one with the file,
+so --purge-history is required. Without it forget shows the commits that
+would be rewritten. A file no longer managed can be named by its path in
+the repository, e.g. shell/secrets.sh.
+
+The remote keeps the old commits until you force-push, and other clones
+must be cloned again. Treat a purged secret as leaked and rotate it.
+
+Examples:
+  dotcor forget ~/.netrc                    # Show the commits with it
+  dotcor forget ~/.netrc --purge-history
+  dotcor forget shell/secrets.sh --purge-history`,
+	Args: cobra.ExactArgs(1),
+	RunE: runForget,
+}
+
+func init() {
+	forgetCmd.Flags().Bool("purge-history", false, "Rewrite history to remove the file from every commit")
+	forgetCmd.Flags().BoolP("force", "f", false, "Skip the confirmation prompt")
+	rootCmd.AddCommand(forgetCmd)
+}
+
+func runForget(cmd *cobra.Command, args []string) error {
+	purge, _ := cmd.Flags().GetBool("purge-history")
+	force, _ := cmd.Flags().GetBool("force")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid repo path: %w", err)
+	}
+	if !git.IsGitInstalled() || !git.IsRepo(filesRoot) {
+		return fmt.Errorf("forget needs git and a git repository at %s", filesRoot)
+	}
+
+	// A managed file, or the repo path of one removed already
+	var mf *config.ManagedFile
+	repoPaths := []string{args[0]}
+	if found, err := cfg.GetManagedFile(args[0]); err == nil {
+		mf = found
+		if cfg.SharesRepoPath(*mf) {
+			return fmt.Errorf("%s shares %s with other managed files; forgetting it would purge theirs too", mf.SourcePath, mf.RepoPath)
+		}
+		repoPaths = []string{mf.RepoPath}
+		for _, v := range mf.Variants {
+			repoPaths = append(repoPaths, v.RepoPath)
+		}
+	}
+
+	var commits []git.CommitInfo
+	for _, repoPath := range repoPaths {
+		found, err := git.CommitsTouching(filesRoot, repoPath)
+		if err != nil {
+			return err
+		}
+		commits = append(commits, found...)
+	}
+	if mf == nil && len(commits) == 0 {
+		return fmt.Errorf("%s is not managed and not in the repository's history", args[0])
+	}
+
+	if !purge {
+		fmt.Printf("%s is in %d commit(s).\n", repoPaths[0], len(commits))
+		fmt.Println("Run again with --purge-history to remove it from all of them, rewriting history.")
+		fmt.Println("To only stop managing it, use 'dotcor remove'.")
+		return nil
+	}
+
+	if !force {
+		fmt.Printf("This removes %s from %d commit(s), rewriting every commit since.\n", repoPaths[0], len(commits))
+		fmt.Println("The remote keeps the old history until you force-push.")
+		ok, err := confirm("Continue?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	// The purge needs a clean tree, which is checked before anything changes
+	repoRoot, err := config.ExpandPath(cfg.RepoPath)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+	dirty, err := git.HasChanges(repoRoot)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("the repository has uncommitted changes; commit them with 'dotcor sync' or discard them first")
+	}
+
+	if mf != nil {
+		if err := processRemoveFile(cfg, *mf, false, false); err != nil {
+			return fmt.Errorf("removing %s: %w", mf.SourcePath, err)
+		}
+		// Only the removal is committed; the commit itself is purged as well
+		var removed []string
+		for _, repoPath := range repoPaths {
+			if changed, err := git.HasChanges(filesRoot, repoPath); err == nil && changed {
+				removed = append(removed, repoPath)
+			}
+		}
+		if len(removed) > 0 {
+			if err := git.AutoCommit(filesRoot, "Forget "+mf.RepoPath, removed...); err != nil {
+				return fmt.Errorf("committing the removal: %w", err)
+			}
+		}
+	}
+
+	tool, err := git.PurgeFromHistory(filesRoot, repoPaths...)
+	if err != nil {
+		return fmt.Errorf("purging history: %w", err)
+	}
+	fmt.Printf("✓ Purged %s from the history with %s\n", repoPaths[0], tool)
+
+	if mf != nil {
+		printForgetBackup(cfg, *mf)
+	}
+	if url, err := git.GetRemoteURL(filesRoot); err == nil && url != "" {
+		fmt.Println("  The remote still has the old commits. Replace them with:")
+		fmt.Printf("    git -C %s push --force --all origin && git -C %s push --force --tags origin\n", filesRoot, filesRoot)
+		fmt.Println("  Other clones must be cloned again.")
+	}
+	return nil
+}
+
+// printForgetBackup points at the backup of the repo file made when forget
+// removed it, which holds the content the purge was meant to get rid of
+func printForgetBackup(cfg *config.Config, mf config.ManagedFile) {
+	repoFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return
+	}
+	normalized, err := config.NormalizePath(repoFile)
+	if err != nil {
+		return
+	}
+	if backups, err := core.GetBackupsForSource(normalized); err == nil && len(backups) > 0 {
+		fmt.Printf("  A backup of the repo file is at %s; delete it if it holds a secret.\n", backups[0].BackupPath)
+	}
+}
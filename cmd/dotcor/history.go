@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"text/tabwriter"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/git"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var historyCmd = &cobra.Command{
@@ -17,27 +19,45 @@ var historyCmd = &cobra.Command{
 	Long: `Show the Git commit history for a managed dotfile.
 
 Without a file argument, shows the history for all dotfiles.
-With a file argument, shows history for that specific file.
+With a file argument, shows history for that specific file. Use --follow
+to include commits from before the file was renamed in the repository.
+With --interactive, pick a version from the list to restore the file to.
 
 Examples:
   dotcor history                   # Show all commit history
   dotcor history ~/.zshrc          # Show history for specific file
+  dotcor history ~/.zshrc --follow # Include history from before renames
   dotcor history -n 20             # Show last 20 commits
-  dotcor history --oneline         # Compact format`,
+  dotcor history -n 20 --skip 20   # Show the next 20 commits
+  dotcor history --oneline         # Compact format
+  dotcor history ~/.zshrc -i       # Pick a version to restore`,
 	RunE: runHistory,
 }
 
 func init() {
 	historyCmd.Flags().IntP("number", "n", 10, "Number of commits to show")
+	historyCmd.Flags().Int("skip", 0, "Skip this many of the newest commits")
+	historyCmd.Flags().Bool("follow", false, "Follow the file across renames")
 	historyCmd.Flags().Bool("oneline", false, "Show compact one-line format")
 	historyCmd.Flags().Bool("json", false, "Output as JSON")
+	historyCmd.Flags().BoolP("interactive", "i", false, "Choose a version to restore from the list")
+	// --limit is accepted as a long form of -n
+	historyCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "limit" {
+			name = "number"
+		}
+		return pflag.NormalizedName(name)
+	})
 	rootCmd.AddCommand(historyCmd)
 }
 
 func runHistory(cmd *cobra.Command, args []string) error {
 	limit, _ := cmd.Flags().GetInt("number")
+	skip, _ := cmd.Flags().GetInt("skip")
+	follow, _ := cmd.Flags().GetBool("follow")
 	oneline, _ := cmd.Flags().GetBool("oneline")
 	jsonFormat, _ := cmd.Flags().GetBool("json")
+	interactive, _ := cmd.Flags().GetBool("interactive")
 
 	// Load config
 	cfg, err := config.LoadConfig()
@@ -51,7 +71,7 @@ func runHistory(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get repo path
-	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	repoPath, err := config.GetFilesRoot(cfg)
 	if err != nil {
 		return fmt.Errorf("expanding repo path: %w", err)
 	}
@@ -64,11 +84,12 @@ func runHistory(cmd *cobra.Command, args []string) error {
 	// Determine which file to show history for
 	var filePath string
 	var displayPath string
+	var mf *config.ManagedFile
 
 	if len(args) > 0 {
 		// Specific file
 		sourcePath := args[0]
-		mf, err := cfg.GetManagedFile(sourcePath)
+		mf, err = cfg.GetManagedFile(sourcePath)
 		if err != nil {
 			return fmt.Errorf("file not managed: %s", sourcePath)
 		}
@@ -77,13 +98,29 @@ func runHistory(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get history
-	commits, err := git.GetFileHistory(repoPath, filePath, limit)
+	if follow && filePath == "" {
+		return fmt.Errorf("--follow requires a file")
+	}
+	if skip < 0 {
+		return fmt.Errorf("--skip cannot be negative")
+	}
+	if interactive && (filePath == "" || jsonFormat) {
+		return fmt.Errorf("--interactive requires a file and can't be combined with --json")
+	}
+
+	commits, err := git.GetFileHistoryWithOptions(repoPath, filePath, git.HistoryOptions{
+		Limit:  limit,
+		Skip:   skip,
+		Follow: follow,
+	})
 	if err != nil {
 		return fmt.Errorf("getting history: %w", err)
 	}
 
 	if len(commits) == 0 {
-		if filePath != "" {
+		if skip > 0 {
+			fmt.Println("No more commits.")
+		} else if filePath != "" {
 			fmt.Printf("No commits found for %s\n", displayPath)
 		} else {
 			fmt.Println("No commits found.")
@@ -96,11 +133,26 @@ func runHistory(cmd *cobra.Command, args []string) error {
 		return outputHistoryJSON(commits)
 	}
 
+	if interactive {
+		return chooseAndRestore(cmd, cfg, repoPath, mf, commits, skip)
+	}
+
 	if oneline {
-		return outputHistoryOneline(commits)
+		err = outputHistoryOneline(commits)
+	} else {
+		err = outputHistoryFull(commits, displayPath)
+	}
+	if err != nil {
+		return err
 	}
 
-	return outputHistoryFull(commits, displayPath)
+	// A full page may mean there are older commits
+	if len(commits) == limit {
+		fmt.Println("")
+		fmt.Printf("Use --skip %d to see older commits.\n", skip+limit)
+	}
+
+	return nil
 }
 
 // outputHistoryFull shows detailed commit history
@@ -114,6 +166,9 @@ func outputHistoryFull(commits []git.CommitInfo, filePath string) error {
 		fmt.Printf("commit %s\n", c.Hash)
 		fmt.Printf("Author: %s\n", c.Author)
 		fmt.Printf("Date:   %s\n", c.Date.Format("Mon Jan 2 15:04:05 2006 -0700"))
+		if c.Path != "" {
+			fmt.Printf("Path:   %s\n", c.Path)
+		}
 		fmt.Println("")
 		fmt.Printf("    %s\n", c.Message)
 
@@ -130,28 +185,80 @@ func outputHistoryOneline(commits []git.CommitInfo) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
 	for _, c := range commits {
-		shortHash := c.Hash
-		if len(shortHash) > 7 {
-			shortHash = shortHash[:7]
-		}
-
-		fmt.Fprintf(w, "%s\t%s\t%s\n",
-			shortHash,
+		fmt.Fprintf(w, "%s\t%s\t%s",
+			shortHash(c.Hash),
 			c.Date.Format("2006-01-02"),
 			truncateMessage(c.Message, 60),
 		)
+		if c.Path != "" {
+			fmt.Fprintf(w, "\t%s", c.Path)
+		}
+		fmt.Fprintln(w)
 	}
 
 	w.Flush()
 	return nil
 }
 
+// chooseAndRestore lists commits in a numbered table and restores the file
+// to the one picked, through the same path as 'dotcor restore --to'
+func chooseAndRestore(cmd *cobra.Command, cfg *config.Config, repoRoot string, mf *config.ManagedFile, commits []git.CommitInfo, skip int) error {
+	fmt.Printf("History for %s:\n", mf.SourcePath)
+	fmt.Println("")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tHASH\tDATE\tMESSAGE")
+	for i, c := range commits {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n",
+			skip+i+1,
+			shortHash(c.Hash),
+			c.Date.Format("2006-01-02 15:04"),
+			truncateMessage(c.Message, 60),
+		)
+	}
+	w.Flush()
+
+	fmt.Println("")
+	input, err := ask(fmt.Sprintf("Restore which version? [%d-%d, Enter to skip]: ", skip+1, skip+len(commits)), "")
+	if err != nil {
+		return fmt.Errorf("%w\nRestore a version with 'dotcor restore --to <commit>' instead", err)
+	}
+	if input == "" {
+		return nil
+	}
+
+	choice, err := strconv.Atoi(input)
+	if err != nil || choice <= skip || choice > skip+len(commits) {
+		return fmt.Errorf("invalid choice: %s", input)
+	}
+	commit := commits[choice-skip-1]
+
+	fullRepoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return fmt.Errorf("getting repo path: %w", err)
+	}
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+
+	return restoreFromGit(cfg, *mf, repoRoot, fullRepoPath, shortHash(commit.Hash), false, false)
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
 // commitJSONOutput represents a commit in JSON format
 type commitJSONOutput struct {
 	Hash    string `json:"hash"`
 	Author  string `json:"author"`
 	Date    string `json:"date"`
 	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
 }
 
 // outputHistoryJSON outputs history as JSON
@@ -164,6 +271,7 @@ func outputHistoryJSON(commits []git.CommitInfo) error {
 			Author:  c.Author,
 			Date:    c.Date.Format("2006-01-02T15:04:05Z07:00"),
 			Message: c.Message,
+			Path:    c.Path,
 		})
 	}
 
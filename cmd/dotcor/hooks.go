@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/hooks"
+	"github.com/spf13/cobra"
+)
+
+// runHooks runs the configured hooks for event. A failing pre hook returns
+// an error so the operation is aborted; post hook failures are only reported.
+func runHooks(cmd *cobra.Command, cfg *config.Config, event string) error {
+	commands := cfg.Hooks.Get(event)
+	if len(commands) == 0 || hooksDisabled(cmd) {
+		return nil
+	}
+
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := hooks.Context{Event: event, RepoPath: repoPath}
+
+	for _, command := range commands {
+		fmt.Printf("→ Running %s hook: %s\n", event, command)
+		if err := hooks.Run(command, ctx, os.Stdout); err != nil {
+			if strings.HasPrefix(event, "pre_") {
+				return fmt.Errorf("%w\nUse --no-hooks to skip hooks", err)
+			}
+			fmt.Fprintf(os.Stderr, "⚠ %v\n", err)
+		}
+	}
+	return nil
+}
+
+// runFileHooks runs the per-file hooks of each file, reporting failures
+func runFileHooks(cmd *cobra.Command, cfg *config.Config, event string, files []config.ManagedFile) {
+	if hooksDisabled(cmd) {
+		return
+	}
+
+	for _, mf := range files {
+		if len(mf.Hooks) == 0 {
+			continue
+		}
+
+		ctx, err := hooks.FileContext(cfg, event, mf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ %s: %v\n", mf.SourcePath, err)
+			continue
+		}
+
+		for _, command := range mf.Hooks {
+			fmt.Printf("→ Running hook for %s: %s\n", mf.SourcePath, command)
+			if err := hooks.Run(command, ctx, os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠ %s: %v\n", mf.SourcePath, err)
+				break
+			}
+		}
+	}
+}
+
+// hooksDisabled reports whether --no-hooks was given
+func hooksDisabled(cmd *cobra.Command) bool {
+	noHooks, _ := cmd.Flags().GetBool("no-hooks")
+	return noHooks
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Set up dotfiles from an export",
+	Long: `Set up your dotfiles from an archive written by 'dotcor export', on a
+machine that can't reach your git remote.
+
+Examples:
+  dotcor import tarball dotcor-export-2024-05-01.tar.gz             # Unpack and create symlinks
+  dotcor import tarball dotcor-export-2024-05-01.tar.gz --no-apply  # Only unpack`,
+}
+
+var importTarballCmd = &cobra.Command{
+	Use:   "tarball <archive>",
+	Short: "Unpack an exported .tar.gz archive and apply it",
+	Long: `Unpack an archive written by 'dotcor export tarball' to ~/.dotcor/files, load
+its config and create symlinks for the managed files, as 'dotcor clone
+--apply' does, without any git remote. The repository keeps its history, so
+'dotcor sync' works once the remote can be reached.
+
+The archive's pre_apply, post_apply and per-file hooks run arbitrary
+commands, so they are listed and need confirmation before they run;
+declining applies without them.
+
+With --repo the archive becomes the named repository, keeping the settings
+shared with the others.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportTarball,
+}
+
+func init() {
+	importTarballCmd.Flags().BoolP("force", "f", false, "Overwrite existing dotcor directory")
+	importTarballCmd.Flags().Bool("no-apply", false, "Unpack without creating symlinks")
+	importCmd.AddCommand(importTarballCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+func runImportTarball(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	noApply, _ := cmd.Flags().GetBool("no-apply")
+
+	archivePath, err := config.ExpandPath(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer archive.Close()
+
+	if supported, err := fs.SupportsSymlinks(); !supported {
+		return symlinkSupportError(err)
+	}
+
+	// The repository's home, as for 'dotcor clone'
+	dataDir, err := config.GetRepoStateDir()
+	if err != nil {
+		return fmt.Errorf("getting config directory: %w", err)
+	}
+	filesDir := filepath.Join(dataDir, "files")
+
+	if err := checkEnvironment(cmd, filesDir); err != nil {
+		return err
+	}
+
+	exists := fs.PathExists(dataDir)
+	if exists && !force {
+		fmt.Printf("DotCor directory already exists: %s\n", dataDir)
+		ok, err := confirm("Overwrite?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	// Unpacked next to the data directory first, so a bad archive leaves
+	// the existing setup alone
+	if err := fs.EnsureDir(filepath.Dir(dataDir)); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(filepath.Dir(dataDir), ".dotcor-import-")
+	if err != nil {
+		return fmt.Errorf("unpacking archive: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	spinner := startSpinner("Unpacking")
+	extractedFiles, extractedConfig, err := core.ExtractArchive(archive, tempDir)
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("unpacking archive: %w", err)
+	}
+	fmt.Println("✓ Archive unpacked")
+
+	if exists {
+		// Remove existing (to the trash unless the old config says otherwise)
+		fmt.Println("Removing existing DotCor directory...")
+		oldCfg, _ := config.LoadConfig()
+		trashPath, err := core.DeleteUserFile(oldCfg, dataDir)
+		if err != nil {
+			return fmt.Errorf("removing existing directory: %w", err)
+		}
+		if trashPath != "" {
+			fmt.Printf("  → Moved to trash: %s\n", trashPath)
+		}
+	}
+
+	// Acquire lock - may fail if directory is new, which is expected
+	if err := core.AcquireLock(); err == nil {
+		defer core.ReleaseLock()
+	}
+
+	if err := fs.EnsureDir(dataDir); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	backupsDir, err := core.GetBackupDir()
+	if err != nil {
+		return fmt.Errorf("getting backups directory: %w", err)
+	}
+	if err := fs.EnsureDir(backupsDir); err != nil {
+		return fmt.Errorf("creating backups directory: %w", err)
+	}
+	if err := os.Rename(extractedFiles, filesDir); err != nil {
+		return fmt.Errorf("moving repository into place: %w", err)
+	}
+	fmt.Println("✓ Repository imported")
+
+	// A named repository takes only its files from the archive, keeping
+	// the settings shared with the others
+	if config.SelectedRepo() != "" {
+		err = adoptRepoConfig(extractedConfig, filesDir)
+	} else {
+		err = adoptExportedConfig(extractedConfig, filesDir)
+	}
+	if err != nil {
+		return fmt.Errorf("loading config from archive: %w", err)
+	}
+	fmt.Println("✓ Configuration loaded from archive")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	// The imported content is what 'dotcor verify' checks against
+	if err := core.RecordAllChecksums(cfg); err != nil {
+		fmt.Printf("⚠ Could not record checksums: %v\n", err)
+	}
+
+	if !noApply {
+		if err := confirmClonedHooks(cmd, cfg); err != nil {
+			return err
+		}
+		fmt.Println("")
+		fmt.Println("Creating symlinks...")
+		return applySymlinks(cmd, cfg, false)
+	}
+
+	fmt.Println("")
+	fmt.Println("Import complete!")
+	fmt.Println("")
+	fmt.Println("Next steps:")
+	fmt.Println("  dotcor init --apply    # Create symlinks for managed files")
+	fmt.Println("  dotcor status          # Check current state")
+	return nil
+}
+
+// adoptExportedConfig makes the config.yaml at configPath, from an export
+// archive, this machine's config, with its repository at filesDir
+func adoptExportedConfig(configPath, filesDir string) error {
+	cfg, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	cfg.RepoPath = filesDir
+	return cfg.SaveConfig()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+func TestImportTarballAsksBeforeHooks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// An archive from elsewhere whose config runs a command when applied
+	src := t.TempDir()
+	repoFile := filepath.Join(src, "files", "shell", "zshrc")
+	if err := os.MkdirAll(filepath.Dir(repoFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(repoFile, []byte("# zshrc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(home, "hooked")
+	cfg := &config.Config{
+		Version:      config.CurrentConfigVersion,
+		RepoPath:     filepath.Join(src, "files"),
+		ManagedFiles: []config.ManagedFile{{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"}},
+		Hooks:        config.HooksConfig{PostApply: []string{"touch " + marker}},
+	}
+	archive := filepath.Join(src, "dotfiles.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := core.ExportArchive(cfg, f, core.ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	rootCmd.SilenceUsage, rootCmd.SilenceErrors = true, true
+	t.Cleanup(func() {
+		rootCmd.SilenceUsage, rootCmd.SilenceErrors = false, false
+		rootCmd.PersistentFlags().Set("no-hooks", "false")
+	})
+	importTarball := func() error {
+		rootCmd.SetArgs([]string{"import", "tarball", archive, "--force", "--allow-temp-repo"})
+		return rootCmd.ExecuteContext(context.Background())
+	}
+
+	// Closed stdin can't answer, so nothing is applied
+	if err := importTarball(); err == nil {
+		t.Error("import with no answer to the hooks prompt should return error")
+	}
+	if fs.PathExists(marker) {
+		t.Fatal("hook ran without confirmation")
+	}
+
+	// Declining applies without the hooks
+	terminal, reader := stdinIsTerminal, stdinReader
+	t.Cleanup(func() { stdinIsTerminal, stdinReader = terminal, reader })
+	stdinIsTerminal = func() bool { return true }
+	stdinReader = bufio.NewReader(strings.NewReader("n\n"))
+	if err := importTarball(); err != nil {
+		t.Fatalf("import declining hooks error = %v", err)
+	}
+	if fs.PathExists(marker) {
+		t.Error("hook ran after it was declined")
+	}
+	if isLink, _ := fs.IsSymlink(filepath.Join(home, ".zshrc")); !isLink {
+		t.Error("~/.zshrc not linked after declining hooks")
+	}
+}
@@ -1,17 +1,19 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/crypto"
 	"github.com/justincordova/dotcor/internal/fs"
 	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/progress"
+	"github.com/justincordova/dotcor/internal/template"
 	"github.com/spf13/cobra"
 )
 
@@ -41,49 +43,100 @@ var initCmd = &cobra.Command{
 Examples:
   dotcor init                    # Basic initialization
   dotcor init --interactive      # Scan for dotfiles and select which to add
-  dotcor init --apply            # Create symlinks from existing config (new machine)`,
+  dotcor init --apply            # Create symlinks from existing config (new machine)
+  dotcor init --apply --dry-run  # Show what --apply would do
+  dotcor init --hooks            # Block commits that add secrets to the repository
+  dotcor init --worktree ~/code/machines --branch dotfiles
+                                 # Use a worktree of an existing repository
+  dotcor init --repo-path ~/code/personal --files-subdir dotfiles
+                                 # Keep dotfiles in a subdirectory of an existing repository
+  dotcor --repo work init --repo-path ~/code/work-dotfiles
+                                 # Add a second, named repository`,
 	RunE: runInit,
 }
 
 func init() {
 	initCmd.Flags().Bool("apply", false, "Create symlinks from existing config (for new machine setup)")
+	initCmd.Flags().Bool("dry-run", false, "With --apply, show what would be done without making changes")
 	initCmd.Flags().Bool("interactive", false, "Interactively select existing dotfiles to add")
+	initCmd.Flags().String("worktree", "", "Create the files directory as a worktree of an existing repository")
+	initCmd.Flags().String("branch", "dotfiles", "Branch to check out in the worktree (created if missing)")
+	initCmd.Flags().String("repo-path", "", "Keep dotfiles in this Git repository instead of ~/.dotcor/files (created if missing)")
+	initCmd.Flags().String("files-subdir", "", "Directory within the repository that holds dotfiles")
+	initCmd.Flags().Bool("hooks", false, "Install a git pre-commit hook that blocks commits containing secrets")
 	rootCmd.AddCommand(initCmd)
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
 	applyFlag, _ := cmd.Flags().GetBool("apply")
 	interactiveFlag, _ := cmd.Flags().GetBool("interactive")
+	worktreeRepo, _ := cmd.Flags().GetString("worktree")
+	worktreeBranch, _ := cmd.Flags().GetString("branch")
+	existingRepo, _ := cmd.Flags().GetString("repo-path")
+	filesSubdir, _ := cmd.Flags().GetString("files-subdir")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	hooksFlag, _ := cmd.Flags().GetBool("hooks")
+
+	if dryRun {
+		if !applyFlag {
+			return fmt.Errorf("--dry-run requires --apply")
+		}
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+		}
+		return applySymlinks(cmd, cfg, true)
+	}
 
-	// Check symlink support first
-	supported, err := fs.SupportsSymlinks()
-	if err != nil {
-		return fmt.Errorf("checking symlink support: %w", err)
+	if existingRepo != "" && worktreeRepo != "" {
+		return fmt.Errorf("--repo-path and --worktree cannot be used together")
 	}
-	if !supported {
-		fmt.Fprintln(os.Stderr, "✗ Symlinks not supported on this platform.")
-		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(os.Stderr, "Windows users: Enable Developer Mode")
-		fmt.Fprintln(os.Stderr, "  Settings → Update & Security → For developers → Developer Mode")
-		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(os.Stderr, "Then restart your terminal and try again.")
-		return fmt.Errorf("symlinks not supported")
+	if filesSubdir != "" {
+		if err := config.ValidateFilesSubdir(filesSubdir); err != nil {
+			return err
+		}
+	}
+
+	// Check symlink support first
+	if supported, err := fs.SupportsSymlinks(); !supported {
+		return symlinkSupportError(err)
 	}
 
-	// Get config directory
-	configDir, err := config.GetConfigDir()
+	// Get the repository's home: ~/.dotcor (or its XDG data directory), or
+	// ~/.dotcor/repos/<name> for a repository selected with --repo
+	dataDir, err := config.GetRepoStateDir()
 	if err != nil {
 		return fmt.Errorf("getting config directory: %w", err)
 	}
 
 	// Check if already initialized
-	if fs.PathExists(configDir) && !applyFlag {
-		fmt.Printf("DotCor is already initialized at %s\n", configDir)
+	if fs.PathExists(dataDir) && !applyFlag {
+		if hooksFlag {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			if err := core.AcquireLock(); err != nil {
+				return fmt.Errorf("acquiring lock: %w", err)
+			}
+			defer core.ReleaseLock()
+			return enablePreCommitHook(cfg)
+		}
+		fmt.Printf("DotCor is already initialized at %s\n", dataDir)
 		fmt.Println("Use 'dotcor status' to check current state.")
 		fmt.Println("Use 'dotcor init --apply' to create symlinks from existing config.")
 		return nil
 	}
 
+	// Check environment against the repository init will write to
+	guardRepo := filepath.Join(dataDir, "files")
+	if existingRepo != "" {
+		guardRepo = existingRepo
+	}
+	if err := checkEnvironment(cmd, guardRepo); err != nil {
+		return err
+	}
+
 	// Acquire lock
 	if err := core.AcquireLock(); err != nil {
 		return fmt.Errorf("acquiring lock: %w", err)
@@ -91,35 +144,64 @@ func runInit(cmd *cobra.Command, args []string) error {
 	defer core.ReleaseLock()
 
 	// Create directory structure
-	filesDir := filepath.Join(configDir, "files")
-	backupsDir := filepath.Join(configDir, "backups")
+	filesDir := filepath.Join(dataDir, "files")
+	backupsDir, err := core.GetBackupDir()
+	if err != nil {
+		return fmt.Errorf("getting backups directory: %w", err)
+	}
 
 	fmt.Println("Initializing DotCor...")
 
-	if err := fs.EnsureDir(configDir); err != nil {
+	if err := fs.EnsureDir(dataDir); err != nil {
 		return fmt.Errorf("creating config directory: %w", err)
 	}
-	fmt.Printf("✓ Created %s\n", configDir)
-
-	if err := fs.EnsureDir(filesDir); err != nil {
-		return fmt.Errorf("creating files directory: %w", err)
-	}
+	fmt.Printf("✓ Created %s\n", dataDir)
 
 	if err := fs.EnsureDir(backupsDir); err != nil {
 		return fmt.Errorf("creating backups directory: %w", err)
 	}
 
-	// Initialize Git repository
-	if git.IsGitInstalled() {
-		if !git.IsRepo(filesDir) {
-			if err := git.InitRepo(filesDir); err != nil {
-				fmt.Printf("⚠ Git init failed: %v\n", err)
-			} else {
-				fmt.Println("✓ Initialized Git repository")
+	// Initialize Git repository (or use an existing one)
+	if existingRepo != "" {
+		expanded, err := config.ExpandPath(existingRepo)
+		if err != nil {
+			return fmt.Errorf("expanding repository path: %w", err)
+		}
+		filesDir = expanded
+		if !fs.PathExists(expanded) && git.IsAvailable() {
+			// A new repository in a location of the user's choosing
+			if err := fs.EnsureDir(expanded); err != nil {
+				return fmt.Errorf("creating repository directory: %w", err)
+			}
+			if err := git.InitRepo(expanded); err != nil {
+				return fmt.Errorf("initializing repository: %w", err)
 			}
+			fmt.Printf("✓ Initialized Git repository at %s\n", filesDir)
+		} else if !git.IsAvailable() || !git.IsRepo(expanded) {
+			return fmt.Errorf("not a git repository: %s", existingRepo)
+		} else {
+			fmt.Printf("✓ Using existing repository at %s\n", filesDir)
+		}
+	} else if worktreeRepo != "" {
+		if err := initWorktree(worktreeRepo, filesDir, worktreeBranch); err != nil {
+			return err
 		}
 	} else {
-		fmt.Println("⚠ Git not found. Installing Git is recommended for version control.")
+		if err := fs.EnsureDir(filesDir); err != nil {
+			return fmt.Errorf("creating files directory: %w", err)
+		}
+
+		if git.IsAvailable() {
+			if !git.IsRepo(filesDir) {
+				if err := git.InitRepo(filesDir); err != nil {
+					fmt.Printf("⚠ Git init failed: %v\n", err)
+				} else {
+					fmt.Println("✓ Initialized Git repository")
+				}
+			}
+		} else {
+			fmt.Println("⚠ Git not found. Installing Git is recommended for version control.")
+		}
 	}
 
 	// Create or load config
@@ -131,20 +213,48 @@ func runInit(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("loading config: %w", err)
 		}
 	} else {
-		// Create new default config
-		cfg, err = config.NewDefaultConfig()
+		// Create new default config. A named repository shares the
+		// settings already in config.yaml.
+		if config.SelectedRepo() != "" {
+			cfg, err = config.LoadConfig()
+		} else {
+			cfg, err = config.NewDefaultConfig()
+		}
 		if err != nil {
 			return fmt.Errorf("creating default config: %w", err)
 		}
+		cfg.RepoPath = filesDir
+		cfg.FilesSubdir = filesSubdir
+
+		// Files subdirectory is created inside the repository on demand
+		filesRoot, err := config.GetFilesRoot(cfg)
+		if err != nil {
+			return err
+		}
+		if err := fs.EnsureDir(filesRoot); err != nil {
+			return fmt.Errorf("creating files directory: %w", err)
+		}
+
 		if err := cfg.SaveConfig(); err != nil {
 			return fmt.Errorf("saving config: %w", err)
 		}
 		fmt.Println("✓ Created config.yaml")
 	}
 
-	// Handle --apply flag (create symlinks from existing config)
+	// The hook lives in .git, so a new machine needs it installed again
+	if hooksFlag || cfg.Scan.PreCommit {
+		if err := enablePreCommitHook(cfg); err != nil {
+			fmt.Printf("⚠ %v\n", err)
+		}
+	}
+
+	// Handle --apply flag (create symlinks from existing config). Links
+	// may point into submodules, so those are checked out first.
 	if applyFlag {
-		return applySymlinks(cfg)
+		if repoPath, err := config.GetFilesRoot(cfg); err == nil {
+			updateSubmodules(repoPath)
+		}
+		return applySymlinks(cmd, cfg, false)
 	}
 
 	// Handle --interactive flag
@@ -156,6 +266,11 @@ func runInit(cmd *cobra.Command, args []string) error {
 	fmt.Println("DotCor initialized successfully!")
 	fmt.Println("")
 	fmt.Println("Next steps:")
+	if name := config.SelectedRepo(); name != "" {
+		fmt.Printf("  dotcor --repo %s add ~/.zshrc   # Add a dotfile to this repository\n", name)
+		fmt.Printf("  dotcor --repo %s status         # Check its status\n", name)
+		return nil
+	}
 	fmt.Println("  dotcor add ~/.zshrc     # Add a dotfile")
 	fmt.Println("  dotcor list             # List managed files")
 	fmt.Println("  dotcor status           # Check status")
@@ -163,70 +278,340 @@ func runInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// applySymlinks creates symlinks for all managed files in config
-func applySymlinks(cfg *config.Config) error {
+// enablePreCommitHook installs the secret-scanning pre-commit hook and
+// records in the config that it should be kept installed
+func enablePreCommitHook(cfg *config.Config) error {
+	if !git.IsGitInstalled() {
+		return fmt.Errorf("git is required for --hooks")
+	}
+	if err := installPreCommitHook(cfg); err != nil {
+		return fmt.Errorf("installing pre-commit hook: %w", err)
+	}
+	fmt.Println("✓ Installed pre-commit hook to block commits containing secrets")
+
+	if !cfg.Scan.PreCommit {
+		cfg.Scan.PreCommit = true
+		if err := cfg.SaveConfig(); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+	}
+	return nil
+}
+
+// initWorktree creates filesDir as a linked worktree of an existing repository
+func initWorktree(mainRepo, filesDir, branch string) error {
+	if !git.IsGitInstalled() {
+		return fmt.Errorf("git is required for --worktree")
+	}
+
+	expandedMain, err := config.ExpandPath(mainRepo)
+	if err != nil {
+		return fmt.Errorf("expanding worktree repository path: %w", err)
+	}
+
+	if !git.IsRepo(expandedMain) {
+		return fmt.Errorf("not a git repository: %s", mainRepo)
+	}
+
+	if git.IsRepo(filesDir) {
+		fmt.Printf("✓ Using existing repository at %s\n", filesDir)
+		return nil
+	}
+
+	if err := git.AddWorktree(expandedMain, filesDir, branch); err != nil {
+		return fmt.Errorf("creating worktree: %w", err)
+	}
+
+	fmt.Printf("✓ Created worktree of %s (branch %s)\n", expandedMain, branch)
+	return nil
+}
+
+// applySymlinks creates symlinks for all managed files in config. Each file
+// is applied in its own transaction so a failure rolls back only that file;
+// with dryRun the steps are recorded in a plan transaction and printed.
+func applySymlinks(cmd *cobra.Command, cfg *config.Config, dryRun bool) error {
 	files := cfg.GetManagedFilesForPlatform()
 	if len(files) == 0 {
 		fmt.Println("No files configured for this platform.")
 		return nil
 	}
 
+	if dryRun {
+		fmt.Println("Dry run - no changes will be made:")
+	} else if err := runHooks(cmd, cfg, config.HookPreApply); err != nil {
+		return err
+	}
+
 	fmt.Printf("\nCreating symlinks for %d files...\n", len(files))
 
 	created := 0
 	skipped := 0
+	var linked []config.ManagedFile
 
-	for _, mf := range files {
-		// Get full paths
-		sourcePath, err := config.ExpandPath(mf.SourcePath)
-		if err != nil {
-			fmt.Printf("  ✗ %s (invalid path)\n", mf.SourcePath)
-			continue
+	// Template values are collected once for all files
+	data, err := template.NewData(cfg)
+	if err != nil {
+		return fmt.Errorf("collecting template variables: %w", err)
+	}
+
+	// The secrets backend is only needed if encrypted files are managed
+	var backend crypto.Backend
+
+	var bar *progress.Bar
+	if !dryRun {
+		bar = startBar("Linking", len(files))
+	}
+
+	for i, mf := range files {
+		if cmd.Context().Err() != nil {
+			break
+		}
+		bar.Set(i)
+		tx := core.NewTransaction()
+		if dryRun {
+			tx = core.NewPlanTransaction()
 		}
 
-		repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+		note, applied, err := applyFile(tx, cfg, mf, data, &backend)
 		if err != nil {
-			fmt.Printf("  ✗ %s (invalid repo path)\n", mf.SourcePath)
+			fmt.Printf("  ✗ %s (%v)\n", mf.SourcePath, err)
 			continue
 		}
 
-		// Check if repo file exists
-		if !fs.FileExists(repoPath) {
-			fmt.Printf("  ✗ %s (not in repository)\n", mf.SourcePath)
+		// Git only keeps the executable bit, so clones lose modes like 0600
+		if !dryRun {
+			if restored, err := core.RestorePermissions(cfg, mf); err != nil {
+				fmt.Printf("  ⚠ %s (%v)\n", mf.SourcePath, err)
+			} else if len(restored) > 0 {
+				fmt.Printf("  → Restored mode %s on %s\n", mf.Perm, mf.SourcePath)
+			}
+		}
+
+		if !applied {
+			fmt.Printf("  - %s (%s)\n", mf.SourcePath, note)
+			skipped++
 			continue
 		}
+		tx.Commit()
 
-		// Check if symlink already exists and is correct
-		if isLink, _ := fs.IsSymlink(sourcePath); isLink {
-			if valid, _ := fs.IsValidSymlink(sourcePath); valid {
-				fmt.Printf("  - %s (already linked)\n", mf.SourcePath)
-				skipped++
-				continue
+		if dryRun {
+			fmt.Printf("  + %s\n", mf.SourcePath)
+			for _, step := range tx.Plan() {
+				fmt.Printf("    → %s\n", step)
 			}
+		} else if note != "" {
+			fmt.Printf("  ✓ %s (%s)\n", mf.SourcePath, note)
+		} else {
+			fmt.Printf("  ✓ %s\n", mf.SourcePath)
 		}
+		created++
+		linked = append(linked, mf)
+	}
+	bar.Finish()
+	if err := cmd.Context().Err(); err != nil {
+		return err
+	}
 
-		// Backup existing file if it exists
-		if fs.FileExists(sourcePath) {
-			backupPath, err := core.CreateBackup(sourcePath)
-			if err != nil {
-				fmt.Printf("  ✗ %s (backup failed: %v)\n", mf.SourcePath, err)
-				continue
+	if dryRun {
+		fmt.Printf("\nWould create %d symlinks, skip %d\n", created, skipped)
+		return nil
+	}
+
+	fmt.Printf("\nCreated %d symlinks, skipped %d\n", created, skipped)
+
+	runFileHooks(cmd, cfg, config.HookPostApply, linked)
+	return runHooks(cmd, cfg, config.HookPostApply)
+}
+
+// applyFile runs the steps that deploy mf as part of tx. If mf is already
+// deployed nothing is done and applied is false, with note saying why;
+// otherwise note describes how it was deployed ("" for a plain symlink).
+// backend is created on first use for encrypted files.
+func applyFile(tx *core.Transaction, cfg *config.Config, mf config.ManagedFile, data template.Data, backend *crypto.Backend) (note string, applied bool, err error) {
+	// Pick the repo file for this machine
+	mf, err = config.ResolveVariant(mf)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Get full paths
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid path")
+	}
+
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid repo path")
+	}
+
+	// Check if repo file exists
+	if !fs.FileExists(repoPath) {
+		return "", false, fmt.Errorf("not in repository")
+	}
+
+	// Templates and secrets are linked to their rendered or decrypted output
+	if mf.IsTemplate() || mf.Encrypted {
+		repoPath, err = config.GetLinkTargetPath(cfg, mf)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	// Re-render templates
+	if mf.IsTemplate() {
+		err = tx.Execute(&core.StepOp{
+			Desc: fmt.Sprintf("render %s", mf.RepoPath),
+			DoFunc: func() error {
+				_, _, err := template.RenderManagedFile(cfg, mf, data)
+				return err
+			},
+		})
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	// Decrypt secrets to the local secrets directory
+	if mf.Encrypted {
+		err = tx.Execute(&core.StepOp{
+			Desc: fmt.Sprintf("decrypt %s", mf.RepoPath),
+			DoFunc: func() error {
+				if *backend == nil {
+					var err error
+					if *backend, err = crypto.NewBackend(cfg.Secrets); err != nil {
+						return err
+					}
+				}
+				if _, _, err := core.DecryptSecret(cfg, *backend, mf); err != nil {
+					return fmt.Errorf("decrypting: %w", err)
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	backup := &core.BackupFileOp{Path: sourcePath, File: &mf}
+
+	switch {
+	case mf.IsCopy():
+		// Copy-mode files are deployed as a copy of the repo file
+		isLink, _ := fs.IsSymlink(sourcePath)
+		if !isLink && fs.FileExists(sourcePath) {
+			if same, _ := fs.SameContent(sourcePath, repoPath); same {
+				return "already copied", false, nil
+			}
+			if err := applyBackup(tx, backup); err != nil {
+				return "", false, err
+			}
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc:     fmt.Sprintf("copy %s to %s", repoPath, sourcePath),
+			DoFunc:   func() error { return core.DeployCopy(cfg, mf) },
+			UndoFunc: func() error { return undoDeploy(backup, sourcePath) },
+			Deploys:  sourcePath,
+		})
+		return "copied", err == nil, err
+
+	case mf.IsHardlink():
+		// Hardlink-mode files share the repo file's inode
+		if same, _ := fs.IsHardlinkTo(sourcePath, repoPath); same {
+			return "already linked", false, nil
+		}
+		isLink, _ := fs.IsSymlink(sourcePath)
+		if !isLink && fs.FileExists(sourcePath) {
+			if err := applyBackup(tx, backup); err != nil {
+				return "", false, err
 			}
-			fmt.Printf("  → Backed up to %s\n", backupPath)
-			os.Remove(sourcePath)
 		}
+		err = tx.Execute(&core.StepOp{
+			Desc: fmt.Sprintf("hard link %s => %s", sourcePath, repoPath),
+			DoFunc: func() error {
+				err := core.DeployHardlink(cfg, mf)
+				if errors.Is(err, fs.ErrCrossDevice) {
+					err = fmt.Errorf("%w, use mode: copy instead", err)
+				}
+				return err
+			},
+			UndoFunc: func() error { return undoDeploy(backup, sourcePath) },
+			Deploys:  sourcePath,
+		})
+		return "hard linked", err == nil, err
+
+	case mf.IsComposed():
+		// Compose-mode files are a generated file including the repo file
+		if intact, _ := core.ComposedIntact(cfg, mf); intact {
+			return "already composed", false, nil
+		}
+		isLink, _ := fs.IsSymlink(sourcePath)
+		if !isLink && fs.FileExists(sourcePath) {
+			if err := applyBackup(tx, backup); err != nil {
+				return "", false, err
+			}
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc:     fmt.Sprintf("compose %s from %s", sourcePath, repoPath),
+			DoFunc:   func() error { return core.DeployComposed(cfg, mf) },
+			UndoFunc: func() error { return undoDeploy(backup, sourcePath) },
+			Deploys:  sourcePath,
+		})
+		return "composed", err == nil, err
+	}
 
-		// Create symlink
-		if err := fs.CreateSymlink(repoPath, sourcePath); err != nil {
-			fmt.Printf("  ✗ %s (%v)\n", mf.SourcePath, err)
-			continue
+	// Check if symlink already exists and is correct
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		if valid, _ := fs.IsValidSymlink(sourcePath); valid && !linksToTemplateSource(cfg, mf, sourcePath) {
+			return "already linked", false, nil
 		}
+	}
 
-		fmt.Printf("  ✓ %s\n", mf.SourcePath)
-		created++
+	// Backup existing file if it exists (a link to the raw template is just replaced)
+	if fs.FileExists(sourcePath) && !linksToTemplateSource(cfg, mf, sourcePath) {
+		if err := applyBackup(tx, backup); err != nil {
+			return "", false, err
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc: fmt.Sprintf("remove %s", sourcePath),
+			DoFunc: func() error {
+				_, err := core.DeleteUserFile(cfg, sourcePath)
+				return err
+			},
+			UndoFunc: func() error { return core.RestoreBackup(backup.BackupPath, sourcePath) },
+		})
+		if err != nil {
+			return "", false, err
+		}
 	}
 
-	fmt.Printf("\nCreated %d symlinks, skipped %d\n", created, skipped)
+	// Create symlink
+	err = tx.Execute(&core.CreateSymlinkOp{Target: repoPath, Link: sourcePath, Style: cfg.LinkStyle})
+	return "", err == nil, err
+}
+
+// applyBackup runs backup as a step of tx, reporting where the backup went
+func applyBackup(tx *core.Transaction, backup *core.BackupFileOp) error {
+	if err := tx.Execute(backup); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	if !tx.IsPlan() {
+		fmt.Printf("  → Backed up to %s\n", backup.BackupPath)
+	}
+	return nil
+}
+
+// undoDeploy removes a copy, hard link or composed file deployed at
+// sourcePath, restoring the file it replaced if one was backed up. The link is removed first so a
+// restore can't write through a hard link into the repo file.
+func undoDeploy(backup *core.BackupFileOp, sourcePath string) error {
+	if err := os.Remove(sourcePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if backup.BackupPath != "" {
+		return core.RestoreBackup(backup.BackupPath, sourcePath)
+	}
 	return nil
 }
 
@@ -274,13 +659,11 @@ func interactiveInit(cfg *config.Config) error {
 	}
 
 	fmt.Println("")
-	fmt.Printf("Add all %d files? [Y/n]: ", len(found))
-
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
-
-	if input != "" && input != "y" && input != "yes" {
+	ok, err := confirm(fmt.Sprintf("Add all %d files?", len(found)), true)
+	if err != nil {
+		return err
+	}
+	if !ok {
 		fmt.Println("Cancelled.")
 		return nil
 	}
@@ -299,12 +682,12 @@ func interactiveInit(cfg *config.Config) error {
 	}
 
 	// Git commit
-	if git.IsGitInstalled() && added > 0 {
-		repoPath, err := config.ExpandPath(cfg.RepoPath)
+	if git.IsAvailable() && added > 0 {
+		repoPath, err := config.GetFilesRoot(cfg)
 		if err != nil {
 			fmt.Printf("⚠ Git commit skipped: invalid repo path: %v\n", err)
 		} else {
-			if err := git.AutoCommit(repoPath, fmt.Sprintf("Add %d dotfiles via interactive init", added)); err != nil {
+			if err := git.AutoCommit(repoPath, fmt.Sprintf("Add %d dotfiles via interactive init", added), userPathspecs...); err != nil {
 				fmt.Printf("⚠ Git commit failed: %v\n", err)
 			} else {
 				fmt.Println("✓ Committed to Git")
@@ -346,7 +729,9 @@ func addFile(cfg *config.Config, sourcePath string, customRepoPath string, force
 	}
 
 	// Create backup
-	if _, err := core.CreateBackup(expanded); err != nil {
+	normalized, _ := config.NormalizePath(sourcePath)
+	backupOf := config.ManagedFile{SourcePath: normalized, RepoPath: repoPath}
+	if _, err := core.CreateManagedBackup(expanded, backupOf); err != nil {
 		// Non-fatal, continue
 	}
 
@@ -356,14 +741,13 @@ func addFile(cfg *config.Config, sourcePath string, customRepoPath string, force
 	}
 
 	// Create symlink
-	if err := fs.CreateSymlink(fullRepoPath, expanded); err != nil {
+	if err := fs.CreateSymlinkWithStyle(fullRepoPath, expanded, cfg.LinkStyle); err != nil {
 		// Rollback: move file back
 		fs.MoveFile(fullRepoPath, expanded)
 		return fmt.Errorf("creating symlink: %w", err)
 	}
 
 	// Add to config
-	normalized, _ := config.NormalizePath(sourcePath)
 	mf := config.ManagedFile{
 		SourcePath: normalized,
 		RepoPath:   repoPath,
@@ -375,6 +759,28 @@ func addFile(cfg *config.Config, sourcePath string, customRepoPath string, force
 	if err := cfg.SaveConfig(); err != nil {
 		return fmt.Errorf("saving config: %w", err)
 	}
+	recordChecksums(cfg, repoPath)
 
 	return nil
 }
+
+// symlinkSupportError prints what to do about missing symlink support,
+// depending on whether privilege or the filesystem is the problem
+func symlinkSupportError(err error) error {
+	fmt.Fprintln(os.Stderr, "✗ Symlinks not supported on this platform.")
+	fmt.Fprintln(os.Stderr, "")
+	switch {
+	case errors.Is(err, fs.ErrSymlinkPrivilege):
+		fmt.Fprintln(os.Stderr, "Windows users: Enable Developer Mode")
+		fmt.Fprintln(os.Stderr, "  Settings → Update & Security → For developers → Developer Mode")
+		fmt.Fprintln(os.Stderr, "Or run dotcor from an elevated (Run as administrator) terminal.")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Then restart your terminal and try again.")
+	case errors.Is(err, fs.ErrSymlinkFilesystem):
+		fmt.Fprintln(os.Stderr, "The filesystem can't store symlinks (e.g. FAT32 or a network share).")
+		fmt.Fprintln(os.Stderr, "Keep your home directory and repository on NTFS or another native filesystem.")
+	default:
+		fmt.Fprintf(os.Stderr, "Checking symlink support failed: %v\n", err)
+	}
+	return fmt.Errorf("symlinks not supported")
+}
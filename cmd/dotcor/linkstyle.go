@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var linkStyleCmd = &cobra.Command{
+	Use:   "link-style [relative|absolute]",
+	Short: "Show or change how symlinks point to the repository",
+	Long: `Show or change the link_style setting and convert existing symlinks.
+
+Relative links (the default) keep working when the home directory is moved
+or mounted elsewhere. Absolute links behave better on some network-home
+setups where the link and the repository are reached through different
+mount points.
+
+Changing the style saves it to config.yaml and rewrites every managed
+symlink that points to the repository using the other style. System files
+always use absolute links and are not changed.
+
+Examples:
+  dotcor link-style                    # Show the current style
+  dotcor link-style absolute           # Convert all links to absolute targets
+  dotcor link-style relative --dry-run # Show which links would change`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLinkStyle,
+}
+
+func init() {
+	linkStyleCmd.Flags().Bool("dry-run", false, "Show what would be converted without making changes")
+	rootCmd.AddCommand(linkStyleCmd)
+}
+
+func runLinkStyle(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	current := cfg.LinkStyle
+	if current == "" {
+		current = config.LinkStyleRelative
+	}
+
+	if len(args) == 0 {
+		fmt.Printf("Link style: %s\n", current)
+		return nil
+	}
+
+	style := args[0]
+	if err := config.ValidateLinkStyle(style); err != nil || style == "" {
+		return fmt.Errorf("invalid link style %q (expected relative or absolute)", style)
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	if dryRun {
+		fmt.Println("Dry run - no changes will be made:")
+		fmt.Println("")
+	}
+
+	converted := 0
+	failed := 0
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		ok, err := convertLinkStyle(cfg, mf, style, dryRun)
+		if err != nil {
+			fmt.Printf("  ✗ %s: %v\n", mf.SourcePath, err)
+			failed++
+			continue
+		}
+		if ok {
+			converted++
+		}
+	}
+
+	fmt.Println("")
+	if dryRun {
+		fmt.Printf("Would convert %d link(s) to %s\n", converted, style)
+		return nil
+	}
+
+	cfg.LinkStyle = style
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Printf("Converted %d link(s) to %s", converted, style)
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println("")
+
+	return nil
+}
+
+// convertLinkStyle rewrites a managed symlink in the given style.
+// Links that are missing, broken, or point elsewhere are left for doctor.
+// Returns true if the link was (or would be) converted.
+func convertLinkStyle(cfg *config.Config, mf config.ManagedFile, style string, dryRun bool) (bool, error) {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return false, fmt.Errorf("invalid source path: %w", err)
+	}
+
+	repoPath, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return false, fmt.Errorf("invalid repo path: %w", err)
+	}
+
+	status, err := fs.GetSymlinkStatus(sourcePath, repoPath)
+	if err != nil {
+		return false, err
+	}
+	if !status.IsSymlink || !status.PointsToRepo {
+		return false, nil
+	}
+
+	wantRelative := style != config.LinkStyleAbsolute
+	if status.IsRelative == wantRelative {
+		return false, nil
+	}
+
+	if dryRun {
+		fmt.Printf("  ~ %s\n", mf.SourcePath)
+		return true, nil
+	}
+
+	if err := fs.CreateSymlinkWithStyle(repoPath, sourcePath, style); err != nil {
+		return false, err
+	}
+
+	target, _ := fs.ReadSymlink(sourcePath)
+	fmt.Printf("  ✓ %s → %s\n", mf.SourcePath, filepath.ToSlash(target))
+	return true, nil
+}
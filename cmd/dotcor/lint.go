@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/lint"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [file]...",
+	Short: "Check managed files for syntax errors and common mistakes",
+	Long: `Run a linter on every managed file, or only the given files, chosen by
+file type:
+
+  shellcheck  bashrc, bash_profile, profile, *.sh, *.bash (if installed)
+  zsh         zshrc, zshenv, zprofile, *.zsh, parsed with zsh -n (if installed)
+  gitconfig   gitconfig, git/config, parsed with git config
+  toml        *.toml syntax
+  yaml        *.yaml and *.yml syntax
+
+Linters that aren't installed are skipped. Templates and secrets are not
+linted. Linters for other file types are added, and built-in ones turned
+off, in config.yaml:
+
+  lint:
+    disable: [shellcheck]
+    linters:
+      - name: luacheck
+        command: luacheck --no-color   # Run with the file as last argument
+        files: ['*.lua']               # Fails the file on a non-zero exit
+
+The files dotcor is about to commit (on add, sync, edit and so on) are
+linted too, with problems reported before the commit. With
+lint.fail_on_error set to true, lint errors stop the commit.
+
+Exit codes:
+  0  No lint errors (warnings allowed)
+  1  Lint could not run
+  2  Lint errors found
+
+Examples:
+  dotcor lint                  # Lint every managed file
+  dotcor lint ~/.bashrc        # Lint one file
+  dotcor lint -o json          # Machine-readable results`,
+	Annotations: annotations(structuredOutput, readOnly),
+	RunE:        runLint,
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+}
+
+// lintOutput is the structured output of 'dotcor lint'
+type lintOutput struct {
+	Files   int           `json:"files" yaml:"files"`
+	Errors  int           `json:"errors" yaml:"errors"`
+	Results []lint.Result `json:"results" yaml:"results"`
+}
+
+// lintTarget is a file to lint, with the repo path that selects its linters
+type lintTarget struct {
+	path     string
+	repoPath string
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	var targets []lintTarget
+	if len(args) > 0 {
+		targets, err = lintArgTargets(cfg, args)
+	} else {
+		targets, err = lintManagedTargets(cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	linters := lint.NewSet(cfg.Lint)
+	out := lintOutput{Results: []lint.Result{}}
+	skipped := map[string]int{}
+	for _, target := range targets {
+		if err := cmd.Context().Err(); err != nil {
+			return err
+		}
+		if len(linters.For(target.repoPath)) == 0 {
+			continue
+		}
+		result, err := linters.Lint(target.path, target.repoPath)
+		if err != nil {
+			return fmt.Errorf("linting %s: %w", target.repoPath, err)
+		}
+		for _, s := range result.Skipped {
+			skipped[s]++
+		}
+		out.Files++
+		out.Errors += result.Errors()
+		if len(result.Findings) > 0 {
+			out.Results = append(out.Results, result)
+		}
+	}
+
+	for _, result := range out.Results {
+		printLintFindings(result)
+	}
+	if len(out.Results) > 0 {
+		fmt.Println()
+	}
+	for _, s := range sortedKeys(skipped) {
+		fmt.Printf("  - %s: skipped %d file(s)\n", s, skipped[s])
+	}
+
+	if err := writeResult(out); err != nil {
+		return err
+	}
+
+	if out.Errors == 0 {
+		fmt.Printf("✓ No lint errors in %d file(s)\n", out.Files)
+		return nil
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &exitCodeError{
+		code: exitProblems,
+		msg:  fmt.Sprintf("lint found %d error(s)", out.Errors),
+	}
+}
+
+// lintManagedTargets returns the repo files of every managed file that
+// can be linted as is, skipping templates and secrets
+func lintManagedTargets(cfg *config.Config) ([]lintTarget, error) {
+	var targets []lintTarget
+	for _, files := range [][]config.ManagedFile{cfg.ManagedFiles, cfg.SystemFiles} {
+		for _, mf := range files {
+			if mf.IsTemplate() || mf.Encrypted {
+				continue
+			}
+			path, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid repo path %s: %w", mf.RepoPath, err)
+			}
+			targets = append(targets, lintTarget{path: path, repoPath: mf.RepoPath})
+		}
+	}
+	return targets, nil
+}
+
+// lintArgTargets returns the files to lint for the paths given on the
+// command line: the repo file of a managed file, or the file itself
+func lintArgTargets(cfg *config.Config, args []string) ([]lintTarget, error) {
+	var targets []lintTarget
+	for _, arg := range args {
+		normalized, err := config.NormalizePath(arg)
+		if err != nil {
+			normalized = arg
+		}
+		if mf, err := cfg.GetManagedFile(normalized); err == nil {
+			if mf.IsTemplate() || mf.Encrypted {
+				return nil, fmt.Errorf("%s is a template or secret, which can't be linted", arg)
+			}
+			path, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid repo path %s: %w", mf.RepoPath, err)
+			}
+			targets = append(targets, lintTarget{path: path, repoPath: mf.RepoPath})
+			continue
+		}
+
+		path, err := config.ExpandPath(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", arg, err)
+		}
+		targets = append(targets, lintTarget{path: path, repoPath: arg})
+	}
+	return targets, nil
+}
+
+// printLintFindings prints the findings for one file
+func printLintFindings(result lint.Result) {
+	for _, f := range result.Findings {
+		mark := "⚠"
+		if f.Severity == lint.SeverityError {
+			mark = "✗"
+		}
+		location := result.Path
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", result.Path, f.Line)
+		}
+		fmt.Printf("  %s %s [%s] %s\n", mark, location, f.Linter, f.Message)
+	}
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lintBeforeCommit is the git commit check: it lints the files about to be
+// committed and reports what it finds. With lint.fail_on_error, lint errors
+// stop the commit.
+func lintBeforeCommit(_ string, changes []git.ChangeEntry) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil // Commits don't depend on the config loading
+	}
+	root, err := config.ExpandPath(cfg.RepoPath)
+	if err != nil {
+		return nil
+	}
+
+	linters := lint.NewSet(cfg.Lint)
+	encrypted := encryptedRepoPaths(cfg)
+	failed := 0
+	for _, change := range changes {
+		path := filepath.Join(root, filepath.FromSlash(change.Path))
+		repoPath := filesRootRelative(cfg, change.Path)
+		if !fs.FileExists(path) || strings.HasSuffix(repoPath, config.TemplateExt) || encrypted[filepath.FromSlash(repoPath)] {
+			continue
+		}
+		result, err := linters.Lint(path, repoPath)
+		if err != nil {
+			fmt.Printf("⚠ Could not lint %s: %v\n", repoPath, err)
+			continue
+		}
+		printLintFindings(result)
+		failed += result.Errors()
+	}
+
+	if failed > 0 && cfg.Lint.FailOnError {
+		return fmt.Errorf("%d lint error(s) in the files to commit\nFix them, or set lint.fail_on_error to false to commit anyway", failed)
+	}
+	return nil
+}
@@ -8,7 +8,9 @@ import (
 	"text/tabwriter"
 
 	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
 	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/output"
 	"github.com/spf13/cobra"
 )
 
@@ -25,15 +27,16 @@ Examples:
   dotcor list --long           # Show detailed info including repo paths
   dotcor list --category       # Group by category
   dotcor list --status         # Show symlink status
-  dotcor list --json           # Output as JSON`,
-	RunE: runList,
+  dotcor list --output yaml    # Output as YAML (or json)`,
+	RunE:        runList,
+	Annotations: annotations(structuredOutput, readOnly),
 }
 
 func init() {
 	listCmd.Flags().BoolP("long", "l", false, "Show detailed information")
 	listCmd.Flags().Bool("category", false, "Group files by category")
 	listCmd.Flags().Bool("status", false, "Show symlink status")
-	listCmd.Flags().Bool("json", false, "Output as JSON")
+	listCmd.Flags().Bool("json", false, "Output as JSON (same as --output json)")
 	listCmd.Flags().Bool("paths-only", false, "Output only paths (for scripting)")
 	rootCmd.AddCommand(listCmd)
 }
@@ -53,17 +56,22 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	files := cfg.GetManagedFilesForPlatform()
 
+	// --json predates the global --output flag
+	if jsonFormat && !output.Structured() {
+		if err := output.SetFormat(output.FormatJSON); err != nil {
+			return err
+		}
+	}
+	if output.Structured() {
+		return outputStructured(cfg, files, showStatus)
+	}
+
 	if len(files) == 0 {
 		fmt.Println("No files managed by DotCor.")
 		fmt.Println("Run 'dotcor add <file>' to start managing dotfiles.")
 		return nil
 	}
 
-	// Handle JSON output
-	if jsonFormat {
-		return outputJSON(cfg, files, showStatus)
-	}
-
 	// Handle paths-only output
 	if pathsOnly {
 		for _, f := range files {
@@ -158,32 +166,36 @@ func outputByCategory(cfg *config.Config, files []config.ManagedFile, showStatus
 	return nil
 }
 
-// outputJSON outputs the file list as JSON
-func outputJSON(cfg *config.Config, files []config.ManagedFile, showStatus bool) error {
-	fmt.Println("[")
+// listEntry is a managed file in the structured output of 'dotcor list'
+type listEntry struct {
+	Source    string   `json:"source" yaml:"source"`
+	Repo      string   `json:"repo" yaml:"repo"`
+	Mode      string   `json:"mode" yaml:"mode"`
+	Platforms []string `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+	Status    string   `json:"status,omitempty" yaml:"status,omitempty"`
+	Added     string   `json:"added" yaml:"added"`
+}
 
-	for i, f := range files {
-		status := ""
-		if showStatus {
-			status = getSymlinkStatus(cfg, f)
+// outputStructured writes the file list in the selected structured format
+func outputStructured(cfg *config.Config, files []config.ManagedFile, showStatus bool) error {
+	entries := make([]listEntry, 0, len(files))
+	for _, f := range files {
+		entry := listEntry{
+			Source:    f.SourcePath,
+			Repo:      f.RepoPath,
+			Mode:      f.Mode,
+			Platforms: f.Platforms,
+			Added:     f.AddedAt.Format("2006-01-02"),
 		}
-
-		comma := ","
-		if i == len(files)-1 {
-			comma = ""
+		if entry.Mode == "" {
+			entry.Mode = config.DeployModeSymlink
 		}
-
 		if showStatus {
-			fmt.Printf("  {\"source\": \"%s\", \"repo\": \"%s\", \"status\": \"%s\", \"added\": \"%s\"}%s\n",
-				f.SourcePath, f.RepoPath, status, f.AddedAt.Format("2006-01-02"), comma)
-		} else {
-			fmt.Printf("  {\"source\": \"%s\", \"repo\": \"%s\", \"added\": \"%s\"}%s\n",
-				f.SourcePath, f.RepoPath, f.AddedAt.Format("2006-01-02"), comma)
+			entry.Status = getSymlinkStatus(cfg, f)
 		}
+		entries = append(entries, entry)
 	}
-
-	fmt.Println("]")
-	return nil
+	return output.Write(entries)
 }
 
 // getCategory extracts the category from a repo path
@@ -213,6 +225,42 @@ func getSymlinkStatus(cfg *config.Config, f config.ManagedFile) string {
 		return "error"
 	}
 
+	// Copies are compared with the repo instead
+	if f.IsCopy() {
+		if isLink {
+			return "not-copy"
+		}
+		if differs, err := core.CopyDiffers(cfg, f); err != nil {
+			return "error"
+		} else if differs {
+			return "modified"
+		}
+		return "ok"
+	}
+
+	// Hard links must share an inode with the repo file
+	if f.IsHardlink() {
+		if isLink {
+			return "not-hardlink"
+		}
+		if intact, err := core.HardlinkIntact(cfg, f); err != nil {
+			return "error"
+		} else if !intact {
+			return "not-hardlink"
+		}
+		return "ok"
+	}
+
+	// Composed files must be the file dotcor generates
+	if f.IsComposed() {
+		if intact, err := core.ComposedIntact(cfg, f); err != nil {
+			return "error"
+		} else if !intact {
+			return "not-composed"
+		}
+		return "ok"
+	}
+
 	if !isLink {
 		return "not-symlink"
 	}
@@ -233,7 +281,7 @@ func getSymlinkStatus(cfg *config.Config, f config.ManagedFile) string {
 		return "error"
 	}
 
-	expectedTarget, err := config.GetRepoFilePath(cfg, f.RepoPath)
+	expectedTarget, err := config.GetLinkTargetPath(cfg, f)
 	if err != nil {
 		return "error"
 	}
@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
 	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/log"
+	"github.com/justincordova/dotcor/internal/output"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -14,38 +25,125 @@ var (
 	version = "0.1.1"
 )
 
-// ANSI color codes
+// Exit codes returned by dotcor. Scripts branch on these, so existing
+// values must never change meaning.
 const (
-	colorReset   = "\033[0m"
-	colorDim     = "\033[2m"
-	colorBold    = "\033[1m"
-	colorGreen   = "\033[32m"
-	colorYellow  = "\033[33m"
-	colorCyan    = "\033[36m"
-	colorWhite   = "\033[97m"
-	colorOrange  = "\033[38;5;208m"
-	colorPink    = "\033[38;5;205m"
-	colorLightPink = "\033[38;5;218m"
-	colorLime    = "\033[38;5;118m"
+	exitOK             = 0
+	exitError          = 1   // Generic error (bad config, failed operation)
+	exitProblems       = 2   // Command ran successfully but found problems
+	exitLocked         = 3   // Another dotcor process holds the lock
+	exitNotInitialized = 4   // No config yet, 'dotcor init' hasn't been run
+	exitConflict       = 5   // A merge or rebase in the repository needs resolving
+	exitInterrupted    = 130 // Stopped by Ctrl+C or SIGTERM, as shells report it
 )
 
+// exitCodeError carries a specific process exit code out of a command
+type exitCodeError struct {
+	code int
+	msg  string
+}
+
+func (e *exitCodeError) Error() string {
+	return e.msg
+}
+
+// exitCode returns the process exit code for the error a command returned
+func exitCode(err error) int {
+	var exitErr *exitCodeError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.Is(err, core.ErrLockHeld), errors.Is(err, core.ErrStaleLock):
+		return exitLocked
+	}
+	return exitError
+}
+
+// requireInitialized fails with exitNotInitialized when there is no config
+// file. LoadConfig falls back to defaults, which would otherwise make a
+// machine dotcor was never set up on look healthy.
+func requireInitialized(cmd *cobra.Command) error {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		return nil
+	}
+
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &exitCodeError{
+		code: exitNotInitialized,
+		msg:  fmt.Sprintf("dotcor is not initialized: no config at %s\nRun 'dotcor init' first", configPath),
+	}
+}
+
+// persistentFlagArgs returns the global flags given on the command line,
+// such as --repo, for passing on to a dotcor command run as a child
+// process. --output is left out since the child's output isn't parsed.
+func persistentFlagArgs(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Root().PersistentFlags().Visit(func(f *pflag.Flag) {
+		if f.Name != "output" {
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
 func printBanner() {
 	fmt.Println()
-	fmt.Print(colorLightPink)
-	fmt.Println("  ██████╗  ██████╗ ████████╗ ██████╗ ██████╗ ██████╗ ")
-	fmt.Println("  ██╔══██╗██╔═══██╗╚══██╔══╝██╔════╝██╔═══██╗██╔══██╗")
-	fmt.Println("  ██║  ██║██║   ██║   ██║   ██║     ██║   ██║██████╔╝")
-	fmt.Println("  ██║  ██║██║   ██║   ██║   ██║     ██║   ██║██╔══██╗")
-	fmt.Println("  ██████╔╝╚██████╔╝   ██║   ╚██████╗╚██████╔╝██║  ██║")
-	fmt.Println("  ╚═════╝  ╚═════╝    ╚═╝    ╚═════╝ ╚═════╝ ╚═╝  ╚═╝")
-	fmt.Print(colorReset)
+	for _, line := range []string{
+		"  ██████╗  ██████╗ ████████╗ ██████╗ ██████╗ ██████╗ ",
+		"  ██╔══██╗██╔═══██╗╚══██╔══╝██╔════╝██╔═══██╗██╔══██╗",
+		"  ██║  ██║██║   ██║   ██║   ██║     ██║   ██║██████╔╝",
+		"  ██║  ██║██║   ██║   ██║   ██║     ██║   ██║██╔══██╗",
+		"  ██████╔╝╚██████╔╝   ██║   ╚██████╗╚██████╔╝██║  ██║",
+		"  ╚═════╝  ╚═════╝    ╚═╝    ╚═════╝ ╚═════╝ ╚═╝  ╚═╝",
+	} {
+		fmt.Println(paint(line, colorLightPink))
+	}
 	fmt.Println()
-	fmt.Printf("  %s%sv%s%s %s· symlink-based dotfile manager%s\n", colorBold, colorLightPink, version, colorReset, colorDim, colorReset)
+	fmt.Printf("  %s %s\n", paint("v"+version, colorBold, colorLightPink), paint("· symlink-based dotfile manager", colorDim))
 	fmt.Println()
 }
 
 func init() {
 	viper.SetDefault("version", version)
+
+	rootCmd.PersistentFlags().Bool("allow-any-home", false, "Run even if $HOME is unset or /")
+	rootCmd.PersistentFlags().Bool("allow-read-only", false, "Run even if the config directory is on a read-only filesystem")
+	rootCmd.PersistentFlags().Bool("allow-root", false, "Run as root even if the config belongs to another user")
+	rootCmd.PersistentFlags().Bool("allow-temp-repo", false, "Run even if the repository is inside a temp directory")
+	rootCmd.PersistentFlags().Bool("no-hooks", false, "Don't run hooks from config.yaml")
+	rootCmd.PersistentFlags().Bool("no-color", false, "Don't color output ($NO_COLOR)")
+	rootCmd.PersistentFlags().BoolP("yes", "y", false, "Answer yes to prompts, failing on those that need a real answer ($DOTCOR_NONINTERACTIVE)")
+	rootCmd.PersistentFlags().Bool("non-interactive", false, "Same as --yes")
+	rootCmd.PersistentFlags().String("git-backend", "", "Git implementation: auto, cli or go-git (overrides git_backend)")
+	rootCmd.PersistentFlags().Duration("lock-wait", 0, "Wait up to this long for another dotcor command to release the lock, e.g. 30s")
+	rootCmd.PersistentFlags().String("repo", "", "Work on the named repository from the repositories section of config.yaml")
+	rootCmd.PersistentFlags().Bool("xdg", false, "Keep dotcor's files in the XDG base directories, moving ~/.dotcor there ($DOTCOR_XDG)")
+	rootCmd.PersistentFlags().Bool("verbose", false, "Log what dotcor does to stderr")
+	rootCmd.PersistentFlags().Bool("debug", false, "Log debugging details to stderr and the log file")
+	rootCmd.PersistentFlags().StringP("output", "o", "", "Output format: plain, json or yaml (list, doctor, sync, add, remove and scan)")
+}
+
+// checkEnvironment runs the environment guards before a mutating operation
+func checkEnvironment(cmd *cobra.Command, repoPath string) error {
+	var overrides core.EnvironmentOverrides
+	overrides.AllowAnyHome, _ = cmd.Flags().GetBool("allow-any-home")
+	overrides.AllowReadOnly, _ = cmd.Flags().GetBool("allow-read-only")
+	overrides.AllowRoot, _ = cmd.Flags().GetBool("allow-root")
+	overrides.AllowTempRepo, _ = cmd.Flags().GetBool("allow-temp-repo")
+
+	if err := core.CheckEnvironment(repoPath, overrides); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	return nil
 }
 
 var rootCmd = &cobra.Command{
@@ -57,86 +155,442 @@ Manage your dotfiles with symlinks - edit files directly, changes instantly
 appear in your repository. Built-in Git automation handles commits and sync.`,
 	Version: version,
 	Run:     runRoot,
+
+	PersistentPreRunE: setupCommand,
 }
 
-func runRoot(cmd *cobra.Command, args []string) {
-	printBanner()
+// setupCommand applies the global flags before any command runs
+func setupCommand(cmd *cobra.Command, args []string) error {
+	migrated := setupLocation(cmd)
+	if err := selectRepo(cmd); err != nil {
+		return err
+	}
+	setupLogging(cmd, args)
+	selectColor(cmd)
+	selectInteraction(cmd)
+	silenceOnInterrupt(cmd)
+	if err := selectLockWait(cmd); err != nil {
+		return err
+	}
+	core.SetJournalCommand(cmd.CommandPath())
+	applyConfigSettings()
+	recoverInterrupted(cmd)
+	if err := selectOutput(cmd); err != nil {
+		return err
+	}
+	if err := selectGitBackend(cmd); err != nil {
+		return err
+	}
+	startUpdateCheck(cmd)
+	if migrated {
+		relinkAfterMigration(cmd)
+	}
+	core.SetReadOnly(isReadOnly(cmd))
+	return nil
+}
+
+// readOnly marks commands that only read. They run without the lock, so a
+// stuck 'dotcor watch' or sync doesn't keep anyone from checking status.
+var readOnly = map[string]string{"lock": "none"}
+
+// isReadOnly reports whether cmd is marked readOnly
+func isReadOnly(cmd *cobra.Command) bool {
+	return cmd.Annotations["lock"] == "none"
+}
+
+// annotations merges sets of command annotations, such as readOnly and
+// structuredOutput
+func annotations(sets ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, set := range sets {
+		for k, v := range set {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// setupLocation applies --xdg and, the first time the XDG layout is used,
+// moves ~/.dotcor into it. It reports whether files were moved.
+func setupLocation(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("xdg") {
+		xdg, _ := cmd.Flags().GetBool("xdg")
+		config.SetXDG(xdg)
+	}
+
+	from, err := config.MigrateToXDG()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Moving to the XDG layout: %v\n", err)
+	}
+	if from == "" {
+		return false
+	}
+
+	dataDir, _ := config.GetDataDir()
+	configDir, _ := config.GetConfigDir()
+	fmt.Fprintf(os.Stderr, "✓ Moved %s to %s, with config.yaml in %s\n", from, dataDir, configDir)
+	return true
+}
+
+// relinkAfterMigration re-applies the links of every repository, which
+// still point into ~/.dotcor after it was moved to the XDG layout
+func relinkAfterMigration(cmd *cobra.Command) {
+	selected := config.SelectedRepo()
+	defer config.SelectRepo(selected)
+
+	config.SelectRepo("")
+	root, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	for _, name := range append([]string{""}, root.RepoNames()...) {
+		config.SelectRepo(name)
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			continue
+		}
+		err = core.WithLock(func() error {
+			return applySymlinks(cmd, cfg, false)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Re-applying links failed: %v\n  Run 'dotcor %sinit --apply' to retry\n", err, repoFlag(name))
+		}
+	}
+}
+
+// selectRepo applies --repo. A repository that isn't configured yet can
+// only be created, by init or clone.
+func selectRepo(cmd *cobra.Command) error {
+	name, _ := cmd.Flags().GetString("repo")
+	if err := config.SelectRepo(name); err != nil {
+		return err
+	}
+	if name == "" || cmd == initCmd || cmd == cloneCmd {
+		return nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil // The command reports it
+	}
+	if _, ok := cfg.Repositories[name]; !ok {
+		known := "none"
+		if names := cfg.RepoNames(); len(names) > 0 {
+			known = strings.Join(names, ", ")
+		}
+		return fmt.Errorf("unknown repository %q (configured: %s)\nCreate it with 'dotcor --repo %s init'", name, known, name)
+	}
+	return nil
+}
+
+// repoFlag returns the --repo flag selecting name, to put in suggested
+// commands, or "" for the default repository
+func repoFlag(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "--repo " + name + " "
+}
+
+// applyConfigSettings applies the backups and categories settings from the config
+func applyConfigSettings() {
+	if cfg, err := config.LoadConfig(); err == nil {
+		core.SetBackupCompression(cfg.Backups.Compress)
+		config.SetCategoryRules(cfg.Categories)
+		if !cfg.UI.ColorEnabled() {
+			useColor = false
+		}
+	}
+}
+
+// setupLogging applies --verbose and --debug and opens the log file. The
+// log file is only used once dotcor is initialized, so a command never
+// creates ~/.dotcor just to log.
+func setupLogging(cmd *cobra.Command, args []string) {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	debug, _ := cmd.Flags().GetBool("debug")
+	opts := log.Options{Verbose: verbose, Debug: debug}
+
+	if dataDir, err := config.GetDataDir(); err == nil && fs.PathExists(dataDir) {
+		if logsDir, err := config.GetLogsDir(); err == nil {
+			opts.File = filepath.Join(logsDir, "dotcor.log")
+		}
+		if cfg, err := config.LoadConfig(); err == nil {
+			opts.JSON = cfg.Log.Format == config.LogFormatJSON
+			opts.MaxSize = int64(cfg.Log.MaxSizeMB) << 20
+			opts.MaxFiles = cfg.Log.MaxFiles
+		}
+	}
+
+	if err := log.Setup(opts); err != nil && (verbose || debug) {
+		fmt.Fprintf(os.Stderr, "⚠ %v, not writing the log file\n", err)
+	}
+	log.SetCommand(cmd.CommandPath())
+	log.Debug("command started", "args", args)
+}
+
+// recoverInterrupted rolls back transactions left half done by a dotcor
+// process that was killed. If another dotcor holds the lock it is left for
+// the next run, as it is by read-only commands, which don't take the lock.
+// 'dotcor doctor' reports them instead, fixing with --fix.
+func recoverInterrupted(cmd *cobra.Command) {
+	if cmd == doctorCmd || isReadOnly(cmd) {
+		return
+	}
+	interrupted, err := core.InterruptedTransactions()
+	if err != nil || len(interrupted) == 0 {
+		return
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+
+	err = core.WithLock(func() error {
+		recovered, err := core.RecoverTransactions(cfg)
+		for _, it := range recovered {
+			fmt.Fprintf(os.Stderr, "⚠ Rolled back an interrupted '%s' from %s\n",
+				it.Entry.Command, it.Entry.Time.Local().Format("2006-01-02 15:04"))
+		}
+		return err
+	})
+	if err != nil && !errors.Is(err, core.ErrLockHeld) {
+		fmt.Fprintf(os.Stderr, "⚠ %v\n  Run 'dotcor doctor --fix' to retry\n", err)
+	}
+}
 
+// selectLockWait applies --lock-wait. Ctrl+C stops the wait.
+func selectLockWait(cmd *cobra.Command) error {
+	wait, _ := cmd.Flags().GetDuration("lock-wait")
+	if wait < 0 {
+		return fmt.Errorf("invalid --lock-wait %s (expected 0 or more)", wait)
+	}
+	core.SetLockWait(cmd.Context(), wait)
+	return nil
+}
+
+// selectGitBackend applies the --git-backend flag, or the git_backend setting,
+// and the commit_style and commit_template settings. A commit format that
+// can't be used falls back to plain messages with a warning, rather than
+// failing every command, including the 'dotcor config set' that fixes it.
+func selectGitBackend(cmd *cobra.Command) error {
+	backend, _ := cmd.Flags().GetString("git-backend")
+	if cfg, err := config.LoadConfig(); err == nil {
+		if backend == "" {
+			backend = cfg.GitBackend
+		}
+		if err := git.SetCommitFormat(cfg.CommitStyle, cfg.CommitTemplate); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ %v; using plain commit messages\n", err)
+			_ = git.SetCommitFormat("", "")
+		}
+	}
+	return git.SetBackend(backend)
+}
+
+func runRoot(cmd *cobra.Command, args []string) {
 	// Try to load config and show status
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		// Not initialized
-		fmt.Printf("  %s⚠ Not initialized%s\n", colorYellow, colorReset)
+		printBanner()
+		fmt.Printf("  %s\n", paint("⚠ Not initialized", colorYellow))
 		fmt.Println()
-		fmt.Printf("  %sGet started:%s\n", colorDim, colorReset)
+		fmt.Printf("  %s\n", paint("Get started:", colorDim))
 		fmt.Println("    dotcor init          Initialize DotCor")
 		fmt.Println("    dotcor --help        Show all commands")
 		fmt.Println()
 		return
 	}
 
-	// Show quick status
+	// Compact mode is the status line alone, as 'dotcor summary' prints it
+	if cfg.UI.Compact {
+		fmt.Println(strings.Join(summaryLines(loadStatusSummary(cfg)), " · "))
+		return
+	}
+	if cfg.UI.BannerEnabled() {
+		printBanner()
+	} else {
+		fmt.Println()
+	}
 	showQuickStatus(cfg)
 }
 
 func showQuickStatus(cfg *config.Config) {
-	files := cfg.GetManagedFilesForPlatform()
-	totalFiles := len(files)
+	// Status section
+	fmt.Printf("  %s\n", paint("Status", colorBold))
+	fmt.Printf("  %s\n", paint("──────", colorDim))
+	for _, line := range summaryLines(loadStatusSummary(cfg)) {
+		fmt.Printf("  %s\n", line)
+	}
 
-	// Count problems
-	problemCount := 0
-	for _, f := range files {
-		fs := checkFileStatus(cfg, f)
-		if fs.Status != "ok" {
-			problemCount++
+	fmt.Println()
+	fmt.Printf("  %s  status · add · sync · --help\n", paint("Commands:", colorDim))
+	fmt.Println()
+}
+
+// loadStatusSummary returns the cached status summary, checking afresh
+// and caching the result when the cache is stale
+func loadStatusSummary(cfg *config.Config) core.StatusSummary {
+	summary, ok := core.LoadStatusCache(cfg)
+	if !ok {
+		summary = quickStatus(cfg)
+		if err := core.SaveStatusCache(summary); err != nil {
+			log.Debug("saving status cache failed", "error", err)
 		}
 	}
+	return summary
+}
 
-	// Status section
-	fmt.Printf("  %sStatus%s\n", colorBold, colorReset)
-	fmt.Printf("  %s──────%s\n", colorDim, colorReset)
+// summaryLines formats a status summary as short lines: the files, then
+// the repository if its status is known
+func summaryLines(summary core.StatusSummary) []string {
+	var lines []string
 
 	// Files status
-	if totalFiles == 0 {
-		fmt.Printf("  %s○%s No files managed\n", colorDim, colorReset)
+	if summary.Files == 0 {
+		lines = append(lines, fmt.Sprintf("%s No files managed", paint("○", colorDim)))
+	} else if summary.Problems == 0 {
+		lines = append(lines, fmt.Sprintf("%s %d file(s) %s", paint("●", colorGreen), summary.Files, paint("✓", colorGreen)))
 	} else {
-		if problemCount == 0 {
-			fmt.Printf("  %s●%s %d file(s) %s✓%s\n", colorGreen, colorReset, totalFiles, colorGreen, colorReset)
-		} else {
-			fmt.Printf("  %s●%s %d file(s), %s%d with issues%s\n", colorYellow, colorReset, totalFiles, colorYellow, problemCount, colorReset)
-		}
+		lines = append(lines, fmt.Sprintf("%s %d file(s), %s", paint("●", colorYellow), summary.Files, paint(fmt.Sprintf("%d with issues", summary.Problems), colorYellow)))
 	}
 
 	// Git status
-	repoPath, err := config.ExpandPath(cfg.RepoPath)
-	if err == nil && git.IsGitInstalled() && git.IsRepo(repoPath) {
-		gitStatus, err := git.GetStatus(repoPath)
-		if err == nil {
-			if gitStatus.HasUncommitted {
-				fmt.Printf("  %s○%s uncommitted changes\n", colorYellow, colorReset)
-			} else {
-				fmt.Printf("  %s●%s clean %s✓%s\n", colorGreen, colorReset, colorGreen, colorReset)
-			}
+	if summary.Git {
+		if summary.Uncommitted {
+			lines = append(lines, fmt.Sprintf("%s uncommitted changes", paint("○", colorYellow)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s clean %s", paint("●", colorGreen), paint("✓", colorGreen)))
+		}
 
-			if gitStatus.RemoteExists {
-				if gitStatus.AheadBy > 0 {
-					fmt.Printf("  %s↑%s %d to push\n", colorCyan, colorReset, gitStatus.AheadBy)
-				}
-				if gitStatus.BehindBy > 0 {
-					fmt.Printf("  %s↓%s %d to pull\n", colorCyan, colorReset, gitStatus.BehindBy)
-				}
+		if summary.RemoteExists {
+			if summary.Ahead > 0 {
+				lines = append(lines, fmt.Sprintf("%s %d to push", paint("↑", colorCyan), summary.Ahead))
+			}
+			if summary.Behind > 0 {
+				lines = append(lines, fmt.Sprintf("%s %d to pull", paint("↓", colorCyan), summary.Behind))
 			}
 		}
 	}
+	return lines
+}
 
-	fmt.Println()
-	fmt.Printf("  %sCommands:%s  status · add · sync · --help\n", colorDim, colorReset)
-	fmt.Println()
+// quickStatus checks every file and the repository for the banner
+func quickStatus(cfg *config.Config) core.StatusSummary {
+	files := cfg.GetManagedFilesForPlatform()
+	summary := core.StatusSummary{Files: len(files)}
+
+	// Count problems
+	for _, fs := range checkFilesStatus(cfg, files) {
+		if fs.Status != "ok" {
+			summary.Problems++
+		}
+	}
+
+	repoPath, err := config.GetFilesRoot(cfg)
+	if repo := git.OpenRepo(repoPath); err == nil && git.IsAvailable() && repo.IsRepo() {
+		if gitStatus, err := repo.Status(); err == nil {
+			summary.Git = true
+			summary.Uncommitted = gitStatus.HasUncommitted
+			summary.RemoteExists = gitStatus.RemoteExists
+			summary.Ahead = gitStatus.AheadBy
+			summary.Behind = gitStatus.BehindBy
+		}
+	}
+	return summary
+}
+
+// refreshStatusCache updates the banner's status after a mutating command,
+// so the next bare 'dotcor' shows it at once
+func refreshStatusCache() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	if err := core.SaveStatusCache(quickStatus(cfg)); err != nil {
+		log.Debug("saving status cache failed", "error", err)
+	}
+}
+
+// interruptContext returns a context canceled by the first Ctrl+C or
+// SIGTERM, which stops git commands and walks so the running command can
+// roll back and release the lock. A second signal kills dotcor outright.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// silenceOnInterrupt keeps cobra from printing the error and usage of a
+// command stopped by Ctrl+C; main reports it as interrupted instead. A
+// prompt nobody could answer isn't a usage mistake either, so its usage
+// isn't printed.
+func silenceOnInterrupt(cmd *cobra.Command) {
+	run := cmd.RunE
+	if run == nil {
+		return
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		err := run(cmd, args)
+		if err != nil && cmd.Context().Err() != nil {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		}
+		if errors.Is(err, errNoAnswer) {
+			cmd.SilenceUsage = true
+		}
+		return err
+	}
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := interruptContext()
+	git.SetContext(ctx)
+	git.SetCommitCheck(lintBeforeCommit)
+	promptCtx = ctx
+	err := rootCmd.ExecuteContext(ctx)
+	interrupted := ctx.Err() != nil // stop cancels ctx as well
+	stop()
+
+	// Whatever failed, it failed because it was interrupted
+	if err != nil && interrupted {
+		log.Debug("interrupted", "error", err)
+		err = &exitCodeError{code: exitInterrupted, msg: "interrupted"}
+	}
+
+	// Commands that changed something get a closing record in the log file
+	code := exitCode(err)
+	switch {
+	case code == exitProblems:
+		log.Warn("command found problems", "detail", err)
+	case err != nil:
+		log.Error("command failed", "error", err)
+	case log.Recorded():
+		log.Info("command finished")
+	}
+	if core.LockTaken() {
+		// The command's context may be canceled; run the background
+		// refresh's git commands without it
+		git.SetContext(context.Background())
+		refreshStatusCache()
+	}
+	log.Close()
+
+	if err == nil {
+		printUpdateNotice()
+	}
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+
+		// Scripts reading structured output still get a document on failure
+		if output.Structured() && !output.Written() {
+			output.Write(output.Error{Error: err.Error()})
+		}
+
+		os.Exit(code)
 	}
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Export dotfile health as Prometheus metrics",
+	Long: `Report the health of this machine's dotfiles as Prometheus gauges, so
+config drift across machines can be monitored and alerted on.
+
+With --once (the default), the metrics are printed in the Prometheus text
+format and dotcor exits; point node_exporter's textfile collector at the
+output from cron. With --listen, dotcor serves them at /metrics instead,
+checking afresh on every scrape, until interrupted.
+
+Gauges:
+  dotcor_up                    1 if the config could be loaded
+  dotcor_managed_files         Managed files, by scope (home or system)
+  dotcor_files                 Managed files by scope and status (ok, broken, ...)
+  dotcor_broken_symlinks       Symlinks whose target is missing
+  dotcor_uncommitted_changes   Changed, staged and untracked files in the repository
+  dotcor_commits_ahead         Commits not pushed to origin
+  dotcor_commits_behind        Commits on origin not pulled
+  dotcor_remote_configured     1 if the repository has a remote
+  dotcor_merge_in_progress     1 if a merge or rebase needs resolving
+
+Examples:
+  dotcor metrics                          # Print the metrics once
+  dotcor metrics --listen :9111           # Serve them for Prometheus
+  dotcor metrics > /var/lib/node_exporter/textfile/dotcor.prom`,
+	Args:        cobra.NoArgs,
+	Annotations: readOnly,
+	RunE:        runMetrics,
+}
+
+func init() {
+	metricsCmd.Flags().String("listen", "", "Serve metrics over HTTP at this address, e.g. :9111")
+	metricsCmd.Flags().Bool("once", false, "Print the metrics once and exit (default without --listen)")
+	metricsCmd.MarkFlagsMutuallyExclusive("listen", "once")
+	rootCmd.AddCommand(metricsCmd)
+}
+
+func runMetrics(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+
+	if listen == "" {
+		if err := requireInitialized(cmd); err != nil {
+			return err
+		}
+		return metrics.Write(os.Stdout, collectMetrics())
+	}
+	return serveMetrics(cmd.Context(), listen)
+}
+
+// serveMetrics serves the metrics at /metrics on addr until ctx is canceled
+func serveMetrics(ctx context.Context, addr string) error {
+	// Scrapes are serialized so overlapping ones don't check every file twice
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gauges := collectMetrics()
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", metrics.ContentType)
+		metrics.Write(w, gauges)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `<html><body><h1>dotcor</h1><a href="/metrics">Metrics</a></body></html>`)
+	})
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	// Stop cleanly on Ctrl+C or SIGTERM, which cancel ctx
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving metrics at http://%s/metrics. Press Ctrl+C to stop.\n", displayAddr(addr))
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving metrics: %w", err)
+	}
+	fmt.Println("\nStopped serving metrics.")
+	return nil
+}
+
+// displayAddr fills in localhost for a listen address without a host
+func displayAddr(addr string) string {
+	if len(addr) > 0 && addr[0] == ':' {
+		return "localhost" + addr
+	}
+	return addr
+}
+
+// collectMetrics checks every file and the repository and returns the
+// gauges. When the config can't be loaded, only dotcor_up (0) is returned.
+func collectMetrics() []metrics.Gauge {
+	configPath, err := config.GetConfigPath()
+	if err == nil {
+		_, err = os.Stat(configPath)
+	}
+	var cfg *config.Config
+	if err == nil {
+		cfg, err = config.LoadConfig()
+	}
+	if err != nil {
+		return []metrics.Gauge{metrics.NewGauge("dotcor_up", "Whether the dotcor config could be loaded.", 0)}
+	}
+
+	status := collectStatus(cfg)
+
+	// Files by scope and status
+	byStatus := metrics.Gauge{Name: "dotcor_files", Help: "Managed files by scope and status."}
+	broken := 0
+	for _, scope := range []struct {
+		name  string
+		files []FileStatus
+	}{{"home", status.Files}, {"system", status.SystemFiles}} {
+		counts := make(map[string]int)
+		for _, fs := range scope.files {
+			counts[fs.Status]++
+			if fs.Status == "broken" {
+				broken++
+			}
+		}
+		statuses := make([]string, 0, len(counts))
+		for s := range counts {
+			statuses = append(statuses, s)
+		}
+		sort.Strings(statuses)
+		for _, s := range statuses {
+			byStatus.Samples = append(byStatus.Samples, metrics.Sample{
+				Labels: map[string]string{"scope": scope.name, "status": s},
+				Value:  float64(counts[s]),
+			})
+		}
+	}
+
+	gitStatus := status.GitStatus
+	ahead, behind := 0, 0
+	if gitStatus.RemoteExists {
+		ahead, behind = gitStatus.AheadBy, gitStatus.BehindBy
+	}
+
+	return []metrics.Gauge{
+		metrics.NewGauge("dotcor_up", "Whether the dotcor config could be loaded.", 1),
+		{
+			Name: "dotcor_managed_files",
+			Help: "Managed files on this machine, by scope.",
+			Samples: []metrics.Sample{
+				{Labels: map[string]string{"scope": "home"}, Value: float64(status.Statistics.TotalFiles)},
+				{Labels: map[string]string{"scope": "system"}, Value: float64(status.Statistics.SystemFiles)},
+			},
+		},
+		byStatus,
+		metrics.NewGauge("dotcor_broken_symlinks", "Symlinks whose target is missing.", float64(broken)),
+		metrics.NewGauge("dotcor_uncommitted_changes", "Changed, staged and untracked files in the repository.",
+			float64(gitStatus.Staged+gitStatus.Unstaged+gitStatus.Untracked)),
+		metrics.NewGauge("dotcor_commits_ahead", "Commits not pushed to origin.", float64(ahead)),
+		metrics.NewGauge("dotcor_commits_behind", "Commits on origin not pulled yet.", float64(behind)),
+		metrics.NewGauge("dotcor_remote_configured", "Whether the repository has a remote.", boolGauge(gitStatus.RemoteExists)),
+		metrics.NewGauge("dotcor_merge_in_progress", "Whether a merge or rebase in the repository needs resolving.",
+			boolGauge(gitStatus.Merging || gitStatus.Rebasing || gitStatus.Conflicts > 0)),
+	}
+}
+
+// boolGauge converts b to a gauge value
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var migrateHomeCmd = &cobra.Command{
+	Use:   "migrate-home --from <old-home>",
+	Short: "Rewrite paths after the home directory changed",
+	Long: `Fix up dotcor after moving to a different home directory, like restoring a
+Mac's /Users/old onto a Linux machine's /home/new. Paths in config.yaml
+that still name the old home are rewritten, along with the original paths
+of backups, and symlinks that point into the old home are recreated to
+point at the repository. Composed files are regenerated unless edited.
+
+--to defaults to the current home directory. Paths dotcor writes with ~
+follow the home directory by themselves and need no rewriting.
+
+The config of every repository is rewritten; links are recreated for the
+selected one only, so run it again with --repo for each other repository.
+
+Examples:
+  dotcor migrate-home --from /Users/old
+  dotcor migrate-home --from /Users/old --to /home/new --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateHome,
+}
+
+func init() {
+	migrateHomeCmd.Flags().String("from", "", "Old home directory (required)")
+	migrateHomeCmd.Flags().String("to", "", "New home directory (default: the current one)")
+	migrateHomeCmd.Flags().Bool("dry-run", false, "Show what would be done without making changes")
+	rootCmd.AddCommand(migrateHomeCmd)
+}
+
+func runMigrateHome(cmd *cobra.Command, args []string) error {
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	home, err := config.HomeDir()
+	if err != nil {
+		return fmt.Errorf("getting home directory: %w", err)
+	}
+	if to == "" {
+		to = home
+	}
+	if from == "" {
+		return fmt.Errorf("--from is required: the home directory the config was written in")
+	}
+	if !filepath.IsAbs(from) || !filepath.IsAbs(to) {
+		return fmt.Errorf("--from and --to must be absolute paths")
+	}
+	from, to = filepath.Clean(from), filepath.Clean(to)
+	if from == to {
+		return fmt.Errorf("--from and --to are both %s", from)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	// Every repository's section is rewritten, so the root config is
+	moves, err := moveConfigHome(from, to, dryRun)
+	if err != nil {
+		return err
+	}
+	backups, err := moveBackupsHome(from, to, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if cfg, err = config.LoadConfig(); err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if dryRun {
+		cfg.MoveHome(from, to)
+	}
+	tx := core.NewTransaction()
+	if dryRun {
+		tx = core.NewPlanTransaction()
+	}
+	relinked, err := relinkMovedHome(tx, cfg, from, to)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("recreating links: %w", err)
+	}
+	tx.Commit()
+
+	if dryRun {
+		fmt.Println("Dry run - no changes will be made:")
+	}
+	for _, m := range moves {
+		fmt.Printf("  %s: %s → %s\n", m.Field, m.Old, m.New)
+	}
+	if backups > 0 {
+		fmt.Printf("  %d backup path(s) in %s moved\n", backups, from)
+	}
+	for _, step := range tx.Plan() {
+		fmt.Printf("    → %s\n", step)
+	}
+
+	if len(moves) == 0 && backups == 0 && len(relinked) == 0 {
+		fmt.Printf("Nothing refers to %s\n", from)
+		return nil
+	}
+	if !dryRun {
+		fmt.Printf("✓ Moved %d config path(s) and %d backup(s), recreated %d file(s)\n", len(moves), backups, len(relinked))
+	}
+	return nil
+}
+
+// moveConfigHome rewrites the paths in config.yaml that lie in from, in
+// every repository's section, and returns what changed
+func moveConfigHome(from, to string, dryRun bool) ([]config.PathMove, error) {
+	selected := config.SelectedRepo()
+	config.SelectRepo("")
+	defer config.SelectRepo(selected)
+
+	root, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	moves := root.MoveHome(from, to)
+	if len(moves) == 0 || dryRun {
+		return moves, nil
+	}
+	if err := root.SaveConfig(); err != nil {
+		return nil, fmt.Errorf("saving config: %w", err)
+	}
+	return moves, nil
+}
+
+// moveBackupsHome rewrites the original paths of backups that lie in from.
+// A dry run only counts them.
+func moveBackupsHome(from, to string, dryRun bool) (int, error) {
+	if !dryRun {
+		moved, err := core.MoveBackupsHome(from, to)
+		if err != nil {
+			return moved, fmt.Errorf("rewriting backups: %w", err)
+		}
+		return moved, nil
+	}
+
+	backups, err := core.ListBackups()
+	if err != nil {
+		return 0, fmt.Errorf("listing backups: %w", err)
+	}
+	moved := 0
+	for _, b := range backups {
+		if _, ok := config.MovedHomePath(b.SourcePath, from, to); ok {
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// relinkMovedHome recreates, as part of tx, the links of cfg's symlinked
+// files that point into the old home directory, and regenerates unedited
+// composed files that include files there. It returns the files recreated.
+func relinkMovedHome(tx *core.Transaction, cfg *config.Config, from, to string) ([]string, error) {
+	var relinked []string
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		if mf.IsCopy() {
+			continue
+		}
+		resolved, err := config.ResolveVariant(mf)
+		if err != nil {
+			return relinked, err
+		}
+		sourcePath, err := config.ExpandPath(mf.SourcePath)
+		if err != nil {
+			return relinked, err
+		}
+
+		if mf.IsComposed() {
+			stale, err := composedInOldHome(cfg, resolved, sourcePath, from, to)
+			if err != nil {
+				return relinked, err
+			}
+			if !stale {
+				continue
+			}
+			previous, _ := os.ReadFile(sourcePath)
+			err = tx.Execute(&core.StepOp{
+				Desc:     fmt.Sprintf("compose %s from %s", sourcePath, mf.RepoPath),
+				DoFunc:   func() error { return core.DeployComposed(cfg, resolved) },
+				UndoFunc: func() error { return os.WriteFile(sourcePath, previous, 0644) },
+				Deploys:  sourcePath,
+			})
+			if err != nil {
+				return relinked, err
+			}
+			relinked = append(relinked, mf.SourcePath)
+			continue
+		}
+
+		// Relative links moved along with the home directory
+		if isLink, _ := fs.IsSymlink(sourcePath); !isLink {
+			continue
+		}
+		current, err := os.Readlink(sourcePath)
+		if err != nil {
+			return relinked, fmt.Errorf("reading symlink %s: %w", mf.SourcePath, err)
+		}
+		if _, ok := config.MovedHomePath(current, from, to); !ok {
+			continue
+		}
+		target, err := config.GetLinkTargetPath(cfg, resolved)
+		if err != nil {
+			return relinked, err
+		}
+		if err := tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath}); err != nil {
+			return relinked, err
+		}
+		if err := tx.Execute(&core.CreateSymlinkOp{Target: target, Link: sourcePath, Style: cfg.LinkStyle}); err != nil {
+			return relinked, err
+		}
+		relinked = append(relinked, mf.SourcePath)
+	}
+	return relinked, nil
+}
+
+// composedInOldHome reports whether the composed file at sourcePath is
+// dotcor's generated file as it was written in the old home directory
+func composedInOldHome(cfg *config.Config, mf config.ManagedFile, sourcePath, from, to string) (bool, error) {
+	current, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return false, nil
+	}
+	want, err := core.ComposedContent(cfg, mf)
+	if err != nil {
+		return false, err
+	}
+	old := bytes.ReplaceAll(want, []byte(filepath.ToSlash(to)), []byte(filepath.ToSlash(from)))
+	return !bytes.Equal(old, want) && bytes.Equal(current, old), nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <file>",
+	Short: "Move a managed file to another place in the repository",
+	Long: `Move a managed file's repository copy to a new category or path, keeping it
+managed. The repository file is moved, its symlink re-pointed and the
+config updated, and the move is committed as a rename, so Git keeps the
+file's history ('dotcor history' follows it).
+
+The file itself stays where it is. A move can be reverted with 'dotcor undo'.
+
+Examples:
+  dotcor mv ~/.zshrc --category zsh              # shell/zshrc -> zsh/zshrc
+  dotcor mv ~/.config/foo.toml --repo-path apps/foo/config.toml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMv,
+}
+
+func init() {
+	mvCmd.Flags().StringP("category", "c", "", "Move the file into this category, keeping its name")
+	mvCmd.Flags().String("repo-path", "", "Move the file to this path in the repository")
+	mvCmd.Flags().Bool("dry-run", false, "Show what would be done without making changes")
+	rootCmd.AddCommand(mvCmd)
+}
+
+func runMv(cmd *cobra.Command, args []string) error {
+	category, _ := cmd.Flags().GetString("category")
+	newRepoPath, _ := cmd.Flags().GetString("repo-path")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if (category == "") == (newRepoPath == "") {
+		return fmt.Errorf("specify either --category or --repo-path")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	found, err := cfg.GetManagedFile(args[0])
+	if err != nil {
+		return err
+	}
+	mf := *found
+	if len(mf.Variants) > 0 {
+		return fmt.Errorf("%s has per-host variants; move them by editing config.yaml", mf.SourcePath)
+	}
+	if cfg.SharesRepoPath(mf) {
+		return fmt.Errorf("%s shares %s with other managed files; move them by editing config.yaml", mf.SourcePath, mf.RepoPath)
+	}
+
+	if category != "" {
+		newRepoPath = filepath.Join(category, filepath.Base(mf.RepoPath))
+	}
+	newRepoPath = keepRepoExt(mf, filepath.Clean(newRepoPath))
+	if err := core.ValidateRepoPath(newRepoPath); err != nil {
+		return err
+	}
+	if newRepoPath == mf.RepoPath {
+		return fmt.Errorf("%s is already at %s", mf.SourcePath, mf.RepoPath)
+	}
+	if cfg.TrackedRepoPaths()[newRepoPath] {
+		return fmt.Errorf("%s is already used by another managed file", newRepoPath)
+	}
+
+	oldFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+	newFile, err := config.GetRepoFilePath(cfg, newRepoPath)
+	if err != nil {
+		return err
+	}
+	if !fs.FileExists(oldFile) {
+		return fmt.Errorf("repository file missing: %s", mf.RepoPath)
+	}
+	if fs.PathExists(newFile) {
+		return fmt.Errorf("%s already exists in the repository", newRepoPath)
+	}
+
+	if dryRun {
+		fmt.Println("Dry run - no changes will be made:")
+		fmt.Printf("  %s: %s → %s\n", mf.SourcePath, mf.RepoPath, newRepoPath)
+		return nil
+	}
+
+	if err := moveManagedFile(cfg, mf, newRepoPath); err != nil {
+		return fmt.Errorf("moving %s: %w", mf.SourcePath, err)
+	}
+	cleanEmptyDirs(filepath.Dir(oldFile))
+	fmt.Printf("✓ %s: %s → %s\n", mf.SourcePath, mf.RepoPath, newRepoPath)
+
+	if err := core.RecordChecksums(cfg, newRepoPath); err != nil {
+		fmt.Printf("⚠ Recording checksums failed: %v\n", err)
+	}
+
+	if git.IsAvailable() {
+		repoPath, err := config.GetFilesRoot(cfg)
+		if err != nil {
+			fmt.Printf("⚠ Git commit skipped: invalid repo path: %v\n", err)
+			return nil
+		}
+		message := fmt.Sprintf("Move %s to %s", mf.RepoPath, newRepoPath)
+		if err := git.AutoCommit(repoPath, message, userPathspecs...); err != nil {
+			fmt.Printf("⚠ Git commit failed: %v\n", err)
+		} else {
+			fmt.Println("✓ Committed to Git")
+		}
+	}
+	return nil
+}
+
+// keepRepoExt adds the extension that marks mf as a template or secret to
+// repoPath, if it was left off
+func keepRepoExt(mf config.ManagedFile, repoPath string) string {
+	ext := ""
+	switch {
+	case mf.IsTemplate():
+		ext = config.TemplateExt
+	case mf.Encrypted:
+		ext = filepath.Ext(mf.RepoPath)
+	}
+	if ext != "" && !strings.HasSuffix(repoPath, ext) {
+		return repoPath + ext
+	}
+	return repoPath
+}
+
+// moveManagedFile moves mf's repository file to newRepoPath in one
+// transaction: the repo file, its rendered or decrypted copy, the symlink
+// pointing at it and the config entry
+func moveManagedFile(cfg *config.Config, mf config.ManagedFile, newRepoPath string) error {
+	moved := mf
+	moved.RepoPath = newRepoPath
+
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return err
+	}
+	oldFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+	newFile, err := config.GetRepoFilePath(cfg, newRepoPath)
+	if err != nil {
+		return err
+	}
+	oldTarget, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return err
+	}
+	newTarget, err := config.GetLinkTargetPath(cfg, moved)
+	if err != nil {
+		return err
+	}
+
+	tx := core.NewTransaction()
+	if err := tx.Execute(&core.MoveFileOp{Src: oldFile, Dst: newFile}); err != nil {
+		return err
+	}
+
+	// Templates and secrets are linked to a copy named after the repo path
+	if oldTarget != oldFile && fs.FileExists(oldTarget) {
+		if err := tx.Execute(&core.MoveFileOp{Src: oldTarget, Dst: newTarget}); err != nil {
+			return err
+		}
+	}
+
+	// Copies and hard links don't point at the path; a rename keeps a hard link
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		if err := tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath}); err != nil {
+			return err
+		}
+		if err := tx.Execute(&core.CreateSymlinkOp{Target: newTarget, Link: sourcePath, Style: cfg.LinkStyle}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Execute(&core.RemoveFromConfigOp{Config: cfg, SourcePath: mf.SourcePath}); err != nil {
+		return err
+	}
+	if err := tx.Execute(&core.AddToConfigOp{Config: cfg, File: moved}); err != nil {
+		return err
+	}
+	tx.Commit()
+	return nil
+}
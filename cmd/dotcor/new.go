@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/skeleton"
+	"github.com/spf13/cobra"
+)
+
+var newCmd = &cobra.Command{
+	Use:   "new [path]",
+	Short: "Create a new dotfile from a skeleton and manage it",
+	Long: `Create a dotfile you don't have yet from a skeleton, such as a commented
+starship.toml or a minimal tmux.conf, then add it like 'dotcor add' and
+commit it.
+
+The skeleton is named with --template, or found from the file name of
+path. Built-in skeletons know where their file goes, so path can be left
+out. Your own skeletons go in the skeletons directory next to
+config.yaml, one file each, named after the file without its extension;
+one named like a built-in skeleton replaces it.
+
+An existing file is never overwritten; use 'dotcor add' for it.
+
+Examples:
+  dotcor new --list                          # Show the skeletons
+  dotcor new ~/.tmux.conf                    # Skeleton found from the name
+  dotcor new --template starship             # Created at ~/.config/starship.toml
+  dotcor new ~/.config/alacritty/alacritty.toml --template alacritty`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runNew,
+}
+
+func init() {
+	newCmd.Flags().StringP("template", "t", "", "Skeleton to start from (see --list)")
+	newCmd.Flags().Bool("list", false, "List the available skeletons")
+	newCmd.Flags().StringP("category", "c", "", "Override automatic category detection")
+	rootCmd.AddCommand(newCmd)
+}
+
+func runNew(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("template")
+	list, _ := cmd.Flags().GetBool("list")
+	category, _ := cmd.Flags().GetString("category")
+
+	if list {
+		return listSkeletons()
+	}
+
+	skel, err := chooseSkeleton(name, args)
+	if err != nil {
+		return err
+	}
+	path := skel.Path
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("skeleton %s has no default location, give the path to create", skel.Name)
+	}
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	sourcePath, err := config.NormalizePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	expanded, err := config.ExpandPath(sourcePath)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	if cfg.IsManaged(sourcePath) {
+		return fmt.Errorf("%s is already managed", sourcePath)
+	}
+	if _, err := os.Lstat(expanded); err == nil {
+		return fmt.Errorf("%s already exists; use 'dotcor add %s' to manage it", sourcePath, sourcePath)
+	}
+	content, err := skel.Content()
+	if err != nil {
+		return err
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	if err := runHooks(cmd, cfg, config.HookPreAdd); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(expanded), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(expanded, content, 0644); err != nil {
+		return fmt.Errorf("creating %s: %w", sourcePath, err)
+	}
+	fmt.Printf("✓ Created %s from skeleton %s\n", sourcePath, skel.Name)
+
+	out, err := addFiles(cmd, cfg, []string{expanded}, addOptions{category: category})
+	if err != nil {
+		return err
+	}
+	if out.Added == 0 {
+		// Nothing of the new file is left behind if it couldn't be added
+		if err := os.Remove(expanded); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing %s: %w", sourcePath, err)
+		}
+		return fmt.Errorf("%s could not be added, so it was removed", sourcePath)
+	}
+	return nil
+}
+
+// chooseSkeleton returns the skeleton named name, or the one matching the
+// file name of the path in args
+func chooseSkeleton(name string, args []string) (skeleton.Skeleton, error) {
+	if name != "" {
+		skel, err := skeleton.Get(name)
+		if err != nil {
+			return skel, fmt.Errorf("%w (see 'dotcor new --list')", err)
+		}
+		return skel, nil
+	}
+	if len(args) == 0 {
+		return skeleton.Skeleton{}, fmt.Errorf("give a path or --template (see 'dotcor new --list')")
+	}
+	skel, ok := skeleton.ForPath(args[0])
+	if !ok {
+		return skel, fmt.Errorf("no skeleton for %s, name one with --template (see 'dotcor new --list')", filepath.Base(args[0]))
+	}
+	return skel, nil
+}
+
+// listSkeletons prints the available skeletons
+func listSkeletons() error {
+	skeletons, err := skeleton.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range skeletons {
+		path := s.Path
+		if path == "" {
+			path = "-"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", s.Name, path, s.Description)
+	}
+	w.Flush()
+
+	if dir, err := skeleton.UserDir(); err == nil {
+		fmt.Printf("\nAdd your own in %s\n", dir)
+	}
+	return nil
+}
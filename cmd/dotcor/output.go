@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/justincordova/dotcor/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// structuredOutput marks commands that support --output json|yaml
+var structuredOutput = map[string]string{"output": "structured"}
+
+// selectOutput applies the --output flag, rejecting structured formats for
+// commands that only print text
+func selectOutput(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("output")
+	if err := output.Validate(format); err != nil {
+		return err
+	}
+	if format != "" && format != output.FormatPlain && cmd.Annotations["output"] != "structured" {
+		return fmt.Errorf("'%s' doesn't support --output %s", cmd.CommandPath(), format)
+	}
+	return output.SetFormat(format)
+}
+
+// writeResult writes a command's result when a structured format is selected
+func writeResult(v any) error {
+	if !output.Structured() {
+		return nil
+	}
+	return output.Write(v)
+}
+
+// fileOutcome is one file in the structured output of add and remove
+type fileOutcome struct {
+	Path   string `json:"path" yaml:"path"`
+	Repo   string `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Result string `json:"result" yaml:"result"` // added, removed, skipped or failed
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
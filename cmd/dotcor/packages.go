@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/output"
+	"github.com/justincordova/dotcor/internal/packages"
+	"github.com/spf13/cobra"
+)
+
+var packagesCmd = &cobra.Command{
+	Use:   "packages",
+	Short: "Check and install the packages your dotfiles need",
+	Long: `Check for and install the system packages declared in config.yaml.
+
+Packages are listed per package manager:
+
+  packages:
+    brew: [neovim, starship]
+    apt: [neovim, zsh]
+    cargo: [ripgrep, bat]
+
+Managers that aren't installed on this machine are skipped, so one list can
+serve macOS and Linux machines alike. On a new machine, run
+'dotcor clone <url> --apply --packages' to set up files and packages together.
+
+Examples:
+  dotcor packages check              # Report missing packages
+  dotcor packages install            # Install missing packages
+  dotcor packages install --dry-run  # Show the install commands`,
+}
+
+var packagesCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report declared packages that aren't installed",
+	Long: `Report declared packages that aren't installed.
+
+Exit codes:
+  0  Every package is installed
+  1  The check could not run
+  2  Packages are missing`,
+	Args:        cobra.NoArgs,
+	RunE:        runPackagesCheck,
+	Annotations: annotations(structuredOutput, readOnly),
+}
+
+var packagesInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install declared packages that are missing",
+	Args:  cobra.NoArgs,
+	RunE:  runPackagesInstall,
+}
+
+func init() {
+	packagesInstallCmd.Flags().Bool("dry-run", false, "Show the install commands without running them")
+	packagesCmd.AddCommand(packagesCheckCmd, packagesInstallCmd)
+	rootCmd.AddCommand(packagesCmd)
+}
+
+func runPackagesCheck(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	statuses, err := packages.Check(cfg.Packages)
+	if err != nil {
+		return err
+	}
+
+	missing := 0
+	for _, status := range statuses {
+		missing += len(status.Missing)
+	}
+
+	if output.Structured() {
+		if statuses == nil {
+			statuses = []packages.Status{}
+		}
+		if err := writeResult(statuses); err != nil {
+			return err
+		}
+	} else {
+		printPackageStatuses(statuses)
+	}
+
+	if missing > 0 {
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return &exitCodeError{
+			code: exitProblems,
+			msg:  fmt.Sprintf("%d package(s) missing", missing),
+		}
+	}
+	return nil
+}
+
+// printPackageStatuses prints each manager's installed and missing packages
+func printPackageStatuses(statuses []packages.Status) {
+	if len(statuses) == 0 {
+		fmt.Println("No packages declared in config.yaml.")
+		return
+	}
+
+	for _, status := range statuses {
+		fmt.Printf("%s:\n", status.Manager)
+		if !status.Available {
+			fmt.Printf("  - %s is not installed on this machine; skipping %s\n", status.Manager, strings.Join(status.Skipped, ", "))
+			continue
+		}
+		for _, pkg := range status.Installed {
+			fmt.Printf("  ✓ %s\n", pkg)
+		}
+		for _, pkg := range status.Missing {
+			fmt.Printf("  ✗ %s\n", pkg)
+		}
+	}
+}
+
+func runPackagesInstall(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	return installPackages(cfg, dryRun)
+}
+
+// installPackages installs the declared packages missing from this
+// machine, skipping managers that aren't installed
+func installPackages(cfg *config.Config, dryRun bool) error {
+	statuses, err := packages.Check(cfg.Packages)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		fmt.Println("No packages declared in config.yaml.")
+		return nil
+	}
+
+	installed, skipped := 0, 0
+	var failed []string
+	for _, status := range statuses {
+		if !status.Available {
+			fmt.Printf("⚠ %s is not installed; skipping %s\n", status.Manager, strings.Join(status.Skipped, ", "))
+			skipped += len(status.Skipped)
+			continue
+		}
+		if len(status.Missing) == 0 {
+			fmt.Printf("✓ %s: all %d package(s) installed\n", status.Manager, len(status.Installed))
+			continue
+		}
+
+		m, _ := packages.Lookup(status.Manager)
+		if dryRun {
+			fmt.Printf("Would run: %s\n", strings.Join(m.InstallCommand(status.Missing), " "))
+			continue
+		}
+
+		fmt.Printf("→ Installing with %s: %s\n", status.Manager, strings.Join(status.Missing, ", "))
+		if err := m.Install(status.Missing, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			failed = append(failed, status.Manager)
+			continue
+		}
+		installed += len(status.Missing)
+	}
+
+	if dryRun {
+		fmt.Println("\n(dry run - nothing was installed)")
+		return nil
+	}
+
+	fmt.Printf("\n✓ Installed %d package(s)", installed)
+	if skipped > 0 {
+		fmt.Printf(", skipped %d", skipped)
+	}
+	fmt.Println()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("installing packages failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
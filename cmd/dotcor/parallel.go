@@ -0,0 +1,44 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// maxCheckWorkers bounds the goroutines checking managed files at once.
+// The checks are mostly lstat and readlink calls, which on a network home
+// directory spend their time waiting, so more workers than CPUs pay off.
+const maxCheckWorkers = 32
+
+// parallelMap calls fn on each item from a bounded pool of workers and
+// returns the results in the order of items. fn must be safe to call
+// concurrently.
+func parallelMap[T, R any](items []T, fn func(T) R) []R {
+	results := make([]R, len(items))
+
+	workers := min(4*runtime.GOMAXPROCS(0), maxCheckWorkers, len(items))
+	if workers <= 1 {
+		for i, item := range items {
+			results[i] = fn(item)
+		}
+		return results
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fn(items[i])
+			}
+		}()
+	}
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var pathCmd = &cobra.Command{
+	Use:   "path [file]",
+	Short: "Print where a managed file lives in the repository",
+	Long: `Print the full repository path of a managed file, or of the dotfiles
+directory when no file is given. Per-host variants resolve to the file
+this machine uses.
+
+Examples:
+  dotcor path                    # ~/.dotcor/files
+  dotcor path ~/.zshrc           # ~/.dotcor/files/shell/zshrc
+  dotcor path --dir ~/.zshrc     # ~/.dotcor/files/shell
+  $EDITOR "$(dotcor path ~/.zshrc)"
+
+See 'dotcor shell-init' for a dcd function that changes into these directories.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: readOnly,
+	RunE:        runPath,
+}
+
+func init() {
+	pathCmd.Flags().Bool("dir", false, "Print the directory containing the file")
+	rootCmd.AddCommand(pathCmd)
+}
+
+func runPath(cmd *cobra.Command, args []string) error {
+	dirFlag, _ := cmd.Flags().GetBool("dir")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if len(args) == 0 {
+		root, err := config.GetFilesRoot(cfg)
+		if err != nil {
+			return fmt.Errorf("expanding repo path: %w", err)
+		}
+		fmt.Println(root)
+		return nil
+	}
+
+	managed, err := cfg.GetManagedFile(args[0])
+	if err != nil {
+		return fmt.Errorf("file not managed: %s", args[0])
+	}
+	mf, err := config.ResolveVariant(*managed)
+	if err != nil {
+		return err
+	}
+
+	repoFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+	if dirFlag {
+		repoFile = filepath.Dir(repoFile)
+	}
+	fmt.Println(repoFile)
+	return nil
+}
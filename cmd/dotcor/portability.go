@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/lint"
+	"github.com/spf13/cobra"
+)
+
+var portabilityFix bool
+
+var portabilityCmd = &cobra.Command{
+	Use:   "portability [file]...",
+	Short: "Find home directories hardcoded in managed files",
+	Long: `Look for home directories written out in full, like /Users/jane or
+/home/jane, in every managed file or only the given files. They break the
+file on a machine with another user name or OS.
+
+What to use instead depends on the file:
+
+  templates (.tmpl)  {{ .Home }}
+  shell files        $HOME
+  gitconfig          ~ (at the start of a value)
+  anything else      make it a template and use {{ .Home }}
+
+With --fix, your own home directory is replaced where that's safe: in
+templates, in shell files outside single quotes, and at the start of
+gitconfig values. The changes are redeployed and committed. Other users'
+home directories and other file types are left for you to fix.
+
+Secrets are not checked.
+
+Exit codes:
+  0  No hardcoded home directories
+  1  The check could not run
+  2  Hardcoded home directories found (after --fix, ones that remain)
+
+Examples:
+  dotcor portability               # Check every managed file
+  dotcor portability ~/.zshrc      # Check one file
+  dotcor portability --fix         # Replace the ones that can be fixed`,
+	Annotations: structuredOutput,
+	RunE:        runPortability,
+}
+
+func init() {
+	portabilityCmd.Flags().BoolVar(&portabilityFix, "fix", false, "Replace your home directory with $HOME, ~ or {{ .Home }} where safe")
+	rootCmd.AddCommand(portabilityCmd)
+}
+
+// portabilityOutput is the structured output of 'dotcor portability'
+type portabilityOutput struct {
+	Files   int               `json:"files" yaml:"files"`
+	Fixed   int               `json:"fixed" yaml:"fixed"`
+	Results []portabilityFile `json:"results" yaml:"results"`
+}
+
+// portabilityTarget is a file to check, with the managed file it belongs
+// to (nil for files dotcor doesn't manage)
+type portabilityTarget struct {
+	lintTarget
+	mf *config.ManagedFile
+}
+
+// portabilityFile is the hardcoded home directories left in one file
+type portabilityFile struct {
+	Path  string          `json:"path" yaml:"path"`
+	Found []lint.HomePath `json:"found" yaml:"found"`
+}
+
+func runPortability(cmd *cobra.Command, args []string) error {
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	user, err := lint.UserName()
+	if err != nil {
+		return err
+	}
+
+	var targets []portabilityTarget
+	if len(args) > 0 {
+		targets, err = portabilityArgTargets(cfg, args)
+	} else {
+		targets, err = portabilityManagedTargets(cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	if portabilityFix {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	out := portabilityOutput{Results: []portabilityFile{}}
+	var fixed []config.ManagedFile
+	for _, target := range targets {
+		if err := cmd.Context().Err(); err != nil {
+			return err
+		}
+		content, err := os.ReadFile(target.path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", target.repoPath, err)
+		}
+		out.Files++
+
+		if portabilityFix {
+			n, err := fixHomePaths(cfg, target, content, user)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				out.Fixed += n
+				fixed = append(fixed, *target.mf)
+				if content, err = os.ReadFile(target.path); err != nil {
+					return fmt.Errorf("reading %s: %w", target.repoPath, err)
+				}
+			}
+		}
+
+		if found := lint.FindHomePaths(target.repoPath, content, user); len(found) > 0 {
+			out.Results = append(out.Results, portabilityFile{Path: target.repoPath, Found: found})
+		}
+	}
+
+	if len(fixed) > 0 {
+		if err := commitPortabilityFixes(cfg, fixed, out.Fixed); err != nil {
+			return err
+		}
+	}
+
+	remaining := 0
+	for _, result := range out.Results {
+		for _, h := range result.Found {
+			fmt.Printf("  ✗ %s:%d %s, use %s\n", result.Path, h.Line, h.Path, h.Suggest)
+			remaining++
+		}
+	}
+	if remaining > 0 {
+		fmt.Println()
+	}
+
+	if err := writeResult(out); err != nil {
+		return err
+	}
+
+	if remaining == 0 {
+		fmt.Printf("✓ No hardcoded home directories in %d file(s)\n", out.Files)
+		return nil
+	}
+	if !portabilityFix {
+		fmt.Println("Run 'dotcor portability --fix' to replace the ones that can be fixed")
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &exitCodeError{
+		code: exitProblems,
+		msg:  fmt.Sprintf("found %d hardcoded home path(s)", remaining),
+	}
+}
+
+// portabilityManagedTargets returns the repo files of every managed file
+// that can be read as is, skipping secrets
+func portabilityManagedTargets(cfg *config.Config) ([]portabilityTarget, error) {
+	var targets []portabilityTarget
+	for _, files := range [][]config.ManagedFile{cfg.ManagedFiles, cfg.SystemFiles} {
+		for i := range files {
+			mf := &files[i]
+			if mf.Encrypted {
+				continue
+			}
+			path, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid repo path %s: %w", mf.RepoPath, err)
+			}
+			targets = append(targets, portabilityTarget{lintTarget{path: path, repoPath: mf.RepoPath}, mf})
+		}
+	}
+	return targets, nil
+}
+
+// portabilityArgTargets returns the files to check for the paths given on
+// the command line. Only managed files can be fixed.
+func portabilityArgTargets(cfg *config.Config, args []string) ([]portabilityTarget, error) {
+	var targets []portabilityTarget
+	for _, arg := range args {
+		normalized, err := config.NormalizePath(arg)
+		if err != nil {
+			normalized = arg
+		}
+		if mf, err := cfg.GetManagedFile(normalized); err == nil {
+			if mf.Encrypted {
+				return nil, fmt.Errorf("%s is a secret, which can't be checked", arg)
+			}
+			path, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid repo path %s: %w", mf.RepoPath, err)
+			}
+			targets = append(targets, portabilityTarget{lintTarget{path: path, repoPath: mf.RepoPath}, mf})
+			continue
+		}
+		if portabilityFix {
+			return nil, fmt.Errorf("%s is not managed by dotcor, only managed files can be fixed", arg)
+		}
+
+		path, err := config.ExpandPath(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", arg, err)
+		}
+		targets = append(targets, portabilityTarget{lintTarget: lintTarget{path: path, repoPath: arg}})
+	}
+	return targets, nil
+}
+
+// fixHomePaths rewrites the repo file of target with its fixable home
+// directories replaced and redeploys it, returning how many were replaced
+func fixHomePaths(cfg *config.Config, target portabilityTarget, content []byte, user string) (int, error) {
+	fixedContent, n := lint.FixHomePaths(target.repoPath, content, user)
+	if n == 0 {
+		return 0, nil
+	}
+	mf := target.mf
+
+	// A local copy or broken hard link with its own edits must not be
+	// overwritten afterwards
+	inSync := true
+	switch {
+	case mf.IsCopy():
+		edited, err := core.CopyDiffers(cfg, *mf)
+		if err != nil {
+			return 0, err
+		}
+		inSync = !edited
+	case mf.IsHardlink():
+		inSync, _ = core.HardlinkIntact(cfg, *mf)
+	}
+
+	// Written in place, so the file keeps its mode and hard links
+	if err := os.WriteFile(target.path, fixedContent, 0644); err != nil {
+		return 0, fmt.Errorf("writing %s: %w", target.repoPath, err)
+	}
+	fmt.Printf("✓ Replaced %d home path(s) in %s\n", n, target.repoPath)
+	if err := redeployEdited(cfg, *mf, inSync); err != nil {
+		fmt.Printf("⚠ %v\n", err)
+	}
+	return n, nil
+}
+
+// commitPortabilityFixes records the checksums of the fixed files and
+// commits them
+func commitPortabilityFixes(cfg *config.Config, fixed []config.ManagedFile, n int) error {
+	repoPaths := make([]string, 0, len(fixed))
+	pathspecs := make([]string, 0, len(fixed))
+	for _, mf := range fixed {
+		repoPaths = append(repoPaths, mf.RepoPath)
+		pathspecs = append(pathspecs, filepath.ToSlash(mf.RepoPath))
+	}
+	recordChecksums(cfg, repoPaths...)
+
+	if !git.IsAvailable() {
+		return nil
+	}
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+	message := fmt.Sprintf("Replace %d hardcoded home path(s)", n)
+	if err := git.AutoCommit(filesRoot, message, pathspecs...); err != nil {
+		return fmt.Errorf("committing: %w", err)
+	}
+	fmt.Println("✓ Committed to Git")
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+
+	"github.com/justincordova/dotcor/internal/progress"
+)
+
+// minBarItems is the fewest files worth a progress bar
+const minBarItems = 10
+
+// progressEnabled reports whether spinners and bars are drawn. They go to
+// stderr, and only when it is a terminal that can redraw a line.
+func progressEnabled() bool {
+	return stderrIsTerminal() && os.Getenv("TERM") != "dumb"
+}
+
+// startSpinner shows label with a spinner until Stop is called
+func startSpinner(label string) *progress.Spinner {
+	return progress.StartSpinner(os.Stderr, label, progressEnabled())
+}
+
+// startBar shows a progress bar for total files until Finish is called.
+// Short runs get none, their output is feedback enough.
+func startBar(label string, total int) *progress.Bar {
+	return progress.StartBar(os.Stderr, label, total, total >= minBarItems && progressEnabled())
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// nonInteractiveEnv enables non-interactive mode when set to a true value
+const nonInteractiveEnv = "DOTCOR_NONINTERACTIVE"
+
+// stdinReader is shared so answers typed ahead to several prompts aren't lost
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// promptCtx cancels a prompt waiting for an answer, so Ctrl+C at a prompt
+// stops the command like anywhere else
+var promptCtx = context.Background()
+
+// errNoAnswer is wrapped by the errors of prompts that couldn't be answered
+var errNoAnswer = errors.New("no answer")
+
+// nonInteractive is set by --yes, --non-interactive or $DOTCOR_NONINTERACTIVE.
+// Prompts then answer yes, or take their default, without reading stdin.
+var nonInteractive bool
+
+// selectInteraction applies --yes and --non-interactive
+func selectInteraction(cmd *cobra.Command) {
+	yes, _ := cmd.Flags().GetBool("yes")
+	flag, _ := cmd.Flags().GetBool("non-interactive")
+	env, _ := strconv.ParseBool(os.Getenv(nonInteractiveEnv))
+	nonInteractive = yes || flag || env
+}
+
+// confirm asks a yes/no question, def being the answer to an empty reply.
+// In non-interactive mode the answer is yes.
+func confirm(question string, def bool) (bool, error) {
+	choices := "[y/N]"
+	if def {
+		choices = "[Y/n]"
+	}
+	if nonInteractive {
+		fmt.Printf("%s %s: y\n", question, choices)
+		return true, nil
+	}
+
+	fmt.Printf("%s %s: ", question, choices)
+	input, err := readAnswer(question)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(input) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// ask prints prompt and returns the answer, or def for an empty reply. In
+// non-interactive mode it returns def, failing if there is none.
+func ask(prompt, def string) (string, error) {
+	if nonInteractive {
+		if def == "" {
+			return "", fmt.Errorf("%w: %q needs an answer, which can't be given in non-interactive mode", errNoAnswer, promptText(prompt))
+		}
+		fmt.Printf("%s%s\n", prompt, def)
+		return def, nil
+	}
+
+	fmt.Print(prompt)
+	input, err := readAnswer(prompt)
+	if err != nil {
+		return "", err
+	}
+	if input == "" {
+		return def, nil
+	}
+	return input, nil
+}
+
+// readAnswer reads one line from stdin. Stdin that isn't a terminal, or
+// running out of input before any answer, is an error rather than an empty
+// answer, so a command run from a script or cron job neither hangs nor acts
+// on defaults nobody chose.
+func readAnswer(prompt string) (string, error) {
+	if !stdinIsTerminal() {
+		fmt.Println()
+		return "", noAnswerError(prompt)
+	}
+
+	type answer struct {
+		input string
+		err   error
+	}
+	answers := make(chan answer, 1)
+	go func() {
+		input, err := stdinReader.ReadString('\n')
+		answers <- answer{input, err}
+	}()
+
+	var input string
+	var err error
+	select {
+	case <-promptCtx.Done():
+		fmt.Println()
+		return "", promptCtx.Err()
+	case a := <-answers:
+		input, err = a.input, a.err
+	}
+	if errors.Is(err, io.EOF) && input == "" {
+		fmt.Println()
+		return "", noAnswerError(prompt)
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// noAnswerError explains that prompt can't be answered without a terminal
+func noAnswerError(prompt string) error {
+	return fmt.Errorf("%w to %q: stdin is closed or not a terminal\nUse --yes to answer prompts automatically", errNoAnswer, promptText(prompt))
+}
+
+// promptText strips the choices and trailing colon from a prompt for use
+// in error messages
+func promptText(prompt string) string {
+	prompt = strings.TrimSpace(prompt)
+	prompt = strings.TrimSuffix(prompt, ":")
+	if i := strings.LastIndex(prompt, " ["); i > 0 {
+		prompt = prompt[:i]
+	}
+	return prompt
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var promptStatusCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print a one-token status for shell prompts",
+	Long: `Print the status as a single short token for a shell prompt:
+
+  ✓    Everything is healthy and committed
+  3!   3 files have problems
+  +    Uncommitted changes in the repository
+  ↑2   2 commits to push
+  ↓1   1 commit to pull
+
+Tokens are combined, e.g. "3!+↑2". The status comes from the cache the
+banner uses, so no file or git checks run while the prompt draws. When the
+cache is stale nothing is printed and it is refreshed in the background,
+for the next prompt; --fresh checks right away instead. Nothing is printed
+before dotcor is initialized.
+
+'dotcor shell-init' defines dotcor_prompt, which adds brackets and prints
+nothing when there's no status:
+  bash  PS1='\w$(dotcor_prompt) \$ '
+  zsh   setopt PROMPT_SUBST; PROMPT='%~$(dotcor_prompt) %# '
+
+For starship, in starship.toml:
+  [custom.dotcor]
+  command = "dotcor prompt"
+  when = true`,
+	Args:        cobra.NoArgs,
+	Annotations: readOnly,
+	RunE:        runPromptStatus,
+}
+
+func init() {
+	promptStatusCmd.Flags().Bool("fresh", false, "Check afresh when the cache is stale instead of in the background")
+	rootCmd.AddCommand(promptStatusCmd)
+}
+
+func runPromptStatus(cmd *cobra.Command, args []string) error {
+	fresh, _ := cmd.Flags().GetBool("fresh")
+
+	// A prompt has no room for errors, before init or otherwise
+	if path, err := config.GetConfigPath(); err != nil || !fs.FileExists(path) {
+		return nil
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil
+	}
+
+	summary, ok := core.LoadStatusCache(cfg)
+	switch {
+	case ok:
+	case fresh:
+		summary = loadStatusSummary(cfg)
+	default:
+		refreshInBackground()
+		return nil
+	}
+	fmt.Println(promptToken(summary))
+	return nil
+}
+
+// promptToken condenses a status summary into one token, "✓" when there's
+// nothing to report
+func promptToken(summary core.StatusSummary) string {
+	var b strings.Builder
+	if summary.Problems > 0 {
+		fmt.Fprintf(&b, "%d!", summary.Problems)
+	}
+	if summary.Git {
+		if summary.Uncommitted {
+			b.WriteString("+")
+		}
+		if summary.Ahead > 0 {
+			fmt.Fprintf(&b, "↑%d", summary.Ahead)
+		}
+		if summary.Behind > 0 {
+			fmt.Fprintf(&b, "↓%d", summary.Behind)
+		}
+	}
+	if b.Len() == 0 {
+		return "✓"
+	}
+	return b.String()
+}
+
+// refreshInBackground starts 'dotcor summary' without waiting for it, so
+// the status cache is fresh for the next prompt. Prompts drawn while it
+// runs don't start another.
+func refreshInBackground() {
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	claimed, err := core.ClaimStatusRefresh()
+	if err != nil {
+		log.Debug("claiming status refresh failed", "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	args := []string{"summary"}
+	if name := config.SelectedRepo(); name != "" {
+		args = append(args, "--repo", name)
+	}
+	refresh := exec.Command(exe, args...)
+	if err := refresh.Start(); err != nil {
+		log.Debug("starting status refresh failed", "error", err)
+		core.SetStatusRefreshPID(0)
+		return
+	}
+	if err := core.SetStatusRefreshPID(refresh.Process.Pid); err != nil {
+		log.Debug("recording status refresh failed", "error", err)
+	}
+	refresh.Process.Release()
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -62,7 +62,7 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get repo path
-	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	repoPath, err := config.GetFilesRoot(cfg)
 	if err != nil {
 		return fmt.Errorf("expanding repo path: %w", err)
 	}
@@ -75,6 +75,10 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 		return verifyConfig(cfg, repoPath)
 	}
 
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+
 	return scanAndRebuild(cfg, repoPath, force)
 }
 
@@ -84,10 +88,7 @@ func verifyConfig(cfg *config.Config, repoPath string) error {
 	fmt.Println("")
 
 	// Build set of tracked repo paths
-	tracked := make(map[string]bool)
-	for _, mf := range cfg.ManagedFiles {
-		tracked[mf.RepoPath] = true
-	}
+	tracked := cfg.TrackedRepoPaths()
 
 	// Find files in repo
 	repoFiles, err := scanRepoFiles(repoPath)
@@ -100,12 +101,13 @@ func verifyConfig(cfg *config.Config, repoPath string) error {
 	var orphaned []string   // In repo but not in config
 
 	// Check each tracked file exists in repo
-	for _, mf := range cfg.ManagedFiles {
-		fullPath := filepath.Join(repoPath, mf.RepoPath)
+	for repoFile := range tracked {
+		fullPath := filepath.Join(repoPath, repoFile)
 		if !fs.FileExists(fullPath) {
-			missing = append(missing, mf.RepoPath)
+			missing = append(missing, repoFile)
 		}
 	}
+	sort.Strings(missing)
 
 	// Check each repo file is tracked
 	for _, repoFile := range repoFiles {
@@ -117,7 +119,7 @@ func verifyConfig(cfg *config.Config, repoPath string) error {
 	// Report
 	if len(missing) == 0 && len(orphaned) == 0 {
 		fmt.Println("✓ Configuration matches repository")
-		fmt.Printf("  %d file(s) tracked\n", len(cfg.ManagedFiles))
+		fmt.Printf("  %d file(s) tracked\n", len(tracked))
 		return nil
 	}
 
@@ -147,10 +149,7 @@ func scanAndRebuild(cfg *config.Config, repoPath string, force bool) error {
 	fmt.Println("")
 
 	// Build set of already tracked paths
-	tracked := make(map[string]bool)
-	for _, mf := range cfg.ManagedFiles {
-		tracked[mf.RepoPath] = true
-	}
+	tracked := cfg.TrackedRepoPaths()
 
 	// Find files in repo
 	repoFiles, err := scanRepoFiles(repoPath)
@@ -179,13 +178,11 @@ func scanAndRebuild(cfg *config.Config, repoPath string, force bool) error {
 
 	// Confirmation
 	if !force {
-		fmt.Printf("Add %d file(s) to configuration? [y/N]: ", len(untracked))
-
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
-
-		if input != "y" && input != "yes" {
+		ok, err := confirm(fmt.Sprintf("Add %d file(s) to configuration?", len(untracked)), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Cancelled.")
 			return nil
 		}
@@ -200,6 +197,20 @@ func scanAndRebuild(cfg *config.Config, repoPath string, force bool) error {
 	// Add files to config
 	added := 0
 	for _, repoFile := range untracked {
+		// Files under system/ mirror their absolute location
+		if sourcePath, ok := systemSourcePath(repoFile); ok {
+			cfg.SystemFiles = append(cfg.SystemFiles, config.ManagedFile{
+				SourcePath: sourcePath,
+				RepoPath:   repoFile,
+				AddedAt:    time.Now(),
+				Platforms:  []string{config.GetCurrentPlatform()},
+				Scope:      config.ScopeSystem,
+			})
+			added++
+			fmt.Printf("  ✓ Added %s → %s (system)\n", repoFile, sourcePath)
+			continue
+		}
+
 		// Generate source path from repo path
 		sourcePath := generateSourcePath(repoFile)
 
@@ -224,9 +235,9 @@ func scanAndRebuild(cfg *config.Config, repoPath string, force bool) error {
 	fmt.Printf("Added %d file(s) to configuration.\n", added)
 
 	// Git commit
-	if git.IsGitInstalled() && added > 0 {
+	if git.IsAvailable() && added > 0 {
 		message := fmt.Sprintf("Rebuild config: add %d file(s)", added)
-		if err := git.AutoCommit(repoPath, message); err != nil {
+		if err := git.AutoCommit(repoPath, message, userPathspecs...); err != nil {
 			fmt.Printf("⚠ Git commit failed: %v\n", err)
 		} else {
 			fmt.Println("✓ Committed to Git")
@@ -236,6 +247,15 @@ func scanAndRebuild(cfg *config.Config, repoPath string, force bool) error {
 	return nil
 }
 
+// systemSourcePath returns the absolute source path for a repo file under system/
+func systemSourcePath(repoFile string) (string, bool) {
+	prefix := config.SystemRepoDir + string(filepath.Separator)
+	if !strings.HasPrefix(repoFile, prefix) {
+		return "", false
+	}
+	return string(filepath.Separator) + strings.TrimPrefix(repoFile, prefix), true
+}
+
 // scanRepoFiles scans the repository for managed files
 func scanRepoFiles(repoPath string) ([]string, error) {
 	var files []string
@@ -251,17 +271,22 @@ func scanRepoFiles(repoPath string) ([]string, error) {
 			if info.Name() == ".git" {
 				return filepath.SkipDir
 			}
+			// Skip the captured macOS preferences, which aren't managed files
+			if relPath, err := filepath.Rel(repoPath, path); err == nil && config.IsDataRepoPath(relPath) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		// Skip config.yaml
-		if info.Name() == "config.yaml" {
+		// Skip config.yaml, worktree/submodule .git link files and the
+		// .gitattributes written for Git LFS
+		if info.Name() == "config.yaml" || info.Name() == ".git" || info.Name() == ".gitattributes" {
 			return nil
 		}
 
 		// Get relative path
 		relPath, err := filepath.Rel(repoPath, path)
-		if err != nil {
+		if err != nil || config.IsDataRepoPath(relPath) {
 			return nil
 		}
 
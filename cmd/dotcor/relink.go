@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/crypto"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var relinkCmd = &cobra.Command{
+	Use:   "relink <old-source> <new-source>",
+	Short: "Deploy a managed file at a new location",
+	Long: `Move where a managed file is deployed, for when a program moves its
+config (like ~/.vimrc to ~/.config/nvim/init.vim). The link at the old
+location is removed, the file is linked at the new one and config.yaml is
+updated. The repository file stays where it is, so its history is kept.
+
+A file already at the new location is backed up before it is replaced.
+A relink can be reverted with 'dotcor undo'.
+
+Examples:
+  dotcor relink ~/.vimrc ~/.config/nvim/init.vim
+  dotcor relink ~/.tmux.conf ~/.config/tmux/tmux.conf --dry-run`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRelink,
+}
+
+func init() {
+	relinkCmd.Flags().Bool("dry-run", false, "Show what would be done without making changes")
+	rootCmd.AddCommand(relinkCmd)
+}
+
+func runRelink(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	found, err := cfg.GetManagedFile(args[0])
+	if err != nil {
+		return err
+	}
+	mf := *found
+
+	newSource, err := config.NormalizePath(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	expanded, err := config.ExpandPath(newSource)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	if newSource == mf.SourcePath {
+		return fmt.Errorf("%s is already deployed there", mf.SourcePath)
+	}
+	if cfg.IsManaged(newSource) {
+		return fmt.Errorf("%s is already managed", newSource)
+	}
+	if isSystem, _ := config.IsSystemPath(expanded); isSystem {
+		return fmt.Errorf("%s is outside your home directory", newSource)
+	}
+	if err := core.ValidateNotInDotcorDir(expanded, cfg); err != nil {
+		return err
+	}
+
+	tx := core.NewTransaction()
+	if dryRun {
+		tx = core.NewPlanTransaction()
+	}
+	moved, note, err := relinkFile(tx, cfg, mf, newSource)
+	if err != nil {
+		// Steps that failed before running anything leave earlier ones done
+		tx.Rollback()
+		return fmt.Errorf("relinking %s: %w", mf.SourcePath, err)
+	}
+	tx.Commit()
+
+	if dryRun {
+		fmt.Println("Dry run - no changes will be made:")
+		fmt.Printf("  %s → %s\n", mf.SourcePath, moved.SourcePath)
+		for _, step := range tx.Plan() {
+			fmt.Printf("    → %s\n", step)
+		}
+		return nil
+	}
+
+	if note != "" {
+		fmt.Printf("✓ %s → %s (%s)\n", mf.SourcePath, moved.SourcePath, note)
+	} else {
+		fmt.Printf("✓ %s → %s\n", mf.SourcePath, moved.SourcePath)
+	}
+	if _, err := core.RestorePermissions(cfg, moved); err != nil {
+		fmt.Printf("⚠ %s (%v)\n", moved.SourcePath, err)
+	}
+	return nil
+}
+
+// relinkFile runs the steps that move mf's deployment to newSource as part
+// of tx: the old link is removed, the config entry replaced and the file
+// deployed at newSource. It returns the moved entry and how it was deployed.
+func relinkFile(tx *core.Transaction, cfg *config.Config, mf config.ManagedFile, newSource string) (config.ManagedFile, string, error) {
+	moved := mf
+	moved.SourcePath = newSource
+
+	oldSource, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return moved, "", err
+	}
+	resolved, err := config.ResolveVariant(mf)
+	if err != nil {
+		return moved, "", err
+	}
+	target, err := config.GetLinkTargetPath(cfg, resolved)
+	if err != nil {
+		return moved, "", err
+	}
+
+	// Only dotcor's own deployment is removed from the old location
+	isLink, _ := fs.IsSymlink(oldSource)
+	switch {
+	case isLink:
+		if err := tx.Execute(&core.RemoveSymlinkOp{Link: oldSource}); err != nil {
+			return moved, "", err
+		}
+	case mf.IsCopy() && fs.FileExists(oldSource):
+		if edited, err := core.CopyDiffers(cfg, resolved); err != nil {
+			return moved, "", err
+		} else if edited {
+			return moved, "", fmt.Errorf("%s has edits not in the repository; run 'dotcor sync' first", mf.SourcePath)
+		}
+		if err := tx.Execute(&core.RemoveFileOp{Path: oldSource}); err != nil {
+			return moved, "", err
+		}
+	case mf.IsComposed() && fs.FileExists(oldSource):
+		if intact, _ := core.ComposedIntact(cfg, resolved); !intact {
+			return moved, "", fmt.Errorf("%s was edited, move your edits into %s first", mf.SourcePath, mf.LocalPath())
+		}
+		if err := tx.Execute(&core.RemoveFileOp{Path: oldSource}); err != nil {
+			return moved, "", err
+		}
+	case mf.IsHardlink() && fs.FileExists(oldSource):
+		if intact, _ := fs.IsHardlinkTo(oldSource, target); !intact {
+			return moved, "", fmt.Errorf("%s is no longer a hard link to the repository; run 'dotcor doctor --fix' first", mf.SourcePath)
+		}
+		if err := tx.Execute(&core.RemoveFileOp{Path: oldSource}); err != nil {
+			return moved, "", err
+		}
+	}
+
+	if err := tx.Execute(&core.RemoveFromConfigOp{Config: cfg, SourcePath: mf.SourcePath}); err != nil {
+		return moved, "", err
+	}
+	if err := tx.Execute(&core.AddToConfigOp{Config: cfg, File: moved}); err != nil {
+		return moved, "", err
+	}
+
+	data, err := template.NewData(cfg)
+	if err != nil {
+		return moved, "", fmt.Errorf("collecting template variables: %w", err)
+	}
+	var backend crypto.Backend
+	note, _, err := applyFile(tx, cfg, moved, data, &backend)
+	return moved, note, err
+}
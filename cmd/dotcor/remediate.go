@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/crypto"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var remediateCmd = &cobra.Command{
+	Use:   "remediate [file]...",
+	Short: "Clean up secrets that were committed to the repository",
+	Long: `Find secrets in the repository's history, not only in its current files,
+and walk through cleaning each one up:
+
+  1. Move the file into the encrypted store, like 'dotcor secret add'
+  2. Purge its plain-text versions from the history, like 'dotcor forget'
+  3. Add it to the ignore patterns, so it isn't added unencrypted again
+
+Every version of every file in any commit is scanned with the rules of
+'dotcor scan', or only the given files: managed files by their path, files
+no longer managed by their path in the repository. For each file with
+secrets the commits having them are shown, then each step is offered.
+--encrypt, --purge-history and --ignore choose the steps up front instead,
+skipping the prompts.
+
+Purging rewrites history: the remote keeps the old commits until you
+force-push, and other clones must be cloned again. Anyone with a copy may
+have the secret, so rotate it and store the new value with
+'dotcor secret edit'.
+
+Examples:
+  dotcor remediate                           # Review every committed secret
+  dotcor remediate ~/.netrc
+  dotcor remediate ~/.netrc --encrypt --purge-history --ignore`,
+	RunE: runRemediate,
+}
+
+func init() {
+	remediateCmd.Flags().String("min-severity", config.SeverityLow, "Lowest severity to report: low, medium or high")
+	remediateCmd.Flags().Bool("encrypt", false, "Move the files into the encrypted store")
+	remediateCmd.Flags().Bool("purge-history", false, "Rewrite history to remove the plain-text versions")
+	remediateCmd.Flags().Bool("ignore", false, "Add the files to the ignore patterns")
+	rootCmd.AddCommand(remediateCmd)
+}
+
+// committedSecret is a repository file with secrets in its history
+type committedSecret struct {
+	RepoPath string
+	File     *config.ManagedFile // A copy of the managed file, nil if it is no longer managed
+	Commits  []git.CommitInfo    // Commits whose version of the file has secrets
+	Findings []core.SecretFinding
+}
+
+// remediateSteps are the steps chosen with flags; none chosen means ask
+type remediateSteps struct {
+	encrypt, purge, ignore bool
+}
+
+func (s remediateSteps) chosen() bool {
+	return s.encrypt || s.purge || s.ignore
+}
+
+func runRemediate(cmd *cobra.Command, args []string) error {
+	minSeverity, _ := cmd.Flags().GetString("min-severity")
+	var steps remediateSteps
+	steps.encrypt, _ = cmd.Flags().GetBool("encrypt")
+	steps.purge, _ = cmd.Flags().GetBool("purge-history")
+	steps.ignore, _ = cmd.Flags().GetBool("ignore")
+	if err := config.ValidateSeverity(minSeverity); err != nil || minSeverity == config.SeverityOff {
+		return fmt.Errorf("invalid --min-severity %q (expected low, medium or high)", minSeverity)
+	}
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid repo path: %w", err)
+	}
+	if !git.IsGitInstalled() || !git.IsRepo(filesRoot) {
+		return fmt.Errorf("remediate needs git and a git repository at %s", filesRoot)
+	}
+	scanner, err := core.NewSecretScanner(cfg.Scan)
+	if err != nil {
+		return fmt.Errorf("invalid scan config: %w", err)
+	}
+
+	repoPaths, err := remediateCandidates(cfg, filesRoot, args)
+	if err != nil {
+		return err
+	}
+
+	spinner := startSpinner("Scanning history")
+	var leaks []committedSecret
+	for _, repoPath := range repoPaths {
+		leak, err := findCommittedSecret(filesRoot, repoPath, scanner, minSeverity)
+		if err != nil {
+			spinner.Stop()
+			return err
+		}
+		if len(leak.Commits) > 0 {
+			leak.File = managedByRepoPath(cfg, repoPath)
+			leaks = append(leaks, leak)
+		}
+	}
+	spinner.Stop()
+
+	if len(leaks) == 0 {
+		fmt.Printf("✓ No secrets found in the history of %d file(s)\n", len(repoPaths))
+		return nil
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	// The purge needs a clean tree, which is checked before anything changes
+	dirty, err := git.HasChanges(filesRoot)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("the repository has uncommitted changes; commit them with 'dotcor sync' or discard them first")
+	}
+
+	// A file that fails doesn't stop the others, and the steps chosen for
+	// those before it are still finished
+	var purge, failed []string
+	ignored := 0
+	for _, leak := range leaks {
+		if cmd.Context().Err() != nil {
+			break
+		}
+		printCommittedSecret(leak)
+
+		purgeIt, ignoreIt, err := remediateSecret(cfg, filesRoot, &leak, steps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  ✗ %v\n\n", err)
+			failed = append(failed, leak.RepoPath)
+			continue
+		}
+		if purgeIt {
+			purge = append(purge, leak.RepoPath)
+		}
+		if ignoreIt {
+			source, _ := config.ExpandPath(leak.File.SourcePath)
+			cfg.IgnorePatterns = append(cfg.IgnorePatterns, core.IgnorePatternFor(source))
+			ignored++
+		}
+		fmt.Println()
+	}
+
+	if ignored > 0 {
+		if err := cfg.SaveConfig(); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+		fmt.Printf("✓ Added %d file(s) to the ignore patterns\n", ignored)
+	}
+
+	if len(purge) > 0 {
+		tool, err := git.PurgeFromHistory(filesRoot, purge...)
+		if err != nil {
+			return fmt.Errorf("purging history: %w", err)
+		}
+		fmt.Printf("✓ Purged %s from the history with %s\n", strings.Join(purge, ", "), tool)
+		if url, err := git.GetRemoteURL(filesRoot); err == nil && url != "" {
+			fmt.Println("  The remote still has the old commits. Replace them with:")
+			fmt.Printf("    git -C %s push --force --all origin && git -C %s push --force --tags origin\n", filesRoot, filesRoot)
+			fmt.Println("  Other clones must be cloned again.")
+		}
+	}
+
+	fmt.Println("Treat these secrets as leaked: rotate them, then store the new values")
+	fmt.Println("with 'dotcor secret edit'.")
+	if len(failed) > 0 {
+		return fmt.Errorf("%d file(s) could not be cleaned up: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return cmd.Context().Err()
+}
+
+// remediateCandidates returns the repo paths to scan: those of args, or
+// every path in the history that isn't an encrypted secret
+func remediateCandidates(cfg *config.Config, filesRoot string, args []string) ([]string, error) {
+	if len(args) > 0 {
+		var repoPaths []string
+		for _, arg := range args {
+			if mf, err := cfg.GetManagedFile(arg); err == nil {
+				repoPaths = append(repoPaths, mf.RepoPath)
+			} else if mf, err := cfg.GetSystemFile(arg); err == nil {
+				repoPaths = append(repoPaths, mf.RepoPath)
+			} else {
+				repoPaths = append(repoPaths, filepath.ToSlash(arg))
+			}
+		}
+		return repoPaths, nil
+	}
+
+	paths, err := git.HistoryPaths(filesRoot)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := encryptedRepoPaths(cfg)
+	var repoPaths []string
+	for _, p := range paths {
+		if !encrypted[filepath.FromSlash(p)] {
+			repoPaths = append(repoPaths, p)
+		}
+	}
+	return repoPaths, nil
+}
+
+// findCommittedSecret scans every committed version of repoPath. Findings
+// are those of the newest version with secrets.
+func findCommittedSecret(filesRoot, repoPath string, scanner *core.SecretScanner, minSeverity string) (committedSecret, error) {
+	leak := committedSecret{RepoPath: repoPath}
+	commits, err := git.CommitsTouching(filesRoot, repoPath)
+	if err != nil {
+		return leak, err
+	}
+
+	for _, c := range commits {
+		content, err := git.ContentAt(filesRoot, c.Hash, repoPath)
+		if err != nil {
+			continue // Deleted in this commit
+		}
+		var findings []core.SecretFinding
+		for _, f := range scanner.Scan(content) {
+			if core.SeverityAtLeast(f.Severity, minSeverity) {
+				findings = append(findings, f)
+			}
+		}
+		if len(findings) == 0 {
+			continue
+		}
+		if len(leak.Commits) == 0 {
+			leak.Findings = findings
+		}
+		leak.Commits = append(leak.Commits, c)
+	}
+	return leak, nil
+}
+
+// managedByRepoPath returns a copy of the managed file stored at repoPath,
+// or nil. Copies stay valid while files are removed from cfg.
+func managedByRepoPath(cfg *config.Config, repoPath string) *config.ManagedFile {
+	for _, mf := range cfg.ManagedFiles {
+		if filepath.ToSlash(mf.RepoPath) == repoPath {
+			return &mf
+		}
+	}
+	return nil
+}
+
+// printCommittedSecret shows a file's secrets and the commits having them
+func printCommittedSecret(leak committedSecret) {
+	name := leak.RepoPath
+	if leak.File != nil {
+		name = fmt.Sprintf("%s (%s)", leak.File.SourcePath, leak.RepoPath)
+	}
+	fmt.Println(paint(name, colorBold))
+	for _, f := range leak.Findings {
+		fmt.Printf("  ✗ [%s] line %d %s: %s\n", f.Severity, f.Line, f.Rule, f.Match)
+	}
+	fmt.Printf("  In %d commit(s):\n", len(leak.Commits))
+	for _, c := range leak.Commits {
+		fmt.Printf("    %s %s %s\n", paint(shortHash(c.Hash), colorYellow), c.Date.Format("2006-01-02"), truncateMessage(c.Message, 60))
+	}
+}
+
+// remediateSecret encrypts leak's file if chosen, then reports whether to
+// purge its plain-text path from the history and add it to the ignore
+// patterns, both done for all files at once by the caller
+func remediateSecret(cfg *config.Config, filesRoot string, leak *committedSecret, steps remediateSteps) (purge, ignore bool, err error) {
+	mf := leak.File
+	if mf != nil && !mf.Encrypted && !mf.IsTemplate() && !mf.IsComposed() && len(mf.Variants) == 0 {
+		ok, err := chooseRemediateStep(steps, steps.encrypt, fmt.Sprintf("  Move %s into the encrypted store?", mf.SourcePath))
+		if err != nil {
+			return false, false, err
+		}
+		if ok {
+			repoPath, err := encryptManagedFile(cfg, *mf)
+			if err != nil {
+				return false, false, fmt.Errorf("encrypting %s: %w", mf.SourcePath, err)
+			}
+			leak.File = managedByRepoPath(cfg, filepath.ToSlash(repoPath))
+		}
+	}
+
+	if fs.FileExists(filepath.Join(filesRoot, filepath.FromSlash(leak.RepoPath))) {
+		if steps.purge || !steps.chosen() {
+			fmt.Printf("  %s is still in the repository unencrypted, so it can't be purged from the\n", leak.RepoPath)
+			fmt.Println("  history. Move it into the encrypted store, or use 'dotcor forget --purge-history'.")
+		}
+	} else {
+		purge, err = chooseRemediateStep(steps, steps.purge, fmt.Sprintf("  Purge %s from the history, rewriting %d or more commit(s)?", leak.RepoPath, len(leak.Commits)))
+		if err != nil {
+			return false, false, err
+		}
+	}
+
+	if leak.File != nil {
+		source, err := config.ExpandPath(leak.File.SourcePath)
+		if err != nil {
+			return false, false, fmt.Errorf("invalid source path: %w", err)
+		}
+		if ignored, _ := core.ShouldIgnore(source, cfg.IgnorePatterns); !ignored {
+			ignore, err = chooseRemediateStep(steps, steps.ignore, fmt.Sprintf("  Add %s to the ignore patterns?", leak.File.SourcePath))
+			if err != nil {
+				return false, false, err
+			}
+		}
+	}
+	return purge, ignore, nil
+}
+
+// chooseRemediateStep returns chosen if steps were chosen with flags, or
+// asks question otherwise
+func chooseRemediateStep(steps remediateSteps, chosen bool, question string) (bool, error) {
+	if steps.chosen() {
+		return chosen, nil
+	}
+	return confirm(question, false)
+}
+
+// encryptManagedFile replaces a plain managed file with an encrypted one
+// in the same directory of the repository, then commits. It returns the
+// encrypted file's repo path.
+func encryptManagedFile(cfg *config.Config, mf config.ManagedFile) (string, error) {
+	if cfg.SharesRepoPath(mf) {
+		return "", fmt.Errorf("%s shares %s with other managed files; encrypt them by editing config.yaml", mf.SourcePath, mf.RepoPath)
+	}
+	backend, err := crypto.NewBackend(cfg.Secrets)
+	if err != nil {
+		return "", err
+	}
+
+	// The file is copied back by remove, then taken over by secret add
+	if err := processRemoveFile(cfg, mf, false, false); err != nil {
+		return "", err
+	}
+	category := filepath.Dir(mf.RepoPath)
+	if category == "." {
+		category = ""
+	}
+	repoPath, err := processSecretAdd(cfg, backend, mf.SourcePath, category, false)
+	if err != nil {
+		return "", fmt.Errorf("%w (the file is back at %s unmanaged; run 'dotcor secret add' on it)", err, mf.SourcePath)
+	}
+
+	recordChecksums(cfg, repoPath)
+	if git.IsAvailable() {
+		commitSecretChange(cfg, "Encrypt "+mf.RepoPath)
+	}
+	return repoPath, nil
+}
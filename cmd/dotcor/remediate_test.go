@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/git"
+)
+
+func TestRemediate(t *testing.T) {
+	if !git.IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+
+	cfg, err := config.NewDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(name string, args ...string) {
+		t.Helper()
+		cmd := exec.Command(name, args...)
+		cmd.Dir = filesRoot
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s %v: %v\n%s", name, args, err, out)
+		}
+	}
+	write := func(repoPath, content string) {
+		t.Helper()
+		path := filepath.Join(filesRoot, repoPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a/old had a password before it was deleted. b/netrc has one too, but
+	// is shared by two platforms' entries, so it can't be encrypted.
+	if err := os.MkdirAll(filesRoot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run("git", "init", "-q")
+	write("a/old", "password = hunter2hunter2\n")
+	write("b/netrc", "password = swordfish1234\n")
+	run("git", "add", "-A")
+	run("git", "commit", "-q", "-m", "add files")
+	run("git", "rm", "-q", "a/old")
+	run("git", "commit", "-q", "-m", "remove a/old")
+
+	cfg.ManagedFiles = []config.ManagedFile{
+		{SourcePath: "~/.netrc", RepoPath: "b/netrc", Platforms: []string{"linux", "darwin"}},
+		{SourcePath: "~/_netrc", RepoPath: "b/netrc", Platforms: []string{"windows"}},
+	}
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SilenceUsage, rootCmd.SilenceErrors = true, true
+	t.Cleanup(func() { rootCmd.SilenceUsage, rootCmd.SilenceErrors = false, false })
+	remediate := func() error {
+		rootCmd.SetArgs([]string{"remediate", "--encrypt", "--purge-history", "--ignore", "--allow-temp-repo"})
+		return rootCmd.ExecuteContext(context.Background())
+	}
+
+	// Nothing is changed while the tree is dirty
+	write("c/new", "x\n")
+	if err := remediate(); err == nil {
+		t.Error("remediate with a dirty tree should return error")
+	}
+	if paths, _ := git.HistoryPaths(filesRoot); !slices.Contains(paths, "a/old") {
+		t.Errorf("a/old purged from a dirty tree: %v", paths)
+	}
+	if err := os.RemoveAll(filepath.Join(filesRoot, "c")); err != nil {
+		t.Fatal(err)
+	}
+
+	// b/netrc failing doesn't stop a/old being purged
+	if err := remediate(); err == nil {
+		t.Error("remediate should report b/netrc failing")
+	}
+	paths, err := git.HistoryPaths(filesRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(paths, "a/old") {
+		t.Errorf("a/old still in the history: %v", paths)
+	}
+	if !slices.Contains(paths, "b/netrc") {
+		t.Errorf("b/netrc lost from the history: %v", paths)
+	}
+}
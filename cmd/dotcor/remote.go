@@ -0,0 +1,436 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/github"
+	"github.com/spf13/cobra"
+)
+
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "Configure the remote your dotfiles are pushed to",
+	Long: `Configure the git remote (origin) that 'dotcor sync' pushes to.
+
+Use 'dotcor remote setup' for a guided setup that checks the remote can be
+reached before saving it, or 'dotcor remote create-github' to create a new
+GitHub repository for your dotfiles.`,
+}
+
+var remoteSetupCmd = &cobra.Command{
+	Use:   "setup [url]",
+	Short: "Set up the git remote interactively",
+	Long: `Set up the git remote your dotfiles are pushed to.
+
+This command:
+1. Asks for the remote URL (or takes it as an argument) and validates it
+2. Tests that the remote can be reached with 'git ls-remote'
+3. For SSH remotes that fail, offers to generate an SSH key and prints the
+   public key to add to GitHub (or your git host)
+4. Sets origin in the repository and saves git_remote to config.yaml
+
+Examples:
+  dotcor remote setup                                   # Guided setup
+  dotcor remote setup git@github.com:you/dotfiles.git   # Use this URL
+  dotcor remote setup https://github.com/you/dotfiles.git --skip-test`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRemoteSetup,
+}
+
+var remoteCreateGitHubCmd = &cobra.Command{
+	Use:   "create-github",
+	Short: "Create a GitHub repository for your dotfiles and push to it",
+	Long: `Create a private GitHub repository for your dotfiles, set it as origin and
+push the commits made so far to it. Uncommitted changes are left for
+'dotcor sync'.
+
+The GitHub token is read from $GITHUB_TOKEN or $GH_TOKEN, or asked for. It
+needs permission to create repositories: the "repo" scope for a classic
+token, or "Administration: write" for a fine-grained one. The token is only
+used for this request and is never saved. Set $GITHUB_API_URL to use
+GitHub Enterprise Server.
+
+The remote uses SSH by default; pass --https to push over HTTPS instead.
+
+Examples:
+  dotcor remote create-github                      # Private "dotfiles" repo
+  dotcor remote create-github --name dots --public
+  GITHUB_TOKEN=ghp_... dotcor remote create-github --https`,
+	Args: cobra.NoArgs,
+	RunE: runRemoteCreateGitHub,
+}
+
+func init() {
+	remoteSetupCmd.Flags().Bool("skip-test", false, "Don't test the connection before saving")
+	remoteCreateGitHubCmd.Flags().String("name", "dotfiles", "Repository name")
+	remoteCreateGitHubCmd.Flags().Bool("public", false, "Create a public repository")
+	remoteCreateGitHubCmd.Flags().Bool("https", false, "Use the HTTPS URL instead of SSH")
+	remoteCreateGitHubCmd.Flags().Bool("no-push", false, "Don't push after creating the repository")
+	remoteCmd.AddCommand(remoteSetupCmd)
+	remoteCmd.AddCommand(remoteCreateGitHubCmd)
+	rootCmd.AddCommand(remoteCmd)
+}
+
+func runRemoteSetup(cmd *cobra.Command, args []string) error {
+	skipTest, _ := cmd.Flags().GetBool("skip-test")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+	if !git.IsAvailable() {
+		return fmt.Errorf("git is not installed")
+	}
+
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	current, _ := git.GetRemoteURL(filesRoot)
+	if current == "" {
+		current = cfg.GitRemote
+	}
+
+	var remoteURL string
+	if len(args) > 0 {
+		remoteURL = args[0]
+		if err := git.ValidateRemoteURL(remoteURL); err != nil {
+			return err
+		}
+	} else {
+		if remoteURL, err = promptRemoteURL(current); err != nil {
+			return err
+		}
+		if remoteURL == "" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	if !skipTest && !testRemoteInteractive(remoteURL) {
+		save, err := confirm("Save this remote anyway?", false)
+		if err != nil {
+			return err
+		}
+		if !save {
+			fmt.Println("Cancelled. Nothing was changed.")
+			return nil
+		}
+	}
+
+	if err := git.SetRemote(filesRoot, "origin", remoteURL); err != nil {
+		return fmt.Errorf("setting origin: %w", err)
+	}
+	cfg.GitRemote = remoteURL
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Printf("✓ Remote set to %s\n", remoteURL)
+	fmt.Println("\nRun 'dotcor sync' to push your dotfiles.")
+	return nil
+}
+
+func runRemoteCreateGitHub(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("name")
+	public, _ := cmd.Flags().GetBool("public")
+	useHTTPS, _ := cmd.Flags().GetBool("https")
+	noPush, _ := cmd.Flags().GetBool("no-push")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+	if !git.IsAvailable() {
+		return fmt.Errorf("git is not installed")
+	}
+
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	if current, _ := git.GetRemoteURL(filesRoot); current != "" {
+		fmt.Printf("origin is already set to %s\n", current)
+		replace, err := confirm("Replace it with a new GitHub repository?", false)
+		if err != nil {
+			return err
+		}
+		if !replace {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	token, err := githubToken()
+	if err != nil {
+		return err
+	}
+	client := github.NewClient(token)
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		// GitHub Enterprise Server
+		client.BaseURL = apiURL
+	}
+
+	user, err := client.CurrentUser()
+	if err != nil {
+		return fmt.Errorf("checking GitHub token: %w", err)
+	}
+	fmt.Printf("✓ Authenticated as %s\n", user.Login)
+
+	repo, err := client.CreateRepo(name, "My dotfiles, managed by dotcor", !public)
+	switch {
+	case errors.Is(err, github.ErrRepoExists):
+		fmt.Printf("%s/%s already exists on GitHub.\n", user.Login, name)
+		use, err := confirm("Use it as origin?", false)
+		if err != nil {
+			return err
+		}
+		if !use {
+			fmt.Println("Cancelled. Use --name to pick another name.")
+			return nil
+		}
+		if repo, err = client.GetRepo(user.Login, name); err != nil {
+			return fmt.Errorf("looking up %s/%s: %w", user.Login, name, err)
+		}
+	case err != nil:
+		return fmt.Errorf("creating repository: %w", err)
+	default:
+		visibility := "private"
+		if !repo.Private {
+			visibility = "public"
+		}
+		fmt.Printf("✓ Created %s repository %s\n", visibility, repo.HTMLURL)
+	}
+
+	remoteURL := repo.SSHURL
+	if useHTTPS {
+		remoteURL = repo.CloneURL
+	}
+	if err := git.SetRemote(filesRoot, "origin", remoteURL); err != nil {
+		return fmt.Errorf("setting origin: %w", err)
+	}
+	cfg.GitRemote = remoteURL
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	fmt.Printf("✓ Remote set to %s\n", remoteURL)
+
+	if noPush {
+		fmt.Println("\nRun 'dotcor sync' to push your dotfiles.")
+		return nil
+	}
+	if _, err := git.GetCurrentCommit(filesRoot); err != nil {
+		fmt.Println("\nNothing to push yet. Add files, then run 'dotcor sync'.")
+		return nil
+	}
+
+	// Only what's committed is pushed; 'dotcor sync' commits the rest
+	if err := git.Push(filesRoot); err != nil {
+		fmt.Printf("✗ First push failed: %v\n", err)
+		if errors.Is(err, git.ErrBehindRemote) {
+			fmt.Println("  The repository already has commits. Merge or rebase onto them with git,")
+			fmt.Println("  then run 'dotcor sync'.")
+		} else if git.IsSSHURL(remoteURL) {
+			fmt.Println("  Run 'dotcor remote setup' to test the connection and set up an SSH key,")
+			fmt.Println("  or rerun with --https.")
+		}
+		return fmt.Errorf("pushing to %s failed", repo.FullName)
+	}
+	fmt.Printf("✓ Pushed to %s\n", repo.FullName)
+	if changed, _ := git.HasChanges(filesRoot); changed {
+		fmt.Println("\nUncommitted changes weren't pushed. Run 'dotcor sync' to push them.")
+	}
+	return nil
+}
+
+// githubToken returns the GitHub token from $GITHUB_TOKEN or $GH_TOKEN,
+// otherwise asks for it without echoing
+func githubToken() (string, error) {
+	for _, env := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := strings.TrimSpace(os.Getenv(env)); token != "" {
+			return token, nil
+		}
+	}
+	if nonInteractive {
+		return "", fmt.Errorf("no GitHub token, set $GITHUB_TOKEN to run non-interactively")
+	}
+	if !stdinIsTerminal() {
+		return "", fmt.Errorf("no GitHub token, set $GITHUB_TOKEN to run without a terminal")
+	}
+
+	fmt.Println("Create a token at https://github.com/settings/tokens with the \"repo\" scope.")
+	fmt.Print("GitHub token: ")
+	data, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("reading token: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("no GitHub token given, set $GITHUB_TOKEN or enter one when asked")
+	}
+	return token, nil
+}
+
+// promptRemoteURL asks for a remote URL until a valid one is given,
+// offering current as the default. Empty input with no default cancels.
+func promptRemoteURL(current string) (string, error) {
+	fmt.Println("Enter the URL of the repository to push your dotfiles to, e.g.")
+	fmt.Println("  git@github.com:you/dotfiles.git      (SSH)")
+	fmt.Println("  https://github.com/you/dotfiles.git  (HTTPS)")
+	fmt.Println()
+
+	prompt := "Remote URL: "
+	if current != "" {
+		prompt = fmt.Sprintf("Remote URL [%s]: ", current)
+	}
+	for {
+		remoteURL, err := ask(prompt, current)
+		if err != nil || remoteURL == "" {
+			return "", err
+		}
+
+		if err := git.ValidateRemoteURL(remoteURL); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			if nonInteractive {
+				return "", err
+			}
+			continue
+		}
+		return remoteURL, nil
+	}
+}
+
+// testRemoteInteractive tests that remoteURL can be reached, reporting the
+// result. When an SSH remote fails it offers to generate a key, waits for
+// it to be added to the git host, and tests again.
+func testRemoteInteractive(remoteURL string) bool {
+	fmt.Printf("Testing connection to %s...\n", remoteURL)
+	err := git.CheckRemote(remoteURL)
+	if err == nil {
+		fmt.Println("✓ Remote is reachable")
+		return true
+	}
+	fmt.Printf("✗ Could not reach remote: %v\n", err)
+
+	if !git.IsSSHURL(remoteURL) {
+		fmt.Println("  Check the URL and that your credentials (e.g. a personal access token) are set up.")
+		return false
+	}
+	if nonInteractive {
+		fmt.Println("  Run 'dotcor remote setup' without --yes to set up an SSH key.")
+		return false
+	}
+
+	pubKey, err := ensureSSHKey()
+	if err != nil {
+		fmt.Printf("⚠ %v\n", err)
+		return false
+	}
+	if pubKey == "" {
+		return false
+	}
+
+	fmt.Println()
+	fmt.Println("Add this public key to your git host:")
+	if host := git.RemoteHost(remoteURL); host == "github.com" {
+		fmt.Println("  https://github.com/settings/ssh/new")
+	}
+	fmt.Println()
+	fmt.Println(pubKey)
+	fmt.Println()
+	fmt.Print("Press Enter once the key is added to test again...")
+	if _, err := readAnswer("Press Enter once the key is added"); err != nil {
+		return false
+	}
+
+	if err := git.CheckRemote(remoteURL); err != nil {
+		fmt.Printf("✗ Still could not reach remote: %v\n", err)
+		return false
+	}
+	fmt.Println("✓ Remote is reachable")
+	return true
+}
+
+// ensureSSHKey returns the user's ed25519 public key, offering to generate
+// one with ssh-keygen if it doesn't exist. It returns an empty key if the
+// user declines.
+func ensureSSHKey() (string, error) {
+	keyPath, err := config.ExpandPath("~/.ssh/id_ed25519")
+	if err != nil {
+		return "", err
+	}
+	pubPath := keyPath + ".pub"
+
+	if !fs.PathExists(keyPath) {
+		fmt.Printf("\nNo SSH key found at %s.\n", keyPath)
+		generate, err := confirm("Generate one now?", true)
+		if err != nil || !generate {
+			return "", err
+		}
+		if err := generateSSHKey(keyPath); err != nil {
+			return "", err
+		}
+		fmt.Printf("✓ Generated %s\n", keyPath)
+	} else {
+		fmt.Printf("\nUsing your existing SSH key %s.\n", keyPath)
+	}
+
+	data, err := os.ReadFile(pubPath)
+	if err != nil {
+		return "", fmt.Errorf("reading public key: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// generateSSHKey runs ssh-keygen to create an ed25519 key at keyPath.
+// ssh-keygen asks for the passphrase itself.
+func generateSSHKey(keyPath string) error {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		return fmt.Errorf("ssh-keygen not found, install OpenSSH to generate a key")
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(keyPath), err)
+	}
+
+	comment := "dotcor"
+	if hostname, err := os.Hostname(); err == nil {
+		comment = "dotcor@" + hostname
+	}
+
+	keygen := exec.Command("ssh-keygen", "-t", "ed25519", "-C", comment, "-f", keyPath)
+	keygen.Stdin = os.Stdin
+	keygen.Stdout = os.Stdout
+	keygen.Stderr = os.Stderr
+	if err := keygen.Run(); err != nil {
+		return fmt.Errorf("running ssh-keygen: %w", err)
+	}
+	return nil
+}
@@ -1,11 +1,9 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/core"
@@ -21,17 +19,32 @@ var removeCmd = &cobra.Command{
 	Long: `Remove dotfiles from DotCor management.
 
 By default, the file is copied back to its original location and removed
-from the repository. Use --keep-repo to leave the file in the repository.
+from the repository. Use --keep-repo to leave the file in the repository,
+or --purge to discard the file entirely. Purged files are moved to the OS
+trash unless 'deletion: delete' is set in config.yaml. A removal can be
+reverted with 'dotcor undo'.
 
 Examples:
   dotcor remove ~/.zshrc              # Remove file, copy back to original location
   dotcor remove ~/.zshrc --keep-repo  # Remove from management but keep in repo
+  dotcor remove ~/.zshrc --purge      # Remove symlink and trash the file
   dotcor remove --all                 # Remove all files from management`,
-	RunE: runRemove,
+	RunE:        runRemove,
+	Annotations: structuredOutput,
+}
+
+// removeOutput is the structured output of 'dotcor remove'
+type removeOutput struct {
+	DryRun    bool          `json:"dry_run" yaml:"dry_run"`
+	Removed   int           `json:"removed" yaml:"removed"`
+	Failed    int           `json:"failed" yaml:"failed"`
+	Committed bool          `json:"committed" yaml:"committed"`
+	Files     []fileOutcome `json:"files" yaml:"files"`
 }
 
 func init() {
 	removeCmd.Flags().Bool("keep-repo", false, "Keep file in repository after removing")
+	removeCmd.Flags().Bool("purge", false, "Discard the file instead of copying it back")
 	removeCmd.Flags().Bool("all", false, "Remove all files from management")
 	removeCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompts")
 	removeCmd.Flags().Bool("dry-run", false, "Show what would be done without making changes")
@@ -40,6 +53,7 @@ func init() {
 
 func runRemove(cmd *cobra.Command, args []string) error {
 	keepRepo, _ := cmd.Flags().GetBool("keep-repo")
+	purge, _ := cmd.Flags().GetBool("purge")
 	removeAll, _ := cmd.Flags().GetBool("all")
 	force, _ := cmd.Flags().GetBool("force")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
@@ -48,6 +62,10 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("specify files to remove or use --all")
 	}
 
+	if purge && keepRepo {
+		return fmt.Errorf("--purge and --keep-repo cannot be used together")
+	}
+
 	// Load config
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -56,6 +74,9 @@ func runRemove(cmd *cobra.Command, args []string) error {
 
 	// Acquire lock (skip for dry-run)
 	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
 		if err := core.AcquireLock(); err != nil {
 			return fmt.Errorf("acquiring lock: %w", err)
 		}
@@ -64,18 +85,21 @@ func runRemove(cmd *cobra.Command, args []string) error {
 
 	// Determine which files to remove
 	var filesToRemove []config.ManagedFile
+	out := removeOutput{DryRun: dryRun, Files: []fileOutcome{}}
 
 	if removeAll {
 		filesToRemove = cfg.GetManagedFilesForPlatform()
 		if len(filesToRemove) == 0 {
 			fmt.Println("No files to remove.")
-			return nil
+			return writeResult(out)
 		}
 	} else {
 		for _, arg := range args {
 			mf, err := cfg.GetManagedFile(arg)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "  ✗ %s: not managed\n", arg)
+				out.Files = append(out.Files, fileOutcome{Path: arg, Result: "failed", Error: "not managed"})
+				out.Failed++
 				continue
 			}
 			filesToRemove = append(filesToRemove, *mf)
@@ -94,54 +118,81 @@ func runRemove(cmd *cobra.Command, args []string) error {
 		}
 		fmt.Println("")
 
-		if !confirmRemove() {
+		ok, err := confirm("Continue?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Cancelled.")
-			return nil
+			for _, f := range filesToRemove {
+				out.Files = append(out.Files, fileOutcome{Path: f.SourcePath, Repo: f.RepoPath, Result: "skipped"})
+			}
+			return writeResult(out)
 		}
 	}
 
 	if dryRun {
 		fmt.Println("Dry run - no changes will be made:")
 		fmt.Println("")
+	} else if err := runHooks(cmd, cfg, config.HookPreRemove); err != nil {
+		return err
 	}
 
 	// Process each file
 	removed := 0
 
 	for _, mf := range filesToRemove {
-		err := processRemoveFile(cfg, mf, keepRepo, dryRun)
+		if err := cmd.Context().Err(); err != nil {
+			return err
+		}
+		var err error
+		if purge {
+			err = processPurgeFile(cfg, mf, dryRun)
+		} else {
+			err = processRemoveFile(cfg, mf, keepRepo, dryRun)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", mf.SourcePath, err)
+			out.Files = append(out.Files, fileOutcome{Path: mf.SourcePath, Repo: mf.RepoPath, Result: "failed", Error: err.Error()})
+			out.Failed++
 			continue
 		}
 		removed++
+		out.Files = append(out.Files, fileOutcome{Path: mf.SourcePath, Repo: mf.RepoPath, Result: "removed"})
 	}
+	out.Removed = removed
 
 	// Summary
 	fmt.Println("")
 	if dryRun {
 		fmt.Printf("Would remove %d file(s) from management\n", removed)
-		return nil
+		return writeResult(out)
 	}
 
 	fmt.Printf("Removed %d file(s) from management\n", removed)
 
 	// Git commit
-	if git.IsGitInstalled() && removed > 0 && !keepRepo {
-		repoPath, err := config.ExpandPath(cfg.RepoPath)
+	if git.IsAvailable() && removed > 0 && !keepRepo {
+		repoPath, err := config.GetFilesRoot(cfg)
 		if err != nil {
 			fmt.Printf("⚠ Git commit skipped: invalid repo path: %v\n", err)
 		} else {
-			message := fmt.Sprintf("Remove %d file(s) from management", removed)
-			if err := git.AutoCommit(repoPath, message); err != nil {
+			message := core.RemoveCommitMessage(removed)
+			if err := git.AutoCommit(repoPath, message, userPathspecs...); err != nil {
 				fmt.Printf("⚠ Git commit failed: %v\n", err)
 			} else {
 				fmt.Println("✓ Committed to Git")
+				out.Committed = true
 			}
 		}
 	}
 
-	return nil
+	if removed > 0 {
+		if err := runHooks(cmd, cfg, config.HookPostRemove); err != nil {
+			return err
+		}
+	}
+	return writeResult(out)
 }
 
 // processRemoveFile handles removing a single file
@@ -171,18 +222,23 @@ func processRemoveFile(cfg *config.Config, mf config.ManagedFile, keepRepo bool,
 		return fmt.Errorf("checking symlink status: %w", err)
 	}
 
+	// Each step is rolled back if a later one fails, and the committed
+	// transaction is journaled for 'dotcor undo'
+	tx := core.NewTransaction()
+
 	// If keeping repo, just remove symlink and update config
 	if keepRepo {
 		if isLink {
-			if err := os.Remove(sourcePath); err != nil {
-				return fmt.Errorf("removing symlink: %w", err)
+			if err := tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath}); err != nil {
+				return err
 			}
 		}
 
 		// Remove from config
-		if err := cfg.RemoveManagedFile(mf.SourcePath); err != nil {
-			return fmt.Errorf("updating config: %w", err)
+		if err := tx.Execute(&core.RemoveFromConfigOp{Config: cfg, SourcePath: mf.SourcePath}); err != nil {
+			return err
 		}
+		tx.Commit()
 
 		fmt.Printf("  ✓ %s (removed from management, kept in repo)\n", mf.SourcePath)
 		return nil
@@ -190,58 +246,156 @@ func processRemoveFile(cfg *config.Config, mf config.ManagedFile, keepRepo bool,
 
 	// Full removal: copy back and delete from repo
 
-	// First, create backup of the repo file
-	if fs.FileExists(repoPath) {
-		if _, err := core.CreateBackup(repoPath); err != nil {
-			fmt.Printf("  ⚠ Backup failed for %s: %v\n", mf.RepoPath, err)
+	// Templates are restored as their rendered output for this machine,
+	// secrets as their decrypted copy
+	restoreFrom := repoPath
+	renderedPath := ""
+	if mf.IsTemplate() || mf.Encrypted {
+		if rendered, err := config.GetLinkTargetPath(cfg, mf); err == nil && fs.FileExists(rendered) {
+			restoreFrom = rendered
+			renderedPath = rendered
+		} else if mf.Encrypted {
+			return fmt.Errorf("secret not decrypted, run 'dotcor init --apply' first")
 		}
 	}
 
+	// A copy-mode file is already in place and may hold unsynced edits. A
+	// hard link is kept too: copying over it would truncate the repo file.
+	keepLocalCopy := (mf.IsCopy() || mf.IsHardlink()) && !isLink && fs.FileExists(sourcePath)
+
 	// Ensure parent directory exists
-	if err := fs.EnsureDir(filepath.Dir(sourcePath)); err != nil {
-		return fmt.Errorf("creating parent directory: %w", err)
+	if err := tx.Execute(&core.CreateDirOp{Path: filepath.Dir(sourcePath)}); err != nil {
+		return err
 	}
 
 	// If source is a symlink, remove it first
 	if isLink {
-		if err := os.Remove(sourcePath); err != nil {
-			return fmt.Errorf("removing symlink: %w", err)
+		if err := tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath}); err != nil {
+			return err
 		}
 	}
 
 	// Copy file from repo to source location
-	if fs.FileExists(repoPath) {
-		if err := fs.CopyWithPermissions(repoPath, sourcePath); err != nil {
-			return fmt.Errorf("copying file back: %w", err)
+	inRepo := fs.FileExists(repoPath)
+	if inRepo {
+		if !keepLocalCopy {
+			if err := tx.Execute(&core.CopyFileOp{Src: restoreFrom, Dst: sourcePath, Preserve: true}); err != nil {
+				return err
+			}
 		}
 
-		// Delete from repo
-		if err := os.Remove(repoPath); err != nil {
-			return fmt.Errorf("removing from repo: %w", err)
+		// Delete from repo, keeping a backup, unless another managed file
+		// such as the entry for another platform still uses it
+		if !cfg.SharesRepoPath(mf) {
+			if err := tx.Execute(&core.RemoveFileOp{Path: repoPath}); err != nil {
+				return err
+			}
 		}
 
-		// Clean up empty parent directories in repo
-		cleanEmptyDirs(filepath.Dir(repoPath))
+		// Rendered output is regenerated by 'dotcor init --apply'
+		if renderedPath != "" {
+			err := tx.Execute(&core.StepOp{
+				Desc: fmt.Sprintf("remove %s", renderedPath),
+				DoFunc: func() error {
+					os.Remove(renderedPath)
+					return nil
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Remove from config
-	if err := cfg.RemoveManagedFile(mf.SourcePath); err != nil {
-		return fmt.Errorf("updating config: %w", err)
+	if err := tx.Execute(&core.RemoveFromConfigOp{Config: cfg, SourcePath: mf.SourcePath}); err != nil {
+		return err
+	}
+	tx.Commit()
+
+	// Clean up empty parent directories in repo
+	if inRepo {
+		cleanEmptyDirs(filepath.Dir(repoPath))
 	}
 
 	fmt.Printf("  ✓ %s\n", mf.SourcePath)
+	if mf.IsComposed() {
+		if localPath, err := config.ExpandPath(mf.LocalPath()); err == nil && fs.FileExists(localPath) {
+			fmt.Printf("    %s is no longer included, merge it into %s to keep it\n", mf.LocalPath(), mf.SourcePath)
+		}
+	}
 	return nil
 }
 
-// confirmRemove prompts for confirmation
-func confirmRemove() bool {
-	fmt.Print("Continue? [y/N]: ")
+// processPurgeFile removes the symlink and discards the repo file
+func processPurgeFile(cfg *config.Config, mf config.ManagedFile, dryRun bool) error {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return fmt.Errorf("invalid source path: %w", err)
+	}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return fmt.Errorf("invalid repo path: %w", err)
+	}
 
-	return input == "y" || input == "yes"
+	if dryRun {
+		fmt.Printf("  - %s\n", mf.SourcePath)
+		fmt.Printf("    → Remove symlink %s\n", sourcePath)
+		if cfg.UseTrash() {
+			fmt.Printf("    → Move to trash: %s\n", mf.RepoPath)
+		} else {
+			fmt.Printf("    → Delete: %s\n", mf.RepoPath)
+		}
+		return nil
+	}
+
+	tx := core.NewTransaction()
+
+	// Only remove the source if it is our symlink, never a real file
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		if err := tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath}); err != nil {
+			return err
+		}
+	}
+
+	// The backup lets 'dotcor undo' bring the file back. A repo file other
+	// managed files use is kept.
+	trashPath := ""
+	inRepo := fs.FileExists(repoPath) && !cfg.SharesRepoPath(mf)
+	if inRepo {
+		backup := &core.BackupFileOp{Path: repoPath, File: &mf}
+		if err := tx.Execute(backup); err != nil {
+			return err
+		}
+		err := tx.Execute(&core.StepOp{
+			Desc: fmt.Sprintf("delete %s", repoPath),
+			DoFunc: func() error {
+				var err error
+				trashPath, err = core.DeleteUserFile(cfg, repoPath)
+				return err
+			},
+			UndoFunc: func() error { return core.RestoreBackup(backup.BackupPath, repoPath) },
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Execute(&core.RemoveFromConfigOp{Config: cfg, SourcePath: mf.SourcePath}); err != nil {
+		return err
+	}
+	tx.Commit()
+
+	if inRepo {
+		if trashPath != "" {
+			fmt.Printf("  → Moved to trash: %s\n", trashPath)
+		}
+		cleanEmptyDirs(filepath.Dir(repoPath))
+	}
+
+	fmt.Printf("  ✓ %s (purged)\n", mf.SourcePath)
+	return nil
 }
 
 // cleanEmptyDirs removes empty parent directories up to the repo root
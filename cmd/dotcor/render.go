@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render [file...]",
+	Short: "Render template dotfiles for this machine",
+	Long: `Render managed files stored as templates (repo paths ending in .tmpl).
+
+Templates use Go template syntax. Rendered output is written to
+~/.dotcor/rendered and the dotfile is linked to it, so the repository keeps
+the template while each machine gets its own version.
+
+Available values:
+  {{ .Hostname }}  {{ .OS }}  {{ .Arch }}  {{ .User }}  {{ .Home }}
+  {{ .Vars.name }}  (from 'variables' in config.yaml)
+  {{ env "NAME" }}  {{ default "vim" .Vars.editor }}
+
+Templates are also re-rendered by 'dotcor init --apply'.
+
+Examples:
+  dotcor render                     # Render all templates
+  dotcor render ~/.gitconfig        # Render one template
+  dotcor render ~/.gitconfig --print # Show the output without writing it
+  dotcor render --dry-run           # Show which templates would change`,
+	RunE: runRender,
+}
+
+func init() {
+	renderCmd.Flags().Bool("print", false, "Print rendered output instead of writing it")
+	renderCmd.Flags().Bool("dry-run", false, "Show which templates would change without writing them")
+	rootCmd.AddCommand(renderCmd)
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	printOnly, _ := cmd.Flags().GetBool("print")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	files, err := selectTemplates(cfg, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("No template files managed. Templates are repo files ending in .tmpl.")
+		return nil
+	}
+
+	data, err := template.NewData(cfg)
+	if err != nil {
+		return fmt.Errorf("collecting template variables: %w", err)
+	}
+
+	if printOnly {
+		return printRendered(cfg, files, data)
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	rendered := 0
+	failed := 0
+	for _, mf := range files {
+		changed, err := renderAndLink(cfg, mf, data, dryRun)
+		if err != nil {
+			fmt.Printf("  ✗ %s: %v\n", mf.SourcePath, err)
+			failed++
+			continue
+		}
+		if !changed {
+			fmt.Printf("  - %s (up to date)\n", mf.SourcePath)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("  ~ %s (would change)\n", mf.SourcePath)
+		} else {
+			fmt.Printf("  ✓ %s\n", mf.SourcePath)
+		}
+		rendered++
+	}
+
+	fmt.Println("")
+	if dryRun {
+		fmt.Printf("Would render %d template(s)\n", rendered)
+	} else {
+		fmt.Printf("Rendered %d template(s)", rendered)
+		if failed > 0 {
+			fmt.Printf(", %d failed", failed)
+		}
+		fmt.Println("")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d template(s) failed to render", failed)
+	}
+	return nil
+}
+
+// selectTemplates returns the managed templates named in args, or all of them
+func selectTemplates(cfg *config.Config, args []string) ([]config.ManagedFile, error) {
+	if len(args) == 0 {
+		var templates []config.ManagedFile
+		for _, mf := range cfg.GetManagedFilesForPlatform() {
+			if mf.IsTemplate() {
+				templates = append(templates, mf)
+			}
+		}
+		return templates, nil
+	}
+
+	var templates []config.ManagedFile
+	for _, arg := range args {
+		mf, err := cfg.GetManagedFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("file not managed: %s", arg)
+		}
+		if !mf.IsTemplate() {
+			return nil, fmt.Errorf("not a template: %s (repo path %s)", arg, mf.RepoPath)
+		}
+		templates = append(templates, *mf)
+	}
+	return templates, nil
+}
+
+// printRendered writes rendered templates to stdout
+func printRendered(cfg *config.Config, files []config.ManagedFile, data template.Data) error {
+	for i, mf := range files {
+		src, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("reading template: %w", err)
+		}
+
+		output, err := template.Render(mf.RepoPath, content, data)
+		if err != nil {
+			return err
+		}
+
+		if len(files) > 1 {
+			if i > 0 {
+				fmt.Println("")
+			}
+			fmt.Printf("==> %s <==\n", mf.SourcePath)
+		}
+		os.Stdout.Write(output)
+	}
+	return nil
+}
+
+// renderAndLink renders a template and points its symlink at the output.
+// Returns true if the rendered output or the link changed (or would change).
+func renderAndLink(cfg *config.Config, mf config.ManagedFile, data template.Data, dryRun bool) (bool, error) {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return false, fmt.Errorf("invalid source path: %w", err)
+	}
+
+	target, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return false, err
+	}
+
+	status, _ := fs.GetSymlinkStatus(sourcePath, target)
+	needsLink := !status.Exists || linksToTemplateSource(cfg, mf, sourcePath)
+	if mf.IsCopy() {
+		// Copies are refreshed whenever they differ from the rendered output
+		differs, _ := core.CopyDiffers(cfg, mf)
+		needsLink = !status.Exists || status.IsSymlink || differs
+	} else if mf.IsComposed() {
+		// The composed file includes the rendered output, which is all that changes
+		intact, _ := core.ComposedIntact(cfg, mf)
+		needsLink = !intact
+	} else if status.Exists && !status.IsSymlink {
+		return false, fmt.Errorf("%s is a regular file, not a symlink", mf.SourcePath)
+	}
+
+	if dryRun {
+		src, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+		if err != nil {
+			return false, err
+		}
+		content, err := os.ReadFile(src)
+		if err != nil {
+			return false, fmt.Errorf("reading template: %w", err)
+		}
+		output, err := template.Render(mf.RepoPath, content, data)
+		if err != nil {
+			return false, err
+		}
+		existing, err := os.ReadFile(target)
+		return needsLink || err != nil || string(existing) != string(output), nil
+	}
+
+	_, changed, err := template.RenderManagedFile(cfg, mf, data)
+	if err != nil {
+		return false, err
+	}
+
+	if mf.IsCopy() {
+		if changed || needsLink {
+			if err := core.DeployCopy(cfg, mf); err != nil {
+				return false, err
+			}
+		}
+	} else if mf.IsComposed() {
+		if needsLink {
+			if err := core.DeployComposed(cfg, mf); err != nil {
+				return false, err
+			}
+		}
+	} else if needsLink {
+		if err := fs.CreateSymlinkWithStyle(target, sourcePath, cfg.LinkStyle); err != nil {
+			return false, fmt.Errorf("linking rendered file: %w", err)
+		}
+	}
+
+	return changed || needsLink, nil
+}
+
+// linksToTemplateSource reports whether a template's symlink points at the
+// raw .tmpl file in the repo instead of its rendered output
+func linksToTemplateSource(cfg *config.Config, mf config.ManagedFile, sourcePath string) bool {
+	if !mf.IsTemplate() {
+		return false
+	}
+
+	templatePath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return false
+	}
+
+	status, err := fs.GetSymlinkStatus(sourcePath, templatePath)
+	return err == nil && status.PointsToRepo
+}
@@ -1,10 +1,7 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/core"
@@ -75,22 +72,30 @@ func runRestore(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("getting repo path: %w", err)
 	}
 
-	repoRoot, err := config.ExpandPath(cfg.RepoPath)
+	repoRoot, err := config.GetFilesRoot(cfg)
 	if err != nil {
 		return fmt.Errorf("expanding repo root: %w", err)
 	}
 
+	if !preview {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+	}
+
 	// Handle backup restore
 	if fromBackup {
-		return restoreFromBackup(mf.SourcePath, repoPath, preview, force)
+		return restoreFromBackup(cfg, *mf, repoPath, preview, force)
 	}
 
 	// Git restore
-	return restoreFromGit(repoRoot, mf.RepoPath, repoPath, toRef, preview, force)
+	return restoreFromGit(cfg, *mf, repoRoot, repoPath, toRef, preview, force)
 }
 
 // restoreFromGit restores a file from Git history
-func restoreFromGit(repoRoot, repoPath, fullRepoPath, ref string, preview, force bool) error {
+func restoreFromGit(cfg *config.Config, mf config.ManagedFile, repoRoot, fullRepoPath, ref string, preview, force bool) error {
+	repoPath := mf.RepoPath
+
 	// Check if git is available
 	if !git.IsGitInstalled() {
 		return fmt.Errorf("git is not installed")
@@ -121,7 +126,11 @@ func restoreFromGit(repoRoot, repoPath, fullRepoPath, ref string, preview, force
 		fmt.Println("This will overwrite the current version.")
 		fmt.Println("")
 
-		if !confirmRestore() {
+		ok, err := confirm("Continue?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Cancelled.")
 			return nil
 		}
@@ -134,7 +143,7 @@ func restoreFromGit(repoRoot, repoPath, fullRepoPath, ref string, preview, force
 	defer core.ReleaseLock()
 
 	// Create backup of current version
-	backupPath, err := core.CreateBackup(fullRepoPath)
+	backupPath, err := core.CreateManagedBackup(fullRepoPath, mf)
 	if err != nil {
 		fmt.Printf("⚠ Could not create backup: %v\n", err)
 	} else {
@@ -147,16 +156,16 @@ func restoreFromGit(repoRoot, repoPath, fullRepoPath, ref string, preview, force
 	}
 
 	fmt.Printf("✓ Restored %s from %s\n", repoPath, ref)
+	recordChecksums(cfg, repoPath)
 	return nil
 }
 
 // restoreFromBackup restores a file from backup
-func restoreFromBackup(sourcePath, repoPath string, preview, force bool) error {
-	// Get filename for backup lookup
-	filename := getFilename(sourcePath)
+func restoreFromBackup(cfg *config.Config, mf config.ManagedFile, repoPath string, preview, force bool) error {
+	sourcePath := mf.SourcePath
 
-	// Find backups
-	backups, err := core.GetBackupsForFile(filename)
+	// Find backups taken of this file
+	backups, err := core.GetBackupsForSource(sourcePath)
 	if err != nil {
 		return fmt.Errorf("finding backups: %w", err)
 	}
@@ -180,7 +189,11 @@ func restoreFromBackup(sourcePath, repoPath string, preview, force bool) error {
 		fmt.Printf("Backup: %s (%s)\n", backup.BackupPath, backup.Timestamp.Format("2006-01-02 15:04:05"))
 		fmt.Println("")
 
-		if !confirmRestore() {
+		ok, err := confirm("Continue?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Cancelled.")
 			return nil
 		}
@@ -198,6 +211,7 @@ func restoreFromBackup(sourcePath, repoPath string, preview, force bool) error {
 	}
 
 	fmt.Printf("✓ Restored %s from backup\n", sourcePath)
+	recordChecksums(cfg, mf.RepoPath)
 	return nil
 }
 
@@ -237,24 +251,3 @@ func listAllBackups() error {
 	fmt.Printf("\n%d backup(s) total\n", len(backups))
 	return nil
 }
-
-// confirmRestore prompts for confirmation
-func confirmRestore() bool {
-	fmt.Print("Continue? [y/N]: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
-
-	return input == "y" || input == "yes"
-}
-
-// getFilename extracts filename from a path
-func getFilename(path string) string {
-	for i := len(path) - 1; i >= 0; i-- {
-		if path[i] == '/' || path[i] == '\\' {
-			return path[i+1:]
-		}
-	}
-	return path
-}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan [file]...",
+	Short: "Audit the repository for secrets, or find unmanaged dotfiles",
+	Long: `Scan every file in the dotfiles repository for potential secrets, or only
+the given files.
+
+Secrets are found by pattern rules (API keys, tokens, passwords, private
+keys, connection strings) and by entropy analysis of long random-looking
+strings. Encrypted secret files and binary files are skipped.
+
+A line is never reported if it contains "dotcor:allow-secret", e.g. in a
+trailing comment, or matches a regexp in the scan allowlist. Rules are
+tuned in config.yaml:
+
+  scan:
+    allowlist: ['EXAMPLE_[A-Z_]+']   # Lines matching are never reported
+    entropy: 4.5                     # Bits per character (default 4.0)
+    rules:
+      password: high                 # Change a rule's severity
+      high-entropy: off              # Or turn it off
+
+Exit codes:
+  0  No findings at or above --min-severity
+  1  Scan could not run
+  2  Potential secrets found
+
+With --staged, the content staged for the next commit is scanned instead.
+This is what the pre-commit hook installed by 'dotcor init --hooks' runs, so
+a plain 'git commit' in the repository is blocked when it would add secrets.
+Commits dotcor makes itself, like 'dotcor add --force', aren't blocked: it
+sets $DOTCOR_SKIP_SCAN for them, which --staged honors.
+
+With --home, the home directory is searched for dotfiles that aren't managed
+yet instead. Hidden files and directories are walked down to --depth levels
+(e.g. ~/.config/nvim/init.lua is 3 deep), skipping caches and package
+stores, symlinks, binary and empty files, files over --max-size KiB and
+anything matching the ignore patterns. Each file is listed under the
+category 'dotcor add' would file it in, and files with potential secrets are
+marked. --add then adds every file without secrets.
+
+Examples:
+  dotcor scan                       # Audit the whole repository
+  dotcor scan ~/.npmrc              # Check a file before adding it
+  dotcor scan --staged              # Check what the next commit would add
+  dotcor scan --min-severity high   # Only report high-severity findings
+  dotcor scan -o json               # Machine-readable findings
+  dotcor scan --home                # List unmanaged dotfiles
+  dotcor scan --home --add          # Add the ones without secrets`,
+	Annotations: structuredOutput,
+	RunE:        runScan,
+}
+
+func init() {
+	scanCmd.Flags().String("min-severity", config.SeverityLow, "Lowest severity to report: low, medium or high")
+	scanCmd.Flags().Bool("staged", false, "Scan content staged for commit in the repository")
+	scanCmd.Flags().Bool("home", false, "Find unmanaged dotfiles in the home directory")
+	scanCmd.Flags().Int("depth", core.DefaultDiscoverDepth, "Levels below home to search with --home")
+	scanCmd.Flags().Int64("max-size", core.DefaultDiscoverMaxSize/1024, "Skip files larger than this many KiB with --home")
+	scanCmd.Flags().Bool("add", false, "Add the unmanaged dotfiles without secrets found by --home")
+	rootCmd.AddCommand(scanCmd)
+}
+
+// scanFinding is a secret finding in one file
+type scanFinding struct {
+	Path               string `json:"path" yaml:"path"`
+	core.SecretFinding `yaml:",inline"`
+}
+
+// scanResult is the structured output of 'dotcor scan'
+type scanResult struct {
+	Files    int           `json:"files" yaml:"files"`
+	Findings []scanFinding `json:"findings" yaml:"findings"`
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	minSeverity, _ := cmd.Flags().GetString("min-severity")
+	staged, _ := cmd.Flags().GetBool("staged")
+	if err := config.ValidateSeverity(minSeverity); err != nil || minSeverity == config.SeverityOff {
+		return fmt.Errorf("invalid --min-severity %q (expected low, medium or high)", minSeverity)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	scanner, err := core.NewSecretScanner(cfg.Scan)
+	if err != nil {
+		return fmt.Errorf("invalid scan config: %w", err)
+	}
+
+	if staged && len(args) > 0 {
+		return fmt.Errorf("--staged can't be combined with file arguments")
+	}
+
+	if home, _ := cmd.Flags().GetBool("home"); home {
+		if staged || len(args) > 0 {
+			return fmt.Errorf("--home can't be combined with --staged or file arguments")
+		}
+		return runScanHome(cmd, cfg, scanner)
+	}
+	if add, _ := cmd.Flags().GetBool("add"); add {
+		return fmt.Errorf("--add needs --home")
+	}
+
+	// Files to scan, with the path to report them by
+	paths := map[string]string{}
+	var order []string
+	read := os.ReadFile
+	if staged {
+		// dotcor's own commits skip the hook's scan
+		if skip, _ := strconv.ParseBool(os.Getenv(git.SkipScanEnv)); skip {
+			return nil
+		}
+		root, err := config.GetFilesRoot(cfg)
+		if err != nil {
+			return fmt.Errorf("expanding repo path: %w", err)
+		}
+		order, paths, err = stagedScanFiles(cfg, root)
+		if err != nil {
+			return err
+		}
+		read = func(path string) ([]byte, error) {
+			return git.StagedContent(root, path)
+		}
+	} else if len(args) > 0 {
+		for _, arg := range args {
+			expanded, err := config.ExpandPath(arg)
+			if err != nil {
+				return fmt.Errorf("invalid path %s: %w", arg, err)
+			}
+			paths[expanded] = arg
+			order = append(order, expanded)
+		}
+	} else {
+		order, paths, err = repoScanFiles(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	result := scanResult{Files: len(order), Findings: []scanFinding{}}
+	for _, path := range order {
+		content, err := read(path)
+		if err != nil {
+			return fmt.Errorf("scanning %s: %w", paths[path], err)
+		}
+		for _, f := range scanner.Scan(content) {
+			if core.SeverityAtLeast(f.Severity, minSeverity) {
+				result.Findings = append(result.Findings, scanFinding{Path: paths[path], SecretFinding: f})
+			}
+		}
+	}
+
+	for _, f := range result.Findings {
+		fmt.Printf("  ✗ [%s] %s:%d %s: %s\n", f.Severity, f.Path, f.Line, f.Rule, f.Match)
+	}
+	if len(result.Findings) > 0 {
+		fmt.Println()
+	}
+
+	if err := writeResult(result); err != nil {
+		return err
+	}
+
+	if len(result.Findings) == 0 {
+		fmt.Printf("✓ No secrets found in %d files\n", result.Files)
+		return nil
+	}
+	if staged {
+		fmt.Println("Commit blocked. Remove the secrets, mark a false positive with")
+		fmt.Println("'# dotcor:allow-secret', or bypass once with 'git commit --no-verify'.")
+	} else if len(args) == 0 {
+		fmt.Println("Secrets committed before stay in the history; clean them up with 'dotcor remediate'.")
+	}
+
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &exitCodeError{
+		code: exitProblems,
+		msg:  fmt.Sprintf("scan found %d potential secret(s)", len(result.Findings)),
+	}
+}
+
+// homeDotfile is an unmanaged dotfile found by 'dotcor scan --home'
+type homeDotfile struct {
+	Path     string `json:"path" yaml:"path"`
+	Repo     string `json:"repo" yaml:"repo"`
+	Category string `json:"category" yaml:"category"`
+	Size     int64  `json:"size" yaml:"size"`
+	Secrets  int    `json:"secrets" yaml:"secrets"`
+}
+
+// homeScanResult is the structured output of 'dotcor scan --home'
+type homeScanResult struct {
+	Files []homeDotfile `json:"files" yaml:"files"`
+	Add   *addOutput    `json:"add,omitempty" yaml:"add,omitempty"`
+}
+
+// runScanHome lists the unmanaged dotfiles in the home directory by
+// category, adding the ones without secrets with --add
+func runScanHome(cmd *cobra.Command, cfg *config.Config, scanner *core.SecretScanner) error {
+	depth, _ := cmd.Flags().GetInt("depth")
+	maxSize, _ := cmd.Flags().GetInt64("max-size")
+	add, _ := cmd.Flags().GetBool("add")
+	if depth < 1 {
+		return fmt.Errorf("invalid --depth %d (expected 1 or more)", depth)
+	}
+	if maxSize < 1 {
+		return fmt.Errorf("invalid --max-size %d (expected 1 or more KiB)", maxSize)
+	}
+
+	spinner := startSpinner("Scanning home directory")
+	found, err := core.FindDotfiles(cmd.Context(), cfg, core.DiscoverOptions{MaxDepth: depth, MaxSize: maxSize * 1024})
+	spinner.Stop()
+	if err != nil {
+		return err
+	}
+
+	// The category is the first directory of the repo path add would use
+	result := homeScanResult{Files: []homeDotfile{}}
+	byCategory := map[string][]homeDotfile{}
+	var categories []string
+	var safe []string
+	for _, f := range found {
+		repoPath, err := config.GenerateRepoPath(f.Path, "")
+		if err != nil {
+			continue
+		}
+		category := strings.SplitN(filepath.ToSlash(repoPath), "/", 2)[0]
+		secrets, _ := scanner.ScanFile(f.Path)
+
+		file := homeDotfile{Path: f.Path, Repo: repoPath, Category: category, Size: f.Size, Secrets: len(secrets)}
+		result.Files = append(result.Files, file)
+		if _, ok := byCategory[category]; !ok {
+			categories = append(categories, category)
+		}
+		byCategory[category] = append(byCategory[category], file)
+		if len(secrets) == 0 {
+			safe = append(safe, f.Path)
+		}
+	}
+	sort.Strings(categories)
+
+	if len(result.Files) == 0 {
+		fmt.Println("✓ No unmanaged dotfiles found")
+		return writeResult(result)
+	}
+
+	fmt.Printf("Found %d unmanaged dotfile(s):\n", len(result.Files))
+	for _, category := range categories {
+		fmt.Printf("\n%s\n", paint(category+"/", colorBold))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, f := range byCategory[category] {
+			fmt.Fprintf(w, "  %s\t→ %s\t%s", f.Path, f.Repo, formatSize(f.Size))
+			if f.Secrets > 0 {
+				fmt.Fprintf(w, "\t%s", paint(fmt.Sprintf("⚠ %d potential secret(s)", f.Secrets), colorYellow))
+			}
+			fmt.Fprintln(w)
+		}
+		w.Flush()
+	}
+	fmt.Println()
+
+	if !add {
+		if len(safe) > 0 {
+			fmt.Printf("Run 'dotcor scan --home --add' to add the %d file(s) without secrets,\n", len(safe))
+			fmt.Println("or 'dotcor add <file>' to pick some.")
+		}
+		if len(safe) < len(result.Files) {
+			fmt.Println("Store files with secrets using 'dotcor secret add'.")
+		}
+		return writeResult(result)
+	}
+
+	if len(safe) == 0 {
+		fmt.Println("Nothing to add: every file found has potential secrets.")
+		fmt.Println("Store them using 'dotcor secret add'.")
+		return writeResult(result)
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	if err := runHooks(cmd, cfg, config.HookPreAdd); err != nil {
+		return err
+	}
+
+	fmt.Printf("Adding %d file(s) without secrets:\n", len(safe))
+	out, err := addFiles(cmd, cfg, safe, addOptions{})
+	result.Add = &out
+	if err != nil {
+		return err
+	}
+	return writeResult(result)
+}
+
+// stagedScanFiles lists the files staged under root, relative to root,
+// skipping encrypted secrets
+func stagedScanFiles(cfg *config.Config, root string) ([]string, map[string]string, error) {
+	staged, err := git.StagedFiles(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encrypted := encryptedRepoPaths(cfg)
+	var order []string
+	paths := map[string]string{}
+	for _, file := range staged {
+		if encrypted[filepath.FromSlash(file)] {
+			continue
+		}
+		order = append(order, file)
+		paths[file] = file
+	}
+	return order, paths, nil
+}
+
+// encryptedRepoPaths returns the repo paths of encrypted files, in OS form
+func encryptedRepoPaths(cfg *config.Config) map[string]bool {
+	encrypted := map[string]bool{}
+	for _, files := range [][]config.ManagedFile{cfg.ManagedFiles, cfg.SystemFiles} {
+		for _, mf := range files {
+			if mf.Encrypted {
+				encrypted[filepath.FromSlash(mf.RepoPath)] = true
+			}
+		}
+	}
+	return encrypted
+}
+
+// preCommitScript is the git pre-commit hook that blocks staged secrets
+func preCommitScript() string {
+	// Prefer the dotcor on PATH so the hook survives reinstalls elsewhere
+	bin := "dotcor"
+	if _, err := exec.LookPath(bin); err != nil {
+		if exe, err := os.Executable(); err == nil {
+			bin = exe
+		}
+	}
+	// The hook belongs to one repository, so it scans with that one selected
+	repo := ""
+	if name := config.SelectedRepo(); name != "" {
+		repo = " --repo " + shellQuote(name)
+	}
+	return "#!/bin/sh\n" +
+		git.HookMarker + " to block commits that add secrets.\n" +
+		"# Bypass once with: git commit --no-verify\n" +
+		"exec " + shellQuote(bin) + repo + " scan --staged\n"
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// installPreCommitHook installs the secret-scanning pre-commit hook in the
+// repository
+func installPreCommitHook(cfg *config.Config) error {
+	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+	return git.InstallHook(repoPath, "pre-commit", preCommitScript())
+}
+
+// repoScanFiles lists the regular files in the repository, skipping .git
+// and encrypted secrets, which are reported by their repo path
+func repoScanFiles(cfg *config.Config) ([]string, map[string]string, error) {
+	root, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("expanding repo path: %w", err)
+	}
+
+	encrypted := encryptedRepoPaths(cfg)
+	var order []string
+	paths := map[string]string{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil || encrypted[rel] {
+			return nil
+		}
+		order = append(order, path)
+		paths[path] = filepath.ToSlash(rel)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("walking repository: %w", err)
+	}
+	return order, paths, nil
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/crypto"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage encrypted dotfiles",
+	Long: `Manage dotfiles containing secrets, such as tokens or private keys.
+
+Secrets are stored encrypted in the repository (with age or GPG) and
+decrypted to ~/.dotcor/secrets, which never leaves this machine. The
+dotfile is linked to the decrypted copy. 'dotcor init --apply' decrypts
+secrets on a new machine.
+
+Configure the backend in config.yaml:
+  secrets:
+    backend: age                      # or gpg
+    recipients: [age1...]             # defaults to your own key
+    identity: ~/.config/age/keys.txt  # age only
+
+Examples:
+  dotcor secret add ~/.npmrc      # Encrypt and manage a file
+  dotcor secret edit ~/.npmrc     # Edit and re-encrypt
+  dotcor secret reveal ~/.npmrc   # Print the decrypted contents`,
+}
+
+var secretAddCmd = &cobra.Command{
+	Use:   "add [file]...",
+	Short: "Encrypt dotfiles and add them to DotCor management",
+	Long: `Encrypt one or more dotfiles and add them to DotCor management.
+
+The encrypted copy is committed to the repository. The original moves to
+~/.dotcor/secrets and is replaced with a symlink to it.
+
+Examples:
+  dotcor secret add ~/.npmrc
+  dotcor secret add ~/.aws/credentials --category aws`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSecretAdd,
+}
+
+var secretEditCmd = &cobra.Command{
+	Use:   "edit [file]",
+	Short: "Edit an encrypted dotfile and re-encrypt it",
+	Long: `Open the decrypted copy of a secret in $EDITOR, then re-encrypt it
+into the repository if it changed.
+
+Changes made directly to the dotfile are also picked up and re-encrypted.
+
+Examples:
+  dotcor secret edit ~/.npmrc`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretEdit,
+}
+
+var secretRevealCmd = &cobra.Command{
+	Use:   "reveal [file]",
+	Short: "Print the decrypted contents of a secret",
+	Long: `Decrypt a secret from the repository and print it to stdout.
+Nothing is written to disk.
+
+Examples:
+  dotcor secret reveal ~/.npmrc`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: readOnly,
+	RunE:        runSecretReveal,
+}
+
+func init() {
+	secretAddCmd.Flags().StringP("category", "c", "", "Override automatic category detection")
+	secretAddCmd.Flags().Bool("dry-run", false, "Show what would be done without making changes")
+	secretCmd.AddCommand(secretAddCmd)
+	secretCmd.AddCommand(secretEditCmd)
+	secretCmd.AddCommand(secretRevealCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+func runSecretAdd(cmd *cobra.Command, args []string) error {
+	category, _ := cmd.Flags().GetString("category")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	backend, err := crypto.NewBackend(cfg.Secrets)
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	if dryRun {
+		fmt.Println("Dry run - no changes will be made:")
+		fmt.Println("")
+	}
+
+	added := 0
+	failed := 0
+	var gitFiles []string
+	for _, file := range args {
+		repoPath, err := processSecretAdd(cfg, backend, file, category, dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", file, err)
+			failed++
+			continue
+		}
+		if repoPath != "" {
+			gitFiles = append(gitFiles, repoPath)
+			added++
+		}
+	}
+
+	fmt.Println("")
+	if dryRun {
+		fmt.Printf("Would encrypt %d file(s)\n", added)
+		return nil
+	}
+
+	fmt.Printf("Encrypted %d file(s)", added)
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println("")
+	recordChecksums(cfg, gitFiles...)
+
+	if git.IsAvailable() && added > 0 {
+		commitSecretChange(cfg, core.AddCommitMessage(gitFiles))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) failed", failed)
+	}
+	return nil
+}
+
+// processSecretAdd encrypts a single file into the repo and links it to
+// its decrypted copy. Returns the repo path, or "" if the file was skipped.
+func processSecretAdd(cfg *config.Config, backend crypto.Backend, sourcePath, category string, dryRun bool) (string, error) {
+	expanded, err := config.ExpandPath(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+
+	normalized, err := config.NormalizePath(sourcePath)
+	if err != nil {
+		normalized = sourcePath
+	}
+
+	if !fs.FileExists(expanded) {
+		return "", fmt.Errorf("file does not exist")
+	}
+
+	if cfg.IsManaged(sourcePath) {
+		fmt.Printf("  - %s (already managed)\n", normalized)
+		return "", nil
+	}
+
+	if err := core.ValidateSourceFile(expanded, cfg); err != nil && !core.IsValidationWarning(err) {
+		return "", err
+	}
+
+	customRepoPath := ""
+	if category != "" {
+		customRepoPath = filepath.Join(category, strings.TrimPrefix(filepath.Base(expanded), "."))
+	}
+	repoPath, err := config.GenerateRepoPath(sourcePath, customRepoPath)
+	if err != nil {
+		return "", fmt.Errorf("generating repo path: %w", err)
+	}
+	repoPath += backend.Ext()
+
+	if _, err := config.GetRepoFilePath(cfg, repoPath); err != nil {
+		return "", err
+	}
+
+	if dryRun {
+		fmt.Printf("  + %s → %s (encrypted with %s)\n", normalized, repoPath, backend.Name())
+		return repoPath, nil
+	}
+
+	plaintext, err := os.ReadFile(expanded)
+	if err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+	ciphertext, err := backend.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	if err := core.EnsureSecretsDir(); err != nil {
+		return "", err
+	}
+
+	mf := config.ManagedFile{
+		SourcePath: normalized,
+		RepoPath:   repoPath,
+		AddedAt:    time.Now(),
+		Platforms:  []string{},
+		Encrypted:  true,
+	}
+
+	// Back up the plaintext locally only; backups never enter the repo
+	if _, err := core.CreateManagedBackup(expanded, mf); err != nil {
+		fmt.Printf("  ⚠ Backup failed for %s: %v\n", normalized, err)
+	}
+
+	tx, err := core.AddSecretTransaction(cfg, sourcePath, repoPath, mf, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("creating transaction: %w", err)
+	}
+	if err := tx.ExecuteAll(); err != nil {
+		return "", err
+	}
+	tx.Commit()
+
+	// The decrypted copy is readable only by this user
+	if decrypted, err := config.GetLinkTargetPath(cfg, mf); err == nil {
+		if err := os.Chmod(decrypted, 0600); err != nil {
+			fmt.Printf("  ⚠ %s: restricting permissions failed: %v\n", normalized, err)
+		}
+		if repoFile, err := config.GetRepoFilePath(cfg, repoPath); err == nil {
+			if err := core.RecordDecrypted(repoPath, repoFile, decrypted); err != nil {
+				fmt.Printf("  ⚠ %s: %v\n", normalized, err)
+			}
+		}
+	}
+
+	fmt.Printf("  ✓ %s (encrypted with %s)\n", normalized, backend.Name())
+	return repoPath, nil
+}
+
+func runSecretEdit(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	mf, err := getSecretFile(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	backend, err := crypto.NewBackend(cfg.Secrets)
+	if err != nil {
+		return err
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	repoFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+
+	// Compare against the repo copy so edits made through the symlink count too
+	original, err := crypto.DecryptToBytes(backend, repoFile)
+	if err != nil {
+		return err
+	}
+
+	decrypted, _, err := core.DecryptSecret(cfg, backend, *mf)
+	if err != nil {
+		return err
+	}
+
+	if err := runEditor(decrypted); err != nil {
+		return err
+	}
+
+	edited, err := os.ReadFile(decrypted)
+	if err != nil {
+		return fmt.Errorf("reading edited file: %w", err)
+	}
+	if bytes.Equal(original, edited) {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	if err := crypto.EncryptFile(backend, decrypted, repoFile); err != nil {
+		return err
+	}
+	if err := core.RecordDecrypted(mf.RepoPath, repoFile, decrypted); err != nil {
+		fmt.Printf("⚠ %v\n", err)
+	}
+	fmt.Printf("✓ Re-encrypted %s\n", mf.SourcePath)
+	recordChecksums(cfg, mf.RepoPath)
+
+	if git.IsAvailable() {
+		commitSecretChange(cfg, fmt.Sprintf("Update %s", filepath.Base(mf.RepoPath)))
+	}
+	return nil
+}
+
+func runSecretReveal(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	mf, err := getSecretFile(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	backend, err := crypto.NewBackend(cfg.Secrets)
+	if err != nil {
+		return err
+	}
+
+	repoFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := crypto.DecryptToBytes(backend, repoFile)
+	if err != nil {
+		return err
+	}
+
+	os.Stdout.Write(plaintext)
+	return nil
+}
+
+// getSecretFile looks up a managed file and checks that it is encrypted
+func getSecretFile(cfg *config.Config, path string) (*config.ManagedFile, error) {
+	mf, err := cfg.GetManagedFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("file not managed: %s", path)
+	}
+	if !mf.Encrypted {
+		return nil, fmt.Errorf("not an encrypted file: %s\nUse 'dotcor secret add' to manage secrets", path)
+	}
+	return mf, nil
+}
+
+
+// runEditor opens path in the user's editor and waits for it to exit
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// $EDITOR may include arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	editorCmd := exec.Command(parts[0], append(parts[1:], path)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("running editor %s: %w", editor, err)
+	}
+	return nil
+}
+
+// commitSecretChange commits encrypted repo files, warning on failure
+func commitSecretChange(cfg *config.Config, message string) {
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		fmt.Printf("⚠ Git commit skipped: invalid repo path: %v\n", err)
+		return
+	}
+	if err := git.AutoCommit(repoPath, message, userPathspecs...); err != nil {
+		fmt.Printf("⚠ Git commit failed: %v\n", err)
+	} else {
+		fmt.Println("✓ Committed to Git")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init [bash|zsh|fish]",
+	Short: "Print shell functions for working with the repository",
+	Long: `Print shell functions to load in your shell's startup file:
+
+  dcd            Change into the dotfiles directory
+  dcd <file>     Change into the directory holding a managed file
+  dotcor_prompt  Print " [status]" for a prompt, see 'dotcor prompt --help'
+
+Setup:
+  bash  echo 'eval "$(dotcor shell-init bash)"' >> ~/.bashrc
+  zsh   echo 'eval "$(dotcor shell-init zsh)"' >> ~/.zshrc
+  fish  echo 'dotcor shell-init fish | source' >> ~/.config/fish/config.fish`,
+	Args:        cobra.ExactArgs(1),
+	ValidArgs:   []string{"bash", "zsh", "fish"},
+	Annotations: readOnly,
+	RunE:        runShellInit,
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+}
+
+// posixShellInit defines dcd and dotcor_prompt for bash and zsh
+const posixShellInit = `dcd() {
+  local dir
+  dir="$(command dotcor path --dir "$@")" || return
+  cd "$dir"
+}
+
+dotcor_prompt() {
+  local token
+  token="$(command dotcor prompt 2>/dev/null)"
+  [ -n "$token" ] && echo " [$token]"
+}
+`
+
+// fishShellInit defines dcd and dotcor_prompt for fish
+const fishShellInit = `function dcd --description 'cd into the dotcor repository'
+    set -l dir (command dotcor path --dir $argv); or return
+    cd $dir
+end
+
+function dotcor_prompt --description 'dotcor status for the prompt'
+    set -l status_token (command dotcor prompt 2>/dev/null)
+    test -n "$status_token"; and echo -n " [$status_token]"
+end
+`
+
+func runShellInit(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash", "zsh":
+		fmt.Print(posixShellInit)
+	case "fish":
+		fmt.Print(fishShellInit)
+	default:
+		return fmt.Errorf("unsupported shell %q (expected bash, zsh or fish)", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the deployment state and roll back to it",
+	Long: `Save the complete deployment state, and roll the machine back to it later:
+a safety net before risky changes like a big reorganization or a pull.
+
+A snapshot records the repository commit, the config and where every managed
+file's link pointed. It's stored in ~/.dotcor/snapshots/. Rolling back resets
+the repository files to the recorded commit as a new commit, so history is
+kept, restores the config and re-applies the links. Files managed now but not
+then are copied back into place and no longer managed.
+
+The current state is snapshotted before every rollback, so a rollback can
+itself be rolled back. System files (dotcor system) are not rolled back.
+
+Examples:
+  dotcor snapshot create "before nvim rewrite"   # Save the current state
+  dotcor snapshot list                           # Show saved snapshots
+  dotcor snapshot rollback 2024-05-01_10-30-00   # Roll back to a snapshot`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Save the current deployment state",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runSnapshotCreate,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List saved snapshots, newest first",
+	Args:        cobra.NoArgs,
+	Annotations: annotations(structuredOutput, readOnly),
+	RunE:        runSnapshotList,
+}
+
+var snapshotRollbackCmd = &cobra.Command{
+	Use:   "rollback <id>",
+	Short: "Roll the repository, config and links back to a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotRollback,
+}
+
+func init() {
+	snapshotRollbackCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+	snapshotRollbackCmd.Flags().Bool("dry-run", false, "Show what would be rolled back without making changes")
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotListCmd, snapshotRollbackCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+func runSnapshotCreate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	snap, err := createSnapshot(cfg, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Created snapshot %s (%d files", snap.ID, len(snap.Links))
+	if snap.Commit != "" {
+		fmt.Printf(", commit %s", shortCommit(snap.Commit))
+	}
+	fmt.Println(")")
+	fmt.Printf("Roll back with: dotcor snapshot rollback %s\n", snap.ID)
+	return nil
+}
+
+// createSnapshot snapshots the current state, recording the repository's
+// HEAD. A repository with uncommitted changes is refused, since rolling
+// back to the snapshot couldn't bring them back.
+func createSnapshot(cfg *config.Config, name string) (*core.Snapshot, error) {
+	commit := ""
+	if repoPath, err := config.GetFilesRoot(cfg); err == nil && git.IsAvailable() && git.IsRepo(repoPath) {
+		if changed, _ := git.HasChanges(repoPath, userPathspecs...); changed {
+			return nil, fmt.Errorf("the repository has uncommitted changes\nRun 'dotcor sync' first so the snapshot includes them")
+		}
+		// A repository without commits has nothing to record yet
+		commit, _ = git.GetCurrentCommit(repoPath)
+	}
+
+	snap, err := core.CreateSnapshot(cfg, name, commit)
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+func runSnapshotList(cmd *cobra.Command, args []string) error {
+	snapshots, err := core.ListSnapshots()
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots. Create one with 'dotcor snapshot create'.")
+		return writeResult(snapshots)
+	}
+
+	fmt.Printf("%-22s  %-16s  %-8s  %5s  %s\n", "ID", "CREATED", "COMMIT", "FILES", "NAME")
+	for _, snap := range snapshots {
+		commit := "-"
+		if snap.Commit != "" {
+			commit = shortCommit(snap.Commit)
+		}
+		fmt.Printf("%-22s  %-16s  %-8s  %5d  %s\n", snap.ID, snap.Time.Local().Format("2006-01-02 15:04"), commit, len(snap.Links), snap.Name)
+	}
+	return writeResult(snapshots)
+}
+
+func runSnapshotRollback(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	snap, snapCfg, err := core.LoadSnapshot(args[0])
+	if errors.Is(err, core.ErrSnapshotNotFound) {
+		return fmt.Errorf("%w\nRun 'dotcor snapshot list' to see saved snapshots", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+	if snapCfg.RepoPath != cfg.RepoPath || snapCfg.FilesSubdir != cfg.FilesSubdir {
+		return fmt.Errorf("snapshot %s was taken of a different repository (%s)", snap.ID, snapCfg.RepoPath)
+	}
+	if snap.Commit != "" {
+		if !git.IsGitInstalled() {
+			return fmt.Errorf("rolling back the repository requires git")
+		}
+		if !git.CommitExists(repoPath, snap.Commit) {
+			return fmt.Errorf("commit %s of snapshot %s is not in the repository", shortCommit(snap.Commit), snap.ID)
+		}
+		if changed, _ := git.HasChanges(repoPath, userPathspecs...); changed {
+			return fmt.Errorf("the repository has uncommitted changes\nRun 'dotcor sync' first, so they are kept in the snapshot taken before rolling back")
+		}
+	}
+
+	// System files aren't part of the rollback
+	snapCfg.SystemFiles = cfg.SystemFiles
+
+	// Files managed now but not then are handed back as plain files
+	var unmanage []config.ManagedFile
+	for _, mf := range cfg.ManagedFiles {
+		if !snapCfg.IsManaged(mf.SourcePath) {
+			unmanage = append(unmanage, mf)
+		}
+	}
+
+	// Only files that were deployed at the time are deployed again
+	deployed := make(map[string]bool)
+	for _, link := range snap.Links {
+		deployed[link.SourcePath] = link.State != core.SnapshotMissing && link.State != core.SnapshotFile
+	}
+	apply := *snapCfg
+	apply.ManagedFiles = nil
+	for _, mf := range snapCfg.ManagedFiles {
+		if deployed[mf.SourcePath] {
+			apply.ManagedFiles = append(apply.ManagedFiles, mf)
+		}
+	}
+
+	title := snap.ID
+	if snap.Name != "" {
+		title = fmt.Sprintf("%s (%s)", snap.ID, snap.Name)
+	}
+	fmt.Printf("Roll back to snapshot %s from %s:\n", title, snap.Time.Local().Format("2006-01-02 15:04"))
+	if snap.Commit != "" {
+		fmt.Printf("  → reset repository files to commit %s\n", shortCommit(snap.Commit))
+	}
+	for _, mf := range unmanage {
+		fmt.Printf("  → stop managing %s, leaving a copy in place\n", mf.SourcePath)
+	}
+	fmt.Printf("  → restore config (%d managed files)\n", len(snapCfg.ManagedFiles))
+	fmt.Printf("  → re-apply links of %d files\n", len(apply.ManagedFiles))
+
+	if dryRun {
+		return nil
+	}
+
+	if !force {
+		fmt.Println("")
+		ok, err := confirm("Continue?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	// The current state is saved first, so the rollback can be undone
+	before, err := createSnapshot(cfg, "before rollback to "+snap.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n✓ Saved the current state as snapshot %s\n", before.ID)
+	undoHint := fmt.Sprintf("Run 'dotcor snapshot rollback %s' to return to the state before", before.ID)
+
+	for _, mf := range unmanage {
+		if err := unmanageForRollback(cfg, mf); err != nil {
+			return fmt.Errorf("unmanaging %s: %w\n%s", mf.SourcePath, err, undoHint)
+		}
+		fmt.Printf("  ✓ %s (no longer managed)\n", mf.SourcePath)
+	}
+
+	if snap.Commit != "" {
+		if err := git.RestoreTree(repoPath, snap.Commit, userPathspecs...); err != nil {
+			return fmt.Errorf("resetting repository: %w\n%s", err, undoHint)
+		}
+	}
+	if err := snapCfg.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w\n%s", err, undoHint)
+	}
+	if snap.Commit != "" {
+		if changed, _ := git.HasChanges(repoPath, userPathspecs...); changed {
+			if err := git.AutoCommit(repoPath, fmt.Sprintf("Roll back to snapshot %s", snap.ID), userPathspecs...); err != nil {
+				fmt.Printf("⚠ Git commit failed: %v\n", err)
+			} else {
+				fmt.Println("✓ Reset repository files and committed to Git")
+			}
+		}
+	}
+
+	if len(apply.ManagedFiles) > 0 {
+		if err := applySymlinks(cmd, &apply, false); err != nil {
+			return fmt.Errorf("%w\n%s", err, undoHint)
+		}
+	}
+	if err := core.RecordAllChecksums(snapCfg); err != nil {
+		fmt.Printf("⚠ Recording checksums failed: %v\n", err)
+	}
+
+	fmt.Printf("\n✓ Rolled back to snapshot %s\n", snap.ID)
+	fmt.Println(undoHint)
+	return nil
+}
+
+// unmanageForRollback replaces mf's link with a copy of its content before
+// the repository file goes away. Copies and hard links are already
+// independent files and are left as they are.
+func unmanageForRollback(cfg *config.Config, mf config.ManagedFile) error {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return err
+	}
+
+	tx := core.NewTransaction()
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		target, err := config.GetLinkTargetPath(cfg, mf)
+		if err != nil {
+			return err
+		}
+		if err := tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath}); err != nil {
+			return err
+		}
+		if fs.FileExists(target) {
+			if err := tx.Execute(&core.CopyFileOp{Src: target, Dst: sourcePath, Preserve: true}); err != nil {
+				return err
+			}
+		}
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/justincordova/dotcor/internal/config"
@@ -23,17 +25,33 @@ Displays:
 - Git repository status (uncommitted changes, remote sync)
 - Overall statistics
 
+Each problem has a severity: info for setups that work but aren't how
+dotcor would deploy them (a link style to convert, a link that reaches the
+repo file through another link), warn for drift that needs a sync, render
+or re-apply, and error for files that aren't deployed from the repo at
+all. --fail-on sets the lowest severity that fails the command, so
+automation can ignore noise and still catch real breakage.
+
+Exit codes:
+  0  No file has a problem at the --fail-on severity or above
+  2  One or more files have problems
+  4  dotcor is not initialized
+  5  The repository is in the middle of a merge or rebase
+
 Examples:
   dotcor status                # Show full status
   dotcor status --quick        # Show summary only
-  dotcor status --problems     # Show only files with issues`,
-	RunE: runStatus,
+  dotcor status --problems     # Show only files with issues
+  dotcor status --fail-on error  # Only fail on broken files`,
+	Annotations: readOnly,
+	RunE:        runStatus,
 }
 
 func init() {
 	statusCmd.Flags().BoolP("quick", "q", false, "Show summary only")
 	statusCmd.Flags().Bool("problems", false, "Show only files with problems")
 	statusCmd.Flags().Bool("json", false, "Output as JSON")
+	statusCmd.Flags().String("fail-on", severityInfo, "Lowest problem severity that fails: info, warn or error")
 	rootCmd.AddCommand(statusCmd)
 }
 
@@ -41,6 +59,14 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	quick, _ := cmd.Flags().GetBool("quick")
 	problemsOnly, _ := cmd.Flags().GetBool("problems")
 	jsonFormat, _ := cmd.Flags().GetBool("json")
+	failOn, _ := cmd.Flags().GetString("fail-on")
+
+	if err := validateSeverity(failOn); err != nil {
+		return err
+	}
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
 
 	// Load config
 	cfg, err := config.LoadConfig()
@@ -52,22 +78,54 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	status := collectStatus(cfg)
 
 	// Output
-	if jsonFormat {
-		return outputStatusJSON(status)
+	switch {
+	case jsonFormat:
+		err = outputStatusJSON(status)
+	case quick:
+		err = outputStatusQuick(status)
+	default:
+		err = outputStatusFull(status, problemsOnly)
+	}
+	if err != nil {
+		return err
 	}
+	return statusExitError(cmd, status, failOn)
+}
 
-	if quick {
-		return outputStatusQuick(status)
+// statusExitError returns the exit code error for what status found: a
+// conflict when the repository is mid-merge or mid-rebase, problems when
+// any file has a problem of severity failOn or above, nil otherwise
+func statusExitError(cmd *cobra.Command, status StatusReport, failOn string) error {
+	gitStatus := status.GitStatus
+	problems := 0
+	for _, group := range [][]FileStatus{status.Files, status.SystemFiles} {
+		for _, f := range group {
+			if f.Status != "ok" && severityRank(f.Severity) >= severityRank(failOn) {
+				problems++
+			}
+		}
 	}
 
-	return outputStatusFull(status, problemsOnly)
+	var exitErr *exitCodeError
+	switch {
+	case gitStatus.Conflicts > 0 || gitStatus.Merging || gitStatus.Rebasing:
+		exitErr = &exitCodeError{code: exitConflict, msg: "status found a merge or rebase to resolve in the repository"}
+	case problems > 0:
+		exitErr = &exitCodeError{code: exitProblems, msg: fmt.Sprintf("status found %d file(s) with problems", problems)}
+	default:
+		return nil
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return exitErr
 }
 
 // StatusReport contains all status information
 type StatusReport struct {
-	Files      []FileStatus
-	GitStatus  GitStatusInfo
-	Statistics StatusStats
+	Files       []FileStatus
+	SystemFiles []FileStatus // Files outside $HOME, reported as a separate group
+	GitStatus   GitStatusInfo
+	Statistics  StatusStats
 }
 
 // FileStatus represents the status of a single managed file
@@ -76,6 +134,50 @@ type FileStatus struct {
 	RepoPath   string
 	Status     string
 	Problem    string
+	Severity   string // severityInfo, severityWarn or severityError; empty when ok
+}
+
+// Severities of file problems, least serious first
+const (
+	severityInfo  = "info"  // Works, but not as dotcor would deploy it
+	severityWarn  = "warn"  // Drifted from the repo, or couldn't be checked
+	severityError = "error" // Not deployed from the repo
+)
+
+// validateSeverity returns an error if s isn't a problem severity
+func validateSeverity(s string) error {
+	if severityRank(s) == 0 {
+		return fmt.Errorf("invalid severity %q (use info, warn or error)", s)
+	}
+	return nil
+}
+
+// severityRank orders severities, 0 for unknown ones
+func severityRank(s string) int {
+	switch s {
+	case severityInfo:
+		return 1
+	case severityWarn:
+		return 2
+	case severityError:
+		return 3
+	}
+	return 0
+}
+
+// statusSeverity returns the severity of a file status, unless the check
+// that found it already set one
+func statusSeverity(status string) string {
+	switch status {
+	case "ok":
+		return ""
+	case "wrong-style":
+		return severityInfo
+	case "modified", "wrong-permissions", "not-rendered", "not-decrypted", "not-composed", "permission-denied":
+		return severityWarn
+	default:
+		return severityError
+	}
 }
 
 // GitStatusInfo contains git-related status
@@ -86,6 +188,15 @@ type GitStatusInfo struct {
 	AheadBy        int
 	BehindBy       int
 	RemoteExists   bool
+	Staged         int
+	Unstaged       int
+	Untracked      int
+	Conflicts      int
+	Detached       bool
+	Rebasing       bool
+	Merging        bool
+	Remotes        []git.RemoteStatus
+	Submodules     []git.Submodule
 }
 
 // StatusStats contains summary statistics
@@ -93,21 +204,54 @@ type StatusStats struct {
 	TotalFiles     int
 	HealthyFiles   int
 	ProblematicFiles int
+	SystemFiles      int
+	ProblematicSystemFiles int
 }
 
-// collectStatus gathers all status information
+// collectStatus gathers all status information. Files are checked in
+// parallel while git status runs, since both mostly wait on the disk.
 func collectStatus(cfg *config.Config) StatusReport {
 	report := StatusReport{}
 
-	// Get managed files
+	// Get git status
+	gitDone := make(chan struct{})
+	go func() {
+		defer close(gitDone)
+		repoPath, err := config.GetFilesRoot(cfg)
+		if err != nil || !git.IsAvailable() {
+			return
+		}
+		repo := git.OpenRepo(repoPath)
+		if !repo.IsRepo() {
+			return
+		}
+		gitStatus, _ := repo.Status()
+		report.GitStatus = GitStatusInfo{
+			IsRepo:         true,
+			HasUncommitted: gitStatus.HasUncommitted,
+			Branch:         gitStatus.Branch,
+			AheadBy:        gitStatus.AheadBy,
+			BehindBy:       gitStatus.BehindBy,
+			RemoteExists:   gitStatus.RemoteExists,
+			Staged:         gitStatus.StagedCount,
+			Unstaged:       gitStatus.UnstagedCount,
+			Untracked:      gitStatus.UntrackedCount,
+			Conflicts:      gitStatus.ConflictCount,
+			Detached:       gitStatus.Detached,
+			Rebasing:       gitStatus.Rebasing,
+			Merging:        gitStatus.Merging,
+			Remotes:        gitStatus.Remotes,
+		}
+		if git.IsGitInstalled() && git.HasSubmodules(repoPath) {
+			report.GitStatus.Submodules, _ = git.ListSubmodules(repoPath)
+		}
+	}()
+
+	// Check each managed file
 	files := cfg.GetManagedFilesForPlatform()
 	report.Statistics.TotalFiles = len(files)
-
-	// Check each file
-	for _, f := range files {
-		fs := checkFileStatus(cfg, f)
-		report.Files = append(report.Files, fs)
-
+	report.Files = checkFilesStatus(cfg, files)
+	for _, fs := range report.Files {
 		if fs.Status == "ok" {
 			report.Statistics.HealthyFiles++
 		} else {
@@ -115,25 +259,63 @@ func collectStatus(cfg *config.Config) StatusReport {
 		}
 	}
 
-	// Get git status
-	repoPath, err := config.ExpandPath(cfg.RepoPath)
-	if err == nil && git.IsGitInstalled() && git.IsRepo(repoPath) {
-		gitStatus, _ := git.GetStatus(repoPath)
-		report.GitStatus = GitStatusInfo{
-			IsRepo:         true,
-			HasUncommitted: gitStatus.HasUncommitted,
-			Branch:         gitStatus.Branch,
-			AheadBy:        gitStatus.AheadBy,
-			BehindBy:       gitStatus.BehindBy,
-			RemoteExists:   gitStatus.RemoteExists,
+	// Check system files separately
+	report.SystemFiles = checkFilesStatus(cfg, cfg.GetSystemFilesForPlatform())
+	report.Statistics.SystemFiles = len(report.SystemFiles)
+	for _, fs := range report.SystemFiles {
+		if fs.Status != "ok" {
+			report.Statistics.ProblematicSystemFiles++
 		}
 	}
 
+	<-gitDone
 	return report
 }
 
+// checkFilesStatus checks managed files in parallel, keeping their order
+func checkFilesStatus(cfg *config.Config, files []config.ManagedFile) []FileStatus {
+	return parallelMap(files, func(mf config.ManagedFile) FileStatus {
+		return checkFileStatus(cfg, mf)
+	})
+}
+
 // checkFileStatus checks the status of a single managed file
 func checkFileStatus(cfg *config.Config, mf config.ManagedFile) FileStatus {
+	status := checkPermissionStatus(cfg, mf)
+	if status.Severity == "" {
+		status.Severity = statusSeverity(status.Status)
+	}
+	return status
+}
+
+// checkPermissionStatus checks that a managed file is deployed correctly
+// with its recorded permissions
+func checkPermissionStatus(cfg *config.Config, mf config.ManagedFile) FileStatus {
+	status := checkDeployStatus(cfg, mf)
+	if status.Status != "ok" {
+		return status
+	}
+
+	// A deployed file can still have lost its recorded permissions, e.g. in a fresh clone
+	mismatches, err := core.CheckPermissions(cfg, mf)
+	if err != nil {
+		return permissionStatus(status, mf.SourcePath, err)
+	}
+	if len(mismatches) > 0 {
+		m := mismatches[0]
+		status.Status = "wrong-permissions"
+		status.Problem = fmt.Sprintf("mode %03o, want %03o, run 'dotcor doctor --fix'", m.Have, m.Want)
+		// The directory of a file next to keys, like ~/.ssh, has its own mode
+		if info, err := os.Stat(m.Path); err == nil && info.IsDir() {
+			dir, _ := config.NormalizePath(m.Path)
+			status.Problem = fmt.Sprintf("%s is %03o, want %03o, run 'dotcor doctor --fix'", dir, m.Have, m.Want)
+		}
+	}
+	return status
+}
+
+// checkDeployStatus checks that a managed file is deployed correctly
+func checkDeployStatus(cfg *config.Config, mf config.ManagedFile) FileStatus {
 	status := FileStatus{
 		SourcePath: mf.SourcePath,
 		RepoPath:   mf.RepoPath,
@@ -155,14 +337,72 @@ func checkFileStatus(cfg *config.Config, mf config.ManagedFile) FileStatus {
 	}
 
 	// Check if repo file exists
-	if !fs.FileExists(repoPath) {
+	repoExists, err := fs.CheckExists(repoPath)
+	if err != nil {
+		return permissionStatus(status, repoPath, err)
+	}
+	if !repoExists || !fs.FileExists(repoPath) {
 		status.Status = "missing-repo"
 		status.Problem = "file missing from repository"
 		return status
 	}
 
+	// Templates are linked to their rendered output
+	if mf.IsTemplate() {
+		repoPath, err = config.GetLinkTargetPath(cfg, mf)
+		if err != nil {
+			status.Status = "error"
+			status.Problem = "invalid rendered path"
+			return status
+		}
+		if !fs.FileExists(repoPath) {
+			status.Status = "not-rendered"
+			status.Problem = "template not rendered, run 'dotcor render'"
+			return status
+		}
+	}
+
+	// Secrets are linked to their decrypted copy
+	if mf.Encrypted {
+		repoPath, err = config.GetLinkTargetPath(cfg, mf)
+		if err != nil {
+			status.Status = "error"
+			status.Problem = "invalid decrypted path"
+			return status
+		}
+		if !fs.FileExists(repoPath) {
+			status.Status = "not-decrypted"
+			status.Problem = "secret not decrypted, run 'dotcor init --apply'"
+			return status
+		}
+	}
+
+	// System files are root-owned copies deployed by 'dotcor system apply'
+	if mf.IsSystem() {
+		return checkSystemStatus(status, mf, sourcePath, repoPath)
+	}
+
+	// Copies are compared with the repo instead of checked as symlinks
+	if mf.IsCopy() {
+		return checkCopyStatus(status, mf, sourcePath, repoPath)
+	}
+
+	// Hard links must share an inode with the repo file
+	if mf.IsHardlink() {
+		return checkHardlinkStatus(status, sourcePath, repoPath)
+	}
+
+	// Composed files must be the file dotcor generates
+	if mf.IsComposed() {
+		return checkComposedStatus(cfg, status, mf, sourcePath)
+	}
+
 	// Check if source path exists
-	if !fs.PathExists(sourcePath) {
+	sourceExists, err := fs.CheckExists(sourcePath)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
+	}
+	if !sourceExists || !fs.PathExists(sourcePath) {
 		status.Status = "missing-source"
 		status.Problem = "symlink missing"
 		return status
@@ -214,6 +454,23 @@ func checkFileStatus(cfg *config.Config, mf config.ManagedFile) FileStatus {
 		if resolvedTarget != repoPath {
 			status.Status = "wrong-target"
 			status.Problem = fmt.Sprintf("points to %s instead of repo file", target)
+			if reachesFile(sourcePath, repoPath) {
+				status.Problem = fmt.Sprintf("reaches the repo file through %s", target)
+				status.Severity = severityInfo
+			}
+			return status
+		}
+	}
+
+	// Check link style (system files always use absolute links)
+	if !mf.IsSystem() {
+		wantStyle := config.LinkStyleRelative
+		if cfg.UseAbsoluteLinks() {
+			wantStyle = config.LinkStyleAbsolute
+		}
+		if filepath.IsAbs(target) != cfg.UseAbsoluteLinks() {
+			status.Status = "wrong-style"
+			status.Problem = fmt.Sprintf("link_style is %s, run 'dotcor link-style %s' to convert", wantStyle, wantStyle)
 			return status
 		}
 	}
@@ -222,53 +479,196 @@ func checkFileStatus(cfg *config.Config, mf config.ManagedFile) FileStatus {
 	return status
 }
 
-// outputStatusFull outputs detailed status
-func outputStatusFull(status StatusReport, problemsOnly bool) error {
-	// Header
-	fmt.Println("DotCor Status")
-	fmt.Println("=============")
-	fmt.Println("")
+// checkCopyStatus checks a copy-mode file against the repo file it was
+// copied from (the rendered or decrypted output for templates and secrets)
+func checkCopyStatus(status FileStatus, mf config.ManagedFile, sourcePath, repoPath string) FileStatus {
+	sourceExists, err := fs.CheckExists(sourcePath)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
+	}
+	if !sourceExists {
+		status.Status = "missing-source"
+		status.Problem = "copy missing, run 'dotcor init --apply'"
+		return status
+	}
 
-	// Files section
-	if len(status.Files) > 0 {
-		fmt.Println("Managed Files:")
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		status.Status = "not-copy"
+		status.Problem = "source is a symlink, run 'dotcor init --apply' to copy it"
+		return status
+	}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	same, err := fs.SameContent(sourcePath, repoPath)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
+	}
+	if !same {
+		status.Status = "modified"
+		if mf.IsTemplate() || mf.Encrypted {
+			status.Problem = "local copy differs from repo, edit the repo file and run 'dotcor init --apply'"
+		} else {
+			status.Problem = "local copy differs from repo, run 'dotcor sync' to pull edits in"
+		}
+		return status
+	}
 
-		hasProblems := false
-		for _, f := range status.Files {
-			if problemsOnly && f.Status == "ok" {
-				continue
-			}
+	status.Status = "ok"
+	return status
+}
 
-			icon := getStatusIcon(f.Status)
-			if f.Status == "ok" {
-				fmt.Fprintf(w, "  %s %s\tok\n", icon, f.SourcePath)
-			} else {
-				fmt.Fprintf(w, "  %s %s\t%s\n", icon, f.SourcePath, f.Problem)
-				hasProblems = true
-			}
-		}
+// checkSystemStatus checks a system file is a copy of the repo file with
+// its recorded owner and mode
+func checkSystemStatus(status FileStatus, mf config.ManagedFile, sourcePath, repoPath string) FileStatus {
+	sourceExists, err := fs.CheckExists(sourcePath)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
+	}
+	if !sourceExists {
+		status.Status = "missing-source"
+		status.Problem = "not deployed, run 'dotcor system apply'"
+		return status
+	}
+
+	// Older versions linked system files to the user-owned repo copy
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		status.Status = "not-copy"
+		status.Problem = "symlink into the repo, run 'dotcor system apply' to replace it with a copy"
+		return status
+	}
 
-		w.Flush()
+	same, err := fs.SameContent(sourcePath, repoPath)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
+	}
+	if !same {
+		status.Status = "modified"
+		status.Problem = "differs from repo, run 'dotcor system apply'"
+		return status
+	}
 
-		if problemsOnly && !hasProblems {
-			fmt.Println("  All files are healthy!")
+	if mf.Owner != "" {
+		if owner, err := fs.GetOwner(sourcePath); err == nil && owner != mf.Owner {
+			status.Status = "wrong-owner"
+			status.Problem = fmt.Sprintf("owned by %s, want %s, run 'dotcor system apply'", owner, mf.Owner)
+			return status
+		}
+	}
+	if want, ok, _ := mf.FilePerm(); ok {
+		if info, err := os.Stat(sourcePath); err == nil && info.Mode().Perm() != want {
+			status.Status = "wrong-permissions"
+			status.Problem = fmt.Sprintf("mode %03o, want %03o, run 'dotcor system apply'", info.Mode().Perm(), want)
+			return status
 		}
+	}
 
-		fmt.Println("")
+	status.Status = "ok"
+	return status
+}
+
+// checkHardlinkStatus checks a hardlink-mode file still shares its inode
+// with the repo file
+func checkHardlinkStatus(status FileStatus, sourcePath, repoPath string) FileStatus {
+	sourceExists, err := fs.CheckExists(sourcePath)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
+	}
+	if !sourceExists {
+		status.Status = "missing-source"
+		status.Problem = "hard link missing, run 'dotcor init --apply'"
+		return status
+	}
+
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		status.Status = "not-hardlink"
+		status.Problem = "source is a symlink, run 'dotcor init --apply' to hard link it"
+		return status
+	}
+
+	same, err := fs.IsHardlinkTo(sourcePath, repoPath)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
+	}
+	if !same {
+		status.Status = "not-hardlink"
+		status.Problem = "hard link broken (file was replaced), run 'dotcor doctor --fix'"
+		return status
+	}
+
+	status.Status = "ok"
+	return status
+}
+
+// checkComposedStatus checks a compose-mode file is the generated file
+// that includes its repo file
+func checkComposedStatus(cfg *config.Config, status FileStatus, mf config.ManagedFile, sourcePath string) FileStatus {
+	sourceExists, err := fs.CheckExists(sourcePath)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
+	}
+	if !sourceExists {
+		status.Status = "missing-source"
+		status.Problem = "composed file missing, run 'dotcor init --apply'"
+		return status
+	}
+
+	intact, err := core.ComposedIntact(cfg, mf)
+	if err != nil {
+		return permissionStatus(status, sourcePath, err)
 	}
+	if !intact {
+		status.Status = "not-composed"
+		status.Problem = fmt.Sprintf("composed file replaced or edited, move edits into %s and run 'dotcor init --apply'", mf.LocalPath())
+		return status
+	}
+
+	status.Status = "ok"
+	return status
+}
+
+// permissionStatus reports an error checking path, distinguishing
+// permission problems (with a suggested fix) from other failures
+func permissionStatus(status FileStatus, path string, err error) FileStatus {
+	if fs.IsPermissionError(err) {
+		status.Status = "permission-denied"
+		status.Problem = fmt.Sprintf("permission denied (%s)", fs.PermissionHint(path))
+		return status
+	}
+
+	status.Status = "error"
+	status.Problem = err.Error()
+	return status
+}
+
+// outputStatusFull outputs detailed status
+func outputStatusFull(status StatusReport, problemsOnly bool) error {
+	// Header
+	fmt.Println("DotCor Status")
+	fmt.Println("=============")
+	fmt.Println("")
+
+	// Files sections
+	outputFileGroup("Managed Files:", status.Files, problemsOnly)
+	outputFileGroup("System Files:", status.SystemFiles, problemsOnly)
 
 	// Git section
 	if status.GitStatus.IsRepo {
 		fmt.Println("Git Repository:")
 
-		if status.GitStatus.Branch != "" {
+		if status.GitStatus.Detached {
+			fmt.Println("  Branch: (detached HEAD)")
+		} else if status.GitStatus.Branch != "" {
 			fmt.Printf("  Branch: %s\n", status.GitStatus.Branch)
 		}
 
+		if status.GitStatus.Rebasing {
+			fmt.Println("  ⚠ Rebase in progress")
+		}
+		if status.GitStatus.Merging {
+			fmt.Println("  ⚠ Merge in progress")
+		}
+
 		if status.GitStatus.HasUncommitted {
-			fmt.Println("  ⚠ Uncommitted changes")
+			fmt.Printf("  ⚠ Uncommitted changes: %s\n", describeChanges(status.GitStatus))
 		} else {
 			fmt.Println("  ✓ Working tree clean")
 		}
@@ -287,6 +687,28 @@ func outputStatusFull(status StatusReport, problemsOnly bool) error {
 			fmt.Println("  - No remote configured")
 		}
 
+		// Submodules, e.g. vendored plugins, that aren't as recorded
+		for _, s := range status.GitStatus.Submodules {
+			if s.NeedsAttention() {
+				fmt.Printf("  ⚠ Submodule %s: %s\n", s.Path, s.State())
+			}
+		}
+
+		// Remotes besides origin, such as push mirrors from git_remotes
+		for _, r := range status.GitStatus.Remotes {
+			switch {
+			case r.Name == "origin":
+			case !r.Tracked:
+				fmt.Printf("  - %s: branch not pushed yet\n", r.Name)
+			case r.AheadBy > 0:
+				fmt.Printf("  ↑ %d commit(s) ahead of %s\n", r.AheadBy, r.Name)
+			case r.BehindBy > 0:
+				fmt.Printf("  ↓ %d commit(s) behind %s\n", r.BehindBy, r.Name)
+			default:
+				fmt.Printf("  ✓ In sync with %s\n", r.Name)
+			}
+		}
+
 		fmt.Println("")
 	}
 
@@ -295,10 +717,16 @@ func outputStatusFull(status StatusReport, problemsOnly bool) error {
 	if status.Statistics.ProblematicFiles > 0 {
 		fmt.Printf(", %d with issues", status.Statistics.ProblematicFiles)
 	}
+	if status.Statistics.SystemFiles > 0 {
+		fmt.Printf("; %d system files", status.Statistics.SystemFiles)
+		if status.Statistics.ProblematicSystemFiles > 0 {
+			fmt.Printf(", %d with issues", status.Statistics.ProblematicSystemFiles)
+		}
+	}
 	fmt.Println("")
 
 	// Suggestions
-	if status.Statistics.ProblematicFiles > 0 {
+	if status.Statistics.ProblematicFiles > 0 || status.Statistics.ProblematicSystemFiles > 0 {
 		fmt.Println("")
 		fmt.Println("Run 'dotcor doctor' for detailed diagnostics and repair suggestions.")
 	}
@@ -316,13 +744,74 @@ func outputStatusQuick(status StatusReport) error {
 			status.Statistics.TotalFiles, status.Statistics.ProblematicFiles)
 	}
 
+	if status.Statistics.SystemFiles > 0 {
+		if status.Statistics.ProblematicSystemFiles == 0 {
+			fmt.Printf("✓ %d system files managed, all healthy\n", status.Statistics.SystemFiles)
+		} else {
+			fmt.Printf("⚠ %d system files managed, %d with issues\n",
+				status.Statistics.SystemFiles, status.Statistics.ProblematicSystemFiles)
+		}
+	}
+
 	if status.GitStatus.IsRepo && status.GitStatus.HasUncommitted {
-		fmt.Println("⚠ Uncommitted changes in repository")
+		fmt.Printf("⚠ Uncommitted changes in repository (%s)\n", describeChanges(status.GitStatus))
 	}
 
 	return nil
 }
 
+// describeChanges summarizes uncommitted changes, e.g. "1 staged, 2 modified"
+func describeChanges(g GitStatusInfo) string {
+	var parts []string
+	if g.Staged > 0 {
+		parts = append(parts, fmt.Sprintf("%d staged", g.Staged))
+	}
+	if g.Unstaged > 0 {
+		parts = append(parts, fmt.Sprintf("%d modified", g.Unstaged))
+	}
+	if g.Untracked > 0 {
+		parts = append(parts, fmt.Sprintf("%d untracked", g.Untracked))
+	}
+	if g.Conflicts > 0 {
+		parts = append(parts, fmt.Sprintf("%d conflicted", g.Conflicts))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// outputFileGroup prints one group of file statuses under a title
+func outputFileGroup(title string, files []FileStatus, problemsOnly bool) {
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Println(title)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	hasProblems := false
+	for _, f := range files {
+		if problemsOnly && f.Status == "ok" {
+			continue
+		}
+
+		icon := getStatusIcon(f)
+		if f.Status == "ok" {
+			fmt.Fprintf(w, "  %s %s\tok\n", icon, f.SourcePath)
+		} else {
+			fmt.Fprintf(w, "  %s %s\t%s\n", icon, f.SourcePath, paintSeverity(f.Problem, f.Severity))
+			hasProblems = true
+		}
+	}
+
+	w.Flush()
+
+	if problemsOnly && !hasProblems {
+		fmt.Println("  All files are healthy!")
+	}
+
+	fmt.Println("")
+}
+
 // statusJSONOutput represents the JSON structure for status output
 type statusJSONOutput struct {
 	TotalFiles       int              `json:"total_files"`
@@ -330,20 +819,44 @@ type statusJSONOutput struct {
 	ProblematicFiles int              `json:"problematic_files"`
 	Git              *gitJSONOutput   `json:"git,omitempty"`
 	Files            []fileJSONOutput `json:"files"`
+	SystemFiles      []fileJSONOutput `json:"system_files,omitempty"`
 }
 
 type gitJSONOutput struct {
-	Branch       string `json:"branch"`
-	Uncommitted  bool   `json:"uncommitted"`
-	Ahead        int    `json:"ahead"`
-	Behind       int    `json:"behind"`
-	RemoteExists bool   `json:"remote_exists"`
+	Branch       string                `json:"branch"`
+	Uncommitted  bool                  `json:"uncommitted"`
+	Staged       int                   `json:"staged"`
+	Unstaged     int                   `json:"unstaged"`
+	Untracked    int                   `json:"untracked"`
+	Conflicts    int                   `json:"conflicts"`
+	Detached     bool                  `json:"detached"`
+	Rebasing     bool                  `json:"rebasing"`
+	Merging      bool                  `json:"merging"`
+	Ahead        int                   `json:"ahead"`
+	Behind       int                   `json:"behind"`
+	RemoteExists bool                  `json:"remote_exists"`
+	Remotes      []remoteJSONOutput    `json:"remotes,omitempty"`
+	Submodules   []submoduleJSONOutput `json:"submodules,omitempty"`
+}
+
+type submoduleJSONOutput struct {
+	Path   string `json:"path"`
+	Commit string `json:"commit"`
+	State  string `json:"state"` // ok, not initialized, different commit, modified or conflict
+}
+
+type remoteJSONOutput struct {
+	Name    string `json:"name"`
+	Tracked bool   `json:"tracked"`
+	Ahead   int    `json:"ahead"`
+	Behind  int    `json:"behind"`
 }
 
 type fileJSONOutput struct {
-	Source  string `json:"source"`
-	Status  string `json:"status"`
-	Problem string `json:"problem"`
+	Source   string `json:"source"`
+	Status   string `json:"status"`
+	Problem  string `json:"problem"`
+	Severity string `json:"severity,omitempty"`
 }
 
 // outputStatusJSON outputs status as JSON
@@ -359,22 +872,39 @@ func outputStatusJSON(status StatusReport) error {
 		output.Git = &gitJSONOutput{
 			Branch:       status.GitStatus.Branch,
 			Uncommitted:  status.GitStatus.HasUncommitted,
+			Staged:       status.GitStatus.Staged,
+			Unstaged:     status.GitStatus.Unstaged,
+			Untracked:    status.GitStatus.Untracked,
+			Conflicts:    status.GitStatus.Conflicts,
+			Detached:     status.GitStatus.Detached,
+			Rebasing:     status.GitStatus.Rebasing,
+			Merging:      status.GitStatus.Merging,
 			Ahead:        status.GitStatus.AheadBy,
 			Behind:       status.GitStatus.BehindBy,
 			RemoteExists: status.GitStatus.RemoteExists,
 		}
+		for _, r := range status.GitStatus.Remotes {
+			output.Git.Remotes = append(output.Git.Remotes, remoteJSONOutput{
+				Name:    r.Name,
+				Tracked: r.Tracked,
+				Ahead:   r.AheadBy,
+				Behind:  r.BehindBy,
+			})
+		}
+		for _, s := range status.GitStatus.Submodules {
+			output.Git.Submodules = append(output.Git.Submodules, submoduleJSONOutput{
+				Path:   s.Path,
+				Commit: s.Commit,
+				State:  s.State(),
+			})
+		}
 	}
 
 	for _, f := range status.Files {
-		problem := f.Problem
-		if problem == "" {
-			problem = "none"
-		}
-		output.Files = append(output.Files, fileJSONOutput{
-			Source:  f.SourcePath,
-			Status:  f.Status,
-			Problem: problem,
-		})
+		output.Files = append(output.Files, toFileJSON(f))
+	}
+	for _, f := range status.SystemFiles {
+		output.SystemFiles = append(output.SystemFiles, toFileJSON(f))
 	}
 
 	data, err := json.MarshalIndent(output, "", "  ")
@@ -386,18 +916,61 @@ func outputStatusJSON(status StatusReport) error {
 	return nil
 }
 
-// getStatusIcon returns an icon for the given status
-func getStatusIcon(status string) string {
-	switch status {
-	case "ok":
-		return "✓"
-	case "missing-repo", "missing-source", "broken", "not-symlink", "wrong-target":
-		return "✗"
+// toFileJSON converts a file status to its JSON form
+func toFileJSON(f FileStatus) fileJSONOutput {
+	problem := f.Problem
+	if problem == "" {
+		problem = "none"
+	}
+	return fileJSONOutput{
+		Source:   f.SourcePath,
+		Status:   f.Status,
+		Problem:  problem,
+		Severity: f.Severity,
+	}
+}
+
+// getStatusIcon returns an icon for a file's status, colored by severity
+func getStatusIcon(f FileStatus) string {
+	if f.Status == "ok" {
+		return paint("✓", colorGreen)
+	}
+	switch f.Severity {
+	case severityInfo:
+		return paintSeverity("ℹ", f.Severity)
+	case severityWarn:
+		return paintSeverity("⚠", f.Severity)
+	case severityError:
+		return paintSeverity("✗", f.Severity)
 	default:
 		return "?"
 	}
 }
 
+// paintSeverity colors text by problem severity
+func paintSeverity(text, severity string) string {
+	switch severity {
+	case severityInfo:
+		return paint(text, colorCyan)
+	case severityWarn:
+		return paint(text, colorYellow)
+	case severityError:
+		return paint(text, colorRed)
+	}
+	return text
+}
+
+// reachesFile reports whether the link at path leads to file through other
+// links
+func reachesFile(path, file string) bool {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	want, err := filepath.EvalSymlinks(file)
+	return err == nil && resolved == want
+}
+
 // CheckLockStatus checks if there's a stale lock (used by doctor)
 func CheckLockStatus() (bool, *core.LockInfo, error) {
 	info, err := core.GetLockInfo()
@@ -428,11 +1001,7 @@ func CheckLockStatus() (bool, *core.LockInfo, error) {
 
 // getLockPathForCheck returns lock path for checking (internal use)
 func getLockPathForCheck() (string, error) {
-	configDir, err := config.GetConfigDir()
-	if err != nil {
-		return "", err
-	}
-	return configDir + "/.lock", nil
+	return config.GetLockPath()
 }
 
 // Note: getDir and resolvePath are defined in list.go
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+// ANSI color codes
+const (
+	colorReset     = "\033[0m"
+	colorDim       = "\033[2m"
+	colorBold      = "\033[1m"
+	colorRed       = "\033[31m"
+	colorGreen     = "\033[32m"
+	colorYellow    = "\033[33m"
+	colorCyan      = "\033[36m"
+	colorWhite     = "\033[97m"
+	colorOrange    = "\033[38;5;208m"
+	colorPink      = "\033[38;5;205m"
+	colorLightPink = "\033[38;5;218m"
+	colorLime      = "\033[38;5;118m"
+)
+
+// useColor is whether paint adds color, decided by selectColor
+var useColor = false
+
+// selectColor turns colors on when stdout is a terminal, unless --no-color
+// is given or $NO_COLOR is set (https://no-color.org)
+func selectColor(cmd *cobra.Command) {
+	noColor, _ := cmd.Flags().GetBool("no-color")
+	useColor = !noColor && os.Getenv("NO_COLOR") == "" && isTerminal()
+}
+
+// paint wraps text in the given color codes when colors are on
+func paint(text string, codes ...string) string {
+	if !useColor || len(codes) == 0 || text == "" {
+		return text
+	}
+	return strings.Join(codes, "") + text + colorReset
+}
+
+// isTerminal checks if stdout is a terminal
+func isTerminal() bool {
+	return term.IsTerminal(os.Stdout.Fd())
+}
+
+// stdinIsTerminal reports whether stdin is a terminal. Tests replace it to
+// answer prompts.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(os.Stdin.Fd())
+}
+
+// stderrIsTerminal reports whether stderr is a terminal
+func stderrIsTerminal() bool {
+	return term.IsTerminal(os.Stderr.Fd())
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Print the quick status lines",
+	Long: `Print the status lines dotcor shows when run without a command, without
+the banner and headings, for shell prompts and tmux status bars.
+
+The summary comes from the same cache as the banner, so it is instant
+unless something changed. With --compact, or ui.compact set in
+config.yaml, the lines are joined into one.
+
+Examples:
+  dotcor summary
+  dotcor summary --compact     # e.g. in tmux: #(dotcor summary --compact)`,
+	Args:        cobra.NoArgs,
+	Annotations: readOnly,
+	RunE:        runSummary,
+}
+
+func init() {
+	summaryCmd.Flags().Bool("compact", false, "Print everything on one line (default: ui.compact)")
+	rootCmd.AddCommand(summaryCmd)
+}
+
+func runSummary(cmd *cobra.Command, args []string) error {
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	compact := cfg.UI.Compact
+	if cmd.Flags().Changed("compact") {
+		compact, _ = cmd.Flags().GetBool("compact")
+	}
+
+	lines := summaryLines(loadStatusSummary(cfg))
+	if compact {
+		fmt.Println(strings.Join(lines, " · "))
+		return nil
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -1,16 +1,17 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/core"
 	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/output"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var syncCmd = &cobra.Command{
@@ -21,19 +22,82 @@ var syncCmd = &cobra.Command{
 This command:
 1. Checks for uncommitted changes
 2. Creates a timestamped commit
-3. Pushes to remote (if configured and not --no-push)
+3. Pulls from remote (with --pull or --rebase), backing up linked files the
+   pull changes
+4. Pushes to origin and every remote in git_remotes (unless --no-push)
+
+Pulls always come from origin. Before pushing, origin is fetched; if it has
+commits that aren't here, nothing is pushed. Run again with --rebase to
+replay your commits on top of them, or with --pull to merge them. A push that fails on one remote doesn't stop
+the others; each remote's result is reported.
+
+On a machine with git_branch set (see 'dotcor branch'), sync refuses to run
+while another branch is checked out. --branch switches to the named branch
+first, creating it if it exists neither here nor on origin, and makes it this
+machine's git_branch. A branch is pushed with its upstream set on the first
+push.
+
+Submodules, such as vendored shell plugins, are checked out at their
+recorded commits after a pull. --submodules moves them to the latest commit
+of the branch they track and commits the new commits along with your changes.
+
+Backups taken before a pull can be restored with 'dotcor restore --from-backup'.
+
+Exit codes:
+  0  Synced, or nothing to sync
+  1  Sync failed, e.g. origin has newer commits or a push was rejected
+  3  Another dotcor command holds the lock
+  4  dotcor is not initialized
+  5  A merge or rebase in the repository needs resolving first
 
 Examples:
   dotcor sync                 # Commit and push
   dotcor sync --no-push       # Commit only
-  dotcor sync --preview       # Show what would be synced
+  dotcor sync --pull          # Pull remote changes before pushing
+  dotcor sync --rebase        # Rebase onto remote changes before pushing
+  dotcor sync --branch machine/laptop  # Sync this machine's own branch
+  dotcor sync --submodules    # Update vendored plugins too
+  dotcor sync --preview       # Show what would be synced (or --dry-run)
   dotcor sync -m "message"    # Custom commit message`,
-	RunE: runSync,
+	RunE:        runSync,
+	Annotations: structuredOutput,
+}
+
+// syncResult is the structured output of 'dotcor sync'
+type syncResult struct {
+	Status    string       `json:"status" yaml:"status"` // synced, up-to-date, cancelled, preview or push-failed
+	Changes   []string     `json:"changes" yaml:"changes"`
+	Copies    []string     `json:"copies,omitempty" yaml:"copies,omitempty"` // Copy-mode files with local edits
+	Committed bool         `json:"committed" yaml:"committed"`
+	Pulled    []string     `json:"pulled,omitempty" yaml:"pulled,omitempty"`
+	AheadBy   int          `json:"ahead_by" yaml:"ahead_by"`
+	BehindBy  int          `json:"behind_by" yaml:"behind_by"`
+	Pushed    bool         `json:"pushed" yaml:"pushed"` // Every remote was pushed to
+	Remotes   []remotePush `json:"remotes,omitempty" yaml:"remotes,omitempty"`
+}
+
+// remotePush is the outcome of pushing to one remote
+type remotePush struct {
+	Name   string `json:"name" yaml:"name"`
+	Pushed bool   `json:"pushed" yaml:"pushed"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
 func init() {
 	syncCmd.Flags().Bool("no-push", false, "Commit but don't push to remote")
+	syncCmd.Flags().Bool("pull", false, "Pull from remote before pushing (backs up affected files)")
+	syncCmd.Flags().Bool("rebase", false, "Pull with rebase before pushing, keeping history linear (backs up affected files)")
+	syncCmd.MarkFlagsMutuallyExclusive("pull", "rebase")
 	syncCmd.Flags().Bool("preview", false, "Show what would be synced without making changes")
+	// --dry-run is accepted like in add, remove and init --apply
+	syncCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "dry-run" {
+			name = "preview"
+		}
+		return pflag.NormalizedName(name)
+	})
+	syncCmd.Flags().String("branch", "", "Switch to this branch (created if missing) and sync it")
+	syncCmd.Flags().Bool("submodules", false, "Update submodules to their latest upstream commits and commit them")
 	syncCmd.Flags().BoolP("force", "f", false, "Sync without confirmation")
 	syncCmd.Flags().StringP("message", "m", "", "Custom commit message")
 	rootCmd.AddCommand(syncCmd)
@@ -41,9 +105,17 @@ func init() {
 
 func runSync(cmd *cobra.Command, args []string) error {
 	noPush, _ := cmd.Flags().GetBool("no-push")
+	pull, _ := cmd.Flags().GetBool("pull")
+	rebase, _ := cmd.Flags().GetBool("rebase")
 	preview, _ := cmd.Flags().GetBool("preview")
 	force, _ := cmd.Flags().GetBool("force")
 	message, _ := cmd.Flags().GetString("message")
+	branch, _ := cmd.Flags().GetString("branch")
+	bumpSubmodules, _ := cmd.Flags().GetBool("submodules")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
 
 	// Load config
 	cfg, err := config.LoadConfig()
@@ -52,119 +124,307 @@ func runSync(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check if git is available
-	if !git.IsGitInstalled() {
+	if !git.IsAvailable() {
 		return fmt.Errorf("git is not installed")
 	}
 
 	// Get repo path
-	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	repoPath, err := config.GetFilesRoot(cfg)
 	if err != nil {
 		return fmt.Errorf("expanding repo path: %w", err)
 	}
 
 	// Check if it's a git repo
-	if !git.IsRepo(repoPath) {
+	repo := git.OpenRepo(repoPath)
+	if !repo.IsRepo() {
 		return fmt.Errorf("dotcor repository is not a git repository")
 	}
 
-	// Check for changes
-	hasChanges, err := git.HasChanges(repoPath)
+	// Get git status (branch, remote and changed files in one pass)
+	gitStatus, err := repo.Status()
 	if err != nil {
-		return fmt.Errorf("checking for changes: %w", err)
+		return fmt.Errorf("getting git status: %w", err)
 	}
 
-	// Get git status
-	gitStatus, err := git.GetStatus(repoPath)
-	if err != nil {
-		return fmt.Errorf("getting git status: %w", err)
+	// Local edits to copy-mode files are pulled into the repo and committed
+	editedCopies := core.EditedCopies(cfg)
+	hasChanges := gitStatus.HasUncommitted || len(editedCopies) > 0
+
+	result := syncResult{
+		Changes:  []string{},
+		AheadBy:  gitStatus.AheadBy,
+		BehindBy: gitStatus.BehindBy,
+	}
+	for _, entry := range gitStatus.Changes {
+		result.Changes = append(result.Changes, entry.DisplayPath())
+	}
+	for _, mf := range editedCopies {
+		result.Copies = append(result.Copies, mf.SourcePath)
+	}
+
+	// Don't commit on top of a half-finished rebase or merge
+	if err := core.CheckSyncState(repoPath, gitStatus); err != nil {
+		return conflictError(cmd, err.Error())
+	}
+
+	// Sync the branch this machine is set up for, or the one given with --branch
+	switchTo := ""
+	if branch != "" {
+		if err := git.ValidateBranchName(branch); err != nil {
+			return err
+		}
+		if branch != gitStatus.Branch {
+			switchTo = branch
+		}
+	} else if err := core.CheckSyncBranch(cfg, gitStatus); err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%w, or sync another branch with --branch", err)
+	}
+	createBranch := switchTo != "" && !git.BranchExists(repoPath, switchTo) && !git.RemoteBranchExists(repoPath, "origin", switchTo)
+	if switchTo != "" {
+		if err := checkBranchCheckout(cmd, repo, switchTo, createBranch); err != nil {
+			return err
+		}
 	}
 
 	// Preview mode
 	if preview {
-		return showSyncPreview(repoPath, hasChanges, gitStatus, noPush)
+		if switchTo != "" && !output.Structured() {
+			fmt.Printf("Would switch to branch %s first.\n\n", switchTo)
+		}
+		if output.Structured() {
+			result.Status = "preview"
+			return output.Write(result)
+		}
+		return showSyncPreview(cfg, repoPath, hasChanges, gitStatus, editedCopies, noPush)
+	}
+
+	// Extra remotes can lag behind origin, e.g. after a failed push
+	toPush := gitStatus.AheadBy
+	if lag := remotesBehind(cfg, gitStatus); lag > toPush {
+		toPush = lag
 	}
 
 	// Nothing to sync
-	if !hasChanges && gitStatus.AheadBy == 0 {
+	if !hasChanges && toPush == 0 && !pull && !rebase && switchTo == "" && !bumpSubmodules {
 		fmt.Println("Nothing to sync. Working tree is clean and up to date.")
-		return nil
+		result.Status = "up-to-date"
+		return writeResult(result)
 	}
 
 	// Show what will be synced
 	if hasChanges {
 		fmt.Println("Changes to be committed:")
-		changedFiles, _ := git.GetChangedFiles(repoPath)
-		for _, f := range changedFiles {
-			fmt.Printf("  %s\n", f)
+		for _, entry := range gitStatus.Changes {
+			fmt.Printf("  %s\n", entry.DisplayPath())
+		}
+		for _, mf := range editedCopies {
+			fmt.Printf("  %s (local copy)\n", mf.RepoPath)
 		}
 		fmt.Println("")
 	}
 
-	if gitStatus.AheadBy > 0 && !noPush {
-		fmt.Printf("%d commit(s) to push to remote.\n", gitStatus.AheadBy)
+	if toPush > 0 && !noPush {
+		fmt.Printf("%d commit(s) to push to remote.\n", toPush)
 		fmt.Println("")
 	}
 
 	// Confirm unless --force
 	if !force {
-		if !confirmSync(hasChanges, gitStatus.AheadBy > 0 && !noPush) {
+		ok, err := confirmSync(hasChanges, toPush > 0 && !noPush)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			fmt.Println("Sync cancelled.")
-			return nil
+			result.Status = "cancelled"
+			return writeResult(result)
 		}
 	}
 
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+
 	// Acquire lock
 	if err := core.AcquireLock(); err != nil {
 		return fmt.Errorf("acquiring lock: %w", err)
 	}
 	defer core.ReleaseLock()
 
+	if err := runHooks(cmd, cfg, config.HookPreSync); err != nil {
+		return err
+	}
+
+	if switchTo != "" {
+		if err := checkoutBranch(cfg, repo, switchTo, createBranch); err != nil {
+			return err
+		}
+		cfg.GitBranch = switchTo
+		if err := cfg.SaveConfig(); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+		// Uncommitted changes came along; the branch's commits may differ
+		if gitStatus, err = repo.Status(); err != nil {
+			return fmt.Errorf("getting git status: %w", err)
+		}
+		result.AheadBy, result.BehindBy = gitStatus.AheadBy, gitStatus.BehindBy
+	}
+
+	// Files changed by this sync get their per-file hooks run afterwards
+	var changedPaths []string
+	for _, entry := range gitStatus.Changes {
+		changedPaths = append(changedPaths, filesRootRelative(cfg, entry.Path))
+	}
+
+	if bumpSubmodules {
+		bumped, err := bumpRepoSubmodules(repo)
+		if err != nil {
+			return err
+		}
+		if len(bumped) > 0 {
+			hasChanges = true
+			result.Changes = append(result.Changes, bumped...)
+		}
+	}
+
+	for _, mf := range editedCopies {
+		if err := core.PullCopyEdits(cfg, mf); err != nil {
+			return fmt.Errorf("pulling local edits: %w", err)
+		}
+		fmt.Printf("✓ Pulled local edits of %s\n", mf.SourcePath)
+		changedPaths = append(changedPaths, mf.RepoPath)
+	}
+
 	// Commit changes
 	if hasChanges {
-		commitMsg := message
-		if commitMsg == "" {
-			commitMsg = fmt.Sprintf("Sync dotfiles - %s", time.Now().Format("2006-01-02 15:04"))
+		committed, err := core.CommitSync(repoPath, message)
+		if err != nil {
+			return err
+		}
+		if committed {
+			fmt.Println("✓ Changes committed")
+			result.Committed = true
 		}
+		repo.Refresh()
+	}
 
-		if err := git.AutoCommit(repoPath, commitMsg); err != nil {
-			return fmt.Errorf("committing changes: %w", err)
+	// Pull remote changes
+	if pull || rebase {
+		incoming, err := pullWithBackup(cfg, repo, rebase)
+		if err != nil {
+			// A pull that stopped on conflicts leaves a merge to resolve
+			repo.Refresh()
+			if st, statusErr := repo.Status(); statusErr == nil && (st.Merging || st.Rebasing || st.ConflictCount > 0) {
+				return conflictError(cmd, fmt.Sprintf("pulling from remote: %v\nResolve the conflicts in %s with git, then run 'dotcor sync' again", err, repoPath))
+			}
+			return fmt.Errorf("pulling from remote: %w", err)
 		}
-		fmt.Println("✓ Changes committed")
+		changedPaths = append(changedPaths, incoming...)
+		result.Pulled = incoming
+
+		redeployChanged(cfg, incoming)
 	}
 
+	// What was committed and pulled is the new known-good content
+	recordChecksums(cfg, changedPaths...)
+
 	// Push to remote
 	if !noPush {
-		// Check if remote exists
-		remoteURL, _ := git.GetRemoteURL(repoPath)
-		if remoteURL != "" {
-			if err := pushToRemote(repoPath); err != nil {
+		if gitStatus.Detached {
+			fmt.Println("⚠ HEAD is detached, not pushing. Check out a branch to push.")
+		} else {
+			pushes, err := pushAllRemotes(cfg, repo)
+			if len(pushes) == 0 {
+				fmt.Println("⚠ No remote configured. Use 'git remote add origin <url>' to set up.")
+			}
+			result.Remotes = pushes
+			if err != nil {
+				// Which remotes were pushed to is still reported
+				result.Status = "push-failed"
+				if err := writeResult(result); err != nil {
+					return err
+				}
+			}
+			if errors.Is(err, git.ErrBehindRemote) {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("pushing to remote: %w\nRun 'dotcor sync --rebase' to replay your commits on top of the remote's, or 'dotcor sync --pull' to merge them", err)
+			}
+			if err != nil {
 				return fmt.Errorf("pushing to remote: %w", err)
 			}
-			fmt.Println("✓ Pushed to remote")
-		} else {
-			fmt.Println("⚠ No remote configured. Use 'git remote add origin <url>' to set up.")
+			result.Pushed = len(pushes) > 0
 		}
 	}
 
 	fmt.Println("")
 	fmt.Println("Sync complete!")
-	return nil
+
+	runFileHooks(cmd, cfg, config.HookPostSync, core.ManagedFilesAt(cfg, changedPaths))
+	if err := runHooks(cmd, cfg, config.HookPostSync); err != nil {
+		return err
+	}
+
+	result.Status = "synced"
+	return writeResult(result)
+}
+
+// conflictError fails sync with exitConflict, for a merge or rebase in the
+// repository that has to be resolved by hand
+func conflictError(cmd *cobra.Command, msg string) error {
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &exitCodeError{code: exitConflict, msg: msg}
+}
+
+// filesRootRelative converts a path relative to the repository root into
+// one relative to the files root, as used by managed file repo paths
+func filesRootRelative(cfg *config.Config, path string) string {
+	path = filepath.ToSlash(path)
+	if cfg.FilesSubdir == "" {
+		return path
+	}
+	return strings.TrimPrefix(path, filepath.ToSlash(cfg.FilesSubdir)+"/")
+}
+
+// redeployChanged updates the copies and hard links of the managed files
+// at repoPaths after git changed them, reporting each
+func redeployChanged(cfg *config.Config, repoPaths []string) {
+	for _, r := range core.RedeployChanged(cfg, repoPaths) {
+		switch {
+		case r.Err != nil && r.File.IsCopy():
+			fmt.Printf("⚠ Updating copy of %s failed: %v\n", r.File.SourcePath, r.Err)
+		case r.Err != nil:
+			fmt.Printf("⚠ Relinking %s failed: %v\n", r.File.SourcePath, r.Err)
+		case r.File.IsCopy():
+			fmt.Printf("✓ Updated copy of %s\n", r.File.SourcePath)
+		default:
+			fmt.Printf("✓ Relinked %s\n", r.File.SourcePath)
+		}
+	}
 }
 
 // showSyncPreview shows what would be synced
-func showSyncPreview(repoPath string, hasChanges bool, gitStatus git.StatusInfo, noPush bool) error {
+func showSyncPreview(cfg *config.Config, repoPath string, hasChanges bool, gitStatus git.StatusInfo, editedCopies []config.ManagedFile, noPush bool) error {
 	fmt.Println("Sync Preview")
 	fmt.Println("============")
 	fmt.Println("")
 
 	if hasChanges {
 		fmt.Println("Uncommitted changes:")
-		changedFiles, _ := git.GetChangedFiles(repoPath)
-		for _, f := range changedFiles {
-			fmt.Printf("  M %s\n", f)
+		for _, entry := range gitStatus.Changes {
+			fmt.Printf("  %-2s %s\n", entry.Status, entry.DisplayPath())
 		}
 		fmt.Println("")
 
+		if len(editedCopies) > 0 {
+			fmt.Println("Local edits to pull into the repo:")
+			for _, mf := range editedCopies {
+				fmt.Printf("  %s → %s\n", mf.SourcePath, mf.RepoPath)
+			}
+			fmt.Println("")
+		}
+
 		// Show diff stat
 		diffStat, _ := git.GetDiffStat(repoPath)
 		if diffStat != "" {
@@ -183,20 +443,23 @@ func showSyncPreview(repoPath string, hasChanges bool, gitStatus git.StatusInfo,
 			if gitStatus.AheadBy > 0 {
 				fmt.Printf("Would push %d commit(s) to remote.\n", gitStatus.AheadBy)
 			} else if gitStatus.BehindBy > 0 {
-				fmt.Printf("⚠ Remote is %d commit(s) ahead. Consider 'git pull' first.\n", gitStatus.BehindBy)
+				fmt.Printf("⚠ Remote is %d commit(s) ahead. Run 'dotcor sync --rebase' to pull them first.\n", gitStatus.BehindBy)
 			} else {
 				fmt.Println("Already in sync with remote.")
 			}
 		} else {
 			fmt.Println("No remote configured.")
 		}
+		if lag := remotesBehind(cfg, gitStatus); lag > 0 {
+			fmt.Printf("Would push %d commit(s) to the remotes in git_remotes.\n", lag)
+		}
 	}
 
 	return nil
 }
 
 // confirmSync prompts for confirmation
-func confirmSync(hasChanges bool, willPush bool) bool {
+func confirmSync(hasChanges bool, willPush bool) (bool, error) {
 	var action string
 	if hasChanges && willPush {
 		action = "commit and push"
@@ -205,20 +468,121 @@ func confirmSync(hasChanges bool, willPush bool) bool {
 	} else if willPush {
 		action = "push"
 	} else {
-		return true
+		return true, nil
 	}
 
-	fmt.Printf("Proceed to %s? [Y/n]: ", action)
+	return confirm(fmt.Sprintf("Proceed to %s?", action), true)
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
+// pullWithBackup fetches from remote and backs up linked files that the pull
+// will change before merging, or rebasing onto the remote with rebase.
+// Returns the repo paths the pull changed.
+func pullWithBackup(cfg *config.Config, repo *git.Repo, rebase bool) ([]string, error) {
+	remoteURL, _ := repo.RemoteURL()
+	if remoteURL == "" {
+		fmt.Println("⚠ No remote configured, skipping pull.")
+		return nil, nil
+	}
 
-	return input == "" || input == "y" || input == "yes"
+	spinner := startSpinner("Pulling from origin")
+	pulled, err := core.PullWithBackup(cfg, repo.Path(), rebase)
+	spinner.Stop()
+	repo.Refresh()
+	if len(pulled.Backups) > 0 {
+		fmt.Printf("✓ Backed up %d linked file(s) before pull\n", len(pulled.Backups))
+	}
+	if err != nil {
+		return pulled.Incoming, err
+	}
+	switch {
+	case len(pulled.Incoming) == 0:
+		fmt.Println("✓ Already up to date with remote")
+	case rebase:
+		fmt.Println("✓ Rebased onto remote")
+	default:
+		fmt.Println("✓ Pulled from remote")
+	}
+	return pulled.Incoming, nil
 }
 
-// pushToRemote pushes changes to remote
-func pushToRemote(repoPath string) error {
-	// Use git push
-	return git.Sync(repoPath)
+// bumpRepoSubmodules moves the repository's submodules to the latest commit
+// of the branch they track. Returns the paths of those that moved.
+func bumpRepoSubmodules(repo *git.Repo) ([]string, error) {
+	if !git.HasSubmodules(repo.Path()) {
+		fmt.Println("⚠ The repository has no submodules.")
+		return nil, nil
+	}
+
+	before, err := git.ListSubmodules(repo.Path())
+	if err != nil {
+		return nil, err
+	}
+	spinner := startSpinner("Updating submodules")
+	err = git.BumpSubmodules(repo.Path())
+	spinner.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("updating submodules: %w", err)
+	}
+	after, err := git.ListSubmodules(repo.Path())
+	if err != nil {
+		return nil, err
+	}
+	repo.Refresh()
+
+	commits := make(map[string]string)
+	for _, s := range before {
+		commits[s.Path] = s.Commit
+	}
+	var bumped []string
+	for _, s := range after {
+		if commits[s.Path] != s.Commit {
+			bumped = append(bumped, s.Path)
+			fmt.Printf("✓ Updated submodule %s to %s\n", s.Path, shortHash(s.Commit))
+		}
+	}
+	if len(bumped) == 0 {
+		fmt.Println("✓ Submodules are up to date")
+	}
+	return bumped, nil
+}
+
+// pushAllRemotes pushes the current branch to origin, if configured, and to
+// every remote in git_remotes, reporting each. The error names every push
+// that failed; the mirrors are skipped when origin is ahead.
+func pushAllRemotes(cfg *config.Config, repo *git.Repo) ([]remotePush, error) {
+	spinner := startSpinner("Pushing")
+	results, err := core.PushAllRemotes(cfg, repo.Path())
+	spinner.Stop()
+	repo.Refresh()
+
+	var pushes []remotePush
+	for _, r := range results {
+		push := remotePush{Name: r.Name, Pushed: r.Err == nil}
+		if r.Err != nil {
+			push.Error = r.Err.Error()
+			fmt.Printf("✗ Push to %s failed\n", r.Name)
+		} else {
+			fmt.Printf("✓ Pushed to %s\n", r.Name)
+		}
+		pushes = append(pushes, push)
+	}
+	return pushes, err
+}
+
+// remotesBehind returns the most commits any remote in git_remotes is
+// missing, counting a remote without the branch as one behind
+func remotesBehind(cfg *config.Config, gitStatus git.StatusInfo) int {
+	lag := 0
+	for _, remote := range cfg.GitRemotes {
+		behind := 1
+		for _, rs := range gitStatus.Remotes {
+			if rs.Name == remote.Name && rs.Tracked {
+				behind = rs.AheadBy
+			}
+		}
+		if behind > lag {
+			lag = behind
+		}
+	}
+	return lag
 }
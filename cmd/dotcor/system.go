@@ -0,0 +1,515 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var systemCmd = &cobra.Command{
+	Use:   "system",
+	Short: "Manage system files outside your home directory",
+	Long: `Manage files outside $HOME, such as /etc/hosts, in a separate "system" scope.
+
+System files are kept in their own system_files section of config.yaml and
+stored under system/ in the repository. They are never symlinked: the repo
+copy belongs to you, so a link would let anything running as you rewrite
+system config. Instead 'dotcor system apply' copies the repo file into place
+and gives it the owner and permissions recorded when it was added. Only the
+individual filesystem operations that need it (copying, chown, chmod) are
+run through sudo, and each one is shown for confirmation first.
+
+Examples:
+  dotcor system add /etc/hosts                      # Manage /etc/hosts
+  dotcor system add /etc/nixos/configuration.nix    # Manage NixOS config
+  dotcor system apply                               # Deploy system files (new machine)
+  dotcor system remove /etc/hosts                   # Restore file with original owner
+  dotcor system list                                # List system files`,
+}
+
+var systemAddCmd = &cobra.Command{
+	Use:   "add [file]...",
+	Short: "Add system files to DotCor management",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runSystemAdd,
+}
+
+var systemApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Copy system files into place with their recorded owner and mode",
+	RunE:  runSystemApply,
+}
+
+var systemRemoveCmd = &cobra.Command{
+	Use:     "remove [file]...",
+	Aliases: []string{"rm"},
+	Short:   "Stop managing system files and restore them in place",
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runSystemRemove,
+}
+
+var systemListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List managed system files",
+	Annotations: readOnly,
+	RunE:        runSystemList,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{systemAddCmd, systemApplyCmd, systemRemoveCmd} {
+		c.Flags().Bool("dry-run", false, "Show what would be done without making changes")
+	}
+	for _, c := range []*cobra.Command{systemApplyCmd, systemRemoveCmd} {
+		c.Flags().BoolP("force", "f", false, "Skip confirmation of privileged operations")
+	}
+	systemCmd.AddCommand(systemAddCmd, systemApplyCmd, systemRemoveCmd, systemListCmd)
+	rootCmd.AddCommand(systemCmd)
+}
+
+// privilegedStep is a filesystem operation shown for confirmation before running
+type privilegedStep struct {
+	describe string
+	run      func() error
+}
+
+// loadSystemConfig loads config and rejects platforms without system scope support
+func loadSystemConfig() (*config.Config, error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("system files are not supported on Windows")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+	return cfg, nil
+}
+
+// confirmPrivileged lists the steps and asks for confirmation
+func confirmPrivileged(path string, steps []privilegedStep, force bool) (bool, error) {
+	fmt.Printf("  %s requires:\n", path)
+	for _, step := range steps {
+		fmt.Printf("    $ %s\n", step.describe)
+	}
+	if force {
+		return true, nil
+	}
+	return confirm("Continue?", false)
+}
+
+// runSteps runs each privileged step in order
+func runSteps(steps []privilegedStep) error {
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runSystemAdd(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := loadSystemConfig()
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	added := 0
+	for _, arg := range args {
+		if err := processSystemAdd(cfg, arg, dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", arg, err)
+			continue
+		}
+		added++
+	}
+
+	fmt.Println("")
+	if dryRun {
+		fmt.Printf("Would add %d system file(s)\n", added)
+		return nil
+	}
+	fmt.Printf("Added %d system file(s)\n", added)
+
+	if git.IsAvailable() && added > 0 {
+		commitSystemChange(cfg, fmt.Sprintf("Add %d system file(s)", added))
+	}
+
+	return nil
+}
+
+// processSystemAdd copies a system file into the repo, recording its owner
+// and mode. The file itself is left in place, owned by whoever owns it.
+func processSystemAdd(cfg *config.Config, path string, dryRun bool) error {
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	isSystem, err := config.IsSystemPath(expanded)
+	if err != nil {
+		return err
+	}
+	if !isSystem {
+		return fmt.Errorf("path is inside your home directory, use 'dotcor add' instead")
+	}
+
+	if _, err := cfg.GetSystemFile(expanded); err == nil {
+		fmt.Printf("  - %s (already managed)\n", expanded)
+		return nil
+	}
+
+	if isLink, _ := fs.IsSymlink(expanded); isLink {
+		return fmt.Errorf("path is a symlink")
+	}
+
+	info, err := os.Stat(expanded)
+	if err != nil {
+		return fmt.Errorf("checking file: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("path is a directory")
+	}
+	if !fs.IsReadable(expanded) {
+		return fmt.Errorf("file is not readable")
+	}
+
+	owner, err := fs.GetOwner(expanded)
+	if err != nil {
+		return err
+	}
+
+	repoPath, err := config.GenerateSystemRepoPath(expanded)
+	if err != nil {
+		return err
+	}
+	fullRepoPath, err := config.GetRepoFilePath(cfg, repoPath)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("  + %s → %s\n", expanded, repoPath)
+		return nil
+	}
+
+	// The repo copy is owned by the current user so it can be committed
+	if err := fs.CopyWithPermissions(expanded, fullRepoPath); err != nil {
+		return fmt.Errorf("copying to repo: %w", err)
+	}
+
+	mf := config.ManagedFile{
+		SourcePath: expanded,
+		RepoPath:   repoPath,
+		AddedAt:    time.Now(),
+		Platforms:  []string{config.GetCurrentPlatform()},
+		Scope:      config.ScopeSystem,
+		Owner:      owner,
+		Perm:       fmt.Sprintf("%o", info.Mode().Perm()),
+	}
+	if err := cfg.AddSystemFile(mf); err != nil {
+		os.Remove(fullRepoPath)
+		return fmt.Errorf("updating config: %w", err)
+	}
+	recordChecksums(cfg, repoPath)
+
+	fmt.Printf("  ✓ %s\n", expanded)
+	return nil
+}
+
+func runSystemApply(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := loadSystemConfig()
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	created := 0
+	skipped := 0
+	for _, mf := range cfg.GetSystemFilesForPlatform() {
+		fullRepoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+		if err != nil || !fs.FileExists(fullRepoPath) {
+			fmt.Printf("  ✗ %s (not in repository)\n", mf.SourcePath)
+			continue
+		}
+
+		if systemFileDeployed(mf, fullRepoPath) {
+			fmt.Printf("  - %s (already deployed)\n", mf.SourcePath)
+			skipped++
+			continue
+		}
+
+		steps, err := deploySystemSteps(mf, fullRepoPath)
+		if err != nil {
+			fmt.Printf("  ✗ %s (%v)\n", mf.SourcePath, err)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("  + %s ← %s\n", mf.SourcePath, mf.RepoPath)
+			for _, step := range steps {
+				fmt.Printf("    $ %s\n", step.describe)
+			}
+			created++
+			continue
+		}
+
+		ok, err := confirmPrivileged(mf.SourcePath, steps, force)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			skipped++
+			continue
+		}
+
+		// Keep the file being replaced, unless it is a link into the repo
+		if isLink, _ := fs.IsSymlink(mf.SourcePath); !isLink && fs.FileExists(mf.SourcePath) {
+			if _, err := core.CreateManagedBackup(mf.SourcePath, mf); err != nil {
+				fmt.Printf("  ⚠ Backup failed for %s: %v\n", mf.SourcePath, err)
+			}
+		}
+
+		if err := runSteps(steps); err != nil {
+			fmt.Printf("  ✗ %s (%v)\n", mf.SourcePath, err)
+			continue
+		}
+
+		fmt.Printf("  ✓ %s\n", mf.SourcePath)
+		created++
+	}
+
+	if dryRun {
+		fmt.Printf("\nWould deploy %d system file(s)\n", created)
+		return nil
+	}
+	fmt.Printf("\nDeployed %d system file(s), skipped %d\n", created, skipped)
+	return nil
+}
+
+// systemFileDeployed reports whether the live file is a regular file with
+// the repo file's content and the recorded owner and mode
+func systemFileDeployed(mf config.ManagedFile, fullRepoPath string) bool {
+	if isLink, _ := fs.IsSymlink(mf.SourcePath); isLink {
+		return false
+	}
+	if same, err := fs.SameContent(mf.SourcePath, fullRepoPath); err != nil || !same {
+		return false
+	}
+	if mf.Owner != "" {
+		if owner, err := fs.GetOwner(mf.SourcePath); err != nil || owner != mf.Owner {
+			return false
+		}
+	}
+	if perm, ok, _ := mf.FilePerm(); ok {
+		if info, err := os.Stat(mf.SourcePath); err != nil || info.Mode().Perm() != perm {
+			return false
+		}
+	}
+	return true
+}
+
+// deploySystemSteps returns the privileged steps copying the repo file into
+// place with the recorded owner and mode, so the live file stays owned by
+// its original owner rather than by you
+func deploySystemSteps(mf config.ManagedFile, fullRepoPath string) ([]privilegedStep, error) {
+	target := mf.SourcePath
+	steps := []privilegedStep{{
+		describe: fs.DescribePrivileged(target, "cp", fullRepoPath, target),
+		run:      func() error { return fs.PrivilegedCopy(fullRepoPath, target) },
+	}}
+	if mf.Owner != "" {
+		steps = append(steps, privilegedStep{
+			describe: fs.DescribePrivileged(target, "chown", mf.Owner, target),
+			run:      func() error { return fs.PrivilegedChown(target, mf.Owner) },
+		})
+	}
+	perm, ok, err := mf.FilePerm()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		steps = append(steps, privilegedStep{
+			describe: fs.DescribePrivileged(target, "chmod", mf.Perm, target),
+			run:      func() error { return fs.PrivilegedChmod(target, perm) },
+		})
+	}
+	return steps, nil
+}
+
+func runSystemRemove(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := loadSystemConfig()
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	removed := 0
+	for _, arg := range args {
+		mf, err := cfg.GetSystemFile(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  ✗ %s: not a managed system file\n", arg)
+			continue
+		}
+
+		if err := processSystemRemove(cfg, *mf, force, dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", mf.SourcePath, err)
+			continue
+		}
+		removed++
+	}
+
+	fmt.Println("")
+	if dryRun {
+		fmt.Printf("Would remove %d system file(s) from management\n", removed)
+		return nil
+	}
+	fmt.Printf("Removed %d system file(s) from management\n", removed)
+
+	if git.IsAvailable() && removed > 0 {
+		commitSystemChange(cfg, fmt.Sprintf("Remove %d system file(s) from management", removed))
+	}
+
+	return nil
+}
+
+// processSystemRemove stops managing a system file. A file deployed by
+// older versions as a symlink into the repo is first replaced by a copy
+// with its original owner and mode.
+func processSystemRemove(cfg *config.Config, mf config.ManagedFile, force, dryRun bool) error {
+	fullRepoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+	if !fs.FileExists(fullRepoPath) {
+		return fmt.Errorf("not in repository: %s", mf.RepoPath)
+	}
+
+	target := mf.SourcePath
+	var steps []privilegedStep
+	if isLink, _ := fs.IsSymlink(target); isLink || !fs.FileExists(target) {
+		steps, err = deploySystemSteps(mf, fullRepoPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("  - %s\n", target)
+		for _, step := range steps {
+			fmt.Printf("    $ %s\n", step.describe)
+		}
+		return nil
+	}
+
+	if len(steps) > 0 {
+		confirmed, err := confirmPrivileged(target, steps, force)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("cancelled")
+		}
+	}
+
+	if _, err := core.CreateManagedBackup(fullRepoPath, mf); err != nil {
+		fmt.Printf("  ⚠ Backup failed for %s: %v\n", mf.RepoPath, err)
+	}
+
+	if err := runSteps(steps); err != nil {
+		return err
+	}
+
+	if err := os.Remove(fullRepoPath); err != nil {
+		return fmt.Errorf("removing from repo: %w", err)
+	}
+	cleanEmptyDirs(filepath.Dir(fullRepoPath))
+
+	if err := cfg.RemoveSystemFile(mf.SourcePath); err != nil {
+		return fmt.Errorf("updating config: %w", err)
+	}
+
+	fmt.Printf("  ✓ %s\n", target)
+	return nil
+}
+
+func runSystemList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	for _, mf := range cfg.SystemFiles {
+		fmt.Printf("  %s → %s (owner %s, mode %s)\n", mf.SourcePath, mf.RepoPath, mf.Owner, mf.Perm)
+	}
+
+	if len(cfg.SystemFiles) == 0 {
+		fmt.Println("No system files managed.")
+		fmt.Println("Use 'dotcor system add <file>' to start managing system files.")
+	}
+
+	return nil
+}
+
+// Git pathspecs that keep user dotfiles and system files in separate commits.
+// Pathspecs are relative to the files root, where system/ lives.
+var (
+	userPathspecs   = core.UserPathspecs
+	systemPathspecs = core.SystemPathspecs
+)
+
+// commitSystemChange auto-commits system files only, after a system file operation
+func commitSystemChange(cfg *config.Config, message string) {
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		fmt.Printf("⚠ Git commit skipped: invalid repo path: %v\n", err)
+		return
+	}
+
+	if err := git.AutoCommit(repoPath, message, systemPathspecs...); err != nil {
+		fmt.Printf("⚠ Git commit failed: %v\n", err)
+	} else {
+		fmt.Println("✓ Committed to Git")
+	}
+}
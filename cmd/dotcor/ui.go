@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse and manage dotfiles interactively",
+	Long: `Open an interactive view of managed files, symlink health and Git status.
+
+Keys:
+  ↑/k ↓/j   Move the selection
+  a         Add a file (type its path, then Enter)
+  d         Remove the selected file from management
+  r         Restore the selected file from Git
+  p         Show only files with problems
+  g         Refresh
+  q         Quit
+
+Actions run the matching dotcor command, so its prompts, hooks and
+output work as usual.`,
+	Annotations: readOnly,
+	RunE:        runUI,
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+}
+
+func runUI(cmd *cobra.Command, args []string) error {
+	if nonInteractive {
+		return fmt.Errorf("dotcor ui is interactive and can't run with --yes or $%s", nonInteractiveEnv)
+	}
+	if _, err := config.LoadConfig(); err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding dotcor executable: %w", err)
+	}
+
+	m := newUIModel(self, persistentFlagArgs(cmd))
+	m.reload()
+
+	_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// uiMode is what the UI is waiting for
+type uiMode int
+
+const (
+	uiBrowse  uiMode = iota // Navigating the file list
+	uiAdding                // Typing the path of a file to add
+	uiConfirm               // Waiting for y/n before removing
+)
+
+// uiModel is the bubbletea model for 'dotcor ui'
+type uiModel struct {
+	self         string   // Path of the dotcor executable, for running actions
+	flags        []string // Global flags passed to actions, such as --repo
+	report       StatusReport
+	files        []FileStatus // Visible files (dotfiles, then system files)
+	cursor       int
+	problemsOnly bool
+	mode         uiMode
+	input        string // Path typed in uiAdding mode
+	message      string // Result of the last action
+	err          error  // Error loading status
+}
+
+// uiActionDoneMsg reports that an action's dotcor command finished
+type uiActionDoneMsg struct {
+	action string
+	err    error
+}
+
+func newUIModel(self string, flags []string) *uiModel {
+	return &uiModel{self: self, flags: flags}
+}
+
+// reload collects status again, keeping the selection in range
+func (m *uiModel) reload() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		m.err = err
+		return
+	}
+	m.err = nil
+	m.report = collectStatus(cfg)
+
+	m.files = nil
+	for _, f := range append(m.report.Files, m.report.SystemFiles...) {
+		if m.problemsOnly && f.Status == "ok" {
+			continue
+		}
+		m.files = append(m.files, f)
+	}
+
+	if m.cursor >= len(m.files) {
+		m.cursor = len(m.files) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// selected returns the file under the cursor
+func (m *uiModel) selected() (FileStatus, bool) {
+	if len(m.files) == 0 {
+		return FileStatus{}, false
+	}
+	return m.files[m.cursor], true
+}
+
+// run suspends the UI and runs a dotcor command in the terminal
+func (m *uiModel) run(action string, args ...string) tea.Cmd {
+	c := exec.Command(m.self, append(append([]string{}, m.flags...), args...)...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return uiActionDoneMsg{action: action, err: err}
+	})
+}
+
+func (m *uiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case uiActionDoneMsg:
+		if msg.err != nil {
+			m.message = fmt.Sprintf("✗ %s failed: %v", msg.action, msg.err)
+		} else {
+			m.message = fmt.Sprintf("✓ %s done", msg.action)
+		}
+		m.reload()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch m.mode {
+		case uiAdding:
+			return m.updateAdding(msg)
+		case uiConfirm:
+			return m.updateConfirm(msg)
+		}
+		return m.updateBrowse(msg)
+	}
+
+	return m, nil
+}
+
+// updateBrowse handles keys while navigating the list
+func (m *uiModel) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.message = ""
+
+	switch msg.String() {
+	case "q", "esc", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.files)-1 {
+			m.cursor++
+		}
+	case "p":
+		m.problemsOnly = !m.problemsOnly
+		m.reload()
+	case "g":
+		m.reload()
+	case "a":
+		m.mode = uiAdding
+		m.input = ""
+	case "d":
+		if _, ok := m.selected(); ok {
+			m.mode = uiConfirm
+		}
+	case "r":
+		if f, ok := m.selected(); ok {
+			return m, m.run("restore "+f.SourcePath, "restore", f.SourcePath)
+		}
+	}
+
+	return m, nil
+}
+
+// updateAdding handles keys while typing a path to add
+func (m *uiModel) updateAdding(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.mode = uiBrowse
+	case tea.KeyEnter:
+		m.mode = uiBrowse
+		path := strings.TrimSpace(m.input)
+		if path != "" {
+			return m, m.run("add "+path, "add", path)
+		}
+	case tea.KeyBackspace:
+		if len(m.input) > 0 {
+			runes := []rune(m.input)
+			m.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.input += string(msg.Runes)
+	}
+
+	return m, nil
+}
+
+// updateConfirm handles the y/n answer before removing a file
+func (m *uiModel) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.mode = uiBrowse
+
+	f, ok := m.selected()
+	if !ok || (msg.String() != "y" && msg.String() != "Y") {
+		m.message = "Remove cancelled"
+		return m, nil
+	}
+	return m, m.run("remove "+f.SourcePath, "remove", f.SourcePath)
+}
+
+func (m *uiModel) View() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n\n", paint("DotCor", colorBold))
+
+	if m.err != nil {
+		fmt.Fprintf(&b, "  %s\n\n", paint("✗ "+m.err.Error(), colorYellow))
+	}
+
+	m.viewGit(&b)
+	m.viewFiles(&b)
+
+	if m.message != "" {
+		fmt.Fprintf(&b, "\n  %s\n", m.message)
+	}
+
+	b.WriteString("\n")
+	switch m.mode {
+	case uiAdding:
+		fmt.Fprintf(&b, "  Add file: %s█\n", m.input)
+		fmt.Fprintf(&b, "  %s\n", paint("enter add · esc cancel", colorDim))
+	case uiConfirm:
+		f, _ := m.selected()
+		fmt.Fprintf(&b, "  Stop managing %s? [y/N]\n", f.SourcePath)
+	default:
+		filter := "p problems"
+		if m.problemsOnly {
+			filter = "p all files"
+		}
+		fmt.Fprintf(&b, "  %s\n", paint("↑/↓ move · a add · d remove · r restore · "+filter+" · g refresh · q quit", colorDim))
+	}
+
+	return b.String()
+}
+
+// viewGit renders the repository summary line
+func (m *uiModel) viewGit(b *strings.Builder) {
+	g := m.report.GitStatus
+	if !g.IsRepo {
+		fmt.Fprintf(b, "  %s\n\n", paint("- Not a Git repository", colorDim))
+		return
+	}
+
+	branch := g.Branch
+	if g.Detached {
+		branch = "(detached HEAD)"
+	}
+	fmt.Fprintf(b, "  %s", paint(branch, colorCyan))
+
+	if g.HasUncommitted {
+		fmt.Fprintf(b, "  %s", paint("○ "+describeChanges(g), colorYellow))
+	} else {
+		fmt.Fprintf(b, "  %s", paint("● clean", colorGreen))
+	}
+	if g.AheadBy > 0 {
+		fmt.Fprintf(b, "  ↑%d", g.AheadBy)
+	}
+	if g.BehindBy > 0 {
+		fmt.Fprintf(b, "  ↓%d", g.BehindBy)
+	}
+	if g.Rebasing {
+		b.WriteString("  ⚠ rebasing")
+	}
+	if g.Merging {
+		b.WriteString("  ⚠ merging")
+	}
+	b.WriteString("\n\n")
+}
+
+// viewFiles renders the file list with the selection marked
+func (m *uiModel) viewFiles(b *strings.Builder) {
+	if len(m.files) == 0 {
+		if m.problemsOnly {
+			b.WriteString("  All files are healthy!\n")
+		} else {
+			b.WriteString("  No files managed. Press a to add one.\n")
+		}
+		return
+	}
+
+	width := 0
+	for _, f := range m.files {
+		if len(f.SourcePath) > width {
+			width = len(f.SourcePath)
+		}
+	}
+
+	for i, f := range m.files {
+		marker := "  "
+		if i == m.cursor {
+			marker = paint("▸ ", colorPink)
+		}
+
+		detail := "ok"
+		if f.Status != "ok" {
+			detail = paintSeverity(f.Problem, f.Severity)
+		}
+		fmt.Fprintf(b, "%s%s %-*s  %s\n", marker, getStatusIcon(f), width, f.SourcePath, detail)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the last add, remove or apply",
+	Long: `Revert the most recent command that changed managed files, like add,
+remove or init --apply. Run it again to step further back.
+
+Every change dotcor commits is recorded in ~/.dotcor/journal/, along with
+the backups it took. Undo replays the inverse of each recorded operation,
+newest first: symlinks are removed, moved files moved back and replaced
+files restored from their backups. It never overwrites a file created
+since, and if any step fails everything is rolled back.
+
+Templates and secrets brought back under management are linked to their
+rendered output; run 'dotcor init --apply' to regenerate it.
+
+Examples:
+  dotcor undo            # Revert the last change
+  dotcor undo --dry-run  # Show what would be reverted`,
+	Args: cobra.NoArgs,
+	RunE: runUndo,
+}
+
+func init() {
+	undoCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+	undoCmd.Flags().Bool("dry-run", false, "Show what would be reverted without making changes")
+	rootCmd.AddCommand(undoCmd)
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	entries, err := core.ReadJournal()
+	if err != nil {
+		return err
+	}
+	run := core.LatestUndoable(entries)
+	if len(run) == 0 {
+		fmt.Println("Nothing to undo.")
+		return nil
+	}
+	command := run[0].Command
+
+	tx, err := core.UndoTransaction(cfg, run)
+	if err != nil {
+		return fmt.Errorf("can't undo '%s': %w", command, err)
+	}
+
+	fmt.Printf("Undo '%s' from %s:\n", command, run[0].Time.Local().Format("2006-01-02 15:04"))
+	steps := tx.Plan()
+	for _, step := range steps {
+		fmt.Printf("  → %s\n", step)
+	}
+	if len(steps) == 0 {
+		fmt.Println("  (no file changes to revert)")
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if !force && len(steps) > 0 {
+		fmt.Println("")
+		ok, err := confirm("Continue?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	if err := tx.ExecuteAll(); err != nil {
+		return fmt.Errorf("undoing '%s': %w\nNo files were changed", command, err)
+	}
+	tx.Commit()
+	fmt.Printf("\n✓ Reverted '%s'\n", command)
+
+	if git.IsAvailable() && len(steps) > 0 {
+		if repoPath, err := config.GetFilesRoot(cfg); err == nil {
+			if changed, _ := git.HasChanges(repoPath, userPathspecs...); changed {
+				if err := git.AutoCommit(repoPath, fmt.Sprintf("Undo %s", command), userPathspecs...); err != nil {
+					fmt.Printf("⚠ Git commit failed: %v\n", err)
+				} else {
+					fmt.Println("✓ Committed to Git")
+				}
+			}
+		}
+	}
+
+	if restoredRendered(run, cfg) {
+		fmt.Println("Run 'dotcor init --apply' to render templates and decrypt secrets.")
+	}
+	return nil
+}
+
+// restoredRendered reports whether undoing run brought back a template or secret,
+// whose rendered output isn't restored
+func restoredRendered(run []core.JournalEntry, cfg *config.Config) bool {
+	for _, entry := range run {
+		for _, op := range entry.Ops {
+			if op.File == nil || !(op.File.IsTemplate() || op.File.Encrypted) {
+				continue
+			}
+			if cfg.IsManaged(op.File.SourcePath) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/github"
+	"github.com/justincordova/dotcor/internal/output"
+	"github.com/justincordova/dotcor/internal/update"
+	"github.com/spf13/cobra"
+)
+
+// updateNotice is set when a new release may be announced at exit
+var updateNotice bool
+
+// startUpdateCheck refreshes the cached latest release in the background
+// when it is due. The request never delays the command: if it hasn't
+// finished by exit it is simply retried on a later run. Checks only happen
+// for people at a terminal, so scripts and cron jobs stay quiet.
+func startUpdateCheck(cmd *cobra.Command) {
+	if isCompletionCommand(cmd) || cmd == promptStatusCmd || output.Structured() || !stderrIsTerminal() {
+		return
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil || !cfg.UpdateCheckEnabled() {
+		return
+	}
+	updateNotice = true
+
+	if update.Due() {
+		client := github.NewClient("")
+		client.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+		go update.Refresh(client)
+	}
+}
+
+// printUpdateNotice prints a dim one-line notice if a newer release is known
+func printUpdateNotice() {
+	if !updateNotice {
+		return
+	}
+	latest, url, ok := update.Newer(version)
+	if !ok {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n", paint(fmt.Sprintf("A new dotcor release is available: v%s → %s %s", version, latest, url), colorDim))
+}
+
+// isCompletionCommand reports whether cmd produces shell completions,
+// whose output must not be mixed with anything else
+func isCompletionCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "completion" || strings.HasPrefix(c.Name(), "__") {
+			return true
+		}
+	}
+	return false
+}
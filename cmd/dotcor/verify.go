@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/log"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check repo files against their recorded checksums",
+	Long: `Check that every file in the repository still has the content dotcor
+last recorded for it.
+
+dotcor keeps a SHA-256 checksum of each repo file in ~/.dotcor/checksums.json,
+updated when a file is added, synced, pulled or restored. Verify reports files
+whose content changed any other way, such as a backup restored over the wrong
+file or an edit made directly in the repo, without relying on git history.
+
+Edits made through a symlink since the last sync are reported too; syncing
+records them. After reviewing a change, record it with --update.
+
+With --content, each dotfile is also checked from the home side: the file
+reachable at its path is compared byte for byte with the repo file it should
+lead to, unless it is a symlink or hard link to that very file. This catches
+edited copy-mode files, links into a stale checkout, broken links and files
+that replaced their link with the same content, which a symlink that merely
+resolves wouldn't show.
+
+Exit codes:
+  0  All files match
+  2  One or more files are modified, missing or (with --content) diverged
+  4  dotcor is not initialized
+
+Examples:
+  dotcor verify           # Report files that changed outside dotcor
+  dotcor verify --update  # Accept the current content of every file
+  dotcor verify --content # Also compare each dotfile with its repo file`,
+	Args:        cobra.NoArgs,
+	Annotations: structuredOutput,
+	RunE:        runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().Bool("update", false, "Record the current content of every repo file")
+	verifyCmd.Flags().Bool("content", false, "Compare the file at each dotfile's path with its repo file")
+	verifyCmd.MarkFlagsMutuallyExclusive("update", "content")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// verifyResult is the structured output of verify
+type verifyResult struct {
+	Checked  int                  `json:"checked" yaml:"checked"`
+	Updated  bool                 `json:"updated" yaml:"updated"`
+	Problems []core.ChecksumDrift `json:"problems" yaml:"problems"`
+	Content  []core.ContentDrift  `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	update, _ := cmd.Flags().GetBool("update")
+	content, _ := cmd.Flags().GetBool("content")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if update {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+
+		if err := core.RecordAllChecksums(cfg); err != nil {
+			return fmt.Errorf("recording checksums: %w", err)
+		}
+		fmt.Printf("✓ Recorded checksums of %d file(s)\n", len(cfg.TrackedRepoPaths()))
+		return writeResult(verifyResult{Checked: len(cfg.TrackedRepoPaths()), Updated: true, Problems: []core.ChecksumDrift{}})
+	}
+
+	drift, err := core.VerifyChecksums(cfg)
+	if err != nil {
+		return err
+	}
+	result := verifyResult{Checked: len(cfg.TrackedRepoPaths()), Problems: []core.ChecksumDrift{}}
+
+	// Show the dotfile each repo file belongs to
+	sources := make(map[string]string)
+	for _, mf := range append(cfg.ManagedFiles, cfg.SystemFiles...) {
+		sources[mf.RepoPath] = mf.SourcePath
+		for _, v := range mf.Variants {
+			sources[v.RepoPath] = mf.SourcePath
+		}
+	}
+
+	problems, unrecorded := 0, 0
+	for _, d := range drift {
+		result.Problems = append(result.Problems, d)
+		label := d.RepoPath
+		if source := sources[d.RepoPath]; source != "" {
+			label = fmt.Sprintf("%s (%s)", d.RepoPath, source)
+		}
+
+		switch d.Kind {
+		case core.DriftModified:
+			fmt.Printf("  ✗ %s: changed since dotcor last recorded it\n", label)
+			problems++
+		case core.DriftMissing:
+			fmt.Printf("  ✗ %s: missing from the repository\n", label)
+			problems++
+		case core.DriftUnrecorded:
+			unrecorded++
+		}
+	}
+
+	if unrecorded > 0 {
+		fmt.Printf("  ⚠ %d file(s) have no recorded checksum yet\n", unrecorded)
+	}
+
+	diverged := 0
+	if content {
+		if result.Content, err = core.VerifyContent(cfg); err != nil {
+			return err
+		}
+		for _, d := range result.Content {
+			switch d.Kind {
+			case core.ContentDiffers:
+				fmt.Printf("  ✗ %s: content differs from %s\n", d.SourcePath, d.RepoPath)
+			case core.ContentUnreachable:
+				fmt.Printf("  ✗ %s: missing or a broken link, %s can't be reached\n", d.SourcePath, d.RepoPath)
+			case core.ContentDetached:
+				fmt.Printf("  ✗ %s: same content as %s but not linked to it, edits won't reach the repo\n", d.SourcePath, d.RepoPath)
+			}
+		}
+		diverged = len(result.Content)
+	}
+
+	if problems == 0 && diverged == 0 {
+		fmt.Printf("✓ %d file(s) match their recorded checksums\n", result.Checked-unrecorded)
+		if content {
+			fmt.Println("✓ Every dotfile leads to its repo content")
+		}
+		if unrecorded > 0 {
+			fmt.Println("  Run 'dotcor verify --update' to record the rest")
+		}
+		return writeResult(result)
+	}
+
+	fmt.Println("")
+	if problems > 0 {
+		fmt.Println("Check the changes with 'dotcor diff', then run 'dotcor sync' to commit")
+		fmt.Println("them or 'dotcor restore' to undo them. 'dotcor verify --update' accepts")
+		fmt.Println("the current content without committing.")
+	}
+	if diverged > 0 {
+		fmt.Println("Edited copies are pulled back by 'dotcor sync'. 'dotcor doctor --fix'")
+		fmt.Println("repairs links, and 'dotcor diff' shows how a file differs.")
+	}
+
+	if err := writeResult(result); err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	var failures []string
+	if problems > 0 {
+		failures = append(failures, fmt.Sprintf("%d file(s) changed outside dotcor", problems))
+	}
+	if diverged > 0 {
+		failures = append(failures, fmt.Sprintf("%d dotfile(s) don't match the repository", diverged))
+	}
+	return &exitCodeError{
+		code: exitProblems,
+		msg:  "verify failed: " + strings.Join(failures, ", "),
+	}
+}
+
+// recordChecksums records the content of repo files dotcor just wrote or
+// committed. A failure only means verify will report them, so it warns.
+func recordChecksums(cfg *config.Config, repoPaths ...string) {
+	if len(repoPaths) == 0 {
+		return
+	}
+	if err := core.RecordChecksums(cfg, repoPaths...); err != nil {
+		log.Warn("recording checksums failed", "error", err)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/crypto"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/template"
+	"github.com/justincordova/dotcor/internal/vscode"
+	"github.com/spf13/cobra"
+)
+
+var vscodeCmd = &cobra.Command{
+	Use:   "vscode",
+	Short: "Sync VS Code settings, keybindings and extensions",
+	Long: `Keep VS Code's settings.json and keybindings.json in the repository and
+the list of installed extensions next to them.
+
+VS Code keeps its settings in a different place on each platform:
+
+  macOS    ~/Library/Application Support/Code/User
+  Linux    ~/.config/Code/User
+  Windows  ~/AppData/Roaming/Code/User
+
+'dotcor vscode add' stores each file once, in vscode/ in the repository,
+with an entry per platform in config.yaml, so every machine links its own
+location to the same file. 'dotcor init --apply' links them like any other
+managed file; 'dotcor vscode apply' links them and installs the missing
+extensions.
+
+The extensions are listed with VS Code's code command. On a Mac without it
+in PATH, the one in the app bundle is used.
+
+Examples:
+  dotcor vscode add              # Manage the settings and export extensions
+  dotcor vscode export           # Update the list of extensions
+  dotcor vscode apply --dry-run  # Show what would be linked and installed
+  dotcor vscode apply`,
+}
+
+var vscodeAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Manage VS Code's settings and export its extensions",
+	Args:  cobra.NoArgs,
+	RunE:  runVSCodeAdd,
+}
+
+var vscodeExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the installed extensions to the repository",
+	Args:  cobra.NoArgs,
+	RunE:  runVSCodeExport,
+}
+
+var vscodeApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Link VS Code's settings and install the listed extensions",
+	Args:  cobra.NoArgs,
+	RunE:  runVSCodeApply,
+}
+
+func init() {
+	vscodeAddCmd.Flags().BoolP("force", "f", false, "Add settings even if they look like they hold secrets")
+	vscodeApplyCmd.Flags().Bool("dry-run", false, "Show what would be linked and installed")
+	vscodeCmd.AddCommand(vscodeAddCmd, vscodeExportCmd, vscodeApplyCmd)
+	rootCmd.AddCommand(vscodeCmd)
+}
+
+// vscodePlatform returns the current platform, failing where VS Code's
+// settings location isn't known
+func vscodePlatform() (string, error) {
+	platform := config.GetCurrentPlatform()
+	if _, ok := vscode.UserPath(platform, vscode.Files[0]); !ok {
+		return "", fmt.Errorf("VS Code settings aren't supported on %s", platform)
+	}
+	return platform, nil
+}
+
+func runVSCodeAdd(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	platform, err := vscodePlatform()
+	if err != nil {
+		return err
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	fmt.Println("Settings:")
+	for _, file := range vscode.Files {
+		source, _ := vscode.UserPath(platform, file)
+		repoPath := filepath.Join(config.VSCodeDir, file)
+		repoFile, err := config.GetRepoFilePath(cfg, repoPath)
+		if err != nil {
+			return err
+		}
+		expanded, err := config.ExpandPath(source)
+		if err != nil {
+			return fmt.Errorf("invalid path %s: %w", source, err)
+		}
+
+		switch {
+		case cfg.IsManaged(source):
+			// Added before, maybe with plain 'dotcor add'; share it as is
+			mf, _ := cfg.GetManagedFile(source)
+			repoPath = mf.RepoPath
+			fmt.Printf("  - %s (already managed)\n", source)
+		case fs.FileExists(repoFile):
+			// Added on another platform; link this machine's location to it
+			mf := config.ManagedFile{SourcePath: source, RepoPath: repoPath, AddedAt: time.Now(), Platforms: []string{platform}}
+			cfg.ManagedFiles = append(cfg.ManagedFiles, mf)
+			if err := linkVSCodeFile(cfg, mf, false); err != nil {
+				fmt.Printf("  ✗ %s (%v)\n", source, err)
+			}
+		case fs.FileExists(expanded):
+			result, added, err := processAddFile(cfg, source, config.VSCodeDir, force, false, false, "", false)
+			if result == addResultError {
+				fmt.Printf("  ✗ %s (%v)\n", source, err)
+				continue
+			}
+			if result == addResultSkipped {
+				continue
+			}
+			// The file may have been stored under another name, e.g. to
+			// avoid a collision
+			repoPath = added
+		default:
+			fmt.Printf("  - %s (not found)\n", source)
+			continue
+		}
+
+		shareVSCodeFile(cfg, file, repoPath)
+	}
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	fmt.Println("Extensions:")
+	if cli := vscode.CLI(); cli == "" {
+		fmt.Println("  ⚠ VS Code's code command was not found; extensions were not exported")
+	} else if err := exportVSCodeExtensions(cfg, cli); err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+	}
+
+	commitVSCodeChange(cfg, "Add VS Code settings")
+	return nil
+}
+
+// shareVSCodeFile gives file an entry for every platform, each at its
+// location there, all stored at repoPath
+func shareVSCodeFile(cfg *config.Config, file, repoPath string) {
+	for _, platform := range vscode.Platforms {
+		source, _ := vscode.UserPath(platform, file)
+		if mf, err := cfg.GetManagedFile(source); err == nil {
+			if mf.RepoPath == repoPath {
+				mf.Platforms = []string{platform}
+			}
+			continue
+		}
+		cfg.ManagedFiles = append(cfg.ManagedFiles, config.ManagedFile{
+			SourcePath: source,
+			RepoPath:   repoPath,
+			AddedAt:    time.Now(),
+			Platforms:  []string{platform},
+		})
+	}
+}
+
+func runVSCodeExport(cmd *cobra.Command, args []string) error {
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	cli := vscode.CLI()
+	if cli == "" {
+		return fmt.Errorf("VS Code's code command was not found; install it with \"Shell Command: Install 'code' command in PATH\" from VS Code's command palette")
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+	if err := core.AcquireLock(); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+
+	if err := exportVSCodeExtensions(cfg, cli); err != nil {
+		return err
+	}
+	commitVSCodeChange(cfg, "Update VS Code extensions")
+	return nil
+}
+
+// exportVSCodeExtensions writes the installed extensions to the repository
+func exportVSCodeExtensions(cfg *config.Config, cli string) error {
+	ids, err := vscode.Extensions(cli)
+	if err != nil {
+		return err
+	}
+	listFile, err := config.GetRepoFilePath(cfg, config.VSCodeExtensionsPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(listFile), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(listFile), err)
+	}
+	if err := os.WriteFile(listFile, vscode.FormatExtensions(ids), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", listFile, err)
+	}
+	fmt.Printf("  ✓ Exported %d extension(s) to %s\n", len(ids), config.VSCodeExtensionsPath)
+	return nil
+}
+
+// commitVSCodeChange commits the repository, warning on failure
+func commitVSCodeChange(cfg *config.Config, message string) {
+	if !git.IsAvailable() {
+		return
+	}
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		fmt.Printf("⚠ Git commit skipped: invalid repo path: %v\n", err)
+		return
+	}
+	if err := git.AutoCommit(repoPath, message, userPathspecs...); err != nil {
+		fmt.Printf("⚠ Git commit failed: %v\n", err)
+	} else {
+		fmt.Println("✓ Committed to Git")
+	}
+}
+
+func runVSCodeApply(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if err := requireInitialized(cmd); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	platform, err := vscodePlatform()
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+			return err
+		}
+		if err := core.AcquireLock(); err != nil {
+			return fmt.Errorf("acquiring lock: %w", err)
+		}
+		defer core.ReleaseLock()
+	}
+
+	fmt.Println("Settings:")
+	for _, file := range vscode.Files {
+		source, _ := vscode.UserPath(platform, file)
+		mf, err := cfg.GetManagedFile(source)
+		if err != nil {
+			fmt.Printf("  - %s (not managed, run 'dotcor vscode add')\n", source)
+			continue
+		}
+		if err := linkVSCodeFile(cfg, *mf, dryRun); err != nil {
+			fmt.Printf("  ✗ %s (%v)\n", source, err)
+		}
+	}
+
+	fmt.Println("Extensions:")
+	if err := installVSCodeExtensions(cfg, dryRun); err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Println("\n(dry run - no changes were made)")
+	}
+	return nil
+}
+
+// linkVSCodeFile deploys mf like 'dotcor init --apply' does, reporting
+// the outcome
+func linkVSCodeFile(cfg *config.Config, mf config.ManagedFile, dryRun bool) error {
+	data, err := template.NewData(cfg)
+	if err != nil {
+		return fmt.Errorf("collecting template variables: %w", err)
+	}
+	var backend crypto.Backend
+
+	tx := core.NewTransaction()
+	if dryRun {
+		tx = core.NewPlanTransaction()
+	}
+	note, applied, err := applyFile(tx, cfg, mf, data, &backend)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		fmt.Printf("  - %s (%s)\n", mf.SourcePath, note)
+		return nil
+	}
+	tx.Commit()
+
+	if dryRun {
+		fmt.Printf("  + %s\n", mf.SourcePath)
+		for _, step := range tx.Plan() {
+			fmt.Printf("    → %s\n", step)
+		}
+	} else if note != "" {
+		fmt.Printf("  ✓ %s (%s)\n", mf.SourcePath, note)
+	} else {
+		fmt.Printf("  ✓ %s\n", mf.SourcePath)
+	}
+	return nil
+}
+
+// installVSCodeExtensions installs the listed extensions that are missing
+func installVSCodeExtensions(cfg *config.Config, dryRun bool) error {
+	listFile, err := config.GetRepoFilePath(cfg, config.VSCodeExtensionsPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(listFile)
+	if os.IsNotExist(err) {
+		fmt.Println("  - No extensions exported yet, run 'dotcor vscode export' where they are installed")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", listFile, err)
+	}
+	want := vscode.ParseExtensions(data)
+
+	cli := vscode.CLI()
+	if cli == "" {
+		fmt.Printf("  ⚠ VS Code's code command was not found; skipping %d extension(s)\n", len(want))
+		return nil
+	}
+	installed, err := vscode.Extensions(cli)
+	if err != nil {
+		return err
+	}
+	missing := vscode.Missing(want, installed)
+	if len(missing) == 0 {
+		fmt.Printf("  ✓ All %d extension(s) installed\n", len(want))
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("  Would install: %s\n", strings.Join(missing, ", "))
+		return nil
+	}
+
+	var failed []string
+	for _, id := range missing {
+		fmt.Printf("  → Installing %s\n", id)
+		if err := vscode.Install(cli, id, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "  ✗ %v\n", err)
+			failed = append(failed, id)
+		}
+	}
+	fmt.Printf("  ✓ Installed %d extension(s)\n", len(missing)-len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("installing extensions failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+	"github.com/justincordova/dotcor/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Auto-commit changes to dotfiles as they happen",
+	Long: `Watch the repository and commit changes automatically.
+
+Since managed dotfiles are symlinks into the repository, editing them changes
+the repository directly. 'dotcor watch' commits those changes once no files
+have changed for the debounce period, and can push after each commit.
+
+The watcher runs until interrupted. Only one watcher runs at a time; its PID
+is kept in ~/.dotcor/watch.pid. Commits wait while another dotcor command
+holds the lock.
+
+Defaults can be set in config.yaml:
+  watch:
+    debounce: 30s
+    push: true
+
+Examples:
+  dotcor watch                  # Commit 30s after the last change
+  dotcor watch --debounce 5m    # Wait for 5 minutes of quiet
+  dotcor watch --push           # Also push after each commit
+  dotcor watch --status         # Show whether a watcher is running
+  dotcor watch --stop           # Stop the running watcher`,
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().Duration("debounce", 0, "Quiet period before committing (default from config, or 30s)")
+	watchCmd.Flags().Bool("push", false, "Push to remote after each commit")
+	watchCmd.Flags().Bool("status", false, "Show whether a watcher is running")
+	watchCmd.Flags().Bool("stop", false, "Stop the running watcher")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	debounce, _ := cmd.Flags().GetDuration("debounce")
+	push, _ := cmd.Flags().GetBool("push")
+	showStatus, _ := cmd.Flags().GetBool("status")
+	stop, _ := cmd.Flags().GetBool("stop")
+
+	if showStatus {
+		return showWatchStatus()
+	}
+	if stop {
+		return stopWatch()
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w\nRun 'dotcor init' first", err)
+	}
+
+	if !git.IsAvailable() {
+		return fmt.Errorf("git is not installed")
+	}
+
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("expanding repo path: %w", err)
+	}
+	if !git.IsRepo(repoPath) {
+		return fmt.Errorf("dotcor repository is not a git repository")
+	}
+
+	if err := checkEnvironment(cmd, cfg.RepoPath); err != nil {
+		return err
+	}
+
+	// Flags override config
+	if debounce == 0 && cfg.Watch.Debounce != "" {
+		debounce, _ = time.ParseDuration(cfg.Watch.Debounce)
+	}
+	if debounce == 0 {
+		debounce = watch.DefaultDebounce
+	}
+	if !cmd.Flags().Changed("push") {
+		push = cfg.Watch.Push
+	}
+
+	if err := core.WriteWatchPID(); err != nil {
+		return err
+	}
+	defer core.RemoveWatchPID()
+
+	watcher, err := watch.New(repoPath, debounce)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Stop cleanly on Ctrl+C or 'dotcor watch --stop', which cancel the
+	// command's context
+	done := cmd.Context().Done()
+
+	fmt.Printf("Watching %s\n", repoPath)
+	fmt.Printf("Changes are committed after %s without edits", debounce)
+	if push {
+		fmt.Print(" and pushed")
+	}
+	fmt.Println(". Press Ctrl+C to stop.")
+
+	err = watcher.Run(done,
+		func() error { return autoCommit(cfg, repoPath, push) },
+		func(err error) { fmt.Fprintf(os.Stderr, "%s ⚠ %v\n", time.Now().Format("15:04:05"), err) },
+	)
+
+	fmt.Println("\nStopped watching.")
+	return err
+}
+
+// autoCommit commits pending dotfile changes and optionally pushes them.
+// Returns an error (so the commit is retried) if another command holds the lock.
+func autoCommit(cfg *config.Config, repoPath string, push bool) error {
+	if err := core.AcquireLock(); err != nil {
+		if errors.Is(err, core.ErrLockHeld) {
+			return fmt.Errorf("another dotcor command is running, will retry")
+		}
+		return err
+	}
+	defer core.ReleaseLock()
+
+	changed, err := git.HasChanges(repoPath, userPathspecs...)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	changes, err := git.GetChangedFiles(repoPath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	message := fmt.Sprintf("Auto-commit dotfiles - %s", now.Format("2006-01-02 15:04"))
+	if err := git.AutoCommit(repoPath, message, userPathspecs...); err != nil {
+		return fmt.Errorf("committing changes: %w", err)
+	}
+	fmt.Printf("%s ✓ Committed %d change(s)\n", now.Format("15:04:05"), len(changes))
+
+	var committed []string
+	for _, entry := range changes {
+		committed = append(committed, filesRootRelative(cfg, entry.Path))
+	}
+	recordChecksums(cfg, committed...)
+
+	if push {
+		pushes, err := pushAllRemotes(cfg, git.OpenRepo(repoPath))
+		if len(pushes) == 0 {
+			fmt.Printf("%s ⚠ No remote configured, not pushing\n", now.Format("15:04:05"))
+			return nil
+		}
+		if err != nil {
+			// The commit succeeded; the next commit pushes again
+			fmt.Fprintf(os.Stderr, "%s ⚠ Push failed: %v\n", now.Format("15:04:05"), err)
+		}
+	}
+
+	return nil
+}
+
+// showWatchStatus reports whether a watcher is running
+func showWatchStatus() error {
+	pid, running, err := core.GetWatchPID()
+	if err != nil {
+		return err
+	}
+	if running {
+		fmt.Printf("✓ dotcor watch is running (PID %d)\n", pid)
+	} else {
+		fmt.Println("- dotcor watch is not running")
+	}
+	return nil
+}
+
+// stopWatch signals the running watcher to exit
+func stopWatch() error {
+	pid, running, err := core.GetWatchPID()
+	if err != nil {
+		return err
+	}
+	if !running {
+		fmt.Println("- dotcor watch is not running")
+		return nil
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("finding watcher process: %w", err)
+	}
+
+	// Windows has no SIGTERM; the watcher can't clean up its pid file there,
+	// but the next watcher replaces it
+	if runtime.GOOS == "windows" {
+		err = process.Kill()
+	} else {
+		err = process.Signal(syscall.SIGTERM)
+	}
+	if err != nil {
+		return fmt.Errorf("stopping watcher (PID %d): %w", pid, err)
+	}
+
+	fmt.Printf("✓ Stopped dotcor watch (PID %d)\n", pid)
+	return nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CategoryRule places new files matching Pattern in the Category
+// directory of the repo. A pattern without a slash is matched against the
+// file name; one with a slash against the path under the home directory or
+// a directory above the file, whose layout below it is kept.
+//
+//	categories:
+//	  - pattern: .wezterm.lua
+//	    category: terminal
+//	  - pattern: ~/.config/kitty
+//	    category: terminal/kitty
+type CategoryRule struct {
+	Pattern  string `yaml:"pattern"`
+	Category string `yaml:"category"`
+}
+
+var (
+	categoryMu    sync.Mutex
+	categoryRules []CategoryRule
+)
+
+// SetCategoryRules sets the rules from the categories section of the
+// config, which GenerateRepoPath checks before the built-in categories
+func SetCategoryRules(rules []CategoryRule) {
+	categoryMu.Lock()
+	defer categoryMu.Unlock()
+	categoryRules = rules
+}
+
+// CategoryRules returns the rules set with SetCategoryRules
+func CategoryRules() []CategoryRule {
+	categoryMu.Lock()
+	defer categoryMu.Unlock()
+	return categoryRules
+}
+
+// ValidateCategories returns an error if a rule has an invalid pattern or
+// a category that isn't a relative path inside the repo
+func ValidateCategories(rules []CategoryRule) error {
+	for _, rule := range rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("category rule for %q has no pattern", rule.Category)
+		}
+		if _, err := path.Match(rulePattern(rule), ""); err != nil {
+			return fmt.Errorf("invalid category pattern %q: %w", rule.Pattern, err)
+		}
+		if rule.Category == "" {
+			return fmt.Errorf("category rule %q has no category", rule.Pattern)
+		}
+		category := filepath.ToSlash(rule.Category)
+		if path.IsAbs(category) || filepath.IsAbs(rule.Category) {
+			return fmt.Errorf("category %q must be relative to the repo", rule.Category)
+		}
+		for _, part := range strings.Split(category, "/") {
+			if part == ".." {
+				return fmt.Errorf("category %q cannot contain '..'", rule.Category)
+			}
+		}
+	}
+	return nil
+}
+
+// BuiltinCategoryRules returns the built-in categories as rules: exact
+// file names first, then name prefixes in the order they are checked
+func BuiltinCategoryRules() []CategoryRule {
+	names := make([]string, 0, len(categoryMap))
+	for name := range categoryMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make([]CategoryRule, 0, len(names)+len(categoryPrefixes))
+	for _, name := range names {
+		rules = append(rules, CategoryRule{Pattern: name, Category: categoryMap[name]})
+	}
+	for _, p := range categoryPrefixes {
+		rules = append(rules, CategoryRule{Pattern: p.Prefix + "*", Category: p.Category})
+	}
+	return rules
+}
+
+// rulePattern returns rule's pattern in slash form without a leading ~/
+func rulePattern(rule CategoryRule) string {
+	pattern := filepath.ToSlash(rule.Pattern)
+	pattern = strings.TrimPrefix(pattern, "~/")
+	return strings.TrimSuffix(pattern, "/")
+}
+
+// matchCategoryRule returns the first configured rule matching the file at
+// relPath under the home directory, with the path it gets in the category
+func matchCategoryRule(relPath string) (CategoryRule, string, bool) {
+	categoryMu.Lock()
+	rules := categoryRules
+	categoryMu.Unlock()
+
+	slashPath := filepath.ToSlash(relPath)
+	filename := path.Base(slashPath)
+	for _, rule := range rules {
+		pattern := rulePattern(rule)
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, filename); ok {
+				return rule, stripLeadingDot(filename), true
+			}
+			continue
+		}
+
+		// The file itself, or a directory above it
+		for dir := slashPath; dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if ok, _ := path.Match(pattern, dir); !ok {
+				continue
+			}
+			if dir == slashPath {
+				return rule, stripLeadingDot(filename), true
+			}
+			return rule, filepath.FromSlash(strings.TrimPrefix(slashPath, dir+"/")), true
+		}
+	}
+	return CategoryRule{}, "", false
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCategoryRules(t *testing.T) {
+	SetCategoryRules([]CategoryRule{
+		{Pattern: ".wezterm.lua", Category: "terminal"},
+		{Pattern: ".zsh*", Category: "zsh"},
+		{Pattern: "~/.config/kitty", Category: "terminal/kitty"},
+	})
+	t.Cleanup(func() { SetCategoryRules(nil) })
+
+	tests := []struct {
+		sourcePath string
+		want       string
+		wantReason string
+	}{
+		{"~/.wezterm.lua", "terminal/wezterm.lua", "categories: .wezterm.lua"},
+		{"~/.zshrc", "zsh/zshrc", "categories: .zsh*"},
+		{"~/.config/kitty/themes/dark.conf", "terminal/kitty/themes/dark.conf", "categories: ~/.config/kitty"},
+		{"~/.config/kitty", "terminal/kitty/kitty", "categories: ~/.config/kitty"},
+		{"~/.bashrc", "shell/bashrc", "built-in: .bashrc"},
+		{"~/.config/nvim/init.lua", "nvim/init.lua", "built-in: ~/.config layout"},
+		{"~/.obscurefile", "misc/obscurefile", "default"},
+	}
+	for _, tt := range tests {
+		got, reason, err := ExplainRepoPath(tt.sourcePath)
+		if err != nil {
+			t.Fatalf("ExplainRepoPath(%s) error = %v", tt.sourcePath, err)
+		}
+		if filepath.ToSlash(got) != tt.want || reason != tt.wantReason {
+			t.Errorf("ExplainRepoPath(%s) = %s (%s), want %s (%s)", tt.sourcePath, got, reason, tt.want, tt.wantReason)
+		}
+	}
+}
+
+func TestValidateCategories(t *testing.T) {
+	valid := []CategoryRule{{Pattern: "*.lua", Category: "lua"}, {Pattern: "~/.config/foo/", Category: "apps/foo"}}
+	if err := ValidateCategories(valid); err != nil {
+		t.Errorf("ValidateCategories() error = %v", err)
+	}
+
+	for _, rule := range []CategoryRule{
+		{Pattern: "", Category: "shell"},
+		{Pattern: "[", Category: "shell"},
+		{Pattern: ".zshrc", Category: ""},
+		{Pattern: ".zshrc", Category: "/shell"},
+		{Pattern: ".zshrc", Category: "../shell"},
+	} {
+		if err := ValidateCategories([]CategoryRule{rule}); err == nil {
+			t.Errorf("ValidateCategories(%+v) = nil, want error", rule)
+		}
+	}
+}
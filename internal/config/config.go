@@ -3,33 +3,705 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
-	"runtime"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/justincordova/dotcor/internal/log"
 	"gopkg.in/yaml.v3"
 )
 
 // CurrentConfigVersion is the current schema version
-const CurrentConfigVersion = "1.0"
+const CurrentConfigVersion = "1.2"
 
 // Config represents the DotCor configuration
 type Config struct {
-	Version        string        `yaml:"version"`         // Schema version for migrations
-	RepoPath       string        `yaml:"repo_path"`       // ~/.dotcor/files
-	GitEnabled     bool          `yaml:"git_enabled"`     // Whether Git integration is enabled
-	GitRemote      string        `yaml:"git_remote"`      // Optional remote URL
-	IgnorePatterns []string      `yaml:"ignore_patterns"` // Files/patterns to never add
-	ManagedFiles   []ManagedFile `yaml:"managed_files"`   // List of managed dotfiles
+	Version        string            `yaml:"version"`                // Schema version for migrations
+	RepoPath       string            `yaml:"repo_path"`              // ~/.dotcor/files
+	FilesSubdir    string            `yaml:"files_subdir,omitempty"` // Optional directory within repo_path holding dotfiles
+	GitEnabled     bool              `yaml:"git_enabled"`            // Whether Git integration is enabled
+	GitRemote      string            `yaml:"git_remote"`             // Optional remote URL
+	GitRemotes     []Remote          `yaml:"git_remotes,omitempty"`  // Extra remotes that sync also pushes to
+	GitBranch      string            `yaml:"git_branch,omitempty"`   // Branch this machine syncs, e.g. machine/laptop (default: the checked-out branch)
+	IgnorePatterns []string          `yaml:"ignore_patterns"`        // Files/patterns to never add
+	ManagedFiles   []ManagedFile     `yaml:"managed_files"`          // List of managed dotfiles
+	SystemFiles    []ManagedFile     `yaml:"system_files,omitempty"` // Files outside $HOME, kept apart from dotfiles
+	Check          CheckConfig       `yaml:"check,omitempty"`        // Strictness levels for 'dotcor check'
+	Deletion       string            `yaml:"deletion,omitempty"`     // How user files are deleted: trash (default) or delete
+	LinkStyle      string            `yaml:"link_style,omitempty"`   // Symlink targets: relative (default) or absolute
+	GitBackend     string            `yaml:"git_backend,omitempty"`  // Git implementation: auto (default), cli or go-git
+	Variables      map[string]string `yaml:"variables,omitempty"`    // User-defined values for templates
+	Secrets        SecretsConfig     `yaml:"secrets,omitempty"`      // Encryption settings for secret files
+	Hooks          HooksConfig       `yaml:"hooks,omitempty"`        // Shell commands run around operations
+	Watch          WatchConfig       `yaml:"watch,omitempty"`        // Settings for 'dotcor watch'
+	Autosync       AutosyncConfig    `yaml:"autosync,omitempty"`     // Schedule for 'dotcor autosync'
+	Log            LogConfig         `yaml:"log,omitempty"`          // Settings for ~/.dotcor/logs/dotcor.log
+	Backups        BackupsConfig     `yaml:"backups,omitempty"`      // Settings for ~/.dotcor/backups
+	LFS            LFSConfig         `yaml:"lfs,omitempty"`          // Git LFS for large binary files
+	Bundles        []Bundle          `yaml:"bundles,omitempty"`      // Named groups of files enabled and disabled together
+	Scan           ScanConfig        `yaml:"scan,omitempty"`         // Secret scanner settings for add, check and scan
+	Lint           LintConfig        `yaml:"lint,omitempty"`         // Linters run by 'dotcor lint' and before commits
+	Categories     []CategoryRule    `yaml:"categories,omitempty"`   // Where new files go in the repo, before the built-in categories
+	UpdateCheck    *bool             `yaml:"update_check,omitempty"` // Check for new dotcor releases once a day (default true)
+	UI             UIConfig          `yaml:"ui,omitempty"`           // Banner, colors and compact output
+	Packages       PackagesConfig    `yaml:"packages,omitempty"`     // System packages installed by 'dotcor packages install'
+	Defaults       DefaultsConfig    `yaml:"defaults,omitempty"`     // macOS preference domains kept by 'dotcor defaults'
+
+	// Messages of dotcor's automatic commits
+	CommitStyle    string `yaml:"commit_style,omitempty"`    // plain (default) or conventional
+	CommitTemplate string `yaml:"commit_template,omitempty"` // Template with .Files, .Count, .Hostname, ...; overrides commit_style
+
+	Repositories map[string]RepoConfig `yaml:"repositories,omitempty"` // Named repositories selected with --repo
+}
+
+// UpdateCheckEnabled reports whether dotcor looks for new releases
+func (c *Config) UpdateCheckEnabled() bool {
+	return c.UpdateCheck == nil || *c.UpdateCheck
+}
+
+// Secret severities, lowest first
+const (
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+	SeverityOff    = "off" // Disables a rule in scan.rules
+)
+
+// ScanConfig tunes the secret scanner
+type ScanConfig struct {
+	Allowlist []string          `yaml:"allowlist,omitempty"`  // Regexps; lines matching one are never reported
+	Entropy   float64           `yaml:"entropy,omitempty"`    // Minimum bits per character for high-entropy strings (default 4.0)
+	Rules     map[string]string `yaml:"rules,omitempty"`      // Severity override per rule ID: low, medium, high or off
+	PreCommit bool              `yaml:"pre_commit,omitempty"` // Keep a git pre-commit hook that blocks staged secrets
+}
+
+// ValidateSeverity returns an error if severity is not a known severity
+func ValidateSeverity(severity string) error {
+	switch severity {
+	case SeverityLow, SeverityMedium, SeverityHigh, SeverityOff:
+		return nil
+	}
+	return fmt.Errorf("invalid severity %q (expected low, medium, high or off)", severity)
+}
+
+// ValidateScanConfig returns an error if an allowlist entry doesn't compile,
+// the entropy threshold is negative, or a rule has an unknown severity
+func ValidateScanConfig(scan ScanConfig) error {
+	for _, pattern := range scan.Allowlist {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid scan allowlist pattern %q: %w", pattern, err)
+		}
+	}
+	if scan.Entropy < 0 {
+		return fmt.Errorf("invalid scan entropy %v (must not be negative)", scan.Entropy)
+	}
+	for rule, severity := range scan.Rules {
+		if err := ValidateSeverity(severity); err != nil {
+			return fmt.Errorf("scan rule %s: %w", rule, err)
+		}
+	}
+	return nil
+}
+
+// LintConfig selects the linters run by 'dotcor lint' and before dotcor
+// commits
+type LintConfig struct {
+	FailOnError bool          `yaml:"fail_on_error,omitempty"` // Refuse to commit files with lint errors
+	Disable     []string      `yaml:"disable,omitempty"`       // Built-in or configured linters not to run, e.g. shellcheck
+	Linters     []LintCommand `yaml:"linters,omitempty"`       // Extra linters for other file types
+}
+
+// LintCommand is a linter configured in the lint section: a command run
+// with the file to lint as its last argument, failing the file when it
+// exits non-zero
+type LintCommand struct {
+	Name    string   `yaml:"name"`
+	Command string   `yaml:"command"` // e.g. "luacheck --no-color"
+	Files   []string `yaml:"files"`   // Glob patterns matched against the repo path or its file name, e.g. *.lua
+}
+
+// ValidateLintConfig returns an error if a configured linter lacks a name,
+// command or file patterns, or has an invalid pattern
+func ValidateLintConfig(lint LintConfig) error {
+	for _, c := range lint.Linters {
+		if c.Name == "" || strings.TrimSpace(c.Command) == "" || len(c.Files) == 0 {
+			return fmt.Errorf("lint linter %q needs a name, a command and files", c.Name)
+		}
+		for _, pattern := range c.Files {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("lint linter %s: invalid files pattern %q", c.Name, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Bundle groups related managed files, e.g. everything for nvim, so they
+// can be enabled and disabled on a machine as one unit
+type Bundle struct {
+	Name     string   `yaml:"name"`
+	Files    []string `yaml:"files"`              // Source paths; a directory covers every managed file below it
+	Disabled bool     `yaml:"disabled,omitempty"` // Files are not deployed while disabled
+}
+
+// Contains checks if sourcePath is one of the bundle's files or lies below one of its directories
+func (b Bundle) Contains(sourcePath string) bool {
+	for _, f := range b.Files {
+		if sourcePath == f || strings.HasPrefix(sourcePath, strings.TrimSuffix(f, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateBundles returns an error if a bundle has no name, no files, or a duplicate name
+func ValidateBundles(bundles []Bundle) error {
+	seen := make(map[string]bool)
+	for _, b := range bundles {
+		if b.Name == "" {
+			return fmt.Errorf("bundle name is empty")
+		}
+		if strings.ContainsAny(b.Name, "/\\ ") {
+			return fmt.Errorf("invalid bundle name %q (no spaces or slashes)", b.Name)
+		}
+		if seen[b.Name] {
+			return fmt.Errorf("duplicate bundle %q", b.Name)
+		}
+		seen[b.Name] = true
+		if len(b.Files) == 0 {
+			return fmt.Errorf("bundle %q has no files", b.Name)
+		}
+	}
+	return nil
+}
+
+// Remote is an extra git remote, pushed to alongside origin
+type Remote struct {
+	Name string `yaml:"name"` // Git remote name, e.g. "gitea"
+	URL  string `yaml:"url"`
+}
+
+// ValidateRemotes returns an error if a remote has no name or URL, reuses
+// origin (set by git_remote), or has a duplicate name
+func ValidateRemotes(remotes []Remote) error {
+	seen := make(map[string]bool)
+	for _, r := range remotes {
+		if r.Name == "" {
+			return fmt.Errorf("git remote name is empty")
+		}
+		if strings.ContainsAny(r.Name, "/\\: ") || strings.HasPrefix(r.Name, "-") {
+			return fmt.Errorf("invalid git remote name %q", r.Name)
+		}
+		if r.Name == "origin" {
+			return fmt.Errorf("git remote %q is set by git_remote", r.Name)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("duplicate git remote %q", r.Name)
+		}
+		seen[r.Name] = true
+		if r.URL == "" {
+			return fmt.Errorf("git remote %q has no url", r.Name)
+		}
+	}
+	return nil
+}
+
+// WatchConfig configures auto-commit in 'dotcor watch'
+type WatchConfig struct {
+	Debounce string `yaml:"debounce,omitempty"` // Quiet period before committing, e.g. "30s" (default)
+	Push     bool   `yaml:"push,omitempty"`     // Push to remote after each auto-commit
+}
+
+// ValidateWatchDebounce returns an error if debounce is not a valid duration
+func ValidateWatchDebounce(debounce string) error {
+	if debounce == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(debounce); err != nil || d <= 0 {
+		return fmt.Errorf("invalid watch debounce %q (expected a duration like 30s or 2m)", debounce)
+	}
+	return nil
+}
+
+// Package managers supported in the packages section
+const (
+	PackageManagerBrew  = "brew"
+	PackageManagerApt   = "apt"
+	PackageManagerCargo = "cargo"
+)
+
+// PackagesConfig lists the packages the dotfiles need on a machine,
+// installed by 'dotcor packages install'
+type PackagesConfig struct {
+	Brew  []string `yaml:"brew,omitempty"`  // Homebrew formulas
+	Apt   []string `yaml:"apt,omitempty"`   // Debian/Ubuntu packages
+	Cargo []string `yaml:"cargo,omitempty"` // Rust crates installed with 'cargo install'
+}
+
+// Get returns the packages declared for a package manager
+func (p PackagesConfig) Get(manager string) []string {
+	switch manager {
+	case PackageManagerBrew:
+		return p.Brew
+	case PackageManagerApt:
+		return p.Apt
+	case PackageManagerCargo:
+		return p.Cargo
+	}
+	return nil
+}
+
+// IsEmpty reports whether no packages are declared
+func (p PackagesConfig) IsEmpty() bool {
+	return len(p.Brew) == 0 && len(p.Apt) == 0 && len(p.Cargo) == 0
+}
+
+// ValidatePackages returns an error if a package name is empty, contains
+// whitespace, starts with a dash, or is listed twice for a manager
+func ValidatePackages(p PackagesConfig) error {
+	for _, manager := range []string{PackageManagerBrew, PackageManagerApt, PackageManagerCargo} {
+		seen := make(map[string]bool)
+		for _, name := range p.Get(manager) {
+			if name == "" || strings.ContainsAny(name, " \t\n") || strings.HasPrefix(name, "-") {
+				return fmt.Errorf("invalid %s package %q", manager, name)
+			}
+			if seen[name] {
+				return fmt.Errorf("duplicate %s package %q", manager, name)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
+}
+
+// DefaultsDir is the directory of the repository holding the macOS
+// preference domains captured by 'dotcor defaults capture'
+const DefaultsDir = "macos-defaults"
+
+// VSCodeDir is the directory of the repository holding VS Code's settings
+// and the list of its extensions
+const VSCodeDir = "vscode"
+
+// VSCodeExtensionsPath is the list of VS Code extensions written by
+// 'dotcor vscode export', relative to the files root
+const VSCodeExtensionsPath = VSCodeDir + "/extensions.txt"
+
+// IsDataRepoPath checks if relPath, relative to the files root, is kept by
+// dotcor itself rather than as a managed file, like captured macOS
+// preferences
+func IsDataRepoPath(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return relPath == DefaultsDir || strings.HasPrefix(relPath, DefaultsDir+"/") || relPath == VSCodeExtensionsPath
+}
+
+// DefaultsConfig selects the macOS preference domains 'dotcor defaults'
+// captures and applies, each with the keys to keep; no keys keeps them all
+type DefaultsConfig struct {
+	Domains map[string][]string `yaml:"domains,omitempty"` // e.g. com.apple.dock: [autohide, tilesize]
+}
+
+// RepoPath returns where a captured domain is stored, relative to the
+// files root
+func (d DefaultsConfig) RepoPath(domain string) string {
+	return path.Join(DefaultsDir, domain+".plist")
+}
+
+// ValidateDefaults returns an error if a domain or key name is empty, or a
+// domain name can't be a file name or could be taken for an option
+func ValidateDefaults(d DefaultsConfig) error {
+	for domain, keys := range d.Domains {
+		if domain == "" || strings.ContainsAny(domain, "/\\ \t\n") || strings.HasPrefix(domain, "-") || strings.HasPrefix(domain, ".") {
+			return fmt.Errorf("invalid defaults domain %q", domain)
+		}
+		for _, key := range keys {
+			if key == "" {
+				return fmt.Errorf("defaults domain %s has an empty key", domain)
+			}
+		}
+	}
+	return nil
+}
+
+// AutosyncConfig configures the scheduled sync installed by 'dotcor autosync'
+type AutosyncConfig struct {
+	Interval string `yaml:"interval,omitempty"` // Time between syncs, e.g. "1h" (default)
+}
+
+// ValidateAutosyncInterval returns an error if interval is not a duration
+// of at least a minute
+func ValidateAutosyncInterval(interval string) error {
+	if interval == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil || d < time.Minute {
+		return fmt.Errorf("invalid autosync interval %q (expected a duration of at least 1m, like 30m or 2h)", interval)
+	}
+	return nil
+}
+
+// BackupsConfig configures the backups taken before destructive operations
+type BackupsConfig struct {
+	Compress bool `yaml:"compress,omitempty"` // Store finished backup sets as .tar.zst archives (needs zstd)
+}
+
+// DefaultLFSMinSizeKB is the size from which binary files go to Git LFS
+const DefaultLFSMinSizeKB = 512
+
+// LFSConfig configures storing large binary files, such as fonts and
+// terminal background images, with Git LFS
+type LFSConfig struct {
+	Enabled   bool     `yaml:"enabled,omitempty"`     // Track large binary files with Git LFS when git-lfs is installed
+	MinSizeKB int      `yaml:"min_size_kb,omitempty"` // Binary files at least this large are tracked (default 512)
+	Patterns  []string `yaml:"patterns,omitempty"`    // File name globs always tracked, e.g. "*.ttf"
+}
+
+// GetMinSize returns the size in bytes from which binary files are tracked
+func (l LFSConfig) GetMinSize() int64 {
+	if l.MinSizeKB <= 0 {
+		return DefaultLFSMinSizeKB * 1024
+	}
+	return int64(l.MinSizeKB) * 1024
+}
+
+// ValidateLFSConfig returns an error if the LFS settings are invalid
+func ValidateLFSConfig(l LFSConfig) error {
+	if l.MinSizeKB < 0 {
+		return fmt.Errorf("invalid lfs min_size_kb %d", l.MinSizeKB)
+	}
+	for _, pattern := range l.Patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid lfs pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Log file formats
+const (
+	LogFormatText = "text" // key=value lines (default)
+	LogFormatJSON = "json" // One JSON object per line
+)
+
+// UIConfig controls how dotcor's output looks
+type UIConfig struct {
+	Banner  *bool `yaml:"banner,omitempty"`  // Show the banner when dotcor runs without a command (default true)
+	Color   *bool `yaml:"color,omitempty"`   // Color output on terminals (default true)
+	Compact bool  `yaml:"compact,omitempty"` // Show status as one line instead of a section
+}
+
+// BannerEnabled reports whether dotcor with no command shows the banner
+func (u UIConfig) BannerEnabled() bool {
+	return u.Banner == nil || *u.Banner
+}
+
+// ColorEnabled reports whether output is colored on terminals
+func (u UIConfig) ColorEnabled() bool {
+	return u.Color == nil || *u.Color
+}
+
+// LogConfig configures the log of mutating operations
+type LogConfig struct {
+	Format    string `yaml:"format,omitempty"`      // text (default) or json
+	MaxSizeMB int    `yaml:"max_size_mb,omitempty"` // Rotate dotcor.log past this size (default 1)
+	MaxFiles  int    `yaml:"max_files,omitempty"`   // Rotated log files kept (default 3)
+}
+
+// ValidateLogConfig returns an error if the log settings are invalid
+func ValidateLogConfig(lc LogConfig) error {
+	switch lc.Format {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("invalid log format %q (expected text or json)", lc.Format)
+	}
+	if lc.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid log max_size_mb %d", lc.MaxSizeMB)
+	}
+	if lc.MaxFiles < 0 {
+		return fmt.Errorf("invalid log max_files %d", lc.MaxFiles)
+	}
+	return nil
+}
+
+// Hook events, named as in the hooks section of config.yaml
+const (
+	HookPreAdd     = "pre_add"
+	HookPostAdd    = "post_add"
+	HookPreRemove  = "pre_remove"
+	HookPostRemove = "post_remove"
+	HookPreSync    = "pre_sync"
+	HookPostSync   = "post_sync"
+	HookPreApply   = "pre_apply"
+	HookPostApply  = "post_apply"
+)
+
+// HooksConfig holds shell commands run before and after operations.
+// A failing pre hook aborts the operation; post hook failures are reported.
+type HooksConfig struct {
+	PreAdd     []string `yaml:"pre_add,omitempty"`
+	PostAdd    []string `yaml:"post_add,omitempty"`
+	PreRemove  []string `yaml:"pre_remove,omitempty"`
+	PostRemove []string `yaml:"post_remove,omitempty"`
+	PreSync    []string `yaml:"pre_sync,omitempty"`
+	PostSync   []string `yaml:"post_sync,omitempty"`
+	PreApply   []string `yaml:"pre_apply,omitempty"`
+	PostApply  []string `yaml:"post_apply,omitempty"`
+}
+
+// Get returns the commands for a hook event
+func (h HooksConfig) Get(event string) []string {
+	switch event {
+	case HookPreAdd:
+		return h.PreAdd
+	case HookPostAdd:
+		return h.PostAdd
+	case HookPreRemove:
+		return h.PreRemove
+	case HookPostRemove:
+		return h.PostRemove
+	case HookPreSync:
+		return h.PreSync
+	case HookPostSync:
+		return h.PostSync
+	case HookPreApply:
+		return h.PreApply
+	case HookPostApply:
+		return h.PostApply
+	}
+	return nil
+}
+
+// Encryption backends for secret files
+const (
+	SecretsBackendAge = "age" // age (default)
+	SecretsBackendGPG = "gpg" // GnuPG
+)
+
+// SecretsConfig configures how secret files are encrypted in the repo
+type SecretsConfig struct {
+	Backend    string   `yaml:"backend,omitempty"`    // age (default) or gpg
+	Recipients []string `yaml:"recipients,omitempty"` // age public keys or GPG key IDs to encrypt to
+	Identity   string   `yaml:"identity,omitempty"`   // age identity file (default ~/.config/age/keys.txt)
+}
+
+// ValidateSecretsBackend returns an error if backend is not a known backend
+func ValidateSecretsBackend(backend string) error {
+	switch backend {
+	case "", SecretsBackendAge, SecretsBackendGPG:
+		return nil
+	}
+	return fmt.Errorf("invalid secrets backend %q (expected age or gpg)", backend)
+}
+
+// GetBackend returns the configured backend, defaulting to age
+func (s SecretsConfig) GetBackend() string {
+	if s.Backend == "" {
+		return SecretsBackendAge
+	}
+	return s.Backend
+}
+
+// Git backends for repository operations
+const (
+	GitBackendAuto  = "auto"   // git command if installed, otherwise built-in go-git (default)
+	GitBackendCLI   = "cli"    // Always use the git command
+	GitBackendGoGit = "go-git" // Built-in go-git, falling back to the git command where needed
+)
+
+// ValidateGitBackend returns an error if backend is not a known git backend
+func ValidateGitBackend(backend string) error {
+	switch backend {
+	case "", GitBackendAuto, GitBackendCLI, GitBackendGoGit:
+		return nil
+	}
+	return fmt.Errorf("invalid git backend %q (expected auto, cli or go-git)", backend)
+}
+
+// Deletion modes for user files that dotcor removes or overwrites
+const (
+	DeletionTrash  = "trash"  // Move to the OS trash (default)
+	DeletionDelete = "delete" // Delete permanently
+)
+
+// ValidateDeletion returns an error if mode is not a known deletion mode
+func ValidateDeletion(mode string) error {
+	switch mode {
+	case "", DeletionTrash, DeletionDelete:
+		return nil
+	}
+	return fmt.Errorf("invalid deletion mode %q (expected trash or delete)", mode)
+}
+
+// UseTrash reports whether deleted user files should go to the OS trash
+func (c *Config) UseTrash() bool {
+	return c.Deletion != DeletionDelete
+}
+
+// Link styles for symlinks created in the home directory
+const (
+	LinkStyleRelative = "relative" // Target relative to the link's directory (default)
+	LinkStyleAbsolute = "absolute" // Absolute target path
+)
+
+// ValidateLinkStyle returns an error if style is not a known link style
+func ValidateLinkStyle(style string) error {
+	switch style {
+	case "", LinkStyleRelative, LinkStyleAbsolute:
+		return nil
+	}
+	return fmt.Errorf("invalid link style %q (expected relative or absolute)", style)
+}
+
+// UseAbsoluteLinks reports whether new symlinks should use absolute targets
+func (c *Config) UseAbsoluteLinks() bool {
+	return c.LinkStyle == LinkStyleAbsolute
+}
+
+// Check levels control how a finding affects the result of 'dotcor check'
+const (
+	CheckLevelOff  = "off"  // Finding type is not checked
+	CheckLevelWarn = "warn" // Finding is reported but does not fail the check
+	CheckLevelFail = "fail" // Finding fails the check
+)
+
+// Check names used in CheckConfig and check reports
+const (
+	CheckLargeFiles = "large_files"
+	CheckSecrets    = "secrets"
+	CheckOrphans    = "orphans"
+)
+
+// CheckConfig holds per-check strictness levels (empty means default)
+type CheckConfig struct {
+	LargeFiles string `yaml:"large_files,omitempty"` // Repo files over the size threshold
+	Secrets    string `yaml:"secrets,omitempty"`     // Potential secrets in repo files
+	Orphans    string `yaml:"orphans,omitempty"`     // Repo files not tracked in config
 }
 
 // ManagedFile represents a single managed dotfile
 type ManagedFile struct {
-	SourcePath     string    `yaml:"source_path"`     // ~/.zshrc (normalized, with ~)
-	RepoPath       string    `yaml:"repo_path"`       // shell/zshrc (relative to files/)
-	AddedAt        time.Time `yaml:"added_at"`        // When the file was added
-	Platforms      []string  `yaml:"platforms"`       // ["darwin", "linux"] or empty for all
-	HasUncommitted bool      `yaml:"has_uncommitted"` // Track if Git commit failed
+	SourcePath     string    `yaml:"source_path"`         // ~/.zshrc (normalized, with ~)
+	RepoPath       string    `yaml:"repo_path"`           // shell/zshrc (relative to files/)
+	AddedAt        time.Time `yaml:"added_at"`            // When the file was added
+	Platforms      []string  `yaml:"platforms"`           // ["darwin", "linux"] or empty for all
+	HasUncommitted bool      `yaml:"has_uncommitted"`     // Track if Git commit failed
+	Scope          string    `yaml:"scope,omitempty"`     // ScopeSystem for files outside $HOME, empty for user dotfiles
+	Owner          string    `yaml:"owner,omitempty"`     // Original "uid:gid" of a system file
+	Perm           string    `yaml:"perm,omitempty"`      // Original permissions (octal), kept on the repo file and deployed copies
+	Mode           string    `yaml:"mode,omitempty"`      // How the file is deployed: symlink (default), copy, hardlink or compose
+	Encrypted      bool      `yaml:"encrypted,omitempty"` // Stored encrypted in the repo, decrypted locally
+	Hooks          []string  `yaml:"hooks,omitempty"`     // Commands run after the file is linked by apply or changed by sync
+	Variants       []Variant `yaml:"variants,omitempty"`  // Per-host alternatives to RepoPath
+}
+
+// ScopeSystem marks a managed file outside $HOME that needs elevated privileges
+const ScopeSystem = "system"
+
+// IsSystem checks if the managed file is in the system scope
+func (mf ManagedFile) IsSystem() bool {
+	return mf.Scope == ScopeSystem
+}
+
+// Deployment modes for managed files
+const (
+	DeployModeSymlink  = "symlink"  // Symlink to the repo file (default)
+	DeployModeCopy     = "copy"     // Copy of the repo file, synced back by 'dotcor sync'
+	DeployModeHardlink = "hardlink" // Hard link to the repo file, same filesystem only
+	DeployModeCompose  = "compose"  // Generated file including the repo file and an unmanaged local file
+)
+
+// ValidateDeployMode returns an error if mode is not a known deployment mode
+func ValidateDeployMode(mode string) error {
+	switch mode {
+	case "", DeployModeSymlink, DeployModeCopy, DeployModeHardlink, DeployModeCompose:
+		return nil
+	}
+	return fmt.Errorf("invalid deployment mode %q (expected symlink, copy, hardlink or compose)", mode)
+}
+
+// IsCopy checks if the file is deployed as a copy instead of a symlink
+func (mf ManagedFile) IsCopy() bool {
+	return mf.Mode == DeployModeCopy
+}
+
+// IsHardlink checks if the file is deployed as a hard link to the repo file
+func (mf ManagedFile) IsHardlink() bool {
+	return mf.Mode == DeployModeHardlink
+}
+
+// IsComposed checks if the file is deployed as a generated file that
+// includes the repo file and LocalPath
+func (mf ManagedFile) IsComposed() bool {
+	return mf.Mode == DeployModeCompose
+}
+
+// LocalSuffix names the unmanaged file a composed file includes after the
+// repo file, e.g. ~/.zshrc.local for ~/.zshrc
+const LocalSuffix = ".local"
+
+// LocalPath returns the unmanaged file a composed file includes, for
+// machine-local additions that stay out of the repo
+func (mf ManagedFile) LocalPath() string {
+	return mf.SourcePath + LocalSuffix
+}
+
+// TemplateExt marks repo files that are rendered per machine before linking
+const TemplateExt = ".tmpl"
+
+// IsTemplate checks if the repo file is a template
+func (mf ManagedFile) IsTemplate() bool {
+	return strings.HasSuffix(mf.RepoPath, TemplateExt)
+}
+
+// FilePerm returns the recorded permissions, with ok=false if none were recorded
+func (mf ManagedFile) FilePerm() (perm os.FileMode, ok bool, err error) {
+	if mf.Perm == "" {
+		return 0, false, nil
+	}
+	mode, err := strconv.ParseUint(mf.Perm, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, false, fmt.Errorf("invalid perm %q (expected octal like 600)", mf.Perm)
+	}
+	return os.FileMode(mode), true, nil
+}
+
+// GetDefaultCheckLevels returns the default strictness for each check
+func GetDefaultCheckLevels() map[string]string {
+	return map[string]string{
+		CheckLargeFiles: CheckLevelWarn,
+		CheckSecrets:    CheckLevelFail,
+		CheckOrphans:    CheckLevelWarn,
+	}
+}
+
+// ValidateCheckLevel returns an error if level is not off, warn, or fail
+func ValidateCheckLevel(level string) error {
+	switch level {
+	case CheckLevelOff, CheckLevelWarn, CheckLevelFail:
+		return nil
+	}
+	return fmt.Errorf("invalid check level %q (expected off, warn, or fail)", level)
+}
+
+// GetLevel returns the configured level for a check, falling back to the default
+func (c CheckConfig) GetLevel(check string) string {
+	var level string
+	switch check {
+	case CheckLargeFiles:
+		level = c.LargeFiles
+	case CheckSecrets:
+		level = c.Secrets
+	case CheckOrphans:
+		level = c.Orphans
+	}
+
+	if level == "" {
+		return GetDefaultCheckLevels()[check]
+	}
+	return level
 }
 
 // GetDefaultIgnorePatterns returns sensible default ignore patterns
@@ -55,28 +727,31 @@ func GetDefaultIgnorePatterns() []string {
 	}
 }
 
-// GetConfigDir returns the DotCor config directory path
-func GetConfigDir() (string, error) {
-	home, err := os.UserHomeDir()
+// LoadConfig loads config.yaml from the config directory (see GetConfigDir)
+// Returns default config if file doesn't exist
+// Handles version migrations automatically
+// With a repository selected (SelectRepo), its section replaces the
+// default repository's settings
+func LoadConfig() (*Config, error) {
+	root, err := loadRootConfig(true)
 	if err != nil {
-		return "", fmt.Errorf("getting home directory: %w", err)
+		return nil, err
 	}
-	return filepath.Join(home, ".dotcor"), nil
+	return withSelectedRepo(root)
 }
 
-// GetConfigPath returns the config file path
-func GetConfigPath() (string, error) {
-	configDir, err := GetConfigDir()
+// LoadConfigUnvalidated is LoadConfig without validating the settings, so
+// 'dotcor config set' can fix an invalid one
+func LoadConfigUnvalidated() (*Config, error) {
+	root, err := loadRootConfig(false)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return filepath.Join(configDir, "config.yaml"), nil
+	return withSelectedRepo(root)
 }
 
-// LoadConfig loads config from ~/.dotcor/config.yaml
-// Returns default config if file doesn't exist
-// Handles version migrations automatically
-func LoadConfig() (*Config, error) {
+// loadRootConfig loads config.yaml as stored, with the default repository
+func loadRootConfig(validate bool) (*Config, error) {
 	configPath, err := GetConfigPath()
 	if err != nil {
 		return nil, err
@@ -87,7 +762,19 @@ func LoadConfig() (*Config, error) {
 		// Return default config
 		return NewDefaultConfig()
 	}
+	return loadConfigFile(configPath, validate)
+}
 
+// LoadConfigFile loads the config file at path, migrating it to the
+// current version, e.g. the config.yaml of a cloned repository. A config
+// with invalid settings, like an unknown deploy mode, is an error.
+func LoadConfigFile(configPath string) (*Config, error) {
+	return loadConfigFile(configPath, true)
+}
+
+// loadConfigFile loads and migrates the config file at path, validating it
+// if validate is set
+func loadConfigFile(configPath string, validate bool) (*Config, error) {
 	// Read config file
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -101,27 +788,34 @@ func LoadConfig() (*Config, error) {
 	}
 
 	// Check if migration is needed
+	loaded := &cfg
 	if cfg.Version != CurrentConfigVersion {
 		migratedCfg, err := MigrateConfig(&cfg)
 		if err != nil {
 			return nil, fmt.Errorf("migrating config: %w", err)
 		}
-		return migratedCfg, nil
+		loaded = migratedCfg
 	}
 
-	return &cfg, nil
+	if !validate {
+		return loaded, nil
+	}
+	if err := ValidateConfig(loaded); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w\nFix it with 'dotcor config set' or 'dotcor config edit'", configPath, err)
+	}
+	return loaded, nil
 }
 
 // NewDefaultConfig creates a new config with sensible defaults
 func NewDefaultConfig() (*Config, error) {
-	configDir, err := GetConfigDir()
+	dataDir, err := GetDataDir()
 	if err != nil {
 		return nil, err
 	}
 
 	return &Config{
 		Version:        CurrentConfigVersion,
-		RepoPath:       filepath.Join(configDir, "files"),
+		RepoPath:       filepath.Join(dataDir, "files"),
 		GitEnabled:     true,
 		GitRemote:      "",
 		IgnorePatterns: GetDefaultIgnorePatterns(),
@@ -143,8 +837,16 @@ func (c *Config) SaveConfig() error {
 		return fmt.Errorf("creating config directory: %w", err)
 	}
 
+	// A named repository's settings go into its section
+	out := c
+	if name := SelectedRepo(); name != "" {
+		if out, err = c.intoRoot(name); err != nil {
+			return err
+		}
+	}
+
 	// Marshal to YAML
-	data, err := yaml.Marshal(c)
+	data, err := yaml.Marshal(out)
 	if err != nil {
 		return fmt.Errorf("marshaling config: %w", err)
 	}
@@ -161,6 +863,7 @@ func (c *Config) SaveConfig() error {
 		return fmt.Errorf("renaming config file: %w", err)
 	}
 
+	log.Info("saved config", "path", configPath)
 	return nil
 }
 
@@ -177,35 +880,107 @@ func (c *Config) AddManagedFile(mf ManagedFile) error {
 
 // RemoveManagedFile removes a managed file by source path
 func (c *Config) RemoveManagedFile(sourcePath string) error {
-	normalized, err := NormalizePath(sourcePath)
-	if err != nil {
-		normalized = sourcePath
+	i := findFile(c.ManagedFiles, sourcePath)
+	if i < 0 {
+		return fmt.Errorf("file %s is not managed", sourcePath)
 	}
 
-	for i, mf := range c.ManagedFiles {
-		if mf.SourcePath == normalized || mf.SourcePath == sourcePath {
-			c.ManagedFiles = append(c.ManagedFiles[:i], c.ManagedFiles[i+1:]...)
-			return c.SaveConfig()
+	c.ManagedFiles = append(c.ManagedFiles[:i], c.ManagedFiles[i+1:]...)
+	return c.SaveConfig()
+}
+
+// GetManagedFile retrieves managed file by source path
+func (c *Config) GetManagedFile(sourcePath string) (*ManagedFile, error) {
+	i := findFile(c.ManagedFiles, sourcePath)
+	if i < 0 {
+		return nil, fmt.Errorf("file %s is not managed", sourcePath)
+	}
+
+	return &c.ManagedFiles[i], nil
+}
+
+// AddSystemFile adds a new system file to the config
+func (c *Config) AddSystemFile(mf ManagedFile) error {
+	if findFile(c.SystemFiles, mf.SourcePath) >= 0 {
+		return fmt.Errorf("system file %s is already managed", mf.SourcePath)
+	}
+
+	mf.Scope = ScopeSystem
+	c.SystemFiles = append(c.SystemFiles, mf)
+	return c.SaveConfig()
+}
+
+// RemoveSystemFile removes a system file by source path
+func (c *Config) RemoveSystemFile(sourcePath string) error {
+	i := findFile(c.SystemFiles, sourcePath)
+	if i < 0 {
+		return fmt.Errorf("system file %s is not managed", sourcePath)
+	}
+
+	c.SystemFiles = append(c.SystemFiles[:i], c.SystemFiles[i+1:]...)
+	return c.SaveConfig()
+}
+
+// GetSystemFile retrieves a system file by source path
+func (c *Config) GetSystemFile(sourcePath string) (*ManagedFile, error) {
+	i := findFile(c.SystemFiles, sourcePath)
+	if i < 0 {
+		return nil, fmt.Errorf("system file %s is not managed", sourcePath)
+	}
+
+	return &c.SystemFiles[i], nil
+}
+
+// GetSystemFilesForPlatform returns system files that apply on current
+// platform, marked as system scope
+func (c *Config) GetSystemFilesForPlatform() []ManagedFile {
+	files := filterForPlatform(c.SystemFiles, GetCurrentPlatform())
+	for i := range files {
+		files[i].Scope = ScopeSystem
+	}
+	return files
+}
+
+// TrackedRepoPaths returns the repo paths of all managed and system files
+func (c *Config) TrackedRepoPaths() map[string]bool {
+	tracked := make(map[string]bool)
+	for _, mf := range c.ManagedFiles {
+		tracked[mf.RepoPath] = true
+		for _, v := range mf.Variants {
+			tracked[v.RepoPath] = true
 		}
 	}
+	for _, mf := range c.SystemFiles {
+		tracked[mf.RepoPath] = true
+	}
+	return tracked
+}
 
-	return fmt.Errorf("file %s is not managed", sourcePath)
+// SharesRepoPath checks if a managed file other than mf, such as the same
+// file's entry for another platform, is stored at mf's repo path
+func (c *Config) SharesRepoPath(mf ManagedFile) bool {
+	for _, other := range c.ManagedFiles {
+		if other.RepoPath == mf.RepoPath && other.SourcePath != mf.SourcePath {
+			return true
+		}
+	}
+	return false
 }
 
-// GetManagedFile retrieves managed file by source path
-func (c *Config) GetManagedFile(sourcePath string) (*ManagedFile, error) {
+// findFile returns the index of the file with sourcePath, or -1
+func findFile(files []ManagedFile, sourcePath string) int {
 	normalized, err := NormalizePath(sourcePath)
 	if err != nil {
 		normalized = sourcePath
 	}
 
-	for i := range c.ManagedFiles {
-		if c.ManagedFiles[i].SourcePath == normalized || c.ManagedFiles[i].SourcePath == sourcePath {
-			return &c.ManagedFiles[i], nil
+	for i := range files {
+		if files[i].SourcePath == normalized || files[i].SourcePath == sourcePath {
+			return i
 		}
 	}
 
-	return nil, fmt.Errorf("file %s is not managed", sourcePath)
+	return -1
 }
 
 // IsManaged checks if a file is already managed
@@ -214,12 +989,59 @@ func (c *Config) IsManaged(sourcePath string) bool {
 	return err == nil
 }
 
-// GetManagedFilesForPlatform returns files that should be linked on current platform
+// GetManagedFilesForPlatform returns files that should be linked on current platform,
+// with the variant for this machine selected. Files in a disabled bundle are left
+// out; files whose variant can't be selected keep their default RepoPath.
 func (c *Config) GetManagedFilesForPlatform() []ManagedFile {
-	platform := GetCurrentPlatform()
 	result := []ManagedFile{}
+	for _, mf := range filterForPlatform(c.ManagedFiles, GetCurrentPlatform()) {
+		if c.InDisabledBundle(mf.SourcePath) {
+			continue
+		}
+		if resolved, err := ResolveVariant(mf); err == nil {
+			mf = resolved
+		}
+		result = append(result, mf)
+	}
+	return result
+}
 
-	for _, mf := range c.ManagedFiles {
+// GetBundle retrieves a bundle by name
+func (c *Config) GetBundle(name string) (*Bundle, error) {
+	for i := range c.Bundles {
+		if c.Bundles[i].Name == name {
+			return &c.Bundles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("bundle %s does not exist", name)
+}
+
+// GetBundleFiles returns the managed files in bundle b that apply on current platform
+func (c *Config) GetBundleFiles(b Bundle) []ManagedFile {
+	result := []ManagedFile{}
+	for _, mf := range filterForPlatform(c.ManagedFiles, GetCurrentPlatform()) {
+		if b.Contains(mf.SourcePath) {
+			result = append(result, mf)
+		}
+	}
+	return result
+}
+
+// InDisabledBundle checks if sourcePath belongs to a disabled bundle
+func (c *Config) InDisabledBundle(sourcePath string) bool {
+	for _, b := range c.Bundles {
+		if b.Disabled && b.Contains(sourcePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterForPlatform returns the files that apply on platform
+func filterForPlatform(files []ManagedFile, platform string) []ManagedFile {
+	result := []ManagedFile{}
+
+	for _, mf := range files {
 		if ShouldApplyOnPlatform(mf.Platforms, platform) {
 			result = append(result, mf)
 		}
@@ -263,21 +1085,10 @@ func (c *Config) GetUncommittedFiles() []ManagedFile {
 	return result
 }
 
-// GetCurrentPlatform returns current OS identifier
+// GetCurrentPlatform returns current OS identifier from the environment
 // Returns: "darwin", "linux", "windows", "wsl"
-// Detects WSL by checking /proc/version for "Microsoft"
 func GetCurrentPlatform() string {
-	if runtime.GOOS == "linux" {
-		// Check for WSL
-		data, err := os.ReadFile("/proc/version")
-		if err == nil {
-			content := string(data)
-			if contains(content, "Microsoft") || contains(content, "WSL") {
-				return "wsl"
-			}
-		}
-	}
-	return runtime.GOOS
+	return CurrentEnvironment().Platform()
 }
 
 // ShouldApplyOnPlatform checks if file should be linked on the given platform
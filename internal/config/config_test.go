@@ -194,6 +194,36 @@ func TestGetManagedFilesForPlatform(t *testing.T) {
 	}
 }
 
+func TestSharesRepoPath(t *testing.T) {
+	cfg := &Config{
+		ManagedFiles: []ManagedFile{
+			{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"},
+			{SourcePath: "~/Library/Application Support/Code/User/settings.json", RepoPath: "vscode/settings.json", Platforms: []string{"darwin"}},
+			{SourcePath: "~/.config/Code/User/settings.json", RepoPath: "vscode/settings.json", Platforms: []string{"linux"}},
+		},
+	}
+
+	if cfg.SharesRepoPath(cfg.ManagedFiles[0]) {
+		t.Error("SharesRepoPath() = true for a file of its own")
+	}
+	if !cfg.SharesRepoPath(cfg.ManagedFiles[2]) {
+		t.Error("SharesRepoPath() = false for per-platform entries")
+	}
+}
+
+func TestIsDataRepoPath(t *testing.T) {
+	for _, p := range []string{"macos-defaults", "macos-defaults/com.apple.dock.plist", "vscode/extensions.txt"} {
+		if !IsDataRepoPath(p) {
+			t.Errorf("IsDataRepoPath(%q) = false", p)
+		}
+	}
+	for _, p := range []string{"vscode/settings.json", "macos-defaults.plist", "shell/zshrc"} {
+		if IsDataRepoPath(p) {
+			t.Errorf("IsDataRepoPath(%q) = true", p)
+		}
+	}
+}
+
 func TestGetUncommittedFiles(t *testing.T) {
 	cfg := &Config{
 		Version:    CurrentConfigVersion,
@@ -256,3 +286,409 @@ func TestContains(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateCheckLevel(t *testing.T) {
+	for _, level := range []string{CheckLevelOff, CheckLevelWarn, CheckLevelFail} {
+		if err := ValidateCheckLevel(level); err != nil {
+			t.Errorf("ValidateCheckLevel(%q) error = %v", level, err)
+		}
+	}
+
+	for _, level := range []string{"", "error", "WARN"} {
+		if err := ValidateCheckLevel(level); err == nil {
+			t.Errorf("ValidateCheckLevel(%q) should return error", level)
+		}
+	}
+}
+
+func TestValidateDeletion(t *testing.T) {
+	for _, mode := range []string{"", DeletionTrash, DeletionDelete} {
+		if err := ValidateDeletion(mode); err != nil {
+			t.Errorf("ValidateDeletion(%q) error = %v", mode, err)
+		}
+	}
+
+	if err := ValidateDeletion("shred"); err == nil {
+		t.Error("ValidateDeletion(\"shred\") should return error")
+	}
+
+	cfg := &Config{}
+	if !cfg.UseTrash() {
+		t.Error("UseTrash() should default to true")
+	}
+	cfg.Deletion = DeletionDelete
+	if cfg.UseTrash() {
+		t.Error("UseTrash() should be false for deletion: delete")
+	}
+}
+
+func TestValidateLinkStyle(t *testing.T) {
+	for _, style := range []string{"", LinkStyleRelative, LinkStyleAbsolute} {
+		if err := ValidateLinkStyle(style); err != nil {
+			t.Errorf("ValidateLinkStyle(%q) error = %v", style, err)
+		}
+	}
+
+	if err := ValidateLinkStyle("hard"); err == nil {
+		t.Error("ValidateLinkStyle(\"hard\") should return error")
+	}
+}
+
+func TestValidateGitBackend(t *testing.T) {
+	for _, backend := range []string{"", GitBackendAuto, GitBackendCLI, GitBackendGoGit} {
+		if err := ValidateGitBackend(backend); err != nil {
+			t.Errorf("ValidateGitBackend(%q) error = %v", backend, err)
+		}
+	}
+
+	if err := ValidateGitBackend("libgit2"); err == nil {
+		t.Error("ValidateGitBackend(\"libgit2\") should return error")
+	}
+}
+
+func TestCheckConfigGetLevel(t *testing.T) {
+	// Empty config uses defaults
+	var cc CheckConfig
+	defaults := GetDefaultCheckLevels()
+	for check, want := range defaults {
+		if got := cc.GetLevel(check); got != want {
+			t.Errorf("GetLevel(%q) = %q, want default %q", check, got, want)
+		}
+	}
+
+	// Configured values override defaults
+	cc = CheckConfig{Secrets: CheckLevelWarn, Orphans: CheckLevelOff}
+	if got := cc.GetLevel(CheckSecrets); got != CheckLevelWarn {
+		t.Errorf("GetLevel(secrets) = %q, want %q", got, CheckLevelWarn)
+	}
+	if got := cc.GetLevel(CheckOrphans); got != CheckLevelOff {
+		t.Errorf("GetLevel(orphans) = %q, want %q", got, CheckLevelOff)
+	}
+	if got := cc.GetLevel(CheckLargeFiles); got != defaults[CheckLargeFiles] {
+		t.Errorf("GetLevel(large_files) = %q, want %q", got, defaults[CheckLargeFiles])
+	}
+}
+
+func TestSystemFilesSeparate(t *testing.T) {
+	cfg := &Config{
+		Version:  CurrentConfigVersion,
+		RepoPath: "~/.dotcor/files",
+		ManagedFiles: []ManagedFile{
+			{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"},
+		},
+		SystemFiles: []ManagedFile{
+			{SourcePath: "/etc/hosts", RepoPath: "system/etc/hosts", Scope: ScopeSystem},
+		},
+	}
+
+	if cfg.IsManaged("/etc/hosts") {
+		t.Error("IsManaged() should not include system files")
+	}
+
+	got, err := cfg.GetSystemFile("/etc/hosts")
+	if err != nil {
+		t.Fatalf("GetSystemFile() error = %v", err)
+	}
+	if got.RepoPath != "system/etc/hosts" {
+		t.Errorf("GetSystemFile().RepoPath = %v, want system/etc/hosts", got.RepoPath)
+	}
+
+	if _, err := cfg.GetSystemFile("~/.zshrc"); err == nil {
+		t.Error("GetSystemFile() should not include user dotfiles")
+	}
+
+	tracked := cfg.TrackedRepoPaths()
+	if !tracked["shell/zshrc"] || !tracked["system/etc/hosts"] {
+		t.Errorf("TrackedRepoPaths() = %v, want both sections", tracked)
+	}
+}
+
+func TestMigrateV10ToV11(t *testing.T) {
+	cfg := &Config{
+		Version: "1.0",
+		ManagedFiles: []ManagedFile{
+			{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"},
+			{SourcePath: "/etc/hosts", RepoPath: "system/etc/hosts", Scope: ScopeSystem},
+		},
+	}
+
+	if err := migrateV10ToV11(cfg); err != nil {
+		t.Fatalf("migrateV10ToV11() error = %v", err)
+	}
+
+	if len(cfg.ManagedFiles) != 1 || cfg.ManagedFiles[0].SourcePath != "~/.zshrc" {
+		t.Errorf("ManagedFiles = %v, want only ~/.zshrc", cfg.ManagedFiles)
+	}
+	if len(cfg.SystemFiles) != 1 || cfg.SystemFiles[0].SourcePath != "/etc/hosts" {
+		t.Errorf("SystemFiles = %v, want only /etc/hosts", cfg.SystemFiles)
+	}
+
+	if len(GetMigrationPath("1.0", CurrentConfigVersion)) == 0 {
+		t.Error("GetMigrationPath() should include the 1.0 -> 1.1 migration")
+	}
+}
+
+func TestMigrateV11ToV12(t *testing.T) {
+	cfg := &Config{
+		Version: "1.1",
+		SystemFiles: []ManagedFile{
+			{SourcePath: "/etc/hosts", RepoPath: "system/etc/hosts", Scope: ScopeSystem, Mode: "644"},
+		},
+	}
+
+	if err := migrateV11ToV12(cfg); err != nil {
+		t.Fatalf("migrateV11ToV12() error = %v", err)
+	}
+
+	if mf := cfg.SystemFiles[0]; mf.Perm != "644" || mf.Mode != "" {
+		t.Errorf("system file Perm = %q, Mode = %q, want perm 644 and no mode", mf.Perm, mf.Mode)
+	}
+
+	if len(GetMigrationPath("1.0", CurrentConfigVersion)) != 2 {
+		t.Error("GetMigrationPath() from 1.0 should include both migrations")
+	}
+	// Versions compare as numbers, not strings
+	if len(GetMigrationPath("1.0", "1.10")) != 2 {
+		t.Error("GetMigrationPath() to 1.10 should include both migrations")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2", "1.2", 0},
+		{"1.10", "1.9", 1},
+		{"1.2", "1.10", -1},
+		{"2", "1.9", 1},
+		{"1.2", "1.2.0", 0},
+		{"1.x", "1.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestValidateDeployMode(t *testing.T) {
+	for _, mode := range []string{"", DeployModeSymlink, DeployModeCopy, DeployModeHardlink} {
+		if err := ValidateDeployMode(mode); err != nil {
+			t.Errorf("ValidateDeployMode(%q) error = %v", mode, err)
+		}
+	}
+
+	if err := ValidateDeployMode("644"); err == nil {
+		t.Error("ValidateDeployMode(\"644\") should return error")
+	}
+}
+
+func TestLoadConfigFileValidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(mode string) {
+		t.Helper()
+		data := "version: \"" + CurrentConfigVersion + "\"\nrepo_path: /tmp/files\nmanaged_files:\n  - source_path: ~/.vimrc\n    repo_path: vim/vimrc\n    mode: " + mode + "\n"
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(DeployModeCopy)
+	if _, err := LoadConfigFile(path); err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+
+	write("hardlnk")
+	if _, err := LoadConfigFile(path); err == nil {
+		t.Error("LoadConfigFile() with an unknown deploy mode should return error")
+	}
+}
+
+func TestHooksConfigGet(t *testing.T) {
+	hooks := HooksConfig{
+		PreAdd:   []string{"echo before"},
+		PostSync: []string{"tmux source ~/.tmux.conf"},
+	}
+
+	if got := hooks.Get(HookPreAdd); len(got) != 1 || got[0] != "echo before" {
+		t.Errorf("Get(pre_add) = %v", got)
+	}
+	if got := hooks.Get(HookPostSync); len(got) != 1 {
+		t.Errorf("Get(post_sync) = %v", got)
+	}
+	if got := hooks.Get(HookPostApply); got != nil {
+		t.Errorf("Get(post_apply) = %v, want nil", got)
+	}
+	if got := hooks.Get("bogus"); got != nil {
+		t.Errorf("Get(bogus) = %v, want nil", got)
+	}
+}
+
+func TestBundles(t *testing.T) {
+	cfg := &Config{
+		Version:  CurrentConfigVersion,
+		RepoPath: "~/.dotcor/files",
+		ManagedFiles: []ManagedFile{
+			{SourcePath: "~/.config/nvim/init.lua", RepoPath: "config/nvim/init.lua"},
+			{SourcePath: "~/.config/nvim/lua/plugins.lua", RepoPath: "config/nvim/lua/plugins.lua"},
+			{SourcePath: "~/.config/nvim-old/init.vim", RepoPath: "config/nvim-old/init.vim"},
+			{SourcePath: "~/.zshrc", RepoPath: "zshrc"},
+		},
+		Bundles: []Bundle{
+			{Name: "nvim", Files: []string{"~/.config/nvim/"}, Disabled: true},
+		},
+	}
+
+	b, err := cfg.GetBundle("nvim")
+	if err != nil {
+		t.Fatalf("GetBundle() error = %v", err)
+	}
+	if got := len(cfg.GetBundleFiles(*b)); got != 2 {
+		t.Errorf("GetBundleFiles() returned %d files, want 2", got)
+	}
+	if b.Contains("~/.config/nvim-old/init.vim") {
+		t.Error("Contains() should not match a sibling with the same prefix")
+	}
+
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		if b.Contains(mf.SourcePath) {
+			t.Errorf("GetManagedFilesForPlatform() included %s from a disabled bundle", mf.SourcePath)
+		}
+	}
+	if got := len(cfg.GetManagedFilesForPlatform()); got != 2 {
+		t.Errorf("GetManagedFilesForPlatform() returned %d files, want 2", got)
+	}
+
+	if _, err := cfg.GetBundle("tmux"); err == nil {
+		t.Error("GetBundle() should fail for an unknown bundle")
+	}
+}
+
+func TestValidateBundles(t *testing.T) {
+	valid := []Bundle{{Name: "nvim", Files: []string{"~/.config/nvim"}}}
+	if err := ValidateBundles(valid); err != nil {
+		t.Errorf("ValidateBundles() error = %v", err)
+	}
+
+	invalid := [][]Bundle{
+		{{Name: "", Files: []string{"~/.zshrc"}}},
+		{{Name: "my nvim", Files: []string{"~/.zshrc"}}},
+		{{Name: "nvim"}},
+		{{Name: "nvim", Files: []string{"~/a"}}, {Name: "nvim", Files: []string{"~/b"}}},
+	}
+	for _, bundles := range invalid {
+		if err := ValidateBundles(bundles); err == nil {
+			t.Errorf("ValidateBundles(%v) should return error", bundles)
+		}
+	}
+}
+
+func TestValidatePackages(t *testing.T) {
+	valid := PackagesConfig{Brew: []string{"owner/tap/tool"}, Apt: []string{"libc6:amd64"}, Cargo: []string{"ripgrep"}}
+	if err := ValidatePackages(valid); err != nil {
+		t.Errorf("ValidatePackages() error = %v", err)
+	}
+
+	invalid := []PackagesConfig{
+		{Brew: []string{""}},
+		{Apt: []string{"git vim"}},
+		{Cargo: []string{"--git"}},
+		{Apt: []string{"git", "git"}},
+	}
+	for _, packages := range invalid {
+		if err := ValidatePackages(packages); err == nil {
+			t.Errorf("ValidatePackages(%+v) should return error", packages)
+		}
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	valid := DefaultsConfig{Domains: map[string][]string{"com.apple.dock": {"autohide"}, "NSGlobalDomain": nil}}
+	if err := ValidateDefaults(valid); err != nil {
+		t.Errorf("ValidateDefaults() error = %v", err)
+	}
+	if got := valid.RepoPath("com.apple.dock"); got != "macos-defaults/com.apple.dock.plist" {
+		t.Errorf("RepoPath() = %q", got)
+	}
+
+	invalid := []map[string][]string{
+		{"": nil},
+		{"com.apple/dock": nil},
+		{"-g": nil},
+		{"..": nil},
+		{"com.apple.dock": {""}},
+	}
+	for _, domains := range invalid {
+		if err := ValidateDefaults(DefaultsConfig{Domains: domains}); err == nil {
+			t.Errorf("ValidateDefaults(%v) should return error", domains)
+		}
+	}
+}
+
+func TestValidateLintConfig(t *testing.T) {
+	valid := LintConfig{Disable: []string{"shellcheck"}, Linters: []LintCommand{{Name: "luacheck", Command: "luacheck", Files: []string{"*.lua"}}}}
+	if err := ValidateLintConfig(valid); err != nil {
+		t.Errorf("ValidateLintConfig() error = %v", err)
+	}
+
+	invalid := []LintCommand{
+		{Command: "luacheck", Files: []string{"*.lua"}},
+		{Name: "luacheck", Command: " ", Files: []string{"*.lua"}},
+		{Name: "luacheck", Command: "luacheck"},
+		{Name: "luacheck", Command: "luacheck", Files: []string{"[*.lua"}},
+	}
+	for _, c := range invalid {
+		if err := ValidateLintConfig(LintConfig{Linters: []LintCommand{c}}); err == nil {
+			t.Errorf("ValidateLintConfig(%+v) should return error", c)
+		}
+	}
+}
+
+func TestValidateRemotes(t *testing.T) {
+	valid := []Remote{{Name: "gitea", URL: "ssh://git@gitea.home/me/dotfiles.git"}}
+	if err := ValidateRemotes(valid); err != nil {
+		t.Errorf("ValidateRemotes() error = %v", err)
+	}
+
+	invalid := [][]Remote{
+		{{Name: "", URL: "git@example.com:me/dotfiles.git"}},
+		{{Name: "my gitea", URL: "git@example.com:me/dotfiles.git"}},
+		{{Name: "origin", URL: "git@example.com:me/dotfiles.git"}},
+		{{Name: "gitea"}},
+		{{Name: "gitea", URL: "a"}, {Name: "gitea", URL: "b"}},
+	}
+	for _, remotes := range invalid {
+		if err := ValidateRemotes(remotes); err == nil {
+			t.Errorf("ValidateRemotes(%v) should return error", remotes)
+		}
+	}
+}
+
+func TestValidateAutosyncInterval(t *testing.T) {
+	for _, interval := range []string{"", "1m", "30m", "2h"} {
+		if err := ValidateAutosyncInterval(interval); err != nil {
+			t.Errorf("ValidateAutosyncInterval(%q) error = %v", interval, err)
+		}
+	}
+
+	for _, interval := range []string{"hourly", "30s", "-1h", "0"} {
+		if err := ValidateAutosyncInterval(interval); err == nil {
+			t.Errorf("ValidateAutosyncInterval(%q) should return error", interval)
+		}
+	}
+}
+
+func TestValidateLogConfig(t *testing.T) {
+	for _, lc := range []LogConfig{{}, {Format: LogFormatText}, {Format: LogFormatJSON, MaxSizeMB: 5, MaxFiles: 10}} {
+		if err := ValidateLogConfig(lc); err != nil {
+			t.Errorf("ValidateLogConfig(%+v) error = %v", lc, err)
+		}
+	}
+
+	for _, lc := range []LogConfig{{Format: "xml"}, {MaxSizeMB: -1}, {MaxFiles: -1}} {
+		if err := ValidateLogConfig(lc); err == nil {
+			t.Errorf("ValidateLogConfig(%+v) should return error", lc)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Environment is what dotcor's paths depend on: the home directory,
+// environment variables and the platform. The process environment is used
+// unless SetEnvironment installs another, such as a StaticEnvironment with
+// a temporary home in tests, or a custom root when embedding dotcor.
+type Environment interface {
+	HomeDir() (string, error)
+	Getenv(key string) string
+	Platform() string // "darwin", "linux", "windows" or "wsl"
+}
+
+// OSEnvironment is the environment of the running process
+type OSEnvironment struct{}
+
+// HomeDir returns the user's home directory
+func (OSEnvironment) HomeDir() (string, error) {
+	return os.UserHomeDir()
+}
+
+// Getenv returns the value of the environment variable key
+func (OSEnvironment) Getenv(key string) string {
+	return os.Getenv(key)
+}
+
+// Platform returns runtime.GOOS, or "wsl" under the Windows Subsystem for
+// Linux, detected from /proc/version
+func (OSEnvironment) Platform() string {
+	if runtime.GOOS == "linux" {
+		data, err := os.ReadFile("/proc/version")
+		if err == nil {
+			content := string(data)
+			if contains(content, "Microsoft") || contains(content, "WSL") {
+				return "wsl"
+			}
+		}
+	}
+	return runtime.GOOS
+}
+
+// StaticEnvironment is a fixed environment. $HOME is Home unless set in Vars.
+type StaticEnvironment struct {
+	Home string            // Home directory, under which ~/.dotcor lives
+	Vars map[string]string // Environment variables; others are empty
+	OS   string            // Platform, runtime.GOOS if empty
+}
+
+// HomeDir returns Home
+func (e StaticEnvironment) HomeDir() (string, error) {
+	if e.Home == "" {
+		return "", errors.New("no home directory set")
+	}
+	return e.Home, nil
+}
+
+// Getenv returns the value of key in Vars
+func (e StaticEnvironment) Getenv(key string) string {
+	if value, ok := e.Vars[key]; ok {
+		return value
+	}
+	if key == "HOME" {
+		return e.Home
+	}
+	return ""
+}
+
+// Platform returns OS, or runtime.GOOS if it isn't set
+func (e StaticEnvironment) Platform() string {
+	if e.OS == "" {
+		return runtime.GOOS
+	}
+	return strings.ToLower(e.OS)
+}
+
+// SetEnvironment makes path expansion, the config location and platform
+// detection use env. Call the returned function to go back to the previous
+// environment.
+func SetEnvironment(env Environment) (restore func()) {
+	resolver.mu.Lock()
+	previous := resolver.env
+	resolver.env = env
+	resolver.reset()
+	resolver.mu.Unlock()
+
+	return func() {
+		resolver.mu.Lock()
+		resolver.env = previous
+		resolver.reset()
+		resolver.mu.Unlock()
+	}
+}
+
+// CurrentEnvironment returns the environment paths are resolved in
+func CurrentEnvironment() Environment {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	return resolver.env
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSetEnvironment(t *testing.T) {
+	home := makeTempDir(t)
+	t.Setenv("HOME", makeTempDir(t))
+	t.Setenv(XDGEnv, "")
+
+	restore := SetEnvironment(StaticEnvironment{
+		Home: home,
+		Vars: map[string]string{"DOTFILES": "/srv/dotfiles", ProfileEnv: "work"},
+		OS:   "darwin",
+	})
+
+	if got, err := HomeDir(); err != nil || got != home {
+		t.Errorf("HomeDir() = %s, %v, want %s", got, err, home)
+	}
+	if got, _ := ExpandPath("~/.zshrc"); got != filepath.Join(home, ".zshrc") {
+		t.Errorf("ExpandPath(~/.zshrc) = %s, want under %s", got, home)
+	}
+	if got, _ := ExpandPath("$HOME/.vimrc"); got != filepath.Join(home, ".vimrc") {
+		t.Errorf("ExpandPath($HOME/.vimrc) = %s, want under %s", got, home)
+	}
+	if got, _ := ExpandPath("$DOTFILES/zshrc"); got != "/srv/dotfiles/zshrc" {
+		t.Errorf("ExpandPath($DOTFILES/zshrc) = %s, want /srv/dotfiles/zshrc", got)
+	}
+	if got, _ := NormalizePath(filepath.Join(home, ".gitconfig")); got != "~/.gitconfig" {
+		t.Errorf("NormalizePath() = %s, want ~/.gitconfig", got)
+	}
+	if got, _ := GetConfigDir(); got != filepath.Join(home, ".dotcor") {
+		t.Errorf("GetConfigDir() = %s, want %s", got, filepath.Join(home, ".dotcor"))
+	}
+	if got := GetCurrentPlatform(); got != "darwin" {
+		t.Errorf("GetCurrentPlatform() = %s, want darwin", got)
+	}
+
+	mf := ManagedFile{
+		SourcePath: "~/.gitconfig",
+		RepoPath:   "git/gitconfig",
+		Variants:   []Variant{{Profile: "work", RepoPath: "git/gitconfig.work"}},
+	}
+	if resolved, err := ResolveVariant(mf); err != nil || resolved.RepoPath != "git/gitconfig.work" {
+		t.Errorf("ResolveVariant() = %s, %v, want the work variant", resolved.RepoPath, err)
+	}
+
+	restore()
+	if got, _ := HomeDir(); got == home {
+		t.Error("HomeDir() still returns the static home after restore")
+	}
+}
+
+func TestStaticEnvironmentWithoutHome(t *testing.T) {
+	defer SetEnvironment(StaticEnvironment{})()
+
+	if _, err := HomeDir(); err == nil {
+		t.Error("HomeDir() without a home should return error")
+	}
+	if _, err := ExpandPath("~/.zshrc"); err == nil {
+		t.Error("ExpandPath(~) without a home should return error")
+	}
+}
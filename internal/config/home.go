@@ -0,0 +1,72 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// PathMove is one path in the config rewritten by MoveHome
+type PathMove struct {
+	Field string // Where the path is, e.g. "managed_files" or "repositories.work.repo_path"
+	Old   string
+	New   string
+}
+
+// MovedHomePath returns path moved from the home directory from to the home
+// directory to, and whether it was inside from. The moved path is written
+// with ~ when it's under the current home. Paths already written with ~
+// follow the home directory and are never moved.
+func MovedHomePath(path, from, to string) (string, bool) {
+	if path == "" || strings.HasPrefix(path, "~") || !filepath.IsAbs(path) {
+		return path, false
+	}
+	rel, err := filepath.Rel(filepath.Clean(from), filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path, false
+	}
+
+	moved := filepath.Join(to, rel)
+	if normalized, err := NormalizePath(moved); err == nil {
+		return normalized, true
+	}
+	return moved, true
+}
+
+// MoveHome rewrites the paths in c that lie in the home directory from to
+// lie in to instead: repository paths, source paths of managed files and
+// bundles, and the secrets identity, in every repository section. It
+// returns what was changed.
+func (c *Config) MoveHome(from, to string) []PathMove {
+	var moves []PathMove
+	move := func(field string, path *string) {
+		if moved, ok := MovedHomePath(*path, from, to); ok && moved != *path {
+			moves = append(moves, PathMove{Field: field, Old: *path, New: moved})
+			*path = moved
+		}
+	}
+	moveFiles := func(field string, files []ManagedFile) {
+		for i := range files {
+			move(field, &files[i].SourcePath)
+		}
+	}
+
+	move("repo_path", &c.RepoPath)
+	moveFiles("managed_files", c.ManagedFiles)
+	moveFiles("system_files", c.SystemFiles)
+	move("secrets.identity", &c.Secrets.Identity)
+	for i := range c.Bundles {
+		for j := range c.Bundles[i].Files {
+			move("bundles."+c.Bundles[i].Name, &c.Bundles[i].Files[j])
+		}
+	}
+
+	for _, name := range c.RepoNames() {
+		section := c.Repositories[name]
+		prefix := "repositories." + name + "."
+		move(prefix+"repo_path", &section.RepoPath)
+		moveFiles(prefix+"managed_files", section.ManagedFiles)
+		moveFiles(prefix+"system_files", section.SystemFiles)
+		c.Repositories[name] = section
+	}
+	return moves
+}
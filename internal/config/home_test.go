@@ -0,0 +1,70 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMovedHomePath(t *testing.T) {
+	home := setupLocationTest(t)
+	from := "/Users/old"
+
+	tests := []struct {
+		path  string
+		want  string
+		moved bool
+	}{
+		{"/Users/old/.zshrc", "~/.zshrc", true},
+		{"/Users/old", "~", true},
+		{"/Users/old/.dotcor/files", "~/.dotcor/files", true},
+		{"/Users/older/.zshrc", "/Users/older/.zshrc", false},
+		{"~/.zshrc", "~/.zshrc", false},
+		{"/etc/hosts", "/etc/hosts", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, moved := MovedHomePath(tt.path, from, home)
+		if got != tt.want || moved != tt.moved {
+			t.Errorf("MovedHomePath(%q) = %q, %v, want %q, %v", tt.path, got, moved, tt.want, tt.moved)
+		}
+	}
+
+	// A new home other than the current one stays absolute
+	if got, _ := MovedHomePath("/Users/old/.vimrc", from, "/home/new"); got != filepath.Join("/home/new", ".vimrc") {
+		t.Errorf("MovedHomePath() = %q, want /home/new/.vimrc", got)
+	}
+}
+
+func TestMoveHome(t *testing.T) {
+	home := setupLocationTest(t)
+
+	cfg := &Config{
+		RepoPath:     "/Users/old/.dotcor/files",
+		ManagedFiles: []ManagedFile{{SourcePath: "/Users/old/.zshrc"}, {SourcePath: "~/.vimrc"}},
+		Secrets:      SecretsConfig{Identity: "/Users/old/.config/age/keys.txt"},
+		Bundles:      []Bundle{{Name: "shell", Files: []string{"/Users/old/.zshrc"}}},
+		Repositories: map[string]RepoConfig{
+			"work": {RepoPath: "/Users/old/work-dotfiles", ManagedFiles: []ManagedFile{{SourcePath: "/Users/old/.ssh/config"}}},
+		},
+	}
+
+	moves := cfg.MoveHome("/Users/old", home)
+	if len(moves) != 6 {
+		t.Errorf("MoveHome() made %d moves, want 6: %v", len(moves), moves)
+	}
+	if cfg.RepoPath != "~/.dotcor/files" || cfg.ManagedFiles[0].SourcePath != "~/.zshrc" ||
+		cfg.Secrets.Identity != "~/.config/age/keys.txt" || cfg.Bundles[0].Files[0] != "~/.zshrc" {
+		t.Errorf("MoveHome() left %+v", cfg)
+	}
+	if cfg.ManagedFiles[1].SourcePath != "~/.vimrc" {
+		t.Errorf("MoveHome() changed ~/.vimrc to %q", cfg.ManagedFiles[1].SourcePath)
+	}
+	work := cfg.Repositories["work"]
+	if work.RepoPath != "~/work-dotfiles" || work.ManagedFiles[0].SourcePath != "~/.ssh/config" {
+		t.Errorf("MoveHome() left work repository %+v", work)
+	}
+
+	if moves := cfg.MoveHome("/Users/old", home); len(moves) != 0 {
+		t.Errorf("second MoveHome() made moves %v", moves)
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config keys are dotted paths of yaml names, e.g. "repo_path" or
+// "watch.debounce". Values are read and written as YAML, so lists can be
+// given in flow style: ['*.log', .cache].
+
+// GetValue returns the value at key formatted as YAML, or "" if it is unset
+func GetValue(cfg *Config, key string) (string, error) {
+	parts := strings.Split(key, ".")
+	if !knownKey(reflect.TypeOf(Config{}), parts) {
+		return "", fmt.Errorf("unknown config key %q", key)
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return "", fmt.Errorf("encoding config: %w", err)
+	}
+
+	node := &doc
+	for _, part := range parts {
+		if node = mappingValue(node, part); node == nil {
+			return "", nil
+		}
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return "", fmt.Errorf("encoding %s: %w", key, err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// SetValue returns a copy of cfg with key set to value, parsed as YAML.
+// The result is validated; cfg itself is not modified.
+func SetValue(cfg *Config, key, value string) (*Config, error) {
+	parts := strings.Split(key, ".")
+	if !knownKey(reflect.TypeOf(Config{}), parts) {
+		return nil, fmt.Errorf("unknown config key %q", key)
+	}
+	if key == "version" {
+		return nil, fmt.Errorf("version is managed by dotcor and can't be set")
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	newValue := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+	if len(parsed.Content) > 0 {
+		newValue = parsed.Content[0]
+	}
+
+	// Walk to the parent mapping, creating missing levels
+	node := &doc
+	for _, part := range parts[:len(parts)-1] {
+		next := mappingValue(node, part)
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, next)
+		}
+		node = next
+	}
+
+	last := parts[len(parts)-1]
+	if existing := mappingValue(node, last); existing != nil {
+		*existing = *newValue
+	} else {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: last}, newValue)
+	}
+
+	data, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	updated, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return updated, nil
+}
+
+// ParseConfig parses config YAML, rejecting unknown keys, and validates the
+// result. Unlike LoadConfig it doesn't migrate, so the version must be current.
+func ParseConfig(data []byte) (*Config, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var cfg Config
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	if cfg.Version != CurrentConfigVersion {
+		return nil, fmt.Errorf("config version must be %s, got %q", CurrentConfigVersion, cfg.Version)
+	}
+	if err := ValidateConfig(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// mappingValue returns the value for key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// knownKey checks if parts name a field of t by yaml tag. Any key below a
+// map field (such as variables) is allowed.
+func knownKey(t reflect.Type, parts []string) bool {
+	if len(parts) == 0 {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return len(parts) == 1
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == parts[0] {
+				return knownKey(t.Field(i).Type, parts[1:])
+			}
+		}
+	}
+	return false
+}
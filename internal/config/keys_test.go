@@ -0,0 +1,109 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func newKeysTestConfig() *Config {
+	return &Config{
+		Version:        CurrentConfigVersion,
+		RepoPath:       "~/.dotcor/files",
+		GitEnabled:     true,
+		IgnorePatterns: []string{"*.log"},
+		ManagedFiles:   []ManagedFile{},
+	}
+}
+
+func TestGetValue(t *testing.T) {
+	cfg := newKeysTestConfig()
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"repo_path", "~/.dotcor/files"},
+		{"git_enabled", "true"},
+		{"ignore_patterns", "- '*.log'"},
+		{"watch.debounce", ""},
+		{"variables.email", ""},
+	}
+	for _, tt := range tests {
+		got, err := GetValue(cfg, tt.key)
+		if err != nil {
+			t.Errorf("GetValue(%q) error = %v", tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("GetValue(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	for _, key := range []string{"bogus", "repo_path.nested", "watch.bogus"} {
+		if _, err := GetValue(cfg, key); err == nil {
+			t.Errorf("GetValue(%q) should return error", key)
+		}
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	cfg := newKeysTestConfig()
+
+	updated, err := SetValue(cfg, "git_enabled", "false")
+	if err != nil {
+		t.Fatalf("SetValue(git_enabled) error = %v", err)
+	}
+	if updated.GitEnabled || !cfg.GitEnabled {
+		t.Error("SetValue() should change the copy, not the original")
+	}
+
+	updated, err = SetValue(updated, "watch.debounce", "1m")
+	if err != nil || updated.Watch.Debounce != "1m" {
+		t.Errorf("SetValue(watch.debounce) = %q, %v", updated.Watch.Debounce, err)
+	}
+
+	updated, err = SetValue(updated, "ignore_patterns", "['*.log', .cache]")
+	if err != nil {
+		t.Fatalf("SetValue(ignore_patterns) error = %v", err)
+	}
+	if got := strings.Join(updated.IgnorePatterns, ","); got != "*.log,.cache" {
+		t.Errorf("SetValue(ignore_patterns) = %v", got)
+	}
+
+	updated, err = SetValue(updated, "variables.email", "you@example.com")
+	if err != nil || updated.Variables["email"] != "you@example.com" {
+		t.Errorf("SetValue(variables.email) = %v, %v", updated.Variables, err)
+	}
+
+	invalid := [][2]string{
+		{"bogus", "x"},
+		{"version", "9.9"},
+		{"git_enabled", "maybe"},
+		{"deletion", "shred"},
+		{"watch.debounce", "soon"},
+	}
+	for _, kv := range invalid {
+		if _, err := SetValue(cfg, kv[0], kv[1]); err == nil {
+			t.Errorf("SetValue(%q, %q) should return error", kv[0], kv[1])
+		}
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	valid := "version: \"" + CurrentConfigVersion + "\"\nrepo_path: ~/.dotcor/files\n"
+	if _, err := ParseConfig([]byte(valid)); err != nil {
+		t.Errorf("ParseConfig() error = %v", err)
+	}
+
+	invalid := []string{
+		valid + "bogus: true\n",
+		"version: \"1.0\"\nrepo_path: ~/.dotcor/files\n",
+		valid + "link_style: sideways\n",
+		"repo_path: [\n",
+	}
+	for _, data := range invalid {
+		if _, err := ParseConfig([]byte(data)); err == nil {
+			t.Errorf("ParseConfig(%q) should return error", data)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// XDGEnv selects the XDG Base Directory layout when set to a true value.
+// Once ~/.dotcor has been migrated the layout is detected without it.
+const XDGEnv = "DOTCOR_XDG"
+
+// legacyDirName is the classic dotcor home, holding both config and data
+const legacyDirName = ".dotcor"
+
+// configFileName is the name of the config file in the config directory
+const configFileName = "config.yaml"
+
+// location is where dotcor keeps its files. In the classic layout both
+// directories are ~/.dotcor; in the XDG layout config.yaml lives in
+// $XDG_CONFIG_HOME/dotcor and everything else in $XDG_DATA_HOME/dotcor.
+type location struct {
+	XDG       bool
+	ConfigDir string // Holds config.yaml
+	DataDir   string // Holds the repository, backups, logs, lock and the rest
+}
+
+// locationResolver decides between the classic and the XDG layout.
+// The layout is resolved on every call, since $HOME, the XDG variables and
+// the directories themselves change under tests and during migration.
+type locationResolver struct {
+	mu       sync.Mutex
+	override *bool // Set by SetXDG, takes precedence over $DOTCOR_XDG
+}
+
+var locations = &locationResolver{}
+
+// SetXDG forces the XDG layout on or off, as the --xdg flag does
+func SetXDG(enabled bool) {
+	locations.mu.Lock()
+	defer locations.mu.Unlock()
+	locations.override = &enabled
+}
+
+// resolve returns the layout in use: the one set by SetXDG or $DOTCOR_XDG,
+// otherwise XDG if only the XDG config exists, otherwise classic
+func (r *locationResolver) resolve() (location, error) {
+	home, err := HomeDir()
+	if err != nil {
+		return location{}, err
+	}
+
+	legacy := filepath.Join(home, legacyDirName)
+	xdg := location{
+		XDG:       true,
+		ConfigDir: filepath.Join(xdgBaseDir("XDG_CONFIG_HOME", home, ".config"), "dotcor"),
+		DataDir:   filepath.Join(xdgBaseDir("XDG_DATA_HOME", home, filepath.Join(".local", "share")), "dotcor"),
+	}
+	classic := location{ConfigDir: legacy, DataDir: legacy}
+
+	r.mu.Lock()
+	override := r.override
+	r.mu.Unlock()
+
+	if override != nil {
+		if *override {
+			return xdg, nil
+		}
+		return classic, nil
+	}
+	if enabled, err := strconv.ParseBool(CurrentEnvironment().Getenv(XDGEnv)); err == nil {
+		if enabled {
+			return xdg, nil
+		}
+		return classic, nil
+	}
+
+	if _, err := os.Stat(legacy); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(xdg.ConfigDir, configFileName)); err == nil {
+			return xdg, nil
+		}
+	}
+	return classic, nil
+}
+
+// xdgBaseDir returns the XDG base directory named by env, or its default
+// under home. The spec says relative values are invalid and ignored.
+func xdgBaseDir(env, home, fallback string) string {
+	if dir := CurrentEnvironment().Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(home, fallback)
+}
+
+// UsingXDG reports whether dotcor uses the XDG Base Directory layout
+func UsingXDG() bool {
+	loc, err := locations.resolve()
+	return err == nil && loc.XDG
+}
+
+// GetConfigDir returns the directory holding config.yaml: ~/.dotcor, or
+// $XDG_CONFIG_HOME/dotcor in the XDG layout
+func GetConfigDir() (string, error) {
+	loc, err := locations.resolve()
+	if err != nil {
+		return "", err
+	}
+	return loc.ConfigDir, nil
+}
+
+// GetDataDir returns the dotcor home holding the repository, backups and
+// all other state: ~/.dotcor, or $XDG_DATA_HOME/dotcor in the XDG layout
+func GetDataDir() (string, error) {
+	loc, err := locations.resolve()
+	if err != nil {
+		return "", err
+	}
+	return loc.DataDir, nil
+}
+
+// GetConfigPath returns the config file path
+func GetConfigPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, configFileName), nil
+}
+
+// lockFilePrefix starts the name of every lock file in the data directory
+const lockFilePrefix = ".lock-"
+
+// legacyLockFile is the lock file older versions keep in each repository's
+// state directory
+const legacyLockFile = ".lock"
+
+// lockPaths caches GetLockPath by repository state directory, so the config
+// is only loaded for it once
+var lockPaths sync.Map
+
+// GetLockPath returns the path of the lock file held by mutating commands.
+// Its name carries a hash of the selected repository's path, so commands on
+// different repositories can overlap while two names for the same one
+// can't. If the config can't be read, the default repository path is used,
+// so 'dotcor doctor --fix' can still repair it.
+func GetLockPath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	stateDir, err := GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	if lockPath, ok := lockPaths.Load(stateDir); ok {
+		return lockPath.(string), nil
+	}
+
+	repoPath := filepath.Join(stateDir, "files")
+	cfg, loadErr := LoadConfig()
+	if loadErr == nil {
+		if repoPath, err = ExpandPath(cfg.RepoPath); err != nil {
+			return "", fmt.Errorf("expanding repo path: %w", err)
+		}
+	}
+
+	// A symlinked path locks the repository it points to
+	if resolved, err := filepath.EvalSymlinks(repoPath); err == nil {
+		repoPath = resolved
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(repoPath)))
+	lockPath := filepath.Join(dataDir, lockFilePrefix+hex.EncodeToString(sum[:6]))
+
+	// The fallback isn't kept, so the path follows a repaired config
+	if loadErr == nil {
+		lockPaths.Store(stateDir, lockPath)
+	}
+	return lockPath, nil
+}
+
+// GetLegacyLockPath returns the lock file that older versions of dotcor
+// take for the selected repository, which is checked as well so an old and
+// a new dotcor don't work on it at once
+func GetLegacyLockPath() (string, error) {
+	stateDir, err := GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, legacyLockFile), nil
+}
+
+// MigrateToXDG moves ~/.dotcor into the XDG layout when that layout is in
+// use and it hasn't been migrated yet: the directory becomes the data
+// directory, config.yaml moves to the config directory, and a repo_path
+// inside ~/.dotcor is rewritten. It returns the directory moved from, or ""
+// if there was nothing to migrate. Links into the old repository location
+// must be re-applied by the caller.
+func MigrateToXDG() (string, error) {
+	loc, err := locations.resolve()
+	if err != nil || !loc.XDG {
+		return "", err
+	}
+	home, err := HomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	legacy := filepath.Join(home, legacyDirName)
+	if info, err := os.Lstat(legacy); err != nil || !info.IsDir() {
+		return "", nil
+	}
+	if _, err := os.Stat(loc.DataDir); err == nil {
+		return "", fmt.Errorf("both %s and %s exist; move or remove one of them", legacy, loc.DataDir)
+	}
+	// A running dotcor, such as 'dotcor watch', would lose its files
+	locks, _ := filepath.Glob(filepath.Join(legacy, lockFilePrefix+"*"))
+	if _, err := os.Stat(filepath.Join(legacy, legacyLockFile)); err == nil {
+		locks = append(locks, legacyLockFile)
+	}
+	if len(locks) > 0 {
+		return "", fmt.Errorf("another dotcor process holds the lock in %s; try again when it's done", legacy)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(loc.DataDir), 0755); err != nil {
+		return "", fmt.Errorf("creating data directory: %w", err)
+	}
+	if err := os.Rename(legacy, loc.DataDir); err != nil {
+		var linkErr *os.LinkError
+		if errors.As(err, &linkErr) {
+			return "", fmt.Errorf("moving %s to %s: %w (on another filesystem? move it by hand)", legacy, loc.DataDir, linkErr.Err)
+		}
+		return "", fmt.Errorf("moving %s: %w", legacy, err)
+	}
+
+	// config.yaml is the only file that belongs in the config directory
+	oldConfig := filepath.Join(loc.DataDir, configFileName)
+	if _, err := os.Stat(oldConfig); err != nil {
+		return legacy, nil
+	}
+	if err := os.MkdirAll(loc.ConfigDir, 0755); err != nil {
+		return legacy, fmt.Errorf("creating config directory: %w", err)
+	}
+	newConfig := filepath.Join(loc.ConfigDir, configFileName)
+	if err := os.Rename(oldConfig, newConfig); err != nil {
+		return legacy, fmt.Errorf("moving config.yaml: %w", err)
+	}
+
+	// Repositories inside ~/.dotcor moved along
+	cfg, err := loadRootConfig(false)
+	if err != nil {
+		return legacy, err
+	}
+	if cfg.RepoPath, err = movedPath(cfg.RepoPath, legacy, loc.DataDir); err != nil {
+		return legacy, err
+	}
+	for name, section := range cfg.Repositories {
+		if section.RepoPath, err = movedPath(section.RepoPath, legacy, loc.DataDir); err != nil {
+			return legacy, err
+		}
+		cfg.Repositories[name] = section
+	}
+
+	// Written as the root config, whichever repository is selected
+	selected := SelectedRepo()
+	SelectRepo("")
+	err = cfg.SaveConfig()
+	SelectRepo(selected)
+	return legacy, err
+}
+
+// movedPath returns path, as written in the config, moved from the
+// directory from to the directory to if it was inside it
+func movedPath(path, from, to string) (string, error) {
+	expanded, err := ExpandPath(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(from, expanded)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path, nil
+	}
+
+	moved := filepath.Join(to, rel)
+	if strings.HasPrefix(path, "~") {
+		return NormalizePath(moved)
+	}
+	return moved, nil
+}
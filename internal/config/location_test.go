@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupLocationTest points $HOME at a temp directory with no XDG settings
+func setupLocationTest(t *testing.T) string {
+	t.Helper()
+	home := makeTempDir(t)
+	t.Setenv("HOME", home)
+	t.Setenv(XDGEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	return home
+}
+
+func TestLocationClassicByDefault(t *testing.T) {
+	home := setupLocationTest(t)
+
+	configDir, _ := GetConfigDir()
+	dataDir, _ := GetDataDir()
+	want := filepath.Join(home, ".dotcor")
+	if configDir != want || dataDir != want {
+		t.Errorf("dirs = %s, %s, want both %s", configDir, dataDir, want)
+	}
+	if lockPath, _ := GetLockPath(); filepath.Dir(lockPath) != want || !strings.HasPrefix(filepath.Base(lockPath), lockFilePrefix) {
+		t.Errorf("GetLockPath() = %s, want a %s* file in %s", lockPath, lockFilePrefix, want)
+	}
+	if UsingXDG() {
+		t.Error("UsingXDG() = true without $DOTCOR_XDG")
+	}
+}
+
+func TestLocationXDG(t *testing.T) {
+	home := setupLocationTest(t)
+	t.Setenv(XDGEnv, "1")
+
+	configDir, _ := GetConfigDir()
+	dataDir, _ := GetDataDir()
+	if configDir != filepath.Join(home, ".config", "dotcor") {
+		t.Errorf("GetConfigDir() = %s, want the default XDG config home", configDir)
+	}
+	if dataDir != filepath.Join(home, ".local", "share", "dotcor") {
+		t.Errorf("GetDataDir() = %s, want the default XDG data home", dataDir)
+	}
+
+	custom := makeTempDir(t)
+	t.Setenv("XDG_CONFIG_HOME", custom)
+	t.Setenv("XDG_DATA_HOME", "relative/data") // Invalid, so ignored
+	configDir, _ = GetConfigDir()
+	dataDir, _ = GetDataDir()
+	if configDir != filepath.Join(custom, "dotcor") {
+		t.Errorf("GetConfigDir() = %s, want under $XDG_CONFIG_HOME", configDir)
+	}
+	if dataDir != filepath.Join(home, ".local", "share", "dotcor") {
+		t.Errorf("GetDataDir() = %s, want the default for a relative $XDG_DATA_HOME", dataDir)
+	}
+}
+
+func TestLocationDetectsXDGConfig(t *testing.T) {
+	home := setupLocationTest(t)
+
+	configDir := filepath.Join(home, ".config", "dotcor")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte("version: \"1.1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !UsingXDG() {
+		t.Error("UsingXDG() = false with only the XDG config present")
+	}
+
+	// ~/.dotcor wins until it has been migrated
+	if err := os.Mkdir(filepath.Join(home, ".dotcor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if UsingXDG() {
+		t.Error("UsingXDG() = true while ~/.dotcor exists")
+	}
+}
+
+func TestMigrateToXDG(t *testing.T) {
+	home := setupLocationTest(t)
+
+	legacy := filepath.Join(home, ".dotcor")
+	if err := os.MkdirAll(filepath.Join(legacy, "files", "shell"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "files", "shell", "zshrc"), []byte("export A=1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{Version: CurrentConfigVersion, RepoPath: "~/.dotcor/files"}
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing moves without the XDG layout selected
+	if from, err := MigrateToXDG(); from != "" || err != nil {
+		t.Fatalf("MigrateToXDG() = %q, %v, want no migration", from, err)
+	}
+
+	t.Setenv(XDGEnv, "true")
+	from, err := MigrateToXDG()
+	if err != nil {
+		t.Fatalf("MigrateToXDG() error = %v", err)
+	}
+	if from != legacy {
+		t.Errorf("MigrateToXDG() = %q, want %q", from, legacy)
+	}
+
+	dataDir := filepath.Join(home, ".local", "share", "dotcor")
+	if _, err := os.Stat(filepath.Join(dataDir, "files", "shell", "zshrc")); err != nil {
+		t.Errorf("repository not moved: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("~/.dotcor still exists")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "config.yaml")); !os.IsNotExist(err) {
+		t.Error("config.yaml left in the data directory")
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if loaded.RepoPath != filepath.FromSlash("~/.local/share/dotcor/files") {
+		t.Errorf("repo_path = %s, want it moved along", loaded.RepoPath)
+	}
+
+	// Once migrated, the layout is detected without $DOTCOR_XDG
+	t.Setenv(XDGEnv, "")
+	if !UsingXDG() {
+		t.Error("UsingXDG() = false after migrating")
+	}
+	if from, err := MigrateToXDG(); from != "" || err != nil {
+		t.Errorf("second MigrateToXDG() = %q, %v, want no migration", from, err)
+	}
+}
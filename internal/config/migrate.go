@@ -1,8 +1,11 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -13,8 +16,8 @@ type MigrationFunc func(*Config) error
 
 // migrations maps version transitions to their migration functions
 var migrations = map[string]MigrationFunc{
-	// Add future migrations here
-	// "1.0->1.1": migrateV10ToV11,
+	"1.0->1.1": migrateV10ToV11,
+	"1.1->1.2": migrateV11ToV12,
 }
 
 // MigrateConfig migrates config from old version to current
@@ -87,24 +90,50 @@ func GetMigrationPath(fromVersion, toVersion string) []MigrationFunc {
 	}
 
 	// Build migration path
-	// For now, we only have v1.0, so no migrations needed
-	// Future versions would be handled here
-
 	var path []MigrationFunc
 
-	// Example of how to add migrations:
-	// if fromVersion == "1.0" && toVersion >= "1.1" {
-	//     path = append(path, migrateV10ToV11)
-	//     fromVersion = "1.1"
-	// }
-	// if fromVersion == "1.1" && toVersion >= "1.2" {
-	//     path = append(path, migrateV11ToV12)
-	//     fromVersion = "1.2"
-	// }
+	if fromVersion == "1.0" && compareVersions(toVersion, "1.1") >= 0 {
+		path = append(path, migrations["1.0->1.1"])
+		fromVersion = "1.1"
+	}
+
+	if fromVersion == "1.1" && compareVersions(toVersion, "1.2") >= 0 {
+		path = append(path, migrations["1.1->1.2"])
+		fromVersion = "1.2"
+	}
 
 	return path
 }
 
+// compareVersions compares two dotted config versions numerically, so 1.10
+// comes after 1.9, returning -1, 0 or 1. Missing parts count as 0, and parts
+// that aren't numbers sort first.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = versionPart(as[i])
+		}
+		if i < len(bs) {
+			y = versionPart(bs[i])
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+// versionPart is the number of one part of a version, or -1 if it isn't one
+func versionPart(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
 // backupConfigForMigration creates a backup of the config file before migration
 func backupConfigForMigration() error {
 	configPath, err := GetConfigPath()
@@ -143,11 +172,11 @@ func MigrateFromEmpty(config *Config) error {
 	}
 
 	if config.RepoPath == "" {
-		configDir, err := GetConfigDir()
+		dataDir, err := GetDataDir()
 		if err != nil {
 			return err
 		}
-		config.RepoPath = configDir + "/files"
+		config.RepoPath = dataDir + "/files"
 	}
 
 	if len(config.IgnorePatterns) == 0 {
@@ -157,11 +186,38 @@ func MigrateFromEmpty(config *Config) error {
 	return nil
 }
 
-// Example migration function template for future use
-// func migrateV10ToV11(config *Config) error {
-//     // Add new fields, transform data, etc.
-//     return nil
-// }
+// migrateV10ToV11 moves system-scope files out of managed_files into the
+// separate system_files section
+func migrateV10ToV11(config *Config) error {
+	var userFiles []ManagedFile
+	for _, mf := range config.ManagedFiles {
+		if mf.IsSystem() {
+			config.SystemFiles = append(config.SystemFiles, mf)
+		} else {
+			userFiles = append(userFiles, mf)
+		}
+	}
+
+	if userFiles == nil {
+		userFiles = []ManagedFile{}
+	}
+	config.ManagedFiles = userFiles
+
+	return nil
+}
+
+// migrateV11ToV12 moves system file permissions from mode to perm, freeing
+// mode for the deployment mode
+func migrateV11ToV12(config *Config) error {
+	for i := range config.SystemFiles {
+		mf := &config.SystemFiles[i]
+		if mf.Perm == "" && ValidateDeployMode(mf.Mode) != nil {
+			mf.Perm = mf.Mode
+			mf.Mode = ""
+		}
+	}
+	return nil
+}
 
 // ValidateConfig checks if config is valid after loading/migration
 func ValidateConfig(config *Config) error {
@@ -177,6 +233,104 @@ func ValidateConfig(config *Config) error {
 		return fmt.Errorf("repo path is empty")
 	}
 
+	if err := ValidateDeletion(config.Deletion); err != nil {
+		return err
+	}
+
+	if err := ValidateLinkStyle(config.LinkStyle); err != nil {
+		return err
+	}
+
+	if err := ValidateGitBackend(config.GitBackend); err != nil {
+		return err
+	}
+
+	if err := ValidateSecretsBackend(config.Secrets.Backend); err != nil {
+		return err
+	}
+
+	if err := ValidateWatchDebounce(config.Watch.Debounce); err != nil {
+		return err
+	}
+
+	if err := ValidateAutosyncInterval(config.Autosync.Interval); err != nil {
+		return err
+	}
+
+	if err := ValidateLogConfig(config.Log); err != nil {
+		return err
+	}
+
+	if err := ValidateLFSConfig(config.LFS); err != nil {
+		return err
+	}
+
+	if err := ValidateBundles(config.Bundles); err != nil {
+		return err
+	}
+
+	if err := ValidateRemotes(config.GitRemotes); err != nil {
+		return err
+	}
+
+	if err := ValidateScanConfig(config.Scan); err != nil {
+		return err
+	}
+
+	if err := ValidateLintConfig(config.Lint); err != nil {
+		return err
+	}
+
+	if err := ValidateCategories(config.Categories); err != nil {
+		return err
+	}
+
+	if err := ValidatePackages(config.Packages); err != nil {
+		return err
+	}
+
+	if err := ValidateDefaults(config.Defaults); err != nil {
+		return err
+	}
+
+	if err := validateManagedFiles(config.ManagedFiles); err != nil {
+		return err
+	}
+	for name, repo := range config.Repositories {
+		if err := ValidateRepoName(name); err != nil {
+			return err
+		}
+		if err := validateManagedFiles(repo.ManagedFiles); err != nil {
+			return fmt.Errorf("repository %s: %w", name, err)
+		}
+	}
+
+	if config.FilesSubdir != "" {
+		if err := ValidateFilesSubdir(config.FilesSubdir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateManagedFiles checks the settings of each managed file
+func validateManagedFiles(files []ManagedFile) error {
+	for _, mf := range files {
+		if err := ValidateDeployMode(mf.Mode); err != nil {
+			return fmt.Errorf("%s: %w", mf.SourcePath, err)
+		}
+		if err := ValidateVariants(mf.Variants); err != nil {
+			return fmt.Errorf("%s: %w", mf.SourcePath, err)
+		}
+		if _, _, err := mf.FilePerm(); err != nil {
+			return fmt.Errorf("%s: %w", mf.SourcePath, err)
+		}
+		// Templates and secrets deploy generated output, which can't share an inode with the repo file
+		if mf.IsHardlink() && (mf.IsTemplate() || mf.Encrypted) {
+			return fmt.Errorf("%s: hardlink mode is not supported for templates or encrypted files", mf.SourcePath)
+		}
+	}
 	return nil
 }
 
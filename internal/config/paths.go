@@ -1,9 +1,11 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
@@ -43,9 +45,9 @@ func NormalizePath(path string) (string, error) {
 		return "", err
 	}
 
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
-		return "", fmt.Errorf("getting home directory: %w", err)
+		return "", err
 	}
 
 	// Clean both paths for consistent comparison
@@ -74,14 +76,34 @@ func NormalizePath(path string) (string, error) {
 // Example: ~/.zshrc -> /Users/you/.zshrc
 // Also handles environment variables: $XDG_CONFIG_HOME, %APPDATA%, etc.
 func ExpandPath(path string) (string, error) {
+	cacheable := isCacheableExpansion(path)
+	if cacheable {
+		if expanded, ok := resolver.cachedExpansion(path); ok {
+			return expanded, nil
+		}
+	}
+
+	expanded, err := expandPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	if cacheable {
+		resolver.storeExpansion(path, expanded)
+	}
+	return expanded, nil
+}
+
+// expandPath does the work of ExpandPath without caching
+func expandPath(path string) (string, error) {
 	// First expand environment variables
-	path = os.ExpandEnv(path)
+	path = os.Expand(path, CurrentEnvironment().Getenv)
 
 	// Handle ~ notation
 	if strings.HasPrefix(path, "~") {
-		home, err := os.UserHomeDir()
+		home, err := HomeDir()
 		if err != nil {
-			return "", fmt.Errorf("getting home directory: %w", err)
+			return "", err
 		}
 
 		if path == "~" {
@@ -103,100 +125,315 @@ func ExpandPath(path string) (string, error) {
 	return filepath.Clean(absPath), nil
 }
 
+// SystemRepoDir is the repo directory holding system-scope files
+const SystemRepoDir = "system"
+
+// IsSystemPath checks if path is outside the home directory
+func IsSystemPath(path string) (bool, error) {
+	expanded, err := ExpandPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	home, err := HomeDir()
+	if err != nil {
+		return false, err
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(home), filepath.Clean(expanded))
+	if err != nil {
+		return true, nil
+	}
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// GenerateSystemRepoPath returns the repo path for a system file,
+// mirroring its absolute location under the system directory
+// Example: /etc/hosts -> system/etc/hosts
+func GenerateSystemRepoPath(sourcePath string) (string, error) {
+	expanded, err := ExpandPath(sourcePath)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(expanded) {
+		return "", fmt.Errorf("system path must be absolute: %s", sourcePath)
+	}
+
+	// Drop the volume name (Windows) and leading separator
+	relPath := strings.TrimPrefix(expanded, filepath.VolumeName(expanded))
+	relPath = strings.TrimLeft(filepath.Clean(relPath), string(filepath.Separator))
+
+	return filepath.Join(SystemRepoDir, relPath), nil
+}
+
+// GetFilesRoot returns the directory that managed repo paths are relative to:
+// repo_path, or repo_path/files_subdir when a subdirectory is configured
+// Example: repo_path=~/code/personal, files_subdir=dotfiles -> /Users/you/code/personal/dotfiles
+func GetFilesRoot(config *Config) (string, error) {
+	expanded, err := ExpandPath(config.RepoPath)
+	if err != nil {
+		return "", err
+	}
+
+	if config.FilesSubdir == "" {
+		return expanded, nil
+	}
+
+	if err := ValidateFilesSubdir(config.FilesSubdir); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(expanded, config.FilesSubdir), nil
+}
+
+// ValidateFilesSubdir checks that files_subdir stays inside the repository
+func ValidateFilesSubdir(subdir string) error {
+	if filepath.IsAbs(subdir) {
+		return fmt.Errorf("files_subdir must be relative to repo_path: %s", subdir)
+	}
+
+	cleaned := filepath.Clean(subdir)
+	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("files_subdir cannot point outside repo_path: %s", subdir)
+	}
+
+	return nil
+}
+
 // GetRepoFilePath returns full path to file in repo
 // Example: shell/zshrc -> /Users/you/.dotcor/files/shell/zshrc
 func GetRepoFilePath(config *Config, repoPath string) (string, error) {
-	expanded, err := ExpandPath(config.RepoPath)
+	filesRoot, err := GetFilesRoot(config)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filesRoot, repoPath), nil
+}
+
+// GetRenderedDir returns the directory holding rendered templates (~/.dotcor/rendered),
+// kept per repository
+func GetRenderedDir() (string, error) {
+	stateDir, err := GetRepoStateDir()
 	if err != nil {
 		return "", err
 	}
+	return filepath.Join(stateDir, "rendered"), nil
+}
 
-	return filepath.Join(expanded, repoPath), nil
+// GetLogsDir returns the directory holding dotcor's log files (~/.dotcor/logs)
+func GetLogsDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "logs"), nil
+}
+
+// GetJournalDir returns the directory holding the operation journal
+// (~/.dotcor/journal), kept per repository so undo and recovery replay
+// operations against the repository they ran on
+func GetJournalDir() (string, error) {
+	stateDir, err := GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "journal"), nil
+}
+
+// GetSecretsDir returns the local-only directory holding decrypted secrets
+// (~/.dotcor/secrets), kept per repository
+func GetSecretsDir() (string, error) {
+	stateDir, err := GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "secrets"), nil
+}
+
+// GetLinkTargetPath returns the path a managed file's symlink points to:
+// the decrypted copy for secrets, the rendered output for templates,
+// the repo file otherwise
+// Example: git/gitconfig.tmpl -> /Users/you/.dotcor/rendered/git/gitconfig
+// Example: ssh/config.age -> /Users/you/.dotcor/secrets/ssh/config
+func GetLinkTargetPath(config *Config, mf ManagedFile) (string, error) {
+	if mf.Encrypted {
+		secretsDir, err := GetSecretsDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(secretsDir, strings.TrimSuffix(mf.RepoPath, filepath.Ext(mf.RepoPath))), nil
+	}
+
+	if !mf.IsTemplate() {
+		return GetRepoFilePath(config, mf.RepoPath)
+	}
+
+	renderedDir, err := GetRenderedDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(renderedDir, strings.TrimSuffix(mf.RepoPath, TemplateExt)), nil
 }
 
 // GenerateRepoPath creates repo path from source path with optional override
 // Example: ~/.config/nvim/init.vim -> nvim/init.vim
 // Example: ~/.zshrc -> shell/zshrc
 // customPath parameter allows manual override (e.g., "custom/myshell/zshrc")
+// On Windows, paths that can't be created there (e.g. "misc/nul") are rejected.
 func GenerateRepoPath(sourcePath string, customPath string) (string, error) {
+	repoPath, err := generateRepoPath(sourcePath, customPath)
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS == "windows" {
+		if err := ValidateWindowsPath(repoPath); err != nil {
+			return "", fmt.Errorf("invalid repo path %s: %w", repoPath, err)
+		}
+	}
+
+	return repoPath, nil
+}
+
+// generateRepoPath does the work of GenerateRepoPath
+func generateRepoPath(sourcePath string, customPath string) (string, error) {
 	// If custom path provided, use it
 	if customPath != "" {
 		return customPath, nil
 	}
 
-	// Expand the source path
+	relPath, err := homeRelPath(sourcePath)
+	if err != nil {
+		return "", err
+	}
+	repoPath, _ := placeRepoPath(relPath)
+	return repoPath, nil
+}
+
+// ExplainRepoPath returns the repo path GenerateRepoPath picks for
+// sourcePath and which rule picked it
+func ExplainRepoPath(sourcePath string) (repoPath string, reason string, err error) {
+	relPath, err := homeRelPath(sourcePath)
+	if err != nil {
+		return "", "", err
+	}
+	repoPath, reason = placeRepoPath(relPath)
+
+	if runtime.GOOS == "windows" {
+		if err := ValidateWindowsPath(repoPath); err != nil {
+			return "", "", fmt.Errorf("invalid repo path %s: %w", repoPath, err)
+		}
+	}
+	return repoPath, reason, nil
+}
+
+// homeRelPath returns sourcePath relative to the home directory
+func homeRelPath(sourcePath string) (string, error) {
 	expanded, err := ExpandPath(sourcePath)
 	if err != nil {
 		return "", err
 	}
 
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
-		return "", fmt.Errorf("getting home directory: %w", err)
+		return "", err
 	}
 
 	// Strip home directory prefix
 	relPath := strings.TrimPrefix(expanded, home)
-	relPath = strings.TrimPrefix(relPath, string(filepath.Separator))
+	return strings.TrimPrefix(relPath, string(filepath.Separator)), nil
+}
 
+// placeRepoPath returns the repo path for a file at relPath under the home
+// directory, and a description of the rule that placed it
+func placeRepoPath(relPath string) (string, string) {
 	// Get the base filename
 	filename := filepath.Base(relPath)
 
+	// User rules from the categories section come first
+	if rule, rest, ok := matchCategoryRule(relPath); ok {
+		return filepath.Join(rule.Category, rest), fmt.Sprintf("categories: %s", rule.Pattern)
+	}
+
+	// Files next to ssh and gpg keys keep their directory, so ~/.ssh/config
+	// doesn't collide with other files named config
+	for _, dir := range []string{".ssh", ".gnupg"} {
+		if strings.HasPrefix(relPath, dir+string(filepath.Separator)) {
+			return strings.TrimPrefix(relPath, "."), fmt.Sprintf("built-in: ~/%s layout", dir)
+		}
+	}
+
 	// Check category map for exact match
 	if category, ok := categoryMap[filename]; ok {
 		// Strip leading dot from filename for repo
-		repoFilename := strings.TrimPrefix(filename, ".")
-		return filepath.Join(category, repoFilename), nil
+		repoFilename := stripLeadingDot(filename)
+		return filepath.Join(category, repoFilename), fmt.Sprintf("built-in: %s", filename)
 	}
 
 	// Check prefix matching for patterns
-	category := getCategoryByPrefix(filename)
+	category, prefix := categoryByPrefix(filename)
 
 	// Handle .config/ directory specially
 	if strings.HasPrefix(relPath, ".config"+string(filepath.Separator)) {
 		// Strip .config/ prefix
 		configPath := strings.TrimPrefix(relPath, ".config"+string(filepath.Separator))
-		return configPath, nil
+		return configPath, "built-in: ~/.config layout"
 	}
 
 	// Handle .local/share/ directory
 	if strings.HasPrefix(relPath, ".local"+string(filepath.Separator)) {
 		// Preserve structure but strip leading dot
-		return strings.TrimPrefix(relPath, "."), nil
+		return strings.TrimPrefix(relPath, "."), "built-in: ~/.local layout"
 	}
 
 	// If we found a category by prefix, use it
 	if category != "misc" {
-		repoFilename := strings.TrimPrefix(filename, ".")
-		return filepath.Join(category, repoFilename), nil
+		repoFilename := stripLeadingDot(filename)
+		return filepath.Join(category, repoFilename), fmt.Sprintf("built-in: %s*", prefix)
 	}
 
 	// Default: use misc category with original filename (minus dot)
-	repoFilename := strings.TrimPrefix(filename, ".")
-	return filepath.Join("misc", repoFilename), nil
+	repoFilename := stripLeadingDot(filename)
+	return filepath.Join("misc", repoFilename), "default"
+}
+
+// stripLeadingDot drops the leading dot from a dotfile name for the repo,
+// unless that would leave a Windows reserved name (e.g. ".aux" -> "aux")
+func stripLeadingDot(filename string) string {
+	stripped := strings.TrimPrefix(filename, ".")
+	if IsReservedName(stripped) {
+		return filename
+	}
+	return stripped
+}
+
+// categoryPrefixes maps dotfile name prefixes to categories, checked in order
+var categoryPrefixes = []struct{ Prefix, Category string }{
+	{".zsh", "shell"},
+	{".bash", "shell"},
+	{".vim", "vim"},
+	{".nvim", "nvim"},
+	{".git", "git"},
+	{".tmux", "tmux"},
 }
 
 // getCategoryByPrefix returns category based on filename prefix
 func getCategoryByPrefix(filename string) string {
-	if strings.HasPrefix(filename, ".zsh") {
-		return "shell"
-	}
-	if strings.HasPrefix(filename, ".bash") {
-		return "shell"
-	}
-	if strings.HasPrefix(filename, ".vim") {
-		return "vim"
-	}
-	if strings.HasPrefix(filename, ".nvim") {
-		return "nvim"
-	}
-	if strings.HasPrefix(filename, ".git") {
-		return "git"
-	}
-	if strings.HasPrefix(filename, ".tmux") {
-		return "tmux"
+	category, _ := categoryByPrefix(filename)
+	return category
+}
+
+// categoryByPrefix returns the category for filename and the prefix that
+// matched, or "misc" and ""
+func categoryByPrefix(filename string) (string, string) {
+	for _, p := range categoryPrefixes {
+		if strings.HasPrefix(filename, p.Prefix) {
+			return p.Category, p.Prefix
+		}
 	}
-	return "misc"
+	return "misc", ""
 }
 
 // ComputeRelativeSymlink computes relative path from symlink to target
@@ -260,8 +497,9 @@ func ExpandGlob(pattern string) ([]string, error) {
 	return files, nil
 }
 
-// GetFilesRecursive returns all files in a directory recursively
-func GetFilesRecursive(dir string) ([]string, error) {
+// GetFilesRecursive returns all files in a directory recursively, stopping
+// early if ctx is canceled
+func GetFilesRecursive(ctx context.Context, dir string) ([]string, error) {
 	expanded, err := ExpandPath(dir)
 	if err != nil {
 		return nil, err
@@ -272,6 +510,9 @@ func GetFilesRecursive(dir string) ([]string, error) {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if !info.IsDir() {
 			files = append(files, path)
 		}
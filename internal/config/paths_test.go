@@ -158,6 +158,12 @@ func TestGenerateRepoPath(t *testing.T) {
 			customPath: "",
 			want:       "nvim/init.lua",
 		},
+		{
+			name:       "ssh dir kept",
+			sourcePath: "~/.ssh/config",
+			customPath: "",
+			want:       "ssh/config",
+		},
 		{
 			name:       "custom path override",
 			sourcePath: "~/.zshrc",
@@ -170,6 +176,12 @@ func TestGenerateRepoPath(t *testing.T) {
 			customPath: "",
 			want:       "misc/obscurefile",
 		},
+		{
+			name:       "reserved name keeps its dot",
+			sourcePath: "~/.aux",
+			customPath: "",
+			want:       "misc/.aux",
+		},
 	}
 
 	for _, tt := range tests {
@@ -256,3 +268,85 @@ func TestGetCategoryByPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFilesRoot(t *testing.T) {
+	repo := filepath.Join(os.TempDir(), "repo")
+
+	tests := []struct {
+		name    string
+		subdir  string
+		want    string
+		wantErr bool
+	}{
+		{"no subdir", "", repo, false},
+		{"subdir", "dotfiles", filepath.Join(repo, "dotfiles"), false},
+		{"nested subdir", "home/dotfiles", filepath.Join(repo, "home", "dotfiles"), false},
+		{"parent escape", "../elsewhere", "", true},
+		{"absolute", filepath.Join(os.TempDir(), "abs"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{RepoPath: repo, FilesSubdir: tt.subdir}
+			got, err := GetFilesRoot(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetFilesRoot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("GetFilesRoot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSystemRepoPath(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"/etc/hosts", filepath.Join("system", "etc", "hosts"), false},
+		{"/etc/nixos/configuration.nix", filepath.Join("system", "etc", "nixos", "configuration.nix"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := GenerateSystemRepoPath(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateSystemRepoPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("GenerateSystemRepoPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSystemPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("cannot get home directory")
+	}
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"~/.zshrc", false},
+		{filepath.Join(home, ".config", "nvim", "init.lua"), false},
+		{"/etc/hosts", true},
+		{home + "-other/.zshrc", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := IsSystemPath(tt.input)
+			if err != nil {
+				t.Fatalf("IsSystemPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsSystemPath(%s) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrReservedName is returned for paths Windows can't create, like
+// reserved device names (CON, NUL, COM1) or names ending in a dot
+var ErrReservedName = errors.New("name is not allowed on Windows")
+
+// reservedNames are Windows device names, reserved with any extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true,
+	"LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// IsReservedName reports whether a single path component is a Windows
+// reserved device name, e.g. "nul" or "com1.txt"
+func IsReservedName(name string) bool {
+	base := name
+	if idx := strings.Index(base, "."); idx != -1 {
+		base = base[:idx]
+	}
+	base = strings.TrimRight(base, " ")
+	return reservedNames[strings.ToUpper(base)]
+}
+
+// ValidateWindowsPath checks that every component of path can be created on
+// Windows: no reserved device names, no characters Windows forbids, and no
+// trailing dots or spaces. Long-path prefixes and drive letters are skipped.
+func ValidateWindowsPath(path string) error {
+	path = strings.TrimPrefix(path, `\\?\`)
+	if len(path) >= 2 && path[1] == ':' && unicode.IsLetter(rune(path[0])) {
+		path = path[2:]
+	}
+
+	for _, name := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if name == "." || name == ".." {
+			continue
+		}
+
+		if IsReservedName(name) {
+			return fmt.Errorf("%w: %q is a reserved device name", ErrReservedName, name)
+		}
+
+		if idx := strings.IndexFunc(name, isInvalidWindowsRune); idx != -1 {
+			return fmt.Errorf("%w: %q contains %q", ErrReservedName, name, name[idx])
+		}
+
+		if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+			return fmt.Errorf("%w: %q ends with a dot or space", ErrReservedName, name)
+		}
+	}
+
+	return nil
+}
+
+// isInvalidWindowsRune reports characters Windows doesn't allow in file names
+func isInvalidWindowsRune(r rune) bool {
+	return r < 32 || strings.ContainsRune(`<>:"|?*`, r)
+}
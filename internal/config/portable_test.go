@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsReservedName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"CON", true},
+		{"nul", true},
+		{"com1", true},
+		{"Lpt9.txt", true},
+		{"aux .log", true},
+		{"console", false},
+		{"com10", false},
+		{".nul", false},
+		{"zshrc", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsReservedName(tt.name); got != tt.want {
+				t.Errorf("IsReservedName(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWindowsPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"normal repo path", "shell/zshrc", false},
+		{"drive letter", `C:\Users\me\.zshrc`, false},
+		{"long path prefix", `\\?\C:\Users\me\.config\nvim\init.lua`, false},
+		{"reserved directory", "misc/con/file", true},
+		{"reserved with extension", `misc\nul.txt`, true},
+		{"invalid character", "misc/what?", true},
+		{"colon in name", "misc/a:b", true},
+		{"trailing dot", "misc/file.", true},
+		{"trailing space", "misc/file ", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWindowsPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWindowsPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrReservedName) {
+				t.Errorf("ValidateWindowsPath(%q) error should wrap ErrReservedName, got %v", tt.path, err)
+			}
+		})
+	}
+}
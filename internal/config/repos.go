@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// RepoConfig is the section of a named repository in config.yaml. The
+// default repository is configured by the same keys at the top level; all
+// other settings are shared between repositories.
+type RepoConfig struct {
+	RepoPath     string        `yaml:"repo_path"`
+	FilesSubdir  string        `yaml:"files_subdir,omitempty"`
+	GitRemote    string        `yaml:"git_remote,omitempty"`
+	GitRemotes   []Remote      `yaml:"git_remotes,omitempty"`
+	ManagedFiles []ManagedFile `yaml:"managed_files"`
+	SystemFiles  []ManagedFile `yaml:"system_files,omitempty"`
+}
+
+// repoNamePattern limits names to ones safe as a directory name
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+var (
+	repoMu       sync.Mutex
+	selectedRepo string
+)
+
+// ValidateRepoName returns an error if name can't name a repository
+func ValidateRepoName(name string) error {
+	if !repoNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid repository name %q (use letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+// SelectRepo makes LoadConfig and SaveConfig work on the named repository's
+// section, as the --repo flag does. An empty name selects the default one.
+func SelectRepo(name string) error {
+	if name != "" {
+		if err := ValidateRepoName(name); err != nil {
+			return err
+		}
+	}
+	repoMu.Lock()
+	defer repoMu.Unlock()
+	selectedRepo = name
+	return nil
+}
+
+// SelectedRepo returns the name of the selected repository, "" for the default
+func SelectedRepo() string {
+	repoMu.Lock()
+	defer repoMu.Unlock()
+	return selectedRepo
+}
+
+// GetRepoStateDir returns the directory holding the selected repository's
+// own state, such as its lock and checksums: the data directory for the
+// default repository, repos/<name> in it for a named one
+func GetRepoStateDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	if name := SelectedRepo(); name != "" {
+		return filepath.Join(dataDir, "repos", name), nil
+	}
+	return dataDir, nil
+}
+
+// RepoNames returns the names of the configured repositories, sorted
+func (c *Config) RepoNames() []string {
+	names := make([]string, 0, len(c.Repositories))
+	for name := range c.Repositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// repoSection returns c's repository settings
+func (c *Config) repoSection() RepoConfig {
+	return RepoConfig{
+		RepoPath:     c.RepoPath,
+		FilesSubdir:  c.FilesSubdir,
+		GitRemote:    c.GitRemote,
+		GitRemotes:   c.GitRemotes,
+		ManagedFiles: c.ManagedFiles,
+		SystemFiles:  c.SystemFiles,
+	}
+}
+
+// applyRepoSection replaces c's repository settings with those of r
+func (c *Config) applyRepoSection(r RepoConfig) {
+	c.RepoPath = r.RepoPath
+	c.FilesSubdir = r.FilesSubdir
+	c.GitRemote = r.GitRemote
+	c.GitRemotes = r.GitRemotes
+	c.ManagedFiles = r.ManagedFiles
+	c.SystemFiles = r.SystemFiles
+	if c.ManagedFiles == nil {
+		c.ManagedFiles = []ManagedFile{}
+	}
+}
+
+// withSelectedRepo returns root with the selected repository's settings in
+// place of the default one's. A repository not configured yet gets a new
+// one in its state directory.
+func withSelectedRepo(root *Config) (*Config, error) {
+	name := SelectedRepo()
+	if name == "" {
+		return root, nil
+	}
+
+	section, ok := root.Repositories[name]
+	if !ok {
+		stateDir, err := GetRepoStateDir()
+		if err != nil {
+			return nil, err
+		}
+		section = RepoConfig{RepoPath: filepath.Join(stateDir, "files")}
+	}
+
+	cfg := *root
+	cfg.applyRepoSection(section)
+	return &cfg, nil
+}
+
+// intoRoot returns the config to write when c belongs to the named
+// repository: root as stored, with c's settings and c's repository
+// settings in the named section
+func (c *Config) intoRoot(name string) (*Config, error) {
+	root, err := loadRootConfig(false)
+	if err != nil {
+		return nil, err
+	}
+
+	out := *c
+	out.applyRepoSection(root.repoSection())
+	out.Repositories = make(map[string]RepoConfig, len(root.Repositories)+1)
+	for n, section := range root.Repositories {
+		out.Repositories[n] = section
+	}
+	out.Repositories[name] = c.repoSection()
+	return &out, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateRepoName(t *testing.T) {
+	for _, name := range []string{"work", "personal-2", "a_b"} {
+		if err := ValidateRepoName(name); err != nil {
+			t.Errorf("ValidateRepoName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-work", "../work", "my repo", "a/b"} {
+		if err := ValidateRepoName(name); err == nil {
+			t.Errorf("ValidateRepoName(%q) = nil, want error", name)
+		}
+	}
+}
+
+func TestNamedRepoSections(t *testing.T) {
+	home := setupLocationTest(t)
+	t.Cleanup(func() { SelectRepo("") })
+
+	root, err := NewDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.ManagedFiles = []ManagedFile{{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"}}
+	if err := root.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SelectRepo("work"); err != nil {
+		t.Fatal(err)
+	}
+	work, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	stateDir := filepath.Join(home, ".dotcor", "repos", "work")
+	if work.RepoPath != filepath.Join(stateDir, "files") || len(work.ManagedFiles) != 0 {
+		t.Errorf("new repository = %s with %d files, want an empty one in %s", work.RepoPath, len(work.ManagedFiles), stateDir)
+	}
+	SelectRepo("")
+	defaultLock, _ := GetLockPath()
+	SelectRepo("work")
+	if lockPath, _ := GetLockPath(); lockPath == defaultLock {
+		t.Errorf("GetLockPath() = %s, the default repository's lock", lockPath)
+	}
+
+	// Repository settings go to the section, shared settings to the top
+	work.RepoPath = "~/code/work-dotfiles"
+	work.ManagedFiles = []ManagedFile{{SourcePath: "~/.vimrc", RepoPath: "vim/vimrc"}}
+	work.LinkStyle = LinkStyleAbsolute
+	if err := work.SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	SelectRepo("")
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.RepoPath != root.RepoPath || len(loaded.ManagedFiles) != 1 || loaded.ManagedFiles[0].SourcePath != "~/.zshrc" {
+		t.Errorf("default repository changed: %s, %+v", loaded.RepoPath, loaded.ManagedFiles)
+	}
+	if loaded.LinkStyle != LinkStyleAbsolute {
+		t.Errorf("link_style = %q, want the shared setting saved", loaded.LinkStyle)
+	}
+	section, ok := loaded.Repositories["work"]
+	if !ok || section.RepoPath != "~/code/work-dotfiles" || len(section.ManagedFiles) != 1 {
+		t.Errorf("work section = %+v, %v", section, ok)
+	}
+	if names := loaded.RepoNames(); len(names) != 1 || names[0] != "work" {
+		t.Errorf("RepoNames() = %v, want [work]", names)
+	}
+
+	SelectRepo("work")
+	if work, _ := LoadConfig(); work.RepoPath != "~/code/work-dotfiles" || work.ManagedFiles[0].SourcePath != "~/.vimrc" {
+		t.Errorf("LoadConfig() for work = %s, %+v", work.RepoPath, work.ManagedFiles)
+	}
+}
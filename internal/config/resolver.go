@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxCachedPaths bounds the expansion cache; it is cleared when full
+const maxCachedPaths = 4096
+
+// pathResolver memoizes the home directory and path expansions, which
+// status and rebuild otherwise repeat for every managed file.
+// The cache is keyed on the environment the home directory comes from,
+// so changing $HOME (as tests do) invalidates it.
+type pathResolver struct {
+	mu       sync.Mutex
+	env      Environment       // Where the home directory and variables come from
+	override string            // Home directory set by OverrideHomeDir
+	envKey   string            // Environment the cached home was resolved from
+	home     string            // Cached home directory, "" if not resolved
+	expanded map[string]string // Cached ExpandPath results
+}
+
+var resolver = &pathResolver{env: OSEnvironment{}, expanded: make(map[string]string)}
+
+// homeEnvKey returns the environment values os.UserHomeDir depends on
+func homeEnvKey(env Environment) string {
+	return env.Getenv("HOME") + "\x00" + env.Getenv("USERPROFILE")
+}
+
+// HomeDir returns the user's home directory, resolving it at most once
+// per distinct environment
+func HomeDir() (string, error) {
+	return resolver.homeDir()
+}
+
+// OverrideHomeDir makes HomeDir and path expansion use dir instead of the
+// real home directory. Intended for tests; call the returned function to undo.
+func OverrideHomeDir(dir string) (restore func()) {
+	resolver.mu.Lock()
+	previous := resolver.override
+	resolver.override = dir
+	resolver.reset()
+	resolver.mu.Unlock()
+
+	return func() {
+		resolver.mu.Lock()
+		resolver.override = previous
+		resolver.reset()
+		resolver.mu.Unlock()
+	}
+}
+
+// reset clears cached values. Caller must hold mu.
+func (r *pathResolver) reset() {
+	r.envKey = ""
+	r.home = ""
+	r.expanded = make(map[string]string)
+}
+
+// validate drops the cache if the home environment changed. Caller must hold mu.
+func (r *pathResolver) validate() {
+	if r.override != "" {
+		return
+	}
+	if key := homeEnvKey(r.env); key != r.envKey {
+		r.reset()
+		r.envKey = key
+	}
+}
+
+func (r *pathResolver) homeDir() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.override != "" {
+		return r.override, nil
+	}
+
+	r.validate()
+	if r.home != "" {
+		return r.home, nil
+	}
+
+	home, err := r.env.HomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	r.home = home
+	return home, nil
+}
+
+// cachedExpansion returns a previous ExpandPath result for path
+func (r *pathResolver) cachedExpansion(path string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.validate()
+	expanded, ok := r.expanded[path]
+	return expanded, ok
+}
+
+// storeExpansion remembers an ExpandPath result
+func (r *pathResolver) storeExpansion(path, expanded string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.validate()
+	if len(r.expanded) >= maxCachedPaths {
+		r.expanded = make(map[string]string)
+	}
+	r.expanded[path] = expanded
+}
+
+// isCacheableExpansion reports whether expanding path depends only on the
+// home directory. Paths with environment variables or relative to the
+// working directory are expanded every time.
+func isCacheableExpansion(path string) bool {
+	if strings.Contains(path, "$") {
+		return false
+	}
+	return strings.HasPrefix(path, "~") || filepath.IsAbs(path)
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHomeDirFollowsEnvironment(t *testing.T) {
+	first := makeTempDir(t)
+	second := makeTempDir(t)
+
+	t.Setenv("HOME", first)
+	got, err := HomeDir()
+	if err != nil {
+		t.Fatalf("HomeDir() error = %v", err)
+	}
+	if got != first {
+		t.Errorf("HomeDir() = %s, want %s", got, first)
+	}
+	if expanded, _ := ExpandPath("~/.zshrc"); expanded != filepath.Join(first, ".zshrc") {
+		t.Errorf("ExpandPath() = %s, want under %s", expanded, first)
+	}
+
+	// Changing $HOME invalidates cached results
+	t.Setenv("HOME", second)
+	got, err = HomeDir()
+	if err != nil {
+		t.Fatalf("HomeDir() error = %v", err)
+	}
+	if got != second {
+		t.Errorf("HomeDir() after HOME change = %s, want %s", got, second)
+	}
+	if expanded, _ := ExpandPath("~/.zshrc"); expanded != filepath.Join(second, ".zshrc") {
+		t.Errorf("ExpandPath() after HOME change = %s, want under %s", expanded, second)
+	}
+}
+
+func TestOverrideHomeDir(t *testing.T) {
+	t.Setenv("HOME", makeTempDir(t))
+	override := makeTempDir(t)
+
+	restore := OverrideHomeDir(override)
+	got, err := HomeDir()
+	if err != nil {
+		t.Fatalf("HomeDir() error = %v", err)
+	}
+	if got != override {
+		t.Errorf("HomeDir() = %s, want override %s", got, override)
+	}
+	if normalized, _ := NormalizePath(filepath.Join(override, ".vimrc")); normalized != "~/.vimrc" {
+		t.Errorf("NormalizePath() = %s, want ~/.vimrc", normalized)
+	}
+
+	restore()
+	if got, _ := HomeDir(); got == override {
+		t.Error("HomeDir() still returns override after restore")
+	}
+}
+
+func TestExpandPathEnvNotCached(t *testing.T) {
+	first := makeTempDir(t)
+	second := makeTempDir(t)
+
+	t.Setenv("DOTCOR_TEST_DIR", first)
+	if expanded, _ := ExpandPath("$DOTCOR_TEST_DIR/file"); expanded != filepath.Join(first, "file") {
+		t.Errorf("ExpandPath() = %s, want under %s", expanded, first)
+	}
+
+	t.Setenv("DOTCOR_TEST_DIR", second)
+	if expanded, _ := ExpandPath("$DOTCOR_TEST_DIR/file"); expanded != filepath.Join(second, "file") {
+		t.Errorf("ExpandPath() after env change = %s, want under %s", expanded, second)
+	}
+}
+
+func TestIsCacheableExpansion(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"~/.zshrc", true},
+		{"/etc/hosts", true},
+		{"$HOME/.zshrc", false},
+		{"relative/file", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := isCacheableExpansion(tt.path); got != tt.want {
+				t.Errorf("isCacheableExpansion(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// makeTempDir creates a temp directory removed when the test ends
+func makeTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
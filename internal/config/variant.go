@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// ProfileEnv names the environment variable holding this machine's profile
+const ProfileEnv = "DOTCOR_PROFILE"
+
+// Variant is an alternative repo file for a managed file, deployed instead
+// of the default RepoPath on machines it matches
+type Variant struct {
+	RepoPath string   `yaml:"repo_path"`         // shell/zshrc.work (relative to files/)
+	Hosts    []string `yaml:"hosts,omitempty"`   // Hostname patterns, e.g. "work-*"
+	Profile  string   `yaml:"profile,omitempty"` // Matches when $DOTCOR_PROFILE is this value
+}
+
+// Matches checks if the variant applies to hostname and profile. Host
+// patterns match the full or short (before the first dot) hostname, ignoring case.
+func (v Variant) Matches(hostname, profile string) bool {
+	if v.Profile != "" && v.Profile != profile {
+		return false
+	}
+	if len(v.Hosts) == 0 {
+		return v.Profile != ""
+	}
+
+	hostname = strings.ToLower(hostname)
+	short, _, _ := strings.Cut(hostname, ".")
+	for _, pattern := range v.Hosts {
+		pattern = strings.ToLower(pattern)
+		if ok, _ := path.Match(pattern, hostname); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, short); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateVariants returns an error if a variant has no repo path, nothing
+// to match on, or a malformed host pattern
+func ValidateVariants(variants []Variant) error {
+	for _, v := range variants {
+		if v.RepoPath == "" {
+			return fmt.Errorf("variant repo path is empty")
+		}
+		if len(v.Hosts) == 0 && v.Profile == "" {
+			return fmt.Errorf("variant %s needs hosts or a profile", v.RepoPath)
+		}
+		for _, pattern := range v.Hosts {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("variant %s: invalid host pattern %q", v.RepoPath, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// SelectVariant returns mf with RepoPath set to the variant matching
+// hostname and profile. Without a match the default RepoPath is kept; it is
+// an error if there is no default or more than one variant matches.
+func SelectVariant(mf ManagedFile, hostname, profile string) (ManagedFile, error) {
+	if len(mf.Variants) == 0 {
+		return mf, nil
+	}
+
+	var matched []string
+	for _, v := range mf.Variants {
+		if v.Matches(hostname, profile) {
+			matched = append(matched, v.RepoPath)
+		}
+	}
+
+	switch {
+	case len(matched) == 1:
+		mf.RepoPath = matched[0]
+	case len(matched) > 1:
+		return mf, fmt.Errorf("host %s matches several variants (%s)", hostname, strings.Join(matched, ", "))
+	case mf.RepoPath == "":
+		if profile == "" {
+			profile = "unset"
+		}
+		return mf, fmt.Errorf("no variant for host %s (profile %s) and no default repo_path", hostname, profile)
+	}
+	return mf, nil
+}
+
+// ResolveVariant selects the variant of mf for this machine's hostname and $DOTCOR_PROFILE
+func ResolveVariant(mf ManagedFile) (ManagedFile, error) {
+	if len(mf.Variants) == 0 {
+		return mf, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return mf, fmt.Errorf("getting hostname: %w", err)
+	}
+	return SelectVariant(mf, hostname, CurrentEnvironment().Getenv(ProfileEnv))
+}
@@ -0,0 +1,82 @@
+package config
+
+import "testing"
+
+func TestVariantMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		variant  Variant
+		hostname string
+		profile  string
+		want     bool
+	}{
+		{"host pattern", Variant{Hosts: []string{"work-*"}}, "work-laptop", "", true},
+		{"short hostname", Variant{Hosts: []string{"work-laptop"}}, "work-laptop.corp.example.com", "", true},
+		{"case insensitive", Variant{Hosts: []string{"Work-*"}}, "WORK-01", "", true},
+		{"other host", Variant{Hosts: []string{"work-*"}}, "home-desktop", "", false},
+		{"profile", Variant{Profile: "work"}, "anything", "work", true},
+		{"wrong profile", Variant{Profile: "work"}, "anything", "home", false},
+		{"host and profile", Variant{Hosts: []string{"work-*"}, Profile: "work"}, "work-01", "home", false},
+		{"matches nothing", Variant{}, "anything", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.variant.Matches(tt.hostname, tt.profile); got != tt.want {
+				t.Errorf("Matches(%q, %q) = %v, want %v", tt.hostname, tt.profile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectVariant(t *testing.T) {
+	mf := ManagedFile{
+		SourcePath: "~/.zshrc",
+		RepoPath:   "shell/zshrc",
+		Variants: []Variant{
+			{RepoPath: "shell/zshrc.work", Hosts: []string{"work-*"}},
+			{RepoPath: "shell/zshrc.home", Profile: "home"},
+		},
+	}
+
+	got, err := SelectVariant(mf, "work-laptop", "")
+	if err != nil || got.RepoPath != "shell/zshrc.work" {
+		t.Errorf("SelectVariant(work-laptop) = %s, %v", got.RepoPath, err)
+	}
+
+	got, err = SelectVariant(mf, "desktop", "home")
+	if err != nil || got.RepoPath != "shell/zshrc.home" {
+		t.Errorf("SelectVariant(profile home) = %s, %v", got.RepoPath, err)
+	}
+
+	got, err = SelectVariant(mf, "desktop", "")
+	if err != nil || got.RepoPath != "shell/zshrc" {
+		t.Errorf("SelectVariant(no match) = %s, %v, want default", got.RepoPath, err)
+	}
+
+	if _, err := SelectVariant(mf, "work-laptop", "home"); err == nil {
+		t.Error("SelectVariant() should fail when several variants match")
+	}
+
+	mf.RepoPath = ""
+	if _, err := SelectVariant(mf, "desktop", ""); err == nil {
+		t.Error("SelectVariant() should fail with no match and no default")
+	}
+}
+
+func TestValidateVariants(t *testing.T) {
+	if err := ValidateVariants([]Variant{{RepoPath: "shell/zshrc.work", Hosts: []string{"work-*"}}}); err != nil {
+		t.Errorf("ValidateVariants() error = %v", err)
+	}
+
+	invalid := [][]Variant{
+		{{Hosts: []string{"work-*"}}},
+		{{RepoPath: "shell/zshrc.work"}},
+		{{RepoPath: "shell/zshrc.work", Hosts: []string{"work-["}}},
+	}
+	for _, variants := range invalid {
+		if err := ValidateVariants(variants); err == nil {
+			t.Errorf("ValidateVariants(%v) should return error", variants)
+		}
+	}
+}
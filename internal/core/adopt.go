@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// DefaultAdoptDepth is how many levels below home FindAdoptableSymlinks
+// looks by default: home itself and directories like ~/.config
+const DefaultAdoptDepth = 2
+
+// ErrNotSymlink is returned by ResolveAdoptable for paths that aren't symlinks
+var ErrNotSymlink = errors.New("not a symlink")
+
+// AdoptableLink is a symlink that leads into the repository
+type AdoptableLink struct {
+	Path     string // Source path, "~/" notation under home
+	RepoPath string // Where the link leads, relative to the files root
+	Dir      bool   // The link leads to a directory in the repository
+	Indirect bool   // The link reaches the repository through other symlinks
+}
+
+// ResolveAdoptable follows the symlink at path, through any intermediate
+// links, and reports where it leads in the repository. ok is false if it
+// leads outside the repository; a broken link is an error.
+func ResolveAdoptable(cfg *config.Config, path string) (link AdoptableLink, ok bool, err error) {
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return link, false, fmt.Errorf("invalid path: %w", err)
+	}
+	info, err := os.Lstat(expanded)
+	if err != nil {
+		return link, false, err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return link, false, ErrNotSymlink
+	}
+	link.Path, err = config.NormalizePath(expanded)
+	if err != nil {
+		link.Path = path
+	}
+
+	resolved, err := filepath.EvalSymlinks(expanded)
+	if err != nil {
+		return link, false, fmt.Errorf("symlink target does not exist: %w", err)
+	}
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return link, false, fmt.Errorf("expanding repo path: %w", err)
+	}
+	realRoot, err := filepath.EvalSymlinks(filesRoot)
+	if err != nil {
+		return link, false, fmt.Errorf("resolving repo path: %w", err)
+	}
+
+	rel, err := filepath.Rel(realRoot, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return link, false, nil
+	}
+	link.RepoPath = rel
+	if target, err := os.Stat(resolved); err == nil {
+		link.Dir = target.IsDir()
+	}
+
+	// A link straight at the repo file is what dotcor itself creates; any
+	// other route is repointed when adopted
+	target, err := os.Readlink(expanded)
+	if err != nil {
+		return link, false, fmt.Errorf("reading symlink: %w", err)
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(expanded), target)
+	}
+	link.Indirect = filepath.Clean(target) != filepath.Join(filesRoot, rel)
+	return link, true, nil
+}
+
+// FindAdoptableSymlinks walks the home directory for unmanaged symlinks that
+// lead into the repository, including links to directories and links that
+// get there through other links. Entries directly in home are all checked;
+// below that only hidden directories are entered, down to maxDepth
+// levels. Caches and the repository itself are skipped. Results are sorted
+// by path.
+func FindAdoptableSymlinks(ctx context.Context, cfg *config.Config, maxDepth int) ([]AdoptableLink, error) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultAdoptDepth
+	}
+	home, err := config.HomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("getting home directory: %w", err)
+	}
+
+	skipPaths := map[string]bool{}
+	if dir, err := config.GetConfigDir(); err == nil {
+		skipPaths[filepath.Clean(dir)] = true
+	}
+	if root, err := config.GetFilesRoot(cfg); err == nil {
+		skipPaths[filepath.Clean(root)] = true
+	}
+
+	var found []AdoptableLink
+	err = filepath.WalkDir(home, func(p string, d fs.DirEntry, err error) error {
+		if p == home {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(home, p)
+		if err != nil {
+			return nil
+		}
+		depth := strings.Count(rel, string(filepath.Separator)) + 1
+
+		if d.IsDir() {
+			// Below home, only hidden directories like ~/.config hold dotfiles
+			if depth >= maxDepth || discoverSkipDirs[d.Name()] || skipPaths[p] ||
+				(depth == 1 && !strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		link, ok, err := ResolveAdoptable(cfg, p)
+		if err != nil || !ok || cfg.IsManaged(link.Path) {
+			return nil
+		}
+		found = append(found, link)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", home, err)
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return found, nil
+}
+
+// RepoFilesUnder returns the files in the repository directory repoDir,
+// relative to the files root, for adopting a link to the directory. Git
+// metadata is skipped.
+func RepoFilesUnder(cfg *config.Config, repoDir string) ([]string, error) {
+	dir, err := config.GetRepoFilePath(cfg, repoDir)
+	if err != nil {
+		return nil, err
+	}
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("expanding repo path: %w", err)
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(filesRoot, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", repoDir, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestFindAdoptableSymlinks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	repoDir := filepath.Join(home, ".dotcor", "files")
+	cfg := &config.Config{
+		Version:      config.CurrentConfigVersion,
+		RepoPath:     repoDir,
+		ManagedFiles: []config.ManagedFile{{SourcePath: "~/.bashrc", RepoPath: "shell/bashrc"}},
+	}
+	for _, name := range []string{"shell/zshrc", "shell/bashrc", "nvim/init.lua", "nvim/lua/plugins.lua", "tmux/tmux.conf"} {
+		path := filepath.Join(repoDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	link := func(target, name string) {
+		t.Helper()
+		path := filepath.Join(home, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link(filepath.Join(repoDir, "shell", "bashrc"), ".bashrc")                  // Already managed
+	link(filepath.Join(home, "dotfiles", "shell", "zshrc"), ".zshrc")           // Through ~/dotfiles
+	link(repoDir, "dotfiles")                                                   // The repo itself
+	link(filepath.Join(repoDir, "nvim"), ".config/nvim")                        // A directory
+	link(filepath.Join(repoDir, "tmux", "tmux.conf"), ".config/tmux/deep/conf") // Three levels down
+	link(filepath.Join(home, "elsewhere"), ".vimrc")                            // Outside the repo
+
+	found, err := FindAdoptableSymlinks(context.Background(), cfg, 0)
+	if err != nil {
+		t.Fatalf("FindAdoptableSymlinks() error = %v", err)
+	}
+	want := []AdoptableLink{
+		{Path: "~/.config/nvim", RepoPath: "nvim", Dir: true},
+		{Path: "~/.zshrc", RepoPath: filepath.Join("shell", "zshrc"), Indirect: true},
+	}
+	if len(found) != len(want) {
+		t.Fatalf("FindAdoptableSymlinks() = %+v, want %+v", found, want)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("found[%d] = %+v, want %+v", i, found[i], want[i])
+		}
+	}
+
+	found, err = FindAdoptableSymlinks(context.Background(), cfg, 4)
+	if err != nil {
+		t.Fatalf("FindAdoptableSymlinks(depth 4) error = %v", err)
+	}
+	if len(found) != 3 || found[0].Path != "~/.config/nvim" || found[1].Path != "~/.config/tmux/deep/conf" {
+		t.Errorf("FindAdoptableSymlinks(depth 4) = %+v, want the deep link too", found)
+	}
+
+	files, err := RepoFilesUnder(cfg, "nvim")
+	if err != nil {
+		t.Fatalf("RepoFilesUnder() error = %v", err)
+	}
+	if len(files) != 2 || files[0] != filepath.Join("nvim", "init.lua") || files[1] != filepath.Join("nvim", "lua", "plugins.lua") {
+		t.Errorf("RepoFilesUnder() = %v", files)
+	}
+}
+
+func TestResolveAdoptableNotSymlink(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg := &config.Config{Version: config.CurrentConfigVersion, RepoPath: filepath.Join(home, "repo")}
+
+	path := filepath.Join(home, ".profile")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ResolveAdoptable(cfg, path); err != ErrNotSymlink {
+		t.Errorf("ResolveAdoptable(regular file) error = %v, want ErrNotSymlink", err)
+	}
+}
@@ -0,0 +1,328 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// DefaultAutosyncInterval is how often the scheduled sync runs unless
+// autosync.interval is set
+const DefaultAutosyncInterval = time.Hour
+
+// ErrAutosyncUnsupported is returned on platforms without systemd or launchd
+var ErrAutosyncUnsupported = errors.New("autosync needs systemd (Linux) or launchd (macOS)")
+
+const (
+	autosyncUnit  = "dotcor-autosync"     // systemd service and timer name
+	autosyncLabel = "com.dotcor.autosync" // launchd agent label
+)
+
+// AutosyncJob describes the scheduled 'dotcor sync'
+type AutosyncJob struct {
+	Binary   string        // Absolute path of the dotcor binary
+	Repo     string        // Named repository to sync, "" for the default one
+	Interval time.Duration // Time between runs
+	LogPath  string        // File each run's output is appended to
+	Path     string        // PATH for the job, so git and hook commands are found
+}
+
+// Args returns the command the job runs. --force skips the confirmation
+// prompt, which would otherwise wait for input that never comes.
+func (j AutosyncJob) Args() []string {
+	args := []string{j.Binary}
+	if j.Repo != "" {
+		args = append(args, "--repo", j.Repo)
+	}
+	return append(args, "sync", "--force")
+}
+
+// autosyncNames returns the systemd unit name and launchd label of the
+// scheduled sync of repo, so each repository gets its own schedule
+func autosyncNames(repo string) (unit, label string) {
+	if repo == "" {
+		return autosyncUnit, autosyncLabel
+	}
+	return autosyncUnit + "-" + repo, autosyncLabel + "." + repo
+}
+
+// AutosyncInterval returns the configured autosync interval, or the default
+func AutosyncInterval(cfg *config.Config) time.Duration {
+	if d, err := time.ParseDuration(cfg.Autosync.Interval); err == nil && d >= time.Minute {
+		return d
+	}
+	return DefaultAutosyncInterval
+}
+
+// SystemdUnits returns the user service and timer units that run job
+func SystemdUnits(job AutosyncJob) (service, timer string) {
+	description := "dotcor auto-sync"
+	if job.Repo != "" {
+		description += " of " + job.Repo
+	}
+	var args []string
+	for _, arg := range job.Args() {
+		args = append(args, systemdQuote(arg))
+	}
+	logPath := systemdEscape(job.LogPath)
+
+	service = "[Unit]\n" +
+		"Description=" + description + "\n" +
+		"\n" +
+		"[Service]\n" +
+		"Type=oneshot\n" +
+		"ExecStart=" + strings.Join(args, " ") + "\n" +
+		"Environment=" + systemdQuote("PATH="+job.Path) + "\n" +
+		"StandardOutput=append:" + logPath + "\n" +
+		"StandardError=append:" + logPath + "\n"
+
+	seconds := int(job.Interval.Seconds())
+	timer = "[Unit]\n" +
+		"Description=Run " + description + " every " + job.Interval.String() + "\n" +
+		"\n" +
+		"[Timer]\n" +
+		"OnBootSec=2min\n" +
+		"OnUnitActiveSec=" + strconv.Itoa(seconds) + "s\n" +
+		"\n" +
+		"[Install]\n" +
+		"WantedBy=timers.target\n"
+	return service, timer
+}
+
+// LaunchdPlist returns a launchd user agent that runs job
+func LaunchdPlist(job AutosyncJob) string {
+	_, label := autosyncNames(job.Repo)
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + label + `</string>
+	<key>ProgramArguments</key>
+	<array>
+`)
+	for _, arg := range job.Args() {
+		b.WriteString("\t\t<string>" + xmlEscape(arg) + "</string>\n")
+	}
+	b.WriteString(`	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>PATH</key>
+		<string>` + xmlEscape(job.Path) + `</string>
+	</dict>
+	<key>StartInterval</key>
+	<integer>` + strconv.Itoa(int(job.Interval.Seconds())) + `</integer>
+	<key>StandardOutPath</key>
+	<string>` + xmlEscape(job.LogPath) + `</string>
+	<key>StandardErrorPath</key>
+	<string>` + xmlEscape(job.LogPath) + `</string>
+</dict>
+</plist>
+`)
+	return b.String()
+}
+
+// AutosyncStatus describes the installed scheduled sync
+type AutosyncStatus struct {
+	Installed bool
+	Active    bool          // The timer or agent is loaded
+	Interval  time.Duration // As written in the installed unit
+	Files     []string
+}
+
+// unitFile is an autosync file and the kind of content it holds: "service"
+// and "timer" for systemd, "plist" for launchd
+type unitFile struct {
+	kind string
+	path string
+}
+
+// autosyncFiles returns the unit files of repo's schedule for this platform
+func autosyncFiles(repo string) ([]unitFile, error) {
+	unit, label := autosyncNames(repo)
+	home, err := config.HomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		configHome := config.CurrentEnvironment().Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		dir := filepath.Join(configHome, "systemd", "user")
+		return []unitFile{
+			{"service", filepath.Join(dir, unit+".service")},
+			{"timer", filepath.Join(dir, unit+".timer")},
+		}, nil
+	case "darwin":
+		return []unitFile{
+			{"plist", filepath.Join(home, "Library", "LaunchAgents", label+".plist")},
+		}, nil
+	}
+	return nil, ErrAutosyncUnsupported
+}
+
+// InstallAutosync writes the units for job and starts the schedule,
+// replacing an earlier install. It returns the files written.
+func InstallAutosync(job AutosyncJob) ([]string, error) {
+	unit, _ := autosyncNames(job.Repo)
+	files, err := autosyncFiles(job.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(job.LogPath), 0755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+
+	contents := map[string]string{}
+	if runtime.GOOS == "darwin" {
+		contents["plist"] = LaunchdPlist(job)
+		// Unload an earlier version first so the new one takes effect
+		_ = exec.Command("launchctl", "bootout", launchdTarget(job.Repo)).Run()
+	} else {
+		contents["service"], contents["timer"] = SystemdUnits(job)
+	}
+
+	var written []string
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.path), 0755); err != nil {
+			return written, fmt.Errorf("creating %s: %w", filepath.Dir(file.path), err)
+		}
+		if err := os.WriteFile(file.path, []byte(contents[file.kind]), 0644); err != nil {
+			return written, fmt.Errorf("writing %s: %w", file.path, err)
+		}
+		written = append(written, file.path)
+	}
+
+	if runtime.GOOS == "darwin" {
+		return written, runServiceCommand("launchctl", "bootstrap", launchdDomain(), files[0].path)
+	}
+	if err := runServiceCommand("systemctl", "--user", "daemon-reload"); err != nil {
+		return written, err
+	}
+	return written, runServiceCommand("systemctl", "--user", "enable", "--now", unit+".timer")
+}
+
+// RemoveAutosync stops repo's schedule and deletes its units, returning
+// the files removed. Nothing installed is not an error.
+func RemoveAutosync(repo string) ([]string, error) {
+	unit, _ := autosyncNames(repo)
+	files, err := autosyncFiles(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if runtime.GOOS == "darwin" {
+		_ = exec.Command("launchctl", "bootout", launchdTarget(repo)).Run()
+	} else {
+		_ = exec.Command("systemctl", "--user", "disable", "--now", unit+".timer").Run()
+	}
+
+	var removed []string
+	for _, file := range files {
+		if err := os.Remove(file.path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return removed, fmt.Errorf("removing %s: %w", file.path, err)
+		}
+		removed = append(removed, file.path)
+	}
+
+	if runtime.GOOS != "darwin" && len(removed) > 0 {
+		_ = exec.Command("systemctl", "--user", "daemon-reload").Run()
+	}
+	return removed, nil
+}
+
+// intervalPattern finds the interval in a systemd timer or launchd plist
+var intervalPattern = regexp.MustCompile(`(?:OnUnitActiveSec=|<key>StartInterval</key>\s*<integer>)(\d+)`)
+
+// GetAutosyncStatus reports whether repo's scheduled sync is installed and
+// loaded, and how often it runs
+func GetAutosyncStatus(repo string) (AutosyncStatus, error) {
+	var status AutosyncStatus
+	unit, _ := autosyncNames(repo)
+	files, err := autosyncFiles(repo)
+	if err != nil {
+		return status, err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			continue
+		}
+		status.Files = append(status.Files, file.path)
+		if m := intervalPattern.FindSubmatch(data); m != nil {
+			seconds, _ := strconv.Atoi(string(m[1]))
+			status.Interval = time.Duration(seconds) * time.Second
+		}
+	}
+	status.Installed = len(status.Files) == len(files)
+
+	if runtime.GOOS == "darwin" {
+		status.Active = exec.Command("launchctl", "print", launchdTarget(repo)).Run() == nil
+	} else {
+		status.Active = exec.Command("systemctl", "--user", "is-active", "--quiet", unit+".timer").Run() == nil
+	}
+	return status, nil
+}
+
+// launchdDomain is the launchd domain of the current user's GUI session
+func launchdDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+// launchdTarget names repo's autosync agent within launchdDomain
+func launchdTarget(repo string) string {
+	_, label := autosyncNames(repo)
+	return launchdDomain() + "/" + label
+}
+
+// runServiceCommand runs systemctl or launchctl, including its output in
+// the error
+func runServiceCommand(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %s: %w", name, strings.Join(args, " "), strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// systemdEscape escapes the specifiers and variables systemd would
+// otherwise expand in a unit file value
+func systemdEscape(s string) string {
+	return strings.NewReplacer("%", "%%", "$", "$$").Replace(s)
+}
+
+// systemdQuote escapes s and double-quotes it if it contains characters
+// systemd would split on or unquote
+func systemdQuote(s string) string {
+	s = systemdEscape(s)
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// xmlEscape escapes s for use as XML character data
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
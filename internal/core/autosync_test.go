@@ -0,0 +1,97 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func testAutosyncJob() AutosyncJob {
+	return AutosyncJob{
+		Binary:   "/opt/my tools/dotcor",
+		Interval: 30 * time.Minute,
+		LogPath:  "/home/me/.dotcor/logs/autosync.log",
+		Path:     "/usr/local/bin:/usr/bin",
+	}
+}
+
+func TestSystemdUnits(t *testing.T) {
+	service, timer := SystemdUnits(testAutosyncJob())
+
+	for _, want := range []string{
+		`ExecStart="/opt/my tools/dotcor" sync --force`,
+		`Environment=PATH=/usr/local/bin:/usr/bin`,
+		"StandardOutput=append:/home/me/.dotcor/logs/autosync.log",
+	} {
+		if !strings.Contains(service, want) {
+			t.Errorf("service missing %q:\n%s", want, service)
+		}
+	}
+	if !strings.Contains(timer, "OnUnitActiveSec=1800s") {
+		t.Errorf("timer missing interval:\n%s", timer)
+	}
+	if m := intervalPattern.FindStringSubmatch(timer); m == nil || m[1] != "1800" {
+		t.Errorf("intervalPattern in timer = %v, want 1800", m)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	job := testAutosyncJob()
+	job.LogPath = "/Users/me & you/.dotcor/logs/autosync.log"
+	plist := LaunchdPlist(job)
+
+	for _, want := range []string{
+		"<string>com.dotcor.autosync</string>",
+		"<string>/opt/my tools/dotcor</string>\n\t\t<string>sync</string>\n\t\t<string>--force</string>",
+		"<integer>1800</integer>",
+		"<string>/Users/me &amp; you/.dotcor/logs/autosync.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+	if m := intervalPattern.FindStringSubmatch(plist); m == nil || m[1] != "1800" {
+		t.Errorf("intervalPattern in plist = %v, want 1800", m)
+	}
+
+	// A named repository gets its own agent, syncing that repository
+	job.Repo = "work"
+	plist = LaunchdPlist(job)
+	for _, want := range []string{
+		"<string>com.dotcor.autosync.work</string>",
+		"<string>--repo</string>\n\t\t<string>work</string>\n\t\t<string>sync</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/usr/bin/dotcor", "/usr/bin/dotcor"},
+		{"/opt/my tools/dotcor", `"/opt/my tools/dotcor"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"100%", "100%%"},
+		{"$HOME", "$$HOME"},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := systemdQuote(tt.in); got != tt.want {
+			t.Errorf("systemdQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAutosyncInterval(t *testing.T) {
+	cfg := &config.Config{}
+	if got := AutosyncInterval(cfg); got != DefaultAutosyncInterval {
+		t.Errorf("AutosyncInterval(unset) = %v, want %v", got, DefaultAutosyncInterval)
+	}
+	cfg.Autosync.Interval = "15m"
+	if got := AutosyncInterval(cfg); got != 15*time.Minute {
+		t.Errorf("AutosyncInterval(15m) = %v", got)
+	}
+}
@@ -5,18 +5,23 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
 )
 
 // BackupInfo represents information about a backup
 type BackupInfo struct {
-	Timestamp  time.Time
-	SourcePath string // Original file path (normalized)
-	BackupPath string // Full path to backup file
-	Size       int64
+	ID         string    `json:"id"` // Path relative to the backup directory (slash-separated)
+	Timestamp  time.Time `json:"timestamp"`
+	SourcePath string    `json:"source_path"`         // Original file path (normalized)
+	RepoPath   string    `json:"repo_path,omitempty"` // Repo path of the managed file it belongs to
+	BackupPath string    `json:"-"`                   // Full path to backup file
+	Size       int64     `json:"size"`
+	Checksum   string    `json:"checksum,omitempty"` // SHA-256 of the backup content
 }
 
 // TimestampFormat is the format used for backup directory names
@@ -25,16 +30,28 @@ const TimestampFormat = "2006-01-02_15-04-05"
 
 // GetBackupDir returns the backup directory path (~/.dotcor/backups)
 func GetBackupDir() (string, error) {
-	configDir, err := config.GetConfigDir()
+	dataDir, err := config.GetDataDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, "backups"), nil
+	return filepath.Join(dataDir, "backups"), nil
 }
 
 // CreateBackup creates a timestamped backup of a file before destructive operations
 // Returns backup path and error
 func CreateBackup(sourcePath string) (string, error) {
+	return createBackup(sourcePath, nil)
+}
+
+// CreateManagedBackup backs up path, a managed file's dotfile or repo file,
+// recording mf's source and repo paths in the backup manifest so the backup
+// is found by 'dotcor restore --from-backup' for that file
+func CreateManagedBackup(path string, mf config.ManagedFile) (string, error) {
+	return createBackup(path, &mf)
+}
+
+// createBackup backs up sourcePath, recording it under mf when given
+func createBackup(sourcePath string, mf *config.ManagedFile) (string, error) {
 	// Expand source path
 	expanded, err := config.ExpandPath(sourcePath)
 	if err != nil {
@@ -53,7 +70,8 @@ func CreateBackup(sourcePath string) (string, error) {
 	}
 
 	// Create timestamped subdirectory
-	timestamp := time.Now().Format(TimestampFormat)
+	now := time.Now()
+	timestamp := now.Format(TimestampFormat)
 	timestampDir := filepath.Join(backupDir, timestamp)
 
 	if err := fs.EnsureDir(timestampDir); err != nil {
@@ -65,8 +83,9 @@ func CreateBackup(sourcePath string) (string, error) {
 	backupPath := filepath.Join(timestampDir, filename)
 
 	// Handle name collisions by appending counter
+	archived := archivedNames(timestampDir)
 	counter := 1
-	for fs.FileExists(backupPath) {
+	for fs.FileExists(backupPath) || archived[filepath.Base(backupPath)] {
 		ext := filepath.Ext(filename)
 		name := filename[:len(filename)-len(ext)]
 		backupPath = filepath.Join(timestampDir, fmt.Sprintf("%s_%d%s", name, counter, ext))
@@ -74,14 +93,123 @@ func CreateBackup(sourcePath string) (string, error) {
 	}
 
 	// Copy file to backup location
-	if err := fs.CopyWithPermissions(expanded, backupPath); err != nil {
+	if err := fs.CopyPreservingMetadata(expanded, backupPath); err != nil {
 		return "", fmt.Errorf("copying to backup: %w", err)
 	}
 
+	entry, err := manifestEntry(backupPath, expanded, mf)
+	if err != nil {
+		return "", err
+	}
+
+	// The manifest lives with the backups, so it survives losing the index
+	if err := addBackupManifest(timestampDir, entry); err != nil {
+		log.Warn("writing backup manifest failed", "dir", timestampDir, "error", err)
+	}
+
+	// Record the backup in the index. If that fails, drop the index so the
+	// next listing rebuilds it from disk instead of missing this backup.
+	if err := indexBackup(backupDir, backupPath, entry, now); err != nil {
+		if indexPath, pathErr := getBackupIndexPath(); pathErr == nil {
+			os.Remove(indexPath)
+		}
+	}
+
+	log.Info("backed up file", "path", expanded, "backup", backupPath)
+
+	// Earlier sets are finished; a failure leaves them loose for next time
+	if BackupCompression() {
+		if err := compressFinishedBackups(backupDir, timestamp); err != nil {
+			log.Warn("compressing backups failed", "error", err)
+		}
+	}
 	return backupPath, nil
 }
 
-// RestoreBackup restores a file from backup to target path
+// manifestEntry describes the backup at backupPath of the file at
+// sourcePath, which belongs to mf if given
+func manifestEntry(backupPath, sourcePath string, mf *config.ManagedFile) (BackupManifestEntry, error) {
+	checksum, err := fs.FileChecksum(backupPath)
+	if err != nil {
+		return BackupManifestEntry{}, err
+	}
+
+	entry := BackupManifestEntry{File: filepath.Base(backupPath), Checksum: checksum}
+	if mf != nil {
+		entry.SourcePath, entry.RepoPath = mf.SourcePath, mf.RepoPath
+	} else if normalized, err := config.NormalizePath(sourcePath); err == nil {
+		entry.SourcePath = normalized
+	} else {
+		entry.SourcePath = sourcePath
+	}
+	if info, err := os.Stat(sourcePath); err == nil {
+		entry.Perm = fmt.Sprintf("%o", info.Mode().Perm())
+	}
+	return entry, nil
+}
+
+// indexBackup appends a newly created backup to the index
+func indexBackup(backupDir, backupPath string, entry BackupManifestEntry, timestamp time.Time) error {
+	relPath, err := filepath.Rel(backupDir, backupPath)
+	if err != nil {
+		return err
+	}
+
+	size, err := fs.GetFileSize(backupPath)
+	if err != nil {
+		return err
+	}
+
+	return appendBackupIndex(BackupInfo{
+		ID:         filepath.ToSlash(relPath),
+		Timestamp:  timestamp,
+		SourcePath: entry.SourcePath,
+		RepoPath:   entry.RepoPath,
+		Size:       size,
+		Checksum:   entry.Checksum,
+	})
+}
+
+// BackupLinkedFiles backs up the current content of managed files whose repo
+// path is in repoPaths and that are linked into place, e.g. before a pull
+// overwrites them. Backups are stored under the source filename so they can
+// be found with 'dotcor restore --from-backup'.
+// Returns the backup paths created.
+func BackupLinkedFiles(cfg *config.Config, repoPaths []string) ([]string, error) {
+	changed := make(map[string]bool)
+	for _, p := range repoPaths {
+		changed[filepath.ToSlash(p)] = true
+	}
+
+	files := append(cfg.GetManagedFilesForPlatform(), cfg.GetSystemFilesForPlatform()...)
+
+	var backups []string
+	for _, mf := range files {
+		if !changed[filepath.ToSlash(mf.RepoPath)] {
+			continue
+		}
+
+		// Only files currently linked or copied into place are visible to the user
+		sourcePath, err := config.ExpandPath(mf.SourcePath)
+		if err != nil {
+			continue
+		}
+		if isLink, _ := fs.IsSymlink(sourcePath); (!isLink && !mf.IsCopy() && !mf.IsHardlink()) || !fs.FileExists(sourcePath) {
+			continue
+		}
+
+		backupPath, err := CreateManagedBackup(sourcePath, mf)
+		if err != nil {
+			return backups, fmt.Errorf("backing up %s: %w", mf.SourcePath, err)
+		}
+		backups = append(backups, backupPath)
+	}
+
+	return backups, nil
+}
+
+// RestoreBackup restores a file from backup to target path. Backups in a
+// compressed backup set are extracted transparently.
 func RestoreBackup(backupPath string, targetPath string) error {
 	// Expand paths
 	expandedBackup, err := config.ExpandPath(backupPath)
@@ -94,9 +222,14 @@ func RestoreBackup(backupPath string, targetPath string) error {
 		return fmt.Errorf("expanding target path: %w", err)
 	}
 
-	// Check if backup exists
+	// Extract the backup if its set has been compressed
+	source := expandedBackup
 	if !fs.FileExists(expandedBackup) {
-		return fmt.Errorf("backup file does not exist: %s", backupPath)
+		source, err = extractArchivedBackup(expandedBackup)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(source)
 	}
 
 	// Ensure target directory exists
@@ -105,14 +238,16 @@ func RestoreBackup(backupPath string, targetPath string) error {
 	}
 
 	// Copy backup to target
-	if err := fs.CopyWithPermissions(expandedBackup, expandedTarget); err != nil {
+	if err := fs.CopyPreservingMetadata(source, expandedTarget); err != nil {
 		return fmt.Errorf("restoring from backup: %w", err)
 	}
 
+	log.Info("restored backup", "path", expandedTarget, "backup", expandedBackup)
 	return nil
 }
 
-// ListBackups returns list of all backups with timestamps
+// ListBackups returns list of all backups with timestamps, newest first.
+// Backups are read from the index, which is rebuilt from disk if missing.
 func ListBackups() ([]BackupInfo, error) {
 	backupDir, err := GetBackupDir()
 	if err != nil {
@@ -124,76 +259,15 @@ func ListBackups() ([]BackupInfo, error) {
 		return []BackupInfo{}, nil
 	}
 
-	var backups []BackupInfo
-
-	// Walk through backup directory
-	err = filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip directories
-		if info.IsDir() {
-			return nil
-		}
-
-		// Get the timestamp directory name
-		relPath, err := filepath.Rel(backupDir, path)
-		if err != nil {
-			return nil // Skip files we can't process
-		}
-
-		// Get the parent directory (timestamp directory)
-		timestampStr := filepath.Dir(relPath)
-		if timestampStr == "." {
-			return nil // Skip files directly in backup dir
-		}
-
-		// For nested paths, get the first directory component (the timestamp)
-		// e.g., "2024-01-15_10-30-00/subdir/file.txt" -> "2024-01-15_10-30-00"
-		firstDir := timestampStr
-		if idx := findPathSeparator(timestampStr); idx != -1 {
-			firstDir = timestampStr[:idx]
-		}
-
-		// Parse timestamp from the first directory component
-		timestamp, err := time.Parse(TimestampFormat, firstDir)
-		if err != nil {
-			return nil // Skip if we can't parse timestamp
-		}
-
-		backups = append(backups, BackupInfo{
-			Timestamp:  timestamp,
-			SourcePath: info.Name(), // Just filename, original path unknown
-			BackupPath: path,
-			Size:       info.Size(),
-		})
-
-		return nil
-	})
-
+	backups, err := readBackupIndex(backupDir)
 	if err != nil {
-		return nil, fmt.Errorf("walking backup directory: %w", err)
+		return RebuildBackupIndex()
 	}
 
-	// Sort by timestamp (newest first)
-	sort.Slice(backups, func(i, j int) bool {
-		return backups[i].Timestamp.After(backups[j].Timestamp)
-	})
-
+	sortBackups(backups)
 	return backups, nil
 }
 
-// findPathSeparator finds the first path separator in a string
-func findPathSeparator(s string) int {
-	for i, c := range s {
-		if c == '/' || c == filepath.Separator {
-			return i
-		}
-	}
-	return -1
-}
-
 // CleanupCandidate represents a backup directory that can be cleaned up
 type CleanupCandidate struct {
 	Path      string
@@ -227,6 +301,7 @@ func CleanOldBackups(olderThan time.Duration, keepLast int) (deleted int, failed
 
 	var firstErr error
 	var actualFreed int64
+	var removed []string
 
 	// Delete old directories
 	for _, candidate := range candidates {
@@ -239,6 +314,12 @@ func CleanOldBackups(olderThan time.Duration, keepLast int) (deleted int, failed
 		}
 		deleted++
 		actualFreed += candidate.Size
+		removed = append(removed, candidate.Path)
+	}
+
+	// Keep the index in sync with what's left on disk
+	if err := pruneBackupIndex(removed); err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("updating backup index: %w", err)
 	}
 
 	return deleted, failed, actualFreed, firstErr
@@ -269,19 +350,24 @@ func getCleanupCandidates(olderThan time.Duration, keepLast int) ([]CleanupCandi
 		path      string
 	}
 
+	// Compressed sets are candidates like directories
 	var dirs []timestampDir
 	for _, entry := range entries {
+		name := entry.Name()
 		if !entry.IsDir() {
-			continue
+			if !strings.HasSuffix(name, BackupArchiveExt) {
+				continue
+			}
+			name = strings.TrimSuffix(name, BackupArchiveExt)
 		}
 
-		timestamp, err := time.Parse(TimestampFormat, entry.Name())
+		timestamp, err := time.ParseInLocation(TimestampFormat, name, time.Local)
 		if err != nil {
 			continue // Skip directories that don't match timestamp format
 		}
 
 		dirs = append(dirs, timestampDir{
-			name:      entry.Name(),
+			name:      name,
 			timestamp: timestamp,
 			path:      filepath.Join(backupDir, entry.Name()),
 		})
@@ -353,6 +439,32 @@ func GetBackupsForFile(filename string) ([]BackupInfo, error) {
 	return fileBackups, nil
 }
 
+// GetBackupsForSource returns backups of the file at sourcePath, newest
+// first. Backups from before original paths were recorded only know their
+// file name, and are matched by that.
+func GetBackupsForSource(sourcePath string) ([]BackupInfo, error) {
+	normalized, err := config.NormalizePath(sourcePath)
+	if err != nil {
+		normalized = sourcePath
+	}
+	filename := filepath.Base(normalized)
+
+	allBackups, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var fileBackups []BackupInfo
+	for _, backup := range allBackups {
+		legacy := !strings.ContainsAny(backup.SourcePath, `/\`)
+		if backup.SourcePath == normalized || (legacy && backup.SourcePath == filename) {
+			fileBackups = append(fileBackups, backup)
+		}
+	}
+
+	return fileBackups, nil
+}
+
 // GetLatestBackup returns the most recent backup for a file
 func GetLatestBackup(filename string) (*BackupInfo, error) {
 	backups, err := GetBackupsForFile(filename)
@@ -0,0 +1,294 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// BackupArchiveExt is the extension of a compressed backup set. A set
+// ~/.dotcor/backups/<timestamp>/ is stored as <timestamp>.tar.zst, and its
+// backups keep their IDs, so index entries and backup paths stay valid.
+const BackupArchiveExt = ".tar.zst"
+
+// ErrZstdNotInstalled is returned when backups can't be compressed or read
+// back because the zstd command isn't on PATH
+var ErrZstdNotInstalled = errors.New("zstd not installed")
+
+var (
+	compressMu      sync.Mutex
+	compressBackups bool
+)
+
+// SetBackupCompression sets whether finished backup sets are compressed into
+// archives. The newest set stays loose, so a command rolling back restores
+// from plain files.
+func SetBackupCompression(enabled bool) {
+	compressMu.Lock()
+	defer compressMu.Unlock()
+	compressBackups = enabled
+}
+
+// BackupCompression reports whether finished backup sets are compressed
+func BackupCompression() bool {
+	compressMu.Lock()
+	defer compressMu.Unlock()
+	return compressBackups
+}
+
+// archivedBackup is a backup read from a compressed backup set
+type archivedBackup struct {
+	Header *tar.Header
+	Data   []byte
+}
+
+// checksum returns the SHA-256 of the backup content, as fs.FileChecksum does
+func (a archivedBackup) checksum() string {
+	sum := sha256.Sum256(a.Data)
+	return hex.EncodeToString(sum[:])
+}
+
+// backupArchivePath returns the archive a backup set directory is
+// compressed into
+func backupArchivePath(setDir string) string {
+	return filepath.Clean(setDir) + BackupArchiveExt
+}
+
+// runZstd runs zstd with input on stdin and returns stdout
+func runZstd(input io.Reader, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return nil, fmt.Errorf("%w: install zstd or set backups.compress to false", ErrZstdNotInstalled)
+	}
+
+	cmd := exec.Command("zstd", append([]string{"-q", "-c"}, args...)...)
+	cmd.Stdin = input
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("zstd failed: %s", msg)
+		}
+		return nil, fmt.Errorf("zstd failed: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// compressBackupSet packs the files of a backup set directory, including its
+// manifest, into a .tar.zst archive and removes the directory
+func compressBackupSet(setDir string) error {
+	entries, err := os.ReadDir(setDir)
+	if err != nil {
+		return fmt.Errorf("reading backup set: %w", err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		// Leftover temp files from an interrupted manifest write aren't kept
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		if err := addToArchive(tw, filepath.Join(setDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+
+	compressed, err := runZstd(&buf)
+	if err != nil {
+		return err
+	}
+
+	archivePath := backupArchivePath(setDir)
+	tempPath := archivePath + ".tmp"
+	if err := os.WriteFile(tempPath, compressed, 0644); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	if err := os.Rename(tempPath, archivePath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing archive: %w", err)
+	}
+
+	// The archive is complete, so the loose copies can go
+	return fs.RemoveAll(setDir)
+}
+
+// addToArchive writes the file at path to tw, keeping its mode and times
+func addToArchive(tw *tar.Writer, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("archiving %s: %w", path, err)
+	}
+	header.Size = int64(len(data))
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("archiving %s: %w", path, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("archiving %s: %w", path, err)
+	}
+	return nil
+}
+
+// readBackupArchive returns the files in a compressed backup set
+func readBackupArchive(archivePath string) ([]archivedBackup, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := runZstd(f, "-d")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []archivedBackup
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive %s: %w", archivePath, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("reading archive %s: %w", archivePath, err)
+		}
+		files = append(files, archivedBackup{Header: header, Data: content})
+	}
+	return files, nil
+}
+
+// archivedManifest returns the manifest entries stored in a compressed
+// backup set, by file name
+func archivedManifest(files []archivedBackup) map[string]BackupManifestEntry {
+	entries := make(map[string]BackupManifestEntry)
+	for _, f := range files {
+		if f.Header.Name == BackupManifestFile {
+			entries, _ = parseBackupManifest(f.Data)
+		}
+	}
+	return entries
+}
+
+// extractArchivedBackup writes the backup at backupPath, which lives in a
+// compressed backup set, to a temp file with its original mode and times.
+// The caller removes the returned file.
+func extractArchivedBackup(backupPath string) (string, error) {
+	archivePath := backupArchivePath(filepath.Dir(backupPath))
+	if !fs.FileExists(archivePath) {
+		return "", fmt.Errorf("backup file does not exist: %s", backupPath)
+	}
+
+	files, err := readBackupArchive(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(backupPath)
+	for _, f := range files {
+		if f.Header.Name != name {
+			continue
+		}
+
+		tmp, err := os.CreateTemp("", "dotcor-backup-*")
+		if err != nil {
+			return "", fmt.Errorf("extracting backup: %w", err)
+		}
+		tempPath := tmp.Name()
+		_, err = tmp.Write(f.Data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tempPath, f.Header.FileInfo().Mode().Perm())
+		}
+		if err == nil {
+			err = os.Chtimes(tempPath, f.Header.ModTime, f.Header.ModTime)
+		}
+		if err != nil {
+			os.Remove(tempPath)
+			return "", fmt.Errorf("extracting backup: %w", err)
+		}
+		return tempPath, nil
+	}
+	return "", fmt.Errorf("backup file does not exist: %s", backupPath)
+}
+
+// archivedNames returns the names of the backups in setDir's archive, if it
+// has one. A clock set back can reuse a timestamp that was already archived.
+func archivedNames(setDir string) map[string]bool {
+	names := make(map[string]bool)
+	archivePath := backupArchivePath(setDir)
+	if !fs.FileExists(archivePath) {
+		return names
+	}
+	files, err := readBackupArchive(archivePath)
+	if err != nil {
+		return names
+	}
+	for _, f := range files {
+		names[f.Header.Name] = true
+	}
+	return names
+}
+
+// compressFinishedBackups compresses every loose backup set except the one
+// named current. Sets that already have an archive, e.g. after a crash
+// between writing it and removing the directory, are left for both to be read.
+func compressFinishedBackups(backupDir, current string) error {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return fmt.Errorf("reading backup directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == current {
+			continue
+		}
+		if _, err := time.ParseInLocation(TimestampFormat, entry.Name(), time.Local); err != nil {
+			continue
+		}
+
+		setDir := filepath.Join(backupDir, entry.Name())
+		if fs.FileExists(backupArchivePath(setDir)) {
+			continue
+		}
+		if err := compressBackupSet(setDir); err != nil {
+			return fmt.Errorf("compressing backup set %s: %w", entry.Name(), err)
+		}
+		log.Debug("compressed backup set", "set", entry.Name())
+	}
+	return nil
+}
@@ -0,0 +1,459 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// BackupIndexFile is the name of the backup catalog in the backup directory.
+// Each line is a JSON-encoded BackupInfo, appended when a backup is created.
+const BackupIndexFile = "index.jsonl"
+
+// BackupManifestFile is the name of the manifest in each timestamped backup
+// directory. It records where each backup in the directory came from, so
+// backups of different files with the same name can be told apart.
+const BackupManifestFile = "manifest.json"
+
+// BackupManifestEntry describes one backup in a backup directory
+type BackupManifestEntry struct {
+	File       string `json:"file"`                // Backup file name in the directory
+	SourcePath string `json:"source_path"`         // Original file path (normalized)
+	RepoPath   string `json:"repo_path,omitempty"` // Repo path of the managed file it belongs to
+	Perm       string `json:"perm,omitempty"`      // Original permissions (octal)
+	Checksum   string `json:"checksum"`            // SHA-256 of the backup content
+}
+
+// backupManifest is the on-disk format of a backup directory's manifest
+type backupManifest struct {
+	Files []BackupManifestEntry `json:"files"`
+}
+
+// readBackupManifest returns the manifest entries of a backup directory by
+// file name. Directories from before manifests existed have none.
+func readBackupManifest(dir string) (map[string]BackupManifestEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, BackupManifestFile))
+	if os.IsNotExist(err) {
+		return map[string]BackupManifestEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading backup manifest: %w", err)
+	}
+	return parseBackupManifest(data)
+}
+
+// parseBackupManifest decodes a manifest into its entries by file name
+func parseBackupManifest(data []byte) (map[string]BackupManifestEntry, error) {
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing backup manifest: %w", err)
+	}
+	entries := make(map[string]BackupManifestEntry)
+	for _, entry := range manifest.Files {
+		entries[entry.File] = entry
+	}
+	return entries, nil
+}
+
+// addBackupManifest records a new backup in its directory's manifest
+func addBackupManifest(dir string, entry BackupManifestEntry) error {
+	var manifest backupManifest
+	path := filepath.Join(dir, BackupManifestFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Errorf("parsing backup manifest: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("reading backup manifest: %w", err)
+	}
+	manifest.Files = append(manifest.Files, entry)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding backup manifest: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing backup manifest: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing backup manifest: %w", err)
+	}
+	return nil
+}
+
+// getBackupIndexPath returns the path to the backup index file
+func getBackupIndexPath() (string, error) {
+	backupDir, err := GetBackupDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(backupDir, BackupIndexFile), nil
+}
+
+// appendBackupIndex records a new backup in the index
+func appendBackupIndex(info BackupInfo) error {
+	indexPath, err := getBackupIndexPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("encoding index entry: %w", err)
+	}
+
+	f, err := os.OpenFile(indexPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening backup index: %w", err)
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing backup index: %w", err)
+	}
+	return f.Close()
+}
+
+// readBackupIndex reads all entries from the index.
+// Malformed lines (e.g. from an interrupted write) are skipped.
+func readBackupIndex(backupDir string) ([]BackupInfo, error) {
+	f, err := os.Open(filepath.Join(backupDir, BackupIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var backups []BackupInfo
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var info BackupInfo
+		if err := json.Unmarshal([]byte(line), &info); err != nil || info.ID == "" {
+			continue
+		}
+		info.BackupPath = filepath.Join(backupDir, filepath.FromSlash(info.ID))
+		backups = append(backups, info)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading backup index: %w", err)
+	}
+
+	return backups, nil
+}
+
+// writeBackupIndex atomically replaces the index with the given entries
+func writeBackupIndex(backupDir string, backups []BackupInfo) error {
+	var sb strings.Builder
+	for _, info := range backups {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("encoding index entry: %w", err)
+		}
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+
+	indexPath := filepath.Join(backupDir, BackupIndexFile)
+	tempPath := indexPath + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("writing backup index: %w", err)
+	}
+	if err := os.Rename(tempPath, indexPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing backup index: %w", err)
+	}
+	return nil
+}
+
+// RebuildBackupIndex regenerates the index by scanning the backup directory.
+// Source paths and timestamps already recorded in the old index are kept;
+// backups found only on disk get their timestamp from the directory name.
+func RebuildBackupIndex() ([]BackupInfo, error) {
+	backupDir, err := GetBackupDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if !fs.PathExists(backupDir) {
+		return []BackupInfo{}, nil
+	}
+
+	known := make(map[string]BackupInfo)
+	if existing, err := readBackupIndex(backupDir); err == nil {
+		for _, info := range existing {
+			known[info.ID] = info
+		}
+	}
+
+	backups, err := scanBackupDir(backupDir, known)
+	if err != nil {
+		return nil, err
+	}
+
+	sortBackups(backups)
+
+	if err := writeBackupIndex(backupDir, backups); err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
+// scanBackupDir walks the backup directory and returns every backup file.
+// Entries in known are reused for matching IDs; otherwise the original
+// paths come from each directory's manifest.
+func scanBackupDir(backupDir string, known map[string]BackupInfo) ([]BackupInfo, error) {
+	backups := []BackupInfo{}
+	manifests := make(map[string]map[string]BackupManifestEntry)
+
+	err := filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip directories
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(backupDir, path)
+		if err != nil {
+			return nil // Skip files we can't process
+		}
+		id := filepath.ToSlash(relPath)
+
+		// Files directly in the backup dir (like the index) aren't backups,
+		// except compressed backup sets, and neither are the manifests or
+		// their temp files
+		parts := strings.SplitN(id, "/", 2)
+		if len(parts) < 2 {
+			if strings.HasSuffix(id, BackupArchiveExt) {
+				archived, err := scanBackupArchive(backupDir, path, known)
+				if err != nil {
+					log.Warn("reading backup archive failed", "path", path, "error", err)
+				}
+				backups = append(backups, archived...)
+			}
+			return nil
+		}
+		if strings.HasPrefix(parts[1], BackupManifestFile) {
+			return nil
+		}
+
+		if entry, ok := known[id]; ok {
+			entry.BackupPath = path
+			entry.Size = info.Size()
+			if entry.Checksum == "" {
+				entry.Checksum, _ = fs.FileChecksum(path)
+			}
+			backups = append(backups, entry)
+			return nil
+		}
+
+		// The first path component is the timestamp directory
+		timestamp, err := time.ParseInLocation(TimestampFormat, parts[0], time.Local)
+		if err != nil {
+			return nil // Skip if we can't parse timestamp
+		}
+
+		dir := filepath.Join(backupDir, parts[0])
+		if _, ok := manifests[dir]; !ok {
+			manifests[dir], _ = readBackupManifest(dir)
+		}
+		checksum, _ := fs.FileChecksum(path)
+		backup := BackupInfo{
+			ID:         id,
+			Timestamp:  timestamp,
+			SourcePath: info.Name(), // Just filename, unless the manifest knows better
+			BackupPath: path,
+			Size:       info.Size(),
+			Checksum:   checksum,
+		}
+		if entry, ok := manifests[dir][parts[1]]; ok {
+			backup.SourcePath, backup.RepoPath = entry.SourcePath, entry.RepoPath
+		}
+		backups = append(backups, backup)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking backup directory: %w", err)
+	}
+
+	return backups, nil
+}
+
+// scanBackupArchive returns the backups in a compressed backup set. Backups
+// also present loose, from a crash while compressing, are found by the walk.
+func scanBackupArchive(backupDir, archivePath string, known map[string]BackupInfo) ([]BackupInfo, error) {
+	set := strings.TrimSuffix(filepath.Base(archivePath), BackupArchiveExt)
+	timestamp, err := time.ParseInLocation(TimestampFormat, set, time.Local)
+	if err != nil {
+		return nil, nil // Not a backup set
+	}
+	setDir := filepath.Join(backupDir, set)
+
+	files, err := readBackupArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	manifest := archivedManifest(files)
+
+	var backups []BackupInfo
+	for _, f := range files {
+		name := f.Header.Name
+		if strings.Contains(name, "/") || strings.HasPrefix(name, BackupManifestFile) || fs.FileExists(filepath.Join(setDir, name)) {
+			continue
+		}
+
+		id := set + "/" + name
+		backup, ok := known[id]
+		if !ok {
+			backup = BackupInfo{ID: id, Timestamp: timestamp, SourcePath: name}
+			if entry, ok := manifest[name]; ok {
+				backup.SourcePath, backup.RepoPath = entry.SourcePath, entry.RepoPath
+			}
+		}
+		backup.BackupPath = filepath.Join(setDir, name)
+		backup.Size = int64(len(f.Data))
+		if backup.Checksum == "" {
+			backup.Checksum = f.checksum()
+		}
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
+
+// pruneBackupIndex drops index entries stored under the given backup directories
+func pruneBackupIndex(removedDirs []string) error {
+	if len(removedDirs) == 0 {
+		return nil
+	}
+
+	backupDir, err := GetBackupDir()
+	if err != nil {
+		return err
+	}
+
+	backups, err := readBackupIndex(backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	removed := make(map[string]bool)
+	for _, dir := range removedDirs {
+		removed[strings.TrimSuffix(filepath.Base(dir), BackupArchiveExt)] = true
+	}
+
+	kept := backups[:0]
+	for _, info := range backups {
+		if !removed[strings.SplitN(info.ID, "/", 2)[0]] {
+			kept = append(kept, info)
+		}
+	}
+
+	return writeBackupIndex(backupDir, kept)
+}
+
+// sortBackups sorts backups newest first
+func sortBackups(backups []BackupInfo) {
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Timestamp.After(backups[j].Timestamp)
+	})
+}
+
+// MoveBackupsHome rewrites the original paths in the backup index and in
+// each backup directory's manifest that lie in the home directory from to
+// lie in to, so backups restore to the right place after the home directory
+// moved. Manifests inside compressed backup sets are left alone; the index
+// covers their backups. It returns how many backups were rewritten.
+func MoveBackupsHome(from, to string) (int, error) {
+	backupDir, err := GetBackupDir()
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	backups, err := readBackupIndex(backupDir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	for i := range backups {
+		if path, ok := config.MovedHomePath(backups[i].SourcePath, from, to); ok {
+			backups[i].SourcePath = path
+			moved++
+		}
+	}
+	if moved > 0 {
+		if err := writeBackupIndex(backupDir, backups); err != nil {
+			return 0, err
+		}
+	}
+
+	manifests, err := filepath.Glob(filepath.Join(backupDir, "*", BackupManifestFile))
+	if err != nil {
+		return moved, err
+	}
+	for _, path := range manifests {
+		if err := moveManifestHome(path, from, to); err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// moveManifestHome rewrites the source paths in one backup manifest
+func moveManifestHome(path, from, to string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading backup manifest: %w", err)
+	}
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("parsing backup manifest %s: %w", path, err)
+	}
+
+	changed := false
+	for i := range manifest.Files {
+		if moved, ok := config.MovedHomePath(manifest.Files[i].SourcePath, from, to); ok {
+			manifest.Files[i].SourcePath = moved
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err = json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding backup manifest: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing backup manifest: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing backup manifest: %w", err)
+	}
+	return nil
+}
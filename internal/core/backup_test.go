@@ -2,9 +2,13 @@ package core
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
 )
 
 func TestCreateBackup(t *testing.T) {
@@ -185,3 +189,415 @@ func TestCleanupCandidate(t *testing.T) {
 		t.Error("CleanupCandidate.Size not set correctly")
 	}
 }
+
+func TestBackupLinkedFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	cfg := &config.Config{
+		Version:  config.CurrentConfigVersion,
+		RepoPath: repoDir,
+		ManagedFiles: []config.ManagedFile{
+			{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"},
+			{SourcePath: "~/.bashrc", RepoPath: "shell/bashrc"},
+			{SourcePath: "~/.vimrc", RepoPath: "vim/vimrc"},
+		},
+	}
+
+	// zshrc and bashrc are linked, vimrc is only in the repo
+	for _, name := range []string{"zshrc", "bashrc"} {
+		repoFile := filepath.Join(repoDir, "shell", name)
+		if err := os.MkdirAll(filepath.Dir(repoFile), 0755); err != nil {
+			t.Fatalf("failed to create repo dir: %v", err)
+		}
+		if err := os.WriteFile(repoFile, []byte("old "+name), 0644); err != nil {
+			t.Fatalf("failed to create repo file: %v", err)
+		}
+		if err := os.Symlink(repoFile, filepath.Join(tempDir, "."+name)); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+
+	backups, err := BackupLinkedFiles(cfg, []string{"shell/zshrc", "vim/vimrc"})
+	if err != nil {
+		t.Fatalf("BackupLinkedFiles() error = %v", err)
+	}
+
+	if len(backups) != 1 {
+		t.Fatalf("BackupLinkedFiles() created %d backups, want 1", len(backups))
+	}
+	if filepath.Base(backups[0]) != ".zshrc" {
+		t.Errorf("BackupLinkedFiles() backup name = %s, want .zshrc", filepath.Base(backups[0]))
+	}
+
+	content, err := os.ReadFile(backups[0])
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if string(content) != "old zshrc" {
+		t.Errorf("BackupLinkedFiles() content = %q, want %q", content, "old zshrc")
+	}
+}
+
+func TestBackupIndex(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("HOME", tempDir)
+
+	for _, name := range []string{".zshrc", ".vimrc"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("content of "+name), 0644); err != nil {
+			t.Fatalf("failed to create source file: %v", err)
+		}
+		if _, err := CreateBackup(filepath.Join(tempDir, name)); err != nil {
+			t.Fatalf("CreateBackup() error = %v", err)
+		}
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("ListBackups() returned %d backups, want 2", len(backups))
+	}
+
+	sources := map[string]BackupInfo{}
+	for _, b := range backups {
+		sources[b.SourcePath] = b
+	}
+	zshrc, ok := sources["~/.zshrc"]
+	if !ok {
+		t.Fatalf("ListBackups() missing ~/.zshrc, got %+v", backups)
+	}
+	if zshrc.Size != int64(len("content of .zshrc")) {
+		t.Errorf("Size = %d, want %d", zshrc.Size, len("content of .zshrc"))
+	}
+	if zshrc.Checksum == "" {
+		t.Error("Checksum not recorded")
+	}
+	if content, err := os.ReadFile(zshrc.BackupPath); err != nil || string(content) != "content of .zshrc" {
+		t.Errorf("BackupPath %s has content %q (err %v)", zshrc.BackupPath, content, err)
+	}
+
+	// Without the index, listing falls back to a rebuild from disk
+	indexPath, err := getBackupIndexPath()
+	if err != nil {
+		t.Fatalf("getBackupIndexPath() error = %v", err)
+	}
+	if err := os.Remove(indexPath); err != nil {
+		t.Fatalf("failed to remove index: %v", err)
+	}
+
+	rebuilt, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups() after index removal error = %v", err)
+	}
+	if len(rebuilt) != 2 {
+		t.Fatalf("rebuilt index has %d backups, want 2", len(rebuilt))
+	}
+	if _, err := os.Stat(indexPath); err != nil {
+		t.Errorf("index not rewritten after rebuild: %v", err)
+	}
+	for _, b := range rebuilt {
+		if b.Checksum == "" || b.ID == "" {
+			t.Errorf("rebuilt entry incomplete: %+v", b)
+		}
+	}
+
+	// A truncated trailing line is ignored
+	f, err := os.OpenFile(indexPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	f.WriteString(`{"id":"2024-01-01_00-00`)
+	f.Close()
+
+	backups, err = ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups() with truncated line error = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Errorf("ListBackups() with truncated line returned %d backups, want 2", len(backups))
+	}
+}
+
+func TestCleanOldBackupsPrunesIndex(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("HOME", tempDir)
+
+	backupDir, err := GetBackupDir()
+	if err != nil {
+		t.Fatalf("GetBackupDir() error = %v", err)
+	}
+
+	// An old backup made before the index existed
+	oldDir := filepath.Join(backupDir, "2020-01-01_00-00-00")
+	if err := os.MkdirAll(oldDir, 0755); err != nil {
+		t.Fatalf("failed to create backup dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(oldDir, ".bashrc"), []byte("old"), 0644); err != nil {
+		t.Fatalf("failed to create backup file: %v", err)
+	}
+
+	sourceFile := filepath.Join(tempDir, ".zshrc")
+	if err := os.WriteFile(sourceFile, []byte("new"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	if _, err := CreateBackup(sourceFile); err != nil {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+
+	// The index only knows about the new backup, so rebuild to pick up the old one
+	backups, err := RebuildBackupIndex()
+	if err != nil {
+		t.Fatalf("RebuildBackupIndex() error = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("RebuildBackupIndex() returned %d backups, want 2", len(backups))
+	}
+	if backups[0].SourcePath != "~/.zshrc" {
+		t.Errorf("RebuildBackupIndex() lost source path, got %q", backups[0].SourcePath)
+	}
+
+	deleted, failed, _, err := CleanOldBackups(24*time.Hour, 0)
+	if err != nil || failed != 0 || deleted != 1 {
+		t.Fatalf("CleanOldBackups() = %d deleted, %d failed, err %v", deleted, failed, err)
+	}
+
+	backups, err = ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 1 || backups[0].SourcePath != "~/.zshrc" {
+		t.Errorf("ListBackups() after cleanup = %+v, want only ~/.zshrc", backups)
+	}
+}
+
+func TestBackupManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	// Two managed files with the same name in different directories
+	files := []config.ManagedFile{
+		{SourcePath: "~/a/config", RepoPath: "a/config"},
+		{SourcePath: "~/b/config", RepoPath: "b/config"},
+	}
+	for _, mf := range files {
+		path := filepath.Join(tempDir, filepath.Dir(mf.RepoPath), "config")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("content of "+mf.SourcePath), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CreateManagedBackup(path, mf); err != nil {
+			t.Fatalf("CreateManagedBackup() error = %v", err)
+		}
+	}
+
+	check := func(when string) {
+		t.Helper()
+		for _, mf := range files {
+			backups, err := GetBackupsForSource(mf.SourcePath)
+			if err != nil {
+				t.Fatalf("%s: GetBackupsForSource() error = %v", when, err)
+			}
+			if len(backups) != 1 {
+				t.Fatalf("%s: GetBackupsForSource(%s) = %d backups, want 1", when, mf.SourcePath, len(backups))
+			}
+			if backups[0].RepoPath != mf.RepoPath {
+				t.Errorf("%s: RepoPath = %q, want %q", when, backups[0].RepoPath, mf.RepoPath)
+			}
+			content, _ := os.ReadFile(backups[0].BackupPath)
+			if string(content) != "content of "+mf.SourcePath {
+				t.Errorf("%s: backup of %s has content %q", when, mf.SourcePath, content)
+			}
+		}
+	}
+	check("from index")
+
+	// The manifest records the original path, permissions and checksum
+	backups, _ := GetBackupsForSource("~/a/config")
+	manifest, err := readBackupManifest(filepath.Dir(backups[0].BackupPath))
+	if err != nil {
+		t.Fatalf("readBackupManifest() error = %v", err)
+	}
+	entry := manifest[filepath.Base(backups[0].BackupPath)]
+	if entry.SourcePath != "~/a/config" || entry.Perm != "600" || entry.Checksum != backups[0].Checksum {
+		t.Errorf("manifest entry = %+v", entry)
+	}
+
+	// Rebuilding the index keeps the original paths and skips the manifest
+	indexPath, err := getBackupIndexPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(indexPath); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := ListBackups(); err != nil || len(all) != 2 {
+		t.Fatalf("ListBackups() after rebuild = %d backups, %v, want 2", len(all), err)
+	}
+	check("after rebuild")
+}
+
+func TestCompressedBackups(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	source := filepath.Join(tempDir, ".zshrc")
+	if err := os.WriteFile(source, []byte("export EDITOR=vim\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mf := config.ManagedFile{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"}
+	backupPath, err := CreateManagedBackup(source, mf)
+	if err != nil {
+		t.Fatalf("CreateManagedBackup() error = %v", err)
+	}
+
+	backupDir, _ := GetBackupDir()
+	if err := compressFinishedBackups(backupDir, ""); err != nil {
+		t.Fatalf("compressFinishedBackups() error = %v", err)
+	}
+	setDir := filepath.Dir(backupPath)
+	if fs.PathExists(setDir) || !fs.FileExists(backupArchivePath(setDir)) {
+		t.Fatal("backup set should be replaced by an archive")
+	}
+
+	// Restoring extracts the backup with its permissions
+	target := filepath.Join(tempDir, "restored")
+	if err := RestoreBackup(backupPath, target); err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	content, _ := os.ReadFile(target)
+	if string(content) != "export EDITOR=vim\n" {
+		t.Errorf("restored content = %q", content)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("restored permissions = %o, want 600", info.Mode().Perm())
+	}
+
+	// A rebuilt index finds the archived backup and its original path
+	indexPath, _ := getBackupIndexPath()
+	if err := os.Remove(indexPath); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := GetBackupsForSource("~/.zshrc")
+	if err != nil {
+		t.Fatalf("GetBackupsForSource() error = %v", err)
+	}
+	if len(backups) != 1 || backups[0].BackupPath != backupPath || backups[0].RepoPath != "shell/zshrc" {
+		t.Fatalf("GetBackupsForSource() = %+v, want the archived backup", backups)
+	}
+	if all, _ := ListBackups(); len(all) != 1 {
+		t.Errorf("ListBackups() = %d backups, want 1 (manifest isn't a backup)", len(all))
+	}
+
+	// Cleanup removes archives like directories
+	deleted, _, _, err := CleanOldBackups(0, 0)
+	if err != nil || deleted != 1 {
+		t.Fatalf("CleanOldBackups() = %d, %v, want 1 set removed", deleted, err)
+	}
+	if count, _ := GetBackupCount(); count != 0 {
+		t.Errorf("GetBackupCount() = %d after cleanup, want 0", count)
+	}
+}
+
+func TestCreateBackupCompressesFinishedSets(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	SetBackupCompression(true)
+	t.Cleanup(func() { SetBackupCompression(false) })
+
+	// An older set is compressed when the next backup is taken
+	backupDir, _ := GetBackupDir()
+	oldSet := filepath.Join(backupDir, "2020-01-01_00-00-00")
+	if err := os.MkdirAll(oldSet, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldSet, ".vimrc"), []byte("set number"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := filepath.Join(tempDir, ".zshrc")
+	if err := os.WriteFile(source, []byte("export EDITOR=vim"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	backupPath, err := CreateBackup(source)
+	if err != nil {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+
+	if fs.PathExists(oldSet) || !fs.FileExists(backupArchivePath(oldSet)) {
+		t.Error("finished backup set should be compressed")
+	}
+	if !fs.FileExists(backupPath) {
+		t.Error("newest backup set should stay loose")
+	}
+}
+
+func TestMoveBackupsHome(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	backupDir, err := GetBackupDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(backupDir, "2024-01-02_03-04-05")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range []BackupInfo{
+		{ID: "2024-01-02_03-04-05/.zshrc", SourcePath: "/Users/old/.zshrc"},
+		{ID: "2024-01-02_03-04-05/hosts", SourcePath: "/etc/hosts"},
+	} {
+		if err := appendBackupIndex(info); err != nil {
+			t.Fatal(err)
+		}
+		if err := addBackupManifest(dir, BackupManifestEntry{File: filepath.Base(info.ID), SourcePath: info.SourcePath}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := MoveBackupsHome("/Users/old", tempDir)
+	if err != nil {
+		t.Fatalf("MoveBackupsHome() error = %v", err)
+	}
+	if moved != 1 {
+		t.Errorf("MoveBackupsHome() = %d, want 1", moved)
+	}
+
+	backups, err := readBackupIndex(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backups[0].SourcePath != "~/.zshrc" || backups[1].SourcePath != "/etc/hosts" {
+		t.Errorf("index source paths = %q, %q", backups[0].SourcePath, backups[1].SourcePath)
+	}
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest[".zshrc"].SourcePath != "~/.zshrc" || manifest["hosts"].SourcePath != "/etc/hosts" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// ChecksumsFile is the name of the checksum manifest in ~/.dotcor. It maps
+// each repo path to the SHA-256 of the content dotcor last recorded for it,
+// so changes made behind dotcor's back can be found without git history.
+// A named repository keeps its own in ~/.dotcor/repos/<name>.
+const ChecksumsFile = "checksums.json"
+
+// Drift kinds reported by VerifyChecksums
+const (
+	DriftModified   = "modified"   // Content differs from the recorded checksum
+	DriftMissing    = "missing"    // Repo file is gone
+	DriftUnrecorded = "unrecorded" // No checksum recorded yet
+)
+
+// ChecksumDrift is a repo file whose content doesn't match its recorded checksum
+type ChecksumDrift struct {
+	RepoPath string `json:"repo_path" yaml:"repo_path"`
+	Kind     string `json:"kind" yaml:"kind"`
+	Recorded string `json:"recorded,omitempty" yaml:"recorded,omitempty"`
+	Actual   string `json:"actual,omitempty" yaml:"actual,omitempty"`
+}
+
+// checksumManifest is the on-disk format of the checksum manifest
+type checksumManifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// getChecksumsPath returns the path to the checksum manifest
+func getChecksumsPath() (string, error) {
+	stateDir, err := config.GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, ChecksumsFile), nil
+}
+
+// readChecksums returns the recorded checksums by repo path. A missing
+// manifest has no checksums.
+func readChecksums() (map[string]string, error) {
+	path, err := getChecksumsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading checksums: %w", err)
+	}
+
+	var manifest checksumManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing checksums: %w", err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]string{}
+	}
+	return manifest.Files, nil
+}
+
+// writeChecksums atomically replaces the checksum manifest
+func writeChecksums(sums map[string]string) error {
+	path, err := getChecksumsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(checksumManifest{Files: sums}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding checksums: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing checksums: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing checksums: %w", err)
+	}
+	return nil
+}
+
+// RecordChecksums records the current content of the given repo files as
+// their known-good state. Paths not tracked in the config are ignored, and
+// checksums of files no longer tracked are dropped.
+func RecordChecksums(cfg *config.Config, repoPaths ...string) error {
+	sums, err := readChecksums()
+	if err != nil {
+		return err
+	}
+
+	tracked := cfg.TrackedRepoPaths()
+	for _, repoPath := range repoPaths {
+		if !tracked[repoPath] {
+			continue
+		}
+		fullPath, err := config.GetRepoFilePath(cfg, repoPath)
+		if err != nil {
+			return err
+		}
+		if !fs.FileExists(fullPath) {
+			delete(sums, repoPath)
+			continue
+		}
+		sum, err := fs.FileChecksum(fullPath)
+		if err != nil {
+			return fmt.Errorf("checksumming %s: %w", repoPath, err)
+		}
+		sums[repoPath] = sum
+	}
+
+	for repoPath := range sums {
+		if !tracked[repoPath] {
+			delete(sums, repoPath)
+		}
+	}
+	return writeChecksums(sums)
+}
+
+// RecordAllChecksums records the current content of every repo file
+// tracked in the config
+func RecordAllChecksums(cfg *config.Config) error {
+	var repoPaths []string
+	for repoPath := range cfg.TrackedRepoPaths() {
+		repoPaths = append(repoPaths, repoPath)
+	}
+	return RecordChecksums(cfg, repoPaths...)
+}
+
+// VerifyChecksums compares every repo file tracked in the config with its
+// recorded checksum, returning those that differ, sorted by repo path
+func VerifyChecksums(cfg *config.Config) ([]ChecksumDrift, error) {
+	sums, err := readChecksums()
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []ChecksumDrift
+	for repoPath := range cfg.TrackedRepoPaths() {
+		fullPath, err := config.GetRepoFilePath(cfg, repoPath)
+		if err != nil {
+			return nil, err
+		}
+
+		recorded, ok := sums[repoPath]
+		if !fs.FileExists(fullPath) {
+			drift = append(drift, ChecksumDrift{RepoPath: repoPath, Kind: DriftMissing, Recorded: recorded})
+			continue
+		}
+		actual, err := fs.FileChecksum(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("checksumming %s: %w", repoPath, err)
+		}
+
+		switch {
+		case !ok:
+			drift = append(drift, ChecksumDrift{RepoPath: repoPath, Kind: DriftUnrecorded, Actual: actual})
+		case actual != recorded:
+			drift = append(drift, ChecksumDrift{RepoPath: repoPath, Kind: DriftModified, Recorded: recorded, Actual: actual})
+		}
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].RepoPath < drift[j].RepoPath
+	})
+	return drift, nil
+}
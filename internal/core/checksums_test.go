@@ -0,0 +1,113 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// setupChecksumTest creates a repo with two tracked files
+func setupChecksumTest(t *testing.T) *config.Config {
+	t.Helper()
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	cfg := &config.Config{
+		Version:  config.CurrentConfigVersion,
+		RepoPath: filepath.Join(tempDir, ".dotcor", "files"),
+		ManagedFiles: []config.ManagedFile{
+			{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"},
+			{SourcePath: "~/.vimrc", RepoPath: "vim/vimrc"},
+		},
+	}
+	for _, repoPath := range []string{"shell/zshrc", "vim/vimrc"} {
+		writeRepoFile(t, cfg, repoPath, "original "+repoPath)
+	}
+	return cfg
+}
+
+func writeRepoFile(t *testing.T, cfg *config.Config, repoPath, content string) {
+	t.Helper()
+	path := filepath.Join(cfg.RepoPath, repoPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	cfg := setupChecksumTest(t)
+
+	// Nothing is recorded yet
+	drift, err := VerifyChecksums(cfg)
+	if err != nil {
+		t.Fatalf("VerifyChecksums() error = %v", err)
+	}
+	if len(drift) != 2 || drift[0].Kind != DriftUnrecorded {
+		t.Fatalf("VerifyChecksums() = %v, want 2 unrecorded files", drift)
+	}
+
+	if err := RecordAllChecksums(cfg); err != nil {
+		t.Fatalf("RecordAllChecksums() error = %v", err)
+	}
+	if drift, _ := VerifyChecksums(cfg); len(drift) != 0 {
+		t.Fatalf("VerifyChecksums() = %v, want no drift after recording", drift)
+	}
+
+	// A file changed behind dotcor's back, and one that disappeared
+	writeRepoFile(t, cfg, "shell/zshrc", "restored from the wrong backup")
+	if err := os.Remove(filepath.Join(cfg.RepoPath, "vim", "vimrc")); err != nil {
+		t.Fatal(err)
+	}
+
+	drift, err = VerifyChecksums(cfg)
+	if err != nil {
+		t.Fatalf("VerifyChecksums() error = %v", err)
+	}
+	if len(drift) != 2 {
+		t.Fatalf("VerifyChecksums() = %v, want 2 problems", drift)
+	}
+	if drift[0].RepoPath != "shell/zshrc" || drift[0].Kind != DriftModified {
+		t.Errorf("drift[0] = %+v, want shell/zshrc modified", drift[0])
+	}
+	if drift[0].Recorded == "" || drift[0].Actual == "" || drift[0].Recorded == drift[0].Actual {
+		t.Errorf("drift[0] checksums = %q, %q", drift[0].Recorded, drift[0].Actual)
+	}
+	if drift[1].RepoPath != "vim/vimrc" || drift[1].Kind != DriftMissing {
+		t.Errorf("drift[1] = %+v, want vim/vimrc missing", drift[1])
+	}
+
+	// Recording the changed file accepts its new content
+	if err := RecordChecksums(cfg, "shell/zshrc"); err != nil {
+		t.Fatalf("RecordChecksums() error = %v", err)
+	}
+	if drift, _ := VerifyChecksums(cfg); len(drift) != 1 || drift[0].RepoPath != "vim/vimrc" {
+		t.Errorf("VerifyChecksums() = %v, want only vim/vimrc", drift)
+	}
+}
+
+func TestRecordChecksumsDropsUntracked(t *testing.T) {
+	cfg := setupChecksumTest(t)
+	if err := RecordAllChecksums(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files no longer managed are forgotten, untracked paths never recorded
+	cfg.ManagedFiles = cfg.ManagedFiles[:1]
+	writeRepoFile(t, cfg, "other/file", "not managed")
+	if err := RecordChecksums(cfg, "other/file"); err != nil {
+		t.Fatal(err)
+	}
+
+	sums, err := readChecksums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != 1 || sums["shell/zshrc"] == "" {
+		t.Errorf("checksums = %v, want only shell/zshrc", sums)
+	}
+}
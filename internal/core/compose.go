@@ -0,0 +1,184 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// includeSyntax is how one file type includes another
+type includeSyntax struct {
+	comment  string                   // Line comment prefix
+	include  func(path string) string // Includes a file that must exist
+	optional func(path string) string // Includes a file if it exists
+}
+
+var (
+	shellInclude = includeSyntax{
+		comment:  "#",
+		include:  func(p string) string { return fmt.Sprintf(". %s", quoteShell(p)) },
+		optional: func(p string) string { return fmt.Sprintf("[ -f %[1]s ] && . %[1]s", quoteShell(p)) },
+	}
+	// git skips include paths that don't exist
+	gitInclude = includeSyntax{
+		comment:  "#",
+		include:  func(p string) string { return fmt.Sprintf("[include]\n\tpath = %s", quoteGitValue(p)) },
+		optional: func(p string) string { return fmt.Sprintf("[include]\n\tpath = %s", quoteGitValue(p)) },
+	}
+	vimInclude = includeSyntax{
+		comment:  `"`,
+		include:  func(p string) string { return "source " + escapeVimPath(p) },
+		optional: func(p string) string { return "silent! source " + escapeVimPath(p) },
+	}
+	tmuxInclude = includeSyntax{
+		comment:  "#",
+		include:  func(p string) string { return "source-file " + quoteShell(p) },
+		optional: func(p string) string { return "source-file -q " + quoteShell(p) },
+	}
+	// ssh skips Include globs that match nothing
+	sshInclude = includeSyntax{
+		comment:  "#",
+		include:  func(p string) string { return fmt.Sprintf("Include %q", p) },
+		optional: func(p string) string { return fmt.Sprintf("Include %q", p) },
+	}
+)
+
+// composeSyntax returns the include syntax for the file type of repoPath
+func composeSyntax(repoPath string) (includeSyntax, bool) {
+	repoPath = strings.TrimSuffix(filepath.ToSlash(repoPath), config.TemplateExt)
+	base := strings.TrimPrefix(strings.ToLower(path.Base(repoPath)), ".")
+	dir := path.Base(path.Dir(repoPath))
+
+	switch {
+	case base == "bashrc", base == "bash_profile", base == "bash_login", base == "bash_aliases",
+		base == "profile", base == "zshrc", base == "zshenv", base == "zprofile", base == "zlogin",
+		strings.HasSuffix(base, ".sh"), strings.HasSuffix(base, ".bash"), strings.HasSuffix(base, ".zsh"):
+		return shellInclude, true
+	case base == "gitconfig", strings.HasSuffix(base, ".gitconfig"), base == "config" && dir == "git":
+		return gitInclude, true
+	case base == "vimrc", base == "gvimrc", strings.HasSuffix(base, ".vim"):
+		return vimInclude, true
+	case base == "tmux.conf":
+		return tmuxInclude, true
+	case base == "config" && dir == "ssh":
+		return sshInclude, true
+	}
+	return includeSyntax{}, false
+}
+
+// CanCompose reports whether files like the one at repoPath can be deployed
+// in compose mode: shell startup files, gitconfig, vimrc, tmux.conf and ssh
+// config, which have a way to include other files.
+func CanCompose(repoPath string) bool {
+	_, ok := composeSyntax(repoPath)
+	return ok
+}
+
+// ComposedContent returns the generated file for a compose-mode file: it
+// includes the repo file (or its rendered or decrypted output), then the
+// local file if there is one
+func ComposedContent(cfg *config.Config, mf config.ManagedFile) ([]byte, error) {
+	syntax, ok := composeSyntax(mf.RepoPath)
+	if !ok {
+		return nil, fmt.Errorf("%s can't be composed, its file type has no include syntax dotcor knows", mf.SourcePath)
+	}
+	targetPath, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return nil, err
+	}
+	localPath, err := config.ExpandPath(mf.LocalPath())
+	if err != nil {
+		return nil, fmt.Errorf("expanding local path: %w", err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s Generated by dotcor, changes here are overwritten.\n", syntax.comment)
+	fmt.Fprintf(&b, "%s Shared settings are in the repo ('dotcor edit %s'),\n", syntax.comment, mf.SourcePath)
+	fmt.Fprintf(&b, "%s settings for this machine only go in %s.\n", syntax.comment, mf.LocalPath())
+	fmt.Fprintf(&b, "%s\n", syntax.include(filepath.ToSlash(targetPath)))
+	fmt.Fprintf(&b, "%s\n", syntax.optional(filepath.ToSlash(localPath)))
+	return b.Bytes(), nil
+}
+
+// DeployComposed writes the generated file of a compose-mode file into
+// place, replacing a symlink or stale file at the source path. Callers
+// back up real files first.
+func DeployComposed(cfg *config.Config, mf config.ManagedFile) error {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return fmt.Errorf("expanding source path: %w", err)
+	}
+	content, err := ComposedContent(cfg, mf)
+	if err != nil {
+		return err
+	}
+
+	// Writing onto a symlink would write through it into the repo
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		if err := os.Remove(sourcePath); err != nil {
+			return fmt.Errorf("removing symlink: %w", err)
+		}
+	}
+
+	perm, ok, err := mf.FilePerm()
+	if err != nil || !ok {
+		perm = 0644
+	}
+	if err := os.MkdirAll(filepath.Dir(sourcePath), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(sourcePath, content, perm); err != nil {
+		return fmt.Errorf("writing composed file: %w", err)
+	}
+	log.Info("deployed composed file", "path", sourcePath, "includes", mf.RepoPath)
+	return nil
+}
+
+// ComposedIntact reports whether the generated file of a compose-mode file
+// is in place and unedited
+func ComposedIntact(cfg *config.Config, mf config.ManagedFile) (bool, error) {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return false, err
+	}
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		return false, nil
+	}
+	current, err := os.ReadFile(sourcePath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	want, err := ComposedContent(cfg, mf)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(current, want), nil
+}
+
+// quoteGitValue double-quotes a gitconfig value
+func quoteGitValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// escapeVimPath escapes the characters vim's :source treats specially
+func escapeVimPath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(" \\|\"%#", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestCanCompose(t *testing.T) {
+	tests := []struct {
+		repoPath string
+		want     bool
+	}{
+		{"shell/zshrc", true},
+		{"shell/bashrc.tmpl", true},
+		{"bin/setup.sh", true},
+		{"git/gitconfig", true},
+		{"config/git/config", true},
+		{"vim/vimrc", true},
+		{"tmux/tmux.conf", true},
+		{"ssh/config", true},
+		{"config/starship.toml", false},
+		{"config/app/config", false},
+	}
+	for _, tt := range tests {
+		if got := CanCompose(tt.repoPath); got != tt.want {
+			t.Errorf("CanCompose(%q) = %v, want %v", tt.repoPath, got, tt.want)
+		}
+	}
+}
+
+func TestDeployComposed(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	mf := config.ManagedFile{SourcePath: "~/.bashrc", RepoPath: "shell/bashrc", Mode: config.DeployModeCompose}
+	cfg := &config.Config{
+		Version:      config.CurrentConfigVersion,
+		RepoPath:     repoDir,
+		ManagedFiles: []config.ManagedFile{mf},
+	}
+
+	repoFile := filepath.Join(repoDir, "shell", "bashrc")
+	if err := os.MkdirAll(filepath.Dir(repoFile), 0755); err != nil {
+		t.Fatalf("failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(repoFile, []byte("SHARED=1\n"), 0644); err != nil {
+		t.Fatalf("failed to create repo file: %v", err)
+	}
+
+	// A symlink left from symlink mode is replaced, not written through
+	sourceFile := filepath.Join(tempDir, ".bashrc")
+	if err := os.Symlink(repoFile, sourceFile); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if intact, _ := ComposedIntact(cfg, mf); intact {
+		t.Error("ComposedIntact() = true for a symlink")
+	}
+	if err := DeployComposed(cfg, mf); err != nil {
+		t.Fatalf("DeployComposed() error = %v", err)
+	}
+	if content, _ := os.ReadFile(repoFile); string(content) != "SHARED=1\n" {
+		t.Fatalf("DeployComposed() wrote through the symlink: %q", content)
+	}
+	if intact, err := ComposedIntact(cfg, mf); err != nil || !intact {
+		t.Errorf("ComposedIntact() = %v, %v, want true", intact, err)
+	}
+
+	// The composed file runs the repo file, then the local one if present
+	if _, err := exec.LookPath("sh"); err == nil {
+		run := func() string {
+			out, err := exec.Command("sh", "-c", `. "$1"; echo "$SHARED $LOCAL"`, "sh", sourceFile).CombinedOutput()
+			if err != nil {
+				t.Fatalf("sourcing composed file: %v: %s", err, out)
+			}
+			return strings.TrimSpace(string(out))
+		}
+		if got := run(); got != "1" {
+			t.Errorf("without local file = %q, want %q", got, "1")
+		}
+		if err := os.WriteFile(filepath.Join(tempDir, ".bashrc.local"), []byte("LOCAL=2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := run(); got != "1 2" {
+			t.Errorf("with local file = %q, want %q", got, "1 2")
+		}
+	}
+
+	if err := os.WriteFile(sourceFile, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if intact, _ := ComposedIntact(cfg, mf); intact {
+		t.Error("ComposedIntact() = true for an edited file")
+	}
+}
+
+func TestComposedGitconfig(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	mf := config.ManagedFile{SourcePath: "~/.gitconfig", RepoPath: "git/gitconfig", Mode: config.DeployModeCompose}
+	cfg := &config.Config{Version: config.CurrentConfigVersion, RepoPath: repoDir}
+
+	repoFile := filepath.Join(repoDir, "git", "gitconfig")
+	if err := os.MkdirAll(filepath.Dir(repoFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(repoFile, []byte("[user]\n\tname = Shared\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, ".gitconfig.local"), []byte("[user]\n\temail = me@work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeployComposed(cfg, mf); err != nil {
+		t.Fatalf("DeployComposed() error = %v", err)
+	}
+
+	cmd := exec.Command("git", "config", "--file", filepath.Join(tempDir, ".gitconfig"), "--includes", "--list")
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git config: %v", err)
+	}
+	if got := string(out); !strings.Contains(got, "user.name=Shared") || !strings.Contains(got, "user.email=me@work") {
+		t.Errorf("composed gitconfig = %q, want settings from both files", got)
+	}
+}
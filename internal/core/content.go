@@ -0,0 +1,105 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// Content drift kinds reported by VerifyContent
+const (
+	ContentDiffers     = "differs"     // The file at the source path has other content than the repo file
+	ContentUnreachable = "unreachable" // Nothing can be read at the source path, e.g. a broken symlink
+	ContentDetached    = "detached"    // Same content, but a symlink or hard link no longer reaches the repo file
+)
+
+// ContentDrift is a managed file whose source path doesn't lead to the
+// content in the repository
+type ContentDrift struct {
+	SourcePath string `json:"source_path" yaml:"source_path"`
+	RepoPath   string `json:"repo_path" yaml:"repo_path"`
+	Kind       string `json:"kind" yaml:"kind"`
+}
+
+// VerifyContent compares the file reachable at each managed file's source
+// path with the file it should lead to: the repo file, or the rendered or
+// decrypted output. A symlink or hard link that reaches that very file
+// matches without reading it, and a composed file must be the one dotcor
+// generates; anything else, such as a copy-mode file or a symlink into a
+// stale checkout, is compared byte for byte. Files whose
+// repo side is missing are left to VerifyChecksums. Results are sorted by
+// source path.
+func VerifyContent(cfg *config.Config) ([]ContentDrift, error) {
+	files := append(cfg.GetManagedFilesForPlatform(), cfg.GetSystemFilesForPlatform()...)
+
+	var drift []ContentDrift
+	for _, mf := range files {
+		kind, err := contentDrift(cfg, mf)
+		if err != nil {
+			return nil, fmt.Errorf("verifying %s: %w", mf.SourcePath, err)
+		}
+		if kind != "" {
+			drift = append(drift, ContentDrift{SourcePath: mf.SourcePath, RepoPath: mf.RepoPath, Kind: kind})
+		}
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].SourcePath < drift[j].SourcePath
+	})
+	return drift, nil
+}
+
+// contentDrift returns the drift kind of one managed file, or "" if its
+// source path leads to the expected content
+func contentDrift(cfg *config.Config, mf config.ManagedFile) (string, error) {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return "", fmt.Errorf("expanding source path: %w", err)
+	}
+	targetPath, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return "", err
+	}
+
+	target, err := os.Stat(targetPath)
+	if err != nil || !target.Mode().IsRegular() {
+		return "", nil
+	}
+	source, err := os.Stat(sourcePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ContentUnreachable, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	// A composed file reaches the repo file by including it
+	if mf.IsComposed() {
+		intact, err := ComposedIntact(cfg, mf)
+		if err != nil || intact {
+			return "", err
+		}
+		return ContentDiffers, nil
+	}
+	if !source.Mode().IsRegular() {
+		return ContentDiffers, nil
+	}
+	if os.SameFile(source, target) {
+		return "", nil
+	}
+
+	same, err := fs.SameContent(sourcePath, targetPath)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case !same:
+		return ContentDiffers, nil
+	case !mf.IsCopy():
+		return ContentDetached, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestVerifyContent(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(target, path string) {
+		t.Helper()
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := []config.ManagedFile{
+		{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"},
+		{SourcePath: "~/.bashrc", RepoPath: "shell/bashrc"},
+		{SourcePath: "~/.vimrc", RepoPath: "vim/vimrc"},
+		{SourcePath: "~/.gitconfig", RepoPath: "git/gitconfig"},
+		{SourcePath: "~/.tmux.conf", RepoPath: "tmux/tmux.conf"},
+		{SourcePath: "~/.inputrc", RepoPath: "misc/inputrc", Mode: config.DeployModeCopy},
+		{SourcePath: "~/.npmrc", RepoPath: "misc/npmrc", Mode: config.DeployModeCopy},
+	}
+	for _, mf := range files {
+		write(filepath.Join(repoDir, mf.RepoPath), mf.RepoPath+"\n")
+	}
+
+	// Intact symlink
+	link(filepath.Join(repoDir, "shell/zshrc"), filepath.Join(tempDir, ".zshrc"))
+	// Symlink into a stale checkout with other content
+	write(filepath.Join(tempDir, "old", "bashrc"), "old\n")
+	link(filepath.Join(tempDir, "old", "bashrc"), filepath.Join(tempDir, ".bashrc"))
+	// Symlink into a stale checkout with the same content
+	write(filepath.Join(tempDir, "old", "vimrc"), "vim/vimrc\n")
+	link(filepath.Join(tempDir, "old", "vimrc"), filepath.Join(tempDir, ".vimrc"))
+	// Broken symlink
+	link(filepath.Join(tempDir, "gone"), filepath.Join(tempDir, ".gitconfig"))
+	// ~/.tmux.conf is missing
+	// Copies, one edited
+	write(filepath.Join(tempDir, ".inputrc"), "misc/inputrc\n")
+	write(filepath.Join(tempDir, ".npmrc"), "edited\n")
+
+	cfg := &config.Config{
+		Version:      config.CurrentConfigVersion,
+		RepoPath:     repoDir,
+		ManagedFiles: files,
+	}
+
+	drift, err := VerifyContent(cfg)
+	if err != nil {
+		t.Fatalf("VerifyContent() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, d := range drift {
+		got[d.SourcePath] = d.Kind
+	}
+	want := map[string]string{
+		"~/.bashrc":    ContentDiffers,
+		"~/.vimrc":     ContentDetached,
+		"~/.gitconfig": ContentUnreachable,
+		"~/.tmux.conf": ContentUnreachable,
+		"~/.npmrc":     ContentDiffers,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VerifyContent() = %v, want %v", got, want)
+	}
+}
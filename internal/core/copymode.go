@@ -0,0 +1,200 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// DeployCopy writes a copy-mode file into place from the repo (or its
+// rendered or decrypted output), replacing a symlink left from symlink mode
+func DeployCopy(cfg *config.Config, mf config.ManagedFile) error {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return fmt.Errorf("expanding source path: %w", err)
+	}
+	targetPath, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return err
+	}
+
+	// Copying onto a symlink would write through it into the repo
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink {
+		if err := os.Remove(sourcePath); err != nil {
+			return fmt.Errorf("removing symlink: %w", err)
+		}
+	}
+
+	if err := fs.CopyFile(targetPath, sourcePath); err != nil {
+		return fmt.Errorf("copying from repo: %w", err)
+	}
+	if err := recordDeployed(mf.SourcePath, sourcePath); err != nil {
+		return err
+	}
+	log.Info("deployed copy", "path", sourcePath, "from", targetPath)
+	return nil
+}
+
+// DeployedFile is the name of the manifest in ~/.dotcor mapping each
+// copy-mode file's source path to the SHA-256 of the content last deployed
+// there, so a copy edited locally can be told from one left behind by a
+// repo change. A named repository keeps its own in ~/.dotcor/repos/<name>.
+const DeployedFile = "deployed.json"
+
+// getDeployedPath returns the path to the deployed manifest
+func getDeployedPath() (string, error) {
+	stateDir, err := config.GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, DeployedFile), nil
+}
+
+// readDeployed returns the deployed checksums by source path. A missing
+// manifest has no checksums.
+func readDeployed() (map[string]string, error) {
+	path, err := getDeployedPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading deployed checksums: %w", err)
+	}
+
+	var manifest checksumManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing deployed checksums: %w", err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]string{}
+	}
+	return manifest.Files, nil
+}
+
+// recordDeployed records the content of the copy at path as deployed for
+// sourcePath
+func recordDeployed(sourcePath, path string) error {
+	sum, err := fs.FileChecksum(path)
+	if err != nil {
+		return fmt.Errorf("checksumming %s: %w", path, err)
+	}
+	sums, err := readDeployed()
+	if err != nil {
+		return err
+	}
+	sums[sourcePath] = sum
+
+	manifestPath, err := getDeployedPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(manifestPath), err)
+	}
+	data, err := json.MarshalIndent(checksumManifest{Files: sums}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding deployed checksums: %w", err)
+	}
+	tempPath := manifestPath + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing deployed checksums: %w", err)
+	}
+	if err := os.Rename(tempPath, manifestPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing deployed checksums: %w", err)
+	}
+	return nil
+}
+
+// CopyDiffers reports whether the local copy of a copy-mode file differs
+// from the repo. A missing local copy or a symlink doesn't count as an edit.
+func CopyDiffers(cfg *config.Config, mf config.ManagedFile) (bool, error) {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return false, err
+	}
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink || !fs.FileExists(sourcePath) {
+		return false, nil
+	}
+
+	targetPath, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return false, err
+	}
+	if !fs.FileExists(targetPath) {
+		return false, nil
+	}
+
+	same, err := fs.SameContent(sourcePath, targetPath)
+	if err != nil {
+		return false, err
+	}
+	return !same, nil
+}
+
+// EditedCopies returns copy-mode files whose local copy was edited and can be
+// synced back into the repo. A copy that differs from the repo but still
+// holds what was last deployed is out of date rather than edited: the repo
+// changed, and pulling it back would undo that. Copies deployed before
+// checksums were recorded count as edited when they differ. Templates and
+// secrets are skipped since their repo file isn't what gets copied.
+func EditedCopies(cfg *config.Config) []config.ManagedFile {
+	deployed, err := readDeployed()
+	if err != nil {
+		log.Warn("reading deployed checksums", "err", err)
+		deployed = map[string]string{}
+	}
+
+	var edited []config.ManagedFile
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		if !mf.IsCopy() || mf.IsTemplate() || mf.Encrypted {
+			continue
+		}
+		differs, err := CopyDiffers(cfg, mf)
+		if err != nil || !differs {
+			continue
+		}
+		if sum, ok := deployed[mf.SourcePath]; ok {
+			sourcePath, err := config.ExpandPath(mf.SourcePath)
+			if err != nil {
+				continue
+			}
+			if local, err := fs.FileChecksum(sourcePath); err != nil || local == sum {
+				continue
+			}
+		}
+		edited = append(edited, mf)
+	}
+	return edited
+}
+
+// PullCopyEdits copies a local copy's edits back into the repo
+func PullCopyEdits(cfg *config.Config, mf config.ManagedFile) error {
+	if mf.IsTemplate() || mf.Encrypted {
+		return fmt.Errorf("%s is rendered or decrypted from the repo, edit the repo file instead", mf.SourcePath)
+	}
+
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return fmt.Errorf("expanding source path: %w", err)
+	}
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+
+	if err := fs.CopyFile(sourcePath, repoPath); err != nil {
+		return fmt.Errorf("copying to repo: %w", err)
+	}
+	// The local copy now matches the repo, as if just deployed
+	return recordDeployed(mf.SourcePath, sourcePath)
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestCopyModeRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	mf := config.ManagedFile{SourcePath: "~/.vimrc", RepoPath: "vim/vimrc", Mode: config.DeployModeCopy}
+	cfg := &config.Config{
+		Version:      config.CurrentConfigVersion,
+		RepoPath:     repoDir,
+		ManagedFiles: []config.ManagedFile{mf},
+	}
+
+	repoFile := filepath.Join(repoDir, "vim", "vimrc")
+	if err := os.MkdirAll(filepath.Dir(repoFile), 0755); err != nil {
+		t.Fatalf("failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(repoFile, []byte("set number"), 0644); err != nil {
+		t.Fatalf("failed to create repo file: %v", err)
+	}
+
+	// A symlink left from symlink mode is replaced, not written through
+	sourceFile := filepath.Join(tempDir, ".vimrc")
+	if err := os.Symlink(repoFile, sourceFile); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := DeployCopy(cfg, mf); err != nil {
+		t.Fatalf("DeployCopy() error = %v", err)
+	}
+	if info, err := os.Lstat(sourceFile); err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("DeployCopy() should leave a regular file, got %v, %v", info, err)
+	}
+
+	if differs, err := CopyDiffers(cfg, mf); err != nil || differs {
+		t.Errorf("CopyDiffers() = %v, %v, want false", differs, err)
+	}
+
+	// Edit the local copy and pull it back into the repo
+	if err := os.WriteFile(sourceFile, []byte("set relativenumber"), 0644); err != nil {
+		t.Fatalf("failed to edit copy: %v", err)
+	}
+	if differs, _ := CopyDiffers(cfg, mf); !differs {
+		t.Error("CopyDiffers() should report the local edit")
+	}
+	if edited := EditedCopies(cfg); len(edited) != 1 {
+		t.Fatalf("EditedCopies() = %v, want 1 file", edited)
+	}
+
+	if err := PullCopyEdits(cfg, mf); err != nil {
+		t.Fatalf("PullCopyEdits() error = %v", err)
+	}
+	content, err := os.ReadFile(repoFile)
+	if err != nil || string(content) != "set relativenumber" {
+		t.Errorf("repo file = %q, %v, want the local edit", content, err)
+	}
+	if edited := EditedCopies(cfg); len(edited) != 0 {
+		t.Errorf("EditedCopies() = %v, want none after pulling edits", edited)
+	}
+
+	// A repo change, e.g. from a pull, leaves the copy out of date but not
+	// edited, so it isn't pulled back over the change
+	if err := os.WriteFile(repoFile, []byte("set nonumber"), 0644); err != nil {
+		t.Fatalf("failed to change repo file: %v", err)
+	}
+	if differs, _ := CopyDiffers(cfg, mf); !differs {
+		t.Error("CopyDiffers() should report the repo change")
+	}
+	if edited := EditedCopies(cfg); len(edited) != 0 {
+		t.Errorf("EditedCopies() = %v, want none after a repo change", edited)
+	}
+}
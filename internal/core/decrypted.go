@@ -0,0 +1,172 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/crypto"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// DecryptSecret makes sure the decrypted copy of a secret exists and holds
+// the current ciphertext's plaintext. A copy edited locally is left alone
+// so the edits aren't lost, unless the ciphertext has changed too, which
+// is an error rather than a choice between them. Returns the decrypted path
+// and whether it was written.
+func DecryptSecret(cfg *config.Config, backend crypto.Backend, mf config.ManagedFile) (string, bool, error) {
+	decrypted, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return "", false, err
+	}
+	repoFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return "", false, err
+	}
+
+	if fs.FileExists(decrypted) {
+		stale, edited, err := CheckDecrypted(mf.RepoPath, repoFile, decrypted)
+		if err != nil {
+			return "", false, err
+		}
+		if !stale {
+			return decrypted, false, nil
+		}
+		if edited {
+			return "", false, fmt.Errorf("%s changed in the repository and its decrypted copy %s was edited too; move your edits aside and run again", mf.RepoPath, decrypted)
+		}
+	}
+
+	if err := EnsureSecretsDir(); err != nil {
+		return "", false, err
+	}
+	if err := crypto.DecryptFile(backend, repoFile, decrypted); err != nil {
+		return "", false, err
+	}
+	if err := RecordDecrypted(mf.RepoPath, repoFile, decrypted); err != nil {
+		return "", false, err
+	}
+	return decrypted, true, nil
+}
+
+// EnsureSecretsDir creates the secrets directory, accessible only by this user
+func EnsureSecretsDir() error {
+	secretsDir, err := config.GetSecretsDir()
+	if err != nil {
+		return err
+	}
+	if err := fs.EnsureDir(secretsDir); err != nil {
+		return fmt.Errorf("creating secrets directory: %w", err)
+	}
+	if err := os.Chmod(secretsDir, 0700); err != nil {
+		return fmt.Errorf("restricting secrets directory: %w", err)
+	}
+	return nil
+}
+
+// DecryptedFile is the name of the manifest in the secrets directory
+// recording, for each secret's repo path, the checksums of the ciphertext
+// last decrypted and of the plaintext it gave. A decrypted copy left from
+// older ciphertext, e.g. before a pull, can then be told from a local edit.
+const DecryptedFile = ".decrypted.json"
+
+// decryptRecord is what was last decrypted for a secret
+type decryptRecord struct {
+	Ciphertext string `json:"ciphertext"`
+	Plaintext  string `json:"plaintext"`
+}
+
+// getDecryptedPath returns the path to the decrypted manifest
+func getDecryptedPath() (string, error) {
+	secretsDir, err := config.GetSecretsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(secretsDir, DecryptedFile), nil
+}
+
+// readDecrypted returns the decrypt records by repo path. A missing
+// manifest has no records.
+func readDecrypted() (map[string]decryptRecord, error) {
+	path, err := getDecryptedPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]decryptRecord{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading decrypted checksums: %w", err)
+	}
+
+	records := map[string]decryptRecord{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("parsing decrypted checksums: %w", err)
+	}
+	return records, nil
+}
+
+// RecordDecrypted records that decrypted holds the plaintext of repoFile,
+// the ciphertext stored at repoPath
+func RecordDecrypted(repoPath, repoFile, decrypted string) error {
+	cipherSum, err := fs.FileChecksum(repoFile)
+	if err != nil {
+		return fmt.Errorf("checksumming %s: %w", repoFile, err)
+	}
+	plainSum, err := fs.FileChecksum(decrypted)
+	if err != nil {
+		return fmt.Errorf("checksumming %s: %w", decrypted, err)
+	}
+
+	records, err := readDecrypted()
+	if err != nil {
+		return err
+	}
+	records[repoPath] = decryptRecord{Ciphertext: cipherSum, Plaintext: plainSum}
+
+	path, err := getDecryptedPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding decrypted checksums: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("writing decrypted checksums: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing decrypted checksums: %w", err)
+	}
+	return nil
+}
+
+// CheckDecrypted compares a secret's decrypted copy with what was recorded
+// when it was decrypted. stale is true when the ciphertext has changed
+// since, so the copy must be decrypted again; edited is true when the copy
+// was changed locally. A secret with no record is neither.
+func CheckDecrypted(repoPath, repoFile, decrypted string) (stale, edited bool, err error) {
+	records, err := readDecrypted()
+	if err != nil {
+		return false, false, err
+	}
+	record, ok := records[repoPath]
+	if !ok {
+		return false, false, nil
+	}
+
+	cipherSum, err := fs.FileChecksum(repoFile)
+	if err != nil {
+		return false, false, fmt.Errorf("checksumming %s: %w", repoFile, err)
+	}
+	plainSum, err := fs.FileChecksum(decrypted)
+	if err != nil {
+		return false, false, fmt.Errorf("checksumming %s: %w", decrypted, err)
+	}
+	return cipherSum != record.Ciphertext, plainSum != record.Plaintext, nil
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// rot13Backend is a stand-in for age or gpg that needs no keys
+type rot13Backend struct{}
+
+func (rot13Backend) Name() string { return "rot13" }
+func (rot13Backend) Ext() string  { return ".age" }
+
+func (rot13Backend) Encrypt(plaintext []byte) ([]byte, error) {
+	return bytes.Map(rot13, plaintext), nil
+}
+
+func (rot13Backend) Decrypt(ciphertext []byte) ([]byte, error) {
+	return bytes.Map(rot13, ciphertext), nil
+}
+
+func rot13(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 'a' + (r-'a'+13)%26
+	case r >= 'A' && r <= 'Z':
+		return 'A' + (r-'A'+13)%26
+	}
+	return r
+}
+
+func TestDecryptSecret(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	mf := config.ManagedFile{SourcePath: "~/.netrc", RepoPath: "netrc.age", Encrypted: true}
+	cfg := &config.Config{
+		Version:      config.CurrentConfigVersion,
+		RepoPath:     filepath.Join(tempDir, ".dotcor", "files"),
+		ManagedFiles: []config.ManagedFile{mf},
+	}
+	backend := rot13Backend{}
+	encrypt := func(plaintext string) {
+		t.Helper()
+		ciphertext, _ := backend.Encrypt([]byte(plaintext))
+		writeRepoFile(t, cfg, mf.RepoPath, string(ciphertext))
+	}
+	readDecrypted := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	encrypt("login old")
+	decrypted, written, err := DecryptSecret(cfg, backend, mf)
+	if err != nil || !written {
+		t.Fatalf("DecryptSecret() = %v, %v, want written", written, err)
+	}
+	if got := readDecrypted(decrypted); got != "login old" {
+		t.Errorf("decrypted = %q", got)
+	}
+
+	// A local edit of an up-to-date copy is kept
+	if err := os.WriteFile(decrypted, []byte("login edited"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, written, err := DecryptSecret(cfg, backend, mf); err != nil || written {
+		t.Errorf("DecryptSecret() of an edited copy = %v, %v, want kept", written, err)
+	}
+
+	// New ciphertext, e.g. from a pull, replaces an unedited copy
+	if err := os.WriteFile(decrypted, []byte("login old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	encrypt("login new")
+	if _, written, err := DecryptSecret(cfg, backend, mf); err != nil || !written {
+		t.Fatalf("DecryptSecret() of a stale copy = %v, %v, want written", written, err)
+	}
+	if got := readDecrypted(decrypted); got != "login new" {
+		t.Errorf("decrypted = %q, want the new plaintext", got)
+	}
+
+	// New ciphertext and a local edit together are a conflict
+	if err := os.WriteFile(decrypted, []byte("login edited"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	encrypt("login newer")
+	if _, _, err := DecryptSecret(cfg, backend, mf); err == nil {
+		t.Error("DecryptSecret() with both changed should return error")
+	}
+	if got := readDecrypted(decrypted); got != "login edited" {
+		t.Errorf("decrypted = %q, want the edit kept", got)
+	}
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// DeleteUserFile removes a user file according to the deletion setting.
+// Files go to the OS trash unless deletion is "delete" or the platform has
+// no trash, in which case they are removed permanently.
+// Returns the path inside the trash, or "" if the file was deleted.
+func DeleteUserFile(cfg *config.Config, path string) (string, error) {
+	if cfg != nil && cfg.UseTrash() {
+		trashPath, err := fs.MoveToTrash(path)
+		if err == nil {
+			log.Info("moved file to trash", "path", path, "trash", trashPath)
+			return trashPath, nil
+		}
+		if !errors.Is(err, fs.ErrTrashUnavailable) {
+			return "", err
+		}
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return "", fmt.Errorf("removing %s: %w", path, err)
+	}
+	log.Info("deleted file", "path", path)
+	return "", nil
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// DefaultDiscoverDepth is how many levels below home FindDotfiles looks by
+// default, enough to reach ~/.config/app/config
+const DefaultDiscoverDepth = 3
+
+// DefaultDiscoverMaxSize is the default size in bytes above which a file is
+// taken for data rather than configuration
+const DefaultDiscoverMaxSize = 256 * 1024
+
+// discoverSkipDirs are directories holding caches, installed packages and
+// application state rather than configuration. They are skipped wherever
+// they appear.
+var discoverSkipDirs = map[string]bool{
+	".cache": true, ".local": true, ".var": true, ".Trash": true,
+	".git": true, "node_modules": true, "__pycache__": true,
+	".npm": true, ".yarn": true, ".pnpm-store": true, ".bun": true, ".deno": true,
+	".cargo": true, ".rustup": true, ".gradle": true, ".m2": true, ".ivy2": true,
+	".nvm": true, ".pyenv": true, ".rbenv": true, ".sdkman": true, ".conda": true,
+	".vscode": true, ".vscode-server": true, ".cursor": true,
+	".mozilla": true, ".thunderbird": true, ".steam": true, ".wine": true,
+	".pki": true, ".dbus": true,
+	"Cache": true, "CachedData": true, "GPUCache": true, "logs": true,
+}
+
+// discoverSkipFiles are name patterns of files programs write for
+// themselves, which aren't worth managing
+var discoverSkipFiles = []string{
+	".zcompdump*", ".viminfo", ".wget-hsts", ".xsession-errors*",
+	".sudo_as_admin_successful", ".Xauthority", ".ICEauthority",
+	"*.lock", "*.pid", "*.sock",
+}
+
+// DiscoverOptions bounds FindDotfiles
+type DiscoverOptions struct {
+	MaxDepth int   // Levels below home to look, DefaultDiscoverDepth if 0
+	MaxSize  int64 // Larger files are skipped, DefaultDiscoverMaxSize if 0
+}
+
+// Dotfile is an unmanaged file found by FindDotfiles
+type Dotfile struct {
+	Path string // Home-relative path with "~/", as stored in config
+	Size int64
+}
+
+// FindDotfiles walks the hidden files and directories of the home directory
+// for plausible dotfiles that aren't managed yet. Symlinks, binary and empty
+// files, files over the size cap and anything matching the ignore patterns
+// are skipped, as are the config directory, the repository and the files
+// of sensitive directories that aren't safe to manage. Entries that can't
+// be read are skipped rather than failing the walk; canceling ctx stops it.
+func FindDotfiles(ctx context.Context, cfg *config.Config, opts DiscoverOptions) ([]Dotfile, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultDiscoverDepth
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultDiscoverMaxSize
+	}
+
+	home, err := config.HomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("getting home directory: %w", err)
+	}
+
+	// DotCor's own directories would otherwise look like dotfiles
+	skipPaths := map[string]bool{}
+	if dir, err := config.GetConfigDir(); err == nil {
+		skipPaths[filepath.Clean(dir)] = true
+	}
+	if root, err := config.GetFilesRoot(cfg); err == nil {
+		skipPaths[filepath.Clean(root)] = true
+	}
+	if repo, err := config.ExpandPath(cfg.RepoPath); err == nil && cfg.RepoPath != "" {
+		skipPaths[filepath.Clean(repo)] = true
+	}
+
+	var found []Dotfile
+	err = filepath.WalkDir(home, func(p string, d fs.DirEntry, err error) error {
+		if p == home {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(home, p)
+		if err != nil {
+			return nil
+		}
+		depth := strings.Count(rel, string(filepath.Separator)) + 1
+
+		// Only hidden entries directly in home hold dotfiles
+		if depth == 1 && !strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if depth >= opts.MaxDepth || discoverSkipDirs[d.Name()] || skipPaths[p] {
+				return filepath.SkipDir
+			}
+			if ignored, _ := ShouldIgnore(p, cfg.IgnorePatterns); ignored {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || skipDiscoveredFile(d.Name()) {
+			return nil
+		}
+		// Key material is never offered, only the safe files next to it
+		if sensitive, ok := SensitiveDirFor(p); ok && !sensitive.IsSafe(p) {
+			return nil
+		}
+		if ignored, _ := ShouldIgnore(p, cfg.IgnorePatterns); ignored {
+			return nil
+		}
+		source := "~/" + filepath.ToSlash(rel)
+		if cfg.IsManaged(source) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > opts.MaxSize {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil || isBinary(content) {
+			return nil
+		}
+
+		found = append(found, Dotfile{Path: source, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", home, err)
+	}
+	return found, nil
+}
+
+// skipDiscoveredFile reports whether name matches discoverSkipFiles
+func skipDiscoveredFile(name string) bool {
+	for _, pattern := range discoverSkipFiles {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestFindDotfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	files := map[string]string{
+		".zshrc":                          "export EDITOR=nvim\n",
+		".gitconfig":                      "[user]\n\tname = Test\n",
+		".config/nvim/init.lua":           "vim.o.number = true\n",
+		".config/nvim/lua/plugins.lua":    "return {}\n", // Too deep
+		".cache/app/settings":             "cached\n",
+		".bash_history":                   "ls\n", // Ignored by default
+		".zcompdump":                      "#files: 1\n",
+		".empty":                          "",
+		".big":                            strings.Repeat("x", 2048),
+		".dotcor/config.yaml":             "version: 1\n",
+		".dotcor/files/shell/bashrc":      "alias ll='ls -l'\n",
+		".config/app/state.bin":           "\x00\x01\x02",
+		"Documents/notes.txt":             "not a dotfile\n",
+		".config/managed/config.toml":     "managed = true\n",
+		".config/alacritty/alacritty.yml": "font:\n  size: 12\n",
+		".ssh/config":                     "Host *\n",
+		".ssh/id_ed25519.pub":             "ssh-ed25519 AAAA\n", // Key material
+		".gnupg/gpg.conf":                 "use-agent\n",
+		".gnupg/sshcontrol.bak":           "keygrip\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Deployed files are symlinks into the repository
+	if err := os.Symlink(filepath.Join(tempDir, ".dotcor/files/shell/bashrc"), filepath.Join(tempDir, ".bashrc")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Version:        config.CurrentConfigVersion,
+		RepoPath:       filepath.Join(tempDir, ".dotcor", "files"),
+		IgnorePatterns: config.GetDefaultIgnorePatterns(),
+		ManagedFiles: []config.ManagedFile{
+			{SourcePath: "~/.config/managed/config.toml", RepoPath: "managed/config.toml", Mode: config.DeployModeCopy},
+		},
+	}
+
+	found, err := FindDotfiles(context.Background(), cfg, DiscoverOptions{MaxSize: 1024})
+	if err != nil {
+		t.Fatalf("FindDotfiles() error = %v", err)
+	}
+
+	var paths []string
+	for _, f := range found {
+		paths = append(paths, f.Path)
+	}
+	want := []string{
+		"~/.config/alacritty/alacritty.yml",
+		"~/.config/nvim/init.lua",
+		"~/.gitconfig",
+		"~/.gnupg/gpg.conf",
+		"~/.ssh/config",
+		"~/.zshrc",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("FindDotfiles() = %v, want %v", paths, want)
+	}
+
+	// A deeper scan reaches nested config
+	found, err = FindDotfiles(context.Background(), cfg, DiscoverOptions{MaxDepth: 4, MaxSize: 1024})
+	if err != nil {
+		t.Fatalf("FindDotfiles() error = %v", err)
+	}
+	if len(found) != len(want)+1 {
+		t.Errorf("FindDotfiles(MaxDepth: 4) found %d files, want %d", len(found), len(want)+1)
+	}
+}
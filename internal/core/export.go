@@ -0,0 +1,489 @@
+package core
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/crypto"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/template"
+)
+
+// Layout of an export archive: a gzipped tar with everything under one
+// top-level directory
+const (
+	ExportDir        = "dotcor"
+	ExportFilesDir   = "files"       // The repository, .git included
+	ExportConfigFile = "config.yaml" // The config of the exported repository
+	ExportScriptFile = "apply.sh"    // Links the files without dotcor, if requested
+)
+
+// ErrNotExportArchive is returned when an archive wasn't written by
+// ExportArchive
+var ErrNotExportArchive = errors.New("not a dotcor export archive")
+
+// ExportOptions configures ExportArchive
+type ExportOptions struct {
+	Script bool // Include apply.sh, for machines without dotcor
+}
+
+// ExportArchive writes cfg's repository, history included, and its config
+// to w as a gzipped tar archive. ExtractArchive unpacks it on a machine
+// that can't reach the git remote.
+func ExportArchive(cfg *config.Config, w io.Writer, opts ExportOptions) error {
+	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	if err != nil {
+		return fmt.Errorf("invalid repo path: %w", err)
+	}
+	if _, err := os.Stat(repoPath); err != nil {
+		return fmt.Errorf("reading repository: %w", err)
+	}
+
+	// Only the selected repository goes along
+	exported := *cfg
+	exported.Repositories = nil
+	configData, err := yaml.Marshal(&exported)
+	if err != nil {
+		return fmt.Errorf("marshaling config: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: ExportDir + "/", Mode: 0755, ModTime: now}); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	if err := writeArchiveFile(tw, path.Join(ExportDir, ExportConfigFile), configData, 0644, now); err != nil {
+		return err
+	}
+	if opts.Script {
+		if err := writeArchiveFile(tw, path.Join(ExportDir, ExportScriptFile), applyScript(cfg), 0755, now); err != nil {
+			return err
+		}
+	}
+
+	err = filepath.WalkDir(repoPath, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(repoPath, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(ExportDir, ExportFilesDir, filepath.ToSlash(rel))
+		return addTreeEntry(tw, p, name)
+	})
+	if err != nil {
+		return fmt.Errorf("archiving repository: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	return nil
+}
+
+// writeArchiveFile adds a regular file with the given content to tw
+func writeArchiveFile(tw *tar.Writer, name string, data []byte, mode int64, modTime time.Time) error {
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: mode, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("archiving %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("archiving %s: %w", name, err)
+	}
+	return nil
+}
+
+// addTreeEntry adds the directory, symlink or regular file at p to tw as
+// name. Symlinks are stored as links, not followed.
+func addTreeEntry(tw *tar.Writer, p, name string) error {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+
+	var link string
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	case info.IsDir(), info.Mode().IsRegular():
+	default:
+		return nil // Sockets and the like don't belong in a repository
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("archiving %s: %w", p, err)
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	// Owners mean nothing on the machine the archive is unpacked on
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("archiving %s: %w", p, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("archiving %s: %w", p, err)
+	}
+	return nil
+}
+
+// ExtractArchive unpacks an archive written by ExportArchive into dir,
+// which must not exist yet or be empty. Returns the paths of the unpacked
+// repository and config. Entries that would land outside dir are refused.
+func ExtractArchive(r io.Reader, dir string) (filesDir, configPath string, err error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrNotExportArchive, err)
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+
+	// Links are made last, so no file is written through one
+	type symlink struct{ target, path string }
+	var links []symlink
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("reading archive: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return "", "", fmt.Errorf("refusing archive entry outside the destination: %s", header.Name)
+		}
+		if name != ExportDir && !strings.HasPrefix(name, ExportDir+"/") {
+			return "", "", fmt.Errorf("%w: unexpected entry %s", ErrNotExportArchive, header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", "", err
+			}
+		case tar.TypeSymlink:
+			links = append(links, symlink{header.Linkname, target})
+		case tar.TypeReg:
+			if err := extractFile(tr, target, header.FileInfo().Mode().Perm()); err != nil {
+				return "", "", err
+			}
+		}
+	}
+
+	for _, l := range links {
+		if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+			return "", "", err
+		}
+		if err := os.Symlink(l.target, l.path); err != nil {
+			return "", "", err
+		}
+	}
+
+	filesDir = filepath.Join(dir, ExportDir, ExportFilesDir)
+	configPath = filepath.Join(dir, ExportDir, ExportConfigFile)
+	if _, err := os.Stat(configPath); err != nil {
+		return "", "", fmt.Errorf("%w: no %s", ErrNotExportArchive, ExportConfigFile)
+	}
+	if info, err := os.Stat(filesDir); err != nil || !info.IsDir() {
+		return "", "", fmt.Errorf("%w: no %s directory", ErrNotExportArchive, ExportFilesDir)
+	}
+	return filesDir, configPath, nil
+}
+
+// extractFile writes the content read from r to path with perm
+func extractFile(r io.Reader, path string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("extracting %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// applyScriptHeader starts apply.sh. place links, copies or hard links a
+// file from the repository into $HOME, moving whatever is in the way aside
+// unless it has the same content. Earlier .pre-dotcor files are kept.
+const applyScriptHeader = `#!/bin/sh
+# Deploys the dotfiles in this archive without dotcor.
+# Files in the way are moved aside with a .pre-dotcor suffix, numbered if
+# one is there already. Files with the same content are just replaced.
+# With dotcor installed, use 'dotcor import tarball' instead.
+set -e
+
+root="$(cd "$(dirname "$0")" && pwd)/%s"
+os="$(uname -s | tr '[:upper:]' '[:lower:]')"
+
+on() {
+	for p in "$@"; do
+		[ "$p" = "$os" ] && return 0
+	done
+	return 1
+}
+
+place() {
+	src="$root/$1"
+	dst="$HOME/$2"
+	if [ -L "$dst" ] && [ "$(readlink "$dst")" = "$src" ]; then
+		return 0
+	fi
+	if [ -f "$dst" ] && [ ! -L "$dst" ] && cmp -s "$src" "$dst"; then
+		case "$3" in
+		copy) return 0 ;;
+		hardlink) [ "$dst" -ef "$src" ] && return 0 ;;
+		esac
+		rm "$dst"
+	fi
+	mkdir -p "$(dirname "$dst")"
+	if [ -e "$dst" ] || [ -L "$dst" ]; then
+		bak="$dst.pre-dotcor"
+		n=1
+		while [ -e "$bak" ] || [ -L "$bak" ]; do
+			bak="$dst.pre-dotcor.$n"
+			n=$((n + 1))
+		done
+		mv "$dst" "$bak"
+	fi
+	case "$3" in
+	copy) cp -p "$src" "$dst" ;;
+	hardlink) ln "$src" "$dst" 2>/dev/null || cp -p "$src" "$dst" ;;
+	*) ln -s "$src" "$dst" ;;
+	esac
+	echo "  ~/$2"
+}
+
+`
+
+// applyScript returns a POSIX shell script that deploys cfg's managed files
+// from the unpacked archive. Templates, secrets, composed files and system
+// files need dotcor, so they're listed as skipped instead.
+func applyScript(cfg *config.Config) []byte {
+	filesRoot := ExportFilesDir
+	if cfg.FilesSubdir != "" {
+		filesRoot = path.Join(filesRoot, filepath.ToSlash(cfg.FilesSubdir))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, applyScriptHeader, filesRoot)
+
+	var skipped []string
+	for _, mf := range cfg.ManagedFiles {
+		home, ok := strings.CutPrefix(mf.SourcePath, "~/")
+		switch {
+		case !ok:
+			skipped = append(skipped, mf.SourcePath+" (outside the home directory)")
+			continue
+		case mf.IsTemplate():
+			skipped = append(skipped, mf.SourcePath+" (template)")
+			continue
+		case mf.Encrypted:
+			skipped = append(skipped, mf.SourcePath+" (secret)")
+			continue
+		case mf.IsComposed():
+			// The generated file includes paths on the machine dotcor runs on
+			skipped = append(skipped, mf.SourcePath+" (compose)")
+			continue
+		}
+
+		mode := config.DeployModeSymlink
+		if mf.Mode != "" {
+			mode = mf.Mode
+		}
+		place := fmt.Sprintf("place %s %s %s", quoteShell(mf.RepoPath), quoteShell(home), mode)
+		if len(mf.Platforms) > 0 {
+			var platforms []string
+			for _, p := range mf.Platforms {
+				platforms = append(platforms, quoteShell(p))
+			}
+			place = fmt.Sprintf("on %s && %s", strings.Join(platforms, " "), place)
+		}
+		b.WriteString(place + "\n")
+	}
+	for _, sf := range cfg.SystemFiles {
+		skipped = append(skipped, sf.SourcePath+" (system file)")
+	}
+
+	b.WriteString("echo \"Done.\"\n")
+
+	if len(skipped) > 0 {
+		b.WriteString("echo \"Skipped, deploy these with dotcor:\"\n")
+		for _, s := range skipped {
+			fmt.Fprintf(&b, "echo %s\n", quoteShell("  "+s))
+		}
+	}
+	return []byte(b.String())
+}
+
+// quoteShell quotes s for a POSIX shell
+func quoteShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// TreeOptions configures ExportTree
+type TreeOptions struct {
+	Secrets bool // Decrypt secrets into the tree; by default they're left out
+}
+
+// TreeFile is a managed file written by ExportTree, or left out of the tree
+type TreeFile struct {
+	SourcePath string // In ~ notation
+	Path       string // Relative to the tree directory, "" if left out
+	Skipped    string // Why the file was left out
+}
+
+// ExportTree writes the files a fresh apply would deploy for this platform
+// into dir, as real files at their paths relative to the home directory:
+// templates rendered, variants resolved and permissions set. $HOME isn't
+// touched. dir must be empty or not exist yet. Secrets are decrypted only
+// with opts.Secrets, and system files are left out.
+func ExportTree(cfg *config.Config, dir string, opts TreeOptions) ([]TreeFile, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+	home, err := config.HomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var data *template.Data
+	var backend crypto.Backend
+	var files []TreeFile
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		file := TreeFile{SourcePath: mf.SourcePath}
+		skip := func(reason string) {
+			file.Skipped = reason
+			files = append(files, file)
+		}
+
+		mf, err := config.ResolveVariant(mf)
+		if err != nil {
+			return files, err
+		}
+		sourcePath, err := config.ExpandPath(mf.SourcePath)
+		if err != nil {
+			return files, fmt.Errorf("invalid path %s: %w", mf.SourcePath, err)
+		}
+		rel, err := filepath.Rel(home, sourcePath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			skip("outside the home directory")
+			continue
+		}
+		repoFile, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+		if err != nil {
+			return files, fmt.Errorf("invalid repo path %s: %w", mf.RepoPath, err)
+		}
+		if !fs.FileExists(repoFile) {
+			skip("not in repository")
+			continue
+		}
+		if mf.Encrypted && !opts.Secrets {
+			skip("secret")
+			continue
+		}
+
+		dest := filepath.Join(dir, rel)
+		switch {
+		case mf.IsTemplate():
+			if data == nil {
+				d, err := template.NewData(cfg)
+				if err != nil {
+					return files, fmt.Errorf("collecting template variables: %w", err)
+				}
+				data = &d
+			}
+			err = writeTreeFile(repoFile, dest, func(content []byte) ([]byte, error) {
+				return template.Render(mf.RepoPath, content, *data)
+			})
+		case mf.Encrypted:
+			if backend == nil {
+				if backend, err = crypto.NewBackend(cfg.Secrets); err != nil {
+					return files, err
+				}
+			}
+			err = writeTreeFile(repoFile, dest, backend.Decrypt)
+			if err == nil {
+				err = os.Chmod(dest, 0600)
+			}
+		default:
+			err = fs.CopyFile(repoFile, dest)
+		}
+		if err != nil {
+			return files, fmt.Errorf("writing %s: %w", mf.SourcePath, err)
+		}
+
+		if perm, ok, err := mf.FilePerm(); err != nil {
+			return files, err
+		} else if ok {
+			if err := os.Chmod(dest, perm); err != nil {
+				return files, fmt.Errorf("setting permissions on %s: %w", dest, err)
+			}
+		}
+
+		file.Path = rel
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// writeTreeFile writes src's content, passed through transform, to dst
+// with src's permissions
+func writeTreeFile(src, dst string, transform func([]byte) ([]byte, error)) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if content, err = transform(content); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, content, info.Mode().Perm())
+}
@@ -0,0 +1,245 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestExportArchiveRoundTrip(t *testing.T) {
+	cfg := setupChecksumTest(t)
+	cfg.ManagedFiles = append(cfg.ManagedFiles,
+		config.ManagedFile{SourcePath: "~/.gitconfig", RepoPath: "git/gitconfig", Mode: config.DeployModeCopy},
+		config.ManagedFile{SourcePath: "~/.aws/credentials", RepoPath: "aws/credentials", Encrypted: true},
+	)
+	cfg.Repositories = map[string]config.RepoConfig{"work": {RepoPath: "~/work"}}
+	writeRepoFile(t, cfg, ".git/HEAD", "ref: refs/heads/main\n")
+	if err := os.Symlink("zshrc", filepath.Join(cfg.RepoPath, "shell", "zshrc.link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(cfg.RepoPath, "vim", "vimrc"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportArchive(cfg, &buf, ExportOptions{Script: true}); err != nil {
+		t.Fatalf("ExportArchive() error = %v", err)
+	}
+
+	dest := t.TempDir()
+	filesDir, configPath, err := ExtractArchive(&buf, dest)
+	if err != nil {
+		t.Fatalf("ExtractArchive() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(filesDir, "shell", "zshrc"))
+	if err != nil || string(data) != "original shell/zshrc" {
+		t.Errorf("shell/zshrc = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(filesDir, ".git", "HEAD")); err != nil {
+		t.Errorf(".git should be exported: %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(filesDir, "shell", "zshrc.link")); err != nil || target != "zshrc" {
+		t.Errorf("shell/zshrc.link = %q, %v, want a link to zshrc", target, err)
+	}
+	if info, err := os.Stat(filepath.Join(filesDir, "vim", "vimrc")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("vim/vimrc mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	exported, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("loading exported config: %v", err)
+	}
+	if len(exported.ManagedFiles) != 4 || len(exported.Repositories) != 0 {
+		t.Errorf("exported config = %+v, want the selected repository only", exported)
+	}
+
+	script, err := os.ReadFile(filepath.Join(dest, ExportDir, ExportScriptFile))
+	if err != nil {
+		t.Fatalf("reading %s: %v", ExportScriptFile, err)
+	}
+	for _, want := range []string{
+		"place 'shell/zshrc' '.zshrc' symlink",
+		"place 'git/gitconfig' '.gitconfig' copy",
+		"~/.aws/credentials (secret)",
+	} {
+		if !strings.Contains(string(script), want) {
+			t.Errorf("%s missing %q", ExportScriptFile, want)
+		}
+	}
+}
+
+func TestApplyScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("apply.sh needs a POSIX shell")
+	}
+	cfg := setupChecksumTest(t)
+	cfg.ManagedFiles[1].Mode = config.DeployModeCopy
+	cfg.ManagedFiles = append(cfg.ManagedFiles,
+		config.ManagedFile{SourcePath: "~/.tmux.conf", RepoPath: "tmux/tmux.conf", Platforms: []string{"plan9; rm -rf /"}},
+		config.ManagedFile{SourcePath: "~/.bashrc", RepoPath: "shell/bashrc", Mode: config.DeployModeCompose},
+	)
+	writeRepoFile(t, cfg, "tmux/tmux.conf", "set -g mouse on")
+	writeRepoFile(t, cfg, "shell/bashrc", "shared")
+
+	var buf bytes.Buffer
+	if err := ExportArchive(cfg, &buf, ExportOptions{Script: true}); err != nil {
+		t.Fatalf("ExportArchive() error = %v", err)
+	}
+	dest := t.TempDir()
+	if _, _, err := ExtractArchive(&buf, dest); err != nil {
+		t.Fatalf("ExtractArchive() error = %v", err)
+	}
+
+	// ~/.zshrc is in the way with a backup of its own; ~/.vimrc is a copy
+	// with the repo's content already
+	home := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(home, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".zshrc", "mine")
+	write(".zshrc.pre-dotcor", "older")
+	write(".vimrc", "original vim/vimrc")
+
+	write(".bashrc", "mine")
+
+	cmd := exec.Command("sh", filepath.Join(dest, ExportDir, ExportScriptFile))
+	cmd.Env = append(os.Environ(), "HOME="+home)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("apply.sh error = %v\n%s", err, out)
+	}
+
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(home, name))
+		return string(data)
+	}
+	if got := read(".zshrc.pre-dotcor"); got != "older" {
+		t.Errorf(".zshrc.pre-dotcor = %q, want the earlier backup kept", got)
+	}
+	if got := read(".zshrc.pre-dotcor.1"); got != "mine" {
+		t.Errorf(".zshrc.pre-dotcor.1 = %q, want the file that was in the way", got)
+	}
+	if _, err := os.Lstat(filepath.Join(home, ".vimrc.pre-dotcor")); !os.IsNotExist(err) {
+		t.Error("~/.vimrc with the same content was moved aside")
+	}
+	if _, err := os.Lstat(filepath.Join(home, ".tmux.conf")); !os.IsNotExist(err) {
+		t.Error("~/.tmux.conf was placed on another platform")
+	}
+
+	// A composed file is left for dotcor rather than linked to the shared part
+	if got := read(".bashrc"); got != "mine" {
+		t.Errorf("~/.bashrc = %q, want it left alone", got)
+	}
+	if !strings.Contains(string(out), "~/.bashrc (compose)") {
+		t.Errorf("apply.sh output doesn't list ~/.bashrc as skipped:\n%s", out)
+	}
+}
+
+func TestExportArchiveWithoutScript(t *testing.T) {
+	cfg := setupChecksumTest(t)
+
+	var buf bytes.Buffer
+	if err := ExportArchive(cfg, &buf, ExportOptions{}); err != nil {
+		t.Fatalf("ExportArchive() error = %v", err)
+	}
+	dest := t.TempDir()
+	if _, _, err := ExtractArchive(&buf, dest); err != nil {
+		t.Fatalf("ExtractArchive() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, ExportDir, ExportScriptFile)); !os.IsNotExist(err) {
+		t.Errorf("%s should only be written with Script", ExportScriptFile)
+	}
+}
+
+func TestExtractArchiveRefusesEscapes(t *testing.T) {
+	for _, name := range []string{"../evil", "/etc/evil", "dotcor/../../evil"} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Close()
+		gz.Close()
+
+		dest := t.TempDir()
+		if _, _, err := ExtractArchive(&buf, dest); err == nil {
+			t.Errorf("ExtractArchive() accepted %q", name)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "evil")); err == nil {
+			t.Errorf("%q was written outside the destination", name)
+		}
+	}
+}
+
+func TestExtractArchiveRejectsOtherArchives(t *testing.T) {
+	if _, _, err := ExtractArchive(strings.NewReader("not gzip"), t.TempDir()); !errors.Is(err, ErrNotExportArchive) {
+		t.Errorf("ExtractArchive() error = %v, want ErrNotExportArchive", err)
+	}
+}
+
+func TestExportTree(t *testing.T) {
+	cfg := setupChecksumTest(t)
+	cfg.ManagedFiles = append(cfg.ManagedFiles,
+		config.ManagedFile{SourcePath: "~/.config/git/config", RepoPath: "git/config.tmpl", Perm: "600"},
+		config.ManagedFile{SourcePath: "~/.aws/credentials", RepoPath: "aws/credentials.age", Encrypted: true},
+		config.ManagedFile{SourcePath: "~/.missing", RepoPath: "missing"},
+	)
+	writeRepoFile(t, cfg, "git/config.tmpl", "[user]\n\tname = {{ .User }}\n")
+	writeRepoFile(t, cfg, "aws/credentials.age", "ciphertext")
+
+	dir := filepath.Join(t.TempDir(), "tree")
+	files, err := ExportTree(cfg, dir, TreeOptions{})
+	if err != nil {
+		t.Fatalf("ExportTree() error = %v", err)
+	}
+
+	skipped := map[string]string{}
+	for _, f := range files {
+		if f.Skipped != "" {
+			skipped[f.SourcePath] = f.Skipped
+		}
+	}
+	if skipped["~/.aws/credentials"] != "secret" || skipped["~/.missing"] != "not in repository" || len(skipped) != 2 {
+		t.Errorf("skipped = %v", skipped)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ".zshrc"))
+	if err != nil || string(data) != "original shell/zshrc" {
+		t.Errorf(".zshrc = %q, %v", data, err)
+	}
+	if info, err := os.Lstat(filepath.Join(dir, ".zshrc")); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		t.Error(".zshrc should be a real file")
+	}
+
+	rendered := filepath.Join(dir, ".config", "git", "config")
+	data, err = os.ReadFile(rendered)
+	if err != nil || strings.Contains(string(data), "{{") {
+		t.Errorf("template not rendered: %q, %v", data, err)
+	}
+	if info, err := os.Stat(rendered); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("rendered template mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	// Nothing is deployed in the home directory itself
+	if _, err := os.Lstat(filepath.Join(filepath.Dir(filepath.Dir(cfg.RepoPath)), ".zshrc")); !os.IsNotExist(err) {
+		t.Errorf("ExportTree() touched the home directory: %v", err)
+	}
+
+	if _, err := ExportTree(cfg, dir, TreeOptions{}); err == nil {
+		t.Error("ExportTree() should refuse a directory that isn't empty")
+	}
+}
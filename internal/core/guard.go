@@ -0,0 +1,189 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// EnvironmentOverrides disables individual environment checks
+type EnvironmentOverrides struct {
+	AllowAnyHome  bool // $HOME unset or "/"
+	AllowReadOnly bool // Config directory on a read-only filesystem
+	AllowRoot     bool // Running as root with a config owned by another user
+	AllowTempRepo bool // Repository inside a temp directory
+}
+
+// CheckEnvironment refuses mutating operations when the environment looks wrong.
+// repoPath is the repository the operation will write to.
+// Each failed check names the flag that overrides it.
+func CheckEnvironment(repoPath string, overrides EnvironmentOverrides) error {
+	if !overrides.AllowAnyHome {
+		if err := checkHome(); err != nil {
+			return err
+		}
+	}
+
+	dirs, err := dotcorDirs()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		if !overrides.AllowReadOnly && isReadOnly(dir) {
+			return fmt.Errorf("config directory %s is on a read-only filesystem (use --allow-read-only to override)", dir)
+		}
+
+		if !overrides.AllowRoot {
+			if owner := foreignOwner(dir); owner != "" {
+				return fmt.Errorf("running as root but %s belongs to %s; files would become root-owned (use --allow-root to override)", dir, owner)
+			}
+		}
+	}
+
+	if !overrides.AllowTempRepo && repoPath != "" {
+		expanded, err := config.ExpandPath(repoPath)
+		if err != nil {
+			return fmt.Errorf("invalid repo path: %w", err)
+		}
+		if tempDir := tempDirContaining(expanded); tempDir != "" {
+			return fmt.Errorf("repository %s is inside temp directory %s and may be deleted (use --allow-temp-repo to override)", expanded, tempDir)
+		}
+	}
+
+	return nil
+}
+
+// dotcorDirs returns the directories dotcor writes to: the config
+// directory, and the data directory when it's separate (XDG layout)
+func dotcorDirs() ([]string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return nil, err
+	}
+	if dataDir == configDir {
+		return []string{configDir}, nil
+	}
+	return []string{configDir, dataDir}, nil
+}
+
+// checkHome verifies the home directory is set and isn't the filesystem root
+func checkHome() error {
+	home, err := config.HomeDir()
+	if err != nil || home == "" {
+		return fmt.Errorf("home directory is not set (use --allow-any-home to override)")
+	}
+
+	cleaned := filepath.Clean(home)
+	if cleaned == filepath.VolumeName(cleaned)+string(filepath.Separator) {
+		return fmt.Errorf("home directory is %s (use --allow-any-home to override)", cleaned)
+	}
+
+	return nil
+}
+
+// isReadOnly reports whether the nearest existing directory at or above path
+// is on a read-only filesystem
+func isReadOnly(path string) bool {
+	dir := existingAncestor(path)
+	if dir == "" {
+		return false
+	}
+
+	f, err := os.CreateTemp(dir, ".dotcor-probe-*")
+	if err != nil {
+		return errors.Is(err, syscall.EROFS)
+	}
+	name := f.Name()
+	f.Close()
+	os.Remove(name)
+	return false
+}
+
+// foreignOwner returns the owner of path when running as root and path
+// belongs to a different user, or "" otherwise
+func foreignOwner(path string) string {
+	if os.Geteuid() != 0 {
+		return ""
+	}
+
+	dir := existingAncestor(path)
+	if dir == "" {
+		return ""
+	}
+
+	owner, err := fs.GetOwner(dir)
+	if err != nil || owner == "" || strings.HasPrefix(owner, "0:") {
+		return ""
+	}
+	return owner
+}
+
+// tempDirContaining returns the temp directory that contains path, or ""
+func tempDirContaining(path string) string {
+	resolved := resolvePath(path)
+
+	candidates := []string{os.TempDir()}
+	if runtime.GOOS != "windows" {
+		candidates = append(candidates, "/tmp", "/var/tmp")
+	}
+
+	for _, candidate := range candidates {
+		for _, tempDir := range []string{candidate, resolvePath(candidate)} {
+			rel, err := filepath.Rel(tempDir, resolved)
+			if err != nil {
+				continue
+			}
+			if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+				return candidate
+			}
+		}
+	}
+
+	return ""
+}
+
+// resolvePath resolves symlinks in the existing part of path
+func resolvePath(path string) string {
+	path = filepath.Clean(path)
+	dir := existingAncestor(path)
+	if dir == "" {
+		return path
+	}
+
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return path
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(resolved, rel)
+}
+
+// existingAncestor returns path or its nearest existing parent directory
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return ""
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("HOME-based checks are tested on Unix")
+	}
+
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Setenv("HOME", tempDir)
+	repoPath := filepath.Join(tempDir, ".dotcor", "files")
+
+	tests := []struct {
+		name      string
+		home      string
+		overrides EnvironmentOverrides
+		wantErr   string
+	}{
+		{
+			name:    "repo in temp dir",
+			home:    tempDir,
+			wantErr: "--allow-temp-repo",
+		},
+		{
+			name:      "repo in temp dir allowed",
+			home:      tempDir,
+			overrides: EnvironmentOverrides{AllowTempRepo: true},
+		},
+		{
+			name:      "home is root",
+			home:      "/",
+			overrides: EnvironmentOverrides{AllowTempRepo: true},
+			wantErr:   "--allow-any-home",
+		},
+		{
+			name:      "home unset",
+			home:      "",
+			overrides: EnvironmentOverrides{AllowTempRepo: true},
+			wantErr:   "--allow-any-home",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", tt.home)
+
+			err := CheckEnvironment(repoPath, tt.overrides)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckEnvironment() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckEnvironment() error = %v, want mention of %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTempDirContaining(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if got := tempDirContaining(filepath.Join(tempDir, "not", "yet", "created")); got == "" {
+		t.Error("tempDirContaining() should detect a path under the temp dir")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	if tempDirContaining(home) != "" {
+		t.Skip("home directory is inside a temp dir")
+	}
+	if got := tempDirContaining(filepath.Join(home, ".dotcor", "files")); got != "" {
+		t.Errorf("tempDirContaining() = %q for a path under home", got)
+	}
+}
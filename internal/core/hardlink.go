@@ -0,0 +1,50 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// DeployHardlink hard links a hardlink-mode file to its repo file, replacing
+// a symlink or stale file at the source path. Callers back up real files first.
+func DeployHardlink(cfg *config.Config, mf config.ManagedFile) error {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return fmt.Errorf("expanding source path: %w", err)
+	}
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return err
+	}
+
+	if isLink, _ := fs.IsSymlink(sourcePath); isLink || fs.FileExists(sourcePath) {
+		if err := os.Remove(sourcePath); err != nil {
+			return fmt.Errorf("removing existing file: %w", err)
+		}
+	}
+
+	if err := fs.CreateHardlink(repoPath, sourcePath); err != nil {
+		return err
+	}
+	log.Info("deployed hard link", "path", sourcePath, "to", repoPath)
+	return nil
+}
+
+// HardlinkIntact reports whether a hardlink-mode file still shares an inode
+// with its repo file. Editors that save by writing a new file and renaming it
+// over the old one silently break the link.
+func HardlinkIntact(cfg *config.Config, mf config.ManagedFile) (bool, error) {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return false, err
+	}
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return false, err
+	}
+	return fs.IsHardlinkTo(sourcePath, repoPath)
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestDeployHardlink(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	mf := config.ManagedFile{SourcePath: "~/.vimrc", RepoPath: "vim/vimrc", Mode: config.DeployModeHardlink}
+	cfg := &config.Config{
+		Version:      config.CurrentConfigVersion,
+		RepoPath:     repoDir,
+		ManagedFiles: []config.ManagedFile{mf},
+	}
+
+	repoFile := filepath.Join(repoDir, "vim", "vimrc")
+	if err := os.MkdirAll(filepath.Dir(repoFile), 0755); err != nil {
+		t.Fatalf("failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(repoFile, []byte("set number"), 0644); err != nil {
+		t.Fatalf("failed to create repo file: %v", err)
+	}
+
+	// A stale file at the source path is replaced by the link
+	sourceFile := filepath.Join(tempDir, ".vimrc")
+	if err := os.WriteFile(sourceFile, []byte("old"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	if intact, _ := HardlinkIntact(cfg, mf); intact {
+		t.Fatal("HardlinkIntact() = true for an unrelated file")
+	}
+
+	if err := DeployHardlink(cfg, mf); err != nil {
+		t.Fatalf("DeployHardlink() error = %v", err)
+	}
+	if intact, err := HardlinkIntact(cfg, mf); err != nil || !intact {
+		t.Errorf("HardlinkIntact() = %v, %v, want true", intact, err)
+	}
+	if content, _ := os.ReadFile(sourceFile); string(content) != "set number" {
+		t.Errorf("source content = %q, want the repo file", content)
+	}
+}
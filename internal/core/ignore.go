@@ -3,67 +3,156 @@ package core
 import (
 	"bufio"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
 )
 
 // ShouldIgnore checks if file matches any ignore patterns
 // Returns (matched, matchedPattern)
+//
+// Patterns follow .gitignore rules, relative to the home directory: the
+// last matching pattern wins, "!" negates, "**" matches any number of
+// directories, a trailing "/" matches only directories, and a pattern with
+// a "/" anywhere but the end is anchored. A path inside an ignored
+// directory stays ignored even if a later pattern negates it.
 func ShouldIgnore(path string, patterns []string) (bool, string) {
-	filename := filepath.Base(path)
+	isDir := false
+	if info, err := os.Stat(path); err == nil {
+		isDir = info.IsDir()
+	}
+	return matchIgnore(ignoreRelPath(path), isDir, parseIgnorePatterns(patterns))
+}
 
-	for _, pattern := range patterns {
-		// Try matching against filename
-		matched, err := filepath.Match(pattern, filename)
-		if err == nil && matched {
-			return true, pattern
+// MatchesPattern checks if path matches a single gitignore-style pattern
+func MatchesPattern(path, pattern string) bool {
+	matched, _ := ShouldIgnore(path, []string{pattern})
+	return matched
+}
+
+// IgnorePatternFor returns an anchored pattern that matches path and
+// nothing else, for adding a single file to the ignore patterns
+func IgnorePatternFor(path string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(ignoreRelPath(path))
+	return "/" + escaped
+}
+
+// ignorePattern is a parsed gitignore-style pattern
+type ignorePattern struct {
+	text     string   // Original pattern, reported on a match
+	segments []string // Pattern split on "/"
+	negate   bool     // Pattern started with "!"
+	dirOnly  bool     // Pattern ended with "/"
+	anchored bool     // Pattern is matched from the base instead of against any name
+}
+
+// parseIgnorePatterns parses patterns, skipping blank lines and comments
+func parseIgnorePatterns(patterns []string) []ignorePattern {
+	var parsed []ignorePattern
+	for _, text := range patterns {
+		p := ignorePattern{text: text}
+		pattern := strings.TrimRight(text, " ")
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
 		}
 
-		// Also try matching against full path for patterns like ".env.*"
-		matched, err = filepath.Match(pattern, path)
-		if err == nil && matched {
-			return true, pattern
+		switch {
+		case strings.HasPrefix(pattern, "!"):
+			p.negate = true
+			pattern = pattern[1:]
+		case strings.HasPrefix(pattern, `\!`), strings.HasPrefix(pattern, `\#`):
+			pattern = pattern[1:]
 		}
 
-		// Handle patterns with directory separators
-		if strings.Contains(pattern, string(filepath.Separator)) || strings.Contains(pattern, "/") {
-			// Normalize separators and try match
-			normalizedPattern := filepath.FromSlash(pattern)
-			matched, err = filepath.Match(normalizedPattern, path)
-			if err == nil && matched {
-				return true, pattern
-			}
+		if strings.HasSuffix(pattern, "/") {
+			p.dirOnly = true
+			pattern = strings.TrimRight(pattern, "/")
+		}
+		if strings.Contains(pattern, "/") {
+			p.anchored = true
+			pattern = strings.TrimPrefix(pattern, "/")
+		}
+		if pattern == "" {
+			continue
 		}
-	}
 
-	return false, ""
+		p.segments = strings.Split(pattern, "/")
+		parsed = append(parsed, p)
+	}
+	return parsed
 }
 
-// MatchesPattern checks if path matches a single glob pattern
-func MatchesPattern(path, pattern string) bool {
-	// Try matching filename
-	filename := filepath.Base(path)
-	matched, err := filepath.Match(pattern, filename)
-	if err == nil && matched {
-		return true
+// ignoreRelPath returns path relative to the home directory in slash form,
+// or the absolute path without its leading separator if it is outside home
+func ignoreRelPath(path string) string {
+	if home, err := config.HomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
 	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+}
 
-	// Try matching full path
-	matched, err = filepath.Match(pattern, path)
-	if err == nil && matched {
-		return true
+// matchIgnore checks rel and each of its parent directories against
+// patterns, from the top down, so an ignored directory ignores its contents
+func matchIgnore(rel string, isDir bool, patterns []ignorePattern) (bool, string) {
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		dir := i < len(parts) || isDir
+		if ignored, pattern := matchIgnoreExact(parts[:i], dir, patterns); ignored {
+			return true, pattern
+		}
 	}
+	return false, ""
+}
 
-	// Handle patterns with directory separators
-	if strings.Contains(pattern, "/") {
-		normalizedPattern := filepath.FromSlash(pattern)
-		matched, err = filepath.Match(normalizedPattern, path)
-		if err == nil && matched {
-			return true
+// matchIgnoreExact applies patterns to one path; the last match decides
+func matchIgnoreExact(parts []string, isDir bool, patterns []ignorePattern) (bool, string) {
+	ignored, matched := false, ""
+	for _, p := range patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		var ok bool
+		if p.anchored {
+			ok = matchSegments(p.segments, parts)
+		} else {
+			ok, _ = path.Match(p.segments[0], parts[len(parts)-1])
 		}
+		if ok {
+			ignored, matched = !p.negate, p.text
+		}
+	}
+	if !ignored {
+		return false, ""
 	}
+	return true, matched
+}
 
-	return false
+// matchSegments matches path segments against pattern segments, where "**"
+// matches zero or more whole segments
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:])
 }
 
 // LoadGitignorePatterns loads patterns from a .gitignore-style file
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
 )
 
 func TestShouldIgnore(t *testing.T) {
@@ -73,6 +75,59 @@ func TestShouldIgnore(t *testing.T) {
 	}
 }
 
+func TestShouldIgnoreGitignoreSemantics(t *testing.T) {
+	home := t.TempDir()
+	defer config.OverrideHomeDir(home)()
+
+	if err := os.MkdirAll(filepath.Join(home, ".config", "nvim"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns := []string{
+		"*.log",
+		"!keep.log",
+		".cache/",
+		"/.config/nvim/plugin",
+		"**/node_modules",
+		".config/**/*.bak",
+		".local/share/",
+		"!.local/share/important",
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"basename anywhere", ".config/app/debug.log", true},
+		{"negated", ".config/app/keep.log", false},
+		{"dir only matches directory contents", ".cache/thing/file", true},
+		{"dir only skips files", "notes/.cache", false},
+		{"anchored", ".config/nvim/plugin", true},
+		{"anchored does not float", "other/.config/nvim/plugin", false},
+		{"leading double star", "src/app/node_modules/pkg/index.js", true},
+		{"middle double star", ".config/a/b/c.bak", true},
+		{"middle double star zero dirs", ".config/c.bak", true},
+		{"double star stays anchored", "c.bak", false},
+		{"parent exclusion wins over negation", ".local/share/important", true},
+		{"directory itself", ".config/nvim", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := ShouldIgnore(filepath.Join(home, filepath.FromSlash(tt.path)), patterns)
+			if got != tt.want {
+				t.Errorf("ShouldIgnore(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// A real directory matches dir-only patterns itself
+	if got, pattern := ShouldIgnore(filepath.Join(home, ".config", "nvim"), []string{"nvim/"}); !got || pattern != "nvim/" {
+		t.Errorf("ShouldIgnore(directory) = %v, %q", got, pattern)
+	}
+}
+
 func TestMatchesPattern(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -116,6 +171,28 @@ func TestMatchesPattern(t *testing.T) {
 	}
 }
 
+func TestIgnorePatternFor(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	netrc := filepath.Join(home, ".netrc")
+	pattern := IgnorePatternFor(netrc)
+	if pattern != "/.netrc" {
+		t.Errorf("IgnorePatternFor() = %q, want /.netrc", pattern)
+	}
+	if !MatchesPattern(netrc, pattern) {
+		t.Errorf("%q doesn't match %s", pattern, netrc)
+	}
+	if MatchesPattern(filepath.Join(home, ".config", ".netrc"), pattern) {
+		t.Errorf("%q matches a file of the same name elsewhere", pattern)
+	}
+
+	odd := filepath.Join(home, ".config", "a*b", "x?.conf")
+	if !MatchesPattern(odd, IgnorePatternFor(odd)) || MatchesPattern(filepath.Join(home, ".config", "ab", "xy.conf"), IgnorePatternFor(odd)) {
+		t.Errorf("IgnorePatternFor(%s) = %q doesn't match only it", odd, IgnorePatternFor(odd))
+	}
+}
+
 func TestIsSecretFile(t *testing.T) {
 	tests := []struct {
 		filename string
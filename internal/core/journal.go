@@ -0,0 +1,402 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// Journal operation kinds
+const (
+	journalMove             = "move"
+	journalCopy             = "copy"
+	journalSymlink          = "symlink"
+	journalHardlink         = "hardlink"
+	journalStep             = "step"
+	journalBackup           = "backup"
+	journalRemoveSymlink    = "remove_symlink"
+	journalRemoveFile       = "remove_file"
+	journalCreateDir        = "create_dir"
+	journalWriteFile        = "write_file"
+	journalAddToConfig      = "add_to_config"
+	journalRemoveFromConfig = "remove_from_config"
+)
+
+// JournalOp records one executed operation with the parameters needed to
+// reverse it. Paths are absolute.
+type JournalOp struct {
+	Op     string              `json:"op"`
+	Desc   string              `json:"desc"`
+	Src    string              `json:"src,omitempty"`
+	Dst    string              `json:"dst,omitempty"`
+	Path   string              `json:"path,omitempty"`
+	Target string              `json:"target,omitempty"` // Symlink or hard link target
+	Style  string              `json:"style,omitempty"`  // Symlink style
+	Backup string              `json:"backup,omitempty"` // Backup taken by the operation
+	File   *config.ManagedFile `json:"file,omitempty"`   // Managed file added to or removed from config
+
+	// Whether the path (or config entry) the operation creates was already
+	// there, recorded in the intent log before the operation runs
+	Existed bool `json:"existed,omitempty"`
+}
+
+// JournalEntry records a committed transaction. The transactions of one
+// command share a Run, so 'dotcor undo' reverts the command as a whole.
+type JournalEntry struct {
+	ID      string      `json:"id"`
+	Run     string      `json:"run"`
+	Time    time.Time   `json:"time"`
+	Command string      `json:"command"`          // e.g. "dotcor add"
+	Undoes  string      `json:"undoes,omitempty"` // Run reverted by this entry
+	Ops     []JournalOp `json:"ops"`
+
+	// Set in the intent log of a transaction that is still running
+	PID     int        `json:"pid,omitempty"`
+	Pending *JournalOp `json:"pending,omitempty"` // Operation about to run
+}
+
+const journalFile = "journal.jsonl"
+
+var (
+	journalCommand string // Empty until SetJournalCommand, so nothing is recorded
+	journalRun     string
+	journalSeq     int
+	journalMu      sync.Mutex
+)
+
+// SetJournalCommand turns on the journal for this process, recording
+// committed transactions under command
+func SetJournalCommand(command string) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	journalCommand = command
+	journalRun = fmt.Sprintf("%s-%d", time.Now().Format("20060102T150405.000000000"), os.Getpid())
+	journalSeq = 0
+}
+
+// GetJournalPath returns the journal file (~/.dotcor/journal/journal.jsonl)
+func GetJournalPath() (string, error) {
+	dir, err := config.GetJournalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, journalFile), nil
+}
+
+// journalEnabled reports whether SetJournalCommand has been called
+func journalEnabled() bool {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	return journalCommand != ""
+}
+
+// entry describes the transaction's executed operations as a journal
+// entry, assigning the transaction its ID on first use
+func (t *Transaction) entry() JournalEntry {
+	journalMu.Lock()
+	if t.id == "" {
+		journalSeq++
+		t.id = fmt.Sprintf("%s.%d", journalRun, journalSeq)
+	}
+	entry := JournalEntry{
+		ID:      t.id,
+		Run:     journalRun,
+		Time:    time.Now(),
+		Command: journalCommand,
+		Undoes:  t.undoes,
+	}
+	journalMu.Unlock()
+
+	for _, op := range t.executed {
+		entry.Ops = append(entry.Ops, journalOp(op))
+	}
+	return entry
+}
+
+// recordTransaction appends the executed operations to the journal. A
+// failed write is logged: the transaction itself has already succeeded.
+func recordTransaction(t *Transaction) {
+	if !journalEnabled() {
+		return
+	}
+
+	entry := t.entry()
+	if err := appendJournal(entry); err != nil {
+		log.Warn("journal write failed", "error", err)
+		return
+	}
+	log.Debug("journaled transaction", "id", entry.ID, "ops", len(entry.Ops))
+}
+
+// appendJournal writes entry as one line of the journal
+func appendJournal(entry JournalEntry) error {
+	path, err := GetJournalPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating journal directory: %w", err)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding journal entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("opening journal: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	return f.Close()
+}
+
+// journalOp captures what op did, including state it saved while running
+func journalOp(op Operation) JournalOp {
+	j := JournalOp{Op: journalStep, Desc: op.Describe()}
+
+	switch op := op.(type) {
+	case *MoveFileOp:
+		j.Op, j.Src, j.Dst = journalMove, op.Src, op.Dst
+	case *CopyFileOp:
+		j.Op, j.Src, j.Dst = journalCopy, op.Src, op.Dst
+	case *CreateSymlinkOp:
+		j.Op, j.Path, j.Target, j.Style = journalSymlink, op.Link, op.Target, op.Style
+	case *CreateHardlinkOp:
+		j.Op, j.Path, j.Target = journalHardlink, op.Link, op.Target
+	case *StepOp:
+		j.Path = op.Deploys
+	case *BackupFileOp:
+		j.Op, j.Path, j.Backup = journalBackup, op.Path, op.BackupPath
+	case *RemoveSymlinkOp:
+		j.Op, j.Path = journalRemoveSymlink, op.Link
+		if op.savedTarget != "" {
+			j.Target = absLinkTarget(op.Link, op.savedTarget)
+		}
+		j.Style = config.LinkStyleAbsolute
+		if op.wasRelative {
+			j.Style = config.LinkStyleRelative
+		}
+	case *RemoveFileOp:
+		j.Op, j.Path, j.Backup = journalRemoveFile, op.Path, op.backupPath
+	case *CreateDirOp:
+		j.Op, j.Path = journalCreateDir, op.Path
+	case *WriteFileOp:
+		j.Op, j.Path, j.Backup = journalWriteFile, op.Path, op.backupPath
+	case *AddToConfigOp:
+		file := op.File
+		j.Op, j.File = journalAddToConfig, &file
+	case *RemoveFromConfigOp:
+		j.Op, j.File = journalRemoveFromConfig, op.savedFile
+	}
+	return j
+}
+
+// ReadJournal returns the journal's entries, oldest first. A missing
+// journal has no entries; lines that can't be parsed are skipped.
+func ReadJournal() ([]JournalEntry, error) {
+	path, err := GetJournalPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Warn("skipping unreadable journal entry", "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+	return entries, nil
+}
+
+// LatestUndoable returns the entries of the most recent run that hasn't
+// been undone, oldest first. Undo runs themselves are never returned, so
+// repeated undos step further back.
+func LatestUndoable(entries []JournalEntry) []JournalEntry {
+	undone := make(map[string]bool)
+	for _, e := range entries {
+		if e.Undoes != "" {
+			undone[e.Undoes] = true
+		}
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Undoes != "" || undone[e.Run] {
+			continue
+		}
+		var run []JournalEntry
+		for _, other := range entries[:i+1] {
+			if other.Run == e.Run {
+				run = append(run, other)
+			}
+		}
+		return run
+	}
+	return nil
+}
+
+// UndoTransaction builds a transaction that reverts the journaled entries
+// by running the inverse of each operation, newest first. Call ExecuteAll
+// and Commit to run it; the commit is journaled as undoing the run.
+func UndoTransaction(cfg *config.Config, entries []JournalEntry) (*Transaction, error) {
+	tx := NewTransaction()
+	if len(entries) == 0 {
+		return tx, nil
+	}
+	tx.undoes = entries[0].Run
+
+	released := make(map[string]bool)
+	for i := len(entries) - 1; i >= 0; i-- {
+		ops := entries[i].Ops
+		for k := len(ops) - 1; k >= 0; k-- {
+			inverse, err := ops[k].inverse(cfg, released)
+			if err != nil {
+				return nil, err
+			}
+			tx.operations = append(tx.operations, inverse...)
+		}
+	}
+	return tx, nil
+}
+
+// inverse returns the operations that revert j. Steps without a recorded
+// effect, like rendering a template, have nothing to revert. released holds
+// the source paths whose config entry an earlier inverse removes, as when
+// reverting a 'dotcor mv' that replaced an entry.
+func (j JournalOp) inverse(cfg *config.Config, released map[string]bool) ([]Operation, error) {
+	switch j.Op {
+	case journalMove:
+		return []Operation{&noClobberOp{Operation: &MoveFileOp{Src: j.Dst, Dst: j.Src}, Path: j.Src}}, nil
+	case journalCopy:
+		return []Operation{&RemoveFileOp{Path: j.Dst}}, nil
+	case journalSymlink:
+		return []Operation{&RemoveSymlinkOp{Link: j.Path}}, nil
+	case journalHardlink:
+		return []Operation{&RemoveFileOp{Path: j.Path}}, nil
+	case journalStep:
+		if j.Path == "" {
+			return nil, nil
+		}
+		return []Operation{&RemoveFileOp{Path: j.Path}}, nil
+	case journalBackup:
+		// The backed up file was replaced by a later operation, whose
+		// inverse removes the replacement
+		if j.Backup == "" {
+			return nil, nil
+		}
+		return []Operation{&restoreBackupOp{BackupPath: j.Backup, Path: j.Path, IfMissing: true}}, nil
+	case journalRemoveSymlink:
+		link := &CreateSymlinkOp{Target: j.Target, Link: j.Path, Style: j.Style}
+		return []Operation{&noClobberOp{Operation: link, Path: j.Path}}, nil
+	case journalRemoveFile:
+		if j.Backup == "" {
+			return nil, fmt.Errorf("no backup of %s was recorded", j.Path)
+		}
+		restore := &restoreBackupOp{BackupPath: j.Backup, Path: j.Path}
+		return []Operation{&noClobberOp{Operation: restore, Path: j.Path}}, nil
+	case journalCreateDir:
+		// Left in place: it may hold files by now and an empty one is harmless
+		return nil, nil
+	case journalWriteFile:
+		ops := []Operation{&RemoveFileOp{Path: j.Path}}
+		if j.Backup != "" {
+			ops = append(ops, &restoreBackupOp{BackupPath: j.Backup, Path: j.Path})
+		}
+		return ops, nil
+	case journalAddToConfig:
+		if j.File == nil {
+			return nil, nil
+		}
+		released[j.File.SourcePath] = true
+		return []Operation{&RemoveFromConfigOp{Config: cfg, SourcePath: j.File.SourcePath}}, nil
+	case journalRemoveFromConfig:
+		if j.File == nil {
+			return nil, nil
+		}
+		if cfg.IsManaged(j.File.SourcePath) && !released[j.File.SourcePath] {
+			return nil, fmt.Errorf("%s is managed again", j.File.SourcePath)
+		}
+		released[j.File.SourcePath] = false
+		return []Operation{&AddToConfigOp{Config: cfg, File: *j.File}}, nil
+	}
+	return nil, fmt.Errorf("unknown journal operation %q", j.Op)
+}
+
+// noClobberOp runs an operation that creates Path, failing instead if
+// something is already there, so undo never replaces a file created since
+// the journaled command ran
+type noClobberOp struct {
+	Operation
+	Path string
+}
+
+func (op *noClobberOp) Do() error {
+	if _, err := os.Lstat(op.Path); err == nil {
+		return fmt.Errorf("%s already exists", op.Path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return op.Operation.Do()
+}
+
+// restoreBackupOp restores Path from BackupPath. With IfMissing an
+// existing Path is left alone.
+type restoreBackupOp struct {
+	BackupPath string
+	Path       string
+	IfMissing  bool
+	restored   bool
+}
+
+func (op *restoreBackupOp) Do() error {
+	if op.IfMissing {
+		if _, err := os.Lstat(op.Path); err == nil {
+			return nil
+		}
+	}
+	if err := RestoreBackup(op.BackupPath, op.Path); err != nil {
+		return err
+	}
+	op.restored = true
+	return nil
+}
+
+func (op *restoreBackupOp) Undo() error {
+	if !op.restored {
+		return nil
+	}
+	return os.Remove(op.Path)
+}
+
+func (op *restoreBackupOp) Describe() string {
+	return fmt.Sprintf("restore %s from %s", op.Path, op.BackupPath)
+}
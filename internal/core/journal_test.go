@@ -0,0 +1,188 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// setupJournalTest creates a home with ~/.zshrc and an empty config
+func setupJournalTest(t *testing.T) (*config.Config, string) {
+	t.Helper()
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Cleanup(func() { journalCommand = "" })
+
+	source := filepath.Join(tempDir, ".zshrc")
+	if err := os.WriteFile(source, []byte("export EDITOR=vim"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Version:  config.CurrentConfigVersion,
+		RepoPath: filepath.Join(tempDir, ".dotcor", "files"),
+	}
+	return cfg, source
+}
+
+// journaledAdd adds ~/.zshrc as its own journaled run
+func journaledAdd(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	SetJournalCommand("dotcor add")
+	mf := config.ManagedFile{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"}
+	tx, err := AddFileTransaction(cfg, "~/.zshrc", "shell/zshrc", mf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.ExecuteAll(); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	tx.Commit()
+}
+
+// undoLatest reverts the latest undoable run
+func undoLatest(t *testing.T, cfg *config.Config) error {
+	t.Helper()
+	entries, err := ReadJournal()
+	if err != nil {
+		t.Fatalf("ReadJournal() error = %v", err)
+	}
+	run := LatestUndoable(entries)
+	if len(run) == 0 {
+		t.Fatal("LatestUndoable() found nothing to undo")
+	}
+
+	SetJournalCommand("dotcor undo")
+	tx, err := UndoTransaction(cfg, run)
+	if err != nil {
+		return err
+	}
+	if err := tx.ExecuteAll(); err != nil {
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+func TestJournalUndo(t *testing.T) {
+	cfg, source := setupJournalTest(t)
+	repoFile := filepath.Join(cfg.RepoPath, "shell", "zshrc")
+	journaledAdd(t, cfg)
+
+	// Remove the file again, the way 'dotcor remove' does
+	SetJournalCommand("dotcor remove")
+	tx := NewTransaction()
+	for _, op := range []Operation{
+		&RemoveSymlinkOp{Link: source},
+		&CopyFileOp{Src: repoFile, Dst: source, Preserve: true},
+		&RemoveFileOp{Path: repoFile},
+		&RemoveFromConfigOp{Config: cfg, SourcePath: "~/.zshrc"},
+	} {
+		if err := tx.Execute(op); err != nil {
+			t.Fatalf("remove: %v", err)
+		}
+	}
+	tx.Commit()
+
+	// Undoing the remove brings back the symlink, repo file and config entry
+	if err := undoLatest(t, cfg); err != nil {
+		t.Fatalf("undo remove: %v", err)
+	}
+	if target, err := os.Readlink(source); err != nil || filepath.Base(target) != "zshrc" {
+		t.Errorf("~/.zshrc should be a symlink to the repo again, got %q, %v", target, err)
+	}
+	if !cfg.IsManaged("~/.zshrc") {
+		t.Error("~/.zshrc should be managed again")
+	}
+
+	// The next undo steps back to the add
+	if err := undoLatest(t, cfg); err != nil {
+		t.Fatalf("undo add: %v", err)
+	}
+	if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("~/.zshrc should be a regular file again, got %v, %v", info, err)
+	}
+	if data, _ := os.ReadFile(source); string(data) != "export EDITOR=vim" {
+		t.Errorf("~/.zshrc = %q after undo", data)
+	}
+	if cfg.IsManaged("~/.zshrc") {
+		t.Error("~/.zshrc should no longer be managed")
+	}
+
+	entries, _ := ReadJournal()
+	if run := LatestUndoable(entries); run != nil {
+		t.Errorf("LatestUndoable() = %v, want nothing left to undo", run)
+	}
+}
+
+func TestJournalUndoNoClobber(t *testing.T) {
+	cfg, source := setupJournalTest(t)
+	journaledAdd(t, cfg)
+
+	// A file written in place of the symlink must survive the undo
+	if err := os.Remove(source); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := undoLatest(t, cfg); err == nil {
+		t.Fatal("undo should fail when the symlink was replaced")
+	}
+	if data, _ := os.ReadFile(source); string(data) != "new" {
+		t.Errorf("~/.zshrc = %q, want the new file kept", data)
+	}
+	if !cfg.IsManaged("~/.zshrc") {
+		t.Error("a failed undo should be rolled back")
+	}
+}
+
+func TestJournalUndoReplacedEntry(t *testing.T) {
+	cfg, source := setupJournalTest(t)
+	journaledAdd(t, cfg)
+
+	// Move the repo file, the way 'dotcor mv' does
+	SetJournalCommand("dotcor mv")
+	oldFile := filepath.Join(cfg.RepoPath, "shell", "zshrc")
+	newFile := filepath.Join(cfg.RepoPath, "zsh", "zshrc")
+	tx := NewTransaction()
+	for _, op := range []Operation{
+		&MoveFileOp{Src: oldFile, Dst: newFile},
+		&RemoveSymlinkOp{Link: source},
+		&CreateSymlinkOp{Target: newFile, Link: source},
+		&RemoveFromConfigOp{Config: cfg, SourcePath: "~/.zshrc"},
+		&AddToConfigOp{Config: cfg, File: config.ManagedFile{SourcePath: "~/.zshrc", RepoPath: "zsh/zshrc"}},
+	} {
+		if err := tx.Execute(op); err != nil {
+			t.Fatalf("mv: %v", err)
+		}
+	}
+	tx.Commit()
+
+	if err := undoLatest(t, cfg); err != nil {
+		t.Fatalf("undo mv: %v", err)
+	}
+	if mf, err := cfg.GetManagedFile("~/.zshrc"); err != nil || mf.RepoPath != "shell/zshrc" {
+		t.Errorf("config entry = %+v, %v, want the old repo path back", mf, err)
+	}
+	if target, err := os.Readlink(source); err != nil || filepath.Base(filepath.Dir(target)) != "shell" {
+		t.Errorf("~/.zshrc -> %q, %v, want the old repo file", target, err)
+	}
+}
+
+func TestJournalOff(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	tx := NewTransaction()
+	if err := tx.Execute(&CreateDirOp{Path: filepath.Join(tempDir, "dir")}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+
+	if entries, err := ReadJournal(); err != nil || len(entries) != 0 {
+		t.Errorf("ReadJournal() = %v, %v, want no entries without SetJournalCommand", entries, err)
+	}
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// lfsExtensions are binary formats common in dotfiles, fonts and the images
+// used by terminal themes and wallpapers, tracked with LFS at any size
+var lfsExtensions = map[string]bool{
+	".ttf": true, ".otf": true, ".woff": true, ".woff2": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".ico": true,
+}
+
+// NeedsLFS reports whether the file at path should be stored with Git LFS:
+// it matches one of the configured patterns, or it's binary and either a
+// font or image or at least the configured minimum size
+func NeedsLFS(l config.LFSConfig, path string) (bool, error) {
+	name := filepath.Base(path)
+	for _, pattern := range l.Patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true, nil
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return false, nil
+	}
+	if info.Size() < l.GetMinSize() && !lfsExtensions[strings.ToLower(filepath.Ext(name))] {
+		return false, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	head := make([]byte, 8000)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return isBinary(head[:n]), nil
+}
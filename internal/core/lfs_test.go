@@ -0,0 +1,50 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestNeedsLFS(t *testing.T) {
+	tempDir := t.TempDir()
+	binary := []byte{0x89, 'P', 'N', 'G', 0, 0, 0, 0}
+
+	files := map[string][]byte{
+		"small.png":         binary,
+		"small.bin":         binary,
+		"large.bin":         bytes.Repeat(binary, 128), // 1 KiB
+		"large.txt":         bytes.Repeat([]byte("text\n"), 512),
+		"theme.itermcolors": []byte("<plist/>"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.LFSConfig{Enabled: true, MinSizeKB: 1, Patterns: []string{"*.itermcolors"}}
+	tests := map[string]bool{
+		"small.png":         true,  // Image at any size
+		"small.bin":         false, // Binary below the minimum size
+		"large.bin":         true,  // Binary at the minimum size
+		"large.txt":         false, // Text at any size
+		"theme.itermcolors": true,  // Configured pattern
+	}
+	for name, want := range tests {
+		got, err := NeedsLFS(cfg, filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("NeedsLFS(%s) error = %v", name, err)
+		}
+		if got != want {
+			t.Errorf("NeedsLFS(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	if _, err := NeedsLFS(cfg, filepath.Join(tempDir, "missing.bin")); err == nil {
+		t.Error("NeedsLFS() on a missing file should return error")
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
 )
 
 // LockInfo contains information about the current lock
@@ -31,24 +33,113 @@ var ErrLockHeld = errors.New("lock is held by another process")
 // ErrStaleLock is returned when lock appears to be stale
 var ErrStaleLock = errors.New("stale lock detected")
 
+// ErrReadOnly is returned by AcquireLock in a process marked read-only
+var ErrReadOnly = errors.New("read-only commands don't take the lock")
+
+// Polling intervals while waiting for the lock, doubling from the first
+const (
+	lockPollMin = 50 * time.Millisecond
+	lockPollMax = time.Second
+)
+
+// lockWriteGrace is how long an unreadable lock file is taken to be one
+// still being written rather than a stale one
+const lockWriteGrace = 5 * time.Second
+
+// lockTaken is set once this process acquires the lock
+var lockTaken bool
+
+// heldLockPath is the lock file this process holds. The lock path depends
+// on the repository path, which a command may change while holding it.
+var heldLockPath string
+
+// readOnly is set with SetReadOnly
+var readOnly bool
+
+// SetReadOnly marks the process as one that only reads, such as 'dotcor
+// status'. Reads never take or wait for the lock, so they keep working
+// while another dotcor, such as a stuck watch, holds it. AcquireLock fails
+// with ErrReadOnly to catch a read-only command that tries.
+func SetReadOnly(enabled bool) {
+	readOnly = enabled
+}
+
+// lockWait is how long AcquireLock waits for another process to release
+// the lock, ending early when lockWaitCtx is canceled
+var (
+	lockWait    time.Duration
+	lockWaitCtx = context.Background()
+)
+
+// SetLockWait makes AcquireLock wait up to wait for another process to
+// release the lock instead of failing at once, as --lock-wait does.
+// Canceling ctx stops the wait.
+func SetLockWait(ctx context.Context, wait time.Duration) {
+	lockWaitCtx, lockWait = ctx, wait
+}
+
 // getLockPath returns the path to the lock file
 func getLockPath() (string, error) {
-	configDir, err := config.GetConfigDir()
-	if err != nil {
-		return "", err
+	if heldLockPath != "" {
+		return heldLockPath, nil
 	}
-	return filepath.Join(configDir, ".lock"), nil
+	return config.GetLockPath()
 }
 
-// AcquireLock acquires file-based lock for dotcor operations
-// Uses O_EXCL for atomic lock creation to prevent race conditions
-// Returns error if lock is already held
+// AcquireLock acquires file-based lock for dotcor operations. While the
+// lock is held by another process it polls with backoff for as long as
+// SetLockWait allows, then fails with ErrLockHeld.
 func AcquireLock() error {
+	if readOnly {
+		return ErrReadOnly
+	}
 	lockPath, err := getLockPath()
 	if err != nil {
 		return err
 	}
 
+	deadline := time.Now().Add(lockWait)
+	delay := lockPollMin
+	for {
+		err := checkLegacyLock()
+		if err == nil {
+			err = tryLock(lockPath)
+		}
+		wait := time.Until(deadline)
+		if !errors.Is(err, ErrLockHeld) || wait <= 0 {
+			return err
+		}
+
+		log.Debug("waiting for lock", "path", lockPath, "error", err)
+		select {
+		case <-lockWaitCtx.Done():
+			return lockWaitCtx.Err()
+		case <-time.After(min(delay, wait)):
+		}
+		delay = min(delay*2, lockPollMax)
+	}
+}
+
+// checkLegacyLock returns ErrLockHeld while an older dotcor holds its lock
+// on the repository. A stale one is ignored.
+func checkLegacyLock() error {
+	legacyPath, err := config.GetLegacyLockPath()
+	if err != nil {
+		return err
+	}
+	if !fs.FileExists(legacyPath) {
+		return nil
+	}
+	if stale, err := IsStale(legacyPath); err != nil || stale {
+		return err
+	}
+	info, _ := ReadLockInfo(legacyPath)
+	return fmt.Errorf("%w: PID %d on %s, an older dotcor. If this is incorrect, remove %s", ErrLockHeld, info.PID, info.Hostname, legacyPath)
+}
+
+// tryLock creates the lock file at lockPath, replacing a stale one. The
+// file is created with O_EXCL, so of two processes only one succeeds.
+func tryLock(lockPath string) error {
 	// Ensure config directory exists
 	if err := fs.EnsureDir(filepath.Dir(lockPath)); err != nil {
 		return fmt.Errorf("creating config directory: %w", err)
@@ -65,13 +156,11 @@ func AcquireLock() error {
 			}
 
 			if stale {
-				// Try to remove stale lock and retry
-				if removeErr := os.Remove(lockPath); removeErr != nil {
+				if removeErr := removeStaleLock(lockPath); removeErr != nil {
 					info, _ := ReadLockInfo(lockPath)
 					return fmt.Errorf("%w: PID %d (process appears dead). Run 'dotcor doctor --fix' to clear", ErrStaleLock, info.PID)
 				}
-				// Retry lock acquisition after removing stale lock
-				return AcquireLock()
+				return tryLock(lockPath)
 			}
 
 			// Lock is held by active process
@@ -100,9 +189,40 @@ func AcquireLock() error {
 		return fmt.Errorf("writing lock file: %w", err)
 	}
 
+	// Every mutating command takes the lock, so this opens its log entries
+	log.Info("command started", "args", os.Args[1:])
+	lockTaken = true
+	heldLockPath = lockPath
 	return nil
 }
 
+// removeStaleLock removes the stale lock file at lockPath. Another process
+// may replace it between the staleness check and the removal, so it is
+// renamed aside first and put back if it turns out to be a live lock.
+func removeStaleLock(lockPath string) error {
+	aside := fmt.Sprintf("%s.stale-%d", lockPath, os.Getpid())
+	if err := os.Rename(lockPath, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil // Another process removed it first
+		}
+		return err
+	}
+
+	if stale, _ := IsStale(aside); !stale {
+		// Link fails rather than replacing a lock taken in the meantime
+		if err := os.Link(aside, lockPath); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return os.Remove(aside)
+}
+
+// LockTaken reports whether this process has acquired the lock, which
+// every mutating command does
+func LockTaken() bool {
+	return lockTaken
+}
+
 // ReleaseLock releases the file lock
 func ReleaseLock() error {
 	lockPath, err := getLockPath()
@@ -110,6 +230,8 @@ func ReleaseLock() error {
 		return err
 	}
 
+	heldLockPath = ""
+
 	// Check if we own the lock
 	if !fs.FileExists(lockPath) {
 		return nil // No lock to release
@@ -160,6 +282,10 @@ func IsLocked() (bool, error) {
 func IsStale(lockPath string) (bool, error) {
 	info, err := ReadLockInfo(lockPath)
 	if err != nil {
+		// A new lock file is empty until its owner writes it
+		if stat, statErr := os.Stat(lockPath); statErr == nil && time.Since(stat.ModTime()) < lockWriteGrace {
+			return false, nil
+		}
 		return true, nil // Malformed lock file is considered stale
 	}
 
@@ -1,10 +1,15 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
 )
 
 func TestLockInfo(t *testing.T) {
@@ -185,3 +190,129 @@ func TestErrLockHeld(t *testing.T) {
 		t.Error("ErrStaleLock should not be nil")
 	}
 }
+
+// writeLock writes a lock file held by pid at the current lock path
+func writeLock(t *testing.T, pid int) string {
+	t.Helper()
+	lockPath, err := getLockPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := fmt.Sprintf("%d\n%s\nother\n", pid, time.Now().Format(time.RFC3339))
+	if err := os.WriteFile(lockPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return lockPath
+}
+
+func TestAcquireLockWait(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// The test runner's parent stands in for another running dotcor
+	lockPath := writeLock(t, os.Getppid())
+	if err := AcquireLock(); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("AcquireLock() without waiting error = %v, want ErrLockHeld", err)
+	}
+
+	SetLockWait(context.Background(), 5*time.Second)
+	t.Cleanup(func() { SetLockWait(context.Background(), 0) })
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.Remove(lockPath)
+	}()
+	if err := AcquireLock(); err != nil {
+		t.Fatalf("AcquireLock() while waiting error = %v", err)
+	}
+	defer ReleaseLock()
+	if own, _ := IsOwnLock(); !own {
+		t.Error("lock not taken after waiting for it")
+	}
+}
+
+func TestAcquireLockWaitCanceled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeLock(t, os.Getppid())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	SetLockWait(ctx, time.Minute)
+	t.Cleanup(func() { SetLockWait(context.Background(), 0) })
+
+	if err := AcquireLock(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AcquireLock() error = %v, want the context's", err)
+	}
+}
+
+func TestAcquireLockReplacesStale(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	lockPath := writeLock(t, 999999999)
+
+	if err := AcquireLock(); err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	defer ReleaseLock()
+	if info, _ := ReadLockInfo(lockPath); info.PID != os.Getpid() {
+		t.Errorf("lock PID = %d, want %d", info.PID, os.Getpid())
+	}
+	if leftovers, _ := filepath.Glob(lockPath + ".stale-*"); len(leftovers) > 0 {
+		t.Errorf("stale lock left behind as %v", leftovers)
+	}
+}
+
+func TestAcquireLockLegacy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	legacyPath, err := config.GetLegacyLockPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(legacyPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeLegacy := func(pid int) {
+		t.Helper()
+		content := fmt.Sprintf("%d\n%s\nother\n", pid, time.Now().Format(time.RFC3339))
+		if err := os.WriteFile(legacyPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An older dotcor still running keeps this one out
+	writeLegacy(os.Getppid())
+	if err := AcquireLock(); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("AcquireLock() with a live legacy lock error = %v, want ErrLockHeld", err)
+	}
+
+	writeLegacy(999999999)
+	if err := AcquireLock(); err != nil {
+		t.Fatalf("AcquireLock() with a stale legacy lock error = %v", err)
+	}
+	ReleaseLock()
+}
+
+func TestRemoveStaleLockKeepsLiveLock(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	lockPath := writeLock(t, os.Getppid())
+
+	if err := removeStaleLock(lockPath); err != nil {
+		t.Fatalf("removeStaleLock() error = %v", err)
+	}
+	if info, err := ReadLockInfo(lockPath); err != nil || info.PID != os.Getppid() {
+		t.Errorf("live lock not put back: %+v, %v", info, err)
+	}
+}
+
+func TestAcquireLockReadOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	SetReadOnly(true)
+	t.Cleanup(func() { SetReadOnly(false) })
+
+	if err := AcquireLock(); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("AcquireLock() error = %v, want ErrReadOnly", err)
+	}
+	if locked, _ := IsLocked(); locked {
+		t.Error("read-only process created a lock file")
+	}
+}
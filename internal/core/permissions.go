@@ -0,0 +1,119 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// PermissionMismatch is a file whose permissions differ from those recorded
+// for its managed file
+type PermissionMismatch struct {
+	Path string
+	Have os.FileMode
+	Want os.FileMode
+}
+
+func (m PermissionMismatch) String() string {
+	return fmt.Sprintf("%s is %03o, want %03o", m.Path, m.Have, m.Want)
+}
+
+// permissionTargets returns the files that carry mf's permissions: the
+// repo file, the rendered or decrypted output it deploys, and a local copy.
+// Symlinks and hard links need nothing more, they share the repo file's mode.
+func permissionTargets(cfg *config.Config, mf config.ManagedFile) ([]string, error) {
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return nil, err
+	}
+	targets := []string{repoPath}
+
+	if mf.IsTemplate() || mf.Encrypted {
+		targetPath, err := config.GetLinkTargetPath(cfg, mf)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, targetPath)
+	}
+
+	if mf.IsCopy() {
+		sourcePath, err := config.ExpandPath(mf.SourcePath)
+		if err != nil {
+			return nil, err
+		}
+		if isLink, _ := fs.IsSymlink(sourcePath); !isLink {
+			targets = append(targets, sourcePath)
+		}
+	}
+	return targets, nil
+}
+
+// CheckPermissions returns the files of a user dotfile whose permissions
+// differ from its recorded perm. A file in a sensitive directory such as
+// ~/.ssh is held to the directory's file mode whatever was recorded, and
+// the directory to its own mode. Missing files are skipped; system files
+// have their permissions restored by 'dotcor system remove' instead.
+func CheckPermissions(cfg *config.Config, mf config.ManagedFile) ([]PermissionMismatch, error) {
+	// Windows has no Unix permission bits to compare
+	want, ok, err := mf.FilePerm()
+	if err != nil || mf.IsSystem() || runtime.GOOS == "windows" {
+		return nil, err
+	}
+	sensitive, inSensitive := SensitiveDirFor(mf.SourcePath)
+	if inSensitive {
+		want, ok = sensitive.FilePerm, true
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	targets, err := permissionTargets(cfg, mf)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []PermissionMismatch
+	for _, path := range targets {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if have := info.Mode().Perm(); have != want {
+			mismatches = append(mismatches, PermissionMismatch{Path: path, Have: have, Want: want})
+		}
+	}
+
+	if inSensitive {
+		dir, err := sensitive.Expanded()
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(dir); err == nil && info.Mode().Perm() != sensitive.DirPerm {
+			mismatches = append(mismatches, PermissionMismatch{Path: dir, Have: info.Mode().Perm(), Want: sensitive.DirPerm})
+		}
+	}
+	return mismatches, nil
+}
+
+// RestorePermissions sets the recorded perm on every file of mf that
+// differs, returning the files it changed
+func RestorePermissions(cfg *config.Config, mf config.ManagedFile) ([]PermissionMismatch, error) {
+	mismatches, err := CheckPermissions(cfg, mf)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range mismatches {
+		if err := os.Chmod(m.Path, m.Want); err != nil {
+			return mismatches[:i], fmt.Errorf("setting permissions on %s: %w", m.Path, err)
+		}
+		log.Info("restored permissions", "path", m.Path, "mode", fmt.Sprintf("%03o", m.Want))
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestRestorePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not enforced on Windows")
+	}
+
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	mf := config.ManagedFile{SourcePath: "~/.netrc", RepoPath: "misc/netrc", Mode: config.DeployModeCopy, Perm: "600"}
+	cfg := &config.Config{
+		Version:      config.CurrentConfigVersion,
+		RepoPath:     repoDir,
+		ManagedFiles: []config.ManagedFile{mf},
+	}
+
+	// A fresh clone and copy get the default mode
+	repoFile := filepath.Join(repoDir, "misc", "netrc")
+	sourceFile := filepath.Join(tempDir, ".netrc")
+	for _, path := range []string{repoFile, sourceFile} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("Host *"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mismatches, err := CheckPermissions(cfg, mf)
+	if err != nil {
+		t.Fatalf("CheckPermissions() error = %v", err)
+	}
+	if len(mismatches) != 2 || mismatches[0].Have != 0644 || mismatches[0].Want != 0600 {
+		t.Fatalf("CheckPermissions() = %v, want repo file and copy at 644", mismatches)
+	}
+
+	if restored, err := RestorePermissions(cfg, mf); err != nil || len(restored) != 2 {
+		t.Fatalf("RestorePermissions() = %v, %v", restored, err)
+	}
+	for _, path := range []string{repoFile, sourceFile} {
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %o, want 600", path, info.Mode().Perm())
+		}
+	}
+	if mismatches, _ := CheckPermissions(cfg, mf); len(mismatches) != 0 {
+		t.Errorf("CheckPermissions() after restore = %v", mismatches)
+	}
+
+	// Files without a recorded mode, and system files, are left alone
+	for _, other := range []config.ManagedFile{
+		{SourcePath: mf.SourcePath, RepoPath: mf.RepoPath},
+		{SourcePath: mf.SourcePath, RepoPath: mf.RepoPath, Perm: "644", Scope: config.ScopeSystem},
+	} {
+		if mismatches, err := CheckPermissions(cfg, other); err != nil || len(mismatches) != 0 {
+			t.Errorf("CheckPermissions(%+v) = %v, %v", other, mismatches, err)
+		}
+	}
+
+	if _, err := CheckPermissions(cfg, config.ManagedFile{RepoPath: mf.RepoPath, Perm: "rw"}); err == nil {
+		t.Error("CheckPermissions() should reject an invalid perm")
+	}
+}
+
+func TestSensitiveDirPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not enforced on Windows")
+	}
+
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	cfg := &config.Config{Version: config.CurrentConfigVersion, RepoPath: repoDir}
+
+	// Recorded as 644 before ~/.ssh had curated handling
+	mf := config.ManagedFile{SourcePath: "~/.ssh/config", RepoPath: "ssh/config", Perm: "644"}
+	repoFile := filepath.Join(repoDir, "ssh", "config")
+	sshDir := filepath.Join(tempDir, ".ssh")
+	for _, dir := range []string{filepath.Dir(repoFile), sshDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(repoFile, []byte("Host *"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches, err := CheckPermissions(cfg, mf)
+	if err != nil {
+		t.Fatalf("CheckPermissions() error = %v", err)
+	}
+	if len(mismatches) != 2 || mismatches[0].Want != 0600 || mismatches[1].Path != sshDir || mismatches[1].Want != 0700 {
+		t.Fatalf("CheckPermissions() = %v, want the file at 600 and ~/.ssh at 700", mismatches)
+	}
+
+	if _, err := RestorePermissions(cfg, mf); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(sshDir); info.Mode().Perm() != 0700 {
+		t.Errorf("~/.ssh mode = %o, want 700", info.Mode().Perm())
+	}
+	if mismatches, _ := CheckPermissions(cfg, mf); len(mismatches) != 0 {
+		t.Errorf("CheckPermissions() after restore = %v", mismatches)
+	}
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// ErrWatchRunning is returned when another 'dotcor watch' is already running
+var ErrWatchRunning = errors.New("dotcor watch is already running")
+
+// getWatchPIDPath returns the path to the watch pid file
+func getWatchPIDPath() (string, error) {
+	stateDir, err := config.GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "watch.pid"), nil
+}
+
+// WriteWatchPID records the current process as the running watcher.
+// A pid file left by a dead watcher is replaced. The file is created with
+// O_EXCL, so of two watchers starting at once only one succeeds.
+func WriteWatchPID() error {
+	pidPath, err := getWatchPIDPath()
+	if err != nil {
+		return err
+	}
+
+	for {
+		f, err := os.OpenFile(pidPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(pidPath)
+				return fmt.Errorf("writing pid file: %w", err)
+			}
+			return nil
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("creating pid file: %w", err)
+		}
+
+		pid, running, err := GetWatchPID()
+		switch {
+		case err == nil && running && pid != os.Getpid():
+			return fmt.Errorf("%w (PID %d). Stop it with 'dotcor watch --stop'", ErrWatchRunning, pid)
+		case err != nil:
+			// A new pid file is empty until its watcher writes it
+			if stat, statErr := os.Stat(pidPath); statErr == nil && time.Since(stat.ModTime()) < lockWriteGrace {
+				return fmt.Errorf("%w: %v", ErrWatchRunning, err)
+			}
+		}
+		if err := os.Remove(pidPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing old pid file: %w", err)
+		}
+	}
+}
+
+// RemoveWatchPID removes the pid file if it belongs to the current process
+func RemoveWatchPID() error {
+	pidPath, err := getWatchPIDPath()
+	if err != nil {
+		return err
+	}
+
+	pid, _, err := GetWatchPID()
+	if err != nil || pid != os.Getpid() {
+		return err
+	}
+
+	return os.Remove(pidPath)
+}
+
+// GetWatchPID returns the PID of the watcher and whether it is still running.
+// Returns 0 if no watcher has been started.
+func GetWatchPID() (int, bool, error) {
+	pidPath, err := getWatchPIDPath()
+	if err != nil {
+		return 0, false, err
+	}
+
+	content, err := os.ReadFile(pidPath)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("reading pid file: %w", err)
+	}
+	return parsePID(content)
+}
+
+// parsePID returns the PID in a pid file's content and whether that
+// process is still running
+func parsePID(content []byte) (int, bool, error) {
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid PID in pid file: %w", err)
+	}
+
+	alive, _ := isProcessAlive(pid)
+	return pid, alive, nil
+}
+
+// refreshTimeout is how long the pid file of a status refresh is trusted,
+// in case its PID has been reused by another process since
+const refreshTimeout = 10 * time.Minute
+
+// getRefreshPIDPath returns the path to the status refresh pid file
+func getRefreshPIDPath() (string, error) {
+	stateDir, err := config.GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "refresh.pid"), nil
+}
+
+// ClaimStatusRefresh claims the refresh of the status cache for a process
+// about to be started, so prompts drawn while it runs don't start more.
+// Returns false if another refresh is running. Once the process has
+// started, record it with SetStatusRefreshPID.
+func ClaimStatusRefresh() (bool, error) {
+	pidPath, err := getRefreshPIDPath()
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		f, err := os.OpenFile(pidPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			return true, f.Close()
+		}
+		if !os.IsExist(err) {
+			return false, fmt.Errorf("creating pid file: %w", err)
+		}
+
+		stat, err := os.Stat(pidPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("checking pid file: %w", err)
+		}
+		if age := time.Since(stat.ModTime()); age < refreshTimeout {
+			content, _ := os.ReadFile(pidPath)
+			_, running, err := parsePID(content)
+			// A claimed pid file is empty until its refresh has started
+			if (err == nil && running) || (err != nil && age < lockWriteGrace) {
+				return false, nil
+			}
+		}
+		if err := os.Remove(pidPath); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf("removing old pid file: %w", err)
+		}
+	}
+}
+
+// SetStatusRefreshPID records pid as the status refresh claimed with
+// ClaimStatusRefresh. A pid of 0 gives the claim up, for a refresh that
+// failed to start.
+func SetStatusRefreshPID(pid int) error {
+	pidPath, err := getRefreshPIDPath()
+	if err != nil {
+		return err
+	}
+	if pid == 0 {
+		return os.Remove(pidPath)
+	}
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		return fmt.Errorf("writing pid file: %w", err)
+	}
+	return nil
+}
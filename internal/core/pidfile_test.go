@@ -0,0 +1,107 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatchPID(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("HOME", tempDir)
+
+	if err := os.MkdirAll(filepath.Join(tempDir, ".dotcor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if pid, running, err := GetWatchPID(); err != nil || pid != 0 || running {
+		t.Fatalf("GetWatchPID() before start = %d, %v, %v; want 0, false, nil", pid, running, err)
+	}
+
+	if err := WriteWatchPID(); err != nil {
+		t.Fatalf("WriteWatchPID() error = %v", err)
+	}
+
+	pid, running, err := GetWatchPID()
+	if err != nil {
+		t.Fatalf("GetWatchPID() error = %v", err)
+	}
+	if pid != os.Getpid() || !running {
+		t.Errorf("GetWatchPID() = %d, %v; want %d, true", pid, running, os.Getpid())
+	}
+
+	if err := RemoveWatchPID(); err != nil {
+		t.Fatalf("RemoveWatchPID() error = %v", err)
+	}
+	if pid, _, _ := GetWatchPID(); pid != 0 {
+		t.Errorf("GetWatchPID() after remove = %d, want 0", pid)
+	}
+}
+
+func TestWriteWatchPIDRejectsRunningWatcher(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("HOME", tempDir)
+
+	pidPath := filepath.Join(tempDir, ".dotcor", "watch.pid")
+	if err := os.MkdirAll(filepath.Dir(pidPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// PID 1 is always running and is never the test process
+	if err := os.WriteFile(pidPath, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if alive, _ := isProcessAlive(1); alive {
+		if err := WriteWatchPID(); !errors.Is(err, ErrWatchRunning) {
+			t.Errorf("WriteWatchPID() error = %v, want ErrWatchRunning", err)
+		}
+	}
+
+	// A dead watcher's pid file is replaced
+	if err := os.WriteFile(pidPath, []byte("999999999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteWatchPID(); err != nil {
+		t.Errorf("WriteWatchPID() over stale pid file error = %v", err)
+	}
+}
+
+func TestClaimStatusRefresh(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	if err := os.MkdirAll(filepath.Join(tempDir, ".dotcor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed, err := ClaimStatusRefresh(); err != nil || !claimed {
+		t.Fatalf("ClaimStatusRefresh() = %v, %v; want claimed", claimed, err)
+	}
+	// Claimed but not started yet
+	if claimed, _ := ClaimStatusRefresh(); claimed {
+		t.Error("ClaimStatusRefresh() claimed a refresh that is starting")
+	}
+
+	if err := SetStatusRefreshPID(os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, _ := ClaimStatusRefresh(); claimed {
+		t.Error("ClaimStatusRefresh() claimed a refresh that is running")
+	}
+
+	// A finished refresh's pid file is replaced
+	if err := SetStatusRefreshPID(999999999); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := ClaimStatusRefresh(); err != nil || !claimed {
+		t.Errorf("ClaimStatusRefresh() after a finished refresh = %v, %v; want claimed", claimed, err)
+	}
+}
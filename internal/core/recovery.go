@@ -0,0 +1,329 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// InterruptedTransaction is a transaction whose process died before it
+// committed or rolled back, leaving its intent log behind
+type InterruptedTransaction struct {
+	Path  string // Intent log file
+	Entry JournalEntry
+}
+
+// getIntentDir returns the directory holding the intent logs of running
+// transactions (~/.dotcor/journal/pending)
+func getIntentDir() (string, error) {
+	dir, err := config.GetJournalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pending"), nil
+}
+
+// logIntent writes the operations executed so far, and pending if it is
+// about to run, to the transaction's intent log. Nothing is written until
+// an operation changes files or config, so steps like rendering a template
+// leave no log behind.
+func (t *Transaction) logIntent(pending Operation) {
+	if !journalEnabled() || (!t.logged && (pending == nil || !recoverable(pending))) {
+		return
+	}
+
+	entry := t.entry()
+	entry.PID = os.Getpid()
+	if pending != nil {
+		intent := intentOp(pending)
+		entry.Pending = &intent
+	}
+
+	if err := writeIntent(entry); err != nil {
+		log.Warn("intent log write failed", "error", err)
+		return
+	}
+	t.logged = true
+}
+
+// clearIntent removes the intent log once the transaction has finished
+func (t *Transaction) clearIntent() {
+	if t.logged {
+		removeIntent(t.id)
+		t.logged = false
+	}
+}
+
+// writeIntent replaces an intent log, syncing it so it survives a crash
+func writeIntent(entry JournalEntry) error {
+	dir, err := getIntentDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("creating intent log directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding intent log: %w", err)
+	}
+
+	path := filepath.Join(dir, entry.ID+".json")
+	tempPath := path + ".tmp"
+	f, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("writing intent log: %w", err)
+	}
+	if _, err := f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("writing intent log: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("writing intent log: %w", err)
+	}
+	return nil
+}
+
+// removeIntent deletes the intent log of the transaction with id
+func removeIntent(id string) {
+	dir, err := getIntentDir()
+	if err != nil {
+		return
+	}
+	if err := os.Remove(filepath.Join(dir, id+".json")); err != nil && !os.IsNotExist(err) {
+		log.Warn("removing intent log failed", "id", id, "error", err)
+	}
+}
+
+// recoverable reports whether op changes anything a crash would need
+// rolled back
+func recoverable(op Operation) bool {
+	j := journalOp(op)
+	return j.Op != journalStep || j.Path != ""
+}
+
+// intentOp records op before it runs, with what was there beforehand so
+// recovery can tell whether an interrupted operation took effect
+func intentOp(op Operation) JournalOp {
+	j := journalOp(op)
+
+	switch op := op.(type) {
+	case *MoveFileOp:
+		j.Existed = lexists(op.Dst)
+	case *CopyFileOp:
+		j.Existed = lexists(op.Dst)
+	case *CreateSymlinkOp, *CreateHardlinkOp, *WriteFileOp:
+		j.Existed = lexists(j.Path)
+	case *StepOp:
+		j.Existed = op.Deploys != "" && lexists(op.Deploys)
+	case *RemoveSymlinkOp:
+		if target, err := os.Readlink(op.Link); err == nil {
+			j.Target = absLinkTarget(op.Link, target)
+			j.Style = config.LinkStyleAbsolute
+			if rel, _ := fs.IsRelativeSymlink(op.Link); rel {
+				j.Style = config.LinkStyleRelative
+			}
+		}
+	case *AddToConfigOp:
+		j.Existed = op.Config.IsManaged(op.File.SourcePath)
+	case *RemoveFromConfigOp:
+		if file, err := op.Config.GetManagedFile(op.SourcePath); err == nil {
+			saved := *file
+			j.File = &saved
+		}
+	}
+	return j
+}
+
+// absLinkTarget resolves a symlink's target against the link's directory
+func absLinkTarget(link, target string) string {
+	if filepath.IsAbs(target) {
+		return target
+	}
+	return filepath.Join(filepath.Dir(link), target)
+}
+
+// lexists reports whether anything, even a broken symlink, is at path
+func lexists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// InterruptedTransactions returns the intent logs left by dotcor processes
+// that are no longer running, newest first
+func InterruptedTransactions() ([]InterruptedTransaction, error) {
+	dir, err := getIntentDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading intent logs: %w", err)
+	}
+
+	var interrupted []InterruptedTransaction
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading intent log: %w", err)
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Warn("skipping unreadable intent log", "path", path, "error", err)
+			continue
+		}
+
+		// A transaction in a live process is still running
+		if entry.PID == os.Getpid() {
+			continue
+		}
+		if alive, _ := isProcessAlive(entry.PID); alive {
+			continue
+		}
+		interrupted = append(interrupted, InterruptedTransaction{Path: path, Entry: entry})
+	}
+
+	sort.Slice(interrupted, func(i, j int) bool {
+		return interrupted[i].Entry.Time.After(interrupted[j].Entry.Time)
+	})
+	return interrupted, nil
+}
+
+// RecoverTransactions rolls back interrupted transactions, newest first, so
+// a killed 'dotcor add' never leaves a moved file without its symlink. It
+// must be called holding the lock. It stops at the first transaction that
+// can't be rolled back, returning those that were.
+func RecoverTransactions(cfg *config.Config) ([]InterruptedTransaction, error) {
+	interrupted, err := InterruptedTransactions()
+	if err != nil || len(interrupted) == 0 {
+		return nil, err
+	}
+
+	// A transaction that reached the journal committed before the crash
+	entries, err := ReadJournal()
+	if err != nil {
+		return nil, err
+	}
+	committed := make(map[string]bool)
+	for _, e := range entries {
+		committed[e.ID] = true
+	}
+
+	var recovered []InterruptedTransaction
+	for _, it := range interrupted {
+		if committed[it.Entry.ID] {
+			os.Remove(it.Path)
+			continue
+		}
+
+		tx, err := recoveryTransaction(cfg, it.Entry)
+		if err == nil {
+			err = tx.ExecuteAll()
+		}
+		if err != nil {
+			log.Error("recovery failed", "id", it.Entry.ID, "command", it.Entry.Command, "error", err)
+			return recovered, fmt.Errorf("rolling back interrupted '%s': %w", it.Entry.Command, err)
+		}
+		tx.Commit()
+
+		log.Warn("rolled back interrupted transaction", "id", it.Entry.ID, "command", it.Entry.Command)
+		recovered = append(recovered, it)
+	}
+	return recovered, nil
+}
+
+// recoveryTransaction builds a transaction reverting an interrupted one:
+// the operation that was running, if it took effect, then the completed
+// operations newest first
+func recoveryTransaction(cfg *config.Config, entry JournalEntry) (*Transaction, error) {
+	tx := NewTransaction()
+	tx.recovers = entry.ID
+
+	if entry.Pending != nil {
+		tx.operations = append(tx.operations, entry.Pending.recoverInverse(cfg)...)
+	}
+	released := make(map[string]bool)
+	for k := len(entry.Ops) - 1; k >= 0; k-- {
+		inverse, err := entry.Ops[k].inverse(cfg, released)
+		if err != nil {
+			return nil, err
+		}
+		tx.operations = append(tx.operations, inverse...)
+	}
+	return tx, nil
+}
+
+// recoverInverse returns the operations that revert an interrupted j,
+// judging from what is on disk whether it took effect
+func (j JournalOp) recoverInverse(cfg *config.Config) []Operation {
+	// created reverts an operation that may have created path
+	created := func(path string) []Operation {
+		if j.Existed || !lexists(path) {
+			return nil
+		}
+		return []Operation{&RemoveFileOp{Path: path}}
+	}
+
+	switch j.Op {
+	case journalMove:
+		if !lexists(j.Dst) {
+			return nil
+		}
+		if !lexists(j.Src) {
+			return []Operation{&MoveFileOp{Src: j.Dst, Dst: j.Src}}
+		}
+		// Copied across devices, but the original wasn't removed yet
+		return created(j.Dst)
+	case journalCopy:
+		return created(j.Dst)
+	case journalHardlink, journalWriteFile, journalStep:
+		if j.Path == "" {
+			return nil
+		}
+		return created(j.Path)
+	case journalSymlink:
+		if isLink, _ := fs.IsSymlink(j.Path); isLink && !j.Existed {
+			return []Operation{&RemoveSymlinkOp{Link: j.Path}}
+		}
+	case journalRemoveSymlink:
+		if !lexists(j.Path) && j.Target != "" {
+			return []Operation{&CreateSymlinkOp{Target: j.Target, Link: j.Path, Style: j.Style}}
+		}
+	case journalRemoveFile:
+		if !lexists(j.Path) {
+			// The backup was taken but not recorded; 'dotcor restore' lists it
+			log.Warn("interrupted removal can't be rolled back", "path", j.Path)
+		}
+	case journalAddToConfig:
+		if j.File != nil && !j.Existed && cfg.IsManaged(j.File.SourcePath) {
+			return []Operation{&RemoveFromConfigOp{Config: cfg, SourcePath: j.File.SourcePath}}
+		}
+	case journalRemoveFromConfig:
+		if j.File != nil && !cfg.IsManaged(j.File.SourcePath) {
+			return []Operation{&AddToConfigOp{Config: cfg, File: *j.File}}
+		}
+	}
+	return nil
+}
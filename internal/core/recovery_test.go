@@ -0,0 +1,120 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// simulateCrash hands the intent logs to a process that no longer exists
+func simulateCrash(t *testing.T) {
+	t.Helper()
+	dir, err := getIntentDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) == 0 {
+		t.Fatal("no intent log was written")
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			t.Fatal(err)
+		}
+		entry.PID = 999999999
+		if err := writeIntent(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// interruptedAdd starts adding ~/.zshrc, stopping once the file has moved
+// into the repo and the symlink is about to be created. With linked, the
+// symlink is created before the crash but after it was logged.
+func interruptedAdd(t *testing.T, cfg *config.Config, source string, linked bool) {
+	t.Helper()
+	SetJournalCommand("dotcor add")
+	repoFile := filepath.Join(cfg.RepoPath, "shell", "zshrc")
+
+	tx := NewTransaction()
+	if err := tx.Execute(&MoveFileOp{Src: source, Dst: repoFile}); err != nil {
+		t.Fatal(err)
+	}
+	link := &CreateSymlinkOp{Target: repoFile, Link: source}
+	tx.logIntent(link)
+	if linked {
+		if err := link.Do(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	simulateCrash(t)
+}
+
+func TestRecoverTransactions(t *testing.T) {
+	for _, linked := range []bool{false, true} {
+		cfg, source := setupJournalTest(t)
+		interruptedAdd(t, cfg, source, linked)
+
+		interrupted, err := InterruptedTransactions()
+		if err != nil || len(interrupted) != 1 {
+			t.Fatalf("InterruptedTransactions() = %v, %v, want 1", interrupted, err)
+		}
+		if interrupted[0].Entry.Command != "dotcor add" {
+			t.Errorf("Command = %q", interrupted[0].Entry.Command)
+		}
+
+		recovered, err := RecoverTransactions(cfg)
+		if err != nil || len(recovered) != 1 {
+			t.Fatalf("linked=%v: RecoverTransactions() = %v, %v", linked, recovered, err)
+		}
+		if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+			t.Fatalf("linked=%v: ~/.zshrc should be moved back, got %v, %v", linked, info, err)
+		}
+		if data, _ := os.ReadFile(source); string(data) != "export EDITOR=vim" {
+			t.Errorf("linked=%v: ~/.zshrc = %q", linked, data)
+		}
+		if lexists(filepath.Join(cfg.RepoPath, "shell", "zshrc")) {
+			t.Errorf("linked=%v: repo file should be gone", linked)
+		}
+
+		// The intent log is gone and nothing reached the journal
+		if left, _ := InterruptedTransactions(); len(left) != 0 {
+			t.Errorf("linked=%v: %d intent log(s) left after recovery", linked, len(left))
+		}
+		if entries, _ := ReadJournal(); len(entries) != 0 {
+			t.Errorf("linked=%v: journal has %d entries, want none", linked, len(entries))
+		}
+	}
+}
+
+func TestIntentLogCleared(t *testing.T) {
+	cfg, source := setupJournalTest(t)
+
+	// A committed transaction leaves no intent log
+	journaledAdd(t, cfg)
+	if files, _ := filepath.Glob(filepath.Join(cfg.RepoPath, "..", "journal", "pending", "*")); len(files) != 0 {
+		t.Errorf("intent logs left after commit: %v", files)
+	}
+
+	// Nor does one that was rolled back
+	SetJournalCommand("dotcor remove")
+	tx := NewTransaction()
+	if err := tx.Execute(&RemoveSymlinkOp{Link: source}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	if files, _ := filepath.Glob(filepath.Join(cfg.RepoPath, "..", "journal", "pending", "*")); len(files) != 0 {
+		t.Errorf("intent logs left after rollback: %v", files)
+	}
+	if !lexists(source) {
+		t.Error("rollback should restore the symlink")
+	}
+}
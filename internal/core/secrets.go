@@ -0,0 +1,276 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// AllowSecretMarker on a line, usually in a comment, suppresses secret
+// findings on that line
+const AllowSecretMarker = "dotcor:allow-secret"
+
+// defaultEntropy is the minimum bits per character for a string to count
+// as high-entropy. Random base64 or mixed-case tokens score above it; words,
+// identifiers and hex hashes (at most 4 bits) don't.
+const defaultEntropy = 4.0
+
+// SecretRule is a pattern for one kind of secret
+type SecretRule struct {
+	ID       string
+	Severity string
+	Pattern  *regexp.Regexp
+}
+
+// secretRules are the built-in rules. Severities can be changed, or rules
+// turned off, by ID in the scan.rules section of config.yaml.
+var secretRules = []SecretRule{
+	// API keys and tokens
+	{"api-key", config.SeverityHigh, regexp.MustCompile(`(?i)api[_-]?key\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`)},
+	{"api-secret", config.SeverityHigh, regexp.MustCompile(`(?i)api[_-]?secret\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`)},
+	{"access-token", config.SeverityHigh, regexp.MustCompile(`(?i)access[_-]?token\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`)},
+	{"auth-token", config.SeverityHigh, regexp.MustCompile(`(?i)auth[_-]?token\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`)},
+
+	// Passwords
+	{"password", config.SeverityMedium, regexp.MustCompile(`(?i)passw(?:or)?d\s*[:=]\s*['"]?[^\s'";]{8,}['"]?`)},
+
+	// Secrets
+	{"secret", config.SeverityMedium, regexp.MustCompile(`(?i)secret\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`)},
+	{"private-key-value", config.SeverityHigh, regexp.MustCompile(`(?i)private[_-]?key\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`)},
+
+	// Private key headers
+	{"private-key", config.SeverityHigh, regexp.MustCompile(`-----BEGIN\s+.*PRIVATE\s+KEY-----`)},
+
+	// Cloud provider credentials
+	{"aws-access-key-id", config.SeverityHigh, regexp.MustCompile(`(?i)aws[_-]?access[_-]?key[_-]?id\s*[:=]\s*['"]?[A-Z0-9]{20}['"]?`)},
+	{"aws-secret-access-key", config.SeverityHigh, regexp.MustCompile(`(?i)aws[_-]?secret[_-]?access[_-]?key\s*[:=]\s*['"]?[a-zA-Z0-9/+=]{40}['"]?`)},
+	{"cloud-secret", config.SeverityLow, regexp.MustCompile(`(?i)(?:azure|gcp)[_-]?.*secret`)},
+
+	// Database connection strings with passwords
+	{"database-url", config.SeverityHigh, regexp.MustCompile(`(?i)(?:postgres|mysql|mongodb)://[^:\s]+:[^@\s]+@`)},
+
+	// Generic credentials
+	{"credentials", config.SeverityMedium, regexp.MustCompile(`(?i)credentials\s*[:=]\s*['"]?[^\s'";]{10,}['"]?`)},
+}
+
+// entropyRuleID identifies findings from entropy analysis
+const entropyRuleID = "high-entropy"
+
+// entropyCandidate matches tokens long enough to be checked for entropy
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/_-]{20,}={0,2}`)
+
+// SecretFinding is a potential secret found by a SecretScanner
+type SecretFinding struct {
+	Rule     string `json:"rule" yaml:"rule"`
+	Severity string `json:"severity" yaml:"severity"`
+	Line     int    `json:"line" yaml:"line"`
+	Match    string `json:"match" yaml:"match"` // Redacted
+}
+
+// String formats the finding as "Line N: match"
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("Line %d: %s", f.Line, f.Match)
+}
+
+// SecretScanner finds potential secrets in file content
+type SecretScanner struct {
+	rules     []SecretRule
+	allowlist []*regexp.Regexp
+	entropy   float64 // Minimum entropy, 0 if the high-entropy rule is off
+	severity  string  // Severity of high-entropy findings
+}
+
+// NewSecretScanner creates a scanner with the built-in rules adjusted by scan
+func NewSecretScanner(scan config.ScanConfig) (*SecretScanner, error) {
+	if err := config.ValidateScanConfig(scan); err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{entropyRuleID: true}
+	for _, rule := range secretRules {
+		known[rule.ID] = true
+	}
+	for id := range scan.Rules {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown scan rule %q", id)
+		}
+	}
+
+	s := &SecretScanner{entropy: scan.Entropy, severity: config.SeverityLow}
+	if s.entropy == 0 {
+		s.entropy = defaultEntropy
+	}
+	if severity, ok := scan.Rules[entropyRuleID]; ok {
+		s.severity = severity
+		if severity == config.SeverityOff {
+			s.entropy = 0
+		}
+	}
+
+	for _, rule := range secretRules {
+		if severity, ok := scan.Rules[rule.ID]; ok {
+			if severity == config.SeverityOff {
+				continue
+			}
+			rule.Severity = severity
+		}
+		s.rules = append(s.rules, rule)
+	}
+
+	for _, pattern := range scan.Allowlist {
+		s.allowlist = append(s.allowlist, regexp.MustCompile(pattern))
+	}
+	return s, nil
+}
+
+// ScanFile scans a file. Binary files have no findings.
+func (s *SecretScanner) ScanFile(path string) ([]SecretFinding, error) {
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("expanding path: %w", err)
+	}
+
+	content, err := os.ReadFile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	return s.Scan(content), nil
+}
+
+// Scan returns the potential secrets in content, skipping binary content,
+// lines marked with AllowSecretMarker and lines matching the allowlist
+func (s *SecretScanner) Scan(content []byte) []SecretFinding {
+	if isBinary(content) {
+		return nil
+	}
+
+	var findings []SecretFinding
+	for lineNum, line := range strings.Split(string(content), "\n") {
+		if strings.Contains(line, AllowSecretMarker) || s.allowed(line) {
+			continue
+		}
+
+		found := false
+		for _, rule := range s.rules {
+			for _, match := range rule.Pattern.FindAllString(line, -1) {
+				findings = append(findings, SecretFinding{
+					Rule:     rule.ID,
+					Severity: rule.Severity,
+					Line:     lineNum + 1,
+					Match:    redactSecret(match),
+				})
+				found = true
+			}
+		}
+
+		// Entropy analysis only reports lines no rule explained
+		if found || s.entropy == 0 {
+			continue
+		}
+		for _, token := range entropyCandidate.FindAllString(line, -1) {
+			if mixedCase(token) && ShannonEntropy(token) >= s.entropy {
+				findings = append(findings, SecretFinding{
+					Rule:     entropyRuleID,
+					Severity: s.severity,
+					Line:     lineNum + 1,
+					Match:    redactSecret(token),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// allowed checks if line matches an allowlist pattern
+func (s *SecretScanner) allowed(line string) bool {
+	for _, re := range s.allowlist {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectSecrets scans file content for potential secrets with the default rules
+func DetectSecrets(path string) ([]string, error) {
+	scanner, err := NewSecretScanner(config.ScanConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	findings, err := scanner.ScanFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, f := range findings {
+		warnings = append(warnings, f.String())
+	}
+	return warnings, nil
+}
+
+// ShannonEntropy returns the Shannon entropy of s in bits per character
+func ShannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// SeverityAtLeast checks if severity is min or more severe
+func SeverityAtLeast(severity, min string) bool {
+	rank := map[string]int{config.SeverityLow: 1, config.SeverityMedium: 2, config.SeverityHigh: 3}
+	return rank[severity] >= rank[min]
+}
+
+// mixedCase checks if token has an upper- and lowercase letter and a digit,
+// as generated secrets do and words, paths and hex hashes don't
+func mixedCase(token string) bool {
+	var upper, lower, digit bool
+	for _, r := range token {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// redactSecret keeps the start of a match so it can be located, hiding the rest
+func redactSecret(match string) string {
+	keep := len(match) / 3
+	if keep > 16 {
+		keep = 16
+	}
+	return match[:keep] + "****"
+}
+
+// isBinary checks for a NUL byte near the start, as git does
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// SensitiveDir is a directory holding key material. Only its safe files can
+// be managed, and they and the directory get fixed permissions, as ssh and
+// gpg refuse to use files others can read.
+type SensitiveDir struct {
+	Path     string      // Home-relative path with "~/"
+	DirPerm  os.FileMode // Enforced on the directory
+	FilePerm os.FileMode // Enforced on the managed files
+	Safe     []string    // Patterns, relative to Path, of the files that hold no keys
+}
+
+// sensitiveDirs are the directories with curated handling
+var sensitiveDirs = []SensitiveDir{
+	{
+		Path: "~/.ssh", DirPerm: 0700, FilePerm: 0600,
+		Safe: []string{"config", "config.d/*", "known_hosts", "authorized_keys", "rc"},
+	},
+	{
+		Path: "~/.gnupg", DirPerm: 0700, FilePerm: 0600,
+		Safe: []string{"gpg.conf", "gpg-agent.conf", "dirmngr.conf", "scdaemon.conf", "common.conf", "sshcontrol"},
+	},
+}
+
+// SensitiveDirFor returns the sensitive directory that is or contains path
+func SensitiveDirFor(p string) (SensitiveDir, bool) {
+	expanded, err := config.ExpandPath(p)
+	if err != nil {
+		return SensitiveDir{}, false
+	}
+	for _, d := range sensitiveDirs {
+		dir, err := d.Expanded()
+		if err != nil {
+			continue
+		}
+		if expanded == dir || strings.HasPrefix(expanded, dir+string(filepath.Separator)) {
+			return d, true
+		}
+	}
+	return SensitiveDir{}, false
+}
+
+// Expanded returns the absolute path of the directory
+func (d SensitiveDir) Expanded() (string, error) {
+	return config.ExpandPath(d.Path)
+}
+
+// IsSafe checks if p, a file in the directory, matches a safe pattern
+func (d SensitiveDir) IsSafe(p string) bool {
+	rel, ok := d.rel(p)
+	if !ok {
+		return false
+	}
+	for _, pattern := range d.Safe {
+		if matched, _ := path.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SafeFiles returns the regular files in the directory that are safe to
+// manage, sorted. Symlinks, such as files managed already, are left out.
+func (d SensitiveDir) SafeFiles() ([]string, error) {
+	dir, err := d.Expanded()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if entry.Type().IsRegular() && d.IsSafe(p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// rel returns p relative to the directory in slash form
+func (d SensitiveDir) rel(p string) (string, bool) {
+	dir, err := d.Expanded()
+	if err != nil {
+		return "", false
+	}
+	expanded, err := config.ExpandPath(p)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, expanded)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSensitiveDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	ssh := filepath.Join(home, ".ssh")
+	for _, name := range []string{"config", "config.d/work", "known_hosts", "id_ed25519", "id_ed25519.pub"} {
+		p := filepath.Join(ssh, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, ok := SensitiveDirFor("~/.ssh/id_ed25519")
+	if !ok || d.Path != "~/.ssh" {
+		t.Fatalf("SensitiveDirFor(~/.ssh/id_ed25519) = %+v, %v", d, ok)
+	}
+	if _, ok := SensitiveDirFor(ssh); !ok {
+		t.Error("SensitiveDirFor() doesn't match the directory itself")
+	}
+	for _, p := range []string{"~/.sshrc", "~/.config/ssh/config", "~/.gnupgx/gpg.conf"} {
+		if d, ok := SensitiveDirFor(p); ok {
+			t.Errorf("SensitiveDirFor(%s) = %+v", p, d)
+		}
+	}
+
+	for p, want := range map[string]bool{
+		"~/.ssh/config":         true,
+		"~/.ssh/config.d/work":  true,
+		"~/.ssh/id_ed25519":     false,
+		"~/.ssh/id_ed25519.pub": false,
+		"~/.ssh":                false,
+	} {
+		if got := d.IsSafe(p); got != want {
+			t.Errorf("IsSafe(%s) = %v, want %v", p, got, want)
+		}
+	}
+
+	files, err := d.SafeFiles()
+	if err != nil {
+		t.Fatalf("SafeFiles() error = %v", err)
+	}
+	want := []string{filepath.Join(ssh, "config"), filepath.Join(ssh, "config.d", "work"), filepath.Join(ssh, "known_hosts")}
+	if len(files) != len(want) {
+		t.Fatalf("SafeFiles() = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("SafeFiles()[%d] = %s, want %s", i, files[i], want[i])
+		}
+	}
+
+	gnupg, _ := SensitiveDirFor("~/.gnupg/gpg.conf")
+	if files, err := gnupg.SafeFiles(); err != nil || len(files) != 0 {
+		t.Errorf("SafeFiles() of a missing directory = %v, %v", files, err)
+	}
+}
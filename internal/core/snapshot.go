@@ -0,0 +1,245 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+	"gopkg.in/yaml.v3"
+)
+
+// Snapshots are stored in ~/.dotcor/snapshots/<id>/, with the snapshot in
+// snapshot.json and the config as it was in config.yaml
+const (
+	snapshotFile       = "snapshot.json"
+	snapshotConfigFile = "config.yaml"
+)
+
+// ErrSnapshotNotFound is returned when no snapshot has the requested ID
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Deployment states recorded for each managed file
+const (
+	SnapshotSymlink  = "symlink"  // Symlink, Target is where it points
+	SnapshotCopy     = "copy"     // Copy-mode file in place
+	SnapshotHardlink = "hardlink" // Hard link to the repo file
+	SnapshotComposed = "composed" // Generated file including the repo file
+	SnapshotFile     = "file"     // A regular file that isn't deployed by dotcor
+	SnapshotMissing  = "missing"  // Nothing at the source path
+)
+
+// Snapshot records the complete deployment state at one point: the repo
+// commit, the config and where every managed file pointed
+type Snapshot struct {
+	ID     string         `json:"id" yaml:"id"`
+	Name   string         `json:"name,omitempty" yaml:"name,omitempty"`
+	Time   time.Time      `json:"time" yaml:"time"`
+	Commit string         `json:"commit,omitempty" yaml:"commit,omitempty"` // Repo HEAD, empty without git
+	Links  []SnapshotLink `json:"links" yaml:"links"`
+}
+
+// SnapshotLink is the deployment state of one managed file
+type SnapshotLink struct {
+	SourcePath string `json:"source_path" yaml:"source_path"`
+	RepoPath   string `json:"repo_path" yaml:"repo_path"`
+	State      string `json:"state" yaml:"state"`
+	Target     string `json:"target,omitempty" yaml:"target,omitempty"` // Symlink target as stored
+}
+
+// GetSnapshotsDir returns the snapshot directory path (~/.dotcor/snapshots),
+// kept per repository
+func GetSnapshotsDir() (string, error) {
+	stateDir, err := config.GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "snapshots"), nil
+}
+
+// CreateSnapshot records the current deployment of cfg's files together
+// with cfg itself and commit, the repo's HEAD
+func CreateSnapshot(cfg *config.Config, name, commit string) (*Snapshot, error) {
+	snapshotsDir, err := GetSnapshotsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{Name: name, Time: time.Now(), Commit: commit, Links: []SnapshotLink{}}
+	for _, mf := range cfg.ManagedFiles {
+		link, err := snapshotLink(cfg, mf)
+		if err != nil {
+			return nil, err
+		}
+		snap.Links = append(snap.Links, link)
+	}
+
+	// Snapshots taken within the same second get a counter
+	base := snap.Time.Format(TimestampFormat)
+	snap.ID = base
+	for counter := 1; fs.PathExists(filepath.Join(snapshotsDir, snap.ID)); counter++ {
+		snap.ID = fmt.Sprintf("%s_%d", base, counter)
+	}
+
+	cfgData, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	snapData, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	// Written to a temp directory and renamed, so a snapshot is never half there
+	dir := filepath.Join(snapshotsDir, snap.ID)
+	tempDir := dir + ".tmp"
+	if err := fs.EnsureDir(tempDir); err != nil {
+		return nil, fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, snapshotConfigFile), cfgData, 0644); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, snapshotFile), append(snapData, '\n'), 0644); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("writing snapshot: %w", err)
+	}
+	if err := os.Rename(tempDir, dir); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("saving snapshot: %w", err)
+	}
+
+	return snap, nil
+}
+
+// snapshotLink records how mf is deployed right now
+func snapshotLink(cfg *config.Config, mf config.ManagedFile) (SnapshotLink, error) {
+	link := SnapshotLink{SourcePath: mf.SourcePath, RepoPath: mf.RepoPath}
+
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return link, fmt.Errorf("invalid path %s: %w", mf.SourcePath, err)
+	}
+
+	info, err := os.Lstat(sourcePath)
+	switch {
+	case os.IsNotExist(err):
+		link.State = SnapshotMissing
+	case err != nil:
+		return link, fmt.Errorf("checking %s: %w", mf.SourcePath, err)
+	case info.Mode()&os.ModeSymlink != 0:
+		link.State = SnapshotSymlink
+		if link.Target, err = os.Readlink(sourcePath); err != nil {
+			return link, fmt.Errorf("reading link %s: %w", mf.SourcePath, err)
+		}
+	case mf.IsCopy():
+		link.State = SnapshotCopy
+	case mf.IsHardlink():
+		link.State = SnapshotFile
+		if repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath); err == nil {
+			if same, _ := fs.IsHardlinkTo(sourcePath, repoPath); same {
+				link.State = SnapshotHardlink
+			}
+		}
+	case mf.IsComposed():
+		link.State = SnapshotFile
+		if intact, _ := ComposedIntact(cfg, mf); intact {
+			link.State = SnapshotComposed
+		}
+	default:
+		link.State = SnapshotFile
+	}
+	return link, nil
+}
+
+// ListSnapshots returns every snapshot, newest first. Directories that
+// can't be read as snapshots are skipped.
+func ListSnapshots() ([]Snapshot, error) {
+	snapshotsDir, err := GetSnapshotsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(snapshotsDir)
+	if os.IsNotExist(err) {
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshots: %w", err)
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		// Temp directories are left by a snapshot that was interrupted
+		if !entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		snap, err := readSnapshot(filepath.Join(snapshotsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, *snap)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.After(snapshots[j].Time)
+	})
+	return snapshots, nil
+}
+
+// LoadSnapshot returns the snapshot with the given ID and the config it
+// recorded, migrated to the current version
+func LoadSnapshot(id string) (*Snapshot, *config.Config, error) {
+	snapshotsDir, err := GetSnapshotsDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	if id == "" || filepath.Base(id) != id {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+	}
+
+	dir := filepath.Join(snapshotsDir, id)
+	snap, err := readSnapshot(dir)
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, snapshotConfigFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading snapshot config: %w", err)
+	}
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("parsing snapshot config: %w", err)
+	}
+	if cfg.Version != config.CurrentConfigVersion {
+		migrated, err := config.MigrateConfig(&cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrating snapshot config: %w", err)
+		}
+		return snap, migrated, nil
+	}
+	return snap, &cfg, nil
+}
+
+// readSnapshot reads the snapshot stored in dir
+func readSnapshot(dir string) (*Snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", filepath.Base(dir), err)
+	}
+	return &snap, nil
+}
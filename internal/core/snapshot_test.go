@@ -0,0 +1,93 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	cfg := setupChecksumTest(t)
+	cfg.ManagedFiles = append(cfg.ManagedFiles, config.ManagedFile{SourcePath: "~/.gitconfig", RepoPath: "git/gitconfig"})
+	home := filepath.Dir(filepath.Dir(cfg.RepoPath))
+
+	// ~/.zshrc is linked, ~/.vimrc is a plain file, ~/.gitconfig is missing
+	zshTarget := filepath.Join(cfg.RepoPath, "shell", "zshrc")
+	if err := os.Symlink(zshTarget, filepath.Join(home, ".zshrc")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".vimrc"), []byte("set number"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := CreateSnapshot(cfg, "before cleanup", "abc123")
+	if err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+
+	want := map[string]string{"~/.zshrc": SnapshotSymlink, "~/.vimrc": SnapshotFile, "~/.gitconfig": SnapshotMissing}
+	for _, link := range snap.Links {
+		if link.State != want[link.SourcePath] {
+			t.Errorf("%s state = %q, want %q", link.SourcePath, link.State, want[link.SourcePath])
+		}
+		if link.SourcePath == "~/.zshrc" && link.Target != zshTarget {
+			t.Errorf("~/.zshrc target = %q, want %q", link.Target, zshTarget)
+		}
+	}
+
+	loaded, loadedCfg, err := LoadSnapshot(snap.ID)
+	if err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	if loaded.Name != "before cleanup" || loaded.Commit != "abc123" || len(loaded.Links) != 3 {
+		t.Errorf("LoadSnapshot() = %+v", loaded)
+	}
+	if len(loadedCfg.ManagedFiles) != 3 || loadedCfg.RepoPath != cfg.RepoPath {
+		t.Errorf("snapshot config = %+v, want the config at the time", loadedCfg)
+	}
+
+	// Later changes to the config don't affect the snapshot
+	cfg.ManagedFiles = cfg.ManagedFiles[:1]
+	if _, loadedCfg, _ := LoadSnapshot(snap.ID); len(loadedCfg.ManagedFiles) != 3 {
+		t.Error("snapshot config should be a copy")
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	cfg := setupChecksumTest(t)
+
+	if snapshots, err := ListSnapshots(); err != nil || len(snapshots) != 0 {
+		t.Fatalf("ListSnapshots() = %v, %v, want none", snapshots, err)
+	}
+
+	// Snapshots in the same second get distinct IDs
+	first, err := CreateSnapshot(cfg, "first", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := CreateSnapshot(cfg, "second", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("snapshots share ID %s", first.ID)
+	}
+
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots() error = %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Name != "second" {
+		t.Errorf("ListSnapshots() = %+v, want 2 newest first", snapshots)
+	}
+
+	if _, _, err := LoadSnapshot("no-such-snapshot"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("LoadSnapshot() error = %v, want ErrSnapshotNotFound", err)
+	}
+	if _, _, err := LoadSnapshot("../config.yaml"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("LoadSnapshot() with a path error = %v, want ErrSnapshotNotFound", err)
+	}
+}
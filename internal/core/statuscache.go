@@ -0,0 +1,125 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// StatusCacheFile is the name of the status cache in ~/.dotcor. It keeps
+// the summary shown by a bare 'dotcor', so the banner doesn't check every
+// file and run git on each call. A named repository keeps its own in
+// ~/.dotcor/repos/<name>.
+const StatusCacheFile = ".status-cache"
+
+// StatusCacheMaxAge bounds how stale the cache can get through changes no
+// watched file reveals, such as a symlink deleted by hand
+const StatusCacheMaxAge = 10 * time.Minute
+
+// StatusSummary is the cached status of the selected repository
+type StatusSummary struct {
+	Files        int  `json:"files"`         // Managed files on this platform
+	Problems     int  `json:"problems"`      // Files whose status isn't ok
+	Git          bool `json:"git"`           // The repository's git status is known
+	Uncommitted  bool `json:"uncommitted"`   // Changes not committed yet
+	RemoteExists bool `json:"remote_exists"` // A remote is configured
+	Ahead        int  `json:"ahead"`         // Commits to push
+	Behind       int  `json:"behind"`        // Commits to pull
+}
+
+// getStatusCachePath returns the path to the status cache
+func getStatusCachePath() (string, error) {
+	stateDir, err := config.GetRepoStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, StatusCacheFile), nil
+}
+
+// LoadStatusCache returns the cached status, or false if there is none or
+// it is stale: older than StatusCacheMaxAge, or than config.yaml or the
+// git files a commit, checkout or fetch touches
+func LoadStatusCache(cfg *config.Config) (StatusSummary, bool) {
+	path, err := getStatusCachePath()
+	if err != nil {
+		return StatusSummary{}, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > StatusCacheMaxAge {
+		return StatusSummary{}, false
+	}
+	for _, watched := range statusCacheWatched(cfg) {
+		if w, err := os.Stat(watched); err == nil && w.ModTime().After(info.ModTime()) {
+			return StatusSummary{}, false
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StatusSummary{}, false
+	}
+	var summary StatusSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return StatusSummary{}, false
+	}
+	return summary, true
+}
+
+// SaveStatusCache atomically replaces the status cache
+func SaveStatusCache(summary StatusSummary) error {
+	path, err := getStatusCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("encoding status cache: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing status cache: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing status cache: %w", err)
+	}
+	return nil
+}
+
+// statusCacheWatched returns the files whose modification makes the
+// status cache stale. Missing ones are skipped by the caller.
+func statusCacheWatched(cfg *config.Config) []string {
+	var watched []string
+	if path, err := config.GetConfigPath(); err == nil {
+		watched = append(watched, path)
+	}
+
+	repoPath, err := config.ExpandPath(cfg.RepoPath)
+	if err != nil || cfg.RepoPath == "" {
+		return watched
+	}
+	gitDir := filepath.Join(repoPath, ".git")
+
+	// A worktree's .git is a file naming its git directory
+	if data, err := os.ReadFile(gitDir); err == nil {
+		dir := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(repoPath, dir)
+		}
+		gitDir = dir
+	}
+	for _, name := range []string{"index", "HEAD", "FETCH_HEAD", "ORIG_HEAD"} {
+		watched = append(watched, filepath.Join(gitDir, name))
+	}
+	return watched
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestStatusCache(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+
+	repoDir := filepath.Join(tempDir, ".dotcor", "files")
+	if err := os.MkdirAll(filepath.Join(repoDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	index := filepath.Join(repoDir, ".git", "index")
+	if err := os.WriteFile(index, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Version: config.CurrentConfigVersion, RepoPath: repoDir}
+
+	if _, ok := LoadStatusCache(cfg); ok {
+		t.Fatal("LoadStatusCache() found a cache before one was saved")
+	}
+
+	want := StatusSummary{Files: 12, Problems: 1, Git: true, RemoteExists: true, Ahead: 2}
+	if err := SaveStatusCache(want); err != nil {
+		t.Fatalf("SaveStatusCache() error = %v", err)
+	}
+	got, ok := LoadStatusCache(cfg)
+	if !ok || got != want {
+		t.Fatalf("LoadStatusCache() = %+v, %v, want %+v, true", got, ok, want)
+	}
+
+	// A commit after the cache was written makes it stale
+	path, err := getStatusCachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LoadStatusCache(cfg); ok {
+		t.Error("LoadStatusCache() returned a cache older than the git index")
+	}
+
+	// So does age alone
+	if err := SaveStatusCache(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(index, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LoadStatusCache(cfg); !ok {
+		t.Error("LoadStatusCache() missed a fresh cache")
+	}
+	old := time.Now().Add(-2 * StatusCacheMaxAge)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LoadStatusCache(cfg); ok {
+		t.Error("LoadStatusCache() returned a cache older than StatusCacheMaxAge")
+	}
+}
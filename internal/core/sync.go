@@ -0,0 +1,229 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/git"
+)
+
+// Pathspecs selecting the dotfiles and the system files of the files root,
+// which are always committed separately
+var (
+	UserPathspecs   = []string{".", git.ExcludePathspec(config.SystemRepoDir)}
+	SystemPathspecs = []string{config.SystemRepoDir}
+)
+
+// SyncConflictError is a merge or rebase in the repository that has to be
+// resolved by hand before syncing
+type SyncConflictError struct {
+	Msg string
+}
+
+func (e *SyncConflictError) Error() string {
+	return e.Msg
+}
+
+// CheckSyncState returns a SyncConflictError if a rebase or merge is in
+// progress in the repository at repoPath, so nothing is committed on top of
+// it
+func CheckSyncState(repoPath string, st git.StatusInfo) error {
+	if st.Rebasing {
+		return &SyncConflictError{Msg: fmt.Sprintf("a rebase is in progress in %s\nFinish or abort it with git before syncing", repoPath)}
+	}
+	if st.Merging || st.ConflictCount > 0 {
+		return &SyncConflictError{Msg: fmt.Sprintf("a merge is in progress in %s\nResolve or abort it with git before syncing", repoPath)}
+	}
+	return nil
+}
+
+// CheckSyncBranch returns an error if this machine has a git_branch set and
+// another branch is checked out
+func CheckSyncBranch(cfg *config.Config, st git.StatusInfo) error {
+	if cfg.GitBranch == "" || cfg.GitBranch == st.Branch {
+		return nil
+	}
+	current := st.Branch
+	if current == "" {
+		current = "A detached HEAD"
+	}
+	return fmt.Errorf("%s is checked out, but this machine syncs %s (git_branch)\nRun 'dotcor branch switch %s' first",
+		current, cfg.GitBranch, cfg.GitBranch)
+}
+
+// CommitSync commits the changes in the repository at repoPath, dotfiles
+// and system files separately. A message given is used as is, without
+// commit_style or commit_template. Reports whether anything was committed.
+func CommitSync(repoPath, message string) (bool, error) {
+	commit := git.AutoCommitVerbatim
+	userMsg, systemMsg := message, message+" (system files)"
+	if message == "" {
+		now := time.Now().Format("2006-01-02 15:04")
+		commit = git.AutoCommit
+		userMsg = "Sync dotfiles - " + now
+		systemMsg = "Sync system files - " + now
+	}
+
+	committed := false
+	for _, group := range []struct {
+		pathspecs []string
+		message   string
+	}{
+		{UserPathspecs, userMsg},
+		{SystemPathspecs, systemMsg},
+	} {
+		changed, err := git.HasChanges(repoPath, group.pathspecs...)
+		if err != nil {
+			return committed, fmt.Errorf("checking for changes: %w", err)
+		}
+		if !changed {
+			continue
+		}
+		if err := commit(repoPath, group.message, group.pathspecs...); err != nil {
+			return committed, fmt.Errorf("committing changes: %w", err)
+		}
+		committed = true
+	}
+	return committed, nil
+}
+
+// AddCommitMessage is the message committing the files added at repoPaths
+func AddCommitMessage(repoPaths []string) string {
+	if len(repoPaths) == 1 {
+		return fmt.Sprintf("Add %s", filepath.Base(repoPaths[0]))
+	}
+	return fmt.Sprintf("Add %d dotfiles", len(repoPaths))
+}
+
+// RemoveCommitMessage is the message committing the removal of count files
+func RemoveCommitMessage(count int) string {
+	return fmt.Sprintf("Remove %d file(s) from management", count)
+}
+
+// PullResult is what PullWithBackup changed
+type PullResult struct {
+	Incoming []string // Repo paths changed by the pulled commits
+	Backups  []string // Backups of the linked files among them
+}
+
+// PullWithBackup fetches origin and backs up the linked files the new
+// commits will change, then merges them, or rebases onto them with rebase,
+// and checks out submodules they moved. Nothing is pulled without a remote.
+func PullWithBackup(cfg *config.Config, repoPath string, rebase bool) (PullResult, error) {
+	var result PullResult
+	if url, _ := git.GetRemoteURL(repoPath); url == "" {
+		return result, nil
+	}
+	if err := git.Fetch(repoPath); err != nil {
+		return result, err
+	}
+	incoming, err := git.GetIncomingFiles(repoPath)
+	if err != nil || len(incoming) == 0 {
+		return result, err
+	}
+	if result.Backups, err = BackupLinkedFiles(cfg, incoming); err != nil {
+		return result, err
+	}
+
+	pull := git.Pull
+	if rebase {
+		pull = git.PullRebase
+	}
+	if err := pull(repoPath); err != nil {
+		return result, err
+	}
+	result.Incoming = incoming
+
+	if git.IsGitInstalled() && git.HasSubmodules(repoPath) {
+		if err := git.UpdateSubmodules(repoPath); err != nil {
+			return result, fmt.Errorf("checking out submodules: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// Redeployed is a managed file whose deployed copy or hard link
+// RedeployChanged updated
+type Redeployed struct {
+	File config.ManagedFile
+	Err  error
+}
+
+// RedeployChanged updates the deployed files of the managed files at
+// repoPaths after git changed them. Copies don't follow the repo like
+// symlinks, so they are refreshed. Git replaces updated files rather than
+// rewriting them, which breaks hard links, so those are relinked.
+func RedeployChanged(cfg *config.Config, repoPaths []string) []Redeployed {
+	var redeployed []Redeployed
+	for _, mf := range ManagedFilesAt(cfg, repoPaths) {
+		var err error
+		switch {
+		case mf.IsCopy():
+			err = DeployCopy(cfg, mf)
+		case mf.IsHardlink():
+			err = DeployHardlink(cfg, mf)
+		default:
+			continue
+		}
+		redeployed = append(redeployed, Redeployed{File: mf, Err: err})
+	}
+	return redeployed
+}
+
+// ManagedFilesAt returns the managed files of this platform stored at the
+// given repo paths
+func ManagedFilesAt(cfg *config.Config, repoPaths []string) []config.ManagedFile {
+	changed := make(map[string]bool)
+	for _, p := range repoPaths {
+		changed[filepath.ToSlash(p)] = true
+	}
+
+	var files []config.ManagedFile
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		if changed[filepath.ToSlash(mf.RepoPath)] {
+			files = append(files, mf)
+		}
+	}
+	return files
+}
+
+// RemotePush is the outcome of pushing to one remote
+type RemotePush struct {
+	Name string
+	Err  error
+}
+
+// PushAllRemotes pushes the current branch to origin, if configured, and to
+// every remote in git_remotes, continuing past failures. Extra remotes are
+// added to the repository first. The error names every push that failed.
+// When origin has commits that aren't here, the mirrors aren't pushed to
+// either, so they don't get ahead of it.
+func PushAllRemotes(cfg *config.Config, repoPath string) ([]RemotePush, error) {
+	var pushes []RemotePush
+	var errs []error
+	report := func(name string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		pushes = append(pushes, RemotePush{Name: name, Err: err})
+	}
+
+	if url, _ := git.GetRemoteURL(repoPath); url != "" {
+		err := git.Push(repoPath)
+		report("origin", err)
+		if errors.Is(err, git.ErrBehindRemote) {
+			return pushes, errors.Join(errs...)
+		}
+	}
+	for _, remote := range cfg.GitRemotes {
+		err := git.SetRemote(repoPath, remote.Name, remote.URL)
+		if err == nil {
+			err = git.PushRemote(repoPath, remote.Name)
+		}
+		report(remote.Name, err)
+	}
+	return pushes, errors.Join(errs...)
+}
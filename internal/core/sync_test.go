@@ -0,0 +1,127 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/git"
+)
+
+func TestCheckSync(t *testing.T) {
+	var conflict *SyncConflictError
+	if err := CheckSyncState("/repo", git.StatusInfo{Rebasing: true}); !errors.As(err, &conflict) {
+		t.Errorf("CheckSyncState() while rebasing = %v, want SyncConflictError", err)
+	}
+	if err := CheckSyncState("/repo", git.StatusInfo{ConflictCount: 1}); !errors.As(err, &conflict) {
+		t.Errorf("CheckSyncState() with conflicts = %v, want SyncConflictError", err)
+	}
+	if err := CheckSyncState("/repo", git.StatusInfo{Branch: "main"}); err != nil {
+		t.Errorf("CheckSyncState() = %v", err)
+	}
+
+	cfg := &config.Config{GitBranch: "laptop"}
+	if err := CheckSyncBranch(cfg, git.StatusInfo{Branch: "main"}); err == nil {
+		t.Error("CheckSyncBranch() on another branch should return error")
+	}
+	if err := CheckSyncBranch(cfg, git.StatusInfo{Branch: "laptop"}); err != nil {
+		t.Errorf("CheckSyncBranch() = %v", err)
+	}
+}
+
+func TestCommitSync(t *testing.T) {
+	if !git.IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+	repoPath := t.TempDir()
+	if err := git.InitRepo(repoPath); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"config", "user.email", "test@example.com"}, {"config", "user.name", "Test"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	for _, p := range []string{"zshrc", filepath.Join(config.SystemRepoDir, "etc", "hosts")} {
+		full := filepath.Join(repoPath, p)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if committed, err := CommitSync(repoPath, "update"); err != nil || !committed {
+		t.Fatalf("CommitSync() = %v, %v", committed, err)
+	}
+	cmd := exec.Command("git", "log", "--format=%s")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "update (system files)\nupdate" {
+		t.Errorf("commits = %q, want dotfiles and system files separately", got)
+	}
+	if committed, err := CommitSync(repoPath, ""); err != nil || committed {
+		t.Errorf("CommitSync() of a clean tree = %v, %v", committed, err)
+	}
+}
+
+func TestPushAllRemotesBehind(t *testing.T) {
+	if !git.IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+	dir := t.TempDir()
+	run := func(wd string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = wd
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	origin := filepath.Join(dir, "origin.git")
+	mirror := filepath.Join(dir, "mirror.git")
+	repoPath := filepath.Join(dir, "repo")
+	other := filepath.Join(dir, "other")
+	run(dir, "init", "-q", "--bare", origin)
+	run(dir, "init", "-q", "--bare", mirror)
+	run(dir, "init", "-q", "-b", "main", repoPath)
+	run(repoPath, "commit", "-q", "--allow-empty", "-m", "first")
+	run(repoPath, "remote", "add", "origin", origin)
+	run(repoPath, "push", "-q", "-u", "origin", "main")
+
+	// Another machine pushes to origin, then this one commits
+	run(dir, "clone", "-q", "-b", "main", origin, other)
+	run(other, "commit", "-q", "--allow-empty", "-m", "theirs")
+	run(other, "push", "-q")
+	run(repoPath, "commit", "-q", "--allow-empty", "-m", "ours")
+
+	cfg := &config.Config{GitRemotes: []config.Remote{{Name: "mirror", URL: mirror}}}
+	pushes, err := PushAllRemotes(cfg, repoPath)
+	if !errors.Is(err, git.ErrBehindRemote) {
+		t.Fatalf("PushAllRemotes() error = %v, want ErrBehindRemote", err)
+	}
+	if len(pushes) != 1 || pushes[0].Name != "origin" {
+		t.Errorf("PushAllRemotes() pushes = %v, want only origin", pushes)
+	}
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "main")
+	cmd.Dir = mirror
+	if cmd.Run() == nil {
+		t.Error("PushAllRemotes() pushed to the mirror while behind origin")
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/log"
 )
 
 // Operation represents a reversible operation
@@ -23,10 +25,18 @@ type Operation interface {
 // 2. Planned execution: add operations to tx.operations, then call ExecuteAll()
 //
 // Both patterns track executed operations in 'executed' for rollback.
+//
+// A plan transaction (NewPlanTransaction) records operations from either
+// pattern without running them, so --dry-run can show what would happen.
 type Transaction struct {
 	operations []Operation // Planned operations (for ExecuteAll pattern)
 	executed   []Operation // Operations that have been executed (for rollback)
 	committed  bool
+	planOnly   bool   // Record operations without executing them
+	undoes     string // Journal run this transaction reverts (see UndoTransaction)
+	recovers   string // Intent log this transaction rolls back (see RecoverTransactions)
+	id         string // Journal ID, assigned when first logged
+	logged     bool   // An intent log is on disk
 }
 
 // NewTransaction creates a new transaction
@@ -38,19 +48,52 @@ func NewTransaction() *Transaction {
 	}
 }
 
-// Execute runs an operation and registers it for potential rollback
+// NewPlanTransaction creates a transaction that only records operations
+func NewPlanTransaction() *Transaction {
+	tx := NewTransaction()
+	tx.planOnly = true
+	return tx
+}
+
+// IsPlan returns whether the transaction only records operations
+func (t *Transaction) IsPlan() bool {
+	return t.planOnly
+}
+
+// Plan returns descriptions of the recorded operations, in order
+func (t *Transaction) Plan() []string {
+	plan := make([]string, 0, len(t.operations))
+	for _, op := range t.operations {
+		plan = append(plan, op.Describe())
+	}
+	return plan
+}
+
+// Execute runs an operation and registers it for potential rollback.
+// In a plan transaction the operation is only recorded.
 func (t *Transaction) Execute(op Operation) error {
 	if t.committed {
 		return fmt.Errorf("transaction already committed")
 	}
 
+	if t.planOnly {
+		t.operations = append(t.operations, op)
+		return nil
+	}
+
+	// Record the operation before it runs, so a crash can be rolled back
+	t.logIntent(op)
+
 	if err := op.Do(); err != nil {
+		log.Error("operation failed", "op", op.Describe(), "error", err)
 		// Operation failed, rollback all previously executed operations
 		t.Rollback()
 		return fmt.Errorf("executing %s: %w", op.Describe(), err)
 	}
 
+	log.Info("executed", "op", op.Describe())
 	t.executed = append(t.executed, op)
+	t.logIntent(nil)
 	return nil
 }
 
@@ -68,11 +111,15 @@ func (t *Transaction) Rollback() error {
 		op := t.executed[i]
 		if err := op.Undo(); err != nil {
 			// Continue rolling back other operations even if one fails
+			log.Error("rollback failed", "op", op.Describe(), "error", err)
 			errs = append(errs, fmt.Errorf("rolling back %s: %w", op.Describe(), err))
+			continue
 		}
+		log.Warn("rolled back", "op", op.Describe())
 	}
 
 	t.executed = nil
+	t.clearIntent()
 
 	if len(errs) > 0 {
 		return fmt.Errorf("rollback errors: %w", errors.Join(errs...))
@@ -80,8 +127,21 @@ func (t *Transaction) Rollback() error {
 	return nil
 }
 
-// Commit marks transaction as successful (clears rollback list)
+// Commit marks transaction as successful (clears rollback list) and
+// records the executed operations in the journal for 'dotcor undo'
 func (t *Transaction) Commit() {
+	if !t.committed && !t.planOnly {
+		switch {
+		case t.recovers != "":
+			// A recovery isn't journaled: the transaction it rolled back never was
+			removeIntent(t.recovers)
+		case len(t.executed) > 0 || t.undoes != "":
+			// An undo is journaled even if it had nothing to revert, so the
+			// next undo moves on to the run before
+			recordTransaction(t)
+		}
+		t.clearIntent()
+	}
 	t.committed = true
 	t.executed = nil // Clear executed list, no longer needed
 }
@@ -120,11 +180,15 @@ func (op *MoveFileOp) Describe() string {
 
 // CopyFileOp copies a file from Src to Dst
 type CopyFileOp struct {
-	Src string
-	Dst string
+	Src      string
+	Dst      string
+	Preserve bool // Keep extended attributes and ownership too, for a copy standing in for the original
 }
 
 func (op *CopyFileOp) Do() error {
+	if op.Preserve {
+		return fs.CopyPreservingMetadata(op.Src, op.Dst)
+	}
 	return fs.CopyFile(op.Src, op.Dst)
 }
 
@@ -140,10 +204,11 @@ func (op *CopyFileOp) Describe() string {
 type CreateSymlinkOp struct {
 	Target string // The file the symlink points to
 	Link   string // The symlink path
+	Style  string // config.LinkStyleAbsolute or relative (default)
 }
 
 func (op *CreateSymlinkOp) Do() error {
-	return fs.CreateSymlink(op.Target, op.Link)
+	return fs.CreateSymlinkWithStyle(op.Target, op.Link, op.Style)
 }
 
 func (op *CreateSymlinkOp) Undo() error {
@@ -154,6 +219,80 @@ func (op *CreateSymlinkOp) Describe() string {
 	return fmt.Sprintf("create symlink %s -> %s", op.Link, op.Target)
 }
 
+// CreateHardlinkOp creates a hard link
+type CreateHardlinkOp struct {
+	Target string // The existing file
+	Link   string // The new link path
+}
+
+func (op *CreateHardlinkOp) Do() error {
+	return fs.CreateHardlink(op.Target, op.Link)
+}
+
+func (op *CreateHardlinkOp) Undo() error {
+	return os.Remove(op.Link)
+}
+
+func (op *CreateHardlinkOp) Describe() string {
+	return fmt.Sprintf("create hard link %s => %s", op.Link, op.Target)
+}
+
+// StepOp is an operation built from functions, for one-off steps that don't
+// warrant their own type. A nil UndoFunc leaves the step in place on rollback.
+// The functions can't be journaled, so 'dotcor undo' only reverts a step
+// that sets Deploys, by removing the file it deployed.
+type StepOp struct {
+	Desc     string
+	DoFunc   func() error
+	UndoFunc func() error
+	Deploys  string // File the step creates, if any
+}
+
+func (op *StepOp) Do() error {
+	return op.DoFunc()
+}
+
+func (op *StepOp) Undo() error {
+	if op.UndoFunc == nil {
+		return nil
+	}
+	return op.UndoFunc()
+}
+
+func (op *StepOp) Describe() string {
+	return op.Desc
+}
+
+// BackupFileOp backs up Path. Backups are kept on rollback.
+type BackupFileOp struct {
+	Path       string
+	File       *config.ManagedFile // Managed file Path belongs to, if any
+	BackupPath string              // Set by Do
+}
+
+func (op *BackupFileOp) Do() error {
+	var backupPath string
+	var err error
+	if op.File != nil {
+		backupPath, err = CreateManagedBackup(op.Path, *op.File)
+	} else {
+		backupPath, err = CreateBackup(op.Path)
+	}
+	if err != nil {
+		return err
+	}
+	op.BackupPath = backupPath
+	return nil
+}
+
+func (op *BackupFileOp) Undo() error {
+	return nil
+}
+
+func (op *BackupFileOp) Describe() string {
+	return fmt.Sprintf("back up %s", op.Path)
+}
+
 // RemoveSymlinkOp removes a symlink (saves target for undo)
 type RemoveSymlinkOp struct {
 	Link         string
@@ -258,20 +397,21 @@ func (op *AddToConfigOp) Describe() string {
 
 // RemoveFromConfigOp removes a managed file from config
 type RemoveFromConfigOp struct {
-	Config    *config.Config
-	savedFile *config.ManagedFile // Saved for undo
-	sourcePath string
+	Config     *config.Config
+	SourcePath string
+	savedFile  *config.ManagedFile // Saved for undo
 }
 
 func (op *RemoveFromConfigOp) Do() error {
 	// Save file info for undo
-	file, err := op.Config.GetManagedFile(op.sourcePath)
+	file, err := op.Config.GetManagedFile(op.SourcePath)
 	if err != nil {
 		return err
 	}
-	op.savedFile = file
+	saved := *file // file points into ManagedFiles, which the removal shifts
+	op.savedFile = &saved
 
-	return op.Config.RemoveManagedFile(op.sourcePath)
+	return op.Config.RemoveManagedFile(op.SourcePath)
 }
 
 func (op *RemoveFromConfigOp) Undo() error {
@@ -282,7 +422,7 @@ func (op *RemoveFromConfigOp) Undo() error {
 }
 
 func (op *RemoveFromConfigOp) Describe() string {
-	return fmt.Sprintf("remove %s from config", op.sourcePath)
+	return fmt.Sprintf("remove %s from config", op.SourcePath)
 }
 
 // WriteFileOp writes content to a file (backs up existing for undo)
@@ -326,7 +466,8 @@ func (op *WriteFileOp) Describe() string {
 
 // AddFileTransaction creates a transaction for adding a file to dotcor.
 // It builds a planned transaction - call ExecuteAll() to run the operations.
-// Steps: move to repo -> create symlink -> add to config
+// Steps: move to repo -> create symlink -> add to config (copy mode: copy to repo -> add to config,
+// hardlink mode: link into repo -> add to config, compose mode: move to repo -> write composed file -> add to config)
 // Note: Backup is handled separately by the caller (backups are kept regardless of rollback).
 func AddFileTransaction(cfg *config.Config, sourcePath string, repoPath string, mf config.ManagedFile) (*Transaction, error) {
 	tx := NewTransaction()
@@ -343,19 +484,170 @@ func AddFileTransaction(cfg *config.Config, sourcePath string, repoPath string,
 		return nil, err
 	}
 
-	// 1. Move file to repo
+	if mf.IsCopy() {
+		// 1. Copy file to repo, leaving the original in place
+		tx.operations = append(tx.operations, &CopyFileOp{
+			Src: expandedSource,
+			Dst: fullRepoPath,
+		})
+	} else if mf.IsHardlink() {
+		// 1. Hard link the original into the repo, so both share an inode
+		tx.operations = append(tx.operations, &CreateHardlinkOp{
+			Target: expandedSource,
+			Link:   fullRepoPath,
+		})
+	} else if mf.IsComposed() {
+		// 1. Move file to repo
+		tx.operations = append(tx.operations, &MoveFileOp{
+			Src: expandedSource,
+			Dst: fullRepoPath,
+		})
+
+		// 2. Write the file that includes it
+		tx.operations = append(tx.operations, &StepOp{
+			Desc:     fmt.Sprintf("compose %s from %s", expandedSource, fullRepoPath),
+			DoFunc:   func() error { return DeployComposed(cfg, mf) },
+			UndoFunc: func() error { return os.Remove(expandedSource) },
+			Deploys:  expandedSource,
+		})
+	} else {
+		// 1. Move file to repo
+		tx.operations = append(tx.operations, &MoveFileOp{
+			Src: expandedSource,
+			Dst: fullRepoPath,
+		})
+
+		// 2. Create symlink
+		tx.operations = append(tx.operations, &CreateSymlinkOp{
+			Target: fullRepoPath,
+			Link:   expandedSource,
+			Style:  cfg.LinkStyle,
+		})
+	}
+
+	// 3. Add to config
+	tx.operations = append(tx.operations, &AddToConfigOp{
+		Config: cfg,
+		File:   mf,
+	})
+
+	return tx, nil
+}
+
+// ReownSymlinkTransaction creates a transaction that imports the target of a
+// symlink pointing outside the repo and repoints the symlink at the repo copy.
+// The original target is left in place.
+func ReownSymlinkTransaction(cfg *config.Config, sourcePath string, repoPath string, mf config.ManagedFile) (*Transaction, error) {
+	return symlinkTargetTransaction(cfg, sourcePath, repoPath, mf, false)
+}
+
+// MoveSymlinkTargetTransaction creates a transaction that moves the target of
+// a symlink pointing outside the repo into the repo and repoints the symlink
+func MoveSymlinkTargetTransaction(cfg *config.Config, sourcePath string, repoPath string, mf config.ManagedFile) (*Transaction, error) {
+	return symlinkTargetTransaction(cfg, sourcePath, repoPath, mf, true)
+}
+
+// symlinkTargetTransaction copies or moves a symlink's real target into the
+// repo, then replaces the symlink with one pointing at the repo file
+func symlinkTargetTransaction(cfg *config.Config, sourcePath string, repoPath string, mf config.ManagedFile, move bool) (*Transaction, error) {
+	tx := NewTransaction()
+
+	fullRepoPath, err := config.GetRepoFilePath(cfg, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	expandedSource, err := config.ExpandPath(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Follow the whole link chain to the real file
+	target, err := filepath.EvalSymlinks(expandedSource)
+	if err != nil {
+		return nil, fmt.Errorf("resolving symlink: %w", err)
+	}
+
+	// 1. Copy or move real target into repo
+	if move {
+		tx.operations = append(tx.operations, &MoveFileOp{
+			Src: target,
+			Dst: fullRepoPath,
+		})
+	} else {
+		tx.operations = append(tx.operations, &CopyFileOp{
+			Src: target,
+			Dst: fullRepoPath,
+		})
+	}
+
+	// 2. Remove old symlink
+	tx.operations = append(tx.operations, &RemoveSymlinkOp{
+		Link: expandedSource,
+	})
+
+	// 3. Create symlink to repo
+	tx.operations = append(tx.operations, &CreateSymlinkOp{
+		Target: fullRepoPath,
+		Link:   expandedSource,
+		Style:  cfg.LinkStyle,
+	})
+
+	// 4. Add to config
+	tx.operations = append(tx.operations, &AddToConfigOp{
+		Config: cfg,
+		File:   mf,
+	})
+
+	return tx, nil
+}
+
+// AddSecretTransaction creates a transaction for adding an encrypted file.
+// The ciphertext is written to the repo while the plaintext moves to the
+// local secrets directory, which the symlink points at.
+// Steps: write encrypted repo file -> move to secrets dir -> create symlink -> add to config
+func AddSecretTransaction(cfg *config.Config, sourcePath string, repoPath string, mf config.ManagedFile, ciphertext []byte) (*Transaction, error) {
+	tx := NewTransaction()
+
+	fullRepoPath, err := config.GetRepoFilePath(cfg, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	decryptedPath, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return nil, err
+	}
+
+	expandedSource, err := config.ExpandPath(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Write encrypted copy to repo
+	tx.operations = append(tx.operations, &CreateDirOp{
+		Path: filepath.Dir(fullRepoPath),
+	})
+	tx.operations = append(tx.operations, &WriteFileOp{
+		Path:    fullRepoPath,
+		Content: ciphertext,
+		Mode:    0644,
+	})
+
+	// 2. Move plaintext to the secrets directory
 	tx.operations = append(tx.operations, &MoveFileOp{
 		Src: expandedSource,
-		Dst: fullRepoPath,
+		Dst: decryptedPath,
 	})
 
-	// 2. Create symlink
+	// 3. Create symlink to decrypted copy
 	tx.operations = append(tx.operations, &CreateSymlinkOp{
-		Target: fullRepoPath,
+		Target: decryptedPath,
 		Link:   expandedSource,
+		Style:  cfg.LinkStyle,
 	})
 
-	// 3. Add to config
+	// 4. Add to config
 	tx.operations = append(tx.operations, &AddToConfigOp{
 		Config: cfg,
 		File:   mf,
@@ -364,8 +656,12 @@ func AddFileTransaction(cfg *config.Config, sourcePath string, repoPath string,
 	return tx, nil
 }
 
-// ExecuteAll executes all operations in the transaction
+// ExecuteAll executes all operations in the transaction. A plan
+// transaction already holds its operations, so nothing runs.
 func (t *Transaction) ExecuteAll() error {
+	if t.planOnly {
+		return nil
+	}
 	for _, op := range t.operations {
 		if err := t.Execute(op); err != nil {
 			return err
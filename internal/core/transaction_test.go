@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
 )
 
 // mockOperation is a simple operation for testing
@@ -341,3 +343,134 @@ func TestTransactionExecuteAll(t *testing.T) {
 		t.Error("ExecuteAll() should have created dest file")
 	}
 }
+
+func TestPlanTransaction(t *testing.T) {
+	tx := NewPlanTransaction()
+	if !tx.IsPlan() {
+		t.Fatal("NewPlanTransaction() should be a plan")
+	}
+
+	op := &mockOperation{}
+	if err := tx.Execute(op); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := tx.Execute(&StepOp{Desc: "second step"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := tx.ExecuteAll(); err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+
+	if op.doCalls != 0 {
+		t.Errorf("plan transaction called Do() %d times, want 0", op.doCalls)
+	}
+	if tx.ExecutedCount() != 0 {
+		t.Errorf("ExecutedCount() = %d, want 0", tx.ExecutedCount())
+	}
+
+	plan := tx.Plan()
+	if len(plan) != 2 || plan[0] != "mock operation" || plan[1] != "second step" {
+		t.Errorf("Plan() = %v, want [mock operation second step]", plan)
+	}
+}
+
+func TestReownSymlinkTransaction(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// SaveConfig writes under $HOME/.dotcor
+	t.Setenv("HOME", tempDir)
+
+	// ~/.zshrc -> ~/dotfiles/zshrc (old dotfiles repo)
+	oldTarget := filepath.Join(tempDir, "dotfiles", "zshrc")
+	if err := os.MkdirAll(filepath.Dir(oldTarget), 0755); err != nil {
+		t.Fatalf("failed to create dotfiles dir: %v", err)
+	}
+	if err := os.WriteFile(oldTarget, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+	link := filepath.Join(tempDir, ".zshrc")
+	if err := os.Symlink(oldTarget, link); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	cfg := &config.Config{
+		Version:  config.CurrentConfigVersion,
+		RepoPath: filepath.Join(tempDir, ".dotcor", "files"),
+	}
+	mf := config.ManagedFile{SourcePath: "~/.zshrc", RepoPath: "shell/zshrc"}
+
+	tx, err := ReownSymlinkTransaction(cfg, link, mf.RepoPath, mf)
+	if err != nil {
+		t.Fatalf("ReownSymlinkTransaction() error = %v", err)
+	}
+	if err := tx.ExecuteAll(); err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+	tx.Commit()
+
+	repoFile := filepath.Join(cfg.RepoPath, "shell", "zshrc")
+	if got, _ := filepath.EvalSymlinks(link); got != repoFile {
+		t.Errorf("symlink resolves to %q, want %q", got, repoFile)
+	}
+	if data, err := os.ReadFile(repoFile); err != nil || string(data) != "content" {
+		t.Errorf("repo file content = %q, err %v", data, err)
+	}
+	if _, err := os.Stat(oldTarget); err != nil {
+		t.Errorf("original target should be left in place: %v", err)
+	}
+	if !cfg.IsManaged("~/.zshrc") {
+		t.Error("file should be added to config")
+	}
+}
+
+func TestMoveSymlinkTargetTransaction(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Setenv("HOME", tempDir)
+
+	oldTarget := filepath.Join(tempDir, "dotfiles", "vimrc")
+	if err := os.MkdirAll(filepath.Dir(oldTarget), 0755); err != nil {
+		t.Fatalf("failed to create dotfiles dir: %v", err)
+	}
+	if err := os.WriteFile(oldTarget, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+	link := filepath.Join(tempDir, ".vimrc")
+	if err := os.Symlink(oldTarget, link); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	cfg := &config.Config{
+		Version:  config.CurrentConfigVersion,
+		RepoPath: filepath.Join(tempDir, ".dotcor", "files"),
+	}
+	mf := config.ManagedFile{SourcePath: "~/.vimrc", RepoPath: "vim/vimrc"}
+
+	tx, err := MoveSymlinkTargetTransaction(cfg, link, mf.RepoPath, mf)
+	if err != nil {
+		t.Fatalf("MoveSymlinkTargetTransaction() error = %v", err)
+	}
+	if err := tx.ExecuteAll(); err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+	tx.Commit()
+
+	repoFile := filepath.Join(cfg.RepoPath, "vim", "vimrc")
+	if got, _ := filepath.EvalSymlinks(link); got != repoFile {
+		t.Errorf("symlink resolves to %q, want %q", got, repoFile)
+	}
+	if _, err := os.Stat(oldTarget); !os.IsNotExist(err) {
+		t.Error("original target should have been moved")
+	}
+	if !cfg.IsManaged("~/.vimrc") {
+		t.Error("file should be added to config")
+	}
+}
@@ -1,52 +1,20 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strings"
 
 	"github.com/justincordova/dotcor/internal/config"
 	"github.com/justincordova/dotcor/internal/fs"
 )
 
-// Secret detection patterns
-var secretPatterns = []*regexp.Regexp{
-	// API keys and tokens
-	regexp.MustCompile(`(?i)api[_-]?key\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`),
-	regexp.MustCompile(`(?i)api[_-]?secret\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`),
-	regexp.MustCompile(`(?i)access[_-]?token\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`),
-	regexp.MustCompile(`(?i)auth[_-]?token\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`),
-
-	// Passwords
-	regexp.MustCompile(`(?i)password\s*[:=]\s*['"]?[^\s'";]{8,}['"]?`),
-	regexp.MustCompile(`(?i)passwd\s*[:=]\s*['"]?[^\s'";]{8,}['"]?`),
-
-	// Secrets
-	regexp.MustCompile(`(?i)secret\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`),
-	regexp.MustCompile(`(?i)private[_-]?key\s*[:=]\s*['"]?[a-zA-Z0-9_-]{20,}['"]?`),
-
-	// Private key headers
-	regexp.MustCompile(`-----BEGIN\s+.*PRIVATE\s+KEY-----`),
-	regexp.MustCompile(`-----BEGIN\s+RSA\s+PRIVATE\s+KEY-----`),
-	regexp.MustCompile(`-----BEGIN\s+EC\s+PRIVATE\s+KEY-----`),
-	regexp.MustCompile(`-----BEGIN\s+OPENSSH\s+PRIVATE\s+KEY-----`),
-
-	// Cloud provider credentials
-	regexp.MustCompile(`(?i)aws[_-]?access[_-]?key[_-]?id\s*[:=]\s*['"]?[A-Z0-9]{20}['"]?`),
-	regexp.MustCompile(`(?i)aws[_-]?secret[_-]?access[_-]?key\s*[:=]\s*['"]?[a-zA-Z0-9/+=]{40}['"]?`),
-	regexp.MustCompile(`(?i)azure[_-]?.*secret`),
-	regexp.MustCompile(`(?i)gcp[_-]?.*secret`),
-
-	// Database connection strings with passwords
-	regexp.MustCompile(`(?i)postgres://[^:]+:[^@]+@`),
-	regexp.MustCompile(`(?i)mysql://[^:]+:[^@]+@`),
-	regexp.MustCompile(`(?i)mongodb://[^:]+:[^@]+@`),
-
-	// Generic credentials
-	regexp.MustCompile(`(?i)credentials\s*[:=]\s*['"]?[^\s'";]{10,}['"]?`),
-}
+// ErrForeignSymlink is returned when a file to add is a symlink pointing
+// outside the dotcor repo
+var ErrForeignSymlink = errors.New("file is a symlink pointing outside the dotcor repo")
 
 // Large file warning threshold (100MB)
 const LargeFileThreshold = 100 * 1024 * 1024
@@ -78,6 +46,15 @@ func ValidateSourceFile(path string, cfg *config.Config) error {
 		return fmt.Errorf("file is not readable: %s", path)
 	}
 
+	// Key material is never managed, only the safe files next to it
+	if sensitive, ok := SensitiveDirFor(expanded); ok && !sensitive.IsSafe(expanded) {
+		display, err := config.NormalizePath(expanded)
+		if err != nil {
+			display = path
+		}
+		return fmt.Errorf("%s may hold key material and is never managed; only %s in %s are", display, strings.Join(sensitive.Safe, ", "), sensitive.Path)
+	}
+
 	// Check if file is inside dotcor directory
 	if err := ValidateNotInDotcorDir(path, cfg); err != nil {
 		return err
@@ -89,20 +66,38 @@ func ValidateSourceFile(path string, cfg *config.Config) error {
 		return fmt.Errorf("checking symlink: %w", err)
 	}
 	if isLink {
-		pointsToRepo, err := fs.SymlinkPointsToRepo(expanded, cfg.RepoPath)
+		filesRoot, err := config.GetFilesRoot(cfg)
+		if err != nil {
+			return fmt.Errorf("resolving files root: %w", err)
+		}
+		pointsToRepo, err := fs.SymlinkPointsToRepo(expanded, filesRoot)
 		if err != nil {
 			return fmt.Errorf("checking symlink target: %w", err)
 		}
 		if pointsToRepo {
 			return fmt.Errorf("file is already a symlink pointing to dotcor repo: %s", path)
 		}
-		// It's a symlink but points elsewhere - suggest using adopt
-		return fmt.Errorf("file is a symlink pointing elsewhere, use 'dotcor adopt' instead: %s", path)
+		// It's a symlink but points elsewhere - add can re-own it
+		return fmt.Errorf("%w: %s", ErrForeignSymlink, path)
 	}
 
 	return nil
 }
 
+// AddedPerm returns the mode to record for a file being added, in octal:
+// its own, or for a file next to keys the mode ssh and gpg insist on. The
+// file then needs RestorePermissions once it's managed.
+func AddedPerm(path string) (string, error) {
+	if sensitive, ok := SensitiveDirFor(path); ok {
+		return fmt.Sprintf("%o", sensitive.FilePerm), nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%o", info.Mode().Perm()), nil
+}
+
 // ValidateRepoPath checks if repo path is valid
 func ValidateRepoPath(path string) error {
 	if path == "" {
@@ -124,6 +119,13 @@ func ValidateRepoPath(path string) error {
 		return fmt.Errorf("repo path cannot start with separator: %s", path)
 	}
 
+	// Must be creatable on Windows when running there
+	if runtime.GOOS == "windows" {
+		if err := config.ValidateWindowsPath(path); err != nil {
+			return fmt.Errorf("invalid repo path %s: %w", path, err)
+		}
+	}
+
 	return nil
 }
 
@@ -142,14 +144,16 @@ func ValidateNotInDotcorDir(path string, cfg *config.Config) error {
 		return fmt.Errorf("expanding path: %w", err)
 	}
 
-	configDir, err := config.GetConfigDir()
+	dirs, err := dotcorDirs()
 	if err != nil {
 		return fmt.Errorf("getting config dir: %w", err)
 	}
 
 	// Check if path is under dotcor directory
-	if strings.HasPrefix(expanded, configDir) {
-		return fmt.Errorf("cannot add files from inside dotcor directory: %s", path)
+	for _, dir := range dirs {
+		if strings.HasPrefix(expanded, dir) {
+			return fmt.Errorf("cannot add files from inside dotcor directory: %s", path)
+		}
 	}
 
 	return nil
@@ -175,44 +179,23 @@ func ValidateFileSize(path string) error {
 	return nil
 }
 
-// DetectSecrets scans file content for potential secrets
-func DetectSecrets(path string) ([]string, error) {
-	expanded, err := config.ExpandPath(path)
-	if err != nil {
-		return nil, fmt.Errorf("expanding path: %w", err)
-	}
-
-	content, err := os.ReadFile(expanded)
-	if err != nil {
-		return nil, fmt.Errorf("reading file: %w", err)
-	}
-
-	var warnings []string
-	lines := strings.Split(string(content), "\n")
-
-	for lineNum, line := range lines {
-		for _, pattern := range secretPatterns {
-			matches := pattern.FindAllString(line, -1)
-			for _, match := range matches {
-				// Truncate match if too long
-				displayMatch := match
-				if len(displayMatch) > 50 {
-					displayMatch = displayMatch[:50] + "..."
-				}
-				warning := fmt.Sprintf("Line %d: %s", lineNum+1, displayMatch)
-				warnings = append(warnings, warning)
-			}
-		}
-	}
-
-	return warnings, nil
-}
-
 // ShouldWarnAboutSecrets returns true if file likely contains secrets
 func ShouldWarnAboutSecrets(path string, warnings []string) bool {
 	return len(warnings) > 0
 }
 
+// IsValidationWarning reports whether a validation error is a warning that
+// can be overridden with --force, rather than a hard error
+func IsValidationWarning(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "warning") ||
+		strings.Contains(msg, "large file") ||
+		strings.Contains(msg, "unusual permissions")
+}
+
 // ValidateAll runs all validations on a file
 func ValidateAll(path string, cfg *config.Config) (warnings []string, err error) {
 	// Basic validations
@@ -230,11 +213,17 @@ func ValidateAll(path string, cfg *config.Config) (warnings []string, err error)
 	}
 
 	// Check for secrets
-	secretWarnings, err := DetectSecrets(path)
+	scanner, err := NewSecretScanner(cfg.Scan)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := scanner.ScanFile(path)
 	if err != nil {
 		// Non-fatal, just skip secret detection
 	} else {
-		warnings = append(warnings, secretWarnings...)
+		for _, f := range findings {
+			warnings = append(warnings, f.String())
+		}
 	}
 
 	return warnings, nil
@@ -261,7 +250,11 @@ func ValidateSymlinkTarget(linkPath string, cfg *config.Config) error {
 	}
 
 	// Check if already points to our repo
-	pointsToRepo, err := fs.SymlinkPointsToRepo(linkPath, cfg.RepoPath)
+	filesRoot, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return fmt.Errorf("resolving files root: %w", err)
+	}
+	pointsToRepo, err := fs.SymlinkPointsToRepo(linkPath, filesRoot)
 	if err != nil {
 		return fmt.Errorf("checking symlink target: %w", err)
 	}
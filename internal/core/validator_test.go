@@ -166,6 +166,43 @@ func TestValidateNotAlreadyManaged(t *testing.T) {
 	}
 }
 
+func TestValidateSourceFileKeyMaterial(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	cfg := &config.Config{Version: config.CurrentConfigVersion, RepoPath: "~/.dotcor/files"}
+
+	sshDir := filepath.Join(tempDir, ".ssh")
+	if err := os.MkdirAll(sshDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(sshDir, "id_ed25519")
+	sshConfig := filepath.Join(sshDir, "config")
+	for _, p := range []string{key, sshConfig} {
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ValidateSourceFile(key, cfg); err == nil {
+		t.Error("ValidateSourceFile() of a private key should return error")
+	}
+	if err := ValidateSourceFile(sshConfig, cfg); err != nil {
+		t.Errorf("ValidateSourceFile() of ssh config error = %v", err)
+	}
+
+	// Files next to keys are recorded with ssh's mode, others with their own
+	if perm, err := AddedPerm(sshConfig); err != nil || perm != "600" {
+		t.Errorf("AddedPerm(ssh config) = %q, %v, want 600", perm, err)
+	}
+	bashrc := filepath.Join(tempDir, ".bashrc")
+	if err := os.WriteFile(bashrc, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if perm, err := AddedPerm(bashrc); err != nil || perm != "644" {
+		t.Errorf("AddedPerm(bashrc) = %q, %v, want 644", perm, err)
+	}
+}
+
 func TestValidateFileSize(t *testing.T) {
 	// Create temp dir
 	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
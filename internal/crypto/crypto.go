@@ -0,0 +1,186 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// ErrBackendNotInstalled is returned when the encryption tool isn't on PATH
+var ErrBackendNotInstalled = errors.New("encryption tool not installed")
+
+// DefaultAgeIdentity is where the age identity is read from when not configured
+const DefaultAgeIdentity = "~/.config/age/keys.txt"
+
+// Backend encrypts and decrypts secret file contents
+type Backend interface {
+	Name() string
+	Ext() string // Extension of encrypted repo files, e.g. ".age"
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// NewBackend returns the backend selected in the secrets config
+func NewBackend(cfg config.SecretsConfig) (Backend, error) {
+	switch cfg.GetBackend() {
+	case config.SecretsBackendAge:
+		if _, err := exec.LookPath("age"); err != nil {
+			return nil, fmt.Errorf("%w: age (see https://age-encryption.org)", ErrBackendNotInstalled)
+		}
+		identity := cfg.Identity
+		if identity == "" {
+			identity = DefaultAgeIdentity
+		}
+		expanded, err := config.ExpandPath(identity)
+		if err != nil {
+			return nil, fmt.Errorf("expanding identity path: %w", err)
+		}
+		return &ageBackend{recipients: cfg.Recipients, identity: expanded}, nil
+
+	case config.SecretsBackendGPG:
+		if _, err := exec.LookPath("gpg"); err != nil {
+			return nil, fmt.Errorf("%w: gpg", ErrBackendNotInstalled)
+		}
+		return &gpgBackend{recipients: cfg.Recipients}, nil
+	}
+
+	return nil, config.ValidateSecretsBackend(cfg.Backend)
+}
+
+// EncryptFile encrypts src into dst (e.g. the repo copy of a secret)
+func EncryptFile(b Backend, src, dst string) error {
+	plaintext, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	ciphertext, err := b.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(dst, ciphertext, 0644)
+}
+
+// DecryptFile decrypts src into dst, readable only by the current user
+func DecryptFile(b Backend, src, dst string) error {
+	plaintext, err := DecryptToBytes(b, src)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(dst, plaintext, 0600)
+}
+
+// DecryptToBytes decrypts src and returns its contents
+func DecryptToBytes(b Backend, src string) ([]byte, error) {
+	ciphertext, err := os.ReadFile(src)
+	if err != nil {
+		return nil, fmt.Errorf("reading encrypted file: %w", err)
+	}
+
+	return b.Decrypt(ciphertext)
+}
+
+// writeFileAtomic writes data to a temp file and renames it over path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := fs.EnsureDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, perm); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	if err := os.Chmod(tempPath, perm); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("setting permissions: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing file: %w", err)
+	}
+	return nil
+}
+
+// run executes a command with input on stdin and returns stdout.
+// stderr is included in the error so tool messages reach the user.
+func run(input []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %s", name, msg)
+		}
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// ageBackend encrypts with the age command-line tool
+type ageBackend struct {
+	recipients []string
+	identity   string
+}
+
+func (a *ageBackend) Name() string { return config.SecretsBackendAge }
+func (a *ageBackend) Ext() string  { return ".age" }
+
+// Encrypt encrypts to the configured recipients, or to the identity's
+// own public key when none are configured
+func (a *ageBackend) Encrypt(plaintext []byte) ([]byte, error) {
+	args := []string{"--encrypt", "--armor"}
+	if len(a.recipients) == 0 {
+		if !fs.FileExists(a.identity) {
+			return nil, fmt.Errorf("no age recipients configured and identity %s not found\nCreate one with 'age-keygen -o %s'", a.identity, a.identity)
+		}
+		args = append(args, "--identity", a.identity)
+	}
+	for _, r := range a.recipients {
+		args = append(args, "--recipient", r)
+	}
+	return run(plaintext, "age", args...)
+}
+
+func (a *ageBackend) Decrypt(ciphertext []byte) ([]byte, error) {
+	if !fs.FileExists(a.identity) {
+		return nil, fmt.Errorf("age identity not found: %s", a.identity)
+	}
+	return run(ciphertext, "age", "--decrypt", "--identity", a.identity)
+}
+
+// gpgBackend encrypts with GnuPG using the user's keyring
+type gpgBackend struct {
+	recipients []string
+}
+
+func (g *gpgBackend) Name() string { return config.SecretsBackendGPG }
+func (g *gpgBackend) Ext() string  { return ".gpg" }
+
+// Encrypt encrypts to the configured recipients, or to the default key
+// when none are configured
+func (g *gpgBackend) Encrypt(plaintext []byte) ([]byte, error) {
+	args := []string{"--batch", "--yes", "--armor", "--encrypt"}
+	if len(g.recipients) == 0 {
+		args = append(args, "--default-recipient-self")
+	}
+	for _, r := range g.recipients {
+		args = append(args, "--recipient", r)
+	}
+	return run(plaintext, "gpg", args...)
+}
+
+func (g *gpgBackend) Decrypt(ciphertext []byte) ([]byte, error) {
+	return run(ciphertext, "gpg", "--batch", "--quiet", "--decrypt")
+}
@@ -0,0 +1,105 @@
+package crypto
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// setupGPG creates a throwaway keyring with an unprotected key
+func setupGPG(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+
+	home, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(home) })
+
+	// Keep the socket path short; gpg-agent refuses long ones
+	gnupgHome := filepath.Join(home, "g")
+	if err := os.Mkdir(gnupgHome, 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GNUPGHOME", gnupgHome)
+	t.Cleanup(func() { exec.Command("gpgconf", "--kill", "gpg-agent").Run() })
+
+	out, err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", "dotcor-test@example.com", "default", "default", "never").CombinedOutput()
+	if err != nil {
+		t.Skipf("generating gpg key failed: %v\n%s", err, out)
+	}
+
+	return home
+}
+
+func TestNewBackend(t *testing.T) {
+	if _, err := NewBackend(config.SecretsConfig{Backend: "rot13"}); err == nil {
+		t.Error("NewBackend() with unknown backend should fail")
+	}
+
+	if _, err := exec.LookPath("gpg"); err == nil {
+		b, err := NewBackend(config.SecretsConfig{Backend: config.SecretsBackendGPG})
+		if err != nil {
+			t.Fatalf("NewBackend(gpg) error = %v", err)
+		}
+		if b.Name() != "gpg" || b.Ext() != ".gpg" {
+			t.Errorf("NewBackend(gpg) = %s %s, want gpg .gpg", b.Name(), b.Ext())
+		}
+	}
+}
+
+func TestGPGEncryptDecryptFile(t *testing.T) {
+	dir := setupGPG(t)
+
+	backend, err := NewBackend(config.SecretsConfig{Backend: config.SecretsBackendGPG})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := filepath.Join(dir, "npmrc")
+	content := "//registry.npmjs.org/:_authToken=secret\n"
+	if err := os.WriteFile(plain, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted := filepath.Join(dir, "repo", "npmrc.gpg")
+	if err := EncryptFile(backend, plain, encrypted); err != nil {
+		t.Fatalf("EncryptFile() error = %v", err)
+	}
+
+	ciphertext, err := os.ReadFile(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ciphertext) == content {
+		t.Fatal("EncryptFile() wrote plaintext")
+	}
+
+	decrypted := filepath.Join(dir, "secrets", "npmrc")
+	if err := DecryptFile(backend, encrypted, decrypted); err != nil {
+		t.Fatalf("DecryptFile() error = %v", err)
+	}
+
+	got, err := os.ReadFile(decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != content {
+		t.Errorf("DecryptFile() content = %q, want %q", got, content)
+	}
+
+	info, err := os.Stat(decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("DecryptFile() permissions = %o, want 600", perm)
+	}
+}
@@ -0,0 +1,231 @@
+// Package defaults reads and writes macOS preference domains with the
+// defaults command, for the domains selected in the defaults section of
+// config.yaml.
+package defaults
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Command is the executable reading and writing preference domains
+const Command = "defaults"
+
+// Preset is the domains and keys captured when none are configured: Dock,
+// Finder and keyboard settings
+var Preset = map[string][]string{
+	"com.apple.dock": {
+		"autohide", "autohide-delay", "autohide-time-modifier", "largesize", "magnification",
+		"mineffect", "minimize-to-application", "mru-spaces", "orientation", "show-recents", "tilesize",
+	},
+	"com.apple.finder": {
+		"AppleShowAllFiles", "FXDefaultSearchScope", "FXEnableExtensionChangeWarning",
+		"FXPreferredViewStyle", "NewWindowTarget", "ShowPathbar", "ShowStatusBar", "_FXShowPosixPathInTitle",
+	},
+	"NSGlobalDomain": {
+		"AppleKeyboardUIMode", "ApplePressAndHoldEnabled", "AppleShowAllExtensions", "InitialKeyRepeat", "KeyRepeat",
+		"NSAutomaticCapitalizationEnabled", "NSAutomaticDashSubstitutionEnabled", "NSAutomaticPeriodSubstitutionEnabled",
+		"NSAutomaticQuoteSubstitutionEnabled", "NSAutomaticSpellingCorrectionEnabled", "com.apple.swipescrolldirection",
+	},
+}
+
+// restarts are the processes that only pick up a domain's changes when
+// restarted
+var restarts = map[string]string{
+	"com.apple.dock":            "Dock",
+	"com.apple.finder":          "Finder",
+	"com.apple.menuextra.clock": "SystemUIServer",
+	"com.apple.systemuiserver":  "SystemUIServer",
+}
+
+// RestartProcess returns the process to restart for changes to domain to
+// take effect, or "" if none is known
+func RestartProcess(domain string) string {
+	return restarts[domain]
+}
+
+// Available reports whether the defaults command is installed
+func Available() bool {
+	_, err := exec.LookPath(Command)
+	return err == nil
+}
+
+// Plist is the top level of a domain: each key with its value as the XML
+// element of the property list, so values of every type are kept as is
+type Plist map[string]string
+
+// Export reads domain from the preferences system
+func Export(domain string) (Plist, error) {
+	out, err := exec.Command(Command, "export", domain, "-").Output()
+	if err != nil {
+		return nil, fmt.Errorf("exporting %s: %w", domain, commandError(err))
+	}
+	return ParsePlist(out)
+}
+
+// Import replaces domain in the preferences system with p
+func Import(domain string, p Plist) error {
+	cmd := exec.Command(Command, "import", domain, "-")
+	cmd.Stdin = bytes.NewReader(p.Encode())
+	if _, err := cmd.Output(); err != nil {
+		return fmt.Errorf("importing %s: %w", domain, commandError(err))
+	}
+	return nil
+}
+
+// commandError returns the first line of a failed command's stderr, or err
+func commandError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		msg, _, _ := strings.Cut(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+		return errors.New(msg)
+	}
+	return err
+}
+
+// ParsePlist reads an XML property list whose top level is a dict
+func ParsePlist(data []byte) (Plist, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+
+	// Find the top-level dict
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("property list has no dict")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading property list: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "dict" {
+			break
+		}
+	}
+
+	p := Plist{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("reading property list: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return p, nil // End of the dict
+		case xml.StartElement:
+			if t.Name.Local != "key" {
+				return nil, fmt.Errorf("reading property list: <%s> where a key was expected", t.Name.Local)
+			}
+			var key string
+			if err := d.DecodeElement(&key, &t); err != nil {
+				return nil, fmt.Errorf("reading property list: %w", err)
+			}
+			value, err := rawElement(d, data)
+			if err != nil {
+				return nil, fmt.Errorf("reading value of %s: %w", key, err)
+			}
+			p[key] = value
+		}
+	}
+}
+
+// rawElement returns the source of the next element read by d
+func rawElement(d *xml.Decoder, data []byte) (string, error) {
+	start := d.InputOffset()
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			break
+		}
+		if _, ok := tok.(xml.EndElement); ok {
+			return "", fmt.Errorf("missing value")
+		}
+	}
+	if err := d.Skip(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data[start:d.InputOffset()])), nil
+}
+
+// Encode writes p as an XML property list with its keys sorted
+func (p Plist) Encode() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	for _, key := range p.Keys() {
+		b.WriteString("\t<key>")
+		xml.EscapeText(&b, []byte(key))
+		b.WriteString("</key>\n\t")
+		b.WriteString(p[key])
+		b.WriteString("\n")
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes()
+}
+
+// Keys returns the keys of p, sorted
+func (p Plist) Keys() []string {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Select returns the entries of p with keys, or all of them if keys is
+// empty. Keys p doesn't have are left out.
+func (p Plist) Select(keys []string) Plist {
+	selected := Plist{}
+	if len(keys) == 0 {
+		for key, value := range p {
+			selected[key] = value
+		}
+		return selected
+	}
+	for _, key := range keys {
+		if value, ok := p[key]; ok {
+			selected[key] = value
+		}
+	}
+	return selected
+}
+
+// Merge returns p with the entries of other added or replaced
+func (p Plist) Merge(other Plist) Plist {
+	merged := p.Select(nil)
+	for key, value := range other {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Changed returns the sorted keys of want whose values differ in p,
+// ignoring the indentation of nested values
+func (p Plist) Changed(want Plist) []string {
+	var changed []string
+	for _, key := range want.Keys() {
+		current, ok := p[key]
+		if !ok || normalize(current) != normalize(want[key]) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+var betweenTags = regexp.MustCompile(`>\s+<`)
+
+// normalize drops the whitespace between the tags of a value
+func normalize(value string) string {
+	return betweenTags.ReplaceAllString(strings.TrimSpace(value), "><")
+}
@@ -0,0 +1,129 @@
+package defaults
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const dockPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>autohide</key>
+	<true/>
+	<key>persistent-apps</key>
+	<array>
+		<dict>
+			<key>tile-type</key>
+			<string>file-tile</string>
+		</dict>
+	</array>
+	<key>tilesize</key>
+	<integer>48</integer>
+	<key>wvous-tl-corner</key>
+	<real>2.5</real>
+</dict>
+</plist>
+`
+
+func TestParsePlist(t *testing.T) {
+	p, err := ParsePlist([]byte(dockPlist))
+	if err != nil {
+		t.Fatalf("ParsePlist() error = %v", err)
+	}
+
+	if got := p.Keys(); !reflect.DeepEqual(got, []string{"autohide", "persistent-apps", "tilesize", "wvous-tl-corner"}) {
+		t.Errorf("Keys() = %v", got)
+	}
+	if p["autohide"] != "<true/>" || p["tilesize"] != "<integer>48</integer>" {
+		t.Errorf("ParsePlist() = %v", p)
+	}
+	if !strings.HasPrefix(p["persistent-apps"], "<array>") || !strings.HasSuffix(p["persistent-apps"], "</array>") {
+		t.Errorf("persistent-apps = %q", p["persistent-apps"])
+	}
+
+	// Encoding and parsing again keeps every value
+	again, err := ParsePlist(p.Encode())
+	if err != nil {
+		t.Fatalf("ParsePlist(Encode()) error = %v", err)
+	}
+	if changed := again.Changed(p); len(changed) != 0 {
+		t.Errorf("round trip changed %v", changed)
+	}
+
+	if _, err := ParsePlist([]byte("<plist><array/></plist>")); err == nil {
+		t.Error("ParsePlist() of a plist without a dict succeeded")
+	}
+}
+
+func TestPlistSelectMerge(t *testing.T) {
+	current := Plist{"autohide": "<false/>", "tilesize": "<integer>48</integer>", "orientation": "<string>left</string>"}
+	stored := Plist{"autohide": "<true/>", "tilesize": "<integer>48</integer>"}
+
+	if got := current.Select([]string{"autohide", "missing"}); !reflect.DeepEqual(got, Plist{"autohide": "<false/>"}) {
+		t.Errorf("Select() = %v", got)
+	}
+	if got := current.Select(nil); !reflect.DeepEqual(got, current) {
+		t.Errorf("Select(nil) = %v", got)
+	}
+	if changed := current.Changed(stored); !reflect.DeepEqual(changed, []string{"autohide"}) {
+		t.Errorf("Changed() = %v", changed)
+	}
+
+	merged := current.Merge(stored)
+	if merged["autohide"] != "<true/>" || merged["orientation"] != "<string>left</string>" {
+		t.Errorf("Merge() = %v", merged)
+	}
+	if current["autohide"] != "<false/>" {
+		t.Error("Merge() changed the receiver")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake defaults in this test is a shell script")
+	}
+
+	// The fake defaults exports a fixed domain and saves what is imported
+	bin := t.TempDir()
+	imported := filepath.Join(bin, "imported.plist")
+	fixture := filepath.Join(bin, "dock.plist")
+	if err := os.WriteFile(fixture, []byte(dockPlist), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncase \"$1\" in\nexport) cat '" + fixture + "' ;;\nimport) cat > '" + imported + "' ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(bin, Command), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if !Available() {
+		t.Fatal("Available() = false")
+	}
+	p, err := Export("com.apple.dock")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if p["tilesize"] != "<integer>48</integer>" {
+		t.Errorf("Export() = %v", p)
+	}
+
+	if err := Import("com.apple.dock", p.Merge(Plist{"tilesize": "<integer>36</integer>"})); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	data, err := os.ReadFile(imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParsePlist(data)
+	if err != nil {
+		t.Fatalf("imported plist: %v", err)
+	}
+	if got["tilesize"] != "<integer>36</integer>" || got["autohide"] != "<true/>" {
+		t.Errorf("imported %v", got)
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package fs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// SameDevice reports whether two existing paths are on the same filesystem
+func SameDevice(a, b string) (bool, error) {
+	devA, err := deviceID(a)
+	if err != nil {
+		return false, err
+	}
+	devB, err := deviceID(b)
+	if err != nil {
+		return false, err
+	}
+	return devA == devB, nil
+}
+
+// deviceID returns the ID of the device holding path
+func deviceID(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("getting file info: %w", err)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("device not available for %s", path)
+	}
+	return uint64(stat.Dev), nil
+}
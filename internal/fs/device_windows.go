@@ -0,0 +1,22 @@
+//go:build windows
+
+package fs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SameDevice reports whether two existing paths are on the same volume
+func SameDevice(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, fmt.Errorf("resolving %s: %w", a, err)
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, fmt.Errorf("resolving %s: %w", b, err)
+	}
+	return strings.EqualFold(filepath.VolumeName(absA), filepath.VolumeName(absB)), nil
+}
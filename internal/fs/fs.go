@@ -1,6 +1,9 @@
 package fs
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -10,19 +13,24 @@ import (
 // MoveFile moves a file from src to dst, preserving permissions
 // Uses os.Rename when possible, falls back to copy+delete for cross-device moves
 func MoveFile(src, dst string) error {
+	src = LongPath(src)
+	dst, err := prepareWritePath(dst)
+	if err != nil {
+		return err
+	}
+
 	// Ensure destination directory exists
 	if err := EnsureDir(filepath.Dir(dst)); err != nil {
 		return fmt.Errorf("creating destination directory: %w", err)
 	}
 
 	// Try rename first (fast, atomic on same filesystem)
-	err := os.Rename(src, dst)
-	if err == nil {
+	if err := os.Rename(src, dst); err == nil {
 		return nil
 	}
 
 	// If rename failed (likely cross-device), fall back to copy+delete
-	if err := CopyWithPermissions(src, dst); err != nil {
+	if err := CopyPreservingMetadata(src, dst); err != nil {
 		return fmt.Errorf("copying file: %w", err)
 	}
 
@@ -41,8 +49,35 @@ func CopyFile(src, dst string) error {
 	return CopyWithPermissions(src, dst)
 }
 
+// CopyOptions selects metadata preserved by CopyWithOptions beyond
+// permissions and timestamps
+type CopyOptions struct {
+	PreserveXattrs bool // Copy extended attributes (quarantine, tags, SELinux labels)
+	PreserveOwner  bool // Copy uid/gid when running with enough privileges
+}
+
+// CopyPreservingMetadata copies a file keeping permissions, timestamps,
+// extended attributes and, when privileged, ownership. Used where a copy
+// stands in for the original, like backups and restores.
+func CopyPreservingMetadata(src, dst string) error {
+	return CopyWithOptions(src, dst, CopyOptions{PreserveXattrs: true, PreserveOwner: true})
+}
+
 // CopyWithPermissions copies file preserving all metadata (permissions, timestamps)
 func CopyWithPermissions(src, dst string) error {
+	return CopyWithOptions(src, dst, CopyOptions{})
+}
+
+// CopyWithOptions copies file preserving permissions and timestamps, plus
+// the metadata selected in opts. Metadata the platform or filesystem
+// doesn't support is silently skipped.
+func CopyWithOptions(src, dst string, opts CopyOptions) error {
+	src = LongPath(src)
+	dst, err := prepareWritePath(dst)
+	if err != nil {
+		return err
+	}
+
 	// Get source file info
 	srcInfo, err := os.Stat(src)
 	if err != nil {
@@ -54,6 +89,32 @@ func CopyWithPermissions(src, dst string) error {
 		return fmt.Errorf("creating destination directory: %w", err)
 	}
 
+	// Clone the file where the filesystem supports it, otherwise copy bytes
+	if !cloneFile(src, dst, srcInfo.Mode()) {
+		if err := copyContents(src, dst, srcInfo.Mode()); err != nil {
+			return err
+		}
+	}
+
+	if opts.PreserveXattrs {
+		copyXattrs(src, dst)
+	}
+
+	if opts.PreserveOwner {
+		copyOwner(src, dst)
+	}
+
+	// Preserve timestamps
+	if err := os.Chtimes(dst, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
+		// Non-fatal, just log or ignore
+		// Some filesystems don't support this
+	}
+
+	return nil
+}
+
+// copyContents writes the contents of src to dst with io.Copy
+func copyContents(src, dst string, mode os.FileMode) error {
 	// Open source file
 	srcFile, err := os.Open(src)
 	if err != nil {
@@ -62,7 +123,7 @@ func CopyWithPermissions(src, dst string) error {
 	defer srcFile.Close()
 
 	// Create destination file with same permissions
-	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, srcInfo.Mode())
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 	if err != nil {
 		return fmt.Errorf("creating destination file: %w", err)
 	}
@@ -78,12 +139,6 @@ func CopyWithPermissions(src, dst string) error {
 		return fmt.Errorf("syncing destination file: %w", err)
 	}
 
-	// Preserve timestamps
-	if err := os.Chtimes(dst, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
-		// Non-fatal, just log or ignore
-		// Some filesystems don't support this
-	}
-
 	return nil
 }
 
@@ -108,6 +163,11 @@ func EnsureDir(path string) error {
 		return nil
 	}
 
+	path, err := prepareWritePath(path)
+	if err != nil {
+		return err
+	}
+
 	info, err := os.Stat(path)
 	if err == nil {
 		if info.IsDir() {
@@ -147,9 +207,49 @@ func GetFileSize(path string) (int64, error) {
 	return info.Size(), nil
 }
 
+// FileChecksum returns the hex-encoded SHA-256 checksum of a file's content
+func FileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SameContent reports whether two files have identical content
+func SameContent(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	sumA, err := FileChecksum(a)
+	if err != nil {
+		return false, err
+	}
+	sumB, err := FileChecksum(b)
+	if err != nil {
+		return false, err
+	}
+	return sumA == sumB, nil
+}
+
 // RemoveFile removes a file or empty directory
 func RemoveFile(path string) error {
-	if err := os.Remove(path); err != nil {
+	if err := os.Remove(LongPath(path)); err != nil {
 		return fmt.Errorf("removing file: %w", err)
 	}
 	return nil
@@ -157,20 +257,24 @@ func RemoveFile(path string) error {
 
 // RemoveAll removes a file or directory and all its contents
 func RemoveAll(path string) error {
-	if err := os.RemoveAll(path); err != nil {
+	if err := os.RemoveAll(LongPath(path)); err != nil {
 		return fmt.Errorf("removing path: %w", err)
 	}
 	return nil
 }
 
-// GetFilesRecursive returns all files in directory recursively
-func GetFilesRecursive(dir string) ([]string, error) {
+// GetFilesRecursive returns all files in directory recursively, stopping
+// early if ctx is canceled
+func GetFilesRecursive(ctx context.Context, dir string) ([]string, error) {
 	var files []string
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if !info.IsDir() {
 			files = append(files, path)
 		}
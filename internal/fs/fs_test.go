@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -192,6 +193,64 @@ func TestCopyWithPermissions(t *testing.T) {
 	}
 }
 
+func TestCopyOverwritesExisting(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	src := filepath.Join(tempDir, "src")
+	dst := filepath.Join(tempDir, "dst")
+	if err := os.WriteFile(src, []byte("new"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	if err := os.WriteFile(dst, []byte("old and longer"), 0644); err != nil {
+		t.Fatalf("failed to create destination file: %v", err)
+	}
+
+	if err := CopyWithPermissions(src, dst); err != nil {
+		t.Fatalf("CopyWithPermissions() error = %v", err)
+	}
+
+	content, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("failed to read destination: %v", err)
+	}
+	if string(content) != "new" {
+		t.Errorf("CopyWithPermissions() content = %q, want %q", content, "new")
+	}
+}
+
+func TestCopyPreservesOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	src := filepath.Join(tempDir, "src")
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	if err := os.Chown(src, 1234, 5678); err != nil {
+		t.Skipf("chown not supported: %v", err)
+	}
+
+	dst := filepath.Join(tempDir, "dst")
+	if err := CopyPreservingMetadata(src, dst); err != nil {
+		t.Fatalf("CopyPreservingMetadata() error = %v", err)
+	}
+
+	if owner, _ := GetOwner(dst); owner != "1234:5678" {
+		t.Errorf("CopyPreservingMetadata() owner = %s, want 1234:5678", owner)
+	}
+}
+
 func TestMoveFile(t *testing.T) {
 	// Create temp dir
 	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
@@ -332,6 +391,65 @@ func TestGetFileSize(t *testing.T) {
 	}
 }
 
+func TestFileChecksum(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "testfile")
+	if err := os.WriteFile(testFile, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	sum, err := FileChecksum(testFile)
+	if err != nil {
+		t.Fatalf("FileChecksum() error = %v", err)
+	}
+	want := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if sum != want {
+		t.Errorf("FileChecksum() = %s, want %s", sum, want)
+	}
+
+	if _, err := FileChecksum(filepath.Join(tempDir, "missing")); err == nil {
+		t.Error("FileChecksum() should error for missing file")
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{"a": "same", "b": "same", "c": "diff", "d": "longer"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"a", "b", true},
+		{"a", "c", false},
+		{"a", "d", false},
+	}
+	for _, tt := range tests {
+		got, err := SameContent(filepath.Join(tempDir, tt.a), filepath.Join(tempDir, tt.b))
+		if err != nil {
+			t.Fatalf("SameContent(%s, %s) error = %v", tt.a, tt.b, err)
+		}
+		if got != tt.want {
+			t.Errorf("SameContent(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	if _, err := SameContent(filepath.Join(tempDir, "a"), filepath.Join(tempDir, "missing")); err == nil {
+		t.Error("SameContent() should error for missing file")
+	}
+}
+
 func TestRemoveFile(t *testing.T) {
 	// Create temp dir
 	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
@@ -382,7 +500,7 @@ func TestGetFilesRecursive(t *testing.T) {
 	}
 
 	// Get all files
-	got, err := GetFilesRecursive(tempDir)
+	got, err := GetFilesRecursive(context.Background(), tempDir)
 	if err != nil {
 		t.Fatalf("GetFilesRecursive() error = %v", err)
 	}
@@ -0,0 +1,62 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrCrossDevice is returned when a hard link would span two filesystems
+var ErrCrossDevice = errors.New("source and repository are on different filesystems")
+
+// CreateHardlink creates link as a hard link to target, creating parent
+// directories as needed. Returns ErrCrossDevice if link's directory is on
+// another filesystem than target.
+func CreateHardlink(target, link string) error {
+	if err := EnsureDir(filepath.Dir(link)); err != nil {
+		return fmt.Errorf("creating parent directory: %w", err)
+	}
+
+	same, err := SameDevice(target, filepath.Dir(link))
+	if err != nil {
+		return err
+	}
+	if !same {
+		return ErrCrossDevice
+	}
+
+	if err := os.Link(LongPath(target), LongPath(link)); err != nil {
+		return fmt.Errorf("creating hard link: %w", err)
+	}
+	return nil
+}
+
+// CanHardlink reports whether a hard link from a file in dir to target is
+// possible, i.e. both are on the same filesystem. dir need not exist yet.
+func CanHardlink(target, dir string) bool {
+	for !PathExists(dir) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+
+	same, err := SameDevice(target, dir)
+	return err == nil && same
+}
+
+// IsHardlinkTo reports whether path and target are the same file on disk
+// (same device and inode)
+func IsHardlinkTo(path, target string) (bool, error) {
+	pathInfo, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	targetInfo, err := os.Lstat(target)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(pathInfo, targetInfo), nil
+}
@@ -0,0 +1,53 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateHardlink(t *testing.T) {
+	tempDir := t.TempDir()
+
+	target := filepath.Join(tempDir, "repo", "vimrc")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatalf("failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("set number"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+
+	if !CanHardlink(target, filepath.Join(tempDir, "home", "nested")) {
+		t.Fatal("CanHardlink() = false for a directory on the same filesystem")
+	}
+
+	link := filepath.Join(tempDir, "home", ".vimrc")
+	if err := CreateHardlink(target, link); err != nil {
+		t.Fatalf("CreateHardlink() error = %v", err)
+	}
+
+	same, err := IsHardlinkTo(link, target)
+	if err != nil || !same {
+		t.Fatalf("IsHardlinkTo() = %v, %v, want true", same, err)
+	}
+
+	// Writing in place is visible through both names
+	if err := os.WriteFile(link, []byte("set relativenumber"), 0644); err != nil {
+		t.Fatalf("failed to write link: %v", err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "set relativenumber" {
+		t.Errorf("target content = %q, want the edit made through the link", content)
+	}
+
+	// Replacing the file (as some editors do on save) breaks the link
+	replacement := link + ".tmp"
+	if err := os.WriteFile(replacement, []byte("set relativenumber"), 0644); err != nil {
+		t.Fatalf("failed to write replacement: %v", err)
+	}
+	if err := os.Rename(replacement, link); err != nil {
+		t.Fatalf("failed to replace link: %v", err)
+	}
+	if same, _ := IsHardlinkTo(link, target); same {
+		t.Error("IsHardlinkTo() = true after the file was replaced")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package fs
+
+// LongPath returns path unchanged; only Windows limits path length
+func LongPath(path string) string {
+	return path
+}
+
+// prepareWritePath returns path unchanged; only Windows restricts names
+func prepareWritePath(path string) (string, error) {
+	return path, nil
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package fs
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// maxShortPath is the length above which Windows APIs need the \\?\ prefix
+// (MAX_PATH minus room for an 8.3 file name when creating directories)
+const maxShortPath = 248
+
+// LongPath returns path with the \\?\ prefix when it is too long for the
+// classic Windows APIs, so deep .config trees can be read and written.
+// Short, relative, and already-prefixed paths are returned unchanged.
+func LongPath(path string) string {
+	if len(path) < maxShortPath || !filepath.IsAbs(path) || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	path = filepath.Clean(path)
+	if strings.HasPrefix(path, `\\`) {
+		// UNC path: \\server\share -> \\?\UNC\server\share
+		return `\\?\UNC\` + path[2:]
+	}
+	return `\\?\` + path
+}
+
+// prepareWritePath checks that path can be created on Windows and returns
+// it in long-path form, so reserved names fail with a clear error instead
+// of a cryptic one from the Windows API
+func prepareWritePath(path string) (string, error) {
+	if err := config.ValidateWindowsPath(path); err != nil {
+		return "", err
+	}
+	return LongPath(path), nil
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package fs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLongPath(t *testing.T) {
+	deep := `C:\Users\me\` + strings.Repeat(`nested\`, 40) + "file"
+	unc := `\\server\share\` + strings.Repeat(`nested\`, 40) + "file"
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"short path unchanged", `C:\Users\me\.zshrc`, `C:\Users\me\.zshrc`},
+		{"relative path unchanged", strings.Repeat(`nested\`, 40), strings.Repeat(`nested\`, 40)},
+		{"long path prefixed", deep, `\\?\` + deep},
+		{"long UNC path prefixed", unc, `\\?\UNC\` + unc[2:]},
+		{"already prefixed", `\\?\` + deep, `\\?\` + deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LongPath(tt.path); got != tt.want {
+				t.Errorf("LongPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package fs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// GetOwner returns the owner of path as "uid:gid"
+func GetOwner(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", fmt.Errorf("getting file info: %w", err)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("ownership not available for %s", path)
+	}
+
+	return fmt.Sprintf("%d:%d", stat.Uid, stat.Gid), nil
+}
+
+// copyOwner gives dst the uid and gid of src.
+// Changing ownership needs root (or, for the group, membership), so
+// failures are ignored and the copy keeps the current user's ownership.
+func copyOwner(src, dst string) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+
+	os.Chown(dst, int(stat.Uid), int(stat.Gid))
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package fs
+
+// GetOwner returns the owner of path as "uid:gid"
+// Windows has no uid/gid ownership, so this always returns an empty string
+func GetOwner(path string) (string, error) {
+	return "", nil
+}
+
+// copyOwner is a no-op on Windows, which has no uid/gid ownership
+func copyOwner(src, dst string) {}
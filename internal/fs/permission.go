@@ -0,0 +1,79 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// CheckExists reports whether path exists (without following a final symlink).
+// Unlike PathExists, errors other than "does not exist" are returned, so a
+// file in an unreadable directory isn't mistaken for a missing one.
+// Use IsPermissionError to classify the error.
+func CheckExists(path string) (bool, error) {
+	_, err := os.Lstat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) {
+		return false, nil
+	}
+	return false, fmt.Errorf("checking %s: %w", path, err)
+}
+
+// IsPermissionError reports whether err was caused by missing permissions
+func IsPermissionError(err error) bool {
+	return errors.Is(err, os.ErrPermission)
+}
+
+// PermissionBlocker returns the path that denies access to path: the first
+// ancestor directory that can't be searched, or path itself if only the
+// file is unreadable.
+func PermissionBlocker(path string) string {
+	path = filepath.Clean(path)
+
+	// Walk down from the root; the parent of the first entry we can't stat
+	// is the directory missing search (x) permission
+	volume := filepath.VolumeName(path)
+	current := volume + string(filepath.Separator)
+	parts := strings.Split(strings.TrimPrefix(path[len(volume):], string(filepath.Separator)), string(filepath.Separator))
+	for _, part := range parts {
+		next := filepath.Join(current, part)
+		if _, err := os.Lstat(next); IsPermissionError(err) {
+			return current
+		}
+		current = next
+	}
+
+	return path
+}
+
+// PermissionHint suggests a command that would restore access to path.
+// It never changes anything itself; fixing ownership may need sudo.
+func PermissionHint(path string) string {
+	blocker := PermissionBlocker(path)
+
+	info, err := os.Lstat(blocker)
+	if err != nil {
+		return fmt.Sprintf("check permissions on %s", blocker)
+	}
+
+	owner, _ := GetOwner(blocker)
+	if uid := strings.SplitN(owner, ":", 2)[0]; owner != "" && uid != strconv.Itoa(os.Getuid()) {
+		name := strconv.Itoa(os.Getuid())
+		if u, err := user.Current(); err == nil {
+			name = u.Username
+		}
+		return fmt.Sprintf("%s is owned by another user, try: sudo chown %s %s", blocker, name, blocker)
+	}
+
+	if info.IsDir() {
+		return fmt.Sprintf("try: chmod u+rwx %s", blocker)
+	}
+	return fmt.Sprintf("try: chmod u+rw %s", blocker)
+}
@@ -0,0 +1,82 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckExists(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	file := filepath.Join(tempDir, "file")
+	if err := os.WriteFile(file, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"existing file", file, true},
+		{"missing file", filepath.Join(tempDir, "missing"), false},
+		{"parent is a file", filepath.Join(file, "child"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckExists(tt.path)
+			if err != nil {
+				t.Fatalf("CheckExists() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CheckExists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckExistsPermissionDenied(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("permission checks need a non-root Unix user")
+	}
+
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	locked := filepath.Join(tempDir, "locked")
+	file := filepath.Join(locked, "file")
+	if err := os.MkdirAll(locked, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(file, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatalf("failed to chmod: %v", err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	exists, err := CheckExists(file)
+	if exists || !IsPermissionError(err) {
+		t.Fatalf("CheckExists() = %v, %v; want permission error", exists, err)
+	}
+
+	if got := PermissionBlocker(file); got != locked {
+		t.Errorf("PermissionBlocker() = %s, want %s", got, locked)
+	}
+
+	if hint := PermissionHint(file); !strings.Contains(hint, "chmod u+rwx "+locked) {
+		t.Errorf("PermissionHint() = %q, want chmod suggestion for %s", hint, locked)
+	}
+}
@@ -0,0 +1,87 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Privileged operations for system files (outside $HOME).
+// Each operation runs directly when the current user can already write the
+// destination, and through sudo otherwise. Only the single filesystem
+// command is escalated - dotcor itself never runs as root.
+
+// sudoCommand is the program used for privilege escalation
+var sudoCommand = "sudo"
+
+// NeedsPrivilege checks if modifying path requires elevated privileges
+func NeedsPrivilege(path string) bool {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		return false
+	}
+
+	// Replacing or removing a file requires write access to its directory
+	return !IsWritable(filepath.Dir(path)) || (PathExists(path) && !IsWritable(path))
+}
+
+// DescribePrivileged returns a human-readable form of a privileged command
+// for confirmation prompts
+func DescribePrivileged(path string, name string, args ...string) string {
+	command := strings.Join(append([]string{name}, args...), " ")
+	if NeedsPrivilege(path) {
+		return sudoCommand + " " + command
+	}
+	return command
+}
+
+// runPrivileged runs a command, escalating through sudo if path needs it
+func runPrivileged(path string, name string, args ...string) error {
+	var cmd *exec.Cmd
+	if NeedsPrivilege(path) {
+		cmd = exec.Command(sudoCommand, append([]string{name}, args...)...)
+	} else {
+		cmd = exec.Command(name, args...)
+	}
+
+	// sudo may need to prompt for a password
+	cmd.Stdin = os.Stdin
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %s: %w", name, strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// PrivilegedCopy copies src over dst, creating its directory
+func PrivilegedCopy(src, dst string) error {
+	if !PathExists(filepath.Dir(dst)) {
+		if err := runPrivileged(dst, "mkdir", "-p", filepath.Dir(dst)); err != nil {
+			return err
+		}
+	}
+	// Remove a symlink first so cp doesn't write through it into the repo
+	if isLink, _ := IsSymlink(dst); isLink {
+		if err := PrivilegedRemove(dst); err != nil {
+			return err
+		}
+	}
+	return runPrivileged(dst, "cp", src, dst)
+}
+
+// PrivilegedRemove removes a file or symlink
+func PrivilegedRemove(path string) error {
+	return runPrivileged(path, "rm", "-f", path)
+}
+
+// PrivilegedChown sets the owner of path ("uid:gid")
+func PrivilegedChown(path, owner string) error {
+	return runPrivileged(path, "chown", owner, path)
+}
+
+// PrivilegedChmod sets the permissions of path
+func PrivilegedChmod(path string, mode os.FileMode) error {
+	return runPrivileged(path, "chmod", fmt.Sprintf("%o", mode.Perm()), path)
+}
@@ -0,0 +1,80 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPrivilegedOperations(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("privileged operations are not supported on Windows")
+	}
+
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Writable temp dir must not need sudo
+	if NeedsPrivilege(filepath.Join(tempDir, "file")) {
+		t.Fatal("NeedsPrivilege() should be false for a writable directory")
+	}
+
+	target := filepath.Join(tempDir, "repo", "hosts")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatalf("failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("127.0.0.1 localhost"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+
+	// Copy creates the directory of a file not there yet
+	link := filepath.Join(tempDir, "etc", "hosts")
+	if err := PrivilegedCopy(target, link); err != nil {
+		t.Fatalf("PrivilegedCopy() error = %v", err)
+	}
+	if same, _ := SameContent(target, link); !same {
+		t.Error("PrivilegedCopy() content differs")
+	}
+
+	// Copy replaces a symlink, as older versions deployed, with a regular
+	// file, leaving the repo intact
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := PrivilegedCopy(target, link); err != nil {
+		t.Fatalf("PrivilegedCopy() error = %v", err)
+	}
+	if isLink, _ := IsSymlink(link); isLink {
+		t.Error("PrivilegedCopy() should replace the symlink")
+	}
+
+	if err := PrivilegedChmod(link, 0600); err != nil {
+		t.Fatalf("PrivilegedChmod() error = %v", err)
+	}
+	mode, _ := GetFileMode(link)
+	if mode.Perm() != 0600 {
+		t.Errorf("PrivilegedChmod() mode = %o, want 600", mode.Perm())
+	}
+
+	owner, err := GetOwner(link)
+	if err != nil {
+		t.Fatalf("GetOwner() error = %v", err)
+	}
+	if err := PrivilegedChown(link, owner); err != nil {
+		t.Errorf("PrivilegedChown() error = %v", err)
+	}
+
+	if err := PrivilegedRemove(link); err != nil {
+		t.Fatalf("PrivilegedRemove() error = %v", err)
+	}
+	if PathExists(link) {
+		t.Error("PrivilegedRemove() should remove the file")
+	}
+}
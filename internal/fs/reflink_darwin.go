@@ -0,0 +1,32 @@
+//go:build darwin
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile makes dst a copy-on-write clone of src with clonefile(2) on
+// APFS. clonefile can't replace an existing file, so an existing dst is
+// left for a normal copy. Returns false if cloning isn't possible.
+func cloneFile(src, dst string, mode os.FileMode) bool {
+	if _, err := os.Lstat(dst); err == nil {
+		return false
+	}
+
+	// A normal copy follows symlinks, so the clone is made of the file a
+	// link points to rather than of the link itself
+	resolved, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return false
+	}
+	if err := unix.Clonefile(resolved, dst, unix.CLONE_NOFOLLOW); err != nil {
+		return false
+	}
+
+	// Match the permissions a normal copy would give
+	return os.Chmod(dst, mode) == nil
+}
@@ -0,0 +1,32 @@
+//go:build linux
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile makes dst a copy-on-write clone of src with FICLONE, which
+// btrfs, XFS and other reflink-capable filesystems support. Returns false
+// if cloning isn't possible, leaving dst to be written with a normal copy.
+func cloneFile(src, dst string, mode os.FileMode) bool {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return false
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return false
+	}
+	defer dstFile.Close()
+
+	if err := unix.IoctlFileClone(int(dstFile.Fd()), int(srcFile.Fd())); err != nil {
+		return false
+	}
+
+	return dstFile.Sync() == nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package fs
+
+import "os"
+
+// cloneFile reports that copy-on-write clones aren't supported here
+func cloneFile(src, dst string, mode os.FileMode) bool {
+	return false
+}
@@ -14,6 +14,14 @@ import (
 // ErrSymlinkUnsupported is returned when symlinks are not supported on the platform
 var ErrSymlinkUnsupported = errors.New("symlink support required - enable Developer Mode on Windows")
 
+// The reasons symlinks can't be created. Both wrap ErrSymlinkUnsupported.
+var (
+	// ErrSymlinkPrivilege: Windows without Developer Mode or an elevated shell
+	ErrSymlinkPrivilege = fmt.Errorf("%w: creating symlinks needs Developer Mode (Settings → For developers) or an elevated shell", ErrSymlinkUnsupported)
+	// ErrSymlinkFilesystem: the filesystem can't store symlinks, e.g. FAT32 or some network shares
+	ErrSymlinkFilesystem = fmt.Errorf("%w: the filesystem does not support symlinks - move the files to an NTFS or other native volume, or use copy mode", ErrSymlinkUnsupported)
+)
+
 // SymlinkStatus represents the detailed status of a symlink
 type SymlinkStatus struct {
 	Exists       bool   // Whether the symlink path exists
@@ -28,15 +36,17 @@ type SymlinkStatus struct {
 // The symlink uses a relative path computed from link's location to target.
 // Returns error if symlink fails (NO COPY FALLBACK).
 func CreateSymlink(target, link string) error {
-	// Check if platform supports symlinks
-	supported, err := SupportsSymlinks()
-	if err != nil {
-		return fmt.Errorf("checking symlink support: %w", err)
-	}
-	if !supported {
-		return ErrSymlinkUnsupported
-	}
+	return CreateSymlinkWithStyle(target, link, config.LinkStyleRelative)
+}
 
+// CreateSymlinkWithStyle creates a symlink at `link` pointing to `target`,
+// using an absolute target for config.LinkStyleAbsolute and a relative one
+// otherwise. Returns error if symlink fails (NO COPY FALLBACK), wrapping
+// ErrSymlinkPrivilege or ErrSymlinkFilesystem when that is the reason.
+//
+// On Windows without symlink privilege a directory is linked with an NTFS
+// junction instead, which is always absolute.
+func CreateSymlinkWithStyle(target, link, style string) error {
 	// Expand paths
 	expandedTarget, err := config.ExpandPath(target)
 	if err != nil {
@@ -53,22 +63,39 @@ func CreateSymlink(target, link string) error {
 		return fmt.Errorf("creating parent directory: %w", err)
 	}
 
-	// Compute RELATIVE path from link to target
-	relPath, err := config.ComputeRelativeSymlink(expandedLink, expandedTarget)
+	// Compute path from link to target
+	linkTarget := expandedTarget
+	if style != config.LinkStyleAbsolute {
+		linkTarget, err = config.ComputeRelativeSymlink(expandedLink, expandedTarget)
+		if err != nil {
+			return fmt.Errorf("computing relative path: %w", err)
+		}
+	}
+
+	linkPath, err := prepareWritePath(expandedLink)
 	if err != nil {
-		return fmt.Errorf("computing relative path: %w", err)
+		return err
 	}
 
 	// Remove existing file/symlink if it exists
-	if _, err := os.Lstat(expandedLink); err == nil {
-		if err := os.Remove(expandedLink); err != nil {
+	if _, err := os.Lstat(linkPath); err == nil {
+		if err := os.Remove(linkPath); err != nil {
 			return fmt.Errorf("removing existing file: %w", err)
 		}
 	}
 
-	// Create symlink with RELATIVE path
-	if err := os.Symlink(relPath, expandedLink); err != nil {
-		return fmt.Errorf("creating symlink: %w", err)
+	if err := os.Symlink(linkTarget, linkPath); err != nil {
+		reason := classifySymlinkError(err)
+		if reason == nil {
+			return fmt.Errorf("creating symlink: %w", err)
+		}
+		// Junctions need no privilege, but only link directories
+		if errors.Is(reason, ErrSymlinkPrivilege) {
+			if isDir, _ := IsDirectory(expandedTarget); isDir {
+				return createJunction(LongPath(expandedTarget), linkPath)
+			}
+		}
+		return fmt.Errorf("creating symlink %s: %w", link, reason)
 	}
 
 	return nil
@@ -90,21 +117,21 @@ func RemoveSymlink(link string) error {
 		return fmt.Errorf("path is not a symlink: %s", link)
 	}
 
-	if err := os.Remove(expandedLink); err != nil {
+	if err := os.Remove(LongPath(expandedLink)); err != nil {
 		return fmt.Errorf("removing symlink: %w", err)
 	}
 
 	return nil
 }
 
-// IsSymlink checks if path is a symlink
+// IsSymlink checks if path is a symlink. NTFS junctions count as symlinks.
 func IsSymlink(path string) (bool, error) {
 	expandedPath, err := config.ExpandPath(path)
 	if err != nil {
 		return false, fmt.Errorf("expanding path: %w", err)
 	}
 
-	info, err := os.Lstat(expandedPath)
+	info, err := os.Lstat(LongPath(expandedPath))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, nil
@@ -112,7 +139,22 @@ func IsSymlink(path string) (bool, error) {
 		return false, fmt.Errorf("getting file info: %w", err)
 	}
 
-	return info.Mode()&os.ModeSymlink != 0, nil
+	return isLink(expandedPath, info), nil
+}
+
+// isLink reports whether info, from os.Lstat of path, is a symlink or junction
+func isLink(path string, info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0 || isJunction(LongPath(path), info)
+}
+
+// readLink returns the target of the link at path, without the long-path
+// prefix Windows adds to junction and long targets
+func readLink(path string) (string, error) {
+	target, err := os.Readlink(LongPath(path))
+	if err != nil {
+		return "", err
+	}
+	return trimLongPathPrefix(target), nil
 }
 
 // ReadSymlink reads the target of a symlink (returns raw target, may be relative)
@@ -122,7 +164,7 @@ func ReadSymlink(link string) (string, error) {
 		return "", fmt.Errorf("expanding path: %w", err)
 	}
 
-	target, err := os.Readlink(expandedLink)
+	target, err := readLink(expandedLink)
 	if err != nil {
 		return "", fmt.Errorf("reading symlink: %w", err)
 	}
@@ -162,7 +204,7 @@ func IsValidSymlink(link string) (bool, error) {
 	}
 
 	// Check if target exists
-	_, err = os.Stat(fullTarget)
+	_, err = os.Stat(LongPath(fullTarget))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, nil // Symlink exists but target doesn't
@@ -175,7 +217,8 @@ func IsValidSymlink(link string) (bool, error) {
 
 // SupportsSymlinks checks if current platform supports symlinks
 // Windows: requires admin rights or developer mode
-// Returns true on macOS/Linux, checks on Windows
+// Returns true on macOS/Linux, checks on Windows. When unsupported, the error
+// is ErrSymlinkPrivilege or ErrSymlinkFilesystem, saying what to do.
 func SupportsSymlinks() (bool, error) {
 	if runtime.GOOS != "windows" {
 		return true, nil
@@ -199,7 +242,10 @@ func SupportsSymlinks() (bool, error) {
 	// Try to create symlink
 	err := os.Symlink(testFile, testLink)
 	if err != nil {
-		return false, nil // Symlinks not supported
+		if reason := classifySymlinkError(err); reason != nil {
+			return false, reason
+		}
+		return false, fmt.Errorf("%w: %v", ErrSymlinkUnsupported, err)
 	}
 	defer os.Remove(testLink) // Clean up test symlink
 
@@ -216,7 +262,7 @@ func GetSymlinkStatus(linkPath string, expectedTarget string) (SymlinkStatus, er
 	}
 
 	// Check if path exists
-	info, err := os.Lstat(expandedLink)
+	info, err := os.Lstat(LongPath(expandedLink))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return status, nil // Path doesn't exist
@@ -226,13 +272,13 @@ func GetSymlinkStatus(linkPath string, expectedTarget string) (SymlinkStatus, er
 	status.Exists = true
 
 	// Check if it's a symlink
-	status.IsSymlink = info.Mode()&os.ModeSymlink != 0
+	status.IsSymlink = isLink(expandedLink, info)
 	if !status.IsSymlink {
 		return status, nil // Not a symlink
 	}
 
 	// Read symlink target
-	target, err := os.Readlink(expandedLink)
+	target, err := readLink(expandedLink)
 	if err != nil {
 		return status, fmt.Errorf("reading symlink: %w", err)
 	}
@@ -250,7 +296,7 @@ func GetSymlinkStatus(linkPath string, expectedTarget string) (SymlinkStatus, er
 	}
 
 	// Check if target exists
-	_, err = os.Stat(fullTarget)
+	_, err = os.Stat(LongPath(fullTarget))
 	status.TargetExists = err == nil
 
 	// Check if target points to our repo
@@ -260,10 +306,7 @@ func GetSymlinkStatus(linkPath string, expectedTarget string) (SymlinkStatus, er
 			return status, fmt.Errorf("expanding expected target path: %w", err)
 		}
 
-		// Clean both paths for comparison
-		cleanTarget := filepath.Clean(fullTarget)
-		cleanExpected := filepath.Clean(expandedExpected)
-		status.PointsToRepo = cleanTarget == cleanExpected
+		status.PointsToRepo = ComparablePath(fullTarget) == ComparablePath(expandedExpected)
 	}
 
 	return status, nil
@@ -277,7 +320,7 @@ func ResolveSymlink(link string) (string, error) {
 	}
 
 	// Read the symlink target
-	target, err := os.Readlink(expandedLink)
+	target, err := readLink(expandedLink)
 	if err != nil {
 		return "", fmt.Errorf("reading symlink: %w", err)
 	}
@@ -311,6 +354,9 @@ func SymlinkPointsToRepo(link string, repoPath string) (bool, error) {
 		return false, fmt.Errorf("expanding repo path: %w", err)
 	}
 
-	// Check if resolved path is under repo
-	return strings.HasPrefix(resolved, expandedRepo), nil
+	// Check if resolved path is under repo, on a path boundary so ~/dotfiles
+	// doesn't match ~/dotfiles-old
+	resolved = ComparablePath(resolved)
+	expandedRepo = ComparablePath(expandedRepo)
+	return resolved == expandedRepo || strings.HasPrefix(resolved, expandedRepo+string(filepath.Separator)), nil
 }
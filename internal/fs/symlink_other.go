@@ -0,0 +1,40 @@
+//go:build !windows
+
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// classifySymlinkError maps an error from os.Symlink to ErrSymlinkFilesystem
+// for filesystems without symlinks, such as FAT on a USB stick, or nil.
+// Unix needs no privilege to create symlinks.
+func classifySymlinkError(err error) error {
+	if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EOPNOTSUPP) {
+		return ErrSymlinkFilesystem
+	}
+	return nil
+}
+
+// createJunction is never reached; only Windows has junctions
+func createJunction(target, link string) error {
+	return ErrSymlinkUnsupported
+}
+
+// isJunction reports false; only Windows has junctions
+func isJunction(path string, info os.FileInfo) bool {
+	return false
+}
+
+// trimLongPathPrefix returns path unchanged; only Windows has long-path prefixes
+func trimLongPathPrefix(path string) string {
+	return path
+}
+
+// ComparablePath returns path cleaned, so it can be compared with ==
+func ComparablePath(path string) string {
+	return filepath.Clean(path)
+}
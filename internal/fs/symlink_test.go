@@ -1,10 +1,13 @@
 package fs
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
 )
 
 func TestSupportsSymlinks(t *testing.T) {
@@ -19,6 +22,70 @@ func TestSupportsSymlinks(t *testing.T) {
 	}
 }
 
+func TestSymlinkErrorsWrapUnsupported(t *testing.T) {
+	for _, err := range []error{ErrSymlinkPrivilege, ErrSymlinkFilesystem} {
+		if !errors.Is(err, ErrSymlinkUnsupported) {
+			t.Errorf("%v does not wrap ErrSymlinkUnsupported", err)
+		}
+	}
+	if errors.Is(ErrSymlinkPrivilege, ErrSymlinkFilesystem) {
+		t.Error("ErrSymlinkPrivilege should be distinguishable from ErrSymlinkFilesystem")
+	}
+}
+
+func TestCreateSymlinkWithStyle(t *testing.T) {
+	supported, _ := SupportsSymlinks()
+	if !supported {
+		t.Skip("symlinks not supported on this platform")
+	}
+
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	target := filepath.Join(tempDir, "repo", "zshrc")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatalf("failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("test"), 0644); err != nil {
+		t.Fatalf("failed to create target: %v", err)
+	}
+
+	tests := []struct {
+		style        string
+		wantRelative bool
+	}{
+		{"", true},
+		{config.LinkStyleRelative, true},
+		{config.LinkStyleAbsolute, false},
+	}
+
+	link := filepath.Join(tempDir, "home", ".zshrc")
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			// Existing link is replaced
+			if err := CreateSymlinkWithStyle(target, link, tt.style); err != nil {
+				t.Fatalf("CreateSymlinkWithStyle() error = %v", err)
+			}
+
+			isRel, err := IsRelativeSymlink(link)
+			if err != nil {
+				t.Fatalf("IsRelativeSymlink() error = %v", err)
+			}
+			if isRel != tt.wantRelative {
+				t.Errorf("relative = %v, want %v", isRel, tt.wantRelative)
+			}
+
+			resolved, _ := ResolveSymlink(link)
+			if resolved != target {
+				t.Errorf("link resolves to %q, want %q", resolved, target)
+			}
+		})
+	}
+}
+
 func TestIsSymlink(t *testing.T) {
 	// Skip on Windows if symlinks not supported
 	supported, _ := SupportsSymlinks()
@@ -418,3 +485,47 @@ func TestRemoveSymlinkErrorsOnRegularFile(t *testing.T) {
 		t.Error("RemoveSymlink() removed regular file")
 	}
 }
+
+func TestSymlinkPointsToRepo(t *testing.T) {
+	supported, _ := SupportsSymlinks()
+	if !supported {
+		t.Skip("symlinks not supported on this platform")
+	}
+
+	tempDir := t.TempDir()
+	for _, name := range []string{"dotfiles/zshrc", "dotfiles-old/zshrc"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := filepath.Join(tempDir, "dotfiles")
+	tests := []struct {
+		name   string
+		target string
+		want   bool
+	}{
+		{"in repo", filepath.Join(repo, "zshrc"), true},
+		{"sibling with repo name as prefix", filepath.Join(tempDir, "dotfiles-old", "zshrc"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := filepath.Join(tempDir, "link")
+			if err := CreateSymlinkWithStyle(tt.target, link, config.LinkStyleAbsolute); err != nil {
+				t.Fatal(err)
+			}
+			got, err := SymlinkPointsToRepo(link, repo)
+			if err != nil {
+				t.Fatalf("SymlinkPointsToRepo() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SymlinkPointsToRepo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+//go:build windows
+
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Windows error codes from a failed CreateSymbolicLink
+const (
+	errorInvalidFunction  = syscall.Errno(1)    // Filesystem has no reparse points (FAT32, exFAT)
+	errorNotSupported     = syscall.Errno(50)   // Request not supported, e.g. by a network share
+	errorPrivilegeNotHeld = syscall.Errno(1314) // Neither Developer Mode nor an elevated shell
+)
+
+// classifySymlinkError maps an error from os.Symlink to ErrSymlinkPrivilege
+// or ErrSymlinkFilesystem, or nil when it is neither
+func classifySymlinkError(err error) error {
+	switch {
+	case errors.Is(err, errorPrivilegeNotHeld):
+		return ErrSymlinkPrivilege
+	case errors.Is(err, errorNotSupported), errors.Is(err, errorInvalidFunction):
+		return ErrSymlinkFilesystem
+	}
+	return nil
+}
+
+// createJunction creates an NTFS junction at link pointing to the directory
+// target. Junctions need no privilege, but their target is always absolute
+// and must be on a local volume.
+func createJunction(target, link string) error {
+	output, err := exec.Command("cmd", "/c", "mklink", "/J", link, target).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("creating junction: %s", msg)
+		}
+		return fmt.Errorf("creating junction: %w", err)
+	}
+	return nil
+}
+
+// isJunction reports whether info, from os.Lstat of path, describes an NTFS
+// junction. Go reports junctions as irregular files rather than symlinks.
+func isJunction(path string, info os.FileInfo) bool {
+	if info.Mode()&os.ModeIrregular == 0 {
+		return false
+	}
+	_, err := os.Readlink(path)
+	return err == nil
+}
+
+// trimLongPathPrefix strips the \\?\ prefix that long paths and junction
+// targets carry, so they compare equal to their plain form
+func trimLongPathPrefix(path string) string {
+	switch {
+	case strings.HasPrefix(path, `\\?\UNC\`):
+		return `\\` + path[len(`\\?\UNC\`):]
+	case strings.HasPrefix(path, `\\?\`), strings.HasPrefix(path, `\??\`):
+		return path[4:]
+	}
+	return path
+}
+
+// ComparablePath returns path in a form that can be compared with ==: the
+// long-path prefix is removed, the path is cleaned and the drive letter is
+// upper-cased, since c:\Users and C:\Users are the same directory
+func ComparablePath(path string) string {
+	path = filepath.Clean(trimLongPathPrefix(path))
+	if len(path) >= 2 && path[1] == ':' {
+		path = strings.ToUpper(path[:1]) + path[1:]
+	}
+	return path
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComparablePath(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"drive letter casing", `c:\Users\me\.zshrc`, `C:\Users\me\.zshrc`},
+		{"long-path prefix", `\\?\C:\Users\me\.zshrc`, `C:\Users\me\.zshrc`},
+		{"junction target prefix", `\??\C:\dotfiles`, `C:\dotfiles`},
+		{"long UNC prefix", `\\?\UNC\server\share\rc`, `\\server\share\rc`},
+		{"unclean", `C:\Users\me\..\me\.zshrc`, `C:\Users\me\.zshrc`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, b := ComparablePath(tt.a), ComparablePath(tt.b); a != b {
+				t.Errorf("ComparablePath(%q) = %q, ComparablePath(%q) = %q, want equal", tt.a, a, tt.b, b)
+			}
+		})
+	}
+}
+
+func TestIsSymlinkJunction(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "nvim")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(tempDir, "link")
+	if err := createJunction(target, link); err != nil {
+		t.Skipf("junctions not supported here: %v", err)
+	}
+
+	isLink, err := IsSymlink(link)
+	if err != nil || !isLink {
+		t.Errorf("IsSymlink() = %v, %v, want a junction to count as a link", isLink, err)
+	}
+	resolved, err := ResolveSymlink(link)
+	if err != nil {
+		t.Fatalf("ResolveSymlink() error = %v", err)
+	}
+	if ComparablePath(resolved) != ComparablePath(target) {
+		t.Errorf("ResolveSymlink() = %q, want %q", resolved, target)
+	}
+}
@@ -0,0 +1,151 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// ErrTrashUnavailable is returned when the platform has no usable trash
+var ErrTrashUnavailable = errors.New("trash not available on this platform")
+
+// GetTrashDir returns the user's trash directory:
+// ~/.Trash on macOS, $XDG_DATA_HOME/Trash (~/.local/share/Trash) elsewhere
+func GetTrashDir() (string, error) {
+	home, err := config.HomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, ".Trash"), nil
+	case "windows":
+		return "", ErrTrashUnavailable
+	}
+
+	dataHome := config.CurrentEnvironment().Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+// MoveToTrash moves a file or directory to the user's trash.
+// On XDG systems a .trashinfo entry is written so file managers can restore it.
+// Returns the path inside the trash.
+func MoveToTrash(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("resolving path: %w", err)
+	}
+
+	if _, err := os.Lstat(absPath); err != nil {
+		return "", fmt.Errorf("checking path: %w", err)
+	}
+
+	trashDir, err := GetTrashDir()
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS == "darwin" {
+		if err := EnsureDir(trashDir); err != nil {
+			return "", fmt.Errorf("creating trash directory: %w", err)
+		}
+		dest := uniqueTrashPath(trashDir, filepath.Base(absPath), "")
+		if err := moveToTrashPath(absPath, dest); err != nil {
+			return "", err
+		}
+		return dest, nil
+	}
+
+	// XDG trash: files/ holds the data, info/ the original location
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	if err := EnsureDir(filesDir); err != nil {
+		return "", fmt.Errorf("creating trash directory: %w", err)
+	}
+	if err := EnsureDir(infoDir); err != nil {
+		return "", fmt.Errorf("creating trash directory: %w", err)
+	}
+
+	dest := uniqueTrashPath(filesDir, filepath.Base(absPath), infoDir)
+	infoPath := filepath.Join(infoDir, filepath.Base(dest)+".trashinfo")
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		escapeTrashPath(absPath), time.Now().Format("2006-01-02T15:04:05"))
+	if err := os.WriteFile(infoPath, []byte(info), 0600); err != nil {
+		return "", fmt.Errorf("writing trash info: %w", err)
+	}
+
+	if err := moveToTrashPath(absPath, dest); err != nil {
+		os.Remove(infoPath)
+		return "", err
+	}
+
+	return dest, nil
+}
+
+// moveToTrashPath moves src to dest, copying across filesystems if needed
+func moveToTrashPath(src, dest string) error {
+	renameErr := os.Rename(src, dest)
+	if renameErr == nil {
+		return nil
+	}
+
+	// Directories and symlinks are not copied across filesystems; the trash
+	// counts as unavailable for them, so the caller can delete them instead
+	info, err := os.Lstat(src)
+	if err != nil {
+		return fmt.Errorf("moving to trash: %w", err)
+	}
+	if info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
+		if errors.Is(renameErr, syscall.EXDEV) {
+			return fmt.Errorf("%w: cannot move %s to another filesystem", ErrTrashUnavailable, src)
+		}
+		return fmt.Errorf("moving to trash: %w", renameErr)
+	}
+
+	if err := MoveFile(src, dest); err != nil {
+		return fmt.Errorf("moving to trash: %w", err)
+	}
+	return nil
+}
+
+// uniqueTrashPath returns a path in dir for name that doesn't collide with
+// existing trash entries (or their .trashinfo files in infoDir)
+func uniqueTrashPath(dir, name, infoDir string) string {
+	candidate := name
+	for i := 1; ; i++ {
+		dest := filepath.Join(dir, candidate)
+		_, errData := os.Lstat(dest)
+		errInfo := os.ErrNotExist
+		if infoDir != "" {
+			_, errInfo = os.Lstat(filepath.Join(infoDir, candidate+".trashinfo"))
+		}
+		if os.IsNotExist(errData) && os.IsNotExist(errInfo) {
+			return dest
+		}
+
+		ext := filepath.Ext(name)
+		candidate = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+}
+
+// escapeTrashPath URL-encodes each path segment as required by the trash spec
+func escapeTrashPath(path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
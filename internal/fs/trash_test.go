@@ -0,0 +1,126 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestMoveToTrash(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG trash layout is only tested on Linux")
+	}
+
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tempDir, "data"))
+
+	trashDir, err := GetTrashDir()
+	if err != nil {
+		t.Fatalf("GetTrashDir() error = %v", err)
+	}
+	if want := filepath.Join(tempDir, "data", "Trash"); trashDir != want {
+		t.Errorf("GetTrashDir() = %q, want %q", trashDir, want)
+	}
+
+	// Two files with the same name must not collide in the trash
+	var trashed []string
+	for i, dir := range []string{"a", "b c"} {
+		path := filepath.Join(tempDir, dir, ".zshrc")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte{byte('0' + i)}, 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+
+		dest, err := MoveToTrash(path)
+		if err != nil {
+			t.Fatalf("MoveToTrash() error = %v", err)
+		}
+		if FileExists(path) {
+			t.Errorf("MoveToTrash() left %s in place", path)
+		}
+		trashed = append(trashed, dest)
+	}
+
+	if trashed[0] == trashed[1] {
+		t.Fatalf("MoveToTrash() reused trash path %s", trashed[0])
+	}
+
+	for i, dest := range trashed {
+		data, err := os.ReadFile(dest)
+		if err != nil || string(data) != string(rune('0'+i)) {
+			t.Errorf("trashed file %s has content %q, err %v", dest, data, err)
+		}
+
+		infoPath := filepath.Join(trashDir, "info", filepath.Base(dest)+".trashinfo")
+		info, err := os.ReadFile(infoPath)
+		if err != nil {
+			t.Fatalf("reading trash info: %v", err)
+		}
+		if !strings.Contains(string(info), "DeletionDate=") {
+			t.Errorf("trash info missing DeletionDate: %s", info)
+		}
+	}
+
+	// Original path is URL-escaped in the info file
+	info, _ := os.ReadFile(filepath.Join(trashDir, "info", filepath.Base(trashed[1])+".trashinfo"))
+	if !strings.Contains(string(info), "Path="+filepath.ToSlash(tempDir)+"/b%20c/.zshrc") {
+		t.Errorf("trash info has unexpected Path: %s", info)
+	}
+}
+
+func TestMoveToTrashAcrossFilesystems(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG trash layout is only tested on Linux")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// The trash is put on another filesystem, if there's one to put it on
+	shm, err := os.MkdirTemp("/dev/shm", "dotcor-test-*")
+	if err != nil {
+		t.Skip("no /dev/shm")
+	}
+	defer os.RemoveAll(shm)
+	probe := filepath.Join(home, "probe")
+	if err := os.Mkdir(probe, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(probe, filepath.Join(shm, "probe")); !errors.Is(err, syscall.EXDEV) {
+		t.Skip("/dev/shm is on the same filesystem")
+	}
+	t.Setenv("XDG_DATA_HOME", shm)
+
+	file := filepath.Join(home, ".zshrc")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MoveToTrash(file); err != nil {
+		t.Errorf("MoveToTrash() of a file error = %v", err)
+	}
+
+	dir := filepath.Join(home, ".config", "nvim")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MoveToTrash(dir); !errors.Is(err, ErrTrashUnavailable) {
+		t.Errorf("MoveToTrash() of a directory error = %v, want ErrTrashUnavailable", err)
+	}
+	if !PathExists(dir) {
+		t.Errorf("MoveToTrash() lost %s", dir)
+	}
+	if PathExists(filepath.Join(shm, "Trash", "info", "nvim.trashinfo")) {
+		t.Error("MoveToTrash() left the trash info of a directory it didn't move")
+	}
+}
@@ -0,0 +1,64 @@
+//go:build linux || darwin
+
+package fs
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyXattrs copies extended attributes (macOS quarantine and Finder tags,
+// SELinux labels, user.* attributes) from src to dst.
+// Attributes the filesystem or our privileges don't allow are skipped.
+func copyXattrs(src, dst string) {
+	names, err := listXattrs(src)
+	if err != nil {
+		return // Filesystem doesn't support xattrs
+	}
+
+	for _, name := range names {
+		value, err := getXattr(src, name)
+		if err != nil {
+			continue
+		}
+		unix.Setxattr(dst, name, value, 0)
+	}
+}
+
+// listXattrs returns the names of the extended attributes on path
+func listXattrs(path string) ([]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+// getXattr returns the value of an extended attribute
+func getXattr(path, name string) ([]byte, error) {
+	size, err := unix.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Getxattr(path, name, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
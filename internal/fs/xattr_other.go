@@ -0,0 +1,6 @@
+//go:build !linux && !darwin
+
+package fs
+
+// copyXattrs is a no-op on platforms without extended attribute support
+func copyXattrs(src, dst string) {}
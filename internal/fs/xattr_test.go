@@ -0,0 +1,37 @@
+//go:build linux || darwin
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCopyPreservesXattrs(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	src := filepath.Join(tempDir, "src")
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	if err := unix.Setxattr(src, "user.dotcor.test", []byte("tagged"), 0); err != nil {
+		t.Skipf("filesystem does not support user xattrs: %v", err)
+	}
+
+	preserved := filepath.Join(tempDir, "preserved")
+	if err := CopyPreservingMetadata(src, preserved); err != nil {
+		t.Fatalf("CopyPreservingMetadata() error = %v", err)
+	}
+	value, err := getXattr(preserved, "user.dotcor.test")
+	if err != nil || string(value) != "tagged" {
+		t.Errorf("xattr not preserved: value %q, err %v", value, err)
+	}
+
+}
@@ -0,0 +1,377 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/log"
+)
+
+// Backend names, as used in the git_backend config setting
+const (
+	BackendAuto  = "auto"   // git command if installed, otherwise go-git (default)
+	BackendCLI   = "cli"    // Always use the git command
+	BackendGoGit = "go-git" // Built-in go-git, falling back to the git command where needed
+)
+
+// ErrUnsupported is returned by the go-git backend for operations it can't
+// perform, such as authenticating through a credential helper
+var ErrUnsupported = errors.New("not supported by the built-in git backend")
+
+// ErrBehindRemote is returned when a push is refused because the remote has
+// commits the local branch doesn't, so they have to be pulled first
+var ErrBehindRemote = errors.New("remote has commits that aren't here yet")
+
+// Backend performs the repository operations dotcor's core workflow needs.
+// History, diffs and worktrees always use the git command.
+type Backend interface {
+	Name() string
+	InitRepo(repoPath string) error
+	IsRepo(repoPath string) bool
+	HasChanges(repoPath string, pathspecs ...string) (bool, error)
+	AutoCommit(repoPath, message string, pathspecs ...string) error
+	GetStatus(repoPath string) (StatusInfo, error)
+	GetChangedFiles(repoPath string, pathspecs ...string) ([]ChangeEntry, error)
+	GetCurrentCommit(repoPath string) (string, error)
+	GetRemoteURL(repoPath string) (string, error)
+	SetRemote(repoPath, remoteName, remoteURL string) error
+	Clone(url, destPath string) error
+	Fetch(repoPath string) error
+	GetIncomingFiles(repoPath string) ([]string, error)
+	Pull(repoPath string) error
+	PullRebase(repoPath string) error
+	Push(repoPath string) error
+	PushRemote(repoPath, remoteName string) error
+}
+
+// backendName is the backend selected with SetBackend
+var backendName = BackendAuto
+
+// ValidateBackend returns an error if name is not a known backend
+func ValidateBackend(name string) error {
+	switch name {
+	case "", BackendAuto, BackendCLI, BackendGoGit:
+		return nil
+	}
+	return fmt.Errorf("invalid git backend %q (expected auto, cli or go-git)", name)
+}
+
+// SetBackend selects the backend used by the package-level functions
+func SetBackend(name string) error {
+	if err := ValidateBackend(name); err != nil {
+		return err
+	}
+	if name == "" {
+		name = BackendAuto
+	}
+	backendName = name
+	return nil
+}
+
+// SelectedBackend returns the backend setting selected with SetBackend
+func SelectedBackend() string {
+	return backendName
+}
+
+// CurrentBackend returns the backend for the selected setting
+func CurrentBackend() Backend {
+	switch {
+	case backendName == BackendCLI:
+		return cliBackend{}
+	case backendName == BackendAuto && IsGitInstalled():
+		return cliBackend{}
+	}
+	return fallbackBackend{primary: goGitBackend{}}
+}
+
+// IsAvailable reports whether repository operations can run, either with
+// the git command or the built-in backend
+func IsAvailable() bool {
+	return backendName != BackendCLI || IsGitInstalled()
+}
+
+// InitRepo initializes git repository in directory
+// A linked worktree already belongs to a repository and is left untouched
+func InitRepo(repoPath string) error {
+	return logged(CurrentBackend().InitRepo(repoPath), "git init", "repo", repoPath)
+}
+
+// IsRepo checks if directory is a git repository
+func IsRepo(repoPath string) bool {
+	return CurrentBackend().IsRepo(repoPath)
+}
+
+// AutoCommit stages all changes and commits with message
+// Changes are scoped to repoPath, which may be a subdirectory of the repository,
+// and further limited to pathspecs when given (default ".")
+// The message is rewritten by the commit format set with SetCommitFormat,
+// and the check set with SetCommitCheck can stop the commit.
+// Returns nil if no changes to commit
+func AutoCommit(repoPath, message string, pathspecs ...string) error {
+	if commitFormat.enabled() {
+		changes, err := CurrentBackend().GetChangedFiles(repoPath, pathspecs...)
+		if err != nil {
+			return fmt.Errorf("listing changes to commit: %w", err)
+		}
+		message = commitFormat.render(message, changes)
+	}
+	return AutoCommitVerbatim(repoPath, message, pathspecs...)
+}
+
+// AutoCommitVerbatim is AutoCommit with message used exactly as given, for
+// messages the user wrote
+func AutoCommitVerbatim(repoPath, message string, pathspecs ...string) error {
+	if err := runCommitCheck(repoPath, pathspecs); err != nil {
+		return err
+	}
+	err := CurrentBackend().AutoCommit(repoPath, message, pathspecs...)
+	return logged(err, "git commit", "repo", repoPath, "message", message, "paths", pathspecs)
+}
+
+// HasChanges checks if working tree has uncommitted changes under repoPath,
+// limited to pathspecs when given
+func HasChanges(repoPath string, pathspecs ...string) (bool, error) {
+	return CurrentBackend().HasChanges(repoPath, pathspecs...)
+}
+
+// GetStatus returns git status information: the branch, how far it is from
+// its remote, and the changed files under repoPath
+func GetStatus(repoPath string) (StatusInfo, error) {
+	return CurrentBackend().GetStatus(repoPath)
+}
+
+// GetChangedFiles returns changed files under repoPath, limited to pathspecs
+// when given. Renames and paths with spaces or non-ASCII characters are
+// reported exactly.
+func GetChangedFiles(repoPath string, pathspecs ...string) ([]ChangeEntry, error) {
+	return CurrentBackend().GetChangedFiles(repoPath, pathspecs...)
+}
+
+// GetCurrentCommit returns the current commit hash
+func GetCurrentCommit(repoPath string) (string, error) {
+	return CurrentBackend().GetCurrentCommit(repoPath)
+}
+
+// GetRemoteURL returns configured remote URL, or empty if none
+func GetRemoteURL(repoPath string) (string, error) {
+	return CurrentBackend().GetRemoteURL(repoPath)
+}
+
+// SetRemote configures git remote
+func SetRemote(repoPath, remoteName, remoteURL string) error {
+	err := CurrentBackend().SetRemote(repoPath, remoteName, remoteURL)
+	return logged(err, "git remote set", "repo", repoPath, "remote", remoteName, "url", RedactURL(remoteURL))
+}
+
+// Clone clones a repository to the specified path
+func Clone(url, destPath string) error {
+	return logged(CurrentBackend().Clone(url, destPath), "git clone", "url", RedactURL(url), "dest", destPath)
+}
+
+// Fetch fetches changes from remote without merging
+func Fetch(repoPath string) error {
+	return CurrentBackend().Fetch(repoPath)
+}
+
+// GetIncomingFiles returns files under repoPath that a pull from the upstream
+// branch would change. Paths are relative to repoPath. Call Fetch first.
+func GetIncomingFiles(repoPath string) ([]string, error) {
+	return CurrentBackend().GetIncomingFiles(repoPath)
+}
+
+// Pull pulls changes from remote
+func Pull(repoPath string) error {
+	return logged(CurrentBackend().Pull(repoPath), "git pull", "repo", repoPath)
+}
+
+// PullRebase pulls changes from remote and replays local commits on top of
+// them instead of merging
+func PullRebase(repoPath string) error {
+	return logged(CurrentBackend().PullRebase(repoPath), "git pull --rebase", "repo", repoPath)
+}
+
+// PushRemote pushes the current branch to the named remote, leaving the
+// branch's upstream unchanged
+func PushRemote(repoPath, remoteName string) error {
+	return logged(CurrentBackend().PushRemote(repoPath, remoteName), "git push", "repo", repoPath, "remote", remoteName)
+}
+
+// Sync commits all changes and pushes to remote (if configured), as Push
+// does
+func Sync(repoPath string) error {
+	// Generate commit message with timestamp
+	message := fmt.Sprintf("Sync dotfiles - %s", time.Now().Format("2006-01-02 15:04"))
+
+	if err := AutoCommit(repoPath, message); err != nil {
+		return err
+	}
+	return Push(repoPath)
+}
+
+// Push pushes committed changes to origin (if configured) without
+// committing anything. The remote is fetched first; when it has commits
+// that aren't here, nothing is pushed and an error wrapping ErrBehindRemote
+// is returned, so they can be pulled with Pull or PullRebase before pushing
+// again.
+func Push(repoPath string) error {
+	// Check if remote exists
+	backend := CurrentBackend()
+	remoteURL, err := backend.GetRemoteURL(repoPath)
+	if err != nil || remoteURL == "" {
+		return nil // No remote configured, skip push
+	}
+
+	if err := backend.Fetch(repoPath); err != nil {
+		return logged(err, "git fetch", "repo", repoPath, "remote", "origin")
+	}
+	status, err := backend.GetStatus(repoPath)
+	if err != nil {
+		return fmt.Errorf("getting git status: %w", err)
+	}
+	if status.RemoteExists && status.BehindBy > 0 {
+		err := fmt.Errorf("%w (%d commit(s) to pull)", ErrBehindRemote, status.BehindBy)
+		return logged(err, "git push", "repo", repoPath, "remote", "origin")
+	}
+
+	return logged(backend.Push(repoPath), "git push", "repo", repoPath, "remote", "origin")
+}
+
+// logged records a git operation that changed the repository or a remote
+// in the log, returning err
+func logged(err error, msg string, args ...any) error {
+	if err != nil {
+		log.Error(msg+" failed", append(args, "error", err)...)
+	} else {
+		log.Info(msg, args...)
+	}
+	return err
+}
+
+// fallbackBackend runs operations with primary, retrying with the git
+// command when primary returns ErrUnsupported and git is installed
+type fallbackBackend struct {
+	primary Backend
+}
+
+func (f fallbackBackend) Name() string { return f.primary.Name() }
+
+// fallback reports whether err should be retried with the git command
+func (f fallbackBackend) fallback(err error) bool {
+	return errors.Is(err, ErrUnsupported) && IsGitInstalled()
+}
+
+func (f fallbackBackend) InitRepo(repoPath string) error {
+	if err := f.primary.InitRepo(repoPath); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.InitRepo(repoPath)
+}
+
+func (f fallbackBackend) IsRepo(repoPath string) bool {
+	return f.primary.IsRepo(repoPath)
+}
+
+func (f fallbackBackend) HasChanges(repoPath string, pathspecs ...string) (bool, error) {
+	changed, err := f.primary.HasChanges(repoPath, pathspecs...)
+	if !f.fallback(err) {
+		return changed, err
+	}
+	return cliBackend{}.HasChanges(repoPath, pathspecs...)
+}
+
+func (f fallbackBackend) AutoCommit(repoPath, message string, pathspecs ...string) error {
+	if err := f.primary.AutoCommit(repoPath, message, pathspecs...); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.AutoCommit(repoPath, message, pathspecs...)
+}
+
+func (f fallbackBackend) GetStatus(repoPath string) (StatusInfo, error) {
+	status, err := f.primary.GetStatus(repoPath)
+	if !f.fallback(err) {
+		return status, err
+	}
+	return cliBackend{}.GetStatus(repoPath)
+}
+
+func (f fallbackBackend) GetChangedFiles(repoPath string, pathspecs ...string) ([]ChangeEntry, error) {
+	changes, err := f.primary.GetChangedFiles(repoPath, pathspecs...)
+	if !f.fallback(err) {
+		return changes, err
+	}
+	return cliBackend{}.GetChangedFiles(repoPath, pathspecs...)
+}
+
+func (f fallbackBackend) GetCurrentCommit(repoPath string) (string, error) {
+	hash, err := f.primary.GetCurrentCommit(repoPath)
+	if !f.fallback(err) {
+		return hash, err
+	}
+	return cliBackend{}.GetCurrentCommit(repoPath)
+}
+
+func (f fallbackBackend) GetRemoteURL(repoPath string) (string, error) {
+	url, err := f.primary.GetRemoteURL(repoPath)
+	if !f.fallback(err) {
+		return url, err
+	}
+	return cliBackend{}.GetRemoteURL(repoPath)
+}
+
+func (f fallbackBackend) SetRemote(repoPath, remoteName, remoteURL string) error {
+	if err := f.primary.SetRemote(repoPath, remoteName, remoteURL); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.SetRemote(repoPath, remoteName, remoteURL)
+}
+
+func (f fallbackBackend) Clone(url, destPath string) error {
+	if err := f.primary.Clone(url, destPath); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.Clone(url, destPath)
+}
+
+func (f fallbackBackend) Fetch(repoPath string) error {
+	if err := f.primary.Fetch(repoPath); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.Fetch(repoPath)
+}
+
+func (f fallbackBackend) GetIncomingFiles(repoPath string) ([]string, error) {
+	files, err := f.primary.GetIncomingFiles(repoPath)
+	if !f.fallback(err) {
+		return files, err
+	}
+	return cliBackend{}.GetIncomingFiles(repoPath)
+}
+
+func (f fallbackBackend) Pull(repoPath string) error {
+	if err := f.primary.Pull(repoPath); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.Pull(repoPath)
+}
+
+func (f fallbackBackend) PullRebase(repoPath string) error {
+	if err := f.primary.PullRebase(repoPath); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.PullRebase(repoPath)
+}
+
+func (f fallbackBackend) Push(repoPath string) error {
+	if err := f.primary.Push(repoPath); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.Push(repoPath)
+}
+
+func (f fallbackBackend) PushRemote(repoPath, remoteName string) error {
+	if err := f.primary.PushRemote(repoPath, remoteName); !f.fallback(err) {
+		return err
+	}
+	return cliBackend{}.PushRemote(repoPath, remoteName)
+}
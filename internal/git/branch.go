@@ -0,0 +1,98 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BranchInfo describes a local branch
+type BranchInfo struct {
+	Name     string
+	Upstream string // e.g. "origin/machine/laptop", empty if not tracking a remote branch
+	Current  bool   // The branch is checked out
+}
+
+// ListBranches returns the local branches, sorted by name
+func ListBranches(repoPath string) ([]BranchInfo, error) {
+	cmd := command("for-each-ref", "--format=%(refname:short)%00%(upstream:short)%00%(HEAD)", "refs/heads")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git for-each-ref failed: %s: %w", string(output), err)
+	}
+
+	var branches []BranchInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 3 {
+			continue
+		}
+		branches = append(branches, BranchInfo{
+			Name:     fields[0],
+			Upstream: fields[1],
+			Current:  fields[2] == "*",
+		})
+	}
+	return branches, nil
+}
+
+// ValidateBranchName returns an error if name can't be used as a branch name
+func ValidateBranchName(name string) error {
+	cmd := command("check-ref-format", "--branch", name)
+	if err := cmd.Run(); err != nil || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
+}
+
+// RemoteBranchExists checks if the named remote has branch, as of the last
+// fetch or push
+func RemoteBranchExists(repoPath, remote, branch string) bool {
+	cmd := command("rev-parse", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branch)
+	cmd.Dir = repoPath
+	return cmd.Run() == nil
+}
+
+// SwitchBranch checks out branch. A branch that only exists on origin is
+// created locally, tracking it. Uncommitted changes are carried over; git
+// refuses the switch if they would be overwritten.
+func SwitchBranch(repoPath, branch string) error {
+	cmd := command("switch", branch)
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git switch failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// CreateBranch creates branch at the current commit and checks it out,
+// keeping uncommitted changes. Its upstream is set on the first push.
+func CreateBranch(repoPath, branch string) error {
+	cmd := command("switch", "--no-track", "-c", branch)
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git switch -c failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// GetChangedBetween returns files under repoPath that differ between the
+// commits from and to. Paths are relative to repoPath.
+func GetChangedBetween(repoPath, from, to string) ([]string, error) {
+	cmd := command("diff", "--name-only", "--relative", from, to, "--")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %s: %w", string(output), err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
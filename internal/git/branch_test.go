@@ -0,0 +1,92 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestBranches(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tempDir := t.TempDir()
+	bare := filepath.Join(tempDir, "origin.git")
+	if output, err := exec.Command("git", "init", "--bare", bare).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare failed: %s", output)
+	}
+	repoPath := filepath.Join(tempDir, "repo")
+	if err := Clone(bare, repoPath); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	configureGitUser(t, repoPath)
+
+	if err := os.WriteFile(filepath.Join(repoPath, "zshrc"), []byte("shared"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(repoPath); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	main, err := OpenRepo(repoPath).Branch()
+	if err != nil {
+		t.Fatalf("Branch() error = %v", err)
+	}
+
+	if err := ValidateBranchName("machine/laptop"); err != nil {
+		t.Errorf("ValidateBranchName() error = %v", err)
+	}
+	for _, name := range []string{"bad name", "-x", "a..b", ""} {
+		if err := ValidateBranchName(name); err == nil {
+			t.Errorf("ValidateBranchName(%q) should return error", name)
+		}
+	}
+
+	// A machine branch gets its own commit and is pushed with its upstream set
+	if err := CreateBranch(repoPath, "machine/laptop"); err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	before, _ := GetCurrentCommit(repoPath)
+	if err := os.WriteFile(filepath.Join(repoPath, "zshrc"), []byte("laptop"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(repoPath); err != nil {
+		t.Fatalf("Sync() on branch error = %v", err)
+	}
+	after, _ := GetCurrentCommit(repoPath)
+	if !RemoteBranchExists(repoPath, "origin", "machine/laptop") {
+		t.Error("RemoteBranchExists() = false after pushing the branch")
+	}
+
+	branches, err := ListBranches(repoPath)
+	if err != nil {
+		t.Fatalf("ListBranches() error = %v", err)
+	}
+	if len(branches) != 2 {
+		t.Fatalf("ListBranches() = %+v, want 2 branches", branches)
+	}
+	for _, b := range branches {
+		if b.Name == "machine/laptop" && (!b.Current || b.Upstream != "origin/machine/laptop") {
+			t.Errorf("machine/laptop = %+v, want current and tracking origin/machine/laptop", b)
+		}
+	}
+
+	changed, err := GetChangedBetween(repoPath, before, after)
+	if err != nil {
+		t.Fatalf("GetChangedBetween() error = %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "zshrc" {
+		t.Errorf("GetChangedBetween() = %v, want [zshrc]", changed)
+	}
+
+	if err := SwitchBranch(repoPath, main); err != nil {
+		t.Fatalf("SwitchBranch() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(repoPath, "zshrc")); string(data) != "shared" {
+		t.Errorf("zshrc on %s = %q, want %q", main, data, "shared")
+	}
+	if err := SwitchBranch(repoPath, "missing"); err == nil {
+		t.Error("SwitchBranch() to a missing branch should return error")
+	}
+}
@@ -0,0 +1,400 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// cliBackend runs the git command-line tool
+type cliBackend struct{}
+
+func (c cliBackend) Name() string { return BackendCLI }
+
+// InitRepo initializes git repository in directory
+// A linked worktree already belongs to a repository and is left untouched
+func (c cliBackend) InitRepo(repoPath string) error {
+	if IsWorktree(repoPath) {
+		return nil
+	}
+
+	cmd := command("init")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git init failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// IsRepo checks if directory is a git repository
+func (c cliBackend) IsRepo(repoPath string) bool {
+	cmd := command("rev-parse", "--is-inside-work-tree")
+	cmd.Dir = repoPath
+	err := cmd.Run()
+	return err == nil
+}
+
+// AutoCommit stages all changes and commits with message
+// Changes are scoped to repoPath, which may be a subdirectory of the repository,
+// and further limited to pathspecs when given (default ".")
+// Returns nil if no changes to commit
+func (c cliBackend) AutoCommit(repoPath, message string, pathspecs ...string) error {
+	pathspecs = defaultPathspecs(pathspecs)
+
+	// Check if there are changes
+	hasChanges, err := c.HasChanges(repoPath, pathspecs...)
+	if err != nil {
+		return fmt.Errorf("checking for changes: %w", err)
+	}
+	if !hasChanges {
+		return nil // Nothing to commit
+	}
+
+	// Stage all changes
+	addCmd := command(append([]string{"add", "-A", "--"}, pathspecs...)...)
+	addCmd.Dir = repoPath
+	if output, err := addCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git add failed: %s: %w", string(output), err)
+	}
+
+	// Commit
+	commitCmd := command(append([]string{"commit", "-m", message, "--"}, pathspecs...)...)
+	commitCmd.Dir = repoPath
+	if output, err := commitCmd.CombinedOutput(); err != nil {
+		// Check if it's "nothing to commit" error
+		if strings.Contains(string(output), "nothing to commit") {
+			return nil
+		}
+		return fmt.Errorf("git commit failed: %s: %w", string(output), err)
+	}
+
+	return nil
+}
+
+// HasChanges checks if working tree has uncommitted changes under repoPath,
+// limited to pathspecs when given
+func (c cliBackend) HasChanges(repoPath string, pathspecs ...string) (bool, error) {
+	args := append([]string{"status", "--porcelain", "--"}, defaultPathspecs(pathspecs)...)
+	cmd := command(args...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("git status failed: %w", err)
+	}
+	return len(strings.TrimSpace(string(output))) > 0, nil
+}
+
+// SetRemote configures git remote
+func (c cliBackend) SetRemote(repoPath, remoteName, remoteURL string) error {
+	// Check if remote already exists
+	if namedRemoteURL(repoPath, remoteName) != "" {
+		// Update existing remote
+		cmd := command("remote", "set-url", remoteName, remoteURL)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git remote set-url failed: %s: %w", string(output), err)
+		}
+	} else {
+		// Add new remote
+		cmd := command("remote", "add", remoteName, remoteURL)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git remote add failed: %s: %w", string(output), err)
+		}
+	}
+	return nil
+}
+
+// GetRemoteURL returns configured remote URL, or empty if none
+func (c cliBackend) GetRemoteURL(repoPath string) (string, error) {
+	return namedRemoteURL(repoPath, "origin"), nil
+}
+
+// namedRemoteURL returns the URL of the named remote, or empty if it isn't configured
+func namedRemoteURL(repoPath, remoteName string) string {
+	cmd := command("remote", "get-url", remoteName)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// GetStatus returns git status information, using a single porcelain
+// status call for the branch and changed files under repoPath
+func (c cliBackend) GetStatus(repoPath string) (StatusInfo, error) {
+	status := StatusInfo{}
+
+	porcelain, err := GetPorcelainStatus(repoPath)
+	if err != nil {
+		return status, err
+	}
+	status.Branch = porcelain.Branch
+	status.Detached = porcelain.Detached
+	status.Changes = changeEntries(porcelain.Entries)
+	status.HasUncommitted = len(porcelain.Entries) > 0
+
+	for _, entry := range porcelain.Entries {
+		if entry.IsStaged() {
+			status.StagedCount++
+		}
+		if entry.IsUnstaged() {
+			status.UnstagedCount++
+		}
+		switch entry.Kind {
+		case EntryUntracked:
+			status.UntrackedCount++
+		case EntryUnmerged:
+			status.ConflictCount++
+		}
+	}
+
+	status.Rebasing, status.Merging = getOperationInProgress(repoPath)
+
+	// One 'git remote' call answers both whether origin exists and which
+	// remotes to compare the branch with
+	remotes := remoteNames(repoPath)
+	status.RemoteExists = slices.Contains(remotes, "origin")
+
+	if porcelain.HasUpstream {
+		status.AheadBy = porcelain.AheadBy
+		status.BehindBy = porcelain.BehindBy
+	} else if status.RemoteExists && status.Branch != "" {
+		// No upstream configured - compare against origin/<branch>
+		aheadBehindCmd := command("rev-list", "--left-right", "--count", fmt.Sprintf("origin/%s...HEAD", status.Branch))
+		aheadBehindCmd.Dir = repoPath
+		output, err := aheadBehindCmd.Output()
+		if err == nil {
+			parts := strings.Fields(string(output))
+			if len(parts) >= 2 {
+				status.BehindBy, _ = strconv.Atoi(parts[0])
+				status.AheadBy, _ = strconv.Atoi(parts[1])
+			}
+		}
+	}
+
+	if status.Branch != "" && !status.Detached {
+		status.Remotes = remoteStatuses(repoPath, remotes, status.Branch)
+	}
+
+	return status, nil
+}
+
+// remoteNames returns the names of the configured remotes
+func remoteNames(repoPath string) []string {
+	cmd := command("remote")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(output))
+}
+
+// remoteStatuses compares branch with its copy on each of the named remotes
+func remoteStatuses(repoPath string, names []string, branch string) []RemoteStatus {
+	var remotes []RemoteStatus
+	for _, name := range names {
+		remote := RemoteStatus{Name: name}
+		countCmd := command("rev-list", "--left-right", "--count", fmt.Sprintf("refs/remotes/%s/%s...HEAD", name, branch))
+		countCmd.Dir = repoPath
+		if counts, err := countCmd.Output(); err == nil {
+			if parts := strings.Fields(string(counts)); len(parts) >= 2 {
+				remote.Tracked = true
+				remote.BehindBy, _ = strconv.Atoi(parts[0])
+				remote.AheadBy, _ = strconv.Atoi(parts[1])
+			}
+		}
+		remotes = append(remotes, remote)
+	}
+	return remotes
+}
+
+// Clone clones a repository to the specified path
+func (c cliBackend) Clone(url, destPath string) error {
+	cmd := command("clone", url, destPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git clone failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// Pull pulls changes from remote
+func (c cliBackend) Pull(repoPath string) error {
+	cmd := command("pull")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git pull failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// PullRebase pulls changes from remote, rebasing local commits onto them
+func (c cliBackend) PullRebase(repoPath string) error {
+	cmd := command("pull", "--rebase")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git pull --rebase failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// Fetch fetches changes from remote without merging
+func (c cliBackend) Fetch(repoPath string) error {
+	cmd := command("fetch")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git fetch failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// Push pushes the current branch, setting its upstream on the first push
+func (c cliBackend) Push(repoPath string) error {
+	// Get current branch name
+	branchCmd := command("rev-parse", "--abbrev-ref", "HEAD")
+	branchCmd.Dir = repoPath
+	branchOutput, err := branchCmd.Output()
+	if err != nil {
+		return fmt.Errorf("getting current branch: %w", err)
+	}
+	branch := strings.TrimSpace(string(branchOutput))
+	if branch == "HEAD" {
+		// Common in worktrees checked out at a commit rather than a branch
+		return fmt.Errorf("cannot push from detached HEAD; check out a branch first")
+	}
+
+	// Check if upstream is configured for this branch
+	upstreamCmd := command("config", fmt.Sprintf("branch.%s.remote", branch))
+	upstreamCmd.Dir = repoPath
+	hasUpstream := upstreamCmd.Run() == nil
+
+	// Push to remote, set upstream if not configured
+	var pushCmd *exec.Cmd
+	if hasUpstream {
+		pushCmd = command("push")
+	} else {
+		pushCmd = command("push", "-u", "origin", branch)
+	}
+	pushCmd.Dir = repoPath
+	if output, err := pushCmd.CombinedOutput(); err != nil {
+		return pushError(output, err)
+	}
+
+	return nil
+}
+
+// PushRemote pushes the current branch to the named remote
+func (c cliBackend) PushRemote(repoPath, remoteName string) error {
+	branchCmd := command("rev-parse", "--abbrev-ref", "HEAD")
+	branchCmd.Dir = repoPath
+	branchOutput, err := branchCmd.Output()
+	if err != nil {
+		return fmt.Errorf("getting current branch: %w", err)
+	}
+	branch := strings.TrimSpace(string(branchOutput))
+	if branch == "HEAD" {
+		return fmt.Errorf("cannot push from detached HEAD; check out a branch first")
+	}
+
+	pushCmd := command("push", remoteName, branch)
+	pushCmd.Dir = repoPath
+	if output, err := pushCmd.CombinedOutput(); err != nil {
+		return pushError(output, err)
+	}
+	return nil
+}
+
+// pushError wraps a failed push, marking rejections because the remote has
+// commits that aren't here as ErrBehindRemote
+func pushError(output []byte, err error) error {
+	out := string(output)
+	if strings.Contains(out, "[rejected]") && (strings.Contains(out, "fetch first") || strings.Contains(out, "non-fast-forward")) {
+		return fmt.Errorf("%w: git push: %s", ErrBehindRemote, strings.TrimSpace(out))
+	}
+	return fmt.Errorf("git push failed: %s: %w", out, err)
+}
+
+// GetIncomingFiles returns files under repoPath that a pull from the upstream
+// branch would change. Paths are relative to repoPath. Call Fetch first.
+func (c cliBackend) GetIncomingFiles(repoPath string) ([]string, error) {
+	cmd := command("diff", "--name-only", "--relative", "HEAD...@{upstream}")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %s: %w", string(output), err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+
+	return files, nil
+}
+
+// GetCurrentCommit returns the current commit hash
+func (c cliBackend) GetCurrentCommit(repoPath string) (string, error) {
+	cmd := command("rev-parse", "HEAD")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetChangedFiles returns changed files under repoPath, limited to pathspecs
+// when given.
+// Parsed from NUL-separated porcelain output, so renames and paths with
+// spaces or non-ASCII characters are reported exactly.
+func (c cliBackend) GetChangedFiles(repoPath string, pathspecs ...string) ([]ChangeEntry, error) {
+	porcelain, err := GetPorcelainStatus(repoPath, pathspecs...)
+	if err != nil {
+		return nil, err
+	}
+	return changeEntries(porcelain.Entries), nil
+}
+
+// getOperationInProgress reports whether a rebase or merge is in progress,
+// based on the state files git keeps in the git directory
+func getOperationInProgress(repoPath string) (rebasing, merging bool) {
+	cmd := command("rev-parse",
+		"--git-path", "rebase-merge", "--git-path", "rebase-apply", "--git-path", "MERGE_HEAD")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return false, false
+	}
+
+	paths := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(paths) != 3 {
+		return false, false
+	}
+
+	rebasing = gitPathExists(repoPath, paths[0]) || gitPathExists(repoPath, paths[1])
+	merging = gitPathExists(repoPath, paths[2])
+	return rebasing, merging
+}
+
+// gitPathExists checks if a path from 'git rev-parse --git-path' exists.
+// Relative paths are resolved against repoPath.
+func gitPathExists(repoPath, path string) bool {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repoPath, path)
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,35 @@
+package git
+
+import "fmt"
+
+// CommitCheck inspects the changes AutoCommit is about to commit. An error
+// stops the commit.
+type CommitCheck func(repoPath string, changes []ChangeEntry) error
+
+// commitCheck is the check set with SetCommitCheck, nil for none
+var commitCheck CommitCheck
+
+// SetCommitCheck makes AutoCommit and AutoCommitVerbatim run check before
+// every commit, e.g. to lint the changed files. nil removes the check.
+// Returns a function restoring the previous check.
+func SetCommitCheck(check CommitCheck) (restore func()) {
+	previous := commitCheck
+	commitCheck = check
+	return func() { commitCheck = previous }
+}
+
+// runCommitCheck runs the commit check, if any, on the changes under
+// repoPath limited to pathspecs
+func runCommitCheck(repoPath string, pathspecs []string) error {
+	if commitCheck == nil {
+		return nil
+	}
+	changes, err := CurrentBackend().GetChangedFiles(repoPath, pathspecs...)
+	if err != nil {
+		return fmt.Errorf("listing changes to commit: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return commitCheck(repoPath, changes)
+}
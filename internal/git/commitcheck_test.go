@@ -0,0 +1,54 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitCheck(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	repoPath := t.TempDir()
+	if err := InitRepo(repoPath); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, repoPath)
+
+	errBroken := errors.New("broken")
+	var checked []string
+	defer SetCommitCheck(func(_ string, changes []ChangeEntry) error {
+		checked = checked[:0]
+		for _, c := range changes {
+			checked = append(checked, c.Path)
+			if c.Path == "broken" {
+				return errBroken
+			}
+		}
+		return nil
+	})()
+
+	for _, name := range []string{"zshrc", "broken"} {
+		if err := os.WriteFile(filepath.Join(repoPath, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the changes being committed are checked
+	if err := AutoCommit(repoPath, "Add zshrc", "zshrc"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+	if len(checked) != 1 || checked[0] != "zshrc" {
+		t.Errorf("checked %v, want [zshrc]", checked)
+	}
+
+	if err := AutoCommitVerbatim(repoPath, "Add broken"); !errors.Is(err, errBroken) {
+		t.Fatalf("AutoCommitVerbatim() error = %v, want the check's error", err)
+	}
+	if got := lastCommitMessage(t, repoPath); got != "Add zshrc" {
+		t.Errorf("last commit = %q, the failed check should have stopped the commit", got)
+	}
+}
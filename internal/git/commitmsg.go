@@ -0,0 +1,171 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Commit styles, as used in the commit_style config setting
+const (
+	CommitStylePlain        = "plain"        // dotcor's own messages, e.g. "Add zshrc" (default)
+	CommitStyleConventional = "conventional" // e.g. "chore(dotfiles): add zshrc on macbook"
+)
+
+// conventionalTemplate is the message template for conventional commits
+const conventionalTemplate = `chore(dotfiles): {{ .Action }} {{ .Files }}{{ if .Hostname }} on {{ .Hostname }}{{ end }}`
+
+// commitData is the set of values available to commit message templates
+//
+//	{{ .Message }}   dotcor's own message, e.g. "Add zshrc"
+//	{{ .Action }}    add, remove, move or update
+//	{{ .Files }}     the committed files, e.g. "zshrc and gitconfig" or "5 files"
+//	{{ .Count }}     how many files are committed
+//	{{ .Hostname }}  this machine's short hostname
+//	{{ .Timestamp }} the time of the commit, e.g. "2026-01-04 10:30"
+type commitData struct {
+	Message   string
+	Action    string
+	Files     string
+	Count     int
+	Hostname  string
+	Timestamp string
+}
+
+// commitFormatter rewrites auto-commit messages with a template
+type commitFormatter struct {
+	tmpl *template.Template // nil keeps messages unchanged
+}
+
+// commitFormat is the format selected with SetCommitFormat, from
+// commitStyle and commitTemplate
+var (
+	commitFormat                commitFormatter
+	commitStyle, commitTemplate string
+)
+
+// ValidateCommitFormat returns an error if style is not a known commit style
+// or tmpl is not a valid template
+func ValidateCommitFormat(style, tmpl string) error {
+	_, err := parseCommitFormat(style, tmpl)
+	return err
+}
+
+// SetCommitFormat selects how AutoCommit writes messages. A non-empty tmpl
+// takes precedence over style.
+func SetCommitFormat(style, tmpl string) error {
+	f, err := parseCommitFormat(style, tmpl)
+	if err != nil {
+		return err
+	}
+	commitFormat, commitStyle, commitTemplate = f, style, tmpl
+	return nil
+}
+
+// CommitFormat returns the style and template selected with SetCommitFormat
+func CommitFormat() (style, tmpl string) {
+	return commitStyle, commitTemplate
+}
+
+// parseCommitFormat returns the formatter for style and tmpl
+func parseCommitFormat(style, tmpl string) (commitFormatter, error) {
+	switch style {
+	case "", CommitStylePlain:
+	case CommitStyleConventional:
+		if tmpl == "" {
+			tmpl = conventionalTemplate
+		}
+	default:
+		return commitFormatter{}, fmt.Errorf("invalid commit style %q (expected plain or conventional)", style)
+	}
+	if tmpl == "" {
+		return commitFormatter{}, nil
+	}
+
+	t, err := template.New("commit").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return commitFormatter{}, fmt.Errorf("invalid commit template: %w", err)
+	}
+	// Catch unknown fields now rather than on the next commit
+	if err := t.Execute(&bytes.Buffer{}, commitData{}); err != nil {
+		return commitFormatter{}, fmt.Errorf("invalid commit template: %w", err)
+	}
+	return commitFormatter{tmpl: t}, nil
+}
+
+// enabled reports whether messages are rewritten
+func (f commitFormatter) enabled() bool {
+	return f.tmpl != nil
+}
+
+// render returns the message for a commit of changes, or message itself
+// when the template renders nothing
+func (f commitFormatter) render(message string, changes []ChangeEntry) string {
+	if f.tmpl == nil {
+		return message
+	}
+
+	hostname, _ := os.Hostname()
+	if short, _, found := strings.Cut(hostname, "."); found {
+		hostname = short
+	}
+	names := changedNames(changes)
+
+	data := commitData{
+		Message:   message,
+		Action:    commitAction(message),
+		Files:     summarizeNames(names),
+		Count:     len(names),
+		Hostname:  hostname,
+		Timestamp: time.Now().Format("2006-01-02 15:04"),
+	}
+	var b bytes.Buffer
+	if err := f.tmpl.Execute(&b, data); err != nil || strings.TrimSpace(b.String()) == "" {
+		return message
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// commitAction derives the action from the first word of dotcor's message
+func commitAction(message string) string {
+	verb, _, _ := strings.Cut(message, " ")
+	switch verb = strings.ToLower(verb); verb {
+	case "add", "remove", "move":
+		return verb
+	}
+	return "update"
+}
+
+// changedNames returns the sorted, unique file names of changes
+func changedNames(changes []ChangeEntry) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range changes {
+		name := path.Base(strings.TrimSuffix(c.Path, "/"))
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// summarizeNames lists up to three names, e.g. "zshrc, vimrc and gitconfig",
+// or gives their number for more
+func summarizeNames(names []string) string {
+	switch len(names) {
+	case 0:
+		return "files"
+	case 1:
+		return names[0]
+	case 2, 3:
+		return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+	return fmt.Sprintf("%d files", len(names))
+}
@@ -0,0 +1,115 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitFormatRender(t *testing.T) {
+	hostname, _ := os.Hostname()
+	hostname, _, _ = strings.Cut(hostname, ".")
+
+	changes := func(paths ...string) []ChangeEntry {
+		var entries []ChangeEntry
+		for _, p := range paths {
+			entries = append(entries, ChangeEntry{Status: "M", Path: p})
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name    string
+		style   string
+		tmpl    string
+		message string
+		changes []ChangeEntry
+		want    string
+	}{
+		{"plain", "", "", "Add zshrc", changes("shell/zshrc"), "Add zshrc"},
+		{"conventional add", CommitStyleConventional, "", "Add zshrc", changes("shell/zshrc"),
+			"chore(dotfiles): add zshrc on " + hostname},
+		{"conventional sync", CommitStyleConventional, "", "Sync dotfiles - 2026-01-04 10:30",
+			changes("shell/zshrc", "git/gitconfig"), "chore(dotfiles): update gitconfig and zshrc on " + hostname},
+		{"many files", "", "{{ .Action }}: {{ .Files }} ({{ .Count }})", "Remove 4 file(s) from management",
+			changes("a/one", "a/two", "b/three", "b/four"), "remove: 4 files (4)"},
+		{"template wins", CommitStyleConventional, "dotfiles: {{ .Message }}", "Add vimrc",
+			changes("vim/vimrc"), "dotfiles: Add vimrc"},
+		{"empty result", "", "{{ if .Count }}{{ end }}", "Add vimrc", changes("vim/vimrc"), "Add vimrc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseCommitFormat(tt.style, tt.tmpl)
+			if err != nil {
+				t.Fatalf("parseCommitFormat() error = %v", err)
+			}
+			if got := f.render(tt.message, tt.changes); got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCommitFormat(t *testing.T) {
+	if err := ValidateCommitFormat("angular", ""); err == nil {
+		t.Error("ValidateCommitFormat() should reject an unknown style")
+	}
+	if err := ValidateCommitFormat("", "{{ .Branch }}"); err == nil {
+		t.Error("ValidateCommitFormat() should reject an unknown field")
+	}
+	if err := ValidateCommitFormat("", "{{ .Files }} on {{ .Hostname }} at {{ .Timestamp }}"); err != nil {
+		t.Errorf("ValidateCommitFormat() error = %v", err)
+	}
+}
+
+func TestAutoCommitFormat(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	repoPath := t.TempDir()
+	if err := InitRepo(repoPath); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, repoPath)
+
+	if err := SetCommitFormat("", "dotfiles: {{ .Action }} {{ .Files }}"); err != nil {
+		t.Fatalf("SetCommitFormat() error = %v", err)
+	}
+	defer SetCommitFormat("", "")
+
+	for _, name := range []string{"zshrc", "bashrc"} {
+		if err := os.WriteFile(filepath.Join(repoPath, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only files matching the pathspecs are named
+	if err := AutoCommit(repoPath, "Add zshrc", "zshrc"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+	if got := lastCommitMessage(t, repoPath); got != "dotfiles: add zshrc" {
+		t.Errorf("message = %q, want %q", got, "dotfiles: add zshrc")
+	}
+
+	if err := AutoCommitVerbatim(repoPath, "my own message"); err != nil {
+		t.Fatalf("AutoCommitVerbatim() error = %v", err)
+	}
+	if got := lastCommitMessage(t, repoPath); got != "my own message" {
+		t.Errorf("message = %q, want %q", got, "my own message")
+	}
+}
+
+// lastCommitMessage returns the subject of the last commit in repoPath
+func lastCommitMessage(t *testing.T, repoPath string) string {
+	t.Helper()
+	cmd := exec.Command("git", "log", "-1", "--format=%s")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,43 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// runCtx is the context git commands and go-git network operations run
+// under, set with SetContext
+var runCtx = context.Background()
+
+// SetContext makes git commands and go-git network operations run under
+// ctx, so canceling it (on Ctrl+C, say) stops a clone or push instead of
+// leaving it running. Returns a function restoring the previous context.
+func SetContext(ctx context.Context) (restore func()) {
+	previous := runCtx
+	runCtx = ctx
+	return func() { runCtx = previous }
+}
+
+// cancelWait is how long a canceled git command gets to exit after being
+// interrupted before it's killed
+const cancelWait = 5 * time.Second
+
+// command returns a git command that is interrupted when the context set
+// with SetContext is canceled, so it can clean up its lock files, and killed
+// if it hasn't exited cancelWait later. It runs with SkipScanEnv set, so
+// dotcor's own commits get past its pre-commit hook.
+func command(args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(runCtx, "git", args...)
+	cmd.Cancel = func() error {
+		// Windows can't interrupt a process
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = cancelWait
+	cmd.Env = append(os.Environ(), SkipScanEnv+"=1")
+	return cmd
+}
@@ -1,10 +1,9 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -16,6 +15,23 @@ type StatusInfo struct {
 	BehindBy       int
 	Branch         string
 	RemoteExists   bool
+	Changes        []ChangeEntry  // Changed files under repoPath
+	StagedCount    int            // Files with changes in the index
+	UnstagedCount  int            // Tracked files with changes in the working tree
+	UntrackedCount int            // Files not tracked by git
+	ConflictCount  int            // Files with unresolved merge conflicts
+	Detached       bool           // HEAD is not on a branch
+	Rebasing       bool           // A rebase is in progress
+	Merging        bool           // A merge is in progress
+	Remotes        []RemoteStatus // Every remote, with how far the branch is from it
+}
+
+// RemoteStatus describes the current branch relative to one remote
+type RemoteStatus struct {
+	Name     string
+	Tracked  bool // The remote has a copy of the branch (as of the last fetch or push)
+	AheadBy  int
+	BehindBy int
 }
 
 // CommitInfo represents a single Git commit
@@ -24,6 +40,14 @@ type CommitInfo struct {
 	Author  string
 	Date    time.Time
 	Message string
+	Path    string // File path at this commit, relative to the repository root (set when following renames)
+}
+
+// HistoryOptions controls which commits GetFileHistoryWithOptions returns
+type HistoryOptions struct {
+	Limit  int  // Maximum number of commits (default 10)
+	Skip   int  // Number of newest commits to skip, for pagination
+	Follow bool // Follow the file across renames (single file only)
 }
 
 // IsGitInstalled checks if git command is available
@@ -32,195 +56,40 @@ func IsGitInstalled() bool {
 	return err == nil
 }
 
-// InitRepo initializes git repository in directory
-func InitRepo(repoPath string) error {
-	cmd := exec.Command("git", "init")
-	cmd.Dir = repoPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git init failed: %s: %w", string(output), err)
-	}
-	return nil
-}
-
-// IsRepo checks if directory is a git repository
-func IsRepo(repoPath string) bool {
-	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
-	cmd.Dir = repoPath
-	err := cmd.Run()
-	return err == nil
-}
-
-// AutoCommit stages all changes and commits with message
-// Returns nil if no changes to commit
-func AutoCommit(repoPath, message string) error {
-	// Check if there are changes
-	hasChanges, err := HasChanges(repoPath)
-	if err != nil {
-		return fmt.Errorf("checking for changes: %w", err)
-	}
-	if !hasChanges {
-		return nil // Nothing to commit
-	}
-
-	// Stage all changes
-	addCmd := exec.Command("git", "add", "-A")
-	addCmd.Dir = repoPath
-	if output, err := addCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git add failed: %s: %w", string(output), err)
-	}
-
-	// Commit
-	commitCmd := exec.Command("git", "commit", "-m", message)
-	commitCmd.Dir = repoPath
-	if output, err := commitCmd.CombinedOutput(); err != nil {
-		// Check if it's "nothing to commit" error
-		if strings.Contains(string(output), "nothing to commit") {
-			return nil
-		}
-		return fmt.Errorf("git commit failed: %s: %w", string(output), err)
-	}
-
-	return nil
+// GetFileHistory returns git log for specific file
+func GetFileHistory(repoPath, filePath string, limit int) ([]CommitInfo, error) {
+	return GetFileHistoryWithOptions(repoPath, filePath, HistoryOptions{Limit: limit})
 }
 
-// Sync commits all changes and pushes to remote (if configured)
-func Sync(repoPath string) error {
-	// Generate commit message with timestamp
-	message := fmt.Sprintf("Sync dotfiles - %s", time.Now().Format("2006-01-02 15:04"))
-
-	// Commit changes
-	if err := AutoCommit(repoPath, message); err != nil {
-		return err
-	}
-
-	// Check if remote exists
-	remoteURL, err := GetRemoteURL(repoPath)
-	if err != nil || remoteURL == "" {
-		return nil // No remote configured, skip push
-	}
-
-	// Get current branch name
-	branchCmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	branchCmd.Dir = repoPath
-	branchOutput, err := branchCmd.Output()
-	if err != nil {
-		return fmt.Errorf("getting current branch: %w", err)
-	}
-	branch := strings.TrimSpace(string(branchOutput))
-
-	// Check if upstream is configured for this branch
-	upstreamCmd := exec.Command("git", "config", fmt.Sprintf("branch.%s.remote", branch))
-	upstreamCmd.Dir = repoPath
-	hasUpstream := upstreamCmd.Run() == nil
-
-	// Push to remote, set upstream if not configured
-	var pushCmd *exec.Cmd
-	if hasUpstream {
-		pushCmd = exec.Command("git", "push")
-	} else {
-		pushCmd = exec.Command("git", "push", "-u", "origin", branch)
-	}
-	pushCmd.Dir = repoPath
-	if output, err := pushCmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git push failed: %s: %w", string(output), err)
+// GetFileHistoryWithOptions returns git log for a file (or everything under
+// repoPath if filePath is empty), with pagination and optional rename following
+func GetFileHistoryWithOptions(repoPath, filePath string, opts HistoryOptions) ([]CommitInfo, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
 	}
 
-	return nil
-}
-
-// HasChanges checks if working tree has uncommitted changes
-func HasChanges(repoPath string) (bool, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return false, fmt.Errorf("git status failed: %w", err)
-	}
-	return len(strings.TrimSpace(string(output))) > 0, nil
-}
+	// Records start with \x1e, fields are separated by \x1f: hash, author, date, message
+	// core.quotePath=false keeps non-ASCII paths from --name-only unescaped
+	args := []string{"-c", "core.quotePath=false", "log", "--format=%x1e%H%x1f%an%x1f%aI%x1f%s"}
 
-// SetRemote configures git remote
-func SetRemote(repoPath, remoteName, remoteURL string) error {
-	// Check if remote already exists
-	existingURL, _ := GetRemoteURL(repoPath)
-	if existingURL != "" {
-		// Update existing remote
-		cmd := exec.Command("git", "remote", "set-url", remoteName, remoteURL)
-		cmd.Dir = repoPath
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("git remote set-url failed: %s: %w", string(output), err)
-		}
+	follow := opts.Follow && filePath != ""
+	if follow {
+		// --name-only lists the file's path at each commit. git applies
+		// --skip before following renames, so skip commits ourselves.
+		args = append(args, fmt.Sprintf("-n%d", opts.Skip+opts.Limit), "--follow", "--name-only")
 	} else {
-		// Add new remote
-		cmd := exec.Command("git", "remote", "add", remoteName, remoteURL)
-		cmd.Dir = repoPath
-		if output, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("git remote add failed: %s: %w", string(output), err)
-		}
-	}
-	return nil
-}
-
-// GetRemoteURL returns configured remote URL, or empty if none
-func GetRemoteURL(repoPath string) (string, error) {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return "", nil // No remote configured
-	}
-	return strings.TrimSpace(string(output)), nil
-}
-
-// GetStatus returns git status information
-func GetStatus(repoPath string) (StatusInfo, error) {
-	status := StatusInfo{}
-
-	// Get current branch
-	branchCmd := exec.Command("git", "branch", "--show-current")
-	branchCmd.Dir = repoPath
-	branchOutput, err := branchCmd.Output()
-	if err == nil {
-		status.Branch = strings.TrimSpace(string(branchOutput))
-	}
-
-	// Check for uncommitted changes
-	hasChanges, err := HasChanges(repoPath)
-	if err == nil {
-		status.HasUncommitted = hasChanges
-	}
-
-	// Check if remote exists
-	remoteURL, _ := GetRemoteURL(repoPath)
-	status.RemoteExists = remoteURL != ""
-
-	// Get ahead/behind counts if remote exists
-	if status.RemoteExists && status.Branch != "" {
-		aheadBehindCmd := exec.Command("git", "rev-list", "--left-right", "--count", fmt.Sprintf("origin/%s...HEAD", status.Branch))
-		aheadBehindCmd.Dir = repoPath
-		output, err := aheadBehindCmd.Output()
-		if err == nil {
-			parts := strings.Fields(string(output))
-			if len(parts) >= 2 {
-				status.BehindBy, _ = strconv.Atoi(parts[0])
-				status.AheadBy, _ = strconv.Atoi(parts[1])
-			}
+		args = append(args, fmt.Sprintf("-n%d", opts.Limit))
+		if opts.Skip > 0 {
+			args = append(args, fmt.Sprintf("--skip=%d", opts.Skip))
 		}
 	}
 
-	return status, nil
-}
-
-// GetFileHistory returns git log for specific file
-func GetFileHistory(repoPath, filePath string, limit int) ([]CommitInfo, error) {
-	if limit <= 0 {
-		limit = 10
+	if filePath == "" {
+		filePath = "."
 	}
+	args = append(args, "--", filePath)
 
-	// Use format: hash|author|date|message
-	format := "%H|%an|%aI|%s"
-	cmd := exec.Command("git", "log", fmt.Sprintf("-n%d", limit), fmt.Sprintf("--format=%s", format), "--", filePath)
+	cmd := command(args...)
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -228,25 +97,42 @@ func GetFileHistory(repoPath, filePath string, limit int) ([]CommitInfo, error)
 	}
 
 	var commits []CommitInfo
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-
-	for _, line := range lines {
-		if line == "" {
+	for _, record := range strings.Split(string(output), "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		if lines[0] == "" {
 			continue
 		}
 
-		parts := strings.SplitN(line, "|", 4)
+		parts := strings.SplitN(lines[0], "\x1f", 4)
 		if len(parts) < 4 {
 			continue
 		}
 
 		date, _ := time.Parse(time.RFC3339, parts[2])
-		commits = append(commits, CommitInfo{
+		commit := CommitInfo{
 			Hash:    parts[0],
 			Author:  parts[1],
 			Date:    date,
 			Message: parts[3],
-		})
+		}
+
+		if follow {
+			for _, line := range lines[1:] {
+				if line = strings.TrimSpace(line); line != "" {
+					commit.Path = line
+					break
+				}
+			}
+		}
+
+		commits = append(commits, commit)
+	}
+
+	if follow && opts.Skip > 0 {
+		if opts.Skip >= len(commits) {
+			return nil, nil
+		}
+		commits = commits[opts.Skip:]
 	}
 
 	return commits, nil
@@ -258,7 +144,7 @@ func RestoreFile(repoPath, filePath, ref string) error {
 		ref = "HEAD"
 	}
 
-	cmd := exec.Command("git", "checkout", ref, "--", filePath)
+	cmd := command("checkout", ref, "--", filePath)
 	cmd.Dir = repoPath
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -267,111 +153,96 @@ func RestoreFile(repoPath, filePath, ref string) error {
 	return nil
 }
 
-// GetDiff returns unified diff for uncommitted changes
-func GetDiff(repoPath string) (string, error) {
-	cmd := exec.Command("git", "diff", "HEAD")
+// RestoreTree makes every tracked file under repoPath, limited to pathspecs
+// when given, match ref, deleting files ref doesn't have, and stages the
+// result. Untracked files are left alone.
+func RestoreTree(repoPath, ref string, pathspecs ...string) error {
+	args := append([]string{"restore", "--source=" + ref, "--staged", "--worktree", "--"}, defaultPathspecs(pathspecs)...)
+	cmd := command(args...)
 	cmd.Dir = repoPath
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		// Check if it's just "no diff" situation
-		if len(output) == 0 {
-			return "", nil
-		}
-		return "", fmt.Errorf("git diff failed: %w", err)
+		return fmt.Errorf("git restore failed: %s: %w", strings.TrimSpace(string(output)), err)
 	}
-	return string(output), nil
+	return nil
+}
+
+// CommitExists reports whether ref names a commit in the repository
+func CommitExists(repoPath, ref string) bool {
+	cmd := command("cat-file", "-e", ref+"^{commit}")
+	cmd.Dir = repoPath
+	return cmd.Run() == nil
+}
+
+// DiffOptions controls what GetDiffWithOptions compares and returns
+type DiffOptions struct {
+	Staged bool // Compare the index with HEAD instead of the working tree
+	Stat   bool // Return a diffstat instead of a unified diff
+}
+
+// GetDiff returns unified diff for uncommitted changes under repoPath
+func GetDiff(repoPath string) (string, error) {
+	return GetDiffWithOptions(repoPath, "", DiffOptions{})
 }
 
 // GetFileDiff returns diff for specific file
 func GetFileDiff(repoPath, filePath string) (string, error) {
-	cmd := exec.Command("git", "diff", "HEAD", "--", filePath)
-	cmd.Dir = repoPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		if len(output) == 0 {
-			return "", nil
-		}
-		return "", fmt.Errorf("git diff failed: %w", err)
-	}
-	return string(output), nil
+	return GetDiffWithOptions(repoPath, filePath, DiffOptions{})
 }
 
-// GetDiffStat returns diffstat (summary of changes)
+// GetDiffStat returns diffstat (summary of changes) under repoPath
 func GetDiffStat(repoPath string) (string, error) {
-	cmd := exec.Command("git", "diff", "HEAD", "--stat")
-	cmd.Dir = repoPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		if len(output) == 0 {
-			return "", nil
-		}
-		return "", fmt.Errorf("git diff --stat failed: %w", err)
-	}
-	return string(output), nil
+	return GetDiffWithOptions(repoPath, "", DiffOptions{Stat: true})
 }
 
-// Clone clones a repository to the specified path
-func Clone(url, destPath string) error {
-	cmd := exec.Command("git", "clone", url, destPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git clone failed: %s: %w", string(output), err)
+// GetDiffWithOptions returns the uncommitted changes for filePath (or
+// everything under repoPath if filePath is empty) against HEAD
+func GetDiffWithOptions(repoPath, filePath string, opts DiffOptions) (string, error) {
+	args := []string{"diff"}
+	if opts.Staged {
+		args = append(args, "--cached")
 	}
-	return nil
-}
-
-// Pull pulls changes from remote
-func Pull(repoPath string) error {
-	cmd := exec.Command("git", "pull")
-	cmd.Dir = repoPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git pull failed: %s: %w", string(output), err)
+	args = append(args, "HEAD")
+	if opts.Stat {
+		args = append(args, "--stat")
 	}
-	return nil
-}
+	if filePath == "" {
+		filePath = "."
+	}
+	args = append(args, "--", filePath)
 
-// GetCurrentCommit returns the current commit hash
-func GetCurrentCommit(repoPath string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd := command(args...)
 	cmd.Dir = repoPath
-	output, err := cmd.Output()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", fmt.Errorf("git rev-parse failed: %w", err)
+		// Check if it's just "no diff" situation
+		if len(output) == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("git diff failed: %w", err)
 	}
-	return strings.TrimSpace(string(output)), nil
+	return string(output), nil
 }
 
-// GetChangedFiles returns list of changed files
-func GetChangedFiles(repoPath string) ([]string, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = repoPath
+// DiffFiles returns a unified diff from the file at a to the file at b,
+// which don't need to be in a repository, or "" if they are the same
+func DiffFiles(a, b string) (string, error) {
+	cmd := command("diff", "--no-index", "--no-color", "--", a, b)
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("git status failed: %w", err)
-	}
-
-	var files []string
-	lines := strings.Split(string(output), "\n")
-	// Match pattern: XY filename (where XY is status codes)
-	re := regexp.MustCompile(`^.{2}\s+(.+)$`)
-
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		matches := re.FindStringSubmatch(line)
-		if len(matches) >= 2 {
-			files = append(files, matches[1])
+		// Exit status 1 means the files differ
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return string(output), nil
 		}
+		return "", fmt.Errorf("git diff failed: %w", err)
 	}
-
-	return files, nil
+	return string(output), nil
 }
 
 // StageFile stages a specific file
 func StageFile(repoPath, filePath string) error {
-	cmd := exec.Command("git", "add", filePath)
+	cmd := command("add", filePath)
 	cmd.Dir = repoPath
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -382,7 +253,7 @@ func StageFile(repoPath, filePath string) error {
 
 // UnstageFile unstages a specific file
 func UnstageFile(repoPath, filePath string) error {
-	cmd := exec.Command("git", "reset", "HEAD", "--", filePath)
+	cmd := command("reset", "HEAD", "--", filePath)
 	cmd.Dir = repoPath
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -390,3 +261,16 @@ func UnstageFile(repoPath, filePath string) error {
 	}
 	return nil
 }
+
+// ExcludePathspec returns a pathspec that excludes path from git operations
+func ExcludePathspec(path string) string {
+	return ":(exclude)" + path
+}
+
+// defaultPathspecs returns pathspecs, or "." (everything under the working directory) if empty
+func defaultPathspecs(pathspecs []string) []string {
+	if len(pathspecs) == 0 {
+		return []string{"."}
+	}
+	return pathspecs
+}
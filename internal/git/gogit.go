@@ -0,0 +1,773 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Commit identity used by the go-git backend when no user.name or
+// user.email is configured
+const (
+	defaultAuthorName  = "dotcor"
+	defaultAuthorEmail = "dotcor@localhost"
+)
+
+// goGitBackend implements Backend with go-git, without the git command.
+// Operations it can't match (credential helpers, merges, glob pathspecs)
+// return ErrUnsupported so fallbackBackend can retry with the CLI.
+type goGitBackend struct{}
+
+func (g goGitBackend) Name() string { return BackendGoGit }
+
+// openRepo opens the repository containing repoPath
+func openRepo(repoPath string) (*gogit.Repository, error) {
+	return gogit.PlainOpenWithOptions(repoPath, &gogit.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+}
+
+// InitRepo initializes git repository in directory
+// A linked worktree or existing repository is left untouched
+func (g goGitBackend) InitRepo(repoPath string) error {
+	if _, ok := ReadGitLink(repoPath); ok {
+		return nil
+	}
+
+	_, err := gogit.PlainInit(repoPath, false)
+	if err != nil && !errors.Is(err, gogit.ErrRepositoryAlreadyExists) {
+		return fmt.Errorf("git init failed: %w", err)
+	}
+	return nil
+}
+
+// IsRepo checks if directory is a git repository
+func (g goGitBackend) IsRepo(repoPath string) bool {
+	_, err := openRepo(repoPath)
+	return err == nil
+}
+
+// HasChanges checks if working tree has uncommitted changes under repoPath,
+// limited to pathspecs when given
+func (g goGitBackend) HasChanges(repoPath string, pathspecs ...string) (bool, error) {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("git status failed: %w", err)
+	}
+
+	entries, err := statusEntries(repo, repoPath, pathspecs)
+	if err != nil {
+		return false, err
+	}
+	return len(entries) > 0, nil
+}
+
+// AutoCommit stages all changes and commits with message
+// Changes are scoped to repoPath, which may be a subdirectory of the repository,
+// and further limited to pathspecs when given (default ".")
+// Returns nil if no changes to commit
+func (g goGitBackend) AutoCommit(repoPath, message string, pathspecs ...string) error {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return fmt.Errorf("opening repository: %w", err)
+	}
+
+	matcher, err := newPathspecMatcher(repo, repoPath, pathspecs)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("opening worktree: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return fmt.Errorf("git status failed: %w", err)
+	}
+
+	var toStage []string
+	for file, fileStatus := range status {
+		if fileStatus.Staging == gogit.UpdatedButUnmerged || fileStatus.Worktree == gogit.UpdatedButUnmerged {
+			return fmt.Errorf("%w: committing with unresolved conflicts", ErrUnsupported)
+		}
+		if matcher.match(file) {
+			toStage = append(toStage, file)
+			continue
+		}
+		// go-git commits the whole index, so staged files outside the
+		// pathspecs would be swept into the commit
+		if fileStatus.Staging != gogit.Unmodified && fileStatus.Staging != gogit.Untracked {
+			return fmt.Errorf("%w: committing with other staged changes", ErrUnsupported)
+		}
+	}
+	if len(toStage) == 0 {
+		return nil // Nothing to commit
+	}
+
+	sort.Strings(toStage)
+	for _, file := range toStage {
+		if status[file].Worktree == gogit.Deleted {
+			_, err = worktree.Remove(file)
+		} else {
+			_, err = worktree.Add(file)
+		}
+		if err != nil {
+			return fmt.Errorf("git add failed: %s: %w", file, err)
+		}
+	}
+
+	if _, err := worktree.Commit(message, &gogit.CommitOptions{Author: commitSignature(repo)}); err != nil {
+		if errors.Is(err, gogit.ErrEmptyCommit) {
+			return nil
+		}
+		return fmt.Errorf("git commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// commitSignature returns the configured user identity, or dotcor's
+// default identity when none is set
+func commitSignature(repo *gogit.Repository) *object.Signature {
+	sig := &object.Signature{Name: defaultAuthorName, Email: defaultAuthorEmail, When: time.Now()}
+
+	cfg, err := repo.ConfigScoped(gitconfig.GlobalScope)
+	if err != nil {
+		return sig
+	}
+	if cfg.User.Name != "" {
+		sig.Name = cfg.User.Name
+	}
+	if cfg.User.Email != "" {
+		sig.Email = cfg.User.Email
+	}
+	return sig
+}
+
+// GetStatus returns git status information: the branch, how far it is from
+// its remote, and the changed files under repoPath
+func (g goGitBackend) GetStatus(repoPath string) (StatusInfo, error) {
+	status := StatusInfo{}
+
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return status, fmt.Errorf("git status failed: %w", err)
+	}
+
+	entries, err := statusEntries(repo, repoPath, nil)
+	if err != nil {
+		return status, err
+	}
+	status.Changes = changeEntries(entries)
+	status.HasUncommitted = len(entries) > 0
+
+	for _, entry := range entries {
+		if entry.IsStaged() {
+			status.StagedCount++
+		}
+		if entry.IsUnstaged() {
+			status.UnstagedCount++
+		}
+		switch entry.Kind {
+		case EntryUntracked:
+			status.UntrackedCount++
+		case EntryUnmerged:
+			status.ConflictCount++
+		}
+	}
+
+	status.Rebasing, status.Merging = goGitOperationInProgress(repo)
+
+	remoteURL, _ := g.GetRemoteURL(repoPath)
+	status.RemoteExists = remoteURL != ""
+
+	head, err := repo.Head()
+	if err != nil {
+		// Unborn branch: no commits yet
+		if ref, refErr := repo.Storer.Reference(plumbing.HEAD); refErr == nil && ref.Type() == plumbing.SymbolicReference {
+			status.Branch = ref.Target().Short()
+		}
+		return status, nil
+	}
+	if !head.Name().IsBranch() {
+		status.Detached = true
+		return status, nil
+	}
+	status.Branch = head.Name().Short()
+	status.Remotes = goGitRemoteStatuses(repo, head.Hash(), status.Branch)
+
+	upstream := upstreamRef(repo, status.Branch, status.RemoteExists)
+	if upstream == "" {
+		return status, nil
+	}
+	remoteRef, err := repo.Reference(upstream, true)
+	if err != nil {
+		return status, nil
+	}
+	status.AheadBy, status.BehindBy, _ = aheadBehind(repo, head.Hash(), remoteRef.Hash())
+
+	return status, nil
+}
+
+// goGitRemoteStatuses compares branch, at head, with its copy on every remote
+func goGitRemoteStatuses(repo *gogit.Repository, head plumbing.Hash, branch string) []RemoteStatus {
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return nil
+	}
+
+	var statuses []RemoteStatus
+	for _, remote := range remotes {
+		status := RemoteStatus{Name: remote.Config().Name}
+		ref, err := repo.Reference(plumbing.NewRemoteReferenceName(status.Name, branch), true)
+		if err == nil {
+			if ahead, behind, err := aheadBehind(repo, head, ref.Hash()); err == nil {
+				status.Tracked = true
+				status.AheadBy, status.BehindBy = ahead, behind
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Remotes come from a map; keep the order stable
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// upstreamRef returns the remote-tracking ref for branch: its configured
+// upstream, or origin/<branch> when a remote exists
+func upstreamRef(repo *gogit.Repository, branch string, remoteExists bool) plumbing.ReferenceName {
+	cfg, err := repo.Config()
+	if err == nil {
+		if b, ok := cfg.Branches[branch]; ok && b.Remote != "" && b.Merge != "" {
+			return plumbing.NewRemoteReferenceName(b.Remote, b.Merge.Short())
+		}
+	}
+	if remoteExists {
+		return plumbing.NewRemoteReferenceName("origin", branch)
+	}
+	return ""
+}
+
+// aheadBehind counts commits reachable from local but not remote, and the reverse
+func aheadBehind(repo *gogit.Repository, local, remote plumbing.Hash) (ahead, behind int, err error) {
+	localSet, err := ancestors(repo, local)
+	if err != nil {
+		return 0, 0, err
+	}
+	remoteSet, err := ancestors(repo, remote)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for hash := range localSet {
+		if !remoteSet[hash] {
+			ahead++
+		}
+	}
+	for hash := range remoteSet {
+		if !localSet[hash] {
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+// ancestors returns hash and every commit reachable from it
+func ancestors(repo *gogit.Repository, hash plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[plumbing.Hash]bool)
+	err = object.NewCommitPreorderIter(commit, nil, nil).ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	})
+	return seen, err
+}
+
+// goGitOperationInProgress reports whether a rebase or merge is in progress,
+// based on the state files git keeps in the git directory
+func goGitOperationInProgress(repo *gogit.Repository) (rebasing, merging bool) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return false, false
+	}
+	gitDir := storage.Filesystem().Root()
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+	return exists("rebase-merge") || exists("rebase-apply"), exists("MERGE_HEAD")
+}
+
+// GetChangedFiles returns changed files under repoPath, limited to pathspecs
+// when given.
+// go-git doesn't detect renames; they are reported as a delete and an add.
+func (g goGitBackend) GetChangedFiles(repoPath string, pathspecs ...string) ([]ChangeEntry, error) {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+
+	entries, err := statusEntries(repo, repoPath, pathspecs)
+	if err != nil {
+		return nil, err
+	}
+	return changeEntries(entries), nil
+}
+
+// statusEntries returns status entries for files matching pathspecs under
+// repoPath, sorted by path, in the same form as porcelain status
+func statusEntries(repo *gogit.Repository, repoPath string, pathspecs []string) ([]StatusEntry, error) {
+	matcher, err := newPathspecMatcher(repo, repoPath, pathspecs)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("opening worktree: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+
+	var entries []StatusEntry
+	for file, fileStatus := range status {
+		if !matcher.match(file) {
+			continue
+		}
+		if fileStatus.Staging == gogit.Unmodified && fileStatus.Worktree == gogit.Unmodified {
+			continue
+		}
+		entries = append(entries, statusEntry(file, fileStatus))
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// statusEntry converts a go-git file status to a porcelain status entry
+func statusEntry(file string, fileStatus *gogit.FileStatus) StatusEntry {
+	entry := StatusEntry{
+		Kind:     EntryChanged,
+		Staged:   statusCode(fileStatus.Staging),
+		Unstaged: statusCode(fileStatus.Worktree),
+		Path:     file,
+	}
+
+	switch {
+	case fileStatus.Staging == gogit.Untracked || fileStatus.Worktree == gogit.Untracked:
+		entry.Kind = EntryUntracked
+		entry.Staged, entry.Unstaged = '.', '.'
+	case fileStatus.Staging == gogit.UpdatedButUnmerged || fileStatus.Worktree == gogit.UpdatedButUnmerged:
+		entry.Kind = EntryUnmerged
+	case fileStatus.Staging == gogit.Renamed || fileStatus.Staging == gogit.Copied:
+		entry.Kind = EntryRenamed
+		entry.OrigPath = fileStatus.Extra
+	}
+	return entry
+}
+
+// statusCode converts a go-git status code to porcelain form ('.' if unchanged)
+func statusCode(code gogit.StatusCode) byte {
+	if code == gogit.Unmodified {
+		return '.'
+	}
+	return byte(code)
+}
+
+// pathspecMatcher matches repository-relative paths against the literal
+// pathspecs dotcor uses: plain paths and ":(exclude)" paths
+type pathspecMatcher struct {
+	include []string
+	exclude []string
+}
+
+// newPathspecMatcher resolves pathspecs, relative to repoPath, against the
+// repository root. Pathspecs with globs or other magic return ErrUnsupported.
+func newPathspecMatcher(repo *gogit.Repository, repoPath string, pathspecs []string) (pathspecMatcher, error) {
+	var matcher pathspecMatcher
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return matcher, fmt.Errorf("opening worktree: %w", err)
+	}
+	prefix, err := repoRelativePath(worktree.Filesystem.Root(), repoPath)
+	if err != nil {
+		return matcher, err
+	}
+
+	for _, spec := range defaultPathspecs(pathspecs) {
+		exclude := false
+		if strings.HasPrefix(spec, ":(exclude)") {
+			spec = strings.TrimPrefix(spec, ":(exclude)")
+			exclude = true
+		}
+		if strings.HasPrefix(spec, ":") || strings.ContainsAny(spec, "*?[") {
+			return matcher, fmt.Errorf("%w: pathspec %q", ErrUnsupported, spec)
+		}
+
+		resolved := path.Join(prefix, filepath.ToSlash(spec))
+		if resolved == "." {
+			resolved = ""
+		}
+		if exclude {
+			matcher.exclude = append(matcher.exclude, resolved)
+		} else {
+			matcher.include = append(matcher.include, resolved)
+		}
+	}
+
+	// A pathspec of only exclusions applies to everything under repoPath
+	if len(matcher.include) == 0 {
+		matcher.include = []string{prefix}
+	}
+
+	return matcher, nil
+}
+
+// match reports whether file is under an included path and no excluded path
+func (m pathspecMatcher) match(file string) bool {
+	for _, spec := range m.exclude {
+		if pathUnder(file, spec) {
+			return false
+		}
+	}
+	for _, spec := range m.include {
+		if pathUnder(file, spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathUnder reports whether file is dir or inside it ("" is the repository root)
+func pathUnder(file, dir string) bool {
+	return dir == "" || file == dir || strings.HasPrefix(file, dir+"/")
+}
+
+// repoRelativePath returns repoPath relative to the worktree root in slash
+// form, or "" for the root itself
+func repoRelativePath(root, repoPath string) (string, error) {
+	abs, err := filepath.Abs(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		// Resolve symlinks such as /tmp -> /private/tmp before giving up
+		realRoot, rootErr := filepath.EvalSymlinks(root)
+		realPath, pathErr := filepath.EvalSymlinks(abs)
+		if rootErr != nil || pathErr != nil {
+			return "", fmt.Errorf("%s is not inside repository %s", repoPath, root)
+		}
+		rel, err = filepath.Rel(realRoot, realPath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", fmt.Errorf("%s is not inside repository %s", repoPath, root)
+		}
+	}
+
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// GetCurrentCommit returns the current commit hash
+func (g goGitBackend) GetCurrentCommit(repoPath string) (string, error) {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return head.Hash().String(), nil
+}
+
+// GetRemoteURL returns configured remote URL, or empty if none
+func (g goGitBackend) GetRemoteURL(repoPath string) (string, error) {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return "", nil
+	}
+
+	remote, err := repo.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return "", nil // No remote configured
+	}
+	return remote.Config().URLs[0], nil
+}
+
+// SetRemote configures git remote
+func (g goGitBackend) SetRemote(repoPath, remoteName, remoteURL string) error {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return fmt.Errorf("opening repository: %w", err)
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("reading git config: %w", err)
+	}
+
+	if remote, ok := cfg.Remotes[remoteName]; ok {
+		// Update existing remote
+		remote.URLs = []string{remoteURL}
+		if err := repo.SetConfig(cfg); err != nil {
+			return fmt.Errorf("git remote set-url failed: %w", err)
+		}
+		return nil
+	}
+
+	// Add new remote
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: remoteName, URLs: []string{remoteURL}})
+	if err != nil {
+		return fmt.Errorf("git remote add failed: %w", err)
+	}
+	return nil
+}
+
+// Clone clones a repository to the specified path
+func (g goGitBackend) Clone(url, destPath string) error {
+	_, err := gogit.PlainCloneContext(runCtx, destPath, false, &gogit.CloneOptions{URL: url})
+	if err != nil {
+		return transportError("git clone", err)
+	}
+	return nil
+}
+
+// Fetch fetches changes from remote without merging
+func (g goGitBackend) Fetch(repoPath string) error {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return fmt.Errorf("git fetch failed: %w", err)
+	}
+
+	err = repo.FetchContext(runCtx, &gogit.FetchOptions{RemoteName: "origin"})
+	if err == nil || errors.Is(err, gogit.NoErrAlreadyUpToDate) || errors.Is(err, gogit.ErrRemoteNotFound) {
+		return nil
+	}
+	return transportError("git fetch", err)
+}
+
+// GetIncomingFiles returns files under repoPath that a pull from the upstream
+// branch would change. Paths are relative to repoPath. Call Fetch first.
+func (g goGitBackend) GetIncomingFiles(repoPath string) ([]string, error) {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return nil, fmt.Errorf("git diff failed: HEAD is detached")
+	}
+	upstream := upstreamRef(repo, head.Name().Short(), true)
+	remoteRef, err := repo.Reference(upstream, true)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: no upstream %s: %w", upstream.Short(), err)
+	}
+
+	local, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	remote, err := repo.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+
+	// Same as 'git diff HEAD...@{upstream}': changes on the remote since the merge base
+	bases, err := local.MergeBase(remote)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("%w: histories have no common ancestor", ErrUnsupported)
+	}
+
+	baseTree, err := bases[0].Tree()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	remoteTree, err := remote.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	changes, err := object.DiffTree(baseTree, remoteTree)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("opening worktree: %w", err)
+	}
+	prefix, err := repoRelativePath(worktree.Filesystem.Root(), repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+		if !pathUnder(name, prefix) {
+			continue
+		}
+		if prefix != "" {
+			name = strings.TrimPrefix(name, prefix+"/")
+		}
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// Pull pulls changes from remote. Only fast-forwards are supported; merges
+// return ErrUnsupported.
+func (g goGitBackend) Pull(repoPath string) error {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return fmt.Errorf("git pull failed: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("opening worktree: %w", err)
+	}
+
+	err = worktree.PullContext(runCtx, &gogit.PullOptions{RemoteName: "origin"})
+	switch {
+	case err == nil, errors.Is(err, gogit.NoErrAlreadyUpToDate):
+		return nil
+	case errors.Is(err, gogit.ErrNonFastForwardUpdate), errors.Is(err, gogit.ErrUnstagedChanges):
+		return fmt.Errorf("%w: git pull: %v", ErrUnsupported, err)
+	}
+	return transportError("git pull", err)
+}
+
+// PullRebase isn't supported by go-git and always returns ErrUnsupported
+func (g goGitBackend) PullRebase(repoPath string) error {
+	return fmt.Errorf("%w: git pull --rebase", ErrUnsupported)
+}
+
+// Push pushes the current branch, setting its upstream on the first push
+func (g goGitBackend) Push(repoPath string) error {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return fmt.Errorf("git push failed: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("getting current branch: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		// Common in worktrees checked out at a commit rather than a branch
+		return fmt.Errorf("cannot push from detached HEAD; check out a branch first")
+	}
+	branch := head.Name().Short()
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("reading git config: %w", err)
+	}
+
+	// Push to the upstream remote, or origin when not configured
+	remoteName := "origin"
+	upstream, hasUpstream := cfg.Branches[branch]
+	if hasUpstream && upstream.Remote != "" {
+		remoteName = upstream.Remote
+	}
+
+	refSpec := gitconfig.RefSpec(fmt.Sprintf("%s:%s", head.Name(), head.Name()))
+	if hasUpstream && upstream.Merge != "" {
+		refSpec = gitconfig.RefSpec(fmt.Sprintf("%s:%s", head.Name(), upstream.Merge))
+	}
+
+	err = repo.PushContext(runCtx, &gogit.PushOptions{RemoteName: remoteName, RefSpecs: []gitconfig.RefSpec{refSpec}})
+	if errors.Is(err, gogit.ErrForceNeeded) {
+		return fmt.Errorf("%w: git push: %v", ErrBehindRemote, err)
+	}
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return transportError("git push", err)
+	}
+
+	if !hasUpstream {
+		cfg.Branches[branch] = &gitconfig.Branch{Name: branch, Remote: remoteName, Merge: head.Name()}
+		if err := repo.SetConfig(cfg); err != nil {
+			return fmt.Errorf("setting upstream: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// PushRemote pushes the current branch to the named remote
+func (g goGitBackend) PushRemote(repoPath, remoteName string) error {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return fmt.Errorf("git push failed: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("getting current branch: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("cannot push from detached HEAD; check out a branch first")
+	}
+
+	refSpec := gitconfig.RefSpec(fmt.Sprintf("%s:%s", head.Name(), head.Name()))
+	err = repo.PushContext(runCtx, &gogit.PushOptions{RemoteName: remoteName, RefSpecs: []gitconfig.RefSpec{refSpec}})
+	if errors.Is(err, gogit.ErrForceNeeded) {
+		return fmt.Errorf("%w: git push: %v", ErrBehindRemote, err)
+	}
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return transportError("git push", err)
+	}
+	return nil
+}
+
+// transportError wraps a network error, marking authentication failures as
+// ErrUnsupported since go-git can't use credential helpers or SSH config
+func transportError(op string, err error) error {
+	if errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		strings.Contains(err.Error(), "ssh: ") ||
+		strings.Contains(err.Error(), "SSH agent") {
+		return fmt.Errorf("%w: %s: %v", ErrUnsupported, op, err)
+	}
+	return fmt.Errorf("%s failed: %w", op, err)
+}
@@ -0,0 +1,188 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoGitInitAndIsRepo(t *testing.T) {
+	tempDir := t.TempDir()
+	backend := goGitBackend{}
+
+	if backend.IsRepo(tempDir) {
+		t.Error("IsRepo() should return false for non-repo directory")
+	}
+
+	if err := backend.InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	if !backend.IsRepo(tempDir) {
+		t.Error("IsRepo() should return true after InitRepo()")
+	}
+
+	// Re-initializing an existing repository is a no-op
+	if err := backend.InitRepo(tempDir); err != nil {
+		t.Errorf("InitRepo() on existing repo error = %v", err)
+	}
+}
+
+func TestGoGitAutoCommitSubdir(t *testing.T) {
+	tempDir := t.TempDir()
+	backend := goGitBackend{}
+
+	if err := backend.InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+
+	filesDir := filepath.Join(tempDir, "dotfiles")
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		t.Fatalf("failed to create files dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "README"), []byte("unrelated"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(filesDir, "zshrc"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	changes, err := backend.GetChangedFiles(filesDir)
+	if err != nil {
+		t.Fatalf("GetChangedFiles() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "dotfiles/zshrc" || changes[0].Status != "??" {
+		t.Errorf("GetChangedFiles() = %+v, want untracked dotfiles/zshrc", changes)
+	}
+
+	if err := backend.AutoCommit(filesDir, "add dotfiles"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+
+	hasChanges, err := backend.HasChanges(filesDir)
+	if err != nil {
+		t.Fatalf("HasChanges() error = %v", err)
+	}
+	if hasChanges {
+		t.Error("AutoCommit() should have committed the subdirectory")
+	}
+
+	// Unrelated content stays uncommitted
+	hasChanges, err = backend.HasChanges(tempDir)
+	if err != nil {
+		t.Fatalf("HasChanges() error = %v", err)
+	}
+	if !hasChanges {
+		t.Error("AutoCommit() should not commit content outside the subdirectory")
+	}
+
+	if _, err := backend.GetCurrentCommit(tempDir); err != nil {
+		t.Errorf("GetCurrentCommit() error = %v", err)
+	}
+
+	// Deletions are committed too
+	if err := os.Remove(filepath.Join(filesDir, "zshrc")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	status, err := backend.GetStatus(filesDir)
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if status.UnstagedCount != 1 || len(status.Changes) != 1 || status.Changes[0].Status != "D" {
+		t.Errorf("GetStatus() = %+v, want one unstaged deletion", status)
+	}
+	if err := backend.AutoCommit(filesDir, "remove zshrc"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+	if hasChanges, _ := backend.HasChanges(filesDir); hasChanges {
+		t.Error("AutoCommit() should have committed the deletion")
+	}
+}
+
+func TestGoGitPathspecs(t *testing.T) {
+	tempDir := t.TempDir()
+	backend := goGitBackend{}
+
+	if err := backend.InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	for _, name := range []string{"zshrc", "system/hosts"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	if err := backend.AutoCommit(tempDir, "dotfiles only", ExcludePathspec("system")); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+
+	changes, err := backend.GetChangedFiles(tempDir)
+	if err != nil {
+		t.Fatalf("GetChangedFiles() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "system/hosts" {
+		t.Errorf("GetChangedFiles() = %+v, want only system/hosts", changes)
+	}
+
+	// Globs need the git command
+	if _, err := backend.HasChanges(tempDir, "*.conf"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("HasChanges() with glob error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestGoGitRemote(t *testing.T) {
+	tempDir := t.TempDir()
+	backend := goGitBackend{}
+
+	if err := backend.InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+
+	url, err := backend.GetRemoteURL(tempDir)
+	if err != nil || url != "" {
+		t.Errorf("GetRemoteURL() = %q, %v, want empty", url, err)
+	}
+
+	// Fetch without a remote does nothing
+	if err := backend.Fetch(tempDir); err != nil {
+		t.Errorf("Fetch() without remote error = %v", err)
+	}
+
+	for _, want := range []string{"https://example.com/a.git", "https://example.com/b.git"} {
+		if err := backend.SetRemote(tempDir, "origin", want); err != nil {
+			t.Fatalf("SetRemote() error = %v", err)
+		}
+		if url, _ := backend.GetRemoteURL(tempDir); url != want {
+			t.Errorf("GetRemoteURL() = %q, want %q", url, want)
+		}
+	}
+}
+
+func TestSetBackend(t *testing.T) {
+	defer SetBackend(BackendAuto)
+
+	if err := SetBackend("libgit2"); err == nil {
+		t.Error("SetBackend(\"libgit2\") should return error")
+	}
+
+	if err := SetBackend(BackendGoGit); err != nil {
+		t.Fatalf("SetBackend() error = %v", err)
+	}
+	if name := CurrentBackend().Name(); name != BackendGoGit {
+		t.Errorf("CurrentBackend().Name() = %q, want %q", name, BackendGoGit)
+	}
+	if !IsAvailable() {
+		t.Error("IsAvailable() should be true with the go-git backend")
+	}
+
+	if err := SetBackend(BackendCLI); err != nil {
+		t.Fatalf("SetBackend() error = %v", err)
+	}
+	if name := CurrentBackend().Name(); name != BackendCLI {
+		t.Errorf("CurrentBackend().Name() = %q, want %q", name, BackendCLI)
+	}
+}
@@ -0,0 +1,103 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HookMarker identifies hook scripts written by InstallHook
+const HookMarker = "# Installed by dotcor"
+
+// SkipScanEnv is set for the git commands dotcor runs. 'dotcor scan
+// --staged' passes when it is set, so the pre-commit hook only checks
+// commits made by hand: what dotcor commits was checked when it was added,
+// or added with --force on purpose.
+const SkipScanEnv = "DOTCOR_SKIP_SCAN"
+
+// HooksDir returns the hooks directory for the repository at repoPath.
+// Linked worktrees share the hooks of their main repository.
+func HooksDir(repoPath string) (string, error) {
+	cmd := command("rev-parse", "--git-path", "hooks")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+
+	// --git-path may be relative to the working directory
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoPath, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// HookInstalled reports whether the named hook was installed by dotcor
+func HookInstalled(repoPath, name string) bool {
+	dir, err := HooksDir(repoPath)
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	return err == nil && bytes.Contains(data, []byte(HookMarker))
+}
+
+// InstallHook writes an executable hook script, marked as installed by
+// dotcor. A hook that dotcor didn't install is never overwritten.
+func InstallHook(repoPath, name, script string) error {
+	dir, err := HooksDir(repoPath)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name)
+
+	if data, err := os.ReadFile(path); err == nil && !bytes.Contains(data, []byte(HookMarker)) {
+		return fmt.Errorf("%s already exists and was not installed by dotcor", path)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating hooks directory: %w", err)
+	}
+	if !strings.Contains(script, HookMarker) {
+		first, rest, _ := strings.Cut(script, "\n")
+		script = first + "\n" + HookMarker + "\n" + rest
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return fmt.Errorf("writing %s hook: %w", name, err)
+	}
+	return nil
+}
+
+// StagedFiles returns the files under repoPath with added, copied, modified
+// or renamed content in the index, relative to repoPath
+func StagedFiles(repoPath string) ([]string, error) {
+	cmd := command("diff", "--cached", "--name-only", "--relative", "--diff-filter=ACMR", "-z")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+
+	var files []string
+	for _, name := range strings.Split(string(output), "\x00") {
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+// StagedContent returns the content of filePath, relative to repoPath, as
+// it is in the index
+func StagedContent(repoPath, filePath string) ([]byte, error) {
+	cmd := command("show", ":./"+filepath.ToSlash(filePath))
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git show failed: %w", err)
+	}
+	return output, nil
+}
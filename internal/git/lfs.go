@@ -0,0 +1,143 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lfsAttributes marks a path as stored with Git LFS in .gitattributes
+const lfsAttributes = "filter=lfs diff=lfs merge=lfs -text"
+
+// IsLFSInstalled reports whether the git-lfs extension is installed
+func IsLFSInstalled() bool {
+	return command("lfs", "version").Run() == nil
+}
+
+// LFSHooksInstalled reports whether the LFS filters are configured for the
+// repository, either in it or globally, so LFS files are converted on
+// commit and checkout
+func LFSHooksInstalled(repoPath string) bool {
+	cmd := command("config", "--get", "filter.lfs.clean")
+	cmd.Dir = repoPath
+	return cmd.Run() == nil
+}
+
+// InstallLFS configures the LFS filters and hooks in the repository
+func InstallLFS(repoPath string) error {
+	cmd := command("lfs", "install", "--local")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git lfs install failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// UsesLFS reports whether the .gitattributes in repoPath stores any files
+// with Git LFS
+func UsesLFS(repoPath string) bool {
+	data, err := os.ReadFile(filepath.Join(repoPath, ".gitattributes"))
+	return err == nil && strings.Contains(string(data), "filter=lfs")
+}
+
+// IsLFSTracked reports whether path, relative to repoPath, is stored with
+// Git LFS according to .gitattributes
+func IsLFSTracked(repoPath, path string) bool {
+	cmd := command("check-attr", "filter", "--", path)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	return err == nil && strings.HasSuffix(strings.TrimSpace(string(output)), ": filter: lfs")
+}
+
+// TrackLFS stores the given files, relative to repoPath, with Git LFS by
+// adding them to the .gitattributes in repoPath, so it's committed along
+// with them. Files already committed are re-added so their next commit
+// replaces them with LFS pointers. The LFS filters must be installed.
+func TrackLFS(repoPath string, paths ...string) error {
+	attrPath := filepath.Join(repoPath, ".gitattributes")
+	existing, err := os.ReadFile(attrPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading .gitattributes: %w", err)
+	}
+
+	content := string(existing)
+	var added []string
+	for _, p := range paths {
+		line := lfsAttributesPattern(filepath.ToSlash(p)) + " " + lfsAttributes
+		if strings.Contains("\n"+content, "\n"+line+"\n") {
+			continue
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += line + "\n"
+		added = append(added, p)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if err := os.WriteFile(attrPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("writing .gitattributes: %w", err)
+	}
+
+	cmd := command(append([]string{"add", "--renormalize", "--"}, added...)...)
+	cmd.Dir = repoPath
+	if output, err := cmd.CombinedOutput(); err != nil && !strings.Contains(string(output), "did not match any files") {
+		return fmt.Errorf("git add --renormalize failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// lfsAttributesPattern returns a .gitattributes pattern matching exactly the
+// file at path, relative to the .gitattributes file
+func lfsAttributesPattern(path string) string {
+	var b strings.Builder
+	b.WriteString("/")
+	for _, r := range path {
+		switch r {
+		case ' ':
+			b.WriteString("[[:space:]]")
+		case '*', '?', '[', '\\', '!', '#':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// LFSMissingFiles returns the LFS files under repoPath whose content hasn't
+// been downloaded, so only their pointer is checked out. Paths are relative
+// to the repository root.
+func LFSMissingFiles(repoPath string) ([]string, error) {
+	cmd := command("lfs", "ls-files")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git lfs ls-files failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	var missing []string
+	for _, line := range strings.Split(string(output), "\n") {
+		// "<oid> * <path>" when downloaded, "<oid> - <path>" for a pointer
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) == 3 && fields[1] == "-" {
+			missing = append(missing, fields[2])
+		}
+	}
+	return missing, nil
+}
+
+// PullLFS downloads and checks out the content of the repository's LFS files
+func PullLFS(repoPath string) error {
+	cmd := command("lfs", "pull")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git lfs pull failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrackLFS(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	repoPath := t.TempDir()
+	if err := InitRepo(repoPath); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, repoPath)
+	if UsesLFS(repoPath) {
+		t.Error("UsesLFS() = true without .gitattributes")
+	}
+
+	for _, name := range []string{"font.ttf", "my wallpaper.png"} {
+		path := filepath.Join(repoPath, "fonts", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte{0, 1, 2}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AutoCommit(repoPath, "Add fonts"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+
+	// Tracking works the same for committed files and new ones, and twice
+	// is the same as once
+	paths := []string{"fonts/font.ttf", "fonts/my wallpaper.png", "fonts/new.otf"}
+	for i := 0; i < 2; i++ {
+		if err := TrackLFS(repoPath, paths...); err != nil {
+			t.Fatalf("TrackLFS() error = %v", err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(repoPath, ".gitattributes"))
+	if err != nil {
+		t.Fatalf("reading .gitattributes: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf(".gitattributes has %d lines, want 3:\n%s", lines, data)
+	}
+
+	if !UsesLFS(repoPath) {
+		t.Error("UsesLFS() = false after TrackLFS()")
+	}
+	for _, p := range paths {
+		if !IsLFSTracked(repoPath, p) {
+			t.Errorf("IsLFSTracked(%q) = false", p)
+		}
+	}
+	if IsLFSTracked(repoPath, "fonts/other.ttf") {
+		t.Error("IsLFSTracked() = true for a file that wasn't tracked")
+	}
+}
+
+func TestLFSAttributesPattern(t *testing.T) {
+	tests := map[string]string{
+		"fonts/font.ttf":     "/fonts/font.ttf",
+		"my wallpaper.png":   "/my[[:space:]]wallpaper.png",
+		"themes/[dark]*.png": `/themes/\[dark]\*.png`,
+	}
+	for path, want := range tests {
+		if got := lfsAttributesPattern(path); got != want {
+			t.Errorf("lfsAttributesPattern(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EntryKind classifies a file in porcelain v2 status output
+type EntryKind int
+
+const (
+	EntryChanged   EntryKind = iota // Tracked file with staged and/or unstaged changes
+	EntryRenamed                    // Renamed or copied file
+	EntryUnmerged                   // File with merge conflicts
+	EntryUntracked                  // File not tracked by git
+	EntryIgnored                    // Ignored file (only listed when requested)
+)
+
+// StatusEntry is a single file from 'git status --porcelain=v2'
+type StatusEntry struct {
+	Kind     EntryKind
+	Staged   byte   // Index status (X), '.' if unchanged
+	Unstaged byte   // Working tree status (Y), '.' if unchanged
+	Path     string // Path relative to the repository root
+	OrigPath string // Original path of a renamed or copied file
+}
+
+// IsStaged reports whether the entry has changes in the index
+func (e StatusEntry) IsStaged() bool {
+	return (e.Kind == EntryChanged || e.Kind == EntryRenamed) && e.Staged != '.'
+}
+
+// IsUnstaged reports whether the entry has changes in the working tree
+func (e StatusEntry) IsUnstaged() bool {
+	return (e.Kind == EntryChanged || e.Kind == EntryRenamed) && e.Unstaged != '.'
+}
+
+// Code returns a short status code like 'git status --short' (e.g. "M", "A", "??")
+func (e StatusEntry) Code() string {
+	switch e.Kind {
+	case EntryUntracked:
+		return "??"
+	case EntryIgnored:
+		return "!!"
+	case EntryUnmerged:
+		return "U"
+	}
+	if e.Staged != '.' {
+		return string(e.Staged)
+	}
+	return string(e.Unstaged)
+}
+
+// ChangeEntry is a changed file as shown to users
+type ChangeEntry struct {
+	Status  string // Short status code, e.g. "M", "A", "D", "R", "U", "??"
+	Path    string // Path relative to the repository root (new path for renames)
+	OldPath string // Original path of a renamed or copied file
+}
+
+// DisplayPath returns the path, or "old -> new" for renames and copies
+func (c ChangeEntry) DisplayPath() string {
+	if c.OldPath != "" {
+		return c.OldPath + " -> " + c.Path
+	}
+	return c.Path
+}
+
+// changeEntries converts porcelain status entries to change entries
+func changeEntries(entries []StatusEntry) []ChangeEntry {
+	changes := make([]ChangeEntry, 0, len(entries))
+	for _, entry := range entries {
+		changes = append(changes, ChangeEntry{
+			Status:  entry.Code(),
+			Path:    entry.Path,
+			OldPath: entry.OrigPath,
+		})
+	}
+	return changes
+}
+
+// PorcelainStatus is the parsed output of a single 'git status --porcelain=v2' call
+type PorcelainStatus struct {
+	Branch      string // Current branch, empty when HEAD is detached
+	Detached    bool
+	Upstream    string // Upstream branch (e.g. origin/main), empty if none
+	AheadBy     int    // Commits ahead of upstream
+	BehindBy    int    // Commits behind upstream
+	HasUpstream bool
+	Entries     []StatusEntry
+}
+
+// GetPorcelainStatus runs 'git status --porcelain=v2 -z' once and parses branch
+// information and per-file entries. Entries are limited to pathspecs when given,
+// or everything under repoPath otherwise.
+func GetPorcelainStatus(repoPath string, pathspecs ...string) (PorcelainStatus, error) {
+	args := append([]string{"status", "--porcelain=v2", "-z", "--branch", "--untracked-files=all", "--"}, defaultPathspecs(pathspecs)...)
+	cmd := command(args...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return PorcelainStatus{}, fmt.Errorf("git status failed: %w", err)
+	}
+
+	return parsePorcelainV2(string(output))
+}
+
+// parsePorcelainV2 parses NUL-separated porcelain v2 output
+func parsePorcelainV2(output string) (PorcelainStatus, error) {
+	var status PorcelainStatus
+
+	records := strings.Split(output, "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+
+		switch record[0] {
+		case '#':
+			parseBranchHeader(&status, record)
+
+		case '1':
+			// 1 XY sub mH mI mW hH hI path
+			fields := strings.SplitN(record, " ", 9)
+			if len(fields) < 9 || len(fields[1]) != 2 {
+				return status, fmt.Errorf("malformed status entry: %q", record)
+			}
+			status.Entries = append(status.Entries, StatusEntry{
+				Kind:     EntryChanged,
+				Staged:   fields[1][0],
+				Unstaged: fields[1][1],
+				Path:     fields[8],
+			})
+
+		case '2':
+			// 2 XY sub mH mI mW hH hI Xscore path, followed by origPath record
+			fields := strings.SplitN(record, " ", 10)
+			if len(fields) < 10 || len(fields[1]) != 2 || i+1 >= len(records) {
+				return status, fmt.Errorf("malformed rename entry: %q", record)
+			}
+			i++
+			status.Entries = append(status.Entries, StatusEntry{
+				Kind:     EntryRenamed,
+				Staged:   fields[1][0],
+				Unstaged: fields[1][1],
+				Path:     fields[9],
+				OrigPath: records[i],
+			})
+
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fields := strings.SplitN(record, " ", 11)
+			if len(fields) < 11 || len(fields[1]) != 2 {
+				return status, fmt.Errorf("malformed unmerged entry: %q", record)
+			}
+			status.Entries = append(status.Entries, StatusEntry{
+				Kind:     EntryUnmerged,
+				Staged:   fields[1][0],
+				Unstaged: fields[1][1],
+				Path:     fields[10],
+			})
+
+		case '?', '!':
+			if len(record) < 3 {
+				return status, fmt.Errorf("malformed status entry: %q", record)
+			}
+			kind := EntryUntracked
+			if record[0] == '!' {
+				kind = EntryIgnored
+			}
+			status.Entries = append(status.Entries, StatusEntry{
+				Kind:     kind,
+				Staged:   '.',
+				Unstaged: '.',
+				Path:     record[2:],
+			})
+
+		default:
+			return status, fmt.Errorf("unknown status entry: %q", record)
+		}
+	}
+
+	return status, nil
+}
+
+// parseBranchHeader parses a '# branch.*' header line
+func parseBranchHeader(status *PorcelainStatus, record string) {
+	fields := strings.Fields(record)
+	if len(fields) < 3 {
+		return
+	}
+
+	switch fields[1] {
+	case "branch.head":
+		if fields[2] == "(detached)" {
+			status.Detached = true
+		} else {
+			status.Branch = fields[2]
+		}
+	case "branch.upstream":
+		status.Upstream = fields[2]
+		status.HasUpstream = true
+	case "branch.ab":
+		if len(fields) >= 4 {
+			status.AheadBy, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+			status.BehindBy, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePorcelainV2(t *testing.T) {
+	output := "# branch.oid 1234567890abcdef\x00" +
+		"# branch.head main\x00" +
+		"# branch.upstream origin/main\x00" +
+		"# branch.ab +2 -1\x00" +
+		"1 .M N... 100644 100644 100644 aaa bbb shell/zshrc\x00" +
+		"1 A. N... 000000 100644 100644 000 ccc my file.txt\x00" +
+		"2 R. N... 100644 100644 100644 ddd ddd R100 vim/new name\x00vim/old name\x00" +
+		"u UU N... 100644 100644 100644 100644 e1 e2 e3 conflict.txt\x00" +
+		"? café/notes\x00"
+
+	status, err := parsePorcelainV2(output)
+	if err != nil {
+		t.Fatalf("parsePorcelainV2() error = %v", err)
+	}
+
+	if status.Branch != "main" || status.Detached {
+		t.Errorf("Branch = %q, Detached = %v", status.Branch, status.Detached)
+	}
+	if !status.HasUpstream || status.Upstream != "origin/main" {
+		t.Errorf("Upstream = %q, HasUpstream = %v", status.Upstream, status.HasUpstream)
+	}
+	if status.AheadBy != 2 || status.BehindBy != 1 {
+		t.Errorf("AheadBy = %d, BehindBy = %d, want 2, 1", status.AheadBy, status.BehindBy)
+	}
+
+	want := []StatusEntry{
+		{Kind: EntryChanged, Staged: '.', Unstaged: 'M', Path: "shell/zshrc"},
+		{Kind: EntryChanged, Staged: 'A', Unstaged: '.', Path: "my file.txt"},
+		{Kind: EntryRenamed, Staged: 'R', Unstaged: '.', Path: "vim/new name", OrigPath: "vim/old name"},
+		{Kind: EntryUnmerged, Staged: 'U', Unstaged: 'U', Path: "conflict.txt"},
+		{Kind: EntryUntracked, Staged: '.', Unstaged: '.', Path: "café/notes"},
+	}
+	if len(status.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(status.Entries), len(want), status.Entries)
+	}
+	for i, entry := range status.Entries {
+		if entry != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+	}
+
+	// Staged/unstaged classification
+	if status.Entries[0].IsStaged() || !status.Entries[0].IsUnstaged() {
+		t.Error("modified worktree file should be unstaged only")
+	}
+	if !status.Entries[1].IsStaged() || status.Entries[1].IsUnstaged() {
+		t.Error("added file should be staged only")
+	}
+	if status.Entries[4].IsStaged() || status.Entries[4].IsUnstaged() {
+		t.Error("untracked file should be neither staged nor unstaged")
+	}
+	if got := status.Entries[4].Code(); got != "??" {
+		t.Errorf("untracked Code() = %q, want ??", got)
+	}
+
+	// Detached HEAD
+	status, err = parsePorcelainV2("# branch.oid abc\x00# branch.head (detached)\x00")
+	if err != nil {
+		t.Fatalf("parsePorcelainV2() error = %v", err)
+	}
+	if !status.Detached || status.Branch != "" {
+		t.Errorf("detached: Branch = %q, Detached = %v", status.Branch, status.Detached)
+	}
+
+	// Malformed input
+	if _, err := parsePorcelainV2("1 M\x00"); err == nil {
+		t.Error("parsePorcelainV2() should reject malformed entries")
+	}
+}
+
+func TestGetPorcelainStatus(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, tempDir)
+
+	if err := os.WriteFile(filepath.Join(tempDir, "old name"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := AutoCommit(tempDir, "initial"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+
+	// Staged rename plus an untracked file in a subdirectory
+	cmd := exec.Command("git", "mv", "old name", "new name")
+	cmd.Dir = tempDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git mv failed: %s: %v", output, err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "sub"), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "sub", "untracked"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	status, err := GetPorcelainStatus(tempDir)
+	if err != nil {
+		t.Fatalf("GetPorcelainStatus() error = %v", err)
+	}
+	if status.Branch == "" {
+		t.Error("GetPorcelainStatus() should report the branch")
+	}
+
+	var renamed, untracked bool
+	for _, entry := range status.Entries {
+		switch {
+		case entry.Kind == EntryRenamed && entry.Path == "new name" && entry.OrigPath == "old name":
+			renamed = true
+		case entry.Kind == EntryUntracked && entry.Path == "sub/untracked":
+			untracked = true
+		}
+	}
+	if !renamed || !untracked {
+		t.Errorf("GetPorcelainStatus() entries = %+v", status.Entries)
+	}
+
+	// Pathspecs limit the entries
+	status, err = GetPorcelainStatus(tempDir, "sub")
+	if err != nil {
+		t.Fatalf("GetPorcelainStatus() error = %v", err)
+	}
+	if len(status.Entries) != 1 {
+		t.Errorf("GetPorcelainStatus(sub) entries = %+v, want 1", status.Entries)
+	}
+}
@@ -0,0 +1,179 @@
+package git
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// History rewriting tools PurgeFromHistory can use
+const (
+	PurgeFilterRepo   = "git filter-repo"
+	PurgeFilterBranch = "git filter-branch"
+)
+
+// CommitsTouching returns the commits on any ref that change path,
+// relative to repoPath, newest first
+func CommitsTouching(repoPath, path string) ([]CommitInfo, error) {
+	cmd := command("log", "--all", "--format=%H%x1f%an%x1f%aI%x1f%s", "--", path)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+
+	var commits []CommitInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(line, "\x1f", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, parts[2])
+		commits = append(commits, CommitInfo{Hash: parts[0], Author: parts[1], Date: date, Message: parts[3]})
+	}
+	return commits, nil
+}
+
+// HistoryPaths returns every file path, relative to repoPath, that a commit
+// on any ref has under repoPath, sorted
+func HistoryPaths(repoPath string) ([]string, error) {
+	cmd := command("-c", "core.quotePath=false", "log", "--all", "--format=", "--name-only", "--relative", "--", ".")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+
+	seen := map[string]bool{}
+	var paths []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !seen[line] {
+			seen[line] = true
+			paths = append(paths, line)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ContentAt returns the content of path, relative to repoPath, at commit
+// ref. It fails if the file isn't in that commit.
+func ContentAt(repoPath, ref, path string) ([]byte, error) {
+	cmd := command("show", ref+":./"+filepath.ToSlash(path))
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git show failed: %w", err)
+	}
+	return output, nil
+}
+
+// PurgeFromHistory rewrites every branch and tag of the repository
+// containing repoPath so that no commit has paths, relative to repoPath.
+// git filter-repo is used when installed, git filter-branch otherwise;
+// either way the old commits are dropped from reflogs and pruned, so the
+// content is gone from the local repository. Remotes still have it until
+// they are force-pushed. The working tree must be clean. It returns the
+// tool used.
+func PurgeFromHistory(repoPath string, paths ...string) (string, error) {
+	top, err := repoTopLevel(repoPath)
+	if err != nil {
+		return "", err
+	}
+	if dirty, err := HasChanges(top); err != nil {
+		return "", err
+	} else if dirty {
+		return "", fmt.Errorf("the repository has uncommitted changes; commit or discard them first")
+	}
+
+	// Paths relative to the top level, as both tools want them
+	prefix, err := filepath.Rel(top, repoPath)
+	if err != nil {
+		return "", fmt.Errorf("locating %s in its repository: %w", repoPath, err)
+	}
+	var topPaths []string
+	for _, p := range paths {
+		topPaths = append(topPaths, filepath.ToSlash(filepath.Join(prefix, p)))
+	}
+
+	if hasFilterRepo() {
+		return PurgeFilterRepo, purgeWithFilterRepo(top, topPaths)
+	}
+	return PurgeFilterBranch, purgeWithFilterBranch(top, topPaths)
+}
+
+// hasFilterRepo reports whether git filter-repo is installed
+func hasFilterRepo() bool {
+	return command("filter-repo", "--version").Run() == nil
+}
+
+// purgeWithFilterRepo removes paths with git filter-repo, which also
+// expires reflogs and prunes. It removes the origin remote to prevent
+// accidental pushes; the remote is put back, pushing is left to the user.
+func purgeWithFilterRepo(top string, paths []string) error {
+	originURL := namedRemoteURL(top, "origin")
+
+	args := []string{"filter-repo", "--force", "--invert-paths"}
+	for _, p := range paths {
+		args = append(args, "--path", p)
+	}
+	if err := runIn(top, nil, args...); err != nil {
+		return err
+	}
+
+	if originURL != "" && namedRemoteURL(top, "origin") == "" {
+		return runIn(top, nil, "remote", "add", "origin", originURL)
+	}
+	return nil
+}
+
+// purgeWithFilterBranch removes paths with git filter-branch, then drops
+// the backup refs it leaves, expires reflogs and prunes
+func purgeWithFilterBranch(top string, paths []string) error {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = "'" + strings.ReplaceAll(p, "'", `'\''`) + "'"
+	}
+	indexFilter := "git rm -r --cached --ignore-unmatch --quiet -- " + strings.Join(quoted, " ")
+
+	env := []string{"FILTER_BRANCH_SQUELCH_WARNING=1"}
+	err := runIn(top, env, "filter-branch", "--force", "--index-filter", indexFilter,
+		"--prune-empty", "--tag-name-filter", "cat", "--", "--all")
+	if err != nil {
+		return err
+	}
+
+	// The rewritten commits are still reachable from refs/original
+	cmd := command("for-each-ref", "--format=%(refname)", "refs/original/")
+	cmd.Dir = top
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("listing backup refs: %w", err)
+	}
+	for _, ref := range strings.Fields(string(output)) {
+		if err := runIn(top, nil, "update-ref", "-d", ref); err != nil {
+			return err
+		}
+	}
+
+	if err := runIn(top, nil, "reflog", "expire", "--expire=now", "--all"); err != nil {
+		return err
+	}
+	return runIn(top, nil, "gc", "--prune=now", "--quiet")
+}
+
+// runIn runs a git command in dir with extra environment variables
+func runIn(dir string, env []string, args ...string) error {
+	cmd := command(args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(cmd.Env, env...)
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s failed: %s: %w", args[0], strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPurgeFromHistory(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tempDir := t.TempDir()
+	if err := InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, tempDir)
+	runGit(t, tempDir, "remote", "add", "origin", "https://example.com/dotfiles.git")
+
+	// The dotfiles live in a subdirectory; the secret is committed, then
+	// changed, then deleted
+	filesDir := filepath.Join(tempDir, "files")
+	secret := filepath.Join(filesDir, "shell", "secrets.sh")
+	if err := os.MkdirAll(filepath.Dir(secret), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, content := range []string{"TOKEN=abc123\n", "TOKEN=def456\n"} {
+		if err := os.WriteFile(secret, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := os.WriteFile(filepath.Join(filesDir, "shell", "zshrc"), []byte("export A=1\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		runGit(t, tempDir, "add", "-A")
+		runGit(t, tempDir, "commit", "-q", "-m", "update secret")
+	}
+	runGit(t, tempDir, "tag", "v1")
+	if err := os.Remove(secret); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, tempDir, "commit", "-q", "-am", "remove secret")
+
+	commits, err := CommitsTouching(filesDir, "shell/secrets.sh")
+	if err != nil || len(commits) != 3 {
+		t.Fatalf("CommitsTouching() = %v, %v, want 3 commits", commits, err)
+	}
+	if commits[0].Message != "remove secret" {
+		t.Errorf("CommitsTouching() newest = %+v", commits[0])
+	}
+	if content, err := ContentAt(filesDir, commits[1].Hash, "shell/secrets.sh"); err != nil || string(content) != "TOKEN=def456\n" {
+		t.Errorf("ContentAt() = %q, %v", content, err)
+	}
+	if _, err := ContentAt(filesDir, commits[0].Hash, "shell/secrets.sh"); err == nil {
+		t.Error("ContentAt() of a deleted file succeeded")
+	}
+	if paths, err := HistoryPaths(filesDir); err != nil || strings.Join(paths, ",") != "shell/secrets.sh,shell/zshrc" {
+		t.Errorf("HistoryPaths() = %v, %v", paths, err)
+	}
+
+	if _, err := PurgeFromHistory(filesDir, "shell/secrets.sh"); err != nil {
+		t.Fatalf("PurgeFromHistory() error = %v", err)
+	}
+
+	if commits, err := CommitsTouching(filesDir, "shell/secrets.sh"); err != nil || len(commits) != 0 {
+		t.Errorf("after purge CommitsTouching() = %v, %v, want none", commits, err)
+	}
+	cmd := exec.Command("git", "log", "--all", "--reflog", "-p")
+	cmd.Dir = tempDir
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(output), "TOKEN=") {
+		t.Error("secret content still reachable after purge")
+	}
+	if !strings.Contains(string(output), "export A=1") {
+		t.Error("other files lost in purge")
+	}
+	if url := namedRemoteURL(tempDir, "origin"); url != "https://example.com/dotfiles.git" {
+		t.Errorf("origin = %q after purge", url)
+	}
+}
+
+func TestPurgeFromHistoryDirty(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tempDir := t.TempDir()
+	if err := InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "a"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := PurgeFromHistory(tempDir, "a"); err == nil {
+		t.Error("PurgeFromHistory() with uncommitted changes succeeded")
+	}
+}
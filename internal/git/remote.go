@@ -0,0 +1,152 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// remoteTestTimeout bounds CheckRemote so an unreachable host doesn't hang
+const remoteTestTimeout = 30 * time.Second
+
+// ValidateRemoteURL checks that url looks like something git can push to:
+// an https, http, ssh, git or file URL, an scp-like user@host:path address,
+// or an absolute local path
+func ValidateRemoteURL(remoteURL string) error {
+	switch {
+	case remoteURL == "":
+		return fmt.Errorf("remote URL is empty")
+	case strings.TrimSpace(remoteURL) != remoteURL || strings.ContainsAny(remoteURL, " \t\n"):
+		return fmt.Errorf("remote URL %q contains whitespace", remoteURL)
+	case strings.HasPrefix(remoteURL, "-"):
+		return fmt.Errorf("remote URL %q cannot start with '-'", remoteURL)
+	}
+
+	if scheme, _, ok := strings.Cut(remoteURL, "://"); ok {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return fmt.Errorf("invalid remote URL %q: %w", remoteURL, err)
+		}
+		switch scheme {
+		case "https", "http", "ssh", "git":
+			if u.Host == "" {
+				return fmt.Errorf("remote URL %q has no host", remoteURL)
+			}
+			if strings.Trim(u.Path, "/") == "" {
+				return fmt.Errorf("remote URL %q has no repository path", remoteURL)
+			}
+		case "file":
+			if u.Path == "" {
+				return fmt.Errorf("remote URL %q has no path", remoteURL)
+			}
+		default:
+			return fmt.Errorf("unsupported remote URL scheme %q (use https, ssh, git or file)", scheme)
+		}
+		return nil
+	}
+
+	if filepath.IsAbs(remoteURL) {
+		return nil
+	}
+
+	// scp-like syntax: [user@]host:path
+	host, path, ok := strings.Cut(remoteURL, ":")
+	if !ok || strings.Contains(host, "/") {
+		return fmt.Errorf("remote URL %q is not a URL, user@host:path address or absolute path", remoteURL)
+	}
+	if _, h, found := strings.Cut(host, "@"); found {
+		host = h
+	}
+	if host == "" {
+		return fmt.Errorf("remote URL %q has no host", remoteURL)
+	}
+	if path == "" {
+		return fmt.Errorf("remote URL %q has no repository path", remoteURL)
+	}
+	return nil
+}
+
+// IsSSHURL reports whether remoteURL is reached over SSH, either as an
+// ssh:// URL or in scp-like user@host:path form
+func IsSSHURL(remoteURL string) bool {
+	if scheme, _, ok := strings.Cut(remoteURL, "://"); ok {
+		return scheme == "ssh" || scheme == "git+ssh"
+	}
+	if filepath.IsAbs(remoteURL) {
+		return false
+	}
+	host, _, ok := strings.Cut(remoteURL, ":")
+	return ok && host != "" && !strings.Contains(host, "/")
+}
+
+// RemoteHost returns the host name of remoteURL, or empty for local paths
+func RemoteHost(remoteURL string) string {
+	if _, _, ok := strings.Cut(remoteURL, "://"); ok {
+		if u, err := url.Parse(remoteURL); err == nil {
+			return u.Hostname()
+		}
+		return ""
+	}
+	if filepath.IsAbs(remoteURL) {
+		return ""
+	}
+	host, _, ok := strings.Cut(remoteURL, ":")
+	if !ok {
+		return ""
+	}
+	if _, h, found := strings.Cut(host, "@"); found {
+		host = h
+	}
+	return host
+}
+
+// RedactURL returns remoteURL with the credentials in its userinfo masked,
+// so it can be logged. An https token is often given as the user name, so
+// only ssh user names are kept.
+func RedactURL(remoteURL string) string {
+	scheme, _, ok := strings.Cut(remoteURL, "://")
+	if !ok {
+		return remoteURL
+	}
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return scheme + "://redacted"
+	}
+	if u.User == nil {
+		return remoteURL
+	}
+	if scheme == "ssh" || scheme == "git+ssh" {
+		u.User = url.User(u.User.Username())
+	} else {
+		u.User = url.User("redacted")
+	}
+	return u.String()
+}
+
+// CheckRemote checks that the remote at remoteURL can be reached and read
+// with git ls-remote. Git and SSH are told not to prompt, so missing
+// credentials fail instead of waiting for input.
+func CheckRemote(remoteURL string) error {
+	ctx, cancel := context.WithTimeout(runCtx, remoteTestTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", "--", remoteURL)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if os.Getenv("GIT_SSH_COMMAND") == "" && os.Getenv("GIT_SSH") == "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s connecting to %s", remoteTestTimeout, remoteURL)
+	}
+	if err != nil {
+		return fmt.Errorf("git ls-remote failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package git
+
+import "sync"
+
+// Repo is a handle on the repository at one path that remembers what it
+// has looked up, so a command asking for the status, branch or remote
+// several times runs git once. Commands that commit, pull or change
+// remotes call Refresh afterwards. A Repo is safe for concurrent use.
+type Repo struct {
+	path string
+
+	mu        sync.Mutex
+	isRepo    *bool
+	status    *StatusInfo
+	remoteURL *string
+}
+
+// OpenRepo returns a handle on the repository at repoPath. Nothing is
+// looked up until it's asked for.
+func OpenRepo(repoPath string) *Repo {
+	return &Repo{path: repoPath}
+}
+
+// Path returns the path the handle was opened with
+func (r *Repo) Path() string {
+	return r.path
+}
+
+// IsRepo checks if the path is a git repository
+func (r *Repo) IsRepo() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isRepo == nil {
+		isRepo := IsRepo(r.path)
+		r.isRepo = &isRepo
+	}
+	return *r.isRepo
+}
+
+// Status returns git status information, as GetStatus does. Errors aren't
+// cached, so a failed lookup is retried on the next call. The returned
+// slices are shared between callers and must not be modified.
+func (r *Repo) Status() (StatusInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.status == nil {
+		status, err := GetStatus(r.path)
+		if err != nil {
+			return status, err
+		}
+		isRepo := true
+		r.status, r.isRepo = &status, &isRepo
+	}
+	return *r.status, nil
+}
+
+// Branch returns the current branch, or empty if HEAD is detached
+func (r *Repo) Branch() (string, error) {
+	status, err := r.Status()
+	return status.Branch, err
+}
+
+// RemoteURL returns the URL of origin, or empty if none is configured
+func (r *Repo) RemoteURL() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.remoteURL != nil {
+		return *r.remoteURL, nil
+	}
+	// The status already knows when there's no origin to look up
+	if r.status != nil && !r.status.RemoteExists {
+		return "", nil
+	}
+
+	url, err := GetRemoteURL(r.path)
+	if err != nil {
+		return "", err
+	}
+	r.remoteURL = &url
+	return url, nil
+}
+
+// Refresh forgets everything looked up so far, for use after the
+// repository has changed
+func (r *Repo) Refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.isRepo, r.status, r.remoteURL = nil, nil, nil
+}
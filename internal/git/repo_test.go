@@ -0,0 +1,81 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoCachesLookups(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tempDir := t.TempDir()
+	repo := OpenRepo(tempDir)
+	if repo.IsRepo() {
+		t.Fatal("IsRepo() should return false before init")
+	}
+
+	// The answer is kept until Refresh
+	if err := InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	if repo.IsRepo() {
+		t.Error("IsRepo() should be cached until Refresh()")
+	}
+	repo.Refresh()
+	if !repo.IsRepo() {
+		t.Error("IsRepo() should return true after Refresh()")
+	}
+
+	status, err := repo.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.HasUncommitted {
+		t.Error("Status() should report a clean new repository")
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "zshrc"), []byte("export EDITOR=vim"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := repo.Status(); status.HasUncommitted {
+		t.Error("Status() should be cached until Refresh()")
+	}
+	repo.Refresh()
+	if status, _ := repo.Status(); !status.HasUncommitted {
+		t.Error("Status() should see the new file after Refresh()")
+	}
+}
+
+func TestRepoRemoteURL(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tempDir := t.TempDir()
+	if err := InitRepo(tempDir); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+
+	repo := OpenRepo(tempDir)
+	if _, err := repo.Status(); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if url, err := repo.RemoteURL(); err != nil || url != "" {
+		t.Errorf("RemoteURL() = %q, %v, want no remote", url, err)
+	}
+
+	remoteURL := "https://github.com/user/dotfiles.git"
+	if err := SetRemote(tempDir, "origin", remoteURL); err != nil {
+		t.Fatalf("SetRemote() error = %v", err)
+	}
+	repo.Refresh()
+	if url, err := repo.RemoteURL(); err != nil || url != remoteURL {
+		t.Errorf("RemoteURL() = %q, %v, want %q", url, err, remoteURL)
+	}
+	if status, _ := repo.Status(); !status.RemoteExists {
+		t.Error("Status() should report the origin remote")
+	}
+}
@@ -0,0 +1,156 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Submodule describes a submodule of the repository, such as a vendored
+// zsh plugin or tmux plugin manager
+type Submodule struct {
+	Path        string // Relative to the repository root
+	Commit      string // Checked-out commit, or the recorded one if not initialized
+	Initialized bool   // Cloned and checked out
+	OutOfDate   bool   // The checked-out commit differs from the one recorded in the repository
+	Dirty       bool   // Changed or untracked files inside the submodule
+	Conflict    bool   // Conflicting commits from a merge
+}
+
+// NeedsAttention reports whether the submodule isn't checked out at the
+// recorded commit, or has changes of its own
+func (s Submodule) NeedsAttention() bool {
+	return !s.Initialized || s.OutOfDate || s.Dirty || s.Conflict
+}
+
+// State describes the submodule in a word or two
+func (s Submodule) State() string {
+	switch {
+	case s.Conflict:
+		return "conflict"
+	case !s.Initialized:
+		return "not initialized"
+	case s.OutOfDate:
+		return "different commit"
+	case s.Dirty:
+		return "modified"
+	}
+	return "ok"
+}
+
+// HasSubmodules reports whether the repository containing repoPath declares
+// submodules in .gitmodules
+func HasSubmodules(repoPath string) bool {
+	top, err := repoTopLevel(repoPath)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(top, ".gitmodules"))
+	return err == nil
+}
+
+// ListSubmodules returns the submodules of the repository containing
+// repoPath, including nested ones
+func ListSubmodules(repoPath string) ([]Submodule, error) {
+	top, err := repoTopLevel(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := command("submodule", "status", "--recursive")
+	cmd.Dir = top
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git submodule status failed: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	var submodules []Submodule
+	for _, line := range strings.Split(string(output), "\n") {
+		// "<state><commit> <path> (<describe>)", state being ' ', '-', '+' or 'U'
+		if len(line) < 2 {
+			continue
+		}
+		fields := strings.Fields(line[1:])
+		if len(fields) < 2 {
+			continue
+		}
+		s := Submodule{
+			Path:        fields[1],
+			Commit:      fields[0],
+			Initialized: line[0] != '-',
+			OutOfDate:   line[0] == '+',
+			Conflict:    line[0] == 'U',
+		}
+		submodules = append(submodules, s)
+	}
+
+	dirty := dirtySubmodules(top)
+	for i := range submodules {
+		submodules[i].Dirty = dirty[submodules[i].Path]
+	}
+	return submodules, nil
+}
+
+// dirtySubmodules returns the top-level submodules with changed or
+// untracked files, from the submodule field of porcelain v2 status
+func dirtySubmodules(top string) map[string]bool {
+	cmd := command("status", "--porcelain=v2", "--ignore-submodules=none")
+	cmd.Dir = top
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	dirty := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		// "1 XY S<c><m><u> mH mI mW hH hI path"
+		fields := strings.SplitN(line, " ", 9)
+		if len(fields) < 9 || fields[0] != "1" || len(fields[2]) != 4 || fields[2][0] != 'S' {
+			continue
+		}
+		if fields[2][2] == 'M' || fields[2][3] == 'U' {
+			dirty[fields[8]] = true
+		}
+	}
+	return dirty
+}
+
+// UpdateSubmodules clones missing submodules and checks every submodule out
+// at the commit recorded in the repository
+func UpdateSubmodules(repoPath string) error {
+	return submoduleUpdate(repoPath, "update", "--init", "--recursive")
+}
+
+// BumpSubmodules moves every submodule to the latest commit of the branch
+// it tracks on its remote. The new commits are left to be committed.
+func BumpSubmodules(repoPath string) error {
+	return submoduleUpdate(repoPath, "update", "--init", "--recursive", "--remote")
+}
+
+// submoduleUpdate runs 'git submodule' with args at the repository root
+func submoduleUpdate(repoPath string, args ...string) error {
+	top, err := repoTopLevel(repoPath)
+	if err != nil {
+		return err
+	}
+
+	cmd := command(append([]string{"submodule"}, args...)...)
+	cmd.Dir = top
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git submodule %s failed: %s: %w", args[0], strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// repoTopLevel returns the root of the working tree containing repoPath
+func repoTopLevel(repoPath string) (string, error) {
+	cmd := command("rev-parse", "--show-toplevel")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("finding repository root: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// runGit runs git with args in dir, failing the test on error
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %s", args, output)
+	}
+}
+
+func TestSubmodules(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+	// Submodules are cloned from local paths here, which git blocks by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	tempDir := t.TempDir()
+
+	// A plugin repository with one commit
+	plugin := filepath.Join(tempDir, "plugin")
+	if err := os.MkdirAll(plugin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitRepo(plugin); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, plugin)
+	if err := os.WriteFile(filepath.Join(plugin, "plugin.zsh"), []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AutoCommit(plugin, "v1"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+
+	// Dotfiles vendoring the plugin
+	dotfiles := filepath.Join(tempDir, "dotfiles")
+	if err := os.MkdirAll(dotfiles, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitRepo(dotfiles); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, dotfiles)
+	if HasSubmodules(dotfiles) {
+		t.Error("HasSubmodules() = true without .gitmodules")
+	}
+	runGit(t, dotfiles, "submodule", "add", plugin, "zsh/plugin")
+	if err := AutoCommit(dotfiles, "Add plugin"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+	if !HasSubmodules(dotfiles) {
+		t.Error("HasSubmodules() = false with .gitmodules")
+	}
+
+	// A fresh clone has the submodule declared but not checked out
+	clone := filepath.Join(tempDir, "clone")
+	if err := Clone(dotfiles, clone); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	submodules, err := ListSubmodules(clone)
+	if err != nil {
+		t.Fatalf("ListSubmodules() error = %v", err)
+	}
+	if len(submodules) != 1 || submodules[0].Path != "zsh/plugin" || submodules[0].Initialized {
+		t.Fatalf("ListSubmodules() before update = %+v, want zsh/plugin not initialized", submodules)
+	}
+
+	if err := UpdateSubmodules(clone); err != nil {
+		t.Fatalf("UpdateSubmodules() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(clone, "zsh", "plugin", "plugin.zsh")); string(data) != "v1" {
+		t.Errorf("plugin.zsh after update = %q, want %q", data, "v1")
+	}
+	submodules, _ = ListSubmodules(clone)
+	if len(submodules) != 1 || submodules[0].NeedsAttention() {
+		t.Fatalf("ListSubmodules() after update = %+v, want ok", submodules)
+	}
+
+	// Local edits inside the submodule
+	if err := os.WriteFile(filepath.Join(clone, "zsh", "plugin", "plugin.zsh"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	submodules, _ = ListSubmodules(clone)
+	if len(submodules) != 1 || !submodules[0].Dirty || submodules[0].State() != "modified" {
+		t.Errorf("ListSubmodules() after edit = %+v, want modified", submodules)
+	}
+	runGit(t, filepath.Join(clone, "zsh", "plugin"), "checkout", "--", "plugin.zsh")
+
+	// A new plugin release is picked up by BumpSubmodules
+	if err := os.WriteFile(filepath.Join(plugin, "plugin.zsh"), []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AutoCommit(plugin, "v2"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+	if err := BumpSubmodules(clone); err != nil {
+		t.Fatalf("BumpSubmodules() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(clone, "zsh", "plugin", "plugin.zsh")); string(data) != "v2" {
+		t.Errorf("plugin.zsh after bump = %q, want %q", data, "v2")
+	}
+	submodules, _ = ListSubmodules(clone)
+	if len(submodules) != 1 || !submodules[0].OutOfDate {
+		t.Errorf("ListSubmodules() after bump = %+v, want a different commit to record", submodules)
+	}
+}
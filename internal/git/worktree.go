@@ -0,0 +1,187 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WorktreeInfo describes how a working directory relates to its repository
+type WorktreeInfo struct {
+	TopLevel  string // Root of the working tree
+	GitDir    string // Git directory for this working tree
+	CommonDir string // Git directory shared by all worktrees of the repository
+	IsLinked  bool   // True for a linked worktree (created with 'git worktree add')
+	MainPath  string // Working tree of the main repository
+}
+
+// GetWorktreeInfo returns worktree information for the repository at repoPath
+func GetWorktreeInfo(repoPath string) (WorktreeInfo, error) {
+	info := WorktreeInfo{}
+
+	cmd := command("rev-parse", "--show-toplevel", "--absolute-git-dir", "--git-common-dir")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return info, fmt.Errorf("git rev-parse failed: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) < 3 {
+		return info, fmt.Errorf("unexpected git rev-parse output: %q", string(output))
+	}
+
+	info.TopLevel = lines[0]
+	info.GitDir = lines[1]
+
+	// --git-common-dir may be relative to the working directory
+	commonDir := lines[2]
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(repoPath, commonDir)
+	}
+	info.CommonDir = filepath.Clean(commonDir)
+
+	info.IsLinked = !samePath(info.GitDir, info.CommonDir)
+	info.MainPath = filepath.Dir(info.CommonDir)
+
+	return info, nil
+}
+
+// IsWorktree checks if repoPath is a linked worktree of another repository
+func IsWorktree(repoPath string) bool {
+	info, err := GetWorktreeInfo(repoPath)
+	if err != nil {
+		return false
+	}
+	return info.IsLinked
+}
+
+// ReadGitLink reads the "gitdir:" pointer from a .git file.
+// Returns the absolute git dir and true if repoPath/.git is a file,
+// or false if it is a directory or missing.
+func ReadGitLink(repoPath string) (string, bool) {
+	gitFile := filepath.Join(repoPath, ".git")
+
+	info, err := os.Lstat(gitFile)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+
+	content, err := os.ReadFile(gitFile)
+	if err != nil {
+		return "", false
+	}
+
+	line := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", false
+	}
+
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repoPath, gitDir)
+	}
+
+	return filepath.Clean(gitDir), true
+}
+
+// CheckWorktreeLink verifies both directions of a linked worktree's linkage:
+// the .git file must point at an existing worktree git dir, and that git dir
+// must point back at this working tree. Returns nil if repoPath is not a
+// linked worktree (e.g. a regular repository or a submodule).
+func CheckWorktreeLink(repoPath string) error {
+	gitDir, ok := ReadGitLink(repoPath)
+	if !ok {
+		return nil
+	}
+
+	if _, err := os.Stat(gitDir); err != nil {
+		return fmt.Errorf("worktree git dir does not exist: %s", gitDir)
+	}
+
+	// Only worktree git dirs have a commondir file (submodules do not)
+	if _, err := os.Stat(filepath.Join(gitDir, "commondir")); err != nil {
+		return nil
+	}
+
+	backLink, err := os.ReadFile(filepath.Join(gitDir, "gitdir"))
+	if err != nil {
+		return fmt.Errorf("reading worktree back-link: %w", err)
+	}
+
+	// The back-link records the path of this worktree's .git file
+	linkedPath := filepath.Dir(strings.TrimSpace(string(backLink)))
+	if !samePath(linkedPath, repoPath) {
+		return fmt.Errorf("worktree is registered at %s, not %s", linkedPath, repoPath)
+	}
+
+	return nil
+}
+
+// GetWorktreeMainPath returns the main repository path for a linked worktree,
+// derived from its .git file. Works even when the linkage is broken.
+func GetWorktreeMainPath(repoPath string) (string, error) {
+	gitDir, ok := ReadGitLink(repoPath)
+	if !ok {
+		return "", fmt.Errorf("not a linked worktree: %s", repoPath)
+	}
+
+	// gitDir is <main>/.git/worktrees/<name>
+	worktreesDir := filepath.Dir(gitDir)
+	if filepath.Base(worktreesDir) != "worktrees" {
+		return "", fmt.Errorf("unexpected worktree git dir: %s", gitDir)
+	}
+
+	return filepath.Dir(filepath.Dir(worktreesDir)), nil
+}
+
+// AddWorktree creates a linked worktree of mainRepo at worktreePath.
+// The branch is checked out if it exists, otherwise it is created.
+func AddWorktree(mainRepo, worktreePath, branch string) error {
+	var args []string
+	if branch == "" {
+		args = []string{"worktree", "add", worktreePath}
+	} else if BranchExists(mainRepo, branch) {
+		args = []string{"worktree", "add", worktreePath, branch}
+	} else {
+		args = []string{"worktree", "add", "-b", branch, worktreePath}
+	}
+
+	cmd := command(args...)
+	cmd.Dir = mainRepo
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree add failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// RepairWorktree repairs the linkage between mainRepo and the worktree at worktreePath
+func RepairWorktree(mainRepo, worktreePath string) error {
+	cmd := command("worktree", "repair", worktreePath)
+	cmd.Dir = mainRepo
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree repair failed: %s: %w", string(output), err)
+	}
+	return nil
+}
+
+// BranchExists checks if a local branch exists
+func BranchExists(repoPath, branch string) bool {
+	cmd := command("rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	cmd.Dir = repoPath
+	return cmd.Run() == nil
+}
+
+// samePath compares two paths after resolving symlinks
+func samePath(a, b string) bool {
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
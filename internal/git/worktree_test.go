@@ -0,0 +1,141 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupWorktree creates a main repo with one commit and a linked worktree
+func setupWorktree(t *testing.T) (mainRepo, worktree string) {
+	t.Helper()
+
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	mainRepo = filepath.Join(tempDir, "main")
+	if err := os.MkdirAll(mainRepo, 0755); err != nil {
+		t.Fatalf("failed to create main repo dir: %v", err)
+	}
+
+	if err := InitRepo(mainRepo); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	configureGitUser(t, mainRepo)
+
+	if err := os.WriteFile(filepath.Join(mainRepo, "README"), []byte("main"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if err := AutoCommit(mainRepo, "initial commit"); err != nil {
+		t.Fatalf("AutoCommit() error = %v", err)
+	}
+
+	worktree = filepath.Join(tempDir, "files")
+	if err := AddWorktree(mainRepo, worktree, "dotfiles"); err != nil {
+		t.Fatalf("AddWorktree() error = %v", err)
+	}
+
+	return mainRepo, worktree
+}
+
+func TestAddWorktree(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	mainRepo, worktree := setupWorktree(t)
+
+	if !IsRepo(worktree) {
+		t.Error("IsRepo() should be true for worktree")
+	}
+	if !IsWorktree(worktree) {
+		t.Error("IsWorktree() should be true for linked worktree")
+	}
+	if IsWorktree(mainRepo) {
+		t.Error("IsWorktree() should be false for main repository")
+	}
+	if !BranchExists(mainRepo, "dotfiles") {
+		t.Error("AddWorktree() should create the branch")
+	}
+}
+
+func TestGetWorktreeInfo(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	mainRepo, worktree := setupWorktree(t)
+
+	info, err := GetWorktreeInfo(worktree)
+	if err != nil {
+		t.Fatalf("GetWorktreeInfo() error = %v", err)
+	}
+
+	if !info.IsLinked {
+		t.Error("GetWorktreeInfo().IsLinked should be true")
+	}
+	if !samePath(info.MainPath, mainRepo) {
+		t.Errorf("GetWorktreeInfo().MainPath = %q, want %q", info.MainPath, mainRepo)
+	}
+	if !samePath(info.TopLevel, worktree) {
+		t.Errorf("GetWorktreeInfo().TopLevel = %q, want %q", info.TopLevel, worktree)
+	}
+}
+
+func TestInitRepoSkipsWorktree(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	_, worktree := setupWorktree(t)
+
+	if err := InitRepo(worktree); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+
+	// .git must still be a link file, not a new repository
+	if _, ok := ReadGitLink(worktree); !ok {
+		t.Error("InitRepo() replaced worktree .git link")
+	}
+}
+
+func TestCheckWorktreeLink(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	mainRepo, worktree := setupWorktree(t)
+
+	if err := CheckWorktreeLink(worktree); err != nil {
+		t.Errorf("CheckWorktreeLink() error = %v", err)
+	}
+
+	// Regular repository is not a linked worktree
+	if err := CheckWorktreeLink(mainRepo); err != nil {
+		t.Errorf("CheckWorktreeLink() on main repo error = %v", err)
+	}
+
+	// Moving the worktree breaks the back-link
+	moved := worktree + "-moved"
+	if err := os.Rename(worktree, moved); err != nil {
+		t.Fatalf("failed to move worktree: %v", err)
+	}
+	if err := CheckWorktreeLink(moved); err == nil {
+		t.Error("CheckWorktreeLink() should fail for moved worktree")
+	}
+
+	// Repair restores the linkage
+	main, err := GetWorktreeMainPath(moved)
+	if err != nil {
+		t.Fatalf("GetWorktreeMainPath() error = %v", err)
+	}
+	if err := RepairWorktree(main, moved); err != nil {
+		t.Fatalf("RepairWorktree() error = %v", err)
+	}
+	if err := CheckWorktreeLink(moved); err != nil {
+		t.Errorf("CheckWorktreeLink() after repair error = %v", err)
+	}
+}
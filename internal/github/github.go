@@ -0,0 +1,183 @@
+// Package github is a minimal client for the parts of the GitHub REST API
+// dotcor uses to create a repository for your dotfiles and to look up its
+// own releases.
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the GitHub REST API endpoint
+const DefaultAPIURL = "https://api.github.com"
+
+// ErrRepoExists is returned by CreateRepo when the account already has a
+// repository with that name
+var ErrRepoExists = errors.New("repository already exists")
+
+// Client calls the GitHub API with a personal access token
+type Client struct {
+	Token      string
+	BaseURL    string // Defaults to DefaultAPIURL
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for api.github.com authenticated with token
+func NewClient(token string) *Client {
+	return &Client{
+		Token:      token,
+		BaseURL:    DefaultAPIURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// User is a GitHub account
+type User struct {
+	Login string `json:"login"`
+}
+
+// Repository is a GitHub repository
+type Repository struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+	SSHURL   string `json:"ssh_url"`
+	CloneURL string `json:"clone_url"`
+	Private  bool   `json:"private"`
+}
+
+// Release is a published GitHub release
+type Release struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// APIError is an error response from the GitHub API
+type APIError struct {
+	StatusCode int
+	Message    string `json:"message"`
+	Errors     []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	for _, detail := range e.Errors {
+		if detail.Message != "" {
+			msg += ": " + detail.Message
+		}
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("GitHub API: %s (HTTP %d)", msg, e.StatusCode)
+}
+
+// CurrentUser returns the account the token belongs to
+func (c *Client) CurrentUser() (User, error) {
+	var user User
+	err := c.do(http.MethodGet, "/user", nil, &user)
+	return user, err
+}
+
+// GetRepo returns the repository owner/name
+func (c *Client) GetRepo(owner, name string) (Repository, error) {
+	var repo Repository
+	err := c.do(http.MethodGet, "/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name), nil, &repo)
+	return repo, err
+}
+
+// CreateRepo creates a repository owned by the authenticated user. It
+// returns ErrRepoExists if the name is taken.
+func (c *Client) CreateRepo(name, description string, private bool) (Repository, error) {
+	body := map[string]any{
+		"name":        name,
+		"description": description,
+		"private":     private,
+		"auto_init":   false,
+	}
+
+	var repo Repository
+	err := c.do(http.MethodPost, "/user/repos", body, &repo)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity &&
+		strings.Contains(apiErr.Error(), "already exists") {
+		return repo, fmt.Errorf("%s: %w", name, ErrRepoExists)
+	}
+	return repo, err
+}
+
+// LatestRelease returns the newest published release of owner/name
+func (c *Client) LatestRelease(owner, name string) (Release, error) {
+	var release Release
+	err := c.do(http.MethodGet, "/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name)+"/releases/latest", nil, &release)
+	return release, err
+}
+
+// do sends a request with an optional JSON body and decodes a JSON response
+// into out. Non-2xx responses become an *APIError.
+func (c *Client) do(method, path string, body, out any) error {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultAPIURL
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(base, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "dotcor")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling GitHub API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading GitHub API response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(data, apiErr)
+		return apiErr
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("decoding GitHub API response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret-token")
+	client.BaseURL = server.URL
+	return client
+}
+
+func TestCreateRepo(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/user/repos" {
+			t.Errorf("request = %s %s, want POST /user/repos", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["name"] != "dotfiles" || body["private"] != true {
+			t.Errorf("body = %v, want private dotfiles", body)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Repository{
+			FullName: "you/dotfiles",
+			SSHURL:   "git@github.com:you/dotfiles.git",
+			CloneURL: "https://github.com/you/dotfiles.git",
+			Private:  true,
+		})
+	})
+
+	repo, err := client.CreateRepo("dotfiles", "My dotfiles", true)
+	if err != nil {
+		t.Fatalf("CreateRepo() error = %v", err)
+	}
+	if repo.FullName != "you/dotfiles" || repo.SSHURL != "git@github.com:you/dotfiles.git" || !repo.Private {
+		t.Errorf("CreateRepo() = %+v", repo)
+	}
+}
+
+func TestCreateRepoExists(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message":"Repository creation failed.","errors":[{"resource":"Repository","field":"name","message":"name already exists on this account"}]}`))
+	})
+
+	_, err := client.CreateRepo("dotfiles", "", true)
+	if !errors.Is(err, ErrRepoExists) {
+		t.Errorf("CreateRepo() error = %v, want ErrRepoExists", err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("path = %s, want /user", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"Bad credentials"}`))
+	})
+
+	_, err := client.CurrentUser()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("CurrentUser() error = %v, want 401 APIError", err)
+	}
+	if got, want := err.Error(), "GitHub API: Bad credentials (HTTP 401)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/justincordova/dotcor/releases/latest" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(Release{TagName: "v0.2.0", HTMLURL: "https://github.com/justincordova/dotcor/releases/tag/v0.2.0"})
+	})
+
+	release, err := client.LatestRelease("justincordova", "dotcor")
+	if err != nil {
+		t.Fatalf("LatestRelease() error = %v", err)
+	}
+	if release.TagName != "v0.2.0" {
+		t.Errorf("TagName = %q, want v0.2.0", release.TagName)
+	}
+}
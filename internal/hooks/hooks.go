@@ -0,0 +1,81 @@
+package hooks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// Context describes what triggered a hook. It is passed to hook commands
+// as DOTCOR_* environment variables.
+type Context struct {
+	Event      string // Hook event, e.g. config.HookPostSync
+	RepoPath   string // Files root of the repository
+	SourcePath string // Expanded dotfile path, for per-file hooks
+	RepoFile   string // Repo path of the file, for per-file hooks
+}
+
+// Environ returns the environment for a hook command
+func (c Context) Environ() []string {
+	env := append(os.Environ(),
+		"DOTCOR_HOOK="+c.Event,
+		"DOTCOR_REPO="+c.RepoPath,
+	)
+	if c.SourcePath != "" {
+		env = append(env, "DOTCOR_FILE="+c.SourcePath, "DOTCOR_REPO_FILE="+c.RepoFile)
+	}
+	return env
+}
+
+// Run runs a single hook command through the shell from the home directory.
+// Output goes to out so hooks can report progress.
+func Run(command string, ctx Context, out io.Writer) error {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	if home, err := config.HomeDir(); err == nil {
+		cmd.Dir = home
+	}
+	cmd.Env = ctx.Environ()
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("hook %q failed: %w", command, err)
+	}
+	return nil
+}
+
+// FileContext returns the hook context for a managed file
+func FileContext(cfg *config.Config, event string, mf config.ManagedFile) (Context, error) {
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil {
+		return Context{}, err
+	}
+
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return Context{}, err
+	}
+
+	return Context{
+		Event:      event,
+		RepoPath:   repoPath,
+		SourcePath: sourcePath,
+		RepoFile:   mf.RepoPath,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test use sh syntax")
+	}
+
+	dir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := Context{
+		Event:      "post_sync",
+		RepoPath:   dir,
+		SourcePath: "/home/me/.tmux.conf",
+		RepoFile:   "tmux/tmux.conf",
+	}
+
+	output := filepath.Join(dir, "env.txt")
+	command := `echo "$DOTCOR_HOOK $DOTCOR_FILE $DOTCOR_REPO_FILE" > ` + output
+	if err := Run(command, ctx, os.Stdout); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "post_sync /home/me/.tmux.conf tmux/tmux.conf"
+	if strings.TrimSpace(string(got)) != want {
+		t.Errorf("hook environment = %q, want %q", strings.TrimSpace(string(got)), want)
+	}
+
+	if err := Run("exit 3", ctx, os.Stdout); err == nil {
+		t.Error("Run() should return an error when the command fails")
+	}
+
+	if err := Run("  ", ctx, os.Stdout); err != nil {
+		t.Errorf("Run() with empty command error = %v", err)
+	}
+}
+
+func TestContextEnvironOmitsFileForGlobalHooks(t *testing.T) {
+	env := Context{Event: "pre_add", RepoPath: "/repo"}.Environ()
+
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "DOTCOR_FILE=") {
+			t.Errorf("global hook environment should not set DOTCOR_FILE, got %s", kv)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package lint checks dotfiles for mistakes before they're committed, with a
+// linter per file type: shellcheck for bash and sh startup files, zsh -n for
+// zsh ones, git's own parser for gitconfig, and syntax checks for TOML and
+// YAML. Linters for other file types are configured in the lint section of
+// config.yaml.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// Finding severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is one problem a linter reported
+type Finding struct {
+	Linter   string `json:"linter" yaml:"linter"`
+	Severity string `json:"severity" yaml:"severity"`
+	Line     int    `json:"line,omitempty" yaml:"line,omitempty"` // 0 if the linter didn't say
+	Message  string `json:"message" yaml:"message"`
+}
+
+// Linter checks one type of file
+type Linter struct {
+	Name    string                               // Used in findings and the lint.disable setting
+	Command string                               // Executable the linter runs, "" for built-in checks
+	Match   func(repoPath string) bool           // Whether the linter handles the file at repoPath
+	Run     func(path string) ([]Finding, error) // Lints the file at path
+}
+
+// Linters is every built-in linter
+var Linters = []Linter{shellcheck, zshSyntax, gitconfig, tomlSyntax, yamlSyntax}
+
+// Available reports whether the linter's command is installed
+func (l Linter) Available() bool {
+	if l.Command == "" {
+		return true
+	}
+	_, err := exec.LookPath(l.Command)
+	return err == nil
+}
+
+// Set is the linters in use: the built-in ones not disabled, followed by
+// those configured in config.yaml
+type Set struct {
+	linters []Linter
+}
+
+// NewSet returns the linters selected by cfg, which config.ValidateLintConfig
+// has accepted
+func NewSet(cfg config.LintConfig) *Set {
+	disabled := map[string]bool{}
+	for _, name := range cfg.Disable {
+		disabled[name] = true
+	}
+
+	s := &Set{}
+	for _, l := range Linters {
+		if !disabled[l.Name] {
+			s.linters = append(s.linters, l)
+		}
+	}
+	for _, c := range cfg.Linters {
+		if disabled[c.Name] {
+			continue
+		}
+		s.linters = append(s.linters, commandLinter(c))
+	}
+	return s
+}
+
+// For returns the linters that handle the file at repoPath
+func (s *Set) For(repoPath string) []Linter {
+	var matched []Linter
+	for _, l := range s.linters {
+		if l.Match(repoPath) {
+			matched = append(matched, l)
+		}
+	}
+	return matched
+}
+
+// Result is the outcome of linting one file
+type Result struct {
+	Path     string    `json:"path" yaml:"path"`
+	Findings []Finding `json:"findings,omitempty" yaml:"findings,omitempty"`
+	Skipped  []string  `json:"skipped,omitempty" yaml:"skipped,omitempty"` // Linters that couldn't run, e.g. "shellcheck (not installed)"
+}
+
+// Errors counts the findings at the error level
+func (r Result) Errors() int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// Lint runs every linter that handles repoPath on the file at file. A
+// linter that isn't installed is skipped rather than failing the file.
+func (s *Set) Lint(file, repoPath string) (Result, error) {
+	result := Result{Path: repoPath}
+	for _, l := range s.For(repoPath) {
+		if !l.Available() {
+			result.Skipped = append(result.Skipped, l.Name+" (not installed)")
+			continue
+		}
+		findings, err := l.Run(file)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", l.Name, err)
+		}
+		for i := range findings {
+			findings[i].Linter = l.Name
+		}
+		result.Findings = append(result.Findings, findings...)
+	}
+	return result, nil
+}
+
+// baseName returns the file name of repoPath, lowercased and without a
+// leading dot, so "shell/.bashrc" and "shell/bashrc" both give "bashrc"
+func baseName(repoPath string) string {
+	return strings.TrimPrefix(strings.ToLower(path.Base(filepath.ToSlash(repoPath))), ".")
+}
+
+// matchNames returns a Match func for files with one of names (as given by
+// baseName) or one of the extensions exts
+func matchNames(names []string, exts ...string) func(string) bool {
+	return func(repoPath string) bool {
+		base := baseName(repoPath)
+		for _, name := range names {
+			if base == name {
+				return true
+			}
+		}
+		for _, ext := range exts {
+			if strings.HasSuffix(base, ext) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// commandLinter returns a linter running a command configured in the lint
+// section, with the file to lint as its last argument. The file fails if
+// the command exits non-zero, with the command's output as the message.
+func commandLinter(c config.LintCommand) Linter {
+	args := strings.Fields(c.Command)
+	return Linter{
+		Name:    c.Name,
+		Command: args[0],
+		Match: func(repoPath string) bool {
+			repoPath = filepath.ToSlash(repoPath)
+			for _, pattern := range c.Files {
+				if ok, _ := path.Match(pattern, path.Base(repoPath)); ok {
+					return true
+				}
+				if ok, _ := path.Match(pattern, repoPath); ok {
+					return true
+				}
+			}
+			return false
+		},
+		Run: func(file string) ([]Finding, error) {
+			out, err := exec.Command(args[0], append(args[1:], file)...).CombinedOutput()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				msg := strings.TrimSpace(string(out))
+				if msg == "" {
+					msg = fmt.Sprintf("exited with status %d", exitErr.ExitCode())
+				}
+				return []Finding{{Severity: SeverityError, Message: msg}}, nil
+			}
+			return nil, err
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// writeFile writes content to name in a temp directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSetFor(t *testing.T) {
+	s := NewSet(config.LintConfig{})
+	tests := []struct {
+		repoPath string
+		want     string
+	}{
+		{"shell/bashrc", "shellcheck"},
+		{"shell/.bash_profile", "shellcheck"},
+		{"bin/backup.sh", "shellcheck"},
+		{"shell/zshrc", "zsh"},
+		{"git/gitconfig", "gitconfig"},
+		{"config/git/config", "gitconfig"},
+		{"config/starship.toml", "toml"},
+		{"config/alacritty.yml", "yaml"},
+		{"vim/vimrc", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if linters := s.For(tt.repoPath); len(linters) > 0 {
+			got = linters[0].Name
+		}
+		if got != tt.want {
+			t.Errorf("For(%q) = %q, want %q", tt.repoPath, got, tt.want)
+		}
+	}
+
+	disabled := NewSet(config.LintConfig{Disable: []string{"toml"}})
+	if linters := disabled.For("config/starship.toml"); len(linters) != 0 {
+		t.Errorf("disabled linter still used: %v", linters[0].Name)
+	}
+}
+
+func TestLintSyntax(t *testing.T) {
+	s := NewSet(config.LintConfig{})
+	tests := []struct {
+		name     string
+		content  string
+		wantLine int // 0 for no findings
+	}{
+		{"starship.toml", "[character]\nsymbol = \"➜\"\n", 0},
+		{"starship.toml", "[character]\nsymbol = \n", 2},
+		{"config.yaml", "a: 1\n---\nb: [2, 3]\n", 0},
+		{"config.yaml", "a: 1\nb: [2, 3\nc: 4\n", 3},
+	}
+	for _, tt := range tests {
+		result, err := s.Lint(writeFile(t, tt.name, tt.content), tt.name)
+		if err != nil {
+			t.Fatalf("Lint(%s) error = %v", tt.name, err)
+		}
+		if tt.wantLine == 0 {
+			if len(result.Findings) > 0 {
+				t.Errorf("Lint(%q) = %+v, want no findings", tt.content, result.Findings)
+			}
+			continue
+		}
+		if result.Errors() != 1 || result.Findings[0].Line == 0 {
+			t.Errorf("Lint(%q) = %+v, want one error with a line", tt.content, result.Findings)
+		}
+	}
+}
+
+func TestLintGitconfig(t *testing.T) {
+	s := NewSet(config.LintConfig{})
+	if !gitconfig.Available() {
+		t.Skip("git not installed")
+	}
+
+	result, err := s.Lint(writeFile(t, "gitconfig", "[user]\n\tname = Me\n"), "git/gitconfig")
+	if err != nil || len(result.Findings) != 0 {
+		t.Errorf("Lint(valid gitconfig) = %+v, %v", result, err)
+	}
+
+	result, err = s.Lint(writeFile(t, "gitconfig", "[user]\n\tname = Me\n[broken\n"), "git/gitconfig")
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+	if result.Errors() != 1 || result.Findings[0].Line != 3 || result.Findings[0].Linter != "gitconfig" {
+		t.Errorf("Lint(broken gitconfig) = %+v, want an error on line 3", result.Findings)
+	}
+}
+
+func TestLintSkipsMissingCommands(t *testing.T) {
+	s := NewSet(config.LintConfig{Linters: []config.LintCommand{
+		{Name: "luacheck", Command: "dotcor-no-such-linter", Files: []string{"*.lua"}},
+	}})
+	result, err := s.Lint(writeFile(t, "init.lua", "x = 1"), "nvim/init.lua")
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+	if len(result.Skipped) != 1 || len(result.Findings) != 0 {
+		t.Errorf("Lint() = %+v, want luacheck skipped", result)
+	}
+}
+
+func TestCommandLinter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses test(1)")
+	}
+	// test -s fails on empty files
+	s := NewSet(config.LintConfig{Linters: []config.LintCommand{
+		{Name: "nonempty", Command: "test -s", Files: []string{"*.conf", "tmux/*"}},
+	}})
+
+	result, err := s.Lint(writeFile(t, "app.conf", ""), "app/app.conf")
+	if err != nil || result.Errors() != 1 || result.Findings[0].Linter != "nonempty" {
+		t.Errorf("Lint(empty file) = %+v, %v, want an error", result, err)
+	}
+	result, err = s.Lint(writeFile(t, "tmux.conf", "set -g mouse on"), "tmux/tmux.conf")
+	if err != nil || len(result.Findings) != 0 {
+		t.Errorf("Lint(non-empty file) = %+v, %v, want no findings", result, err)
+	}
+}
+
+func TestParseGCCOutput(t *testing.T) {
+	out := []byte(`bashrc:3:5: warning: foo is referenced but not assigned. [SC2154]
+bashrc:7:1: error: Couldn't parse this if expression. [SC1073]
+bashrc:9:1: note: Double quote to prevent globbing. [SC2086]
+`)
+	findings := parseGCCOutput(out)
+	if len(findings) != 2 {
+		t.Fatalf("parseGCCOutput() = %+v, want 2 findings without the note", findings)
+	}
+	if findings[0].Line != 3 || findings[0].Severity != SeverityWarning {
+		t.Errorf("findings[0] = %+v", findings[0])
+	}
+	if findings[1].Line != 7 || findings[1].Severity != SeverityError || findings[1].Message != "Couldn't parse this if expression. [SC1073]" {
+		t.Errorf("findings[1] = %+v", findings[1])
+	}
+}
@@ -0,0 +1,216 @@
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Startup files by shell. Bash files are checked as bash and the rest as
+// POSIX sh; shellcheck doesn't support zsh.
+var (
+	bashFiles = []string{"bashrc", "bash_profile", "bash_login", "bash_logout", "bash_aliases"}
+	shFiles   = []string{"profile"}
+	zshFiles  = []string{"zshrc", "zshenv", "zprofile", "zlogin", "zlogout"}
+)
+
+// shellcheck runs shellcheck on bash and sh startup files and scripts
+var shellcheck = Linter{
+	Name:    "shellcheck",
+	Command: "shellcheck",
+	Match:   matchNames(append(bashFiles, shFiles...), ".sh", ".bash"),
+	Run: func(file string) ([]Finding, error) {
+		shell := "sh"
+		if matchNames(bashFiles, ".bash")(file) {
+			shell = "bash"
+		}
+		out, err := exec.Command("shellcheck", "--format=gcc", "--severity=warning", "--shell="+shell, file).Output()
+		// Exit status 1 means findings; anything else is shellcheck failing
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return nil, commandError(err)
+		}
+		return parseGCCOutput(out), nil
+	},
+}
+
+// gccLine is a line of shellcheck's gcc format output,
+// "file:line:column: severity: message [SCnnnn]"
+var gccLine = regexp.MustCompile(`^.*?:(\d+):\d+: (\w+): (.*)$`)
+
+// parseGCCOutput returns the findings in shellcheck's gcc format output.
+// Notes are dropped; shellcheck errors are errors and the rest warnings.
+func parseGCCOutput(out []byte) []Finding {
+	var findings []Finding
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := gccLine.FindStringSubmatch(scanner.Text())
+		if m == nil || m[2] == "note" {
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		severity := SeverityWarning
+		if m[2] == SeverityError {
+			severity = SeverityError
+		}
+		findings = append(findings, Finding{Severity: severity, Line: line, Message: m[3]})
+	}
+	return findings
+}
+
+// zshSyntax checks zsh startup files with zsh's own parser
+var zshSyntax = Linter{
+	Name:    "zsh",
+	Command: "zsh",
+	Match:   matchNames(zshFiles, ".zsh"),
+	Run: func(file string) ([]Finding, error) {
+		out, err := exec.Command("zsh", "-n", file).CombinedOutput()
+		var exitErr *exec.ExitError
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.As(err, &exitErr) {
+			return nil, err
+		}
+		return parsePrefixedErrors(out, file), nil
+	},
+}
+
+// prefixedLine is an error from zsh, "file:line: message"
+var prefixedLine = regexp.MustCompile(`^.*?:(\d+): (.*)$`)
+
+// parsePrefixedErrors returns each line of out as an error finding, with
+// the line number when the line starts with "file:line:"
+func parsePrefixedErrors(out []byte, file string) []Finding {
+	var findings []Finding
+	for _, text := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		finding := Finding{Severity: SeverityError, Message: text}
+		if m := prefixedLine.FindStringSubmatch(strings.TrimPrefix(text, file)); m != nil {
+			finding.Line, _ = strconv.Atoi(m[1])
+			finding.Message = m[2]
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// gitconfig parses gitconfig files with git, which refuses to run with a
+// broken ~/.gitconfig
+var gitconfig = Linter{
+	Name:    "gitconfig",
+	Command: "git",
+	Match: func(repoPath string) bool {
+		repoPath = filepath.ToSlash(repoPath)
+		if matchNames([]string{"gitconfig"}, ".gitconfig")(repoPath) {
+			return true
+		}
+		// ~/.config/git/config
+		return path.Base(repoPath) == "config" && path.Base(path.Dir(repoPath)) == "git"
+	},
+	Run: func(file string) ([]Finding, error) {
+		cmd := exec.Command("git", "config", "--file", file, "--list")
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.As(err, &exitErr) {
+			return nil, err
+		}
+
+		msg := strings.TrimPrefix(strings.TrimSpace(stderr.String()), "fatal: ")
+		finding := Finding{Severity: SeverityError, Message: msg}
+		if m := gitConfigLine.FindStringSubmatch(msg); m != nil {
+			finding.Line, _ = strconv.Atoi(m[1])
+		}
+		return []Finding{finding}, nil
+	},
+}
+
+// gitConfigLine finds the line in git's "bad config line 3 in file ..."
+var gitConfigLine = regexp.MustCompile(`line (\d+)`)
+
+// tomlSyntax checks that TOML files parse, e.g. starship.toml and
+// alacritty.toml
+var tomlSyntax = Linter{
+	Name:  "toml",
+	Match: matchNames(nil, ".toml"),
+	Run: func(file string) ([]Finding, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var v map[string]any
+		err = toml.Unmarshal(data, &v)
+		if err == nil {
+			return nil, nil
+		}
+		finding := Finding{Severity: SeverityError, Message: strings.TrimPrefix(err.Error(), "toml: ")}
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			finding.Line, _ = decodeErr.Position()
+		}
+		return []Finding{finding}, nil
+	},
+}
+
+// yamlSyntax checks that every document in YAML files parses
+var yamlSyntax = Linter{
+	Name:  "yaml",
+	Match: matchNames(nil, ".yaml", ".yml"),
+	Run: func(file string) ([]Finding, error) {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		dec := yaml.NewDecoder(f)
+		for {
+			var node yaml.Node
+			err := dec.Decode(&node)
+			if err == io.EOF {
+				return nil, nil
+			}
+			if err != nil {
+				finding := Finding{Severity: SeverityError, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+				if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+					finding.Line, _ = strconv.Atoi(m[1])
+					finding.Message = m[2]
+				}
+				return []Finding{finding}, nil
+			}
+		}
+	},
+}
+
+// yamlLine splits yaml.v3's "yaml: line 3: message"
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// commandError returns err with the command's stderr, if any
+func commandError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		msg, _, _ := strings.Cut(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+		return errors.New(msg)
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+package lint
+
+import (
+	"bytes"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// homePath finds home directories written out in full, /Users/<name> on
+// macOS and /home/<name> on Linux, that aren't part of a longer path
+var homePath = regexp.MustCompile(`(^|[^\w./-])((?:/Users|/home)/([\w.-]+))`)
+
+// notUserHomes are directories under /Users and /home that aren't a user's
+// home, so they're the same on every machine
+var notUserHomes = map[string]bool{
+	"Shared":    true, // /Users/Shared on macOS
+	"linuxbrew": true, // Homebrew on Linux
+}
+
+// Suggestions for a hardcoded home directory, by file type
+const (
+	suggestTemplate  = "{{ .Home }}"
+	suggestShell     = "$HOME"
+	suggestGitconfig = "~"
+	suggestOther     = "a template using {{ .Home }}"
+)
+
+// HomePath is a home directory hardcoded in a file, which won't exist on a
+// machine with another user name or OS
+type HomePath struct {
+	Line    int    `json:"line" yaml:"line"`
+	Path    string `json:"path" yaml:"path"`       // e.g. /Users/jane
+	Suggest string `json:"suggest" yaml:"suggest"` // What to write instead
+	Fixable bool   `json:"fixable" yaml:"fixable"` // FixHomePaths replaces it with Suggest
+}
+
+// FindHomePaths returns the hardcoded home directories in content, the
+// content of the repo file at repoPath. Templates are pointed to
+// {{ .Home }}, shell files to $HOME and gitconfig to ~. Only the user's own
+// home directory is fixable, and only where the replacement means the
+// same: not inside single quotes in shell files, and only at the start of
+// a value in gitconfig.
+func FindHomePaths(repoPath string, content []byte, user string) []HomePath {
+	var found []HomePath
+	scanHomePaths(repoPath, content, user, func(line, _ int, h HomePath) {
+		h.Line = line
+		found = append(found, h)
+	})
+	return found
+}
+
+// FixHomePaths returns content with the fixable home directories found by
+// FindHomePaths replaced, and how many were replaced
+func FixHomePaths(repoPath string, content []byte, user string) ([]byte, int) {
+	type fix struct {
+		start, end int
+		with       string
+	}
+	fixes := map[int][]fix{}
+	fixed := 0
+	scanHomePaths(repoPath, content, user, func(line, start int, h HomePath) {
+		if !h.Fixable {
+			return
+		}
+		fixes[line-1] = append(fixes[line-1], fix{start, start + len(h.Path), h.Suggest})
+		fixed++
+	})
+	if fixed == 0 {
+		return content, 0
+	}
+
+	// Replace from the end of each line, so earlier offsets still hold
+	var out bytes.Buffer
+	for i, line := range bytes.SplitAfter(content, []byte("\n")) {
+		fs := fixes[i]
+		for j := len(fs) - 1; j >= 0; j-- {
+			f := fs[j]
+			line = append(append(append([]byte{}, line[:f.start]...), f.with...), line[f.end:]...)
+		}
+		out.Write(line)
+	}
+	return out.Bytes(), fixed
+}
+
+// scanHomePaths calls found for each hardcoded home directory in content,
+// with its 1-based line and its offset in the line
+func scanHomePaths(repoPath string, content []byte, user string, found func(line, start int, h HomePath)) {
+	kind := fileKind(repoPath)
+	for i, line := range bytes.SplitAfter(content, []byte("\n")) {
+		for _, m := range homePath.FindAllSubmatchIndex(line, -1) {
+			start, end := m[4], m[5]
+			name := string(line[m[6]:m[7]])
+			if notUserHomes[name] {
+				continue
+			}
+
+			h := HomePath{Path: string(line[start:end]), Suggest: suggestOther}
+			switch kind {
+			case suggestTemplate:
+				h.Suggest, h.Fixable = suggestTemplate, true
+			case suggestShell:
+				h.Suggest, h.Fixable = suggestShell, !inSingleQuotes(line[:start])
+			case suggestGitconfig:
+				h.Suggest, h.Fixable = suggestGitconfig, atValueStart(line[:start])
+			}
+			h.Fixable = h.Fixable && name == user
+			found(i+1, start, h)
+		}
+	}
+}
+
+// fileKind returns the suggestion for the type of the file at repoPath:
+// suggestTemplate, suggestShell, suggestGitconfig or suggestOther
+func fileKind(repoPath string) string {
+	switch {
+	case strings.HasSuffix(repoPath, config.TemplateExt):
+		return suggestTemplate
+	case shellcheck.Match(repoPath) || zshSyntax.Match(repoPath):
+		return suggestShell
+	case gitconfig.Match(repoPath):
+		return suggestGitconfig
+	}
+	return suggestOther
+}
+
+// inSingleQuotes reports whether a shell line continuing after before is
+// inside single quotes, where $HOME isn't expanded
+func inSingleQuotes(before []byte) bool {
+	return bytes.Count(before, []byte("'"))%2 == 1
+}
+
+// atValueStart reports whether a gitconfig line continuing after before is
+// at the start of a value, where git expands ~ in paths
+func atValueStart(before []byte) bool {
+	trimmed := bytes.TrimRight(before, " \t\"")
+	return bytes.HasSuffix(trimmed, []byte("="))
+}
+
+// UserName returns the name of the current user's home directory, the
+// <name> in /Users/<name> and /home/<name>
+func UserName() (string, error) {
+	home, err := config.HomeDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Base(filepath.ToSlash(home)), nil
+}
@@ -0,0 +1,61 @@
+package lint
+
+import (
+	"testing"
+)
+
+func TestFindHomePaths(t *testing.T) {
+	tests := []struct {
+		repoPath    string
+		content     string
+		wantPath    string // "" for no findings
+		wantSuggest string
+		wantFixable bool
+	}{
+		{"shell/zshrc", "export PATH=/Users/jane/bin:$PATH\n", "/Users/jane", suggestShell, true},
+		{"shell/bashrc", "alias notes='cd /home/jane/notes'\n", "/home/jane", suggestShell, false},
+		{"shell/bashrc", "source /home/bob/.aliases\n", "/home/bob", suggestShell, false},
+		{"git/gitconfig", "[core]\n\texcludesfile = /home/jane/.gitignore\n", "/home/jane", suggestGitconfig, true},
+		{"git/gitconfig", "[alias]\n\tnotes = !cd /home/jane/notes\n", "/home/jane", suggestGitconfig, false},
+		{"ssh/config.tmpl", "IdentityFile /Users/jane/.ssh/id_ed25519\n", "/Users/jane", suggestTemplate, true},
+		{"vim/vimrc", "set undodir=/Users/jane/.vim/undo\n", "/Users/jane", suggestOther, false},
+		{"shell/zshrc", "export PATH=/home/linuxbrew/.linuxbrew/bin:$PATH\n", "", "", false},
+		{"shell/zshrc", "cp /Users/Shared/file .\n", "", "", false},
+		{"shell/zshrc", "ls /mnt/home/jane\n", "", "", false},
+	}
+	for _, tt := range tests {
+		found := FindHomePaths(tt.repoPath, []byte(tt.content), "jane")
+		if tt.wantPath == "" {
+			if len(found) > 0 {
+				t.Errorf("FindHomePaths(%q) = %+v, want none", tt.content, found)
+			}
+			continue
+		}
+		if len(found) != 1 {
+			t.Errorf("FindHomePaths(%q) = %+v, want one", tt.content, found)
+			continue
+		}
+		h := found[0]
+		if h.Path != tt.wantPath || h.Suggest != tt.wantSuggest || h.Fixable != tt.wantFixable {
+			t.Errorf("FindHomePaths(%s, %q) = %+v, want %s, %s, fixable %v", tt.repoPath, tt.content, h, tt.wantPath, tt.wantSuggest, tt.wantFixable)
+		}
+	}
+}
+
+func TestFixHomePaths(t *testing.T) {
+	content := "export PATH=/home/jane/bin:/home/jane/.local/bin:$PATH\nalias n='vim /home/jane/notes'\nsource /home/bob/.aliases\n"
+	want := "export PATH=$HOME/bin:$HOME/.local/bin:$PATH\nalias n='vim /home/jane/notes'\nsource /home/bob/.aliases\n"
+
+	fixed, n := FixHomePaths("shell/bashrc", []byte(content), "jane")
+	if string(fixed) != want || n != 2 {
+		t.Errorf("FixHomePaths() = %q, %d, want %q, 2", fixed, n, want)
+	}
+	if left := FindHomePaths("shell/bashrc", fixed, "jane"); len(left) != 2 {
+		t.Errorf("FindHomePaths(fixed) = %+v, want the 2 unfixable paths", left)
+	}
+
+	fixed, n = FixHomePaths("zed/settings.json.tmpl", []byte(`{"dir": "/Users/jane/code"}`), "jane")
+	if string(fixed) != `{"dir": "{{ .Home }}/code"}` || n != 1 {
+		t.Errorf("FixHomePaths(template) = %q, %d", fixed, n)
+	}
+}
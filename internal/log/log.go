@@ -0,0 +1,197 @@
+// Package log records what dotcor does: leveled, structured messages on
+// stderr with --verbose or --debug, and a persistent log file of mutating
+// operations, so a broken apply can be reconstructed afterwards.
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Log file defaults
+const (
+	DefaultMaxSize  = 1 << 20 // Rotate the log file once it reaches 1 MiB
+	DefaultMaxFiles = 3       // Rotated log files kept: dotcor.log.1 to .3
+)
+
+// Options configures logging for a run
+type Options struct {
+	Verbose  bool   // Info and above on stderr
+	Debug    bool   // Debug and above on stderr and in the log file
+	File     string // Log file path, empty for none
+	JSON     bool   // Write the log file as JSON lines instead of text
+	MaxSize  int64  // Rotate the log file past this many bytes (DefaultMaxSize if 0)
+	MaxFiles int    // Rotated log files kept (DefaultMaxFiles if 0)
+}
+
+var (
+	logger   = slog.New(slog.DiscardHandler)
+	file     *rotatingFile
+	recorded bool // A record was written to the log file this run
+	mu       sync.Mutex
+)
+
+// Setup replaces the logger according to opts. Messages are dropped until
+// it is called. The log file gets Info and above (Debug with opts.Debug);
+// failing to open it is returned, with stderr logging still set up.
+func Setup(opts Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+
+	var handlers []slog.Handler
+	if opts.Verbose || opts.Debug {
+		level := slog.LevelInfo
+		if opts.Debug {
+			level = slog.LevelDebug
+		}
+		handlers = append(handlers, slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+
+	var err error
+	if opts.File != "" {
+		maxSize, maxFiles := opts.MaxSize, opts.MaxFiles
+		if maxSize <= 0 {
+			maxSize = DefaultMaxSize
+		}
+		if maxFiles <= 0 {
+			maxFiles = DefaultMaxFiles
+		}
+
+		file, err = openRotatingFile(opts.File, maxSize, maxFiles)
+		if err == nil {
+			level := slog.LevelInfo
+			if opts.Debug {
+				level = slog.LevelDebug
+			}
+			handlerOpts := &slog.HandlerOptions{Level: level}
+			var h slog.Handler
+			if opts.JSON {
+				h = slog.NewJSONHandler(file, handlerOpts)
+			} else {
+				h = slog.NewTextHandler(file, handlerOpts)
+			}
+			handlers = append(handlers, recordingHandler{h})
+		}
+	}
+
+	switch len(handlers) {
+	case 0:
+		logger = slog.New(slog.DiscardHandler)
+	case 1:
+		logger = slog.New(handlers[0])
+	default:
+		logger = slog.New(multiHandler(handlers))
+	}
+	return err
+}
+
+// SetCommand adds the running command and process to every record
+func SetCommand(command string) {
+	mu.Lock()
+	defer mu.Unlock()
+	logger = logger.With("cmd", command, "pid", os.Getpid())
+}
+
+// Close flushes and closes the log file
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	logger = slog.New(slog.DiscardHandler)
+	return err
+}
+
+// Recorded reports whether anything was written to the log file
+func Recorded() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return recorded
+}
+
+// Debug logs details useful when tracking down a problem
+func Debug(msg string, args ...any) { current().Debug(msg, args...) }
+
+// Info logs a change dotcor made
+func Info(msg string, args ...any) { current().Info(msg, args...) }
+
+// Warn logs something that went wrong without stopping the operation
+func Warn(msg string, args ...any) { current().Warn(msg, args...) }
+
+// Error logs a failed operation
+func Error(msg string, args ...any) { current().Error(msg, args...) }
+
+func current() *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	return logger
+}
+
+// recordingHandler notes that a record reached the log file
+type recordingHandler struct {
+	slog.Handler
+}
+
+func (h recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	mu.Lock()
+	recorded = true
+	mu.Unlock()
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return recordingHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h recordingHandler) WithGroup(name string) slog.Handler {
+	return recordingHandler{h.Handler.WithGroup(name)}
+}
+
+// multiHandler sends each record to every handler that accepts its level
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "dotcor.log")
+	if err := Setup(Options{File: path}); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	SetCommand("dotcor add")
+
+	Debug("checking", "path", "~/.zshrc")
+	Info("created symlink", "path", "~/.zshrc")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !strings.Contains(got, `msg="created symlink"`) || !strings.Contains(got, `cmd="dotcor add"`) {
+		t.Errorf("log file missing Info record:\n%s", got)
+	}
+	if strings.Contains(got, "checking") {
+		t.Errorf("log file has Debug record without Debug option:\n%s", got)
+	}
+	if !Recorded() {
+		t.Error("Recorded() = false after writing to the log file")
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("log file mode = %o, want 600", info.Mode().Perm())
+	}
+}
+
+func TestLogFileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dotcor.log")
+	if err := Setup(Options{File: path, JSON: true, Debug: true}); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	Debug("git command", "args", []string{"status"})
+	Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, data)
+	}
+	if record["msg"] != "git command" || record["level"] != "DEBUG" {
+		t.Errorf("record = %v", record)
+	}
+}
+
+func TestLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dotcor.log")
+	if err := Setup(Options{File: path, MaxSize: 200, MaxFiles: 2}); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		Info("created symlink", "path", "~/.config/some/long/path/to/a/dotfile")
+	}
+	Close()
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Errorf("%s missing: %v", filepath.Base(p), err)
+			continue
+		}
+		if info.Size() > 200 {
+			t.Errorf("%s is %d bytes, want at most 200", filepath.Base(p), info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("dotcor.log.3 should have been dropped")
+	}
+}
@@ -0,0 +1,72 @@
+// Package metrics renders gauges in the Prometheus text exposition format,
+// which OpenMetrics scrapers and node_exporter's textfile collector read,
+// so the health of each machine's dotfiles can be monitored centrally.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the HTTP Content-Type of the rendered format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Sample is one value of a gauge, told apart from the gauge's other samples
+// by its labels
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Gauge is a metric whose value can go up and down
+type Gauge struct {
+	Name    string
+	Help    string
+	Samples []Sample
+}
+
+// NewGauge returns a gauge with a single unlabeled value
+func NewGauge(name, help string, value float64) Gauge {
+	return Gauge{Name: name, Help: help, Samples: []Sample{{Value: value}}}
+}
+
+// Write renders gauges in the Prometheus text format
+func Write(w io.Writer, gauges []Gauge) error {
+	var b strings.Builder
+	for _, g := range gauges {
+		fmt.Fprintf(&b, "# HELP %s %s\n", g.Name, helpEscaper.Replace(g.Help))
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", g.Name)
+		for _, s := range g.Samples {
+			fmt.Fprintf(&b, "%s%s %s\n", g.Name, formatLabels(s.Labels), strconv.FormatFloat(s.Value, 'g', -1, 64))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatLabels renders labels sorted by name, or "" if there are none
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(labels[name]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes backslashes, quotes and newlines in label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// helpEscaper escapes backslashes and newlines in help text
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	gauges := []Gauge{
+		NewGauge("dotcor_managed_files", "Managed files on this machine.", 12),
+		{
+			Name: "dotcor_files",
+			Help: "Managed files by status.",
+			Samples: []Sample{
+				{Labels: map[string]string{"status": "ok"}, Value: 10},
+				{Labels: map[string]string{"status": "broken", "repo": `a\b"c`}, Value: 2},
+			},
+		},
+	}
+
+	var out strings.Builder
+	if err := Write(&out, gauges); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := `# HELP dotcor_managed_files Managed files on this machine.
+# TYPE dotcor_managed_files gauge
+dotcor_managed_files 12
+# HELP dotcor_files Managed files by status.
+# TYPE dotcor_files gauge
+dotcor_files{status="ok"} 10
+dotcor_files{repo="a\\b\"c",status="broken"} 2
+`
+	if out.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
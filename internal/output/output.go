@@ -0,0 +1,102 @@
+// Package output renders command results as plain text, JSON or YAML so
+// scripts and CI can consume dotcor reliably.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats
+const (
+	FormatPlain = "plain" // Human-readable text (default)
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+var (
+	format            = FormatPlain
+	stdout  io.Writer = os.Stdout
+	written bool
+)
+
+// Validate returns an error if f is not a known output format
+func Validate(f string) error {
+	switch f {
+	case "", FormatPlain, FormatJSON, FormatYAML:
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q (expected json, yaml or plain)", f)
+}
+
+// SetFormat selects the output format. For structured formats, human-readable
+// progress written to os.Stdout (including hook output and prompts) is
+// redirected to stderr, so stdout carries only the result document.
+func SetFormat(f string) error {
+	if err := Validate(f); err != nil {
+		return err
+	}
+	if f == "" {
+		f = FormatPlain
+	}
+
+	format = f
+	if Structured() {
+		stdout = os.Stdout
+		os.Stdout = os.Stderr
+	}
+	return nil
+}
+
+// Format returns the selected output format
+func Format() string {
+	return format
+}
+
+// Structured reports whether a machine-readable format is selected
+func Structured() bool {
+	return format == FormatJSON || format == FormatYAML
+}
+
+// Write encodes v in the selected structured format on stdout. Commands
+// call it once with their result after finishing.
+func Write(v any) error {
+	written = true
+	return Encode(stdout, format, v)
+}
+
+// Written reports whether a result document was already written
+func Written() bool {
+	return written
+}
+
+// Encode writes v to w in the given structured format
+func Encode(w io.Writer, f string, v any) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("encoding JSON: %w", err)
+		}
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("encoding YAML: %w", err)
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("%s is not a structured output format", f)
+	}
+	return nil
+}
+
+// Error is the result document written when a command fails
+type Error struct {
+	Error string `json:"error" yaml:"error"`
+}
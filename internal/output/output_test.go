@@ -0,0 +1,46 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, f := range []string{"", FormatPlain, FormatJSON, FormatYAML} {
+		if err := Validate(f); err != nil {
+			t.Errorf("Validate(%q) error = %v", f, err)
+		}
+	}
+	if err := Validate("xml"); err == nil {
+		t.Error("Validate(\"xml\") should return error")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	v := struct {
+		Source string `json:"source" yaml:"source"`
+		Count  int    `json:"count" yaml:"count"`
+	}{Source: "~/.zshrc", Count: 2}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatJSON, "{\n  \"source\": \"~/.zshrc\",\n  \"count\": 2\n}\n"},
+		{FormatYAML, "source: ~/.zshrc\ncount: 2\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Encode(&buf, tt.format, v); err != nil {
+			t.Fatalf("Encode(%s) error = %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("Encode(%s) = %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	if err := Encode(&bytes.Buffer{}, FormatPlain, v); err == nil {
+		t.Error("Encode(plain) should return error")
+	}
+}
@@ -0,0 +1,207 @@
+// Package packages checks for and installs the system packages declared in
+// the packages section of config.yaml, using brew, apt and cargo.
+package packages
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// Manager is a package manager dotcor can drive
+type Manager struct {
+	Name    string                              // Key in the packages section, e.g. config.PackageManagerApt
+	Command string                              // Executable looked up in PATH
+	list    []string                            // Command line listing installed packages
+	parse   func(output string) map[string]bool // Turns the list output into installed names
+	install []string                            // Arguments installing packages
+	sudo    bool                                // Install as root
+}
+
+// Managers is every supported package manager, in the order they are handled
+var Managers = []Manager{
+	{
+		Name:    config.PackageManagerBrew,
+		Command: "brew",
+		list:    []string{"brew", "list", "-1", "--formula"},
+		parse:   parseNameList,
+		install: []string{"install"},
+	},
+	{
+		Name:    config.PackageManagerApt,
+		Command: "apt-get",
+		list:    []string{"dpkg-query", "-W", "-f=${Package} ${db:Status-Status}\n"},
+		parse:   parseDpkgStatus,
+		install: []string{"install", "-y"},
+		sudo:    true,
+	},
+	{
+		Name:    config.PackageManagerCargo,
+		Command: "cargo",
+		list:    []string{"cargo", "install", "--list"},
+		parse:   parseCargoList,
+		install: []string{"install"},
+	},
+}
+
+// Available reports whether the manager is installed on this machine
+func (m Manager) Available() bool {
+	_, err := exec.LookPath(m.Command)
+	return err == nil
+}
+
+// Installed returns the names of the packages the manager has installed
+func (m Manager) Installed() (map[string]bool, error) {
+	out, err := exec.Command(m.list[0], m.list[1:]...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			msg, _, _ := strings.Cut(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+			return nil, fmt.Errorf("listing %s packages: %s", m.Name, msg)
+		}
+		return nil, fmt.Errorf("listing %s packages: %w", m.Name, err)
+	}
+	return m.parse(string(out)), nil
+}
+
+// InstallCommand returns the command line that installs pkgs. The names
+// follow "--" so none is taken for an option, which apt would run as root.
+func (m Manager) InstallCommand(pkgs []string) []string {
+	command := append([]string{m.Command}, m.install...)
+	if m.sudo && os.Geteuid() > 0 {
+		if _, err := exec.LookPath("sudo"); err == nil {
+			command = append([]string{"sudo"}, command...)
+		}
+	}
+	command = append(command, "--")
+	return append(command, pkgs...)
+}
+
+// Install installs pkgs, passing the terminal through so the manager can
+// show progress and ask for a password
+func (m Manager) Install(pkgs []string, out io.Writer) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+
+	command := m.InstallCommand(pkgs)
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", strings.Join(command, " "), err)
+	}
+	return nil
+}
+
+// Status is the state of one manager's declared packages on this machine
+type Status struct {
+	Manager   string   `json:"manager" yaml:"manager"`
+	Available bool     `json:"available" yaml:"available"` // The manager itself is installed
+	Installed []string `json:"installed,omitempty" yaml:"installed,omitempty"`
+	Missing   []string `json:"missing,omitempty" yaml:"missing,omitempty"`
+	Skipped   []string `json:"skipped,omitempty" yaml:"skipped,omitempty"` // Every declared package if the manager isn't available
+}
+
+// Check reports which declared packages are installed, for each manager
+// with packages declared. Packages of managers that aren't installed are
+// skipped rather than missing. Invalid package names are an error.
+func Check(declared config.PackagesConfig) ([]Status, error) {
+	if err := config.ValidatePackages(declared); err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range Managers {
+		pkgs := declared.Get(m.Name)
+		if len(pkgs) == 0 {
+			continue
+		}
+
+		status := Status{Manager: m.Name, Available: m.Available()}
+		if !status.Available {
+			status.Skipped = pkgs
+			statuses = append(statuses, status)
+			continue
+		}
+		installed, err := m.Installed()
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			if installed[packageName(pkg)] {
+				status.Installed = append(status.Installed, pkg)
+			} else {
+				status.Missing = append(status.Missing, pkg)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Lookup returns the manager with the given name
+func Lookup(name string) (Manager, bool) {
+	for _, m := range Managers {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Manager{}, false
+}
+
+// packageName strips what the list output leaves out of a declared
+// package: the tap of a brew formula ("owner/tap/name") and the
+// architecture of an apt package ("name:amd64")
+func packageName(pkg string) string {
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	name, _, _ := strings.Cut(pkg, ":")
+	return name
+}
+
+// parseNameList parses one package name per line, as printed by 'brew list -1'
+func parseNameList(output string) map[string]bool {
+	installed := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			installed[name] = true
+		}
+	}
+	return installed
+}
+
+// parseDpkgStatus parses "name status" lines from dpkg-query. Removed
+// packages whose config files remain are listed too, so the status counts.
+func parseDpkgStatus(output string) map[string]bool {
+	installed := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "installed" {
+			installed[packageName(fields[0])] = true
+		}
+	}
+	return installed
+}
+
+// parseCargoList parses 'cargo install --list', where each crate is an
+// unindented "name v1.2.3:" line followed by its indented binaries
+func parseCargoList(output string) map[string]bool {
+	installed := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if name, _, ok := strings.Cut(line, " "); ok {
+			installed[name] = true
+		}
+	}
+	return installed
+}
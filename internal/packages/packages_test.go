@@ -0,0 +1,71 @@
+package packages
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestParseInstalled(t *testing.T) {
+	tests := []struct {
+		name   string
+		parse  func(string) map[string]bool
+		output string
+		want   []string
+	}{
+		{"brew", parseNameList, "git\nneovim\n\n", []string{"git", "neovim"}},
+		{"dpkg", parseDpkgStatus, "git installed\nvim config-files\nlibc6:amd64 installed\n", []string{"git", "libc6"}},
+		{"cargo", parseCargoList, "ripgrep v14.1.0:\n    rg\nbat v0.24.0:\n    bat\n", []string{"ripgrep", "bat"}},
+	}
+	for _, tt := range tests {
+		want := make(map[string]bool)
+		for _, name := range tt.want {
+			want[name] = true
+		}
+		if got := tt.parse(tt.output); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: parsed %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake cargo in this test is a shell script")
+	}
+
+	bin := t.TempDir()
+	script := "#!/bin/sh\nprintf 'ripgrep v14.1.0:\\n    rg\\n'\n"
+	if err := os.WriteFile(filepath.Join(bin, "cargo"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	statuses, err := Check(config.PackagesConfig{
+		Brew:  []string{"owner/tap/tool"},
+		Cargo: []string{"ripgrep", "bat"},
+	})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	want := []Status{
+		{Manager: "brew", Available: false, Skipped: []string{"owner/tap/tool"}},
+		{Manager: "cargo", Available: true, Installed: []string{"ripgrep"}, Missing: []string{"bat"}},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("Check() = %+v, want %+v", statuses, want)
+	}
+
+	// An option where a name belongs is refused before anything runs
+	if _, err := Check(config.PackagesConfig{Apt: []string{"-oDPkg::Pre-Invoke::=touch /tmp/x"}}); err == nil {
+		t.Error("Check() with an option as a package name succeeded")
+	}
+	cargo, _ := Lookup(config.PackageManagerCargo)
+	if got := cargo.InstallCommand([]string{"bat"}); !reflect.DeepEqual(got, []string{"cargo", "install", "--", "bat"}) {
+		t.Errorf("InstallCommand() = %v", got)
+	}
+}
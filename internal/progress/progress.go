@@ -0,0 +1,189 @@
+// Package progress draws spinners and progress bars on a terminal while long
+// operations run, so deploying hundreds of files or pushing over a slow link
+// doesn't look like a hang. Callers decide whether to draw at all; a disabled
+// or nil Spinner or Bar does nothing.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clearLine returns the cursor to the start of the line and erases it
+const clearLine = "\r\033[K"
+
+// spinnerFrames are drawn in turn while a spinner runs
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinnerInterval is how often a spinner is redrawn
+const spinnerInterval = 100 * time.Millisecond
+
+// Spinner shows that an operation of unknown length is still running
+type Spinner struct {
+	out   io.Writer
+	label string
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// StartSpinner draws a spinner with label and the elapsed time on out until
+// Stop is called. It returns nil when enabled is false.
+func StartSpinner(out io.Writer, label string, enabled bool) *Spinner {
+	if !enabled {
+		return nil
+	}
+
+	s := &Spinner{out: out, label: label, stop: make(chan struct{}), done: make(chan struct{})}
+	go s.run()
+	return s
+}
+
+func (s *Spinner) run() {
+	defer close(s.done)
+
+	start := time.Now()
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		fmt.Fprintf(s.out, "%s%s %s", clearLine, spinnerFrames[frame%len(spinnerFrames)], s.label)
+		if elapsed := time.Since(start); elapsed >= time.Second {
+			fmt.Fprintf(s.out, " (%ds)", int(elapsed.Seconds()))
+		}
+
+		select {
+		case <-s.stop:
+			fmt.Fprint(s.out, clearLine)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop erases the spinner
+func (s *Spinner) Stop() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+// barWidth is the number of cells in a drawn bar
+const barWidth = 30
+
+// Bar shows how many of a known number of items are done. While it is
+// shown, lines written to os.Stdout are printed above it.
+type Bar struct {
+	out   io.Writer
+	label string
+	total int
+
+	mu     sync.Mutex
+	done   int
+	drawn  bool // The bar is on screen, below the last complete line
+	stdout *os.File
+	pipe   *os.File
+	copied chan struct{}
+}
+
+// StartBar draws a bar for total items with label on out. It returns nil
+// when enabled is false.
+func StartBar(out io.Writer, label string, total int, enabled bool) *Bar {
+	if !enabled {
+		return nil
+	}
+
+	b := &Bar{out: out, label: label, total: total}
+	if r, w, err := os.Pipe(); err == nil {
+		b.stdout, b.pipe = os.Stdout, w
+		b.copied = make(chan struct{})
+		os.Stdout = w
+		go b.copyOutput(r)
+	}
+
+	b.mu.Lock()
+	b.draw()
+	b.mu.Unlock()
+	return b
+}
+
+// copyOutput moves what's written to os.Stdout above the bar. A partial
+// line, such as a prompt waiting for an answer, is left without the bar.
+func (b *Bar) copyOutput(r *os.File) {
+	defer close(b.copied)
+	defer r.Close()
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			b.mu.Lock()
+			if b.drawn {
+				fmt.Fprint(b.out, clearLine)
+				b.drawn = false
+			}
+			b.stdout.Write(buf[:n])
+			if buf[n-1] == '\n' {
+				b.draw()
+			}
+			b.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Set updates the number of items done
+func (b *Bar) Set(done int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.done = done
+	if b.drawn {
+		b.draw()
+	}
+}
+
+// Finish erases the bar and restores os.Stdout
+func (b *Bar) Finish() {
+	if b == nil {
+		return
+	}
+
+	if b.pipe != nil {
+		os.Stdout = b.stdout
+		b.pipe.Close()
+		<-b.copied
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.drawn {
+		fmt.Fprint(b.out, clearLine)
+		b.drawn = false
+	}
+}
+
+// draw renders the bar; b.mu must be held
+func (b *Bar) draw() {
+	fmt.Fprint(b.out, clearLine+render(b.label, b.done, b.total))
+	b.drawn = true
+}
+
+// render returns the text of a bar with done of total items complete
+func render(label string, done, total int) string {
+	filled := barWidth
+	if total > 0 {
+		filled = min(done, total) * barWidth / total
+	}
+	return fmt.Sprintf("%s [%s%s] %d/%d", label, strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), done, total)
+}
@@ -0,0 +1,82 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		done, total int
+		want        string
+	}{
+		{0, 10, "Linking [" + strings.Repeat("░", 30) + "] 0/10"},
+		{5, 10, "Linking [" + strings.Repeat("█", 15) + strings.Repeat("░", 15) + "] 5/10"},
+		{10, 10, "Linking [" + strings.Repeat("█", 30) + "] 10/10"},
+	}
+	for _, tt := range tests {
+		if got := render("Linking", tt.done, tt.total); got != tt.want {
+			t.Errorf("render(%d, %d) = %q, want %q", tt.done, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestBarPrintsStdoutAbove(t *testing.T) {
+	stdoutPath := filepath.Join(t.TempDir(), "stdout")
+	stdout, err := os.Create(stdoutPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+
+	saved := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = saved }()
+
+	var drawn bytes.Buffer
+	bar := StartBar(&drawn, "Linking", 2, true)
+	fmt.Println("  ✓ ~/.zshrc")
+	bar.Set(1)
+	fmt.Println("  ✓ ~/.vimrc")
+	bar.Set(2)
+	bar.Finish()
+
+	if os.Stdout != stdout {
+		t.Error("Finish() didn't restore os.Stdout")
+	}
+	got, err := os.ReadFile(stdoutPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "  ✓ ~/.zshrc\n  ✓ ~/.vimrc\n"; string(got) != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if !strings.HasSuffix(drawn.String(), clearLine) {
+		t.Errorf("bar output %q doesn't end by erasing the bar", drawn.String())
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	var out bytes.Buffer
+	StartSpinner(&out, "Pushing", false).Stop()
+	bar := StartBar(&out, "Linking", 3, false)
+	bar.Set(1)
+	bar.Finish()
+
+	if out.Len() != 0 {
+		t.Errorf("disabled progress wrote %q", out.String())
+	}
+}
+
+func TestSpinnerStop(t *testing.T) {
+	var out bytes.Buffer
+	StartSpinner(&out, "Pushing", true).Stop()
+
+	if !strings.Contains(out.String(), "Pushing") || !strings.HasSuffix(out.String(), clearLine) {
+		t.Errorf("spinner output = %q, want the label then an erased line", out.String())
+	}
+}
@@ -0,0 +1,155 @@
+// Package skeleton provides starting points for dotfiles a user doesn't have
+// yet: commented configs built into dotcor, and the user's own in the
+// skeletons directory next to config.yaml.
+package skeleton
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// DirName is the directory of user skeletons in the config directory
+const DirName = "skeletons"
+
+// Skeleton is the initial content of a new dotfile
+type Skeleton struct {
+	Name        string // e.g. "tmux"
+	Description string
+	Path        string // Where the file goes by default, "" if it has no usual place
+	Builtin     bool   // Shipped with dotcor rather than from the skeletons directory
+	file        string // Built-in file name or user file path
+}
+
+// ErrNotFound is returned by Get for names with no skeleton
+var ErrNotFound = errors.New("no such skeleton")
+
+//go:embed builtin
+var builtinFS embed.FS
+
+// builtins are the skeletons shipped with dotcor
+var builtins = []Skeleton{
+	{Name: "bash", Description: "Bash startup file with history settings", Path: "~/.bashrc", file: "bashrc"},
+	{Name: "editorconfig", Description: "EditorConfig defaults for all projects", Path: "~/.editorconfig", file: "editorconfig"},
+	{Name: "git", Description: "Git config with identity, pull and push defaults", Path: "~/.gitconfig", file: "gitconfig"},
+	{Name: "gitignore", Description: "Global Git ignore rules", Path: "~/.config/git/ignore", file: "gitignore"},
+	{Name: "starship", Description: "Commented starship prompt config", Path: "~/.config/starship.toml", file: "starship.toml"},
+	{Name: "tmux", Description: "Minimal tmux config with mouse and sane numbering", Path: "~/.tmux.conf", file: "tmux.conf"},
+	{Name: "vim", Description: "Minimal vimrc", Path: "~/.vimrc", file: "vimrc"},
+	{Name: "zsh", Description: "Zsh startup file with history and completion", Path: "~/.zshrc", file: "zshrc"},
+}
+
+// UserDir returns the directory holding the user's own skeletons
+func UserDir() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, DirName), nil
+}
+
+// List returns every skeleton sorted by name. A user skeleton named like a
+// built-in one replaces its content and keeps its default path.
+func List() ([]Skeleton, error) {
+	byName := make(map[string]Skeleton, len(builtins))
+	for _, s := range builtins {
+		s.Builtin = true
+		byName[s.Name] = s
+	}
+
+	user, err := userSkeletons()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range user {
+		if builtin, ok := byName[s.Name]; ok {
+			s.Path = builtin.Path
+		}
+		byName[s.Name] = s
+	}
+
+	skeletons := make([]Skeleton, 0, len(byName))
+	for _, s := range byName {
+		skeletons = append(skeletons, s)
+	}
+	sort.Slice(skeletons, func(i, j int) bool { return skeletons[i].Name < skeletons[j].Name })
+	return skeletons, nil
+}
+
+// Get returns the skeleton called name
+func Get(name string) (Skeleton, error) {
+	skeletons, err := List()
+	if err != nil {
+		return Skeleton{}, err
+	}
+	for _, s := range skeletons {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Skeleton{}, fmt.Errorf("%w %q", ErrNotFound, name)
+}
+
+// ForPath returns the skeleton whose default path has the same file name
+// as path, for creating a file without naming a skeleton
+func ForPath(path string) (Skeleton, bool) {
+	skeletons, err := List()
+	if err != nil {
+		return Skeleton{}, false
+	}
+	base := filepath.Base(path)
+	for _, s := range skeletons {
+		if s.Path != "" && filepath.Base(filepath.FromSlash(s.Path)) == base {
+			return s, true
+		}
+	}
+	return Skeleton{}, false
+}
+
+// Content returns the skeleton's file content
+func (s Skeleton) Content() ([]byte, error) {
+	if s.Builtin {
+		return builtinFS.ReadFile("builtin/" + s.file)
+	}
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return nil, fmt.Errorf("reading skeleton %s: %w", s.Name, err)
+	}
+	return data, nil
+}
+
+// userSkeletons reads the skeletons directory. Each file is a skeleton
+// named after the file without its extension, so starship.toml is
+// "starship"; hidden files are skipped.
+func userSkeletons() ([]Skeleton, error) {
+	dir, err := UserDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading skeletons directory: %w", err)
+	}
+
+	var skeletons []Skeleton
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		skeletons = append(skeletons, Skeleton{
+			Name:        strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())),
+			Description: "From " + filepath.Join(dir, e.Name()),
+			file:        filepath.Join(dir, e.Name()),
+		})
+	}
+	return skeletons, nil
+}
@@ -0,0 +1,98 @@
+package skeleton
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+// setupHome points $HOME at a temp directory with the classic layout
+func setupHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.XDGEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	return home
+}
+
+func TestBuiltinContent(t *testing.T) {
+	setupHome(t)
+
+	skeletons, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(skeletons) != len(builtins) {
+		t.Errorf("List() = %d skeletons, want %d", len(skeletons), len(builtins))
+	}
+	for _, s := range skeletons {
+		content, err := s.Content()
+		if err != nil || len(content) == 0 {
+			t.Errorf("%s: Content() = %d bytes, %v", s.Name, len(content), err)
+		}
+		if s.Path == "" || !s.Builtin {
+			t.Errorf("%s: built-in skeleton without a path: %+v", s.Name, s)
+		}
+	}
+}
+
+func TestUserSkeletons(t *testing.T) {
+	home := setupHome(t)
+	dir := filepath.Join(home, ".dotcor", DirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"alacritty.toml": "[window]\n",
+		"tmux.conf":      "# my tmux\n",
+		".hidden":        "skipped\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alacritty, err := Get("alacritty")
+	if err != nil {
+		t.Fatalf("Get(alacritty) error = %v", err)
+	}
+	if alacritty.Builtin || alacritty.Path != "" {
+		t.Errorf("Get(alacritty) = %+v", alacritty)
+	}
+
+	// A user skeleton replaces the built-in one's content, not its path
+	tmux, err := Get("tmux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := tmux.Content()
+	if tmux.Path != "~/.tmux.conf" || string(content) != "# my tmux\n" {
+		t.Errorf("Get(tmux) = %+v with %q", tmux, content)
+	}
+
+	if _, err := Get(".hidden"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(.hidden) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestForPath(t *testing.T) {
+	setupHome(t)
+
+	if s, ok := ForPath("~/.tmux.conf"); !ok || s.Name != "tmux" {
+		t.Errorf("ForPath(~/.tmux.conf) = %+v, %v", s, ok)
+	}
+	if s, ok := ForPath("/elsewhere/starship.toml"); !ok || s.Name != "starship" {
+		t.Errorf("ForPath(starship.toml) = %+v, %v", s, ok)
+	}
+	if s, ok := ForPath("~/.config/foo.conf"); ok {
+		t.Errorf("ForPath(foo.conf) = %+v, want none", s)
+	}
+	if _, err := Get("nope"); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Get(nope) error = %v", err)
+	}
+}
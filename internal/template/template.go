@@ -0,0 +1,239 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// Data is the set of values available to templates
+//
+//	{{ .Hostname }}, {{ .OS }}, {{ .Arch }}, {{ .User }}, {{ .Home }}
+//	{{ .Vars.email }} for variables from the config file
+type Data struct {
+	Hostname string
+	OS       string
+	Arch     string
+	User     string
+	Home     string
+	Vars     map[string]string
+}
+
+// NewData collects template values for this machine and the config's variables
+func NewData(cfg *config.Config) (Data, error) {
+	home, err := config.HomeDir()
+	if err != nil {
+		return Data{}, err
+	}
+
+	hostname, _ := os.Hostname()
+	if short, _, found := strings.Cut(hostname, "."); found {
+		hostname = short
+	}
+
+	username := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+
+	vars := make(map[string]string, len(cfg.Variables))
+	for k, v := range cfg.Variables {
+		vars[k] = v
+	}
+
+	return Data{
+		Hostname: hostname,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		User:     username,
+		Home:     home,
+		Vars:     vars,
+	}, nil
+}
+
+// funcs are helper functions available in templates
+var funcs = texttemplate.FuncMap{
+	"env":       os.Getenv,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"trim":      strings.TrimSpace,
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	// default returns value, or def if value is empty: {{ default "vim" .Vars.editor }}
+	"default": func(def, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+}
+
+// Render executes a template. Referencing an undefined variable is an
+// error rather than silently rendering "<no value>", except as the value
+// of default, which is there for variables that may be unset.
+func Render(name string, content []byte, data Data) ([]byte, error) {
+	tmpl, err := texttemplate.New(name).Funcs(funcs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template %s: %w", name, err)
+	}
+
+	defaulted := map[string]bool{}
+	defaultedVars(tmpl.Root, defaulted)
+	if len(defaulted) > 0 {
+		vars := make(map[string]string, len(data.Vars)+len(defaulted))
+		for k := range defaulted {
+			vars[k] = ""
+		}
+		for k, v := range data.Vars {
+			vars[k] = v
+		}
+		data.Vars = vars
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("rendering template %s: %w", name, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// defaultedVars adds the names of the variables given to default to names,
+// such as editor in {{ default "vim" .Vars.editor }} and
+// {{ .Vars.editor | default "vim" }}
+func defaultedVars(node parse.Node, names map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			defaultedVars(child, names)
+		}
+	case *parse.ActionNode:
+		defaultedVars(n.Pipe, names)
+	case *parse.IfNode:
+		defaultedBranch(&n.BranchNode, names)
+	case *parse.RangeNode:
+		defaultedBranch(&n.BranchNode, names)
+	case *parse.WithNode:
+		defaultedBranch(&n.BranchNode, names)
+	case *parse.TemplateNode:
+		defaultedVars(n.Pipe, names)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for i, cmd := range n.Cmds {
+			if isDefault(cmd) {
+				switch {
+				case len(cmd.Args) == 3:
+					addVarName(cmd.Args[2], names)
+				case len(cmd.Args) == 2 && i > 0 && len(n.Cmds[i-1].Args) == 1:
+					addVarName(n.Cmds[i-1].Args[0], names)
+				}
+			}
+			for _, arg := range cmd.Args {
+				defaultedVars(arg, names)
+			}
+		}
+	}
+}
+
+// defaultedBranch looks for default calls in an if, range or with
+func defaultedBranch(n *parse.BranchNode, names map[string]bool) {
+	defaultedVars(n.Pipe, names)
+	defaultedVars(n.List, names)
+	defaultedVars(n.ElseList, names)
+}
+
+// isDefault reports whether cmd calls default
+func isDefault(cmd *parse.CommandNode) bool {
+	if len(cmd.Args) == 0 {
+		return false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "default"
+}
+
+// addVarName adds the name of arg to names if arg is .Vars.<name>
+func addVarName(arg parse.Node, names map[string]bool) {
+	if field, ok := arg.(*parse.FieldNode); ok && len(field.Ident) == 2 && field.Ident[0] == "Vars" {
+		names[field.Ident[1]] = true
+	}
+}
+
+// RenderFile renders the template at src into dst, keeping src's permissions.
+// dst is only rewritten when the output changes.
+// Returns true if dst was written.
+func RenderFile(src, dst string, data Data) (bool, error) {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return false, fmt.Errorf("reading template: %w", err)
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return false, fmt.Errorf("getting template info: %w", err)
+	}
+
+	output, err := Render(filepath.Base(src), content, data)
+	if err != nil {
+		return false, err
+	}
+
+	if existing, err := os.ReadFile(dst); err == nil && bytes.Equal(existing, output) {
+		return false, nil
+	}
+
+	if err := fs.EnsureDir(filepath.Dir(dst)); err != nil {
+		return false, fmt.Errorf("creating output directory: %w", err)
+	}
+
+	// Write to a temp file and rename so the linked file is never half-written
+	tempPath := dst + ".tmp"
+	if err := os.WriteFile(tempPath, output, info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("writing rendered file: %w", err)
+	}
+	if err := os.Rename(tempPath, dst); err != nil {
+		os.Remove(tempPath)
+		return false, fmt.Errorf("replacing rendered file: %w", err)
+	}
+
+	return true, nil
+}
+
+// RenderManagedFile renders a managed template into the rendered directory.
+// Returns the rendered path and whether it changed.
+func RenderManagedFile(cfg *config.Config, mf config.ManagedFile, data Data) (string, bool, error) {
+	if !mf.IsTemplate() {
+		return "", false, fmt.Errorf("not a template: %s", mf.RepoPath)
+	}
+
+	src, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return "", false, err
+	}
+
+	dst, err := config.GetLinkTargetPath(cfg, mf)
+	if err != nil {
+		return "", false, err
+	}
+
+	changed, err := RenderFile(src, dst, data)
+	if err != nil {
+		return "", false, err
+	}
+
+	return dst, changed, nil
+}
@@ -0,0 +1,111 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+)
+
+func TestRender(t *testing.T) {
+	data := Data{
+		Hostname: "laptop",
+		OS:       "linux",
+		User:     "me",
+		Home:     "/home/me",
+		Vars:     map[string]string{"email": "me@example.com", "pager": ""},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"plain text", "export PATH=$PATH\n", "export PATH=$PATH\n", false},
+		{"machine values", "host={{ .Hostname }} os={{ .OS }}", "host=laptop os=linux", false},
+		{"config variable", "email = {{ .Vars.email }}", "email = me@example.com", false},
+		{"conditional", `{{ if eq .OS "darwin" }}mac{{ else }}other{{ end }}`, "other", false},
+		{"default helper", `{{ default "vim" .Vars.editor }}`, "vim", false},
+		{"default helper piped", `{{ if true }}{{ .Vars.editor | default "vim" }}{{ end }}`, "vim", false},
+		{"default helper empty", `{{ default "less" .Vars.pager }}`, "less", false},
+		{"default helper set", `{{ default "x" .Vars.email }}`, "me@example.com", false},
+		{"upper helper", `{{ upper .User }}`, "ME", false},
+		{"missing variable", "{{ .Vars.missing }}", "", true},
+		{"syntax error", "{{ .Hostname ", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render("test", []byte(tt.content), data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderManagedFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	t.Setenv("HOME", tempDir)
+
+	cfg := &config.Config{
+		Version:   config.CurrentConfigVersion,
+		RepoPath:  filepath.Join(tempDir, ".dotcor", "files"),
+		Variables: map[string]string{"email": "me@example.com"},
+	}
+	mf := config.ManagedFile{SourcePath: "~/.gitconfig", RepoPath: "git/gitconfig.tmpl"}
+
+	templatePath := filepath.Join(cfg.RepoPath, "git", "gitconfig.tmpl")
+	if err := os.MkdirAll(filepath.Dir(templatePath), 0755); err != nil {
+		t.Fatalf("failed to create repo dir: %v", err)
+	}
+	if err := os.WriteFile(templatePath, []byte("[user]\n\temail = {{ .Vars.email }}\n"), 0600); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	data, err := NewData(cfg)
+	if err != nil {
+		t.Fatalf("NewData() error = %v", err)
+	}
+
+	rendered, changed, err := RenderManagedFile(cfg, mf, data)
+	if err != nil {
+		t.Fatalf("RenderManagedFile() error = %v", err)
+	}
+	if !changed {
+		t.Error("RenderManagedFile() changed = false on first render")
+	}
+	if want := filepath.Join(tempDir, ".dotcor", "rendered", "git", "gitconfig"); rendered != want {
+		t.Errorf("RenderManagedFile() path = %s, want %s", rendered, want)
+	}
+
+	content, err := os.ReadFile(rendered)
+	if err != nil {
+		t.Fatalf("failed to read rendered file: %v", err)
+	}
+	if !strings.Contains(string(content), "email = me@example.com") {
+		t.Errorf("rendered content = %q", content)
+	}
+	if info, _ := os.Stat(rendered); info.Mode().Perm() != 0600 {
+		t.Errorf("rendered mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// Rendering again with the same values leaves the file alone
+	if _, changed, err := RenderManagedFile(cfg, mf, data); err != nil || changed {
+		t.Errorf("RenderManagedFile() second run changed = %v, err = %v", changed, err)
+	}
+
+	if _, _, err := RenderManagedFile(cfg, config.ManagedFile{RepoPath: "shell/zshrc"}, data); err == nil {
+		t.Error("RenderManagedFile() should reject non-template files")
+	}
+}
@@ -0,0 +1,155 @@
+// Package update checks GitHub for newer dotcor releases. Checks are
+// rate-limited through a small cache file in the data directory, so at most
+// one request is made per CheckInterval.
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/github"
+)
+
+// CacheFile is the name of the update check cache in the data directory
+const CacheFile = "update-check.json"
+
+// CheckInterval is how long a check result is reused
+const CheckInterval = 24 * time.Hour
+
+// Where dotcor is released
+const (
+	releaseOwner = "justincordova"
+	releaseRepo  = "dotcor"
+)
+
+// cache is the on-disk format of the update check cache
+type cache struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"` // Tag of the newest release, e.g. "v0.2.0"
+	URL       string    `json:"url,omitempty"`
+}
+
+// getCachePath returns the path to the update check cache
+func getCachePath() (string, error) {
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, CacheFile), nil
+}
+
+// readCache returns the cached check result, or a zero one if there is none
+func readCache() cache {
+	var c cache
+	path, err := getCachePath()
+	if err != nil {
+		return c
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &c)
+	}
+	return c
+}
+
+// Due reports whether the cached result is older than CheckInterval
+func Due() bool {
+	return time.Since(readCache().CheckedAt) >= CheckInterval
+}
+
+// Refresh looks up the latest release with client and caches the result.
+// A failed lookup is cached too, so an offline machine doesn't retry on
+// every run.
+func Refresh(client *github.Client) error {
+	path, err := getCachePath()
+	if err != nil {
+		return err
+	}
+
+	c := readCache()
+	c.CheckedAt = time.Now()
+	release, lookupErr := client.LatestRelease(releaseOwner, releaseRepo)
+	if lookupErr == nil {
+		c.Latest, c.URL = release.TagName, release.HTMLURL
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding update cache: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing update cache: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("replacing update cache: %w", err)
+	}
+
+	if lookupErr != nil {
+		return fmt.Errorf("looking up the latest release: %w", lookupErr)
+	}
+	return nil
+}
+
+// Newer returns the latest cached release and its URL if it is newer than
+// current
+func Newer(current string) (latest, url string, ok bool) {
+	c := readCache()
+	if c.Latest == "" || CompareVersions(c.Latest, current) <= 0 {
+		return "", "", false
+	}
+	return c.Latest, c.URL, true
+}
+
+// CompareVersions compares two dotted versions like "v0.2.10" and "0.2.9",
+// returning -1, 0 or 1. Pre-release suffixes ("-rc1") sort before the
+// release; parts that aren't numbers count as 0.
+func CompareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	for i := 0; i < len(aCore) || i < len(bCore); i++ {
+		x, y := versionPart(aCore, i), versionPart(bCore, i)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	}
+	return 1
+}
+
+// splitVersion returns the numeric parts of v and its pre-release suffix
+func splitVersion(v string) ([]string, string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+") // Build metadata doesn't count
+	v, pre, _ := strings.Cut(v, "-")
+	return strings.Split(v, "."), pre
+}
+
+// versionPart returns the i-th numeric part of parts, 0 if missing
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	n, _ := strconv.Atoi(parts[i])
+	return n
+}
@@ -0,0 +1,66 @@
+package update
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/github"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v0.2.0", "0.1.1", 1},
+		{"0.1.1", "v0.1.1", 0},
+		{"0.1.9", "0.1.10", -1},
+		{"1.0", "1.0.0", 0},
+		{"v1.0.0-rc1", "1.0.0", -1},
+		{"v1.0.0-rc2", "v1.0.0-rc1", 1},
+		{"v1.0.0+build5", "1.0.0", 0},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.XDGEnv, "")
+	if err := os.MkdirAll(filepath.Join(home, ".dotcor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(github.Release{TagName: "v0.3.0", HTMLURL: "https://example.com/v0.3.0"})
+	}))
+	t.Cleanup(server.Close)
+	client := github.NewClient("")
+	client.BaseURL = server.URL
+
+	if !Due() {
+		t.Error("Due() = false without a cache")
+	}
+	if err := Refresh(client); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if Due() {
+		t.Error("Due() = true right after a refresh")
+	}
+
+	if latest, url, ok := Newer("0.1.1"); !ok || latest != "v0.3.0" || url != "https://example.com/v0.3.0" {
+		t.Errorf("Newer(0.1.1) = %q, %q, %v, want v0.3.0", latest, url, ok)
+	}
+	if _, _, ok := Newer("0.3.0"); ok {
+		t.Error("Newer(0.3.0) = true for the current release")
+	}
+}
@@ -0,0 +1,130 @@
+// Package vscode locates Visual Studio Code's user settings on each
+// platform, and lists and installs its extensions with the code command.
+package vscode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// Files are the settings files of the user directory dotcor manages
+var Files = []string{"settings.json", "keybindings.json"}
+
+// Platforms are those VS Code keeps settings on, in the order their
+// entries are added
+var Platforms = []string{"darwin", "linux", "windows"}
+
+// userDirs are the home-relative user directories per platform
+var userDirs = map[string]string{
+	"darwin":  "~/Library/Application Support/Code/User",
+	"linux":   "~/.config/Code/User",
+	"windows": "~/AppData/Roaming/Code/User",
+}
+
+// macCLI is the code command in the app bundle, for Macs where the
+// "Install 'code' command in PATH" command hasn't been run
+const macCLI = "/Applications/Visual Studio Code.app/Contents/Resources/app/bin/code"
+
+// UserPath returns where file of the user directory is on platform, with
+// "~/" like source paths in config.yaml. ok is false on other platforms.
+func UserPath(platform, file string) (p string, ok bool) {
+	dir, ok := userDirs[platform]
+	if !ok {
+		return "", false
+	}
+	return path.Join(dir, file), true
+}
+
+// CLI returns the path of the code command, or "" if VS Code isn't
+// installed
+func CLI() string {
+	if p, err := exec.LookPath("code"); err == nil {
+		return p
+	}
+	if fs.FileExists(macCLI) {
+		return macCLI
+	}
+	return ""
+}
+
+// Extensions returns the IDs of the installed extensions, sorted
+func Extensions(cli string) ([]string, error) {
+	out, err := exec.Command(cli, "--list-extensions").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing extensions: %w", commandError(err))
+	}
+	return ParseExtensions(out), nil
+}
+
+// Install installs the extension with id, writing the command's output
+// to out
+func Install(cli, id string, out io.Writer) error {
+	cmd := exec.Command(cli, "--install-extension", id)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("installing %s: %w", id, err)
+	}
+	return nil
+}
+
+// commandError returns the first line of a failed command's stderr, or err
+func commandError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		msg, _, _ := strings.Cut(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+		return errors.New(msg)
+	}
+	return err
+}
+
+// ParseExtensions reads a list of extension IDs, one per line, skipping
+// blank lines and # comments. IDs are sorted and duplicates dropped.
+func ParseExtensions(data []byte) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		id := strings.TrimSpace(line)
+		if id == "" || seen[strings.ToLower(id)] {
+			continue
+		}
+		seen[strings.ToLower(id)] = true
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return strings.ToLower(ids[i]) < strings.ToLower(ids[j]) })
+	return ids
+}
+
+// FormatExtensions writes ids as the list ParseExtensions reads
+func FormatExtensions(ids []string) []byte {
+	var b strings.Builder
+	b.WriteString("# VS Code extensions, installed by 'dotcor vscode apply'\n")
+	for _, id := range ids {
+		b.WriteString(id + "\n")
+	}
+	return []byte(b.String())
+}
+
+// Missing returns the IDs in want that aren't installed. IDs are compared
+// ignoring case, as VS Code does.
+func Missing(want, installed []string) []string {
+	have := map[string]bool{}
+	for _, id := range installed {
+		have[strings.ToLower(id)] = true
+	}
+	var missing []string
+	for _, id := range want {
+		if !have[strings.ToLower(id)] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
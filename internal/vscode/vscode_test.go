@@ -0,0 +1,80 @@
+package vscode
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUserPath(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{"darwin", "~/Library/Application Support/Code/User/settings.json"},
+		{"linux", "~/.config/Code/User/settings.json"},
+		{"windows", "~/AppData/Roaming/Code/User/settings.json"},
+	}
+	for _, tt := range tests {
+		if got, ok := UserPath(tt.platform, "settings.json"); !ok || got != tt.want {
+			t.Errorf("UserPath(%s) = %q, %v, want %q", tt.platform, got, ok, tt.want)
+		}
+	}
+	if _, ok := UserPath("plan9", "settings.json"); ok {
+		t.Error("UserPath() on an unknown platform succeeded")
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	data := []byte("# comment\nms-python.python\n\ngolang.Go  # the Go extension\nesbenp.prettier-vscode\nms-python.Python\n")
+	want := []string{"esbenp.prettier-vscode", "golang.Go", "ms-python.python"}
+	if got := ParseExtensions(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseExtensions() = %v, want %v", got, want)
+	}
+	if got := ParseExtensions(FormatExtensions(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseExtensions(FormatExtensions()) = %v, want %v", got, want)
+	}
+
+	missing := Missing([]string{"golang.Go", "ms-python.python", "rust-lang.rust-analyzer"}, []string{"golang.go", "ms-python.python"})
+	if !reflect.DeepEqual(missing, []string{"rust-lang.rust-analyzer"}) {
+		t.Errorf("Missing() = %v", missing)
+	}
+}
+
+func TestExtensionsInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake code in this test is a shell script")
+	}
+
+	// The fake code lists two extensions and echoes what it installs
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncase \"$1\" in\n--list-extensions) printf 'golang.go\\nms-python.python\\n' ;;\n--install-extension) echo \"installed $2\" ;;\nesac\n"
+	cli := filepath.Join(bin, "code")
+	if err := os.WriteFile(cli, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	if got := CLI(); got != cli {
+		t.Errorf("CLI() = %q, want %q", got, cli)
+	}
+	ids, err := Extensions(cli)
+	if err != nil {
+		t.Fatalf("Extensions() error = %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"golang.go", "ms-python.python"}) {
+		t.Errorf("Extensions() = %v", ids)
+	}
+
+	var out bytes.Buffer
+	if err := Install(cli, "rust-lang.rust-analyzer", &out); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "installed rust-lang.rust-analyzer") {
+		t.Errorf("Install() output = %q", out.String())
+	}
+}
@@ -0,0 +1,129 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long the repository must be quiet before changes are handled
+const DefaultDebounce = 30 * time.Second
+
+// Watcher reports changes under a directory tree, ignoring the .git directory
+type Watcher struct {
+	root     string
+	debounce time.Duration
+	fsw      *fsnotify.Watcher
+}
+
+// New creates a watcher for every directory under root
+func New(root string, debounce time.Duration) (*Watcher, error) {
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("creating file watcher: %w", err)
+	}
+
+	w := &Watcher{root: root, debounce: debounce, fsw: fsw}
+	if err := w.addTree(root); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
+
+// Run calls onChange after a change is followed by a quiet period of the
+// debounce duration, until stop is closed. If onChange returns an error it is
+// passed to onError and the change is retried after the next quiet period.
+func (w *Watcher) Run(stop <-chan struct{}, onChange func() error, onError func(error)) error {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	pending := false
+
+	for {
+		select {
+		case <-stop:
+			timer.Stop()
+			return nil
+
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			if w.ignored(event.Name) {
+				continue
+			}
+
+			// New directories need their own watch (fsnotify is not recursive)
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					if err := w.addTree(event.Name); err != nil {
+						onError(err)
+					}
+				}
+			}
+
+			pending = true
+			timer.Reset(w.debounce)
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			onError(err)
+
+		case <-timer.C:
+			if !pending {
+				continue
+			}
+			if err := onChange(); err != nil {
+				onError(err)
+				timer.Reset(w.debounce)
+				continue
+			}
+			pending = false
+		}
+	}
+}
+
+// addTree watches dir and all directories below it
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if w.ignored(path) {
+			return filepath.SkipDir
+		}
+		if err := w.fsw.Add(path); err != nil {
+			return fmt.Errorf("watching %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// ignored reports whether path is inside the repository's .git directory
+func (w *Watcher) ignored(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return false
+	}
+	first, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return first == ".git"
+}
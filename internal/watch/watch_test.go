@@ -0,0 +1,66 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherDebouncesChanges(t *testing.T) {
+	dir, err := os.MkdirTemp("", "dotcor-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := New(dir, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer w.Close()
+
+	stop := make(chan struct{})
+	changes := make(chan struct{}, 10)
+	finished := make(chan error)
+	go func() {
+		finished <- w.Run(stop, func() error {
+			changes <- struct{}{}
+			return nil
+		}, func(err error) { t.Logf("watch error: %v", err) })
+	}()
+
+	// Several quick writes produce one callback
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "zshrc"), []byte{byte('a' + i)}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+
+	// Changes inside .git are ignored
+	if err := os.WriteFile(filepath.Join(dir, ".git", "index"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changes:
+		t.Error("change reported for .git or reported twice")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	close(stop)
+	if err := <-finished; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
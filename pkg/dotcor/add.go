@@ -0,0 +1,241 @@
+package dotcor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// Deploy modes for AddOptions.Mode
+const (
+	ModeSymlink  = config.DeployModeSymlink  // Symlink to the repo file (default)
+	ModeCopy     = config.DeployModeCopy     // Copy of the repo file
+	ModeHardlink = config.DeployModeHardlink // Hard link to the repo file, same filesystem only
+	ModeCompose  = config.DeployModeCompose  // Generated file including the repo file and <file>.local
+)
+
+// Outcome is what happened to one file
+type Outcome string
+
+// Outcomes of a file in Add, Remove and Apply
+const (
+	OutcomeDone    Outcome = "done"    // Added, removed or deployed
+	OutcomeSkipped Outcome = "skipped" // Nothing to do; Reason says why
+	OutcomeFailed  Outcome = "failed"  // Err says why
+)
+
+// FileResult is the outcome for one file
+type FileResult struct {
+	Path     string  // Source path, in ~ notation under the home directory
+	RepoPath string  // Path in the repository, relative to the files root
+	Outcome  Outcome // OutcomeDone, OutcomeSkipped or OutcomeFailed
+	Reason   string  // Why the file was skipped, or how it was deployed
+	Err      error   // Why the file failed
+}
+
+// AddOptions configures Add
+type AddOptions struct {
+	Category string // Repo directory to file the files under, instead of the automatic one
+	Mode     string // ModeSymlink (default), ModeCopy, ModeHardlink or ModeCompose
+	Force    bool   // Add files with warnings or potential secrets
+	Reown    bool   // Import the targets of symlinks pointing outside the repo
+	DryRun   bool   // Report what would be added without changing anything
+}
+
+// AddResult is the outcome of Add
+type AddResult struct {
+	Files     []FileResult
+	Committed bool // The added files were committed to git
+}
+
+// Added returns the files that were added
+func (r *AddResult) Added() []FileResult {
+	return filterOutcome(r.Files, OutcomeDone)
+}
+
+// Add moves files into the repository and deploys them in their place,
+// then commits them. A file that can't be added is reported in the result
+// and doesn't stop the others; the returned error is for failures of the
+// whole operation, or ctx being done.
+func (c *Client) Add(ctx context.Context, paths []string, opts AddOptions) (*AddResult, error) {
+	switch opts.Mode {
+	case "", ModeSymlink, ModeCopy, ModeHardlink, ModeCompose:
+	default:
+		return nil, fmt.Errorf("invalid mode %q", opts.Mode)
+	}
+	if opts.Mode == ModeSymlink {
+		opts.Mode = ""
+	}
+
+	result := &AddResult{Files: []FileResult{}}
+	err := c.locked(ctx, "dotcor add", func(cfg *config.Config) error {
+		scanner, err := core.NewSecretScanner(cfg.Scan)
+		if err != nil {
+			return err
+		}
+
+		var added []string
+		for _, path := range paths {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			file := addFile(cfg, scanner, path, opts)
+			result.Files = append(result.Files, file)
+			if file.Outcome == OutcomeDone {
+				added = append(added, file.RepoPath)
+			}
+		}
+		if opts.DryRun || len(added) == 0 {
+			return nil
+		}
+
+		if err := core.RecordChecksums(cfg, added...); err != nil {
+			return fmt.Errorf("recording checksums: %w", err)
+		}
+		result.Committed, err = commit(cfg, core.AddCommitMessage(added))
+		return err
+	})
+	return result, err
+}
+
+// addFile adds one file, as 'dotcor add' does without prompting
+func addFile(cfg *config.Config, scanner *core.SecretScanner, path string, opts AddOptions) FileResult {
+	file := FileResult{Path: path}
+	fail := func(err error) FileResult {
+		file.Outcome, file.Err = OutcomeFailed, err
+		return file
+	}
+
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return fail(fmt.Errorf("invalid path: %w", err))
+	}
+	if normalized, err := config.NormalizePath(path); err == nil {
+		file.Path = normalized
+	}
+	if !fs.FileExists(expanded) {
+		return fail(errors.New("file does not exist"))
+	}
+	if isSystem, _ := config.IsSystemPath(expanded); isSystem {
+		return fail(errors.New("file is outside the home directory"))
+	}
+
+	if cfg.IsManaged(path) {
+		file.Outcome, file.Reason = OutcomeSkipped, "already managed"
+		return file
+	}
+	if ignored, pattern := core.ShouldIgnore(expanded, cfg.IgnorePatterns); ignored {
+		file.Outcome, file.Reason = OutcomeSkipped, "ignored, matches "+pattern
+		return file
+	}
+
+	linkTarget := ""
+	if err := core.ValidateSourceFile(expanded, cfg); err != nil {
+		switch {
+		case errors.Is(err, core.ErrForeignSymlink):
+			target, evalErr := filepath.EvalSymlinks(expanded)
+			if evalErr != nil {
+				return fail(fmt.Errorf("resolving symlink: %w", evalErr))
+			}
+			if !opts.Reown || opts.Mode != "" {
+				file.Outcome, file.Reason = OutcomeSkipped, "symlink to "+target
+				return file
+			}
+			linkTarget = target
+		case opts.Force && core.IsValidationWarning(err):
+		default:
+			return fail(err)
+		}
+	}
+
+	if secrets, _ := scanner.ScanFile(expanded); len(secrets) > 0 && !opts.Force {
+		return fail(fmt.Errorf("potential secrets detected: %v", secrets))
+	}
+
+	customRepoPath := ""
+	if opts.Category != "" {
+		customRepoPath = filepath.Join(opts.Category, strings.TrimPrefix(filepath.Base(expanded), "."))
+	}
+	repoPath, err := config.GenerateRepoPath(path, customRepoPath)
+	if err != nil {
+		return fail(fmt.Errorf("generating repo path: %w", err))
+	}
+	file.RepoPath = repoPath
+	fullRepoPath, err := config.GetRepoFilePath(cfg, repoPath)
+	if err != nil {
+		return fail(err)
+	}
+	if opts.Mode == ModeHardlink && !fs.CanHardlink(expanded, filepath.Dir(fullRepoPath)) {
+		return fail(fs.ErrCrossDevice)
+	}
+	if opts.Mode == ModeCompose && !core.CanCompose(repoPath) {
+		return fail(fmt.Errorf("%s has no include syntax, so it can't be composed", file.Path))
+	}
+
+	if opts.DryRun {
+		file.Outcome = OutcomeDone
+		return file
+	}
+
+	mf := config.ManagedFile{
+		SourcePath: file.Path,
+		RepoPath:   repoPath,
+		AddedAt:    time.Now(),
+		Platforms:  []string{},
+		Mode:       opts.Mode,
+	}
+	if perm, err := core.AddedPerm(expanded); err == nil {
+		mf.Perm = perm
+	}
+
+	// A failed backup doesn't stop the add, the file is moved, not deleted
+	backupPath, _ := core.CreateManagedBackup(expanded, mf)
+
+	var tx *core.Transaction
+	if linkTarget != "" {
+		tx, err = core.ReownSymlinkTransaction(cfg, path, repoPath, mf)
+	} else {
+		tx, err = core.AddFileTransaction(cfg, path, repoPath, mf)
+	}
+	if err != nil {
+		return fail(fmt.Errorf("creating transaction: %w", err))
+	}
+	if err := tx.ExecuteAll(); err != nil {
+		if backupPath != "" && linkTarget == "" {
+			core.RestoreBackup(backupPath, expanded)
+		}
+		return fail(err)
+	}
+	tx.Commit()
+
+	// Files next to keys get the modes ssh and gpg insist on
+	if _, ok := core.SensitiveDirFor(expanded); ok {
+		if _, err := core.RestorePermissions(cfg, mf); err != nil {
+			file.Reason = err.Error()
+		}
+	}
+
+	file.Outcome = OutcomeDone
+	if linkTarget != "" {
+		file.Reason = "imported from " + linkTarget
+	}
+	return file
+}
+
+// filterOutcome returns the files with outcome
+func filterOutcome(files []FileResult, outcome Outcome) []FileResult {
+	var matched []FileResult
+	for _, f := range files {
+		if f.Outcome == outcome {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
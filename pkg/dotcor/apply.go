@@ -0,0 +1,238 @@
+package dotcor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/crypto"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/template"
+)
+
+// ApplyOptions configures Apply
+type ApplyOptions struct {
+	DryRun bool // Report what would be deployed without changing anything
+}
+
+// ApplyResult is the outcome of Apply
+type ApplyResult struct {
+	Files []FileResult
+}
+
+// Applied returns the files that were deployed
+func (r *ApplyResult) Applied() []FileResult {
+	return filterOutcome(r.Files, OutcomeDone)
+}
+
+// Apply deploys every managed file for this platform from the repository,
+// as 'dotcor init --apply' does: templates are rendered, secrets decrypted,
+// and files in the way are backed up and replaced. Files already deployed
+// are skipped. Each file is deployed in its own transaction, so a failure
+// rolls back only that file.
+func (c *Client) Apply(ctx context.Context, opts ApplyOptions) (*ApplyResult, error) {
+	result := &ApplyResult{Files: []FileResult{}}
+	err := c.locked(ctx, "dotcor init --apply", func(cfg *config.Config) error {
+		data, err := template.NewData(cfg)
+		if err != nil {
+			return fmt.Errorf("collecting template variables: %w", err)
+		}
+
+		var backend crypto.Backend
+		for _, mf := range cfg.GetManagedFilesForPlatform() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			tx := core.NewTransaction()
+			if opts.DryRun {
+				tx = core.NewPlanTransaction()
+			}
+			file := FileResult{Path: mf.SourcePath, RepoPath: mf.RepoPath}
+			note, applied, err := applyFile(tx, cfg, mf, data, &backend)
+			switch {
+			case err != nil:
+				file.Outcome, file.Err = OutcomeFailed, err
+			case !applied:
+				file.Outcome, file.Reason = OutcomeSkipped, note
+			default:
+				tx.Commit()
+				file.Outcome, file.Reason = OutcomeDone, note
+			}
+
+			// Git only keeps the executable bit, so clones lose modes like 0600
+			if !opts.DryRun && err == nil {
+				if _, err := core.RestorePermissions(cfg, mf); err != nil && file.Err == nil {
+					file.Outcome, file.Err = OutcomeFailed, err
+				}
+			}
+			result.Files = append(result.Files, file)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// applyFile runs the steps that deploy mf as part of tx. If mf is already
+// deployed nothing is done and applied is false, with note saying why;
+// otherwise note describes how it was deployed ("" for a plain symlink).
+func applyFile(tx *core.Transaction, cfg *config.Config, mf config.ManagedFile, data template.Data, backend *crypto.Backend) (note string, applied bool, err error) {
+	mf, err = config.ResolveVariant(mf)
+	if err != nil {
+		return "", false, err
+	}
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid path: %w", err)
+	}
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid repo path: %w", err)
+	}
+	if !fs.FileExists(repoPath) {
+		return "", false, errors.New("not in repository")
+	}
+
+	// Templates and secrets are linked to their rendered or decrypted output
+	if mf.IsTemplate() || mf.Encrypted {
+		if repoPath, err = config.GetLinkTargetPath(cfg, mf); err != nil {
+			return "", false, err
+		}
+		if mf.IsTemplate() {
+			err = tx.Execute(&core.StepOp{
+				Desc: fmt.Sprintf("render %s", mf.RepoPath),
+				DoFunc: func() error {
+					_, _, err := template.RenderManagedFile(cfg, mf, data)
+					return err
+				},
+			})
+		} else {
+			err = tx.Execute(&core.StepOp{
+				Desc:   fmt.Sprintf("decrypt %s", mf.RepoPath),
+				DoFunc: func() error { return decryptSecret(cfg, backend, mf) },
+			})
+		}
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	backup := &core.BackupFileOp{Path: sourcePath, File: &mf}
+	isLink, _ := fs.IsSymlink(sourcePath)
+
+	switch {
+	case mf.IsCopy():
+		if !isLink && fs.FileExists(sourcePath) {
+			if same, _ := fs.SameContent(sourcePath, repoPath); same {
+				return "already copied", false, nil
+			}
+			if err := tx.Execute(backup); err != nil {
+				return "", false, fmt.Errorf("backup failed: %w", err)
+			}
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc:     fmt.Sprintf("copy %s to %s", repoPath, sourcePath),
+			DoFunc:   func() error { return core.DeployCopy(cfg, mf) },
+			UndoFunc: func() error { return undoDeploy(backup, sourcePath) },
+			Deploys:  sourcePath,
+		})
+		return "copied", err == nil, err
+
+	case mf.IsHardlink():
+		if same, _ := fs.IsHardlinkTo(sourcePath, repoPath); same {
+			return "already linked", false, nil
+		}
+		if !isLink && fs.FileExists(sourcePath) {
+			if err := tx.Execute(backup); err != nil {
+				return "", false, fmt.Errorf("backup failed: %w", err)
+			}
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc:     fmt.Sprintf("hard link %s => %s", sourcePath, repoPath),
+			DoFunc:   func() error { return core.DeployHardlink(cfg, mf) },
+			UndoFunc: func() error { return undoDeploy(backup, sourcePath) },
+			Deploys:  sourcePath,
+		})
+		return "hard linked", err == nil, err
+
+	case mf.IsComposed():
+		if intact, _ := core.ComposedIntact(cfg, mf); intact {
+			return "already composed", false, nil
+		}
+		if !isLink && fs.FileExists(sourcePath) {
+			if err := tx.Execute(backup); err != nil {
+				return "", false, fmt.Errorf("backup failed: %w", err)
+			}
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc:     fmt.Sprintf("compose %s from %s", sourcePath, repoPath),
+			DoFunc:   func() error { return core.DeployComposed(cfg, mf) },
+			UndoFunc: func() error { return undoDeploy(backup, sourcePath) },
+			Deploys:  sourcePath,
+		})
+		return "composed", err == nil, err
+	}
+
+	// A link to the raw template is replaced rather than backed up
+	toTemplate := false
+	if mf.IsTemplate() && isLink {
+		templatePath, _ := config.GetRepoFilePath(cfg, mf.RepoPath)
+		status, err := fs.GetSymlinkStatus(sourcePath, templatePath)
+		toTemplate = err == nil && status.PointsToRepo
+	}
+	if isLink && !toTemplate {
+		if valid, _ := fs.IsValidSymlink(sourcePath); valid {
+			return "already linked", false, nil
+		}
+	}
+
+	if fs.FileExists(sourcePath) && !toTemplate {
+		if err := tx.Execute(backup); err != nil {
+			return "", false, fmt.Errorf("backup failed: %w", err)
+		}
+		err = tx.Execute(&core.StepOp{
+			Desc: fmt.Sprintf("remove %s", sourcePath),
+			DoFunc: func() error {
+				_, err := core.DeleteUserFile(cfg, sourcePath)
+				return err
+			},
+			UndoFunc: func() error { return core.RestoreBackup(backup.BackupPath, sourcePath) },
+		})
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	err = tx.Execute(&core.CreateSymlinkOp{Target: repoPath, Link: sourcePath, Style: cfg.LinkStyle})
+	return "", err == nil, err
+}
+
+// decryptSecret decrypts mf's secret unless its decrypted copy is up to
+// date. The backend is created on first use.
+func decryptSecret(cfg *config.Config, backend *crypto.Backend, mf config.ManagedFile) error {
+	if *backend == nil {
+		var err error
+		if *backend, err = crypto.NewBackend(cfg.Secrets); err != nil {
+			return err
+		}
+	}
+	if _, _, err := core.DecryptSecret(cfg, *backend, mf); err != nil {
+		return fmt.Errorf("decrypting: %w", err)
+	}
+	return nil
+}
+
+// undoDeploy removes a copy, hard link or composed file deployed at
+// sourcePath, restoring the file it replaced if one was backed up
+func undoDeploy(backup *core.BackupFileOp, sourcePath string) error {
+	if err := os.Remove(sourcePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if backup.BackupPath != "" {
+		return core.RestoreBackup(backup.BackupPath, sourcePath)
+	}
+	return nil
+}
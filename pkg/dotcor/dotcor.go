@@ -0,0 +1,210 @@
+// Package dotcor lets other Go programs, such as provisioners and GUIs,
+// manage dotfiles the way the dotcor command does without running it.
+//
+// A Client runs the core workflows (Add, Remove, Apply, Status and Sync)
+// against an initialized dotcor setup and reports what happened as typed
+// results instead of printing. Each call reloads config.yaml, so changes
+// made by the dotcor command in the meantime are seen, and mutating calls
+// hold the same lock as the command.
+//
+// Settings such as the home directory and the selected repository are
+// process-wide, so only one Client can be open in a process at a time;
+// Open fails with ErrClientOpen until the previous one is closed. Other
+// code in the process shouldn't change those settings while it's open.
+package dotcor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+)
+
+// Environment supplies the home directory, environment variables and
+// platform that paths are resolved with
+type Environment = config.Environment
+
+// StaticEnvironment is a fixed Environment, e.g. a temporary home for tests
+// or a custom root when embedding dotcor
+type StaticEnvironment = config.StaticEnvironment
+
+var (
+	// ErrNotInitialized is returned by Open when there's no config.yaml
+	ErrNotInitialized = errors.New("dotcor is not initialized")
+
+	// ErrLocked is returned when another dotcor process is changing files
+	ErrLocked = errors.New("another dotcor process holds the lock")
+
+	// ErrNotManaged is reported for a path dotcor doesn't manage
+	ErrNotManaged = errors.New("file is not managed")
+
+	// ErrClientOpen is returned by Open while another Client is open
+	ErrClientOpen = errors.New("another dotcor Client is open in this process")
+)
+
+// clientMu guards clientOpen, which is set while a Client is open
+var (
+	clientMu   sync.Mutex
+	clientOpen bool
+)
+
+// Options configures Open
+type Options struct {
+	Environment Environment // Where paths are resolved; the process environment if nil
+	Repository  string      // Named repository from the repositories section; "" for the default
+
+	// LockWait is how long mutating calls wait for another dotcor process
+	// to release the lock before failing with ErrLocked; 0 fails at once
+	LockWait time.Duration
+}
+
+// Client runs dotcor workflows. Only one Client can be open in a process,
+// and its methods must not be called concurrently.
+type Client struct {
+	restore  func() // Puts back the settings changed by Open
+	lockWait time.Duration
+	closed   bool
+}
+
+// Open returns a Client for the dotcor setup in opts.Environment,
+// returning an error wrapping ErrNotInitialized if there's none, or
+// ErrClientOpen if another Client hasn't been closed
+func Open(opts Options) (*Client, error) {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	if clientOpen {
+		return nil, ErrClientOpen
+	}
+
+	c := &Client{restore: func() {}, lockWait: opts.LockWait}
+	if opts.Environment != nil {
+		c.restore = config.SetEnvironment(opts.Environment)
+	}
+
+	if err := c.setup(opts.Repository); err != nil {
+		c.restore()
+		return nil, err
+	}
+	clientOpen = true
+	return c, nil
+}
+
+// setup selects the repository and applies the settings from config.yaml
+// that the dotcor command applies before every command. Close puts back the
+// ones in use before.
+func (c *Client) setup(repository string) error {
+	repo, rules := config.SelectedRepo(), config.CategoryRules()
+	compress, backend := core.BackupCompression(), git.SelectedBackend()
+	style, tmpl := git.CommitFormat()
+	restoreEnv := c.restore
+	c.restore = func() {
+		config.SelectRepo(repo)
+		config.SetCategoryRules(rules)
+		core.SetBackupCompression(compress)
+		_ = git.SetCommitFormat(style, tmpl)
+		_ = git.SetBackend(backend)
+		restoreEnv()
+	}
+
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return fmt.Errorf("%w: no config at %s", ErrNotInitialized, configPath)
+	}
+
+	if err := config.SelectRepo(repository); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if repository != "" {
+		if _, ok := cfg.Repositories[repository]; !ok {
+			return fmt.Errorf("unknown repository %q", repository)
+		}
+	}
+
+	core.SetBackupCompression(cfg.Backups.Compress)
+	config.SetCategoryRules(cfg.Categories)
+	if err := git.SetCommitFormat(cfg.CommitStyle, cfg.CommitTemplate); err != nil {
+		return err
+	}
+	return git.SetBackend(cfg.GitBackend)
+}
+
+// Close restores the environment and settings that were in use before
+// Open, after which another Client can be opened. Closing twice does nothing.
+func (c *Client) Close() {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.restore()
+	clientOpen = false
+}
+
+// FilesRoot returns the directory managed files are stored in
+func (c *Client) FilesRoot() (string, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("loading config: %w", err)
+	}
+	return config.GetFilesRoot(cfg)
+}
+
+// locked loads the config and runs fn holding the dotcor lock, with git
+// commands killed if ctx is canceled. Changes are journaled under command,
+// so 'dotcor undo' can revert them.
+func (c *Client) locked(ctx context.Context, command string, fn func(cfg *config.Config) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	core.SetLockWait(ctx, c.lockWait)
+	if err := core.AcquireLock(); err != nil {
+		if errors.Is(err, core.ErrLockHeld) {
+			return fmt.Errorf("%w: %v", ErrLocked, err)
+		}
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer core.ReleaseLock()
+	defer git.SetContext(ctx)()
+
+	core.SetJournalCommand(command)
+	return fn(cfg)
+}
+
+// commit commits changes under the files root with message, if git is
+// available and the files root is a repository. It reports whether a
+// commit was made.
+func commit(cfg *config.Config, message string) (bool, error) {
+	if !git.IsAvailable() {
+		return false, nil
+	}
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err != nil || !git.IsRepo(repoPath) {
+		return false, err
+	}
+	if changed, err := git.HasChanges(repoPath, core.UserPathspecs...); err != nil || !changed {
+		return false, err
+	}
+	if err := git.AutoCommit(repoPath, message, core.UserPathspecs...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,210 @@
+package dotcor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/git"
+)
+
+// initHome sets up dotcor in a new home directory with a git repository
+// pushing to a bare remote, returning the environment for it
+func initHome(t *testing.T) StaticEnvironment {
+	t.Helper()
+	if !git.IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+
+	tempDir := t.TempDir()
+	env := StaticEnvironment{Home: filepath.Join(tempDir, "home")}
+	defer config.SetEnvironment(env)()
+
+	cfg, err := config.NewDefaultConfig()
+	if err != nil {
+		t.Fatalf("NewDefaultConfig() error = %v", err)
+	}
+	if err := os.MkdirAll(cfg.RepoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.InitRepo(cfg.RepoPath); err != nil {
+		t.Fatalf("InitRepo() error = %v", err)
+	}
+	remote := filepath.Join(tempDir, "remote.git")
+	for _, args := range [][]string{
+		{"init", "--bare", remote},
+		{"-C", cfg.RepoPath, "config", "user.email", "test@example.com"},
+		{"-C", cfg.RepoPath, "config", "user.name", "Test"},
+		{"-C", cfg.RepoPath, "remote", "add", "origin", remote},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s", args, output)
+		}
+	}
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+	return env
+}
+
+func TestOpenNotInitialized(t *testing.T) {
+	_, err := Open(Options{Environment: StaticEnvironment{Home: t.TempDir()}})
+	if !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Open() error = %v, want ErrNotInitialized", err)
+	}
+}
+
+func TestOpenOneClientAtATime(t *testing.T) {
+	env := initHome(t)
+	c, err := Open(Options{Environment: env})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := Open(Options{Environment: env}); !errors.Is(err, ErrClientOpen) {
+		t.Errorf("second Open() error = %v, want ErrClientOpen", err)
+	}
+
+	c.Close()
+	c.Close()
+	other, err := Open(Options{Environment: env})
+	if err != nil {
+		t.Fatalf("Open() after Close() error = %v", err)
+	}
+	other.Close()
+}
+
+func TestWorkflow(t *testing.T) {
+	env := initHome(t)
+	ctx := context.Background()
+
+	c, err := Open(Options{Environment: env})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer c.Close()
+
+	zshrc := filepath.Join(env.Home, ".zshrc")
+	if err := os.WriteFile(zshrc, []byte("export EDITOR=vim\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add moves the file into the repo and links it back
+	added, err := c.Add(ctx, []string{"~/.zshrc", "~/.missing"}, AddOptions{})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if len(added.Added()) != 1 || added.Files[0].RepoPath != "shell/zshrc" || !added.Committed {
+		t.Fatalf("Add() = %+v, want ~/.zshrc added as shell/zshrc and committed", added)
+	}
+	if added.Files[1].Outcome != OutcomeFailed || added.Files[1].Err == nil {
+		t.Errorf("Add() of a missing file = %+v, want failed", added.Files[1])
+	}
+	if info, err := os.Lstat(zshrc); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("~/.zshrc after Add() should be a symlink, got %v, %v", info, err)
+	}
+
+	status, err := c.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if !status.Healthy() || len(status.Files) != 1 || status.Git == nil {
+		t.Errorf("Status() after Add() = %+v, want one healthy file", status)
+	}
+
+	// Apply redeploys a deleted link
+	if err := os.Remove(zshrc); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := c.Status(ctx); status.Files[0].State != StateMissingSource {
+		t.Errorf("Status() after deleting the link = %s, want %s", status.Files[0].State, StateMissingSource)
+	}
+	applied, err := c.Apply(ctx, ApplyOptions{})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(applied.Applied()) != 1 {
+		t.Errorf("Apply() = %+v, want one file applied", applied)
+	}
+	if status, _ := c.Status(ctx); !status.Healthy() {
+		t.Errorf("Status() after Apply() = %+v, want healthy", status)
+	}
+
+	// Sync pushes the commits to origin
+	synced, err := c.Sync(ctx, SyncOptions{})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(synced.Pushed) != 1 || synced.Pushed[0] != "origin" {
+		t.Errorf("Sync() = %+v, want pushed to origin", synced)
+	}
+
+	// Remove puts the file back in place
+	removed, err := c.Remove(ctx, []string{"~/.zshrc", "~/.vimrc"}, RemoveOptions{})
+	if err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if len(removed.Removed()) != 1 || !removed.Committed {
+		t.Errorf("Remove() = %+v, want ~/.zshrc removed and committed", removed)
+	}
+	if !errors.Is(removed.Files[1].Err, ErrNotManaged) {
+		t.Errorf("Remove() of an unmanaged file error = %v, want ErrNotManaged", removed.Files[1].Err)
+	}
+	if data, err := os.ReadFile(zshrc); err != nil || string(data) != "export EDITOR=vim\n" {
+		t.Errorf("~/.zshrc after Remove() = %q, %v", data, err)
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	env := initHome(t)
+	c, err := Open(Options{Environment: env})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Add(ctx, []string{"~/.zshrc"}, AddOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Add() with a canceled context error = %v, want context.Canceled", err)
+	}
+	if _, err := c.Status(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Status() with a canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSyncBranchAndClose(t *testing.T) {
+	env := initHome(t)
+	restore := config.SetEnvironment(env)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GitBranch = "laptop"
+	cfg.CommitStyle = git.CommitStyleConventional
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	restore()
+
+	c, err := Open(Options{Environment: env})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if style, _ := git.CommitFormat(); style != git.CommitStyleConventional {
+		t.Errorf("commit style after Open() = %q", style)
+	}
+
+	// Another branch than git_branch is checked out
+	if _, err := c.Sync(context.Background(), SyncOptions{}); err == nil {
+		t.Error("Sync() on the wrong branch should return error")
+	}
+
+	c.Close()
+	if style, _ := git.CommitFormat(); style != "" {
+		t.Errorf("commit style after Close() = %q, want the previous one", style)
+	}
+}
@@ -0,0 +1,172 @@
+package dotcor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+)
+
+// RemoveOptions configures Remove
+type RemoveOptions struct {
+	KeepRepo bool // Only stop managing the files, leaving them in the repository
+	DryRun   bool // Report what would be removed without changing anything
+}
+
+// RemoveResult is the outcome of Remove
+type RemoveResult struct {
+	Files     []FileResult
+	Committed bool // The removal was committed to git
+}
+
+// Removed returns the files that were removed
+func (r *RemoveResult) Removed() []FileResult {
+	return filterOutcome(r.Files, OutcomeDone)
+}
+
+// Remove stops managing files, putting a copy of each back in place of its
+// link and deleting it from the repository, then commits. Paths that aren't
+// managed fail with ErrNotManaged.
+func (c *Client) Remove(ctx context.Context, paths []string, opts RemoveOptions) (*RemoveResult, error) {
+	result := &RemoveResult{Files: []FileResult{}}
+	err := c.locked(ctx, "dotcor remove", func(cfg *config.Config) error {
+		removed := 0
+		for _, path := range paths {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			mf, err := cfg.GetManagedFile(path)
+			if err != nil {
+				result.Files = append(result.Files, FileResult{Path: path, Outcome: OutcomeFailed, Err: ErrNotManaged})
+				continue
+			}
+
+			file := FileResult{Path: mf.SourcePath, RepoPath: mf.RepoPath, Outcome: OutcomeDone}
+			if !opts.DryRun {
+				if err := removeFile(cfg, *mf, opts.KeepRepo); err != nil {
+					file.Outcome, file.Err = OutcomeFailed, err
+				} else {
+					removed++
+				}
+			}
+			result.Files = append(result.Files, file)
+		}
+		if removed == 0 || opts.KeepRepo {
+			return nil
+		}
+
+		var err error
+		result.Committed, err = commit(cfg, core.RemoveCommitMessage(removed))
+		return err
+	})
+	return result, err
+}
+
+// removeFile removes one managed file in a transaction, as 'dotcor remove'
+// does
+func removeFile(cfg *config.Config, mf config.ManagedFile, keepRepo bool) error {
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return fmt.Errorf("invalid source path: %w", err)
+	}
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return fmt.Errorf("invalid repo path: %w", err)
+	}
+	isLink, err := fs.IsSymlink(sourcePath)
+	if err != nil {
+		return fmt.Errorf("checking symlink status: %w", err)
+	}
+
+	tx := core.NewTransaction()
+	if keepRepo {
+		if isLink {
+			if err := tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath}); err != nil {
+				return err
+			}
+		}
+		if err := tx.Execute(&core.RemoveFromConfigOp{Config: cfg, SourcePath: mf.SourcePath}); err != nil {
+			return err
+		}
+		tx.Commit()
+		return nil
+	}
+
+	// Templates are restored as their rendered output, secrets as their
+	// decrypted copy
+	restoreFrom := repoPath
+	renderedPath := ""
+	if mf.IsTemplate() || mf.Encrypted {
+		if rendered, err := config.GetLinkTargetPath(cfg, mf); err == nil && fs.FileExists(rendered) {
+			restoreFrom, renderedPath = rendered, rendered
+		} else if mf.Encrypted {
+			return errors.New("secret not decrypted, apply it first")
+		}
+	}
+
+	// Copies and hard links are already in place
+	keepLocalCopy := (mf.IsCopy() || mf.IsHardlink()) && !isLink && fs.FileExists(sourcePath)
+
+	if err := tx.Execute(&core.CreateDirOp{Path: filepath.Dir(sourcePath)}); err != nil {
+		return err
+	}
+	if isLink {
+		if err := tx.Execute(&core.RemoveSymlinkOp{Link: sourcePath}); err != nil {
+			return err
+		}
+	}
+
+	inRepo := fs.FileExists(repoPath)
+	if inRepo {
+		if !keepLocalCopy {
+			if err := tx.Execute(&core.CopyFileOp{Src: restoreFrom, Dst: sourcePath, Preserve: true}); err != nil {
+				return err
+			}
+		}
+		if err := tx.Execute(&core.RemoveFileOp{Path: repoPath}); err != nil {
+			return err
+		}
+		if renderedPath != "" {
+			err := tx.Execute(&core.StepOp{
+				Desc: fmt.Sprintf("remove %s", renderedPath),
+				DoFunc: func() error {
+					os.Remove(renderedPath)
+					return nil
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Execute(&core.RemoveFromConfigOp{Config: cfg, SourcePath: mf.SourcePath}); err != nil {
+		return err
+	}
+	tx.Commit()
+
+	if inRepo {
+		removeEmptyDirs(filepath.Dir(repoPath))
+	}
+	return nil
+}
+
+// removeEmptyDirs removes dir and its parents while they're empty
+func removeEmptyDirs(dir string) {
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 || os.Remove(dir) != nil {
+			return
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,185 @@
+package dotcor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/fs"
+	"github.com/justincordova/dotcor/internal/git"
+)
+
+// FileState is the health of one managed file
+type FileState string
+
+// File states, as shown by 'dotcor status'
+const (
+	StateOK            FileState = "ok"
+	StateMissingRepo   FileState = "missing-repo"   // Not in the repository
+	StateMissingSource FileState = "missing-source" // Nothing deployed in its place
+	StateNotRendered   FileState = "not-rendered"   // Template without rendered output
+	StateNotDecrypted  FileState = "not-decrypted"  // Secret without a decrypted copy
+	StateNotDeployed   FileState = "not-deployed"   // A regular file or another link is in its place
+	StateBroken        FileState = "broken"         // Symlink to a file that doesn't exist
+	StateModified      FileState = "modified"       // Copy that differs from the repository
+	StateError         FileState = "error"          // Problem says what went wrong
+)
+
+// FileStatus is the state of one managed file
+type FileStatus struct {
+	Path     string // Source path, in ~ notation under the home directory
+	RepoPath string // Path in the repository, relative to the files root
+	Mode     string // ModeSymlink, ModeCopy, ModeHardlink or ModeCompose
+	State    FileState
+	Problem  string // What's wrong, for states other than StateOK
+}
+
+// GitStatus is the state of the repository
+type GitStatus struct {
+	Branch      string
+	Uncommitted int  // Changed, staged and untracked files
+	AheadBy     int  // Commits not pushed yet
+	BehindBy    int  // Commits on the remote not pulled yet, as of the last fetch
+	HasRemote   bool // origin is configured
+	Conflicts   int  // Files with unresolved merge conflicts
+}
+
+// StatusResult is the outcome of Status
+type StatusResult struct {
+	Files []FileStatus
+	Git   *GitStatus // nil if the files root isn't a git repository
+}
+
+// Healthy reports whether every file is deployed and nothing is uncommitted
+func (r *StatusResult) Healthy() bool {
+	for _, f := range r.Files {
+		if f.State != StateOK {
+			return false
+		}
+	}
+	return r.Git == nil || (r.Git.Uncommitted == 0 && r.Git.Conflicts == 0)
+}
+
+// Status reports the state of every managed file for this platform and of
+// the repository. It doesn't take the lock or change anything.
+func (c *Client) Status(ctx context.Context) (*StatusResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+
+	result := &StatusResult{Files: []FileStatus{}}
+	for _, mf := range cfg.GetManagedFilesForPlatform() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, fileStatus(cfg, mf))
+	}
+
+	repoPath, err := config.GetFilesRoot(cfg)
+	if err == nil && git.IsAvailable() && git.IsRepo(repoPath) {
+		defer git.SetContext(ctx)()
+		status, err := git.GetStatus(repoPath)
+		if err != nil {
+			return nil, fmt.Errorf("getting git status: %w", err)
+		}
+		result.Git = &GitStatus{
+			Branch:      status.Branch,
+			Uncommitted: len(status.Changes),
+			AheadBy:     status.AheadBy,
+			BehindBy:    status.BehindBy,
+			HasRemote:   status.RemoteExists,
+			Conflicts:   status.ConflictCount,
+		}
+	}
+	return result, nil
+}
+
+// fileStatus checks that mf is deployed from the repository
+func fileStatus(cfg *config.Config, mf config.ManagedFile) FileStatus {
+	status := FileStatus{Path: mf.SourcePath, RepoPath: mf.RepoPath, Mode: ModeSymlink}
+	if mf.Mode != "" {
+		status.Mode = mf.Mode
+	}
+	problem := func(state FileState, format string, args ...any) FileStatus {
+		status.State, status.Problem = state, fmt.Sprintf(format, args...)
+		return status
+	}
+
+	mf, err := config.ResolveVariant(mf)
+	if err != nil {
+		return problem(StateError, "%v", err)
+	}
+	sourcePath, err := config.ExpandPath(mf.SourcePath)
+	if err != nil {
+		return problem(StateError, "invalid source path: %v", err)
+	}
+	repoPath, err := config.GetRepoFilePath(cfg, mf.RepoPath)
+	if err != nil {
+		return problem(StateError, "invalid repo path: %v", err)
+	}
+	if !fs.FileExists(repoPath) {
+		return problem(StateMissingRepo, "file missing from repository")
+	}
+
+	if mf.IsTemplate() || mf.Encrypted {
+		if repoPath, err = config.GetLinkTargetPath(cfg, mf); err != nil {
+			return problem(StateError, "%v", err)
+		}
+		if !fs.FileExists(repoPath) && mf.IsTemplate() {
+			return problem(StateNotRendered, "template not rendered")
+		}
+		if !fs.FileExists(repoPath) {
+			return problem(StateNotDecrypted, "secret not decrypted")
+		}
+	}
+
+	link, err := fs.GetSymlinkStatus(sourcePath, repoPath)
+	if err != nil {
+		return problem(StateError, "%v", err)
+	}
+	if !link.Exists {
+		return problem(StateMissingSource, "nothing deployed at %s", mf.SourcePath)
+	}
+
+	switch {
+	case mf.IsCopy():
+		if link.IsSymlink {
+			return problem(StateNotDeployed, "a symlink instead of a copy")
+		}
+		if differs, err := core.CopyDiffers(cfg, mf); err != nil {
+			return problem(StateError, "%v", err)
+		} else if differs {
+			return problem(StateModified, "local copy differs from repo")
+		}
+	case mf.IsHardlink():
+		if same, err := fs.IsHardlinkTo(sourcePath, repoPath); err != nil {
+			return problem(StateError, "%v", err)
+		} else if !same {
+			return problem(StateNotDeployed, "not hard linked to the repo file")
+		}
+	case mf.IsComposed():
+		if intact, err := core.ComposedIntact(cfg, mf); err != nil {
+			return problem(StateError, "%v", err)
+		} else if !intact {
+			return problem(StateNotDeployed, "not the composed file dotcor generates")
+		}
+	default:
+		if !link.IsSymlink {
+			return problem(StateNotDeployed, "a regular file, not a symlink")
+		}
+		if !link.TargetExists {
+			return problem(StateBroken, "symlink target does not exist")
+		}
+		if !link.PointsToRepo {
+			return problem(StateNotDeployed, "points to %s instead of the repo file", link.ActualTarget)
+		}
+	}
+
+	status.State = StateOK
+	return status
+}
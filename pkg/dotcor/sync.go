@@ -0,0 +1,124 @@
+package dotcor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/justincordova/dotcor/internal/config"
+	"github.com/justincordova/dotcor/internal/core"
+	"github.com/justincordova/dotcor/internal/git"
+)
+
+// ErrBehindRemote is returned by Sync when the remote has commits that
+// aren't here yet. Sync again with Pull or Rebase to bring them in.
+var ErrBehindRemote = git.ErrBehindRemote
+
+// SyncOptions configures Sync
+type SyncOptions struct {
+	Message string // Commit message, used as is; by default one following commit_style
+	Pull    bool   // Merge the remote's new commits before pushing
+	Rebase  bool   // Replay local commits on top of the remote's new ones before pushing
+	NoPush  bool   // Commit (and pull) without pushing
+}
+
+// SyncResult is the outcome of Sync
+type SyncResult struct {
+	Committed bool     // Local changes were committed
+	Pulled    []string // Repo paths changed by commits pulled from the remote
+	Pushed    []string // Remotes pushed to
+}
+
+// Sync commits local changes, including edits to copies, then optionally
+// pulls from origin and pushes to origin and the remotes in git_remotes, as
+// 'dotcor sync' does. Like it, Sync refuses to run while a merge or rebase
+// is in progress or while a branch other than git_branch is checked out.
+// Canceling ctx stops a pull or push in progress.
+func (c *Client) Sync(ctx context.Context, opts SyncOptions) (*SyncResult, error) {
+	if opts.Pull && opts.Rebase {
+		return nil, errors.New("pull and rebase can't be used together")
+	}
+	if !git.IsAvailable() {
+		return nil, errors.New("git is not installed")
+	}
+
+	result := &SyncResult{}
+	err := c.locked(ctx, "dotcor sync", func(cfg *config.Config) error {
+		repoPath, err := config.GetFilesRoot(cfg)
+		if err != nil {
+			return err
+		}
+		if !git.IsRepo(repoPath) {
+			return fmt.Errorf("not a git repository: %s", repoPath)
+		}
+		status, err := git.GetStatus(repoPath)
+		if err != nil {
+			return fmt.Errorf("getting git status: %w", err)
+		}
+		if err := core.CheckSyncState(repoPath, status); err != nil {
+			return err
+		}
+		if err := core.CheckSyncBranch(cfg, status); err != nil {
+			return err
+		}
+
+		// Copies don't write through to the repo like links do
+		var changed []string
+		for _, mf := range core.EditedCopies(cfg) {
+			if err := core.PullCopyEdits(cfg, mf); err != nil {
+				return fmt.Errorf("pulling local edits: %w", err)
+			}
+			changed = append(changed, mf.RepoPath)
+		}
+
+		if result.Committed, err = core.CommitSync(repoPath, opts.Message); err != nil {
+			return err
+		}
+
+		if opts.Pull || opts.Rebase {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			pulled, err := core.PullWithBackup(cfg, repoPath, opts.Rebase)
+			if err != nil {
+				return fmt.Errorf("pulling from remote: %w", err)
+			}
+			result.Pulled = pulled.Incoming
+			changed = append(changed, pulled.Incoming...)
+
+			// Git replaces updated files, which copies and hard links don't follow
+			var errs []error
+			for _, r := range core.RedeployChanged(cfg, pulled.Incoming) {
+				if r.Err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", r.File.SourcePath, r.Err))
+				}
+			}
+			if err := errors.Join(errs...); err != nil {
+				return fmt.Errorf("redeploying pulled files: %w", err)
+			}
+		}
+
+		// What was committed and pulled is the new known-good content
+		if len(changed) > 0 {
+			if err := core.RecordChecksums(cfg, changed...); err != nil {
+				return fmt.Errorf("recording checksums: %w", err)
+			}
+		}
+
+		// A detached HEAD has no branch to push
+		if opts.NoPush || status.Detached {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pushes, err := core.PushAllRemotes(cfg, repoPath)
+		for _, p := range pushes {
+			if p.Err == nil {
+				result.Pushed = append(result.Pushed, p.Name)
+			}
+		}
+		return err
+	})
+	return result, err
+}